@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+const (
+	EntityWorkspace  = "workspace"
+	EntityFeature    = "feature"
+	EntityPhase      = "phase"
+	EntityStory      = "story"
+	EntityTicket     = "ticket"
+	EntityTicketPlan = "ticket_plan"
+	EntityBounty     = "bounty"
+	EntityBudget     = "budget"
+	EntityUserRole   = "user_role"
+	EntityRepository = "repository"
+	EntityPayment    = "payment"
+	// EntityPaymentPolicy entries use the workspace uuid as entity id.
+	EntityPaymentPolicy = "payment_policy"
+	// EntitySpendLimits entries use the workspace uuid as entity id.
+	EntitySpendLimits = "spend_limits"
+	// EntityWorkflows entries use the workspace uuid as entity id.
+	EntityWorkflows = "workflows"
+	// EntityFileRetention entries use the workspace uuid as entity id.
+	EntityFileRetention = "file_retention"
+)
+
+// Entities lists every entity type the log can be filtered on.
+var Entities = []string{
+	EntityWorkspace,
+	EntityFeature,
+	EntityPhase,
+	EntityStory,
+	EntityTicket,
+	EntityTicketPlan,
+	EntityBounty,
+	EntityBudget,
+	EntityUserRole,
+	EntityRepository,
+	EntityPayment,
+	EntityPaymentPolicy,
+	EntitySpendLimits,
+	EntityWorkflows,
+	EntityFileRetention,
+}
+
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionDeposit  = "deposit"
+	ActionWithdraw = "withdraw"
+	ActionPay      = "pay"
+	ActionReverse  = "reverse"
+	ActionRetry    = "retry"
+)
+
+// ignoredFields change on every write and would turn each update into a
+// non-empty diff.
+var ignoredFields = map[string]bool{
+	"updated":      true,
+	"updated_at":   true,
+	"updated_by":   true,
+	"time_updated": true,
+	"last_edited":  true,
+}
+
+// Store persists audit entries. db.Database satisfies it.
+type Store interface {
+	CreateAuditLog(entry *db.AuditLog) error
+}
+
+// Default is the store used by Record. Nothing is recorded until Init is
+// called.
+var Default Store
+
+func Init(store Store) {
+	Default = store
+}
+
+func ValidEntity(entity string) bool {
+	for _, e := range Entities {
+		if e == entity {
+			return true
+		}
+	}
+	return false
+}
+
+// Record appends an entry describing how actor changed an entity from
+// before to after. Either side may be nil for creations and deletions.
+// Updates that leave every field untouched are not recorded.
+func Record(actor string, workspaceUuid string, entityType string, entityID string, action string, before interface{}, after interface{}) {
+	store := Default
+	if store == nil || entityID == "" {
+		return
+	}
+
+	changes := Diff(before, after)
+	if action == ActionUpdate && len(changes) == 0 {
+		return
+	}
+
+	entry := &db.AuditLog{
+		WorkspaceUuid: workspaceUuid,
+		Actor:         actor,
+		EntityType:    entityType,
+		EntityID:      entityID,
+		Action:        action,
+		Changes:       changes,
+	}
+	if err := store.CreateAuditLog(entry); err != nil {
+		logger.Log.Error("[audit] could not record %s of %s %s: %v", action, entityType, entityID, err)
+	}
+}
+
+// Diff maps each field that differs between the JSON encodings of before
+// and after to its old and new value.
+func Diff(before interface{}, after interface{}) db.PropertyMap {
+	old := fields(before)
+	updated := fields(after)
+
+	changes := db.PropertyMap{}
+	for key, value := range old {
+		if ignoredFields[key] {
+			continue
+		}
+		if next, ok := updated[key]; !ok || !reflect.DeepEqual(value, next) {
+			changes[key] = map[string]interface{}{"before": value, "after": updated[key]}
+		}
+	}
+	for key, value := range updated {
+		if ignoredFields[key] {
+			continue
+		}
+		if _, ok := old[key]; !ok {
+			changes[key] = map[string]interface{}{"before": nil, "after": value}
+		}
+	}
+	return changes
+}
+
+// fields decodes v into its JSON fields. Values that don't encode to an
+// object are kept under "value".
+func fields(v interface{}) map[string]interface{} {
+	if v == nil {
+		return map[string]interface{}{}
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return map[string]interface{}{}
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return map[string]interface{}{}
+	}
+	if object, ok := decoded.(map[string]interface{}); ok {
+		return object
+	}
+	return map[string]interface{}{"value": decoded}
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryStore struct {
+	entries []db.AuditLog
+	err     error
+}
+
+func (s *memoryStore) CreateAuditLog(entry *db.AuditLog) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
+func withStore(t *testing.T, store Store) {
+	original := Default
+	t.Cleanup(func() { Default = original })
+	Init(store)
+}
+
+func TestDiff(t *testing.T) {
+	before := db.WorkspaceFeatures{Uuid: "f1", Name: "Payouts", Brief: "old", UpdatedBy: "alice"}
+	after := db.WorkspaceFeatures{Uuid: "f1", Name: "Payouts", Brief: "new", UpdatedBy: "bob"}
+
+	t.Run("Only changed fields", func(t *testing.T) {
+		changes := Diff(before, after)
+
+		assert.Len(t, changes, 1)
+		assert.Equal(t, map[string]interface{}{"before": "old", "after": "new"}, changes["brief"])
+	})
+
+	t.Run("Creation lists every field", func(t *testing.T) {
+		changes := Diff(nil, after)
+
+		assert.Equal(t, map[string]interface{}{"before": nil, "after": "Payouts"}, changes["name"])
+		assert.NotContains(t, changes, "updated_by")
+	})
+
+	t.Run("Deletion lists every field", func(t *testing.T) {
+		var deleted *db.WorkspaceFeatures
+		changes := Diff(&before, deleted)
+
+		assert.Equal(t, map[string]interface{}{"before": "old", "after": nil}, changes["brief"])
+	})
+
+	t.Run("Scalars", func(t *testing.T) {
+		changes := Diff(100, 40)
+
+		assert.Equal(t, map[string]interface{}{"before": float64(100), "after": float64(40)}, changes["value"])
+	})
+}
+
+func TestRecord(t *testing.T) {
+	t.Run("Records entry", func(t *testing.T) {
+		store := &memoryStore{}
+		withStore(t, store)
+
+		Record("alice", "ws-1", EntityFeature, "f1", ActionUpdate,
+			db.WorkspaceFeatures{Uuid: "f1", Brief: "old"},
+			db.WorkspaceFeatures{Uuid: "f1", Brief: "new"})
+
+		assert.Len(t, store.entries, 1)
+		entry := store.entries[0]
+		assert.Equal(t, "alice", entry.Actor)
+		assert.Equal(t, "ws-1", entry.WorkspaceUuid)
+		assert.Equal(t, EntityFeature, entry.EntityType)
+		assert.Equal(t, "f1", entry.EntityID)
+		assert.Equal(t, ActionUpdate, entry.Action)
+		assert.Contains(t, entry.Changes, "brief")
+	})
+
+	t.Run("Skips updates without changes", func(t *testing.T) {
+		store := &memoryStore{}
+		withStore(t, store)
+
+		feature := db.WorkspaceFeatures{Uuid: "f1", Brief: "same"}
+		Record("alice", "ws-1", EntityFeature, "f1", ActionUpdate, feature, feature)
+
+		assert.Empty(t, store.entries)
+	})
+
+	t.Run("Keeps deletes without fields", func(t *testing.T) {
+		store := &memoryStore{}
+		withStore(t, store)
+
+		Record("alice", "ws-1", EntityTicket, "t1", ActionDelete, nil, nil)
+
+		assert.Len(t, store.entries, 1)
+	})
+
+	t.Run("Store errors are not fatal", func(t *testing.T) {
+		withStore(t, &memoryStore{err: errors.New("db down")})
+
+		assert.NotPanics(t, func() {
+			Record("alice", "ws-1", EntityBudget, "ws-1", ActionWithdraw, 100, 40)
+		})
+	})
+
+	t.Run("No-op without store", func(t *testing.T) {
+		withStore(t, nil)
+
+		assert.NotPanics(t, func() {
+			Record("alice", "ws-1", EntityBudget, "ws-1", ActionWithdraw, 100, 40)
+		})
+	})
+}
+
+func TestValidEntity(t *testing.T) {
+	assert.True(t, ValidEntity(EntityTicketPlan))
+	assert.False(t, ValidEntity("chat"))
+}
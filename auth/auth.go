@@ -49,7 +49,7 @@ func PubKeyContext(next http.Handler) http.Handler {
 		}
 
 		if token == "" {
-			logger.Log.Info("[auth] no token")
+			logger.FromContext(r.Context()).Info("[auth] no token")
 			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 			return
 		}
@@ -61,33 +61,35 @@ func PubKeyContext(next http.Handler) http.Handler {
 
 			if err != nil {
 				fmt.Println("JWT error =================================", err)
-				logger.Log.Info("Failed to parse JWT", token)
+				logger.FromContext(r.Context()).Info("Failed to parse JWT", token)
 				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 				return
 			}
 
 			if claims.VerifyExpiresAt(time.Now().UnixNano(), true) {
 				fmt.Println("Token has expired =================================")
-				logger.Log.Info("Token has expired")
+				logger.FromContext(r.Context()).Info("Token has expired")
 				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), ContextKey, claims["pubkey"])
+			ctx = logger.WithPubKey(ctx, fmt.Sprintf("%v", claims["pubkey"]))
 			next.ServeHTTP(w, r.WithContext(ctx))
 		} else {
 			pubkey, err := VerifyTribeUUID(token, true)
 
 			if pubkey == "" || err != nil {
-				logger.Log.Info("[auth] no pubkey || err != nil")
+				logger.FromContext(r.Context()).Info("[auth] no pubkey || err != nil")
 				if err != nil {
-					logger.Log.Error("%v", err)
+					logger.FromContext(r.Context()).Error("%v", err)
 				}
 				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), ContextKey, pubkey)
+			ctx = logger.WithPubKey(ctx, pubkey)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
 	})
@@ -115,7 +117,7 @@ func PubKeyContextSuperAdmin(next http.Handler) http.Handler {
 		}
 
 		if token == "" {
-			logger.Log.Info("[auth] no token")
+			logger.FromContext(r.Context()).Info("[auth] no token")
 			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 			return
 		}
@@ -126,45 +128,47 @@ func PubKeyContextSuperAdmin(next http.Handler) http.Handler {
 
 			if err != nil {
 				fmt.Println("JWT error =================================", err)
-				logger.Log.Info("Failed to parse JWT", token)
+				logger.FromContext(r.Context()).Info("Failed to parse JWT", token)
 				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 				return
 			}
 
 			if claims.VerifyExpiresAt(time.Now().UnixNano(), true) {
-				logger.Log.Info("Token has expired")
+				logger.FromContext(r.Context()).Info("Token has expired")
 				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 				return
 			}
 
 			pubkey := fmt.Sprintf("%v", claims["pubkey"])
 			if !IsFreePass() && !AdminCheck(pubkey) {
-				logger.Log.Info("Not a super admin")
+				logger.FromContext(r.Context()).Info("Not a super admin")
 				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), ContextKey, claims["pubkey"])
+			ctx = logger.WithPubKey(ctx, fmt.Sprintf("%v", claims["pubkey"]))
 			next.ServeHTTP(w, r.WithContext(ctx))
 		} else {
 			pubkey, err := VerifyTribeUUID(token, true)
 
 			if pubkey == "" || err != nil {
-				logger.Log.Info("[auth] no pubkey || err != nil")
+				logger.FromContext(r.Context()).Info("[auth] no pubkey || err != nil")
 				if err != nil {
-					logger.Log.Error("%v", err)
+					logger.FromContext(r.Context()).Error("%v", err)
 				}
 				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 				return
 			}
 
 			if !IsFreePass() && !AdminCheck(pubkey) {
-				logger.Log.Info("Not a super admin : auth")
+				logger.FromContext(r.Context()).Info("Not a super admin : auth")
 				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), ContextKey, pubkey)
+			ctx = logger.WithPubKey(ctx, pubkey)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
 	})
@@ -197,7 +201,7 @@ func CombinedAuthContext(next http.Handler) http.Handler {
 		}
 
 		// No token provided at all.
-		logger.Log.Info("[auth] no token provided")
+		logger.FromContext(r.Context()).Info("[auth] no token provided")
 		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 	})
 }
@@ -370,6 +374,41 @@ func DecodeJwt(token string) (jwt.MapClaims, error) {
 	return claims, err
 }
 
+// PubKeyFromToken returns the pubkey behind either a JWT issued by
+// EncodeJwt or a signed timestamp token, the same credentials accepted
+// by PubKeyContext.
+func PubKeyFromToken(token string) (string, error) {
+	if token == "" {
+		return "", errors.New("no token")
+	}
+
+	isJwt := strings.Contains(token, ".") && !strings.HasPrefix(token, ".")
+	if !isJwt {
+		pubkey, err := VerifyTribeUUID(token, true)
+		if err != nil {
+			return "", err
+		}
+		if pubkey == "" {
+			return "", errors.New("invalid signature")
+		}
+		return pubkey, nil
+	}
+
+	claims, err := DecodeJwt(token)
+	if err != nil {
+		return "", err
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return "", errors.New("token has expired")
+	}
+
+	pubkey, _ := claims["pubkey"].(string)
+	if pubkey == "" {
+		return "", errors.New("token has no pubkey")
+	}
+	return pubkey, nil
+}
+
 func EncodeJwt(pubkey string) (string, error) {
 
 	if pubkey == "" || strings.ContainsAny(pubkey, "!@#$%^&*()") {
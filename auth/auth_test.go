@@ -2135,3 +2135,68 @@ func TestCombinedAuthContext(t *testing.T) {
 		})
 	}
 }
+
+func TestPubKeyFromToken(t *testing.T) {
+	config.InitConfig()
+	InitJwt()
+
+	privKey, err := btcec.NewPrivateKey()
+	assert.NoError(t, err)
+	signerPubKey := hex.EncodeToString(privKey.PubKey().SerializeCompressed())
+
+	signTimestamp := func(timestamp uint32) string {
+		timeBuf := make([]byte, 4)
+		binary.BigEndian.PutUint32(timeBuf, timestamp)
+		sig, err := Sign(timeBuf, privKey)
+		assert.NoError(t, err)
+		return base64.URLEncoding.EncodeToString(append(timeBuf, sig...))
+	}
+
+	validJwt, err := EncodeJwt("jwt-pubkey")
+	assert.NoError(t, err)
+
+	expiredJwt := func() string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"pubkey": "jwt-pubkey",
+			"exp":    time.Now().Add(-time.Hour).Unix(),
+		})
+		tokenString, _ := token.SignedString([]byte(config.JwtKey))
+		return tokenString
+	}()
+
+	noPubkeyJwt := func() string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		tokenString, _ := token.SignedString([]byte(config.JwtKey))
+		return tokenString
+	}()
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedPubkey string
+		expectError    bool
+	}{
+		{name: "Valid JWT", token: validJwt, expectedPubkey: "jwt-pubkey"},
+		{name: "Valid Signed Timestamp", token: signTimestamp(uint32(time.Now().Unix())), expectedPubkey: signerPubKey},
+		{name: "Stale Signed Timestamp", token: signTimestamp(uint32(time.Now().Unix()) - 301), expectError: true},
+		{name: "Expired JWT", token: expiredJwt, expectError: true},
+		{name: "JWT Without Pubkey", token: noPubkeyJwt, expectError: true},
+		{name: "Malformed JWT", token: "header.payload.signature", expectError: true},
+		{name: "Empty Token", token: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pubkey, err := PubKeyFromToken(tt.token)
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Empty(t, pubkey)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedPubkey, pubkey)
+		})
+	}
+}
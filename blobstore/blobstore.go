@@ -0,0 +1,129 @@
+// Package blobstore keeps the files uploaded to hive chat. A BlobStore
+// streams a file to where it is kept and hands back its location, the
+// FileAsset of the file keeps the location, and links to download the file
+// are made from the location when they are asked for. Links expire when the
+// store can make them expire.
+//
+// FILE_STORAGE picks where new files go: "meme" (the default), "s3" or
+// "local". Files stay where they were put when it changes, the store of a
+// file is found from its location.
+package blobstore
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	Meme  = "meme"
+	S3    = "s3"
+	Local = "local"
+)
+
+// sniffLen is as much of a file as content type detection looks at.
+const sniffLen = 512
+
+var (
+	ErrNotFound         = errors.New("blob not found")
+	ErrNotSupported     = errors.New("not supported by the store")
+	ErrInvalidKey       = errors.New("invalid blob key")
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+)
+
+type BlobStore interface {
+	// Put streams body to the store under key and returns the location of
+	// the blob.
+	Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error)
+	// URL returns a link to the blob at location, expiring after expires
+	// when the store can make links expire.
+	URL(ctx context.Context, location string, expires time.Duration) (string, error)
+	// Delete removes the blob at location.
+	Delete(ctx context.Context, location string) error
+	// Owns reports whether location is in the store.
+	Owns(location string) bool
+}
+
+// Stores puts new blobs in Current and finds older ones in whichever store
+// owns their location.
+type Stores struct {
+	Current BlobStore
+	Others  []BlobStore
+}
+
+func (s *Stores) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	return s.Current.Put(ctx, key, body, contentType)
+}
+
+func (s *Stores) URL(ctx context.Context, location string, expires time.Duration) (string, error) {
+	store := s.owner(location)
+	if store == nil {
+		// files uploaded before the stores were kept at public links
+		if isPublicURL(location) {
+			return location, nil
+		}
+		return "", ErrNotFound
+	}
+	return store.URL(ctx, location, expires)
+}
+
+func (s *Stores) Delete(ctx context.Context, location string) error {
+	store := s.owner(location)
+	if store == nil {
+		return ErrNotSupported
+	}
+	return store.Delete(ctx, location)
+}
+
+func (s *Stores) Owns(location string) bool {
+	return s.owner(location) != nil
+}
+
+// ServeHTTP serves the downloads of the store that serves its own links.
+func (s *Stores) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, store := range append([]BlobStore{s.Current}, s.Others...) {
+		if server, ok := store.(http.Handler); ok {
+			server.ServeHTTP(w, r)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func (s *Stores) owner(location string) BlobStore {
+	for _, store := range append([]BlobStore{s.Current}, s.Others...) {
+		if store != nil && store.Owns(location) {
+			return store
+		}
+	}
+	return nil
+}
+
+// Sniff detects the content type of body from its first bytes. The
+// returned reader still reads body from the start.
+func Sniff(body io.Reader) (string, io.Reader, error) {
+	buffered := bufio.NewReaderSize(body, sniffLen)
+	head, err := buffered.Peek(sniffLen)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return "", nil, err
+	}
+	contentType := http.DetectContentType(head)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType, buffered, nil
+}
+
+// validKey keeps keys to a single path element, they name files of the
+// local store.
+func validKey(key string) bool {
+	return key != "" && key != "." && key != ".." && !strings.ContainsAny(key, `/\`)
+}
+
+func isPublicURL(location string) bool {
+	return strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://")
+}
@@ -0,0 +1,71 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"PNG", "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 16), "image/png"},
+		{"PDF", "%PDF-1.7\n", "application/pdf"},
+		{"Text", "just some text", "text/plain"},
+		{"Empty", "", "text/plain"},
+		{"Longer than the sniffed bytes", "GIF89a" + strings.Repeat("x", 2000), "image/gif"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, body, err := Sniff(strings.NewReader(tt.content))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, contentType)
+
+			read, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.content, string(read))
+		})
+	}
+}
+
+func TestStores(t *testing.T) {
+	current := NewLocal(t.TempDir(), "https://example.com/download", []byte("key"))
+	meme := NewMeme(http.DefaultClient, "https://memes.example.com", nil)
+	stores := &Stores{Current: current, Others: []BlobStore{meme}}
+	ctx := context.Background()
+
+	location, err := stores.Put(ctx, "file.txt", strings.NewReader("hello"), "text/plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "local://file.txt", location)
+
+	link, err := stores.URL(ctx, location, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(link, "https://example.com/download?"))
+
+	link, err = stores.URL(ctx, "https://memes.example.com/public/abc", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://memes.example.com/public/abc", link)
+
+	link, err = stores.URL(ctx, "https://elsewhere.example.com/file.png", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://elsewhere.example.com/file.png", link)
+
+	_, err = stores.URL(ctx, "s3://bucket/file.txt", time.Minute)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.ErrorIs(t, stores.Delete(ctx, "https://memes.example.com/public/abc"), ErrNotSupported)
+	assert.NoError(t, stores.Delete(ctx, location))
+
+	rr := httptest.NewRecorder()
+	(&Stores{Current: meme}).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/download", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,139 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const localScheme = "local://"
+
+// LocalStore keeps blobs in a directory and serves them itself, behind
+// links signed with an HMAC of the key and the expiry.
+type LocalStore struct {
+	dir        string
+	baseURL    string
+	signingKey []byte
+	now        func() time.Time
+}
+
+// NewLocal makes a store keeping blobs in dir. Its links point at baseURL,
+// where ServeHTTP has to be routed.
+func NewLocal(dir string, baseURL string, signingKey []byte) *LocalStore {
+	return &LocalStore{dir: dir, baseURL: baseURL, signingKey: signingKey, now: time.Now}
+}
+
+func (l *LocalStore) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return "", err
+	}
+
+	// written aside and moved in place, a failed upload leaves no blob
+	temp, err := os.CreateTemp(l.dir, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := io.Copy(temp, body); err != nil {
+		temp.Close()
+		return "", err
+	}
+	if err := temp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(temp.Name(), filepath.Join(l.dir, key)); err != nil {
+		return "", err
+	}
+	return localScheme + key, nil
+}
+
+func (l *LocalStore) URL(ctx context.Context, location string, expires time.Duration) (string, error) {
+	key, ok := l.key(location)
+	if !ok {
+		return "", ErrNotFound
+	}
+	expiry := strconv.FormatInt(l.now().Add(expires).Unix(), 10)
+	query := url.Values{
+		"key":     {key},
+		"expires": {expiry},
+		"sig":     {l.sign(key, expiry)},
+	}
+	return l.baseURL + "?" + query.Encode(), nil
+}
+
+func (l *LocalStore) Delete(ctx context.Context, location string) error {
+	key, ok := l.key(location)
+	if !ok {
+		return ErrNotFound
+	}
+	err := os.Remove(filepath.Join(l.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (l *LocalStore) Owns(location string) bool {
+	_, ok := l.key(location)
+	return ok
+}
+
+// Verify checks the signature of a link to key, expiring at expiry.
+func (l *LocalStore) Verify(key string, expiry string, signature string) error {
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !validKey(key) {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(l.sign(key, expiry))) || l.now().Unix() > expires {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ServeHTTP serves the blob a signed link points at.
+func (l *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	key := query.Get("key")
+	if err := l.Verify(key, query.Get("expires"), query.Get("sig")); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	file, err := os.Open(filepath.Join(l.dir, key))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, key, info.ModTime(), file)
+}
+
+func (l *LocalStore) sign(key string, expiry string) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	mac.Write([]byte(key + "\n" + expiry))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *LocalStore) key(location string) (string, bool) {
+	key := strings.TrimPrefix(location, localScheme)
+	return key, key != location && validKey(key)
+}
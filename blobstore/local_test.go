@@ -0,0 +1,85 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocal(dir, "https://example.com/hivechat/file/download", []byte("signing key"))
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	location, err := store.Put(ctx, "report.pdf", strings.NewReader("%PDF-1.7"), "application/pdf")
+	assert.NoError(t, err)
+	assert.Equal(t, "local://report.pdf", location)
+	assert.True(t, store.Owns(location))
+
+	content, err := os.ReadFile(filepath.Join(dir, "report.pdf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "%PDF-1.7", string(content))
+
+	t.Run("Serves signed links until they expire", func(t *testing.T) {
+		link, err := store.URL(ctx, location, time.Minute)
+		assert.NoError(t, err)
+		parsed, err := url.Parse(link)
+		assert.NoError(t, err)
+		assert.Equal(t, "1700000060", parsed.Query().Get("expires"))
+
+		rr := httptest.NewRecorder()
+		store.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/download?"+parsed.RawQuery, nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "%PDF-1.7", rr.Body.String())
+
+		tampered := parsed.Query()
+		tampered.Set("expires", "1800000000")
+		rr = httptest.NewRecorder()
+		store.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/download?"+tampered.Encode(), nil))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		now = now.Add(2 * time.Minute)
+		defer func() { now = now.Add(-2 * time.Minute) }()
+		rr = httptest.NewRecorder()
+		store.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/download?"+parsed.RawQuery, nil))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Keeps keys inside its directory", func(t *testing.T) {
+		_, err := store.Put(ctx, "../escape.txt", strings.NewReader("x"), "text/plain")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		assert.False(t, store.Owns("local://../escape.txt"))
+		assert.Error(t, store.Verify("../escape.txt", "1800000000", store.sign("../escape.txt", "1800000000")))
+	})
+
+	t.Run("Leaves nothing behind a failed upload", func(t *testing.T) {
+		_, err := store.Put(ctx, "broken.txt", failingReader{}, "text/plain")
+		assert.Error(t, err)
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("Deletes", func(t *testing.T) {
+		assert.NoError(t, store.Delete(ctx, location))
+		assert.ErrorIs(t, store.Delete(ctx, location), ErrNotFound)
+	})
+}
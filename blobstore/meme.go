@@ -0,0 +1,92 @@
+package blobstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// MemeStore keeps blobs on a meme server. Its links are public and do not
+// expire.
+type MemeStore struct {
+	client  *http.Client
+	baseURL string
+	token   func() (string, error)
+}
+
+// NewMeme makes a store on the meme server at baseURL. token gets a token
+// for an upload.
+func NewMeme(client *http.Client, baseURL string, token func() (string, error)) *MemeStore {
+	return &MemeStore{client: client, baseURL: strings.TrimSuffix(baseURL, "/"), token: token}
+}
+
+func (m *MemeStore) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	token, err := m.token()
+	if err != nil {
+		return "", fmt.Errorf("meme token: %w", err)
+	}
+
+	// the form is written as it is sent, the file is never held whole
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, key))
+		header.Set("Content-Type", contentType)
+		part, err := form.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, body)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+"/public", reader)
+	if err != nil {
+		reader.Close()
+		return "", err
+	}
+	req.Header.Set("Authorization", "BEARER "+token)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		reader.Close()
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("meme server returned %d", res.StatusCode)
+	}
+
+	var uploaded struct {
+		Muid string `json:"muid"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&uploaded); err != nil {
+		return "", err
+	}
+	if uploaded.Muid == "" {
+		return "", fmt.Errorf("meme server returned no muid")
+	}
+	return m.baseURL + "/public/" + uploaded.Muid, nil
+}
+
+func (m *MemeStore) URL(ctx context.Context, location string, expires time.Duration) (string, error) {
+	return location, nil
+}
+
+func (m *MemeStore) Delete(ctx context.Context, location string) error {
+	return ErrNotSupported
+}
+
+func (m *MemeStore) Owns(location string) bool {
+	return strings.HasPrefix(location, m.baseURL+"/public/")
+}
@@ -0,0 +1,66 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemeStore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/public", r.URL.Path)
+		assert.Equal(t, "BEARER meme-token", r.Header.Get("Authorization"))
+
+		file, header, err := r.FormFile("file")
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		assert.Equal(t, "hello", string(content))
+		assert.Equal(t, "note.txt", header.Filename)
+		assert.Equal(t, "text/plain", header.Header.Get("Content-Type"))
+
+		w.Write([]byte(`{"muid":"abc123"}`))
+	}))
+	defer server.Close()
+
+	store := NewMeme(server.Client(), server.URL+"/", func() (string, error) { return "meme-token", nil })
+	ctx := context.Background()
+
+	location, err := store.Put(ctx, "note.txt", strings.NewReader("hello"), "text/plain")
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/public/abc123", location)
+	assert.True(t, store.Owns(location))
+
+	link, err := store.URL(ctx, location, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, location, link)
+	assert.ErrorIs(t, store.Delete(ctx, location), ErrNotSupported)
+
+	t.Run("Fails without a token", func(t *testing.T) {
+		store := NewMeme(server.Client(), server.URL, func() (string, error) { return "", errors.New("no challenge") })
+
+		_, err := store.Put(ctx, "note.txt", strings.NewReader("hello"), "text/plain")
+		assert.Error(t, err)
+	})
+
+	t.Run("Fails when the server refuses the file", func(t *testing.T) {
+		refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer refusing.Close()
+		store := NewMeme(refusing.Client(), refusing.URL, func() (string, error) { return "meme-token", nil })
+
+		_, err := store.Put(ctx, "note.txt", strings.NewReader("hello"), "text/plain")
+		assert.Error(t, err)
+	})
+}
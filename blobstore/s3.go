@@ -0,0 +1,160 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3PartSize is the size of the parts larger blobs are uploaded in, the
+// smallest S3 takes. Only a part is held at a time.
+const s3PartSize = 5 << 20
+
+// S3Store keeps blobs in an S3 compatible bucket. Its links are presigned.
+type S3Store struct {
+	client  *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+	prefix  string
+}
+
+// NewS3 makes a store keeping blobs in bucket, under prefix.
+func NewS3(client *s3.Client, bucket string, prefix string) *S3Store {
+	return &S3Store{
+		client:  client,
+		presign: s3.NewPresignClient(client),
+		bucket:  bucket,
+		prefix:  strings.Trim(prefix, "/"),
+	}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	objectKey := path.Join(s.prefix, key)
+
+	part := make([]byte, s3PartSize)
+	n, err := io.ReadFull(body, part)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(s.bucket),
+			Key:         aws.String(objectKey),
+			Body:        bytes.NewReader(part[:n]),
+			ContentType: aws.String(contentType),
+		})
+		if err != nil {
+			return "", err
+		}
+		return s.location(objectKey), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return s.putParts(ctx, objectKey, part, body, contentType)
+}
+
+// putParts uploads a blob larger than a part, first is its first part.
+func (s *S3Store) putParts(ctx context.Context, objectKey string, first []byte, body io.Reader, contentType string) (string, error) {
+	upload, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(objectKey),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", err
+	}
+	abort := func(err error) (string, error) {
+		s.client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucket),
+			Key:      aws.String(objectKey),
+			UploadId: upload.UploadId,
+		})
+		return "", err
+	}
+
+	parts := []types.CompletedPart{}
+	part, n := first, len(first)
+	for number := int32(1); n > 0; number++ {
+		uploaded, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(s.bucket),
+			Key:        aws.String(objectKey),
+			UploadId:   upload.UploadId,
+			PartNumber: aws.Int32(number),
+			Body:       bytes.NewReader(part[:n]),
+		})
+		if err != nil {
+			return abort(err)
+		}
+		parts = append(parts, types.CompletedPart{ETag: uploaded.ETag, PartNumber: aws.Int32(number)})
+
+		n, err = io.ReadFull(body, part)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return abort(err)
+		}
+	}
+
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(objectKey),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return abort(err)
+	}
+	return s.location(objectKey), nil
+}
+
+func (s *S3Store) URL(ctx context.Context, location string, expires time.Duration) (string, error) {
+	objectKey, ok := s.objectKey(location)
+	if !ok {
+		return "", ErrNotFound
+	}
+	presigned, err := s.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectKey),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return presigned.URL, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, location string) error {
+	objectKey, ok := s.objectKey(location)
+	if !ok {
+		return ErrNotFound
+	}
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectKey),
+	})
+	var missing *types.NoSuchKey
+	if errors.As(err, &missing) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *S3Store) Owns(location string) bool {
+	_, ok := s.objectKey(location)
+	return ok
+}
+
+func (s *S3Store) location(objectKey string) string {
+	return "s3://" + s.bucket + "/" + objectKey
+}
+
+func (s *S3Store) objectKey(location string) (string, bool) {
+	objectKey := strings.TrimPrefix(location, "s3://"+s.bucket+"/")
+	return objectKey, objectKey != location && objectKey != ""
+}
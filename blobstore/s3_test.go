@@ -0,0 +1,104 @@
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 keeps the objects and multipart uploads of a path style bucket.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]string
+	parts   map[string][]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	key := r.URL.Path
+	query := r.URL.Query()
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		f.parts[key] = append(f.parts[key], string(body))
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%s"`, query.Get("partNumber")))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		f.objects[key] = strings.Join(f.parts[key], "")
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Key>%s</Key></CompleteMultipartUploadResult>`, key)
+	case r.Method == http.MethodPut:
+		f.objects[key] = string(body)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string]string{}, parts: map[string][]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("access", "secret", ""),
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+	})
+	store := NewS3(client, "bucket", "/chat/")
+	ctx := context.Background()
+
+	t.Run("Puts a small blob whole", func(t *testing.T) {
+		location, err := store.Put(ctx, "note.txt", strings.NewReader("hello"), "text/plain")
+		assert.NoError(t, err)
+		assert.Equal(t, "s3://bucket/chat/note.txt", location)
+		assert.Equal(t, "hello", fake.objects["/bucket/chat/note.txt"])
+		assert.True(t, store.Owns(location))
+		assert.False(t, store.Owns("s3://other/chat/note.txt"))
+	})
+
+	t.Run("Streams a large blob in parts", func(t *testing.T) {
+		content := strings.Repeat("a", s3PartSize) + strings.Repeat("b", 10)
+
+		location, err := store.Put(ctx, "large.bin", strings.NewReader(content), "application/pdf")
+		assert.NoError(t, err)
+		assert.Equal(t, "s3://bucket/chat/large.bin", location)
+		assert.Len(t, fake.parts["/bucket/chat/large.bin"], 2)
+		assert.Equal(t, content, fake.objects["/bucket/chat/large.bin"])
+	})
+
+	t.Run("Presigns expiring links", func(t *testing.T) {
+		link, err := store.URL(ctx, "s3://bucket/chat/note.txt", 10*time.Minute)
+		assert.NoError(t, err)
+		parsed, err := url.Parse(link)
+		assert.NoError(t, err)
+		assert.Equal(t, "/bucket/chat/note.txt", parsed.Path)
+		assert.Equal(t, "600", parsed.Query().Get("X-Amz-Expires"))
+		assert.NotEmpty(t, parsed.Query().Get("X-Amz-Signature"))
+
+		_, err = store.URL(ctx, "local://note.txt", time.Minute)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Deletes", func(t *testing.T) {
+		assert.NoError(t, store.Delete(ctx, "s3://bucket/chat/note.txt"))
+		assert.NotContains(t, fake.objects, "/bucket/chat/note.txt")
+	})
+}
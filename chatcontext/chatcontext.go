@@ -0,0 +1,188 @@
+// Package chatcontext fits what hive chat hands a workflow into a token
+// budget: the message, the context its tags point at, a rolling summary of
+// the older turns of the chat and as many of the recent turns as fit.
+//
+// Tokens are estimated, not counted: a token is taken to be four
+// characters, which is close enough for English and code to keep a request
+// under a model's limit without pulling in a tokenizer.
+package chatcontext
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultBudget is the budget of a chat that did not ask for one.
+	DefaultBudget = 16000
+
+	// Context tags may take up to a half of the budget and the summary up to
+	// a fifth, the history gets what is left.
+	tagShare     = 2
+	summaryShare = 5
+
+	// summaryLineTokens caps the line a turn gets in the summary.
+	summaryLineTokens = 60
+
+	truncated = "..."
+)
+
+// Tag is the content a context tag of the message points at. Tags are kept
+// in the order they are given, the first ones are cut last.
+type Tag struct {
+	Type    string
+	ID      string
+	Content string
+}
+
+// Turn is a message of the chat. Content is what the workflow gets of it,
+// Message what the summary keeps of it.
+type Turn struct {
+	ID      string
+	Role    string
+	Message string
+	Content string
+}
+
+// Input is what a chat holds for the next message: History has the turns
+// the summary does not cover yet, oldest first.
+type Input struct {
+	Message string
+	Tags    []Tag
+	Summary string
+	History []Turn
+}
+
+// Context is what fits in the budget. Rolled are the turns this build
+// folded into the summary, when there are any the summary changed and
+// covers the chat up to the last of them.
+type Context struct {
+	Tags    []Tag
+	Summary string
+	History []Turn
+	Rolled  []Turn
+	Tokens  int
+}
+
+// EstimateTokens estimates the tokens text takes.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Truncate cuts text down to about tokens tokens, marking the cut.
+func Truncate(text string, tokens int) string {
+	if EstimateTokens(text) <= tokens {
+		return text
+	}
+	keep := tokens*4 - len(truncated)
+	if keep <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	return string(runes[:keep]) + truncated
+}
+
+// Build fits in into budget tokens, DefaultBudget when budget is not above
+// 0. The message always goes whole, even past the budget. Then come the
+// tags, the summary and the history from the newest turn back; the newest
+// turn is cut rather than left out, and the turns older than the ones that
+// fit are rolled into the summary.
+func Build(in Input, budget int) Context {
+	if budget <= 0 {
+		budget = DefaultBudget
+	}
+	result := Context{}
+	used := EstimateTokens(in.Message)
+
+	tagBudget := min(budget/tagShare, budget-used)
+	for _, tag := range in.Tags {
+		tag.Content = Truncate(tag.Content, tagBudget)
+		if tag.Content == "" {
+			continue
+		}
+		tokens := EstimateTokens(tag.Content)
+		tagBudget -= tokens
+		used += tokens
+		result.Tags = append(result.Tags, tag)
+	}
+
+	// the summary only needs its share when turns are rolled into it
+	summaryBudget := budget / summaryShare
+	first, historyTokens := fitHistory(in.History, budget-used-min(summaryBudget, EstimateTokens(in.Summary)))
+	if first > 0 {
+		first, historyTokens = fitHistory(in.History, budget-used-summaryBudget)
+	}
+
+	result.Summary = in.Summary
+	if first > 0 {
+		result.Rolled = in.History[:first]
+		result.Summary = Summarize(in.Summary, result.Rolled, summaryBudget)
+	}
+	result.Summary = trimLines(result.Summary, max(budget-used-historyTokens, 0))
+	used += EstimateTokens(result.Summary)
+
+	for i := first; i < len(in.History); i++ {
+		result.History = append(result.History, in.History[i])
+	}
+	if len(result.History) > 0 {
+		newest := &result.History[len(result.History)-1]
+		newest.Content = Truncate(newest.Content, historyTokens)
+	}
+	result.Tokens = used + historyTokens
+	return result
+}
+
+// fitHistory returns the index of the oldest turn of the newest ones that
+// fit in budget, and the tokens they take. The newest turn fits cut down to
+// the budget unless there is no budget left at all.
+func fitHistory(history []Turn, budget int) (int, int) {
+	if budget <= 0 {
+		return len(history), 0
+	}
+	if len(history) == 0 {
+		return 0, 0
+	}
+
+	last := len(history) - 1
+	used := min(EstimateTokens(history[last].Content), budget)
+	first := last
+	for first > 0 {
+		tokens := EstimateTokens(history[first-1].Content)
+		if used+tokens > budget {
+			break
+		}
+		used += tokens
+		first--
+	}
+	return first, used
+}
+
+// Summarize rolls turns into summary: every turn adds a line with the start
+// of its message, and the oldest lines go once the summary is past budget.
+func Summarize(summary string, turns []Turn, budget int) string {
+	lines := []string{}
+	if summary != "" {
+		lines = strings.Split(summary, "\n")
+	}
+	for _, turn := range turns {
+		message := strings.Join(strings.Fields(turn.Message), " ")
+		if message == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", turn.Role, Truncate(message, summaryLineTokens)))
+	}
+	return trimLines(strings.Join(lines, "\n"), budget)
+}
+
+// trimLines drops the first lines of text until it fits in budget.
+func trimLines(text string, budget int) string {
+	for text != "" && EstimateTokens(text) > budget {
+		cut := strings.Index(text, "\n")
+		if cut < 0 {
+			return Truncate(text, budget)
+		}
+		text = text[cut+1:]
+	}
+	return text
+}
@@ -0,0 +1,140 @@
+package chatcontext
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// turn builds a turn whose content takes tokens tokens.
+func turn(i int, tokens int) Turn {
+	message := fmt.Sprintf("message %d", i)
+	return Turn{
+		ID:      fmt.Sprintf("m%d", i),
+		Role:    "user",
+		Message: message,
+		Content: message + strings.Repeat("x", tokens*4-len(message)),
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTokens(""))
+	assert.Equal(t, 1, EstimateTokens("abc"))
+	assert.Equal(t, 1, EstimateTokens("abcd"))
+	assert.Equal(t, 2, EstimateTokens("abcde"))
+	assert.Equal(t, 1, EstimateTokens("héé"))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("short", 10))
+	assert.Equal(t, "abcde...", Truncate(strings.Repeat("abcde", 10), 2))
+	assert.Equal(t, 2, EstimateTokens(Truncate(strings.Repeat("abcde", 10), 2)))
+	assert.Equal(t, "", Truncate("too long for nothing", 0))
+}
+
+func TestBuild(t *testing.T) {
+	t.Run("Keeps everything that fits", func(t *testing.T) {
+		history := []Turn{turn(1, 10), turn(2, 10)}
+
+		built := Build(Input{
+			Message: "what now",
+			Tags:    []Tag{{Type: "productBrief", Content: "the brief"}},
+			Summary: "user: earlier",
+			History: history,
+		}, 1000)
+
+		assert.Equal(t, history, built.History)
+		assert.Empty(t, built.Rolled)
+		assert.Equal(t, "user: earlier", built.Summary)
+		assert.Equal(t, []Tag{{Type: "productBrief", Content: "the brief"}}, built.Tags)
+		assert.Equal(t, 2+3+4+20, built.Tokens)
+	})
+
+	t.Run("Rolls the turns that do not fit into the summary", func(t *testing.T) {
+		history := []Turn{turn(1, 30), turn(2, 30), turn(3, 30), turn(4, 30)}
+
+		built := Build(Input{Message: "next", Summary: "user: message 0", History: history}, 100)
+
+		assert.Equal(t, history[2:], built.History)
+		assert.Equal(t, history[:2], built.Rolled)
+		assert.Equal(t, "user: message 0\nuser: message 1\nuser: message 2", built.Summary)
+		assert.LessOrEqual(t, built.Tokens, 100)
+	})
+
+	t.Run("Tags come first, in order, and take at most half the budget", func(t *testing.T) {
+		built := Build(Input{
+			Message: "next",
+			Tags: []Tag{
+				{Type: "featureBrief", ID: "f1", Content: strings.Repeat("f", 160)},
+				{Type: "productBrief", Content: strings.Repeat("p", 160)},
+				{Type: "schematic", Content: "left out"},
+			},
+			History: []Turn{turn(1, 10)},
+		}, 100)
+
+		assert.Len(t, built.Tags, 2)
+		assert.Equal(t, strings.Repeat("f", 160), built.Tags[0].Content)
+		assert.Equal(t, 10, EstimateTokens(built.Tags[1].Content))
+		assert.True(t, strings.HasSuffix(built.Tags[1].Content, "..."))
+		assert.Len(t, built.History, 1)
+	})
+
+	t.Run("Cuts the newest turn rather than leave it out", func(t *testing.T) {
+		history := []Turn{turn(1, 10), turn(2, 500)}
+
+		built := Build(Input{Message: "next", History: history}, 100)
+
+		assert.Len(t, built.History, 1)
+		assert.Equal(t, "m2", built.History[0].ID)
+		assert.Equal(t, 79, EstimateTokens(built.History[0].Content))
+		assert.Equal(t, history[:1], built.Rolled)
+		assert.Equal(t, "user: message 1", built.Summary)
+		assert.Equal(t, 1+79+4, built.Tokens)
+	})
+
+	t.Run("Sends the message whole past the budget", func(t *testing.T) {
+		message := strings.Repeat("m", 800)
+
+		built := Build(Input{Message: message, Tags: []Tag{{Content: "brief"}}, History: []Turn{turn(1, 10)}}, 100)
+
+		assert.Empty(t, built.Tags)
+		assert.Empty(t, built.History)
+		assert.Len(t, built.Rolled, 1)
+		assert.Equal(t, "", built.Summary)
+		assert.Equal(t, 200, built.Tokens)
+	})
+
+	t.Run("Uses the default budget when none is set", func(t *testing.T) {
+		history := []Turn{turn(1, DefaultBudget/2), turn(2, DefaultBudget/2)}
+
+		built := Build(Input{Message: "next", History: history}, 0)
+
+		assert.Equal(t, history[1:], built.History)
+	})
+
+	t.Run("Is deterministic", func(t *testing.T) {
+		in := Input{Message: "next", Summary: "user: a\nuser: b", History: []Turn{turn(1, 40), turn(2, 40), turn(3, 40)}}
+
+		assert.Equal(t, Build(in, 100), Build(in, 100))
+	})
+}
+
+func TestSummarize(t *testing.T) {
+	turns := []Turn{
+		{Role: "user", Message: "  add a\n leaderboard  "},
+		{Role: "assistant", Message: ""},
+		{Role: "assistant", Message: strings.Repeat("long ", 100)},
+	}
+
+	summary := Summarize("user: hi", turns, 1000)
+
+	lines := strings.Split(summary, "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "user: hi", lines[0])
+	assert.Equal(t, "user: add a leaderboard", lines[1])
+	assert.Equal(t, summaryLineTokens, EstimateTokens(strings.TrimPrefix(lines[2], "assistant: ")))
+
+	assert.Equal(t, "user: add a leaderboard", Summarize("user: hi", turns[:1], 7))
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,6 +31,10 @@ var RelayAuthKey string
 var RelayNodeKey string
 var SuperAdmins []string = []string{""}
 var LogLevel string
+var LogFormat string
+var OtelExporterEndpoint string
+var OtelServiceName string
+var MetricsToken string
 
 // these are constants for the store
 var InvoiceList = "INVOICELIST"
@@ -48,6 +55,13 @@ var V2BotToken string
 var IsV2Payment bool = false
 var FfWebsocket bool = false
 var SWAuth string
+var WebsocketBroadcaster string
+var WebsocketBroadcastChannel string
+var WorkflowProvider string
+var SecretsMasterKeys string
+var FileStorage string
+var FileStorageDir string
+var FileSigningKey string
 
 func InitConfig() {
 	Host = os.Getenv("LN_SERVER_BASE_URL")
@@ -70,7 +84,18 @@ func InitConfig() {
 	V2BotToken = os.Getenv("V2_BOT_TOKEN")
 	FfWebsocket = os.Getenv("FF_WEBSOCKET") == "true"
 	LogLevel = strings.ToUpper(os.Getenv("LOG_LEVEL"))
+	LogFormat = strings.ToLower(os.Getenv("LOG_FORMAT"))
+	OtelExporterEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	OtelServiceName = os.Getenv("OTEL_SERVICE_NAME")
+	MetricsToken = os.Getenv("METRICS_TOKEN")
 	SWAuth = os.Getenv("SWAUTH")
+	WebsocketBroadcaster = strings.ToLower(os.Getenv("WEBSOCKET_BROADCASTER"))
+	WebsocketBroadcastChannel = os.Getenv("WEBSOCKET_BROADCAST_CHANNEL")
+	WorkflowProvider = strings.ToLower(os.Getenv("WORKFLOW_PROVIDER"))
+	SecretsMasterKeys = os.Getenv("SECRETS_MASTER_KEYS")
+	FileStorage = strings.ToLower(os.Getenv("FILE_STORAGE"))
+	FileStorageDir = os.Getenv("FILE_STORAGE_DIR")
+	FileSigningKey = os.Getenv("FILE_SIGNING_KEY")
 
 	// Add to super admins
 	SuperAdmins = StripSuperAdmins(AdminStrings)
@@ -119,11 +144,24 @@ func InitConfig() {
 		MemeUrl = "https://memes.sphinx.chat"
 	}
 
+	if FileStorageDir == "" {
+		FileStorageDir = "./uploads/files"
+	}
+
 	if JwtKey == "" {
 		fmt.Println("JwtKey is empty , no Jwt found =================================")
 		JwtKey = GenerateRandomString()
 	}
 
+	// links to local files are signed apart from the jwts, and only outlive
+	// a restart when the key they are signed with is set
+	if FileSigningKey == "" {
+		if os.Getenv("LN_JWT_KEY") == "" && FileStorage == "local" {
+			fmt.Println("FILE_SIGNING_KEY and LN_JWT_KEY are empty, links to local files end on restart")
+		}
+		FileSigningKey = DeriveKey(JwtKey, "file-signing")
+	}
+
 	if S3BucketName == "" {
 		S3BucketName = "sphinx-tribes"
 	}
@@ -147,6 +185,18 @@ func InitConfig() {
 	if LogLevel == "" {
 		LogLevel = "DEBUG"
 	}
+
+	if LogFormat == "" {
+		LogFormat = "text"
+	}
+
+	if OtelServiceName == "" {
+		OtelServiceName = "sphinx-tribes"
+	}
+
+	if WebsocketBroadcaster == "" {
+		WebsocketBroadcaster = "memory"
+	}
 }
 
 func StripSuperAdmins(adminStrings string) []string {
@@ -171,6 +221,14 @@ func StripSuperAdmins(adminStrings string) []string {
 	return superAdmins
 }
 
+// DeriveKey derives the key used for purpose from key, so that one secret
+// can key several things without a key for one signing for the others.
+func DeriveKey(key string, purpose string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(purpose))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func GenerateRandomString() string {
 	const charset = "abcdefghijklmnopqrstuvwxyz" +
 		"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
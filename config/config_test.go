@@ -25,6 +25,19 @@ func TestInitConfig(t *testing.T) {
 	}
 }
 
+func TestFileSigningKey(t *testing.T) {
+	t.Setenv("LN_JWT_KEY", "jwt-secret")
+	t.Setenv("FILE_SIGNING_KEY", "")
+	InitConfig()
+	assert.NotEmpty(t, FileSigningKey)
+	assert.NotEqual(t, JwtKey, FileSigningKey)
+	assert.Equal(t, DeriveKey("jwt-secret", "file-signing"), FileSigningKey)
+
+	t.Setenv("FILE_SIGNING_KEY", "file-secret")
+	InitConfig()
+	assert.Equal(t, "file-secret", FileSigningKey)
+}
+
 func TestGenerateRandomString(t *testing.T) {
 
 	testRandString := GenerateRandomString()
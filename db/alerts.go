@@ -9,8 +9,6 @@ import (
 	"net/http"
 	"os"
 	"strconv"
-
-	"github.com/stakwork/sphinx-tribes/logger"
 )
 
 type Action struct {
@@ -37,7 +35,7 @@ func (db database) ProcessAlerts(p Person) {
 	alertTribeUuid := os.Getenv("ALERT_TRIBE_UUID")
 	botId := os.Getenv("ALERT_BOT_ID")
 	if relayUrl == "" || alertSecret == "" || alertTribeUuid == "" || botId == "" {
-		logger.Log.Info("Ticket alerts: ENV information not found")
+		db.log().Info("Ticket alerts: ENV information not found")
 		return
 	}
 
@@ -51,14 +49,14 @@ func (db database) ProcessAlerts(p Person) {
 
 	// Check that new ticket time exists
 	if p.NewTicketTime == 0 {
-		logger.Log.Info("Ticket alerts: New ticket time not found")
+		db.log().Info("Ticket alerts: New ticket time not found")
 		return
 	}
 
 	var issue PropertyMap = nil
 	wanteds, ok := p.Extras["wanted"].([]interface{})
 	if !ok {
-		logger.Log.Info("Ticket alerts: No tickets found for person")
+		db.log().Info("Ticket alerts: No tickets found for person")
 	}
 	for _, wanted := range wanteds {
 		w, ok2 := wanted.(map[string]interface{})
@@ -77,19 +75,19 @@ func (db database) ProcessAlerts(p Person) {
 	}
 
 	if issue == nil {
-		logger.Log.Info("Ticket alerts: No ticket identified with the correct timestamp")
+		db.log().Info("Ticket alerts: No ticket identified with the correct timestamp")
 	}
 
 	languages, ok4 := issue["codingLanguage"].([]interface{})
 	if !ok4 {
-		logger.Log.Info("Ticket alerts: No languages found in ticket")
+		db.log().Info("Ticket alerts: No languages found in ticket")
 		return
 	}
 
 	var err error
 	people, err := db.GetPeopleForNewTicket(languages)
 	if err != nil {
-		logger.Log.Error("Ticket alerts: DB query to get interested people failed: %v", err)
+		db.log().Error("Ticket alerts: DB query to get interested people failed: %v", err)
 		return
 	}
 
@@ -99,12 +97,12 @@ func (db database) ProcessAlerts(p Person) {
 		action.Pubkey = per.OwnerPubKey
 		buf, err := json.Marshal(action)
 		if err != nil {
-			logger.Log.Error("Ticket alerts: Unable to parse message into byte buffer: %v", err)
+			db.log().Error("Ticket alerts: Unable to parse message into byte buffer: %v", err)
 			return
 		}
 		request, err := http.NewRequest("POST", relayUrl, bytes.NewReader(buf))
 		if err != nil {
-			logger.Log.Error("Ticket alerts: Unable to create a request to send to relay: %v", err)
+			db.log().Error("Ticket alerts: Unable to create a request to send to relay: %v", err)
 			return
 		}
 
@@ -116,7 +114,7 @@ func (db database) ProcessAlerts(p Person) {
 		request.Header.Set("Content-Type", "application/json")
 		_, err = client.Do(request)
 		if err != nil {
-			logger.Log.Error("Ticket alerts: Unable to communicate request to relay: %v", err)
+			db.log().Error("Ticket alerts: Unable to communicate request to relay: %v", err)
 		}
 	}
 
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrAssetTransferUsed = errors.New("asset transfer already funded a budget")
+
+// GetWorkspaceAssetBudgets lists the asset budgets of a workspace, the sats
+// budget is not among them. Balances come from the ledger.
+func (db database) GetWorkspaceAssetBudgets(workspace_uuid string) []WorkspaceAssetBudget {
+	budgets := []WorkspaceAssetBudget{}
+	db.db.Where("workspace_uuid = ?", workspace_uuid).Order("asset_id").Find(&budgets)
+	for i := range budgets {
+		budgets[i].Balance = db.ledgerBudget(workspace_uuid, budgets[i].AssetId, budgets[i].Balance)
+	}
+	return budgets
+}
+
+// GetWorkspaceAssetBudget returns the budget a workspace holds in an asset,
+// an empty budget when it never held any. The balance comes from the ledger.
+func (db database) GetWorkspaceAssetBudget(workspace_uuid string, assetId uint) WorkspaceAssetBudget {
+	budget := WorkspaceAssetBudget{WorkspaceUuid: workspace_uuid, AssetId: assetId}
+	db.db.Where("workspace_uuid = ? AND asset_id = ?", workspace_uuid, assetId).Find(&budget)
+	budget.Balance = db.ledgerBudget(workspace_uuid, assetId, budget.Balance)
+	return budget
+}
+
+// DepositAssetBudget records an asset transfer to the tribes node as a
+// deposit and adds it to the workspace's budget in that asset. The
+// payment's Tag is the transfer's transaction id; depositing the same
+// transfer twice fails with ErrAssetTransferUsed.
+func (db database) DepositAssetBudget(payment NewPaymentHistory) (WorkspaceAssetBudget, error) {
+	if payment.WorkspaceUuid == "" || payment.AssetId == 0 || payment.Tag == "" {
+		return WorkspaceAssetBudget{}, errors.New("asset deposit needs a workspace, an asset and a transaction id")
+	}
+
+	now := time.Now()
+	payment.PaymentType = Deposit
+	payment.PaymentStatus = PaymentComplete
+	payment.Status = true
+	payment.Created = &now
+	payment.Updated = &now
+
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		if err := updateAssetBudget(tx, payment.WorkspaceUuid, payment.AssetId, int64(payment.Amount)); err != nil {
+			return err
+		}
+		return postLedgerAssetDeposit(tx, payment)
+	})
+	if errors.Is(err, ErrLedgerDuplicate) {
+		return WorkspaceAssetBudget{}, ErrAssetTransferUsed
+	}
+	if err != nil {
+		return WorkspaceAssetBudget{}, fmt.Errorf("failed to deposit asset budget: %w", err)
+	}
+	return db.GetWorkspaceAssetBudget(payment.WorkspaceUuid, payment.AssetId), nil
+}
+
+// updateAssetBudget adds amount, negative to spend, to an asset budget in
+// place. Deposits open the budget on first use; spending more than the
+// balance, or from a budget the workspace never held, fails with
+// ErrInsufficientBudget.
+func updateAssetBudget(tx *gorm.DB, workspace_uuid string, assetId uint, amount int64) error {
+	now := time.Now()
+	if amount < 0 {
+		updated := tx.Model(&WorkspaceAssetBudget{}).
+			Where("workspace_uuid = ? AND asset_id = ? AND balance >= ?", workspace_uuid, assetId, -amount).
+			Updates(map[string]interface{}{
+				"balance": gorm.Expr("balance - ?", -amount),
+				"updated": now,
+			})
+		if updated.Error == nil && updated.RowsAffected == 0 {
+			return ErrInsufficientBudget
+		}
+		return updated.Error
+	}
+
+	budget := WorkspaceAssetBudget{
+		WorkspaceUuid: workspace_uuid,
+		AssetId:       assetId,
+		Balance:       uint(amount),
+		Created:       now,
+		Updated:       now,
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "workspace_uuid"}, {Name: "asset_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"balance": gorm.Expr("workspace_asset_budgets.balance + ?", amount),
+			"updated": now,
+		}),
+	}).Create(&budget).Error
+}
+
+// reverseAssetPayment returns an asset bounty payment to the workspace's
+// budget in that asset and records the reversal.
+func reverseAssetPayment(tx *gorm.DB, payment NewPaymentHistory) error {
+	wasPending := payment.PaymentStatus == PaymentPending
+	payment.PaymentStatus = PaymentFailed
+	if err := tx.Model(&NewPaymentHistory{}).Where("id = ?", payment.ID).Update("payment_status", payment.PaymentStatus).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	reversal := NewPaymentHistory{
+		Amount:         payment.Amount,
+		AssetId:        payment.AssetId,
+		SenderPubKey:   payment.SenderPubKey,
+		ReceiverPubKey: payment.ReceiverPubKey,
+		WorkspaceUuid:  payment.WorkspaceUuid,
+		BountyId:       payment.BountyId,
+		Tag:            payment.Tag,
+		PaymentType:    Reversal,
+		Created:        &now,
+		Updated:        &now,
+		Error:          "Payment has been reversed",
+		Status:         true,
+	}
+	if err := tx.Create(&reversal).Error; err != nil {
+		return err
+	}
+
+	if err := updateAssetBudget(tx, payment.WorkspaceUuid, payment.AssetId, int64(payment.Amount)); err != nil {
+		return err
+	}
+	return postLedgerReversal(tx, payment, wasPending)
+}
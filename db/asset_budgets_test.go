@@ -0,0 +1,75 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspaceAssetBudgets(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	workspaceUuid := uuid.New().String()
+	now := time.Now()
+	TestDB.CreateWorkspaceBudget(NewBountyBudget{WorkspaceUuid: workspaceUuid, TotalBudget: 0, Created: &now, Updated: &now})
+
+	t.Run("Deposits fund the budget of their asset once", func(t *testing.T) {
+		budget, err := TestDB.DepositAssetBudget(NewPaymentHistory{WorkspaceUuid: workspaceUuid, AssetId: 7, Amount: 100, SenderPubKey: "owner", Tag: "tx-1"})
+		assert.NoError(t, err)
+		assert.Equal(t, uint(100), budget.Balance)
+
+		budget, err = TestDB.DepositAssetBudget(NewPaymentHistory{WorkspaceUuid: workspaceUuid, AssetId: 7, Amount: 50, SenderPubKey: "owner", Tag: "tx-2"})
+		assert.NoError(t, err)
+		assert.Equal(t, uint(150), budget.Balance)
+
+		_, err = TestDB.DepositAssetBudget(NewPaymentHistory{WorkspaceUuid: workspaceUuid, AssetId: 7, Amount: 100, SenderPubKey: "owner", Tag: "tx-1"})
+		assert.ErrorIs(t, err, ErrAssetTransferUsed)
+		assert.Equal(t, uint(150), TestDB.GetWorkspaceAssetBudget(workspaceUuid, 7).Balance)
+
+		assert.Equal(t, uint(0), TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget, "the sats budget is untouched")
+		assert.Equal(t, uint(0), TestDB.GetSumOfDeposits(workspaceUuid))
+	})
+
+	bounty := NewBounty{Type: "coding", Title: "asset", Price: 40, AssetId: 7, WorkspaceUuid: workspaceUuid, Assignee: "hunter", OwnerID: "owner", Created: now.UnixNano()}
+	TestDB.db.Create(&bounty)
+
+	t.Run("Payments and reversals move the asset budget", func(t *testing.T) {
+		bounty.PaymentPending = true
+		payment := NewPaymentHistory{Amount: 40, AssetId: 7, BountyId: bounty.ID, WorkspaceUuid: workspaceUuid, SenderPubKey: "owner",
+			ReceiverPubKey: "hunter", PaymentType: Payment, PaymentStatus: PaymentPending, Status: true, Tag: "asset-tag", Created: &now}
+		assert.NoError(t, TestDB.ProcessBountyPayment(payment, bounty))
+		assert.Equal(t, uint(110), TestDB.GetWorkspaceAssetBudget(workspaceUuid, 7).Balance)
+
+		paid := TestDB.GetPaymentByBountyId(bounty.ID)
+		assert.NoError(t, TestDB.ProcessReversePayments(paid.ID))
+		assert.Equal(t, uint(150), TestDB.GetWorkspaceAssetBudget(workspaceUuid, 7).Balance)
+		assert.Equal(t, uint(0), TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget)
+	})
+
+	t.Run("Spending an asset the workspace never held fails", func(t *testing.T) {
+		payment := NewPaymentHistory{Amount: 1, AssetId: 8, BountyId: bounty.ID, WorkspaceUuid: workspaceUuid, PaymentType: Payment,
+			PaymentStatus: PaymentComplete, Status: true, Created: &now}
+		assert.Error(t, TestDB.ProcessBountyPayment(payment, bounty))
+	})
+
+	t.Run("Spending more than the balance fails", func(t *testing.T) {
+		payment := NewPaymentHistory{Amount: 151, AssetId: 7, BountyId: bounty.ID, WorkspaceUuid: workspaceUuid, PaymentType: Payment,
+			PaymentStatus: PaymentComplete, Status: true, Created: &now}
+		assert.ErrorIs(t, TestDB.ProcessBountyPayment(payment, bounty), ErrInsufficientBudget)
+		assert.Equal(t, uint(150), TestDB.GetWorkspaceAssetBudget(workspaceUuid, 7).Balance)
+	})
+
+	t.Run("The ledger reconciles every asset", func(t *testing.T) {
+		report, err := TestDB.ReconcileLedger(workspaceUuid)
+		assert.NoError(t, err)
+		assert.True(t, report.InSync)
+		assert.Len(t, report.Assets, 1)
+		assert.Equal(t, uint(7), report.Assets[0].AssetId)
+		assert.Equal(t, int64(150), report.Assets[0].LedgerBudget)
+		assert.Equal(t, int64(0), report.Assets[0].Escrow)
+		assert.Equal(t, int64(0), report.LedgerBudget)
+	})
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+// migrateAuditLog installs a trigger rejecting updates and deletes so the
+// audit trail stays append-only even for callers bypassing this package.
+func (db database) migrateAuditLog() {
+	err := db.db.Exec(`
+		CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_logs is append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+		CREATE TRIGGER audit_logs_append_only
+			BEFORE UPDATE OR DELETE ON audit_logs
+			FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+	`).Error
+	if err != nil {
+		logger.Log.Error("[db] could not install audit log trigger: %v", err)
+	}
+}
+
+func (db database) CreateAuditLog(entry *AuditLog) error {
+	if entry.EntityType == "" || entry.EntityID == "" {
+		return errors.New("entity type and id are required")
+	}
+	if entry.Action == "" {
+		return errors.New("action is required")
+	}
+	if entry.Changes == nil {
+		entry.Changes = PropertyMap{}
+	}
+	if entry.Created.IsZero() {
+		entry.Created = time.Now()
+	}
+
+	if err := db.db.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+	return nil
+}
+
+// GetAuditLogs returns the entries of a workspace matching filter, newest
+// first, together with the number of matching entries.
+func (db database) GetAuditLogs(filter AuditLogFilter) ([]AuditLog, int64, error) {
+	entries := []AuditLog{}
+	if filter.WorkspaceUuid == "" {
+		return entries, 0, errors.New("workspace uuid is required")
+	}
+
+	query := db.db.Model(&AuditLog{}).Where("workspace_uuid = ?", filter.WorkspaceUuid)
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return entries, 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	err := query.Order("created DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&entries).Error
+	if err != nil {
+		return []AuditLog{}, 0, fmt.Errorf("failed to fetch audit logs: %w", err)
+	}
+
+	return entries, total, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLogs(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	workspaceUuid := uuid.New().String()
+	now := time.Now()
+
+	entries := []AuditLog{
+		{WorkspaceUuid: workspaceUuid, Actor: "alice", EntityType: "feature", EntityID: "f1", Action: "create", Created: now.Add(-3 * time.Minute)},
+		{WorkspaceUuid: workspaceUuid, Actor: "bob", EntityType: "feature", EntityID: "f1", Action: "update",
+			Changes: PropertyMap{"brief": map[string]interface{}{"before": "old", "after": "new"}}, Created: now.Add(-2 * time.Minute)},
+		{WorkspaceUuid: workspaceUuid, Actor: "alice", EntityType: "budget", EntityID: workspaceUuid, Action: "withdraw", Created: now.Add(-time.Minute)},
+		{WorkspaceUuid: uuid.New().String(), Actor: "alice", EntityType: "feature", EntityID: "f2", Action: "create", Created: now},
+	}
+	for i := range entries {
+		assert.NoError(t, TestDB.CreateAuditLog(&entries[i]))
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		assert.Error(t, TestDB.CreateAuditLog(&AuditLog{WorkspaceUuid: workspaceUuid, Action: "create"}))
+		assert.Error(t, TestDB.CreateAuditLog(&AuditLog{WorkspaceUuid: workspaceUuid, EntityType: "feature", EntityID: "f1"}))
+
+		_, _, err := TestDB.GetAuditLogs(AuditLogFilter{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Newest First Within Workspace", func(t *testing.T) {
+		logs, total, err := TestDB.GetAuditLogs(AuditLogFilter{WorkspaceUuid: workspaceUuid, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+		assert.Len(t, logs, 3)
+		assert.Equal(t, "withdraw", logs[0].Action)
+		assert.Equal(t, "create", logs[2].Action)
+	})
+
+	t.Run("Filter By Entity", func(t *testing.T) {
+		logs, total, err := TestDB.GetAuditLogs(AuditLogFilter{WorkspaceUuid: workspaceUuid, EntityType: "feature", EntityID: "f1", Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Equal(t, "bob", logs[0].Actor)
+		assert.Contains(t, logs[0].Changes, "brief")
+	})
+
+	t.Run("Filter By Actor And Paginate", func(t *testing.T) {
+		logs, total, err := TestDB.GetAuditLogs(AuditLogFilter{WorkspaceUuid: workspaceUuid, Actor: "alice", Limit: 1, Offset: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Len(t, logs, 1)
+		assert.Equal(t, "create", logs[0].Action)
+	})
+
+	t.Run("Entries Are Append Only", func(t *testing.T) {
+		assert.Error(t, TestDB.db.Model(&AuditLog{}).Where("id = ?", entries[0].ID).Update("actor", "mallory").Error)
+		assert.Error(t, TestDB.db.Delete(&AuditLog{}, entries[0].ID).Error)
+	})
+}
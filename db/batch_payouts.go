@@ -0,0 +1,344 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInsufficientBudget = errors.New("workspace budget is not enough to pay the amount")
+	ErrBountyNotPayable   = errors.New("bounty already paid or payment in progress")
+	ErrBatchItemDone      = errors.New("batch payout item already processed")
+)
+
+// heldBatchItemStatuses are the statuses of items whose keysend may have
+// gone out without its outcome being recorded.
+var heldBatchItemStatuses = []string{BatchItemSending, BatchItemNeedsReview}
+
+// ReserveBatchPayout takes the sum of the batch's items from the workspace
+// budget and claims their bounties, all or nothing: it fails with
+// ErrInsufficientBudget when the budget does not cover the batch and with
+// ErrBountyNotPayable when one of the bounties was paid or is being paid
+// meanwhile. Claimed bounties are marked payment pending, so a single
+// payment of the same bounty is refused until the batch pays or releases
+// it.
+func (db database) ReserveBatchPayout(batch *BatchPayout) error {
+	if batch.WorkspaceUuid == "" || len(batch.Items) == 0 {
+		return errors.New("batch payout needs a workspace and bounties")
+	}
+
+	bountyIDs := make([]uint, 0, len(batch.Items))
+	batch.Reserved = 0
+	for i := range batch.Items {
+		batch.Items[i].Status = BatchItemReserved
+		batch.Reserved += batch.Items[i].Amount
+		bountyIDs = append(bountyIDs, batch.Items[i].BountyID)
+	}
+
+	now := time.Now()
+	batch.Uuid = uuid.New().String()
+	batch.Status = BatchPayoutReserved
+	batch.Spent = 0
+	batch.Released = 0
+	batch.Created = now
+	batch.Updated = now
+
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		budget := NewBountyBudget{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("workspace_uuid = ?", batch.WorkspaceUuid).
+			First(&budget).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && budget.TotalBudget < batch.Reserved) {
+			return ErrInsufficientBudget
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&NewBountyBudget{}).
+			Where("workspace_uuid = ?", batch.WorkspaceUuid).
+			Update("total_budget", gorm.Expr("total_budget - ?", batch.Reserved)).Error; err != nil {
+			return err
+		}
+
+		claimed := tx.Model(&NewBounty{}).
+			Where("id IN ? AND paid = ? AND payment_pending = ?", bountyIDs, false, false).
+			Updates(map[string]interface{}{"payment_pending": true, "payment_failed": false})
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected != int64(len(bountyIDs)) {
+			return ErrBountyNotPayable
+		}
+
+		if err := tx.Create(batch).Error; err != nil {
+			return fmt.Errorf("failed to create batch payout: %w", err)
+		}
+		return postLedgerBatchReserve(tx, *batch)
+	})
+}
+
+// StartBatchPayment marks an item of a reserved batch as being sent, right
+// before its keysend. From then on finishing the batch leaves the item's
+// amount and bounty alone. It fails with ErrBatchItemDone when the batch
+// finished or the item was sent already.
+func (db database) StartBatchPayment(batchID uint, itemID uint) error {
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		batch := BatchPayout{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&batch, batchID).Error; err != nil {
+			return fmt.Errorf("failed to load batch payout: %w", err)
+		}
+		if batch.Status != BatchPayoutReserved {
+			return ErrBatchItemDone
+		}
+
+		updated := tx.Model(&BatchPayoutItem{}).
+			Where("id = ? AND batch_id = ? AND status = ?", itemID, batchID, BatchItemReserved).
+			Update("status", BatchItemSending)
+		if updated.Error != nil {
+			return updated.Error
+		}
+		if updated.RowsAffected == 0 {
+			return ErrBatchItemDone
+		}
+		return nil
+	})
+}
+
+// GetBatchPayout returns a batch payout with its items.
+func (db database) GetBatchPayout(uuid string) (BatchPayout, error) {
+	batch := BatchPayout{}
+	err := db.db.Preload("Items", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		Where("uuid = ?", uuid).First(&batch).Error
+	return batch, err
+}
+
+// HoldBatchPayment flags an item whose keysend may have gone out but whose
+// outcome could not be recorded, keeping the keysend's tag when the node
+// returned one. Its amount stays in the batch and its bounty stays
+// pending, so neither is paid twice.
+func (db database) HoldBatchPayment(batchID uint, itemID uint, tag string, reason string) error {
+	return db.db.Model(&BatchPayoutItem{}).
+		Where("id = ? AND batch_id = ? AND status = ?", itemID, batchID, BatchItemSending).
+		Updates(map[string]interface{}{
+			"status": BatchItemNeedsReview,
+			"tag":    tag,
+			"error":  reason,
+		}).Error
+}
+
+// ReleaseBatchPayment gives up on a held item of a finished batch whose
+// keysend never went out: its amount returns to the budget and its bounty
+// is no longer pending. It fails with ErrBatchItemDone when the batch has
+// not finished or the item is not held.
+func (db database) ReleaseBatchPayment(batchID uint, itemID uint, reason string) error {
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		batch := BatchPayout{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&batch, batchID).Error; err != nil {
+			return fmt.Errorf("failed to load batch payout: %w", err)
+		}
+		if batch.Status != BatchPayoutFinished {
+			return ErrBatchItemDone
+		}
+
+		item := BatchPayoutItem{}
+		err := tx.Where("id = ? AND batch_id = ? AND status IN ?", itemID, batchID, heldBatchItemStatuses).First(&item).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrBatchItemDone
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&BatchPayoutItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+			"status": BatchItemReleased,
+			"error":  reason,
+		}).Error; err != nil {
+			return err
+		}
+		if err := releaseBatchItem(tx, batch, item); err != nil {
+			return err
+		}
+		return tx.Model(&NewBounty{}).Where("id = ? AND paid = ?", item.BountyID, false).
+			Update("payment_pending", false).Error
+	})
+}
+
+// ProcessBatchPayment records the payment of one item of a batch, once
+// StartBatchPayment marked it as being sent, or of an item held for
+// review once the node told its outcome. A failed payment frees its
+// bounty and leaves its amount in the reservation for FinishBatchPayout
+// to release, or releases it right away when the batch already finished;
+// any other payment is drawn from the reservation. It fails with
+// ErrBatchItemDone when the item was not being sent or held.
+func (db database) ProcessBatchPayment(batchID uint, item BatchPayoutItem, payment NewPaymentHistory, bounty NewBounty) (NewPaymentHistory, error) {
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		batch := BatchPayout{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&batch, batchID).Error; err != nil {
+			return fmt.Errorf("failed to load batch payout: %w", err)
+		}
+
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+
+		itemUpdates := map[string]interface{}{
+			"payment_id": payment.ID,
+			"tag":        payment.Tag,
+			"status":     payment.PaymentStatus,
+			"error":      payment.Error,
+		}
+		updated := tx.Model(&BatchPayoutItem{}).
+			Where("id = ? AND batch_id = ? AND status IN ?", item.ID, batchID, heldBatchItemStatuses).
+			Updates(itemUpdates)
+		if updated.Error != nil {
+			return updated.Error
+		}
+		if updated.RowsAffected == 0 {
+			return ErrBatchItemDone
+		}
+
+		if payment.PaymentStatus == PaymentFailed {
+			if batch.Status == BatchPayoutFinished {
+				if err := releaseBatchItem(tx, batch, item); err != nil {
+					return err
+				}
+			}
+			return tx.Model(&NewBounty{}).Where("id = ?", bounty.ID).Updates(map[string]interface{}{
+				"paid":            false,
+				"payment_pending": false,
+				"payment_failed":  true,
+			}).Error
+		}
+
+		if err := postLedgerBatchPayment(tx, batch, payment); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&BatchPayout{}).Where("id = ?", batchID).Updates(map[string]interface{}{
+			"spent":   gorm.Expr("spent + ?", payment.Amount),
+			"updated": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&NewBounty{}).Where("id = ?", bounty.ID).Updates(map[string]interface{}{
+			"paid":            bounty.Paid,
+			"payment_pending": bounty.PaymentPending,
+			"payment_failed":  bounty.PaymentFailed,
+			"completed":       bounty.Completed,
+			"paid_date":       bounty.PaidDate,
+			"completion_date": bounty.CompletionDate,
+		}).Error
+	})
+	if err != nil {
+		return NewPaymentHistory{}, err
+	}
+	return payment, nil
+}
+
+// releaseBatchItem returns the amount of a failed item of a finished batch
+// to the budget, which the batch held back when it finished.
+func releaseBatchItem(tx *gorm.DB, batch BatchPayout, item BatchPayoutItem) error {
+	if err := tx.Model(&NewBountyBudget{}).
+		Where("workspace_uuid = ?", batch.WorkspaceUuid).
+		Update("total_budget", gorm.Expr("total_budget + ?", item.Amount)).Error; err != nil {
+		return err
+	}
+	if err := postLedgerBatchItemRelease(tx, batch, item); err != nil {
+		return err
+	}
+	return tx.Model(&BatchPayout{}).Where("id = ?", batch.ID).Updates(map[string]interface{}{
+		"released": gorm.Expr("released + ?", item.Amount),
+		"updated":  time.Now(),
+	}).Error
+}
+
+// FinishBatchPayout releases what the batch left unspent back to the
+// budget and frees the bounties it never sent a payment for. Items whose
+// keysend is going out, or went out without being recorded, are held for
+// review: their amount stays reserved and their bounty pending until they
+// are resolved or released. Finishing a finished batch returns it
+// unchanged, so a crashed payout can be finished later.
+func (db database) FinishBatchPayout(batchID uint) (BatchPayout, error) {
+	batch := BatchPayout{}
+
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&batch, batchID).Error; err != nil {
+			return fmt.Errorf("failed to load batch payout: %w", err)
+		}
+		if batch.Status == BatchPayoutFinished {
+			return nil
+		}
+
+		unpaid := []uint{}
+		if err := tx.Model(&BatchPayoutItem{}).
+			Where("batch_id = ? AND status = ?", batchID, BatchItemReserved).
+			Pluck("bounty_id", &unpaid).Error; err != nil {
+			return err
+		}
+		if len(unpaid) > 0 {
+			if err := tx.Model(&NewBounty{}).
+				Where("id IN ? AND paid = ?", unpaid, false).
+				Update("payment_pending", false).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&BatchPayoutItem{}).
+				Where("batch_id = ? AND status = ?", batchID, BatchItemReserved).
+				Update("status", BatchItemReleased).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&BatchPayoutItem{}).
+			Where("batch_id = ? AND status = ?", batchID, BatchItemSending).
+			Updates(map[string]interface{}{
+				"status": BatchItemNeedsReview,
+				"error":  "Payout stopped before the payment was recorded",
+			}).Error; err != nil {
+			return err
+		}
+
+		var held uint
+		if err := tx.Model(&BatchPayoutItem{}).
+			Where("batch_id = ? AND status IN ?", batchID, heldBatchItemStatuses).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&held).Error; err != nil {
+			return err
+		}
+
+		remainder := batch.Reserved - batch.Spent - held
+		if remainder > 0 {
+			if err := tx.Model(&NewBountyBudget{}).
+				Where("workspace_uuid = ?", batch.WorkspaceUuid).
+				Update("total_budget", gorm.Expr("total_budget + ?", remainder)).Error; err != nil {
+				return err
+			}
+			if err := postLedgerBatchRelease(tx, batch, remainder); err != nil {
+				return err
+			}
+		}
+
+		batch.Released = remainder
+		batch.Status = BatchPayoutFinished
+		batch.Updated = time.Now()
+		return tx.Model(&BatchPayout{}).Where("id = ?", batchID).Updates(map[string]interface{}{
+			"released": batch.Released,
+			"status":   batch.Status,
+			"updated":  batch.Updated,
+		}).Error
+	})
+	if err != nil {
+		return BatchPayout{}, err
+	}
+
+	if err := db.db.Preload("Items").First(&batch, batchID).Error; err != nil {
+		return BatchPayout{}, fmt.Errorf("failed to load batch payout: %w", err)
+	}
+	return batch, nil
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchPayouts(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	workspaceUuid := uuid.New().String()
+	now := time.Now()
+	TestDB.CreateWorkspaceBudget(NewBountyBudget{WorkspaceUuid: workspaceUuid, TotalBudget: 5000, Created: &now, Updated: &now})
+
+	bounties := []NewBounty{}
+	for i := 0; i < 3; i++ {
+		bounty := NewBounty{Type: "coding", Title: "batch", Price: 1000, WorkspaceUuid: workspaceUuid, Assignee: "hunter", OwnerID: "owner", Created: now.UnixNano() + int64(i)}
+		TestDB.db.Create(&bounty)
+		bounties = append(bounties, bounty)
+	}
+	budget := func() uint { return TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget }
+
+	t.Run("Rejects a batch the budget cannot cover", func(t *testing.T) {
+		batch := BatchPayout{WorkspaceUuid: workspaceUuid, SenderPubKey: "owner", Items: []BatchPayoutItem{
+			{BountyID: bounties[0].ID, Amount: 6000},
+		}}
+		assert.ErrorIs(t, TestDB.ReserveBatchPayout(&batch), ErrInsufficientBudget)
+		assert.Equal(t, uint(5000), budget())
+	})
+
+	batch := BatchPayout{WorkspaceUuid: workspaceUuid, SenderPubKey: "owner"}
+	for _, bounty := range bounties {
+		batch.Items = append(batch.Items, BatchPayoutItem{BountyID: bounty.ID, Amount: bounty.Price})
+	}
+
+	t.Run("Reserves the total and claims the bounties", func(t *testing.T) {
+		assert.NoError(t, TestDB.ReserveBatchPayout(&batch))
+		assert.Equal(t, uint(3000), batch.Reserved)
+		assert.Equal(t, uint(2000), budget())
+		assert.True(t, TestDB.GetBounty(bounties[0].ID).PaymentPending)
+
+		again := BatchPayout{WorkspaceUuid: workspaceUuid, SenderPubKey: "owner", Items: []BatchPayoutItem{{BountyID: bounties[0].ID, Amount: 1000}}}
+		assert.ErrorIs(t, TestDB.ReserveBatchPayout(&again), ErrBountyNotPayable)
+		assert.Equal(t, uint(2000), budget())
+	})
+
+	t.Run("Pays and fails items", func(t *testing.T) {
+		paidAt := time.Now()
+		paid := bounties[0]
+		paid.Paid = true
+		paid.PaidDate = &paidAt
+		assert.NoError(t, TestDB.StartBatchPayment(batch.ID, batch.Items[0].ID))
+		assert.NoError(t, TestDB.StartBatchPayment(batch.ID, batch.Items[1].ID))
+		assert.ErrorIs(t, TestDB.StartBatchPayment(batch.ID, batch.Items[0].ID), ErrBatchItemDone)
+
+		_, err := TestDB.ProcessBatchPayment(batch.ID, batch.Items[0], NewPaymentHistory{Amount: 1000, BountyId: paid.ID, WorkspaceUuid: workspaceUuid,
+			ReceiverPubKey: "hunter", PaymentType: Payment, PaymentStatus: PaymentComplete, Status: true, Created: &paidAt}, paid)
+		assert.NoError(t, err)
+
+		_, err = TestDB.ProcessBatchPayment(batch.ID, batch.Items[1], NewPaymentHistory{Amount: 1000, BountyId: bounties[1].ID, WorkspaceUuid: workspaceUuid,
+			ReceiverPubKey: "hunter", PaymentType: Payment, PaymentStatus: PaymentFailed, Created: &paidAt}, bounties[1])
+		assert.NoError(t, err)
+
+		_, err = TestDB.ProcessBatchPayment(batch.ID, batch.Items[0], NewPaymentHistory{Amount: 1000, BountyId: paid.ID, WorkspaceUuid: workspaceUuid,
+			PaymentType: Payment, PaymentStatus: PaymentComplete, Status: true, Created: &paidAt}, paid)
+		assert.ErrorIs(t, err, ErrBatchItemDone)
+
+		assert.True(t, TestDB.GetBounty(bounties[0].ID).Paid)
+		assert.True(t, TestDB.GetBounty(bounties[1].ID).PaymentFailed)
+	})
+
+	t.Run("Finishing releases the unspent reservation once", func(t *testing.T) {
+		finished, err := TestDB.FinishBatchPayout(batch.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, BatchPayoutFinished, finished.Status)
+		assert.Equal(t, uint(1000), finished.Spent)
+		assert.Equal(t, uint(2000), finished.Released)
+		assert.Equal(t, uint(4000), budget())
+		assert.False(t, TestDB.GetBounty(bounties[2].ID).PaymentPending, "unpaid bounty is released")
+
+		_, err = TestDB.FinishBatchPayout(batch.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(4000), budget())
+
+		report, err := TestDB.ReconcileLedger(workspaceUuid)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), report.Escrow)
+	})
+
+	t.Run("Finishing holds an item being sent until it is recorded", func(t *testing.T) {
+		bounty := NewBounty{Type: "coding", Title: "held", Price: 1000, WorkspaceUuid: workspaceUuid, Assignee: "hunter", OwnerID: "owner", Created: time.Now().UnixNano()}
+		TestDB.db.Create(&bounty)
+		held := BatchPayout{WorkspaceUuid: workspaceUuid, SenderPubKey: "owner", Items: []BatchPayoutItem{{BountyID: bounty.ID, Amount: 1000}}}
+		assert.NoError(t, TestDB.ReserveBatchPayout(&held))
+		assert.NoError(t, TestDB.StartBatchPayment(held.ID, held.Items[0].ID))
+
+		finished, err := TestDB.FinishBatchPayout(held.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(0), finished.Released)
+		assert.Equal(t, uint(3000), budget(), "the amount of a keysend in flight is not released")
+		assert.Equal(t, BatchItemNeedsReview, finished.Items[0].Status)
+		assert.True(t, TestDB.GetBounty(bounty.ID).PaymentPending)
+
+		failedAt := time.Now()
+		_, err = TestDB.ProcessBatchPayment(held.ID, held.Items[0], NewPaymentHistory{Amount: 1000, BountyId: bounty.ID, WorkspaceUuid: workspaceUuid,
+			ReceiverPubKey: "hunter", PaymentType: Payment, PaymentStatus: PaymentFailed, Created: &failedAt}, bounty)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(4000), budget(), "a failure recorded after the batch finished is released")
+
+		report, err := TestDB.ReconcileLedger(workspaceUuid)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), report.Escrow)
+	})
+
+	t.Run("Holds an item that could not be recorded", func(t *testing.T) {
+		bounty := NewBounty{Type: "coding", Title: "review", Price: 1000, WorkspaceUuid: workspaceUuid, Assignee: "hunter", OwnerID: "owner", Created: time.Now().UnixNano()}
+		TestDB.db.Create(&bounty)
+		held := BatchPayout{WorkspaceUuid: workspaceUuid, SenderPubKey: "owner", Items: []BatchPayoutItem{{BountyID: bounty.ID, Amount: 1000}}}
+		assert.NoError(t, TestDB.ReserveBatchPayout(&held))
+		assert.NoError(t, TestDB.StartBatchPayment(held.ID, held.Items[0].ID))
+		assert.NoError(t, TestDB.HoldBatchPayment(held.ID, held.Items[0].ID, "tag-1", "Payment sent but could not be recorded"))
+
+		finished, err := TestDB.FinishBatchPayout(held.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(0), finished.Released)
+		assert.Equal(t, BatchItemNeedsReview, finished.Items[0].Status)
+		assert.Equal(t, "tag-1", finished.Items[0].Tag)
+		assert.Equal(t, uint(3000), budget())
+		assert.True(t, TestDB.GetBounty(bounty.ID).PaymentPending)
+	})
+
+	t.Run("Releases a held item once", func(t *testing.T) {
+		bounty := NewBounty{Type: "coding", Title: "release", Price: 1000, WorkspaceUuid: workspaceUuid, Assignee: "hunter", OwnerID: "owner", Created: time.Now().UnixNano()}
+		TestDB.db.Create(&bounty)
+		held := BatchPayout{WorkspaceUuid: workspaceUuid, SenderPubKey: "owner", Items: []BatchPayoutItem{{BountyID: bounty.ID, Amount: 1000}}}
+		assert.NoError(t, TestDB.ReserveBatchPayout(&held))
+		assert.NoError(t, TestDB.StartBatchPayment(held.ID, held.Items[0].ID))
+		assert.ErrorIs(t, TestDB.ReleaseBatchPayment(held.ID, held.Items[0].ID, "Released by owner"), ErrBatchItemDone, "the payout is still running")
+
+		_, err := TestDB.FinishBatchPayout(held.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(2000), budget())
+
+		assert.NoError(t, TestDB.ReleaseBatchPayment(held.ID, held.Items[0].ID, "Released by owner"))
+		assert.ErrorIs(t, TestDB.ReleaseBatchPayment(held.ID, held.Items[0].ID, "Released by owner"), ErrBatchItemDone)
+		assert.Equal(t, uint(3000), budget())
+		assert.False(t, TestDB.GetBounty(bounty.ID).PaymentPending)
+
+		released, err := TestDB.GetBatchPayout(held.Uuid)
+		assert.NoError(t, err)
+		assert.Equal(t, BatchItemReleased, released.Items[0].Status)
+		assert.Equal(t, uint(1000), released.Released)
+	})
+}
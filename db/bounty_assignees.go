@@ -0,0 +1,251 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxBountyAssignees = 10
+
+var (
+	ErrInvalidBountyShares = errors.New("invalid bounty shares")
+	ErrBountySharesLocked  = errors.New("bounty shares cannot change once a share is paid")
+)
+
+// ValidateBountyShares checks that the shares split price between distinct
+// hunters: every share is a percent or every share is a fixed amount, and
+// they add up to 100 percent or to the price.
+func ValidateBountyShares(price uint, shares []BountyAssignee) error {
+	if len(shares) == 0 || len(shares) > maxBountyAssignees {
+		return fmt.Errorf("%w: a bounty is split between 1 and %d hunters", ErrInvalidBountyShares, maxBountyAssignees)
+	}
+
+	byPercent := shares[0].Percent > 0
+	seen := map[string]bool{}
+	var total uint
+	for _, share := range shares {
+		pubkey := strings.TrimSpace(share.Pubkey)
+		if pubkey == "" {
+			return fmt.Errorf("%w: every share needs a hunter", ErrInvalidBountyShares)
+		}
+		if seen[pubkey] {
+			return fmt.Errorf("%w: %s has more than one share", ErrInvalidBountyShares, pubkey)
+		}
+		seen[pubkey] = true
+
+		if (share.Percent > 0) == (share.Amount > 0) {
+			return fmt.Errorf("%w: a share is either a percent or an amount", ErrInvalidBountyShares)
+		}
+		if (share.Percent > 0) != byPercent {
+			return fmt.Errorf("%w: shares cannot mix percents and amounts", ErrInvalidBountyShares)
+		}
+		total += share.Percent + share.Amount
+	}
+
+	if byPercent && total != 100 {
+		return fmt.Errorf("%w: percents add up to %d, not 100", ErrInvalidBountyShares, total)
+	}
+	if !byPercent && total != price {
+		return fmt.Errorf("%w: amounts add up to %d, not the price of %d", ErrInvalidBountyShares, total, price)
+	}
+	return nil
+}
+
+// SplitBountyPrice returns what each share of price pays. Percent shares
+// are rounded down and the sats left over go one each to the first
+// shares, so the split always adds up to the price.
+func SplitBountyPrice(price uint, shares []BountyAssignee) []uint {
+	amounts := make([]uint, len(shares))
+	byPercent := false
+	var paid uint
+	for i, share := range shares {
+		if share.Percent == 0 {
+			amounts[i] = share.Amount
+			continue
+		}
+		byPercent = true
+		amounts[i] = price * share.Percent / 100
+		paid += amounts[i]
+	}
+	if !byPercent {
+		return amounts
+	}
+	for i := 0; paid < price && i < len(shares); i++ {
+		amounts[i]++
+		paid++
+	}
+	return amounts
+}
+
+// GetBountyAssignees lists the shares of a split bounty, none when one
+// hunter takes the whole bounty.
+func (db database) GetBountyAssignees(bountyID uint) []BountyAssignee {
+	shares := []BountyAssignee{}
+	db.db.Where("bounty_id = ?", bountyID).Order("id").Find(&shares)
+	return shares
+}
+
+// SetBountyAssignees replaces the shares of a bounty and makes the first
+// hunter its assignee. Shares are fixed once any of them has been paid.
+func (db database) SetBountyAssignees(bountyID uint, shares []BountyAssignee) ([]BountyAssignee, error) {
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		bounty := NewBounty{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", bountyID).First(&bounty).Error; err != nil {
+			return err
+		}
+		if err := ValidateBountyShares(bounty.Price, shares); err != nil {
+			return err
+		}
+
+		var milestones int64
+		tx.Model(&BountyMilestone{}).Where("bounty_id = ?", bountyID).Count(&milestones)
+		if milestones > 0 {
+			return fmt.Errorf("%w: a bounty paid by milestones has one hunter", ErrInvalidBountyShares)
+		}
+
+		var paid int64
+		tx.Model(&BountyAssignee{}).Where("bounty_id = ?", bountyID).Where("payment_status <> ?", "").Count(&paid)
+		if paid > 0 || bounty.Paid || bounty.PaymentPending {
+			return ErrBountySharesLocked
+		}
+
+		if err := tx.Where("bounty_id = ?", bountyID).Delete(&BountyAssignee{}).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		for i := range shares {
+			shares[i].ID = 0
+			shares[i].BountyID = bountyID
+			shares[i].Pubkey = strings.TrimSpace(shares[i].Pubkey)
+			shares[i].PaymentID = 0
+			shares[i].PaymentStatus = ""
+			shares[i].Created = now
+		}
+		if err := tx.Create(&shares).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&NewBounty{}).Where("id = ?", bountyID).Updates(map[string]interface{}{
+			"assignee": shares[0].Pubkey,
+			"updated":  &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// ProcessBountySplitPayment records the payments of the shares of a split
+// bounty in one transaction: every payment that went out is spent from the
+// workspace budget, its share takes the payment's status and the bounty
+// flags follow from all of its shares. Payments carry the id of their
+// share in ShareID.
+func (db database) ProcessBountySplitPayment(payments []BountySharePayment, bounty NewBounty) (NewBounty, []NewPaymentHistory, error) {
+	recorded := make([]NewPaymentHistory, 0, len(payments))
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		for _, sharePayment := range payments {
+			payment := sharePayment.Payment
+			if err := tx.Create(&payment).Error; err != nil {
+				return err
+			}
+			if payment.PaymentStatus != PaymentFailed {
+				if err := spendBountyPayment(tx, payment); err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&BountyAssignee{}).Where("id = ? AND bounty_id = ?", sharePayment.ShareID, bounty.ID).Updates(map[string]interface{}{
+				"payment_id":     payment.ID,
+				"payment_status": payment.PaymentStatus,
+			}).Error; err != nil {
+				return err
+			}
+			recorded = append(recorded, payment)
+		}
+		return applyShareStatuses(tx, &bounty)
+	})
+	if err != nil {
+		return bounty, nil, err
+	}
+	return bounty, recorded, nil
+}
+
+// applyShareStatuses sets the payment flags of a split bounty from its
+// shares and saves them: paid once every share is complete, failed while
+// any share failed and pending while any share is pending, even when
+// another one failed. Bounties paid to one hunter are left alone.
+func applyShareStatuses(tx *gorm.DB, bounty *NewBounty) error {
+	if bounty.ID == 0 {
+		return nil
+	}
+	shares := []BountyAssignee{}
+	if err := tx.Where("bounty_id = ?", bounty.ID).Order("id").Find(&shares).Error; err != nil {
+		return err
+	}
+	if len(shares) == 0 {
+		return nil
+	}
+
+	complete, failed, pending := 0, 0, 0
+	for _, share := range shares {
+		switch share.PaymentStatus {
+		case PaymentComplete:
+			complete++
+		case PaymentFailed:
+			failed++
+		case PaymentPending:
+			pending++
+		}
+	}
+	bounty.Assignees = shares
+	bounty.Paid = complete == len(shares)
+	bounty.PaymentFailed = failed > 0
+	bounty.PaymentPending = pending > 0
+	if !bounty.Paid {
+		bounty.PaidDate = nil
+	} else if bounty.PaidDate == nil {
+		now := time.Now()
+		bounty.PaidDate = &now
+	}
+
+	return tx.Model(&NewBounty{}).Where("id = ?", bounty.ID).Updates(map[string]interface{}{
+		"paid":            bounty.Paid,
+		"payment_pending": bounty.PaymentPending,
+		"payment_failed":  bounty.PaymentFailed,
+		"completed":       bounty.Completed,
+		"paid_date":       bounty.PaidDate,
+		"completion_date": bounty.CompletionDate,
+	}).Error
+}
+
+// isPartPayment reports whether a payment paid one share of a split bounty
+// or one milestone, rather than a whole bounty.
+func isPartPayment(tx *gorm.DB, paymentID uint) (bool, error) {
+	var shares, milestones int64
+	if err := tx.Model(&BountyAssignee{}).Where("payment_id = ?", paymentID).Count(&shares).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&BountyMilestone{}).Where("payment_id = ?", paymentID).Count(&milestones).Error; err != nil {
+		return false, err
+	}
+	return shares+milestones > 0, nil
+}
+
+// setSharePaymentStatus moves the share paid by a payment to status and
+// refreshes the flags of its bounty.
+func setSharePaymentStatus(tx *gorm.DB, payment NewPaymentHistory, status string) error {
+	updated := tx.Model(&BountyAssignee{}).Where("payment_id = ?", payment.ID).Update("payment_status", status)
+	if updated.Error != nil || updated.RowsAffected == 0 {
+		return updated.Error
+	}
+	bounty := NewBounty{}
+	if err := tx.Where("id = ?", payment.BountyId).Find(&bounty).Error; err != nil {
+		return err
+	}
+	return applyShareStatuses(tx, &bounty)
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitBountyPrice(t *testing.T) {
+	percents := []BountyAssignee{{Pubkey: "a", Percent: 34}, {Pubkey: "b", Percent: 33}, {Pubkey: "c", Percent: 33}}
+	assert.Equal(t, []uint{35, 33, 33}, SplitBountyPrice(101, percents))
+	assert.Equal(t, []uint{1, 0, 0}, SplitBountyPrice(1, percents))
+
+	amounts := []BountyAssignee{{Pubkey: "a", Amount: 70}, {Pubkey: "b", Amount: 31}}
+	assert.Equal(t, []uint{70, 31}, SplitBountyPrice(101, amounts))
+
+	assert.NoError(t, ValidateBountyShares(101, percents))
+	assert.NoError(t, ValidateBountyShares(101, amounts))
+	assert.ErrorIs(t, ValidateBountyShares(100, amounts), ErrInvalidBountyShares)
+	assert.ErrorIs(t, ValidateBountyShares(100, []BountyAssignee{{Pubkey: "a", Percent: 50, Amount: 50}, {Pubkey: "b", Percent: 50}}), ErrInvalidBountyShares)
+	assert.ErrorIs(t, ValidateBountyShares(100, []BountyAssignee{{Pubkey: " ", Percent: 100}}), ErrInvalidBountyShares)
+}
+
+func TestBountySplitPayment(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	workspaceUuid := uuid.New().String()
+	now := time.Now()
+	TestDB.CreateWorkspaceBudget(NewBountyBudget{WorkspaceUuid: workspaceUuid, TotalBudget: 5000, Created: &now, Updated: &now})
+
+	bounty := NewBounty{Type: "coding", Title: "pair", Price: 1000, WorkspaceUuid: workspaceUuid, OwnerID: "owner", Created: now.UnixNano()}
+	TestDB.db.Create(&bounty)
+
+	shares, err := TestDB.SetBountyAssignees(bounty.ID, []BountyAssignee{{Pubkey: "alice", Percent: 70}, {Pubkey: "bob", Percent: 30}})
+	assert.NoError(t, err)
+	assert.Len(t, shares, 2)
+	assert.Equal(t, "alice", TestDB.GetBounty(bounty.ID).Assignee)
+	assert.Len(t, TestDB.GetBounty(bounty.ID).Assignees, 2)
+
+	payment := func(share BountyAssignee, amount uint, status string, tag string) BountySharePayment {
+		return BountySharePayment{ShareID: share.ID, Payment: NewPaymentHistory{Amount: amount, BountyId: bounty.ID, WorkspaceUuid: workspaceUuid,
+			SenderPubKey: "owner", ReceiverPubKey: share.Pubkey, PaymentType: Payment, PaymentStatus: status, Status: status != PaymentFailed, Tag: tag, Created: &now}}
+	}
+
+	t.Run("A failed share fails the bounty and keeps its budget", func(t *testing.T) {
+		bounty.Completed = true
+		paid, recorded, err := TestDB.ProcessBountySplitPayment([]BountySharePayment{
+			payment(shares[0], 700, PaymentComplete, "tag-alice"),
+			payment(shares[1], 300, PaymentFailed, "tag-bob"),
+		}, bounty)
+		assert.NoError(t, err)
+		assert.Len(t, recorded, 2)
+		assert.False(t, paid.Paid)
+		assert.True(t, paid.PaymentFailed)
+		assert.Equal(t, uint(4300), TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget)
+
+		_, err = TestDB.SetBountyAssignees(bounty.ID, []BountyAssignee{{Pubkey: "carol", Percent: 100}})
+		assert.ErrorIs(t, err, ErrBountySharesLocked)
+	})
+
+	t.Run("A pending share pays the bounty once it completes", func(t *testing.T) {
+		paid, _, err := TestDB.ProcessBountySplitPayment([]BountySharePayment{payment(shares[1], 300, PaymentPending, "tag-bob-2")}, bounty)
+		assert.NoError(t, err)
+		assert.True(t, paid.PaymentPending)
+		assert.False(t, paid.PaymentFailed)
+		assert.Equal(t, uint(4000), TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget)
+
+		assert.True(t, TestDB.SetPaymentAsComplete("tag-bob-2"))
+		stored := TestDB.GetBounty(bounty.ID)
+		assert.True(t, stored.Paid)
+		assert.False(t, stored.PaymentPending)
+		for _, share := range stored.Assignees {
+			assert.Equal(t, PaymentComplete, share.PaymentStatus)
+		}
+	})
+
+	t.Run("Reversing one share leaves the others as they are", func(t *testing.T) {
+		TestDB.db.Create(&NewPaymentHistory{Amount: 5000, WorkspaceUuid: workspaceUuid, PaymentType: Deposit, Status: true, Created: &now})
+		split := NewBounty{Type: "coding", Title: "trio", Price: 1000, WorkspaceUuid: workspaceUuid, OwnerID: "owner", Created: now.UnixNano() + 1}
+		TestDB.db.Create(&split)
+		splitShares, err := TestDB.SetBountyAssignees(split.ID, []BountyAssignee{{Pubkey: "alice", Percent: 50}, {Pubkey: "bob", Percent: 50}})
+		assert.NoError(t, err)
+
+		pending := func(share BountyAssignee, tag string) BountySharePayment {
+			return BountySharePayment{ShareID: share.ID, Payment: NewPaymentHistory{Amount: 500, BountyId: split.ID, WorkspaceUuid: workspaceUuid,
+				SenderPubKey: "owner", ReceiverPubKey: share.Pubkey, PaymentType: Payment, PaymentStatus: PaymentPending, Status: true, Tag: tag, Created: &now}}
+		}
+		_, _, err = TestDB.ProcessBountySplitPayment([]BountySharePayment{pending(splitShares[0], "tag-trio-alice"), pending(splitShares[1], "tag-trio-bob")}, split)
+		assert.NoError(t, err)
+		before := TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget
+
+		stored := TestDB.GetBounty(split.ID)
+		assert.NoError(t, TestDB.ProcessReversePayments(stored.Assignees[0].PaymentID))
+
+		stored = TestDB.GetBounty(split.ID)
+		assert.Equal(t, PaymentFailed, stored.Assignees[0].PaymentStatus)
+		assert.Equal(t, PaymentPending, stored.Assignees[1].PaymentStatus)
+		assert.True(t, stored.PaymentPending, "the share still in flight is still followed")
+		assert.False(t, stored.Paid)
+		assert.Equal(t, before+500, TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget)
+	})
+}
@@ -0,0 +1,343 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxBountyMilestones = 20
+
+var (
+	ErrInvalidBountyMilestones = errors.New("invalid bounty milestones")
+	ErrBountyMilestonesLocked  = errors.New("bounty milestones cannot change once a milestone is paid")
+	ErrMilestonePaid           = errors.New("milestone already paid or payment in progress")
+	ErrBountyTermsLocked       = errors.New("bounty price, asset and workspace cannot change while it has milestones")
+)
+
+// ValidateBountyMilestones checks that the milestones are titled, each
+// pays something and together they pay price.
+func ValidateBountyMilestones(price uint, milestones []BountyMilestone) error {
+	if len(milestones) > maxBountyMilestones {
+		return fmt.Errorf("%w: a bounty has at most %d milestones", ErrInvalidBountyMilestones, maxBountyMilestones)
+	}
+	if len(milestones) == 0 {
+		return nil
+	}
+
+	var total uint
+	for _, milestone := range milestones {
+		if strings.TrimSpace(milestone.Title) == "" {
+			return fmt.Errorf("%w: every milestone needs a title", ErrInvalidBountyMilestones)
+		}
+		if milestone.Amount == 0 {
+			return fmt.Errorf("%w: milestone %q pays nothing", ErrInvalidBountyMilestones, milestone.Title)
+		}
+		total += milestone.Amount
+	}
+	if total != price {
+		return fmt.Errorf("%w: milestones add up to %d, not the price of %d", ErrInvalidBountyMilestones, total, price)
+	}
+	return nil
+}
+
+// GetBountyMilestones lists the milestones of a bounty in order, none when
+// the bounty is paid at once.
+func (db database) GetBountyMilestones(bountyID uint) []BountyMilestone {
+	milestones := []BountyMilestone{}
+	db.db.Where("bounty_id = ?", bountyID).Order("position, id").Find(&milestones)
+	return milestones
+}
+
+// SetBountyMilestones replaces the milestones of a bounty and reserves
+// their amounts from the workspace budget, releasing what the milestones
+// they replace had reserved. No milestones pays the bounty at once again.
+// Milestones are fixed once any of them has been paid; reserving more than
+// the budget holds fails with ErrInsufficientBudget.
+func (db database) SetBountyMilestones(bountyID uint, milestones []BountyMilestone) ([]BountyMilestone, error) {
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		bounty := NewBounty{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", bountyID).First(&bounty).Error; err != nil {
+			return err
+		}
+		if err := ValidateBountyMilestones(bounty.Price, milestones); err != nil {
+			return err
+		}
+		if bounty.WorkspaceUuid == "" || bounty.AssetId != 0 {
+			return fmt.Errorf("%w: only workspace bounties priced in sats are paid by milestones", ErrInvalidBountyMilestones)
+		}
+
+		var shares int64
+		tx.Model(&BountyAssignee{}).Where("bounty_id = ?", bountyID).Count(&shares)
+		if shares > 0 {
+			return fmt.Errorf("%w: a split bounty is paid at once", ErrInvalidBountyMilestones)
+		}
+
+		current := []BountyMilestone{}
+		if err := tx.Where("bounty_id = ?", bountyID).Find(&current).Error; err != nil {
+			return err
+		}
+		for _, milestone := range current {
+			if milestone.PaymentStatus != "" {
+				return ErrBountyMilestonesLocked
+			}
+		}
+		if bounty.Paid || bounty.PaymentPending {
+			return ErrBountyMilestonesLocked
+		}
+
+		budget := NewBountyBudget{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("workspace_uuid = ?", bounty.WorkspaceUuid).First(&budget).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInsufficientBudget
+		}
+		if err != nil {
+			return err
+		}
+
+		var released, reserved uint
+		for _, milestone := range current {
+			if !milestone.Reserved {
+				continue
+			}
+			released += milestone.Amount
+			if err := postLedgerMilestoneRelease(tx, bounty.WorkspaceUuid, milestone); err != nil {
+				return err
+			}
+		}
+		for _, milestone := range milestones {
+			reserved += milestone.Amount
+		}
+		if budget.TotalBudget+released < reserved {
+			return ErrInsufficientBudget
+		}
+
+		if err := tx.Where("bounty_id = ?", bountyID).Delete(&BountyMilestone{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&NewBountyBudget{}).Where("workspace_uuid = ?", bounty.WorkspaceUuid).Updates(map[string]interface{}{
+			"total_budget": gorm.Expr("total_budget + ? - ?", released, reserved),
+		}).Error; err != nil {
+			return err
+		}
+		if len(milestones) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		for i := range milestones {
+			milestones[i].ID = 0
+			milestones[i].BountyID = bountyID
+			milestones[i].Position = i
+			milestones[i].Title = strings.TrimSpace(milestones[i].Title)
+			milestones[i].Reserved = true
+			milestones[i].PaymentID = 0
+			milestones[i].PaymentStatus = ""
+			milestones[i].Created = now
+			milestones[i].Updated = now
+		}
+		if err := tx.Create(&milestones).Error; err != nil {
+			return err
+		}
+		for _, milestone := range milestones {
+			if err := postLedgerMilestoneReserve(tx, bounty.WorkspaceUuid, milestone); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if milestones == nil {
+		milestones = []BountyMilestone{}
+	}
+	return milestones, nil
+}
+
+// checkBountyMilestoneTerms refuses an edit of a bounty with milestones
+// that changes its price, asset or workspace, which the milestones were
+// validated and reserved against.
+func checkBountyMilestoneTerms(tx *gorm.DB, b NewBounty) error {
+	current := NewBounty{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? OR owner_id = ? AND created = ?", b.ID, b.OwnerID, b.Created).
+		Limit(1).Find(&current).Error; err != nil {
+		return err
+	}
+	if current.ID == 0 {
+		return nil
+	}
+
+	var milestones int64
+	if err := tx.Model(&BountyMilestone{}).Where("bounty_id = ?", current.ID).Count(&milestones).Error; err != nil {
+		return err
+	}
+	if milestones == 0 {
+		return nil
+	}
+	if (b.Price != 0 && b.Price != current.Price) ||
+		(b.AssetId != 0 && b.AssetId != current.AssetId) ||
+		(b.WorkspaceUuid != "" && b.WorkspaceUuid != current.WorkspaceUuid) {
+		return ErrBountyTermsLocked
+	}
+	return nil
+}
+
+// releaseBountyMilestones removes the milestones of a bounty, returning
+// what the unpaid ones still reserve to the workspace budget.
+func releaseBountyMilestones(tx *gorm.DB, bounty NewBounty) error {
+	milestones := []BountyMilestone{}
+	if err := tx.Where("bounty_id = ?", bounty.ID).Find(&milestones).Error; err != nil {
+		return err
+	}
+
+	var released uint
+	for _, milestone := range milestones {
+		if !milestone.Reserved {
+			continue
+		}
+		released += milestone.Amount
+		if err := postLedgerMilestoneRelease(tx, bounty.WorkspaceUuid, milestone); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where("bounty_id = ?", bounty.ID).Delete(&BountyMilestone{}).Error; err != nil {
+		return err
+	}
+	if released == 0 {
+		return nil
+	}
+	return tx.Model(&NewBountyBudget{}).Where("workspace_uuid = ?", bounty.WorkspaceUuid).Updates(map[string]interface{}{
+		"total_budget": gorm.Expr("total_budget + ?", released),
+	}).Error
+}
+
+// ProcessMilestonePayment records the payment of a milestone. A payment
+// that went out is drawn from the milestone's reservation, or from the
+// budget when a reversal gave the reservation back; a failed one leaves
+// the milestone unpaid. The bounty flags then follow from all of its
+// milestones. Paying a milestone that is paid or being paid fails with
+// ErrMilestonePaid.
+func (db database) ProcessMilestonePayment(milestoneID uint, payment NewPaymentHistory, bounty NewBounty) (NewBounty, NewPaymentHistory, error) {
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		milestone := BountyMilestone{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND bounty_id = ?", milestoneID, bounty.ID).
+			First(&milestone).Error; err != nil {
+			return err
+		}
+		if milestone.PaymentStatus == PaymentPending || milestone.PaymentStatus == PaymentComplete {
+			return ErrMilestonePaid
+		}
+
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+
+		reserved := milestone.Reserved
+		if payment.PaymentStatus != PaymentFailed {
+			var err error
+			if reserved {
+				err = postLedgerMilestonePayment(tx, payment)
+			} else {
+				err = spendBountyPayment(tx, payment)
+			}
+			if err != nil {
+				return err
+			}
+			reserved = false
+		}
+
+		if err := tx.Model(&BountyMilestone{}).Where("id = ?", milestone.ID).Updates(map[string]interface{}{
+			"payment_id":     payment.ID,
+			"payment_status": payment.PaymentStatus,
+			"reserved":       reserved,
+			"updated":        time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return applyMilestoneStatuses(tx, &bounty)
+	})
+	if err != nil {
+		return bounty, NewPaymentHistory{}, err
+	}
+	return bounty, payment, nil
+}
+
+// applyMilestoneStatuses sets the flags of a bounty paid by milestones
+// from its milestones and saves them: completed once every milestone has
+// been paid out, paid once every payment settled, failed while any
+// milestone's payment failed and pending while any is pending. Bounties
+// paid at once are left alone.
+func applyMilestoneStatuses(tx *gorm.DB, bounty *NewBounty) error {
+	if bounty.ID == 0 {
+		return nil
+	}
+	milestones := []BountyMilestone{}
+	if err := tx.Where("bounty_id = ?", bounty.ID).Order("position, id").Find(&milestones).Error; err != nil {
+		return err
+	}
+	if len(milestones) == 0 {
+		return nil
+	}
+
+	complete, failed, pending := 0, 0, 0
+	for _, milestone := range milestones {
+		switch milestone.PaymentStatus {
+		case PaymentComplete:
+			complete++
+		case PaymentFailed:
+			failed++
+		case PaymentPending:
+			pending++
+		}
+	}
+	now := time.Now()
+	bounty.Milestones = milestones
+	bounty.Paid = complete == len(milestones)
+	bounty.PaymentFailed = failed > 0
+	bounty.PaymentPending = pending > 0
+	bounty.Completed = complete+pending == len(milestones)
+	if !bounty.Completed {
+		bounty.CompletionDate = nil
+	} else if bounty.CompletionDate == nil {
+		bounty.CompletionDate = &now
+	}
+	if !bounty.Paid {
+		bounty.PaidDate = nil
+	} else if bounty.PaidDate == nil {
+		bounty.PaidDate = &now
+	}
+
+	return tx.Model(&NewBounty{}).Where("id = ?", bounty.ID).Updates(map[string]interface{}{
+		"paid":            bounty.Paid,
+		"payment_pending": bounty.PaymentPending,
+		"payment_failed":  bounty.PaymentFailed,
+		"completed":       bounty.Completed,
+		"paid_date":       bounty.PaidDate,
+		"completion_date": bounty.CompletionDate,
+	}).Error
+}
+
+// setMilestonePaymentStatus moves the milestone paid by a payment to
+// status and refreshes the flags of its bounty.
+func setMilestonePaymentStatus(tx *gorm.DB, payment NewPaymentHistory, status string) error {
+	updated := tx.Model(&BountyMilestone{}).Where("payment_id = ?", payment.ID).Updates(map[string]interface{}{
+		"payment_status": status,
+		"updated":        time.Now(),
+	})
+	if updated.Error != nil || updated.RowsAffected == 0 {
+		return updated.Error
+	}
+	bounty := NewBounty{}
+	if err := tx.Where("id = ?", payment.BountyId).Find(&bounty).Error; err != nil {
+		return err
+	}
+	return applyMilestoneStatuses(tx, &bounty)
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBountyMilestones(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	workspaceUuid := uuid.New().String()
+	now := time.Now()
+	TestDB.CreateWorkspaceBudget(NewBountyBudget{WorkspaceUuid: workspaceUuid, TotalBudget: 1500, Created: &now, Updated: &now})
+
+	bounty := NewBounty{Type: "coding", Title: "milestones", Price: 1000, WorkspaceUuid: workspaceUuid, Assignee: "hunter", OwnerID: "owner", Created: now.UnixNano()}
+	TestDB.db.Create(&bounty)
+
+	t.Run("Milestones reserve the price and replacing them releases it", func(t *testing.T) {
+		_, err := TestDB.SetBountyMilestones(bounty.ID, []BountyMilestone{{Title: "Design", Amount: 300}, {Title: "Build", Amount: 600}})
+		assert.ErrorIs(t, err, ErrInvalidBountyMilestones)
+
+		_, err = TestDB.SetBountyMilestones(bounty.ID, []BountyMilestone{{Title: "Design", Amount: 500}, {Title: "Build", Amount: 500}})
+		assert.NoError(t, err)
+		assert.Equal(t, uint(500), TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget)
+
+		milestones, err := TestDB.SetBountyMilestones(bounty.ID, []BountyMilestone{{Title: "Design", Amount: 300}, {Title: "Build", Amount: 700}})
+		assert.NoError(t, err)
+		assert.Len(t, milestones, 2)
+		assert.Equal(t, uint(500), TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget)
+		assert.Len(t, TestDB.GetBounty(bounty.ID).Milestones, 2)
+	})
+
+	t.Run("The price stays while milestones reserve it", func(t *testing.T) {
+		edit := bounty
+		edit.Price = 2000
+		_, err := TestDB.CreateOrEditBounty(edit)
+		assert.ErrorIs(t, err, ErrBountyTermsLocked)
+
+		edit.Price = bounty.Price
+		edit.Title = "milestones, renamed"
+		_, err = TestDB.CreateOrEditBounty(edit)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1000), TestDB.GetBounty(bounty.ID).Price)
+	})
+
+	milestones := TestDB.GetBountyMilestones(bounty.ID)
+	payment := func(milestone BountyMilestone, status string, tag string) NewPaymentHistory {
+		return NewPaymentHistory{Amount: milestone.Amount, BountyId: bounty.ID, WorkspaceUuid: workspaceUuid, SenderPubKey: "owner",
+			ReceiverPubKey: "hunter", PaymentType: Payment, PaymentStatus: status, Status: status != PaymentFailed, Tag: tag, Created: &now}
+	}
+
+	t.Run("Milestones are paid from the reservation", func(t *testing.T) {
+		paid, _, err := TestDB.ProcessMilestonePayment(milestones[0].ID, payment(milestones[0], PaymentComplete, "tag-design"), bounty)
+		assert.NoError(t, err)
+		assert.False(t, paid.Paid)
+		assert.False(t, paid.Completed)
+		assert.Equal(t, uint(500), TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget, "the budget was reserved already")
+
+		_, _, err = TestDB.ProcessMilestonePayment(milestones[0].ID, payment(milestones[0], PaymentComplete, "tag-design-2"), bounty)
+		assert.ErrorIs(t, err, ErrMilestonePaid)
+
+		_, err = TestDB.SetBountyMilestones(bounty.ID, []BountyMilestone{{Title: "All", Amount: 1000}})
+		assert.ErrorIs(t, err, ErrBountyMilestonesLocked)
+	})
+
+	t.Run("The last milestone completes the bounty", func(t *testing.T) {
+		pending, _, err := TestDB.ProcessMilestonePayment(milestones[1].ID, payment(milestones[1], PaymentPending, "tag-build"), bounty)
+		assert.NoError(t, err)
+		assert.True(t, pending.Completed)
+		assert.True(t, pending.PaymentPending)
+		assert.False(t, pending.Paid)
+
+		assert.True(t, TestDB.SetPaymentAsComplete("tag-build"))
+		stored := TestDB.GetBounty(bounty.ID)
+		assert.True(t, stored.Paid)
+		assert.True(t, stored.Completed)
+		assert.False(t, stored.PaymentPending)
+
+		report, err := TestDB.ReconcileLedger(workspaceUuid)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), report.Escrow)
+	})
+
+	t.Run("Deleting a bounty releases its unpaid milestones", func(t *testing.T) {
+		other := NewBounty{Type: "coding", Title: "deleted", Price: 200, WorkspaceUuid: workspaceUuid, OwnerID: "owner", Created: now.UnixNano() + 1}
+		TestDB.db.Create(&other)
+		_, err := TestDB.SetBountyMilestones(other.ID, []BountyMilestone{{Title: "One", Amount: 100}, {Title: "Two", Amount: 100}})
+		assert.NoError(t, err)
+		assert.Equal(t, uint(300), TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget)
+
+		_, err = TestDB.DeleteBounty("owner", strconv.FormatInt(other.Created, 10))
+		assert.NoError(t, err)
+		assert.Equal(t, uint(500), TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget)
+		assert.Empty(t, TestDB.GetBountyMilestones(other.ID))
+
+		report, err := TestDB.ReconcileLedger(workspaceUuid)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), report.Escrow)
+	})
+}
@@ -502,3 +502,24 @@ func (db database) DeleteChatStatus(id uuid.UUID) error {
 
 	return nil
 }
+
+// GetChatSummary returns the rolling summary of a chat, an empty one when
+// nothing was summarized yet.
+func (db database) GetChatSummary(chatID string) (ChatSummary, error) {
+	summary := ChatSummary{}
+	if err := db.db.Where("chat_id = ?", chatID).Find(&summary).Error; err != nil {
+		return ChatSummary{}, fmt.Errorf("failed to fetch chat summary: %w", err)
+	}
+	return summary, nil
+}
+
+func (db database) SaveChatSummary(summary *ChatSummary) error {
+	if summary.ChatID == "" {
+		return errors.New("chat ID is required")
+	}
+	summary.UpdatedAt = time.Now()
+	if err := db.db.Save(summary).Error; err != nil {
+		return fmt.Errorf("failed to save chat summary: %w", err)
+	}
+	return nil
+}
@@ -2,9 +2,11 @@ package db
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/stakwork/sphinx-tribes/vault"
 	"gorm.io/gorm"
 )
 
@@ -20,9 +22,13 @@ func (db database) CreateCodeSpaceMap(codeSpace CodeSpaceMap) (CodeSpaceMap, err
 		now := time.Now()
 		existingMap.CodeSpaceURL = codeSpace.CodeSpaceURL
 		existingMap.Username = codeSpace.Username
-		existingMap.GithubPat = codeSpace.GithubPat
+		if !codeSpace.GithubPat.IsRedacted() {
+			existingMap.GithubPat = codeSpace.GithubPat
+		}
 		existingMap.BaseBranch = codeSpace.BaseBranch
-		existingMap.PoolAPIKey = codeSpace.PoolAPIKey
+		if !codeSpace.PoolAPIKey.IsRedacted() {
+			existingMap.PoolAPIKey = codeSpace.PoolAPIKey
+		}
 		existingMap.UpdatedAt = now
 		
 		db.db.Save(&existingMap)
@@ -140,3 +146,42 @@ func (db database) DeleteCodeSpaceMap(id uuid.UUID) error {
 	
 	return nil
 } 
+
+// RewrapCodeSpaceSecrets wraps the secrets of every codespace mapping with
+// the active master key, sealing the ones stored before the vault. It
+// returns how many mappings changed.
+func (db database) RewrapCodeSpaceSecrets() (int, error) {
+	if vault.Default == nil {
+		return 0, vault.ErrNoMasterKey
+	}
+
+	var codespaces []CodeSpaceMap
+	if err := db.db.Where("github_pat <> '' OR pool_api_key <> ''").Find(&codespaces).Error; err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, codespace := range codespaces {
+		updates := map[string]interface{}{}
+		for column, secret := range map[string]vault.Secret{
+			"github_pat":   codespace.GithubPat,
+			"pool_api_key": codespace.PoolAPIKey,
+		} {
+			rewrapped, ok, err := vault.Default.Rewrap(string(secret))
+			if err != nil {
+				return changed, fmt.Errorf("codespace mapping %s: %s: %w", codespace.ID, column, err)
+			}
+			if ok {
+				updates[column] = vault.Secret(rewrapped)
+			}
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := db.db.Model(&CodeSpaceMap{}).Where("id = ?", codespace.ID).UpdateColumns(updates).Error; err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
@@ -1,12 +1,15 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 
 	"github.com/rs/xid"
 	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/metrics"
+	"github.com/stakwork/sphinx-tribes/tracing"
 	"gopkg.in/go-playground/validator.v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -28,6 +31,24 @@ type database struct {
 	getUserRoles       func(uuid string, pubkey string) []WorkspaceUserRoles
 }
 
+// WithContext returns a copy of the database whose queries are traced as
+// children of the span in ctx.
+func (db database) WithContext(ctx context.Context) Database {
+	if db.db != nil {
+		db.db = db.db.WithContext(ctx)
+	}
+	return db
+}
+
+// log is the logger of the request the database was given with
+// WithContext, so its lines carry the request's fields.
+func (db database) log() *logger.Logger {
+	if db.db == nil || db.db.Statement == nil || db.db.Statement.Context == nil {
+		return &logger.Log
+	}
+	return logger.FromContext(db.db.Statement.Context)
+}
+
 func NewDatabaseConfig(db *gorm.DB) *database {
 	return &database{
 		db:                 db,
@@ -67,6 +88,16 @@ func InitDB() {
 		panic(err)
 	}
 
+	if err := db.Use(tracing.GormPlugin()); err != nil {
+		logger.Log.Error("Could not register tracing plugin: %v", err)
+	}
+
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDBStats(sqlDB); err != nil {
+			logger.Log.Error("Could not register db pool metrics: %v", err)
+		}
+	}
+
 	DB.db = db
 	logger.Log.Info("db connected")
 
@@ -109,7 +140,32 @@ func InitDB() {
 	db.AutoMigrate(&BountyStake{})
 	db.AutoMigrate(&ChatWorkflowStatus{})
 	db.AutoMigrate(&BountyStakeProcess{})
-
+	db.AutoMigrate(&WebsocketOutboxStream{})
+	db.AutoMigrate(&WebsocketOutboxMessage{})
+	db.AutoMigrate(&WebhookSubscription{})
+	db.AutoMigrate(&WebhookDelivery{})
+	db.AutoMigrate(&AuditLog{})
+	db.AutoMigrate(&LedgerAccount{})
+	db.AutoMigrate(&LedgerTransaction{})
+	db.AutoMigrate(&LedgerEntry{})
+	db.AutoMigrate(&IdempotencyKey{})
+	db.AutoMigrate(&Job{})
+	db.AutoMigrate(&WorkspacePaymentPolicy{})
+	db.AutoMigrate(&BatchPayout{})
+	db.AutoMigrate(&BatchPayoutItem{})
+	db.AutoMigrate(&WorkspaceAssetBudget{})
+	db.AutoMigrate(&BountyAssignee{})
+	db.AutoMigrate(&BountyMilestone{})
+	db.AutoMigrate(&WorkspaceSpendLimit{})
+	db.AutoMigrate(&WorkspaceBudgetAlert{})
+	db.AutoMigrate(&WorkspaceWorkflow{})
+	db.AutoMigrate(&ChatSummary{})
+	db.AutoMigrate(&FileRetention{})
+
+	DB.migrateSearchIndexes()
+	DB.migrateAuditLog()
+	DB.migrateLedger()
+	DB.migrateJobs()
 	DB.MigrateTablesWithOrgUuid()
 	DB.MigrateOrganizationToWorkspace()
 
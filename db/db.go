@@ -20,6 +20,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/rs/xid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/stakwork/sphinx-tribes/auth"
 	"github.com/stakwork/sphinx-tribes/utils"
@@ -663,6 +664,7 @@ func (db database) GetWorkspaceBounties(r *http.Request, workspace_uuid string)
 	}
 
 	theQuery.Scan(&ms)
+	db.loadBountyParts(ms)
 
 	return ms
 }
@@ -817,6 +819,7 @@ func (db database) GetAssignedBounties(r *http.Request) ([]NewBounty, error) {
 
     allQuery := query + " " + statusQuery + " " + orderQuery + " " + limitQuery
     err := db.db.Raw(allQuery).Find(&ms).Error
+    db.loadBountyParts(ms)
     return ms, err
 }
 
@@ -882,6 +885,7 @@ func (db database) GetCreatedBounties(r *http.Request) ([]NewBounty, error) {
 	allQuery := query + " " + statusQuery + " " + orderQuery + " " + limitQuery
 
 	err := db.db.Raw(allQuery).Find(&ms).Error
+	db.loadBountyParts(ms)
 
 	return ms, err
 }
@@ -889,6 +893,7 @@ func (db database) GetCreatedBounties(r *http.Request) ([]NewBounty, error) {
 func (db database) GetBountyById(id string) ([]NewBounty, error) {
 	ms := []NewBounty{}
 	err := db.db.Raw("SELECT * FROM public.bounty WHERE id = ?", id).Find(&ms).Error
+	db.loadBountyParts(ms)
 	return ms, err
 }
 
@@ -1191,6 +1196,7 @@ func (db database) GetBountyIndexById(id string) int64 {
 func (db database) GetBountyDataByCreated(created string) ([]NewBounty, error) {
 	ms := []NewBounty{}
 	err := db.db.Raw(`SELECT * FROM public.bounty WHERE created = '` + created + `'`).Find(&ms).Error
+	db.loadBountyParts(ms)
 	return ms, err
 }
 
@@ -1317,6 +1323,7 @@ func (db database) GetAllBounties(r *http.Request) []NewBounty {
 	}
 
 	theQuery.Scan(&ms)
+	db.loadBountyParts(ms)
 
 	return ms
 }
@@ -1326,8 +1333,17 @@ func (db database) CreateOrEditBounty(b NewBounty) (NewBounty, error) {
 		return NewBounty{}, errors.New("no pub key")
 	}
 
-	if db.db.Model(&b).Where("id = ? OR owner_id = ? AND created = ?", b.ID, b.OwnerID, b.Created).Updates(&b).RowsAffected == 0 {
-		db.db.Create(&b)
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkBountyMilestoneTerms(tx, b); err != nil {
+			return err
+		}
+		if tx.Model(&b).Where("id = ? OR owner_id = ? AND created = ?", b.ID, b.OwnerID, b.Created).Updates(&b).RowsAffected == 0 {
+			tx.Create(&b)
+		}
+		return nil
+	})
+	if err != nil {
+		return NewBounty{}, err
 	}
 	return b, nil
 }
@@ -1346,10 +1362,23 @@ func (db database) UpdateBountyBoolColumn(b NewBounty, column string) NewBounty
 	return b
 }
 
+// DeleteBounty deletes a bounty with its milestones, returning what its
+// unpaid milestones had reserved to the workspace budget.
 func (db database) DeleteBounty(pubkey string, created string) (NewBounty, error) {
 	m := NewBounty{}
-	db.db.Where("owner_id", pubkey).Where("created", created).Delete(&m)
-	return m, nil
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("owner_id", pubkey).Where("created", created).Find(&m).Error; err != nil {
+			return err
+		}
+		if m.ID == 0 {
+			return nil
+		}
+		if err := releaseBountyMilestones(tx, m); err != nil {
+			return err
+		}
+		return tx.Delete(&m).Error
+	})
+	return m, err
 }
 
 func (db database) GetBountyByCreated(created uint) (NewBounty, error) {
@@ -1360,13 +1389,52 @@ func (db database) GetBountyByCreated(created uint) (NewBounty, error) {
 
 func (db database) GetBounty(id uint) NewBounty {
 	b := NewBounty{}
-	db.db.Where("id", id).Find(&b)
+	db.db.Preload("Assignees", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("id")
+	}).Preload("Milestones", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("position, id")
+	}).Where("id", id).Find(&b)
 	return b
 }
 
+// loadBountyParts fills in the shares and milestones of listed bounties
+// with one query each, rather than one per bounty.
+func (db database) loadBountyParts(bounties []NewBounty) {
+	if len(bounties) == 0 {
+		return
+	}
+	ids := make([]uint, 0, len(bounties))
+	for _, bounty := range bounties {
+		ids = append(ids, bounty.ID)
+	}
+
+	shares := []BountyAssignee{}
+	db.db.Where("bounty_id IN ?", ids).Order("id").Find(&shares)
+	milestones := []BountyMilestone{}
+	db.db.Where("bounty_id IN ?", ids).Order("position, id").Find(&milestones)
+
+	sharesOf := map[uint][]BountyAssignee{}
+	for _, share := range shares {
+		sharesOf[share.BountyID] = append(sharesOf[share.BountyID], share)
+	}
+	milestonesOf := map[uint][]BountyMilestone{}
+	for _, milestone := range milestones {
+		milestonesOf[milestone.BountyID] = append(milestonesOf[milestone.BountyID], milestone)
+	}
+	for i := range bounties {
+		bounties[i].Assignees = sharesOf[bounties[i].ID]
+		bounties[i].Milestones = milestonesOf[bounties[i].ID]
+	}
+}
+
 func (db database) GetBountyByUnlockCode(code string) (NewBounty, error) {
 	b := NewBounty{}
 	err := db.db.Where("unlock_code = ?", code).Where("show = ?", true).First(&b).Error
+	if err == nil {
+		bounties := []NewBounty{b}
+		db.loadBountyParts(bounties)
+		b = bounties[0]
+	}
 	return b, err
 }
 
@@ -1382,6 +1450,15 @@ func (db database) UpdateBountyPaymentStatuses(bounty NewBounty) (NewBounty, err
 	}
 
 	db.db.Model(&NewBounty{}).Where("created", bounty.Created).Updates(bountyUpdates)
+
+	// a split bounty is only paid once all of its shares are, and a
+	// bounty paid by milestones once all of its milestones are
+	if err := applyShareStatuses(db.db, &bounty); err != nil {
+		return bounty, err
+	}
+	if err := applyMilestoneStatuses(db.db, &bounty); err != nil {
+		return bounty, err
+	}
 	return bounty, nil
 }
 
@@ -1962,6 +2039,14 @@ func (db database) UpdateProofStatus(proofID string, status ProofOfWorkStatus) e
 	return db.db.Model(&ProofOfWork{}).Where("id = ?", proofID).Update("status", status).Error
 }
 
+// GetProofByID returns a proof of work, gorm.ErrRecordNotFound when there
+// is none.
+func (db database) GetProofByID(proofID string) (ProofOfWork, error) {
+	proof := ProofOfWork{}
+	err := db.db.Where("id = ?", proofID).First(&proof).Error
+	return proof, err
+}
+
 func (db database) IncrementProofCount(bountyID uint) error { // Ensure bountyID is of type uint
 	var bounty NewBounty
 
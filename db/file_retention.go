@@ -0,0 +1,177 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ValidateFileRetention checks that files are kept at least a day before
+// they are archived and that both periods stay within ten years.
+func ValidateFileRetention(retention FileRetention) error {
+	if retention.ArchiveAfterDays < 1 || retention.ArchiveAfterDays > maxFileRetentionDays {
+		return fmt.Errorf("archive_after_days must be between 1 and %d", maxFileRetentionDays)
+	}
+	if retention.PurgeAfterDays < 0 || retention.PurgeAfterDays > maxFileRetentionDays {
+		return fmt.Errorf("purge_after_days must be between 0 and %d", maxFileRetentionDays)
+	}
+	return nil
+}
+
+// GetFileRetention returns the retention of a workspace, the default one
+// when it did not set any.
+func (db database) GetFileRetention(workspace_uuid string) FileRetention {
+	retention := FileRetention{
+		WorkspaceUuid:    workspace_uuid,
+		ArchiveAfterDays: DefaultFileArchiveAfterDays,
+		PurgeAfterDays:   DefaultFilePurgeAfterDays,
+	}
+	db.db.Where("workspace_uuid = ?", workspace_uuid).Find(&retention)
+	return retention
+}
+
+func (db database) SaveFileRetention(retention FileRetention) (FileRetention, error) {
+	retention.Updated = time.Now()
+	err := db.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "workspace_uuid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"archive_after_days", "purge_after_days", "updated"}),
+	}).Create(&retention).Error
+	if err != nil {
+		return FileRetention{}, fmt.Errorf("failed to save file retention: %w", err)
+	}
+	return retention, nil
+}
+
+// GetFileAssetWorkspaces lists the workspaces that have files in storage.
+func (db database) GetFileAssetWorkspaces() ([]string, error) {
+	workspaces := []string{}
+	err := db.db.Model(&FileAsset{}).
+		Where("status != ? AND workspace_id != ''", DeletedFileStatus).
+		Distinct().
+		Pluck("workspace_id", &workspaces).Error
+	return workspaces, err
+}
+
+// GetRetainedFileAssets lists the files of a workspace still in storage,
+// active or archived.
+func (db database) GetRetainedFileAssets(workspaceID string) ([]FileAsset, error) {
+	assets := []FileAsset{}
+	err := db.db.Where("workspace_id = ? AND status != ?", workspaceID, DeletedFileStatus).
+		Order("id").
+		Find(&assets).Error
+	return assets, err
+}
+
+// CountFileAssetReferences counts the messages of active chats, and the
+// artifacts of those messages, that refer to the file by its upload name
+// or its storage path. Archived chats no longer hold on to their files.
+func (db database) CountFileAssetReferences(asset FileAsset) (int64, error) {
+	patterns := []string{}
+	for _, ref := range []string{asset.UploadFilename, asset.StoragePath} {
+		if ref != "" {
+			patterns = append(patterns, "%"+escapeLike(ref)+"%")
+		}
+	}
+	if len(patterns) == 0 {
+		return 0, nil
+	}
+
+	matches := func(columns ...string) (string, []interface{}) {
+		conditions := []string{}
+		args := []interface{}{}
+		for _, column := range columns {
+			for _, pattern := range patterns {
+				conditions = append(conditions, column+" LIKE ?")
+				args = append(args, pattern)
+			}
+		}
+		return "(" + strings.Join(conditions, " OR ") + ")", args
+	}
+	activeChats := func(query *gorm.DB) *gorm.DB {
+		return query.
+			Joins("JOIN chats ON chats.id = chat_messages.chat_id").
+			Where("chats.status = ?", ActiveStatus)
+	}
+
+	var messages int64
+	condition, args := matches("chat_messages.message", "chat_messages.pdf_url")
+	if err := activeChats(db.db.Model(&ChatMessage{})).Where(condition, args...).Count(&messages).Error; err != nil {
+		return 0, err
+	}
+
+	var artifacts int64
+	condition, args = matches("artifacts.content::text")
+	err := activeChats(db.db.Model(&Artifact{}).Joins("JOIN chat_messages ON chat_messages.id = artifacts.message_id")).
+		Where(condition, args...).
+		Count(&artifacts).Error
+	if err != nil {
+		return 0, err
+	}
+	return messages + artifacts, nil
+}
+
+// UpdateFileAssetReferences keeps the reference count of a file. A file
+// that is referred to counts as referenced now, and is active again if it
+// was archived.
+func (db database) UpdateFileAssetReferences(id uint, references int64) error {
+	updates := map[string]interface{}{
+		"reference_count": references,
+		"updated_at":      time.Now(),
+	}
+	if references > 0 {
+		updates["last_referenced"] = time.Now()
+		updates["status"] = ActiveFileStatus
+		updates["archived_at"] = nil
+	}
+	return db.db.Model(&FileAsset{}).
+		Where("id = ? AND status != ?", id, DeletedFileStatus).
+		Updates(updates).Error
+}
+
+func (db database) ArchiveFileAsset(id uint) error {
+	now := time.Now()
+	return db.db.Model(&FileAsset{}).
+		Where("id = ? AND status = ?", id, ActiveFileStatus).
+		Updates(map[string]interface{}{
+			"status":      ArchivedFileStatus,
+			"archived_at": &now,
+			"updated_at":  now,
+		}).Error
+}
+
+// GetWorkspaceStorageUsage sums the files of a workspace in storage.
+func (db database) GetWorkspaceStorageUsage(workspaceID string) (FileStorageUsage, error) {
+	rows := []struct {
+		Status FileStatus
+		Files  int64
+		Bytes  int64
+	}{}
+	err := db.db.Model(&FileAsset{}).
+		Select("status, COUNT(*) AS files, COALESCE(SUM(file_size), 0) AS bytes").
+		Where("workspace_id = ? AND status != ?", workspaceID, DeletedFileStatus).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return FileStorageUsage{}, err
+	}
+
+	usage := FileStorageUsage{WorkspaceUuid: workspaceID}
+	for _, row := range rows {
+		usage.Files += row.Files
+		usage.Bytes += row.Bytes
+		switch row.Status {
+		case ActiveFileStatus:
+			usage.ActiveFiles, usage.ActiveBytes = row.Files, row.Bytes
+		case ArchivedFileStatus:
+			usage.ArchivedFiles, usage.ArchivedBytes = row.Files, row.Bytes
+		}
+	}
+	return usage, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
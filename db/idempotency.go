@@ -0,0 +1,74 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ClaimIdempotencyKey stores key unless the same key was already used in
+// its scope by the same caller. It returns the stored key and whether this
+// call created it; when it didn't, the returned key is the earlier one.
+func (db database) ClaimIdempotencyKey(key IdempotencyKey) (IdempotencyKey, bool, error) {
+	if key.Key == "" || key.Scope == "" {
+		return IdempotencyKey{}, false, errors.New("idempotency key and scope are required")
+	}
+
+	now := time.Now()
+	key.Created = now
+	key.Updated = now
+	key.Completed = false
+
+	result := db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&key)
+	if result.Error != nil {
+		return IdempotencyKey{}, false, fmt.Errorf("failed to claim idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return key, true, nil
+	}
+
+	existing := IdempotencyKey{}
+	err := db.db.Where("idempotency_key = ? AND scope = ? AND pub_key = ?", key.Key, key.Scope, key.PubKey).
+		First(&existing).Error
+	if err != nil {
+		return IdempotencyKey{}, false, fmt.Errorf("failed to load idempotency key: %w", err)
+	}
+	return existing, false, nil
+}
+
+// CompleteIdempotencyKey stores the response of a claimed key.
+func (db database) CompleteIdempotencyKey(key IdempotencyKey) error {
+	err := db.db.Model(&IdempotencyKey{}).Where("id = ?", key.ID).Updates(map[string]interface{}{
+		"completed":       true,
+		"response_status": key.ResponseStatus,
+		"response_body":   key.ResponseBody,
+		"content_type":    key.ContentType,
+		"updated":         time.Now(),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets a claimed key whose request never
+// completed, so it can be retried.
+func (db database) ReleaseIdempotencyKey(id uint) error {
+	if err := db.db.Where("id = ?", id).Delete(&IdempotencyKey{}).Error; err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (db database) DeleteOldIdempotencyKeys(maxAge time.Duration) (int64, error) {
+	cutoffTime := time.Now().Add(-maxAge)
+
+	result := db.db.Where("created < ?", cutoffTime).Delete(&IdempotencyKey{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete old idempotency keys: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
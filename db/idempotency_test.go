@@ -0,0 +1,92 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	request := IdempotencyKey{
+		Key:         uuid.New().String(),
+		Scope:       "bounty_payment",
+		PubKey:      "owner",
+		RequestHash: "hash",
+	}
+
+	t.Run("Requires key and scope", func(t *testing.T) {
+		_, _, err := TestDB.ClaimIdempotencyKey(IdempotencyKey{Scope: "bounty_payment"})
+		assert.Error(t, err)
+	})
+
+	var claimed IdempotencyKey
+	t.Run("First claim wins", func(t *testing.T) {
+		var ok bool
+		var err error
+		claimed, ok, err = TestDB.ClaimIdempotencyKey(request)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NotZero(t, claimed.ID)
+
+		existing, ok, err := TestDB.ClaimIdempotencyKey(request)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, claimed.ID, existing.ID)
+		assert.False(t, existing.Completed)
+	})
+
+	t.Run("Keys are scoped by endpoint and caller", func(t *testing.T) {
+		other := request
+		other.Scope = "budget_withdraw"
+		_, ok, err := TestDB.ClaimIdempotencyKey(other)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		other = request
+		other.PubKey = "someone_else"
+		_, ok, err = TestDB.ClaimIdempotencyKey(other)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Completed key returns the stored response", func(t *testing.T) {
+		claimed.ResponseStatus = 200
+		claimed.ResponseBody = `{"ok":true}`
+		claimed.ContentType = "application/json"
+		assert.NoError(t, TestDB.CompleteIdempotencyKey(claimed))
+
+		existing, ok, err := TestDB.ClaimIdempotencyKey(request)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.True(t, existing.Completed)
+		assert.Equal(t, 200, existing.ResponseStatus)
+		assert.Equal(t, `{"ok":true}`, existing.ResponseBody)
+	})
+
+	t.Run("Released key can be claimed again", func(t *testing.T) {
+		assert.NoError(t, TestDB.ReleaseIdempotencyKey(claimed.ID))
+
+		_, ok, err := TestDB.ClaimIdempotencyKey(request)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Old keys are pruned", func(t *testing.T) {
+		old := IdempotencyKey{Key: uuid.New().String(), Scope: "stake", RequestHash: "hash",
+			Created: time.Now().Add(-48 * time.Hour), Updated: time.Now().Add(-48 * time.Hour)}
+		TestDB.db.Create(&old)
+
+		removed, err := TestDB.DeleteOldIdempotencyKeys(24 * time.Hour)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, removed, int64(1))
+
+		_, ok, err := TestDB.ClaimIdempotencyKey(IdempotencyKey{Key: old.Key, Scope: old.Scope, RequestHash: "hash"})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -8,6 +9,7 @@ import (
 )
 
 type Database interface {
+	WithContext(ctx context.Context) Database
 	CreateOrEditTribe(m Tribe) (Tribe, error)
 	CreateChannel(c Channel) (Channel, error)
 	CreateOrEditBot(b Bot) (Bot, error)
@@ -371,4 +373,72 @@ type Database interface {
 	GetAllBountyStakeProcesses() ([]BountyStakeProcess, error)
 	UpdateBountyStakeProcess(id uuid.UUID, updates map[string]interface{}) (*BountyStakeProcess, error)
 	DeleteBountyStakeProcess(id uuid.UUID) error
+	AppendWebsocketOutboxMessage(stream string, kind string, payload PropertyMap) (*WebsocketOutboxMessage, error)
+	GetWebsocketOutboxMessagesAfter(stream string, sequence uint64, limit int) ([]WebsocketOutboxMessage, error)
+	ClaimWebsocketOutboxStream(stream string, owner string) (string, error)
+	DeleteOldWebsocketOutboxMessages(maxAge time.Duration) (int64, error)
+	CreateWebhookSubscription(subscription WebhookSubscription) (WebhookSubscription, error)
+	UpdateWebhookSubscription(subscription WebhookSubscription) (WebhookSubscription, error)
+	GetWebhookSubscriptionByUuid(uuid string) (WebhookSubscription, error)
+	GetWebhookSubscriptionsByWorkspace(workspaceUuid string) ([]WebhookSubscription, error)
+	GetActiveWebhookSubscriptions(workspaceUuid string, event string) ([]WebhookSubscription, error)
+	DeleteWebhookSubscription(uuid string) error
+	CreateWebhookDelivery(delivery *WebhookDelivery) error
+	UpdateWebhookDelivery(delivery *WebhookDelivery) error
+	GetDueWebhookDeliveries(now time.Time, limit int) ([]WebhookDelivery, error)
+	GetWebhookDeliveries(subscriptionUuid string, limit int) ([]WebhookDelivery, error)
+	SearchWorkspace(workspaceUuid string, query string, types []string, limit int, offset int) ([]WorkspaceSearchResult, int64, error)
+	CreateAuditLog(entry *AuditLog) error
+	GetAuditLogs(filter AuditLogFilter) ([]AuditLog, int64, error)
+	GetLedgerBalances(workspace_uuid string) ([]LedgerBalance, error)
+	ReconcileLedger(workspace_uuid string) (LedgerReconciliation, error)
+	ClaimIdempotencyKey(key IdempotencyKey) (IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(key IdempotencyKey) error
+	ReleaseIdempotencyKey(id uint) error
+	DeleteOldIdempotencyKeys(maxAge time.Duration) (int64, error)
+	EnqueueJob(job *Job) (bool, error)
+	LeaseJobs(workerID string, now time.Time, lease time.Duration, limit int) ([]Job, error)
+	UpdateJob(job *Job) error
+	GetStuckJobs(now time.Time, overdue time.Duration, limit int) ([]Job, error)
+	RequeueJob(id uint) (Job, error)
+	GetWorkspacePaymentPolicy(workspace_uuid string) WorkspacePaymentPolicy
+	SaveWorkspacePaymentPolicy(policy WorkspacePaymentPolicy) (WorkspacePaymentPolicy, error)
+	GetPaymentHistoryById(id uint) (NewPaymentHistory, error)
+	RecordPaymentRetry(paymentId uint, tag string) (NewPaymentHistory, error)
+	FlagPaymentForReview(paymentId uint, reason string) error
+	ReserveBatchPayout(batch *BatchPayout) error
+	StartBatchPayment(batchID uint, itemID uint) error
+	HoldBatchPayment(batchID uint, itemID uint, tag string, reason string) error
+	GetBatchPayout(uuid string) (BatchPayout, error)
+	ReleaseBatchPayment(batchID uint, itemID uint, reason string) error
+	ProcessBatchPayment(batchID uint, item BatchPayoutItem, payment NewPaymentHistory, bounty NewBounty) (NewPaymentHistory, error)
+	FinishBatchPayout(batchID uint) (BatchPayout, error)
+	GetWorkspaceAssetBudgets(workspace_uuid string) []WorkspaceAssetBudget
+	GetWorkspaceAssetBudget(workspace_uuid string, assetId uint) WorkspaceAssetBudget
+	DepositAssetBudget(payment NewPaymentHistory) (WorkspaceAssetBudget, error)
+	GetBountyAssignees(bountyID uint) []BountyAssignee
+	SetBountyAssignees(bountyID uint, shares []BountyAssignee) ([]BountyAssignee, error)
+	ProcessBountySplitPayment(payments []BountySharePayment, bounty NewBounty) (NewBounty, []NewPaymentHistory, error)
+	GetProofByID(proofID string) (ProofOfWork, error)
+	GetBountyMilestones(bountyID uint) []BountyMilestone
+	SetBountyMilestones(bountyID uint, milestones []BountyMilestone) ([]BountyMilestone, error)
+	ProcessMilestonePayment(milestoneID uint, payment NewPaymentHistory, bounty NewBounty) (NewBounty, NewPaymentHistory, error)
+	GetWorkspaceSpendRules(workspace_uuid string) WorkspaceSpendRules
+	SaveWorkspaceSpendRules(workspace_uuid string, rules WorkspaceSpendRules) (WorkspaceSpendRules, error)
+	CheckSpendLimit(workspace_uuid string, pubkey string, role string, amount uint) error
+	TrackLowBalance(workspace_uuid string) (*Notification, error)
+	GetWorkspaceWorkflows(workspace_uuid string) []WorkspaceWorkflow
+	GetWorkspaceWorkflow(workspace_uuid string, kind string) WorkspaceWorkflow
+	SaveWorkspaceWorkflows(workspace_uuid string, workflows []WorkspaceWorkflow) ([]WorkspaceWorkflow, error)
+	GetChatSummary(chatID string) (ChatSummary, error)
+	SaveChatSummary(summary *ChatSummary) error
+	RewrapCodeSpaceSecrets() (int, error)
+	GetFileRetention(workspace_uuid string) FileRetention
+	SaveFileRetention(retention FileRetention) (FileRetention, error)
+	GetFileAssetWorkspaces() ([]string, error)
+	GetRetainedFileAssets(workspaceID string) ([]FileAsset, error)
+	CountFileAssetReferences(asset FileAsset) (int64, error)
+	UpdateFileAssetReferences(id uint, references int64) error
+	ArchiveFileAsset(id uint) error
+	GetWorkspaceStorageUsage(workspaceID string) (FileStorageUsage, error)
 }
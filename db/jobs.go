@@ -0,0 +1,154 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stakwork/sphinx-tribes/logger"
+	"gorm.io/gorm/clause"
+)
+
+var ErrJobLeaseLost = errors.New("job lease lost")
+
+// migrateJobs adds the partial unique index allowing one job per type and
+// dedupe key until it is done. Dead jobs keep holding their key, so a
+// dead-lettered job is not queued again behind the operator's back.
+func (db database) migrateJobs() {
+	err := db.db.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_dedupe_key
+			ON jobs (type, dedupe_key)
+			WHERE dedupe_key <> '' AND status <> 'done'
+	`).Error
+	if err != nil {
+		logger.Log.Error("[db] could not create job dedupe index: %v", err)
+	}
+}
+
+// EnqueueJob queues job and reports whether it was queued; it is not when
+// a job of the same type and dedupe key is queued, running or dead.
+func (db database) EnqueueJob(job *Job) (bool, error) {
+	if job.Type == "" {
+		return false, errors.New("job type is required")
+	}
+	if job.MaxAttempts < 1 {
+		return false, errors.New("job max attempts must be positive")
+	}
+	if job.Payload == "" {
+		job.Payload = "{}"
+	}
+
+	now := time.Now()
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	job.Status = JobPending
+	job.Attempts = 0
+	job.Created = now
+	job.Updated = now
+
+	result := db.db.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to enqueue job: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// LeaseJobs locks up to limit due jobs for workerID until now+lease and
+// counts the attempt. Due jobs are pending ones whose run time has come and
+// running ones whose lease expired. Rows locked by another worker's lease
+// query are skipped rather than waited on.
+func (db database) LeaseJobs(workerID string, now time.Time, lease time.Duration, limit int) ([]Job, error) {
+	jobs := []Job{}
+	lockedUntil := now.Add(lease)
+
+	err := db.db.Raw(`
+		UPDATE jobs SET status = ?, locked_by = ?, locked_until = ?, attempts = attempts + 1, updated = ?
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE (status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)
+			ORDER BY run_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		JobRunning, workerID, lockedUntil, now,
+		JobPending, now, JobRunning, now,
+		limit,
+	).Scan(&jobs).Error
+	if err != nil {
+		return []Job{}, fmt.Errorf("failed to lease jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// UpdateJob stores the outcome of a leased job. It fails with
+// ErrJobLeaseLost when the lease expired and another worker took the job.
+func (db database) UpdateJob(job *Job) error {
+	job.Updated = time.Now()
+
+	result := db.db.Model(&Job{}).
+		Where("id = ? AND locked_by = ?", job.ID, job.LockedBy).
+		Updates(map[string]interface{}{
+			"status":       job.Status,
+			"run_at":       job.RunAt,
+			"locked_by":    "",
+			"locked_until": nil,
+			"last_error":   job.LastError,
+			"updated":      job.Updated,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update job: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	job.LockedBy = ""
+	job.LockedUntil = nil
+	return nil
+}
+
+// GetStuckJobs lists dead jobs, running jobs whose lease expired and
+// pending jobs more than overdue past their run time, oldest first.
+func (db database) GetStuckJobs(now time.Time, overdue time.Duration, limit int) ([]Job, error) {
+	jobs := []Job{}
+
+	err := db.db.
+		Where("status = ?", JobDead).
+		Or("status = ? AND locked_until < ?", JobRunning, now).
+		Or("status = ? AND run_at < ?", JobPending, now.Add(-overdue)).
+		Order("run_at ASC").
+		Limit(limit).
+		Find(&jobs).Error
+	if err != nil {
+		return []Job{}, fmt.Errorf("failed to fetch stuck jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// RequeueJob gives a dead job a fresh set of attempts, starting now.
+func (db database) RequeueJob(id uint) (Job, error) {
+	job := Job{}
+	now := time.Now()
+
+	result := db.db.Model(&job).
+		Where("id = ? AND status = ?", id, JobDead).
+		Updates(map[string]interface{}{
+			"status":     JobPending,
+			"attempts":   0,
+			"run_at":     now,
+			"last_error": "",
+			"updated":    now,
+		})
+	if result.Error != nil {
+		return Job{}, fmt.Errorf("failed to requeue job: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return Job{}, errors.New("job not found or not dead")
+	}
+
+	if err := db.db.First(&job, id).Error; err != nil {
+		return Job{}, fmt.Errorf("failed to load job: %w", err)
+	}
+	return job, nil
+}
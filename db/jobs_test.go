@@ -0,0 +1,86 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobs(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	TestDB.db.Exec("DELETE FROM jobs")
+	now := time.Now()
+
+	t.Run("Dedupes unfinished jobs", func(t *testing.T) {
+		queued, err := TestDB.EnqueueJob(&Job{Type: "test", DedupeKey: "a", MaxAttempts: 3})
+		assert.NoError(t, err)
+		assert.True(t, queued)
+
+		queued, err = TestDB.EnqueueJob(&Job{Type: "test", DedupeKey: "a", MaxAttempts: 3})
+		assert.NoError(t, err)
+		assert.False(t, queued)
+
+		queued, err = TestDB.EnqueueJob(&Job{Type: "other", DedupeKey: "a", MaxAttempts: 3})
+		assert.NoError(t, err)
+		assert.True(t, queued)
+	})
+
+	var leased []Job
+	t.Run("Leases due jobs once", func(t *testing.T) {
+		var err error
+		leased, err = TestDB.LeaseJobs("worker-1", now.Add(time.Second), time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Len(t, leased, 2)
+		for _, job := range leased {
+			assert.Equal(t, JobRunning, job.Status)
+			assert.Equal(t, "worker-1", job.LockedBy)
+			assert.Equal(t, 1, job.Attempts)
+		}
+
+		again, err := TestDB.LeaseJobs("worker-2", now.Add(time.Second), time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, again)
+	})
+
+	t.Run("Expired leases are taken over and fence the old worker", func(t *testing.T) {
+		takenOver, err := TestDB.LeaseJobs("worker-2", now.Add(2*time.Minute), time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Len(t, takenOver, 2)
+		assert.Equal(t, 2, takenOver[0].Attempts)
+
+		stale := leased[0]
+		stale.Status = JobDone
+		assert.ErrorIs(t, TestDB.UpdateJob(&stale), ErrJobLeaseLost)
+
+		for i := range takenOver {
+			takenOver[i].Status = JobDead
+			takenOver[i].LastError = "boom"
+			assert.NoError(t, TestDB.UpdateJob(&takenOver[i]))
+		}
+		leased = takenOver
+	})
+
+	t.Run("Dead jobs are stuck and hold their dedupe key", func(t *testing.T) {
+		stuck, err := TestDB.GetStuckJobs(now, 15*time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Len(t, stuck, 2)
+
+		queued, err := TestDB.EnqueueJob(&Job{Type: "test", DedupeKey: "a", MaxAttempts: 3})
+		assert.NoError(t, err)
+		assert.False(t, queued)
+	})
+
+	t.Run("Requeues dead jobs only", func(t *testing.T) {
+		job, err := TestDB.RequeueJob(leased[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, JobPending, job.Status)
+		assert.Equal(t, 0, job.Attempts)
+		assert.Empty(t, job.LastError)
+
+		_, err = TestDB.RequeueJob(leased[0].ID)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,484 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stakwork/sphinx-tribes/logger"
+	"gorm.io/gorm"
+)
+
+var ErrLedgerDuplicate = errors.New("ledger transaction already posted")
+
+// pgUniqueViolation is the Postgres error code of a unique index conflict.
+const pgUniqueViolation = "23505"
+
+// ledgerLeg is one side of a ledger transaction, Amount is signed.
+type ledgerLeg struct {
+	Kind   LedgerAccountKind
+	Owner  string
+	Amount int64
+}
+
+// migrateLedger makes the ledger tables append-only, rejects transactions
+// whose entries don't sum to zero at commit, drops the account index that
+// predates asset accounts, keeps the balance of every account with its
+// entries, and opens the ledger of workspaces that had a budget before the
+// ledger existed.
+func (db database) migrateLedger() {
+	err := db.db.Exec(`
+		CREATE OR REPLACE FUNCTION ledger_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS ledger_transactions_append_only ON ledger_transactions;
+		CREATE TRIGGER ledger_transactions_append_only
+			BEFORE UPDATE OR DELETE ON ledger_transactions
+			FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+		DROP TRIGGER IF EXISTS ledger_entries_append_only ON ledger_entries;
+		CREATE TRIGGER ledger_entries_append_only
+			BEFORE UPDATE OR DELETE ON ledger_entries
+			FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+		CREATE OR REPLACE FUNCTION ledger_entries_balanced() RETURNS trigger AS $$
+		BEGIN
+			IF (SELECT SUM(amount) FROM ledger_entries WHERE transaction_id = NEW.transaction_id) <> 0 THEN
+				RAISE EXCEPTION 'ledger transaction % is not balanced', NEW.transaction_id;
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP INDEX IF EXISTS idx_ledger_account;
+
+		DROP TRIGGER IF EXISTS ledger_entries_balanced ON ledger_entries;
+		CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+			AFTER INSERT ON ledger_entries
+			DEFERRABLE INITIALLY DEFERRED
+			FOR EACH ROW EXECUTE FUNCTION ledger_entries_balanced();
+	`).Error
+	if err != nil {
+		logger.Log.Error("[db] could not install ledger triggers: %v", err)
+		return
+	}
+
+	// the balance trigger is swapped and the balances recomputed while
+	// entries are locked, so no entry lands between the two
+	err = db.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec(`
+			LOCK TABLE ledger_entries IN SHARE ROW EXCLUSIVE MODE;
+
+			CREATE OR REPLACE FUNCTION ledger_account_balance() RETURNS trigger AS $$
+			BEGIN
+				UPDATE ledger_accounts SET balance = balance + NEW.amount WHERE id = NEW.account_id;
+				RETURN NULL;
+			END;
+			$$ LANGUAGE plpgsql;
+
+			DROP TRIGGER IF EXISTS ledger_entries_account_balance ON ledger_entries;
+			CREATE TRIGGER ledger_entries_account_balance
+				AFTER INSERT ON ledger_entries
+				FOR EACH ROW EXECUTE FUNCTION ledger_account_balance();
+
+			UPDATE ledger_accounts AS account SET balance = sums.balance
+			FROM (SELECT account.id, COALESCE(SUM(entry.amount), 0) AS balance
+				FROM ledger_accounts AS account
+				LEFT OUTER JOIN ledger_entries AS entry ON entry.account_id = account.id
+				GROUP BY account.id) AS sums
+			WHERE sums.id = account.id AND account.balance <> sums.balance;
+		`).Error
+	})
+	if err != nil {
+		logger.Log.Error("[db] could not install ledger balances: %v", err)
+		return
+	}
+
+	budgets := []NewBountyBudget{}
+	db.db.Raw(`SELECT * FROM new_bounty_budgets AS budget
+		WHERE budget.total_budget > 0
+		AND NOT EXISTS (SELECT 1 FROM ledger_transactions WHERE workspace_uuid = budget.workspace_uuid)`).Find(&budgets)
+
+	for _, budget := range budgets {
+		err := db.db.Transaction(func(tx *gorm.DB) error {
+			return postLedgerTransaction(tx, LedgerTransaction{
+				WorkspaceUuid: budget.WorkspaceUuid,
+				Reference:     "opening:" + budget.WorkspaceUuid,
+				Kind:          "opening",
+			}, ledgerMove(LedgerExternal, "", LedgerWorkspace, "", budget.TotalBudget)...)
+		})
+		if err != nil {
+			logger.Log.Error("[db] could not open ledger of workspace %s: %v", budget.WorkspaceUuid, err)
+		}
+	}
+}
+
+// ledgerMove moves amount from one account of a workspace to another.
+func ledgerMove(fromKind LedgerAccountKind, fromOwner string, toKind LedgerAccountKind, toOwner string, amount uint) []ledgerLeg {
+	return []ledgerLeg{
+		{Kind: fromKind, Owner: fromOwner, Amount: -int64(amount)},
+		{Kind: toKind, Owner: toOwner, Amount: int64(amount)},
+	}
+}
+
+// postLedgerTransaction records a balanced transaction within tx, creating
+// the accounts it touches on first use. A reference that was already
+// posted returns ErrLedgerDuplicate so the caller can roll back.
+func postLedgerTransaction(tx *gorm.DB, txn LedgerTransaction, legs ...ledgerLeg) error {
+	if txn.WorkspaceUuid == "" || txn.Reference == "" {
+		return errors.New("ledger transaction needs a workspace and a reference")
+	}
+	if len(legs) < 2 {
+		return errors.New("ledger transaction needs at least two entries")
+	}
+
+	var sum int64
+	for _, leg := range legs {
+		sum += leg.Amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("ledger transaction %s is not balanced: %d", txn.Reference, sum)
+	}
+
+	var existing int64
+	if err := tx.Model(&LedgerTransaction{}).Where("reference = ?", txn.Reference).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return fmt.Errorf("%w: %s", ErrLedgerDuplicate, txn.Reference)
+	}
+
+	now := time.Now()
+	txn.Created = now
+	txn.Entries = nil
+	if err := tx.Create(&txn).Error; err != nil {
+		// the count above misses a concurrent post of the same reference
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return fmt.Errorf("%w: %s", ErrLedgerDuplicate, txn.Reference)
+		}
+		return fmt.Errorf("failed to create ledger transaction: %w", err)
+	}
+
+	entries := make([]LedgerEntry, 0, len(legs))
+	for _, leg := range legs {
+		// the conditions are spelled out, a struct condition would skip
+		// the empty owner and the sats asset id
+		account := LedgerAccount{WorkspaceUuid: txn.WorkspaceUuid, Kind: leg.Kind, Owner: leg.Owner, AssetId: txn.AssetId, Created: now}
+		err := tx.Where("workspace_uuid = ? AND kind = ? AND owner = ? AND asset_id = ?", account.WorkspaceUuid, account.Kind, account.Owner, account.AssetId).
+			FirstOrCreate(&account).Error
+		if err != nil {
+			return fmt.Errorf("failed to open ledger account: %w", err)
+		}
+		entries = append(entries, LedgerEntry{
+			TransactionID: txn.ID,
+			AccountID:     account.ID,
+			Amount:        leg.Amount,
+			Created:       now,
+		})
+	}
+
+	if err := tx.Create(&entries).Error; err != nil {
+		return fmt.Errorf("failed to create ledger entries: %w", err)
+	}
+	return nil
+}
+
+func postLedgerDeposit(tx *gorm.DB, payment NewPaymentHistory) error {
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: payment.WorkspaceUuid,
+		Reference:     fmt.Sprintf("deposit:%d", payment.ID),
+		AssetId:       payment.AssetId,
+		Kind:          string(Deposit),
+		PaymentID:     payment.ID,
+	}, ledgerMove(LedgerExternal, "", LedgerWorkspace, "", payment.Amount)...)
+}
+
+// postLedgerAssetDeposit funds an asset budget. The reference is the
+// transfer's transaction id, so a transfer funds a budget only once.
+func postLedgerAssetDeposit(tx *gorm.DB, payment NewPaymentHistory) error {
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: payment.WorkspaceUuid,
+		Reference:     "asset-deposit:" + payment.Tag,
+		Kind:          string(Deposit),
+		PaymentID:     payment.ID,
+		AssetId:       payment.AssetId,
+	}, ledgerMove(LedgerExternal, "", LedgerWorkspace, "", payment.Amount)...)
+}
+
+func postLedgerWithdrawal(tx *gorm.DB, payment NewPaymentHistory) error {
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: payment.WorkspaceUuid,
+		Reference:     fmt.Sprintf("withdraw:%d", payment.ID),
+		AssetId:       payment.AssetId,
+		Kind:          string(Withdraw),
+		PaymentID:     payment.ID,
+	}, ledgerMove(LedgerWorkspace, "", LedgerExternal, "", payment.Amount)...)
+}
+
+// postLedgerBountyPayment moves a bounty payment out of the workspace
+// budget, to the hunter when it settled and to escrow while pending.
+func postLedgerBountyPayment(tx *gorm.DB, payment NewPaymentHistory) error {
+	toKind, toOwner := LedgerHunter, payment.ReceiverPubKey
+	if payment.PaymentStatus == PaymentPending {
+		toKind, toOwner = LedgerEscrow, ""
+	}
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: payment.WorkspaceUuid,
+		Reference:     fmt.Sprintf("payment:%d", payment.ID),
+		AssetId:       payment.AssetId,
+		Kind:          string(Payment),
+		PaymentID:     payment.ID,
+		BountyID:      payment.BountyId,
+	}, ledgerMove(LedgerWorkspace, "", toKind, toOwner, payment.Amount)...)
+}
+
+// postLedgerSettlement releases a pending bounty payment from escrow to
+// the hunter.
+func postLedgerSettlement(tx *gorm.DB, payment NewPaymentHistory) error {
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: payment.WorkspaceUuid,
+		Reference:     fmt.Sprintf("settle:%d", payment.ID),
+		AssetId:       payment.AssetId,
+		Kind:          "settle",
+		PaymentID:     payment.ID,
+		BountyID:      payment.BountyId,
+	}, ledgerMove(LedgerEscrow, "", LedgerHunter, payment.ReceiverPubKey, payment.Amount)...)
+}
+
+// postLedgerReversal returns a bounty payment to the workspace budget,
+// from escrow when it was still pending and from the hunter otherwise.
+func postLedgerReversal(tx *gorm.DB, payment NewPaymentHistory, wasPending bool) error {
+	fromKind, fromOwner := LedgerHunter, payment.ReceiverPubKey
+	if wasPending {
+		fromKind, fromOwner = LedgerEscrow, ""
+	}
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: payment.WorkspaceUuid,
+		Reference:     fmt.Sprintf("reversal:%d", payment.ID),
+		AssetId:       payment.AssetId,
+		Kind:          string(Reversal),
+		PaymentID:     payment.ID,
+		BountyID:      payment.BountyId,
+	}, ledgerMove(fromKind, fromOwner, LedgerWorkspace, "", payment.Amount)...)
+}
+
+// batchEscrowOwner names the escrow account holding the reservation of a
+// batch payout.
+func batchEscrowOwner(batch BatchPayout) string {
+	return "batch:" + batch.Uuid
+}
+
+// postLedgerBatchReserve moves the reservation of a batch payout out of the
+// workspace budget into the batch's escrow.
+func postLedgerBatchReserve(tx *gorm.DB, batch BatchPayout) error {
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: batch.WorkspaceUuid,
+		Reference:     fmt.Sprintf("batch:%s", batch.Uuid),
+		Kind:          "batch_reserve",
+	}, ledgerMove(LedgerWorkspace, "", LedgerEscrow, batchEscrowOwner(batch), batch.Reserved)...)
+}
+
+// postLedgerBatchPayment pays a bounty from the batch's escrow, to the
+// hunter when it settled and to the pending escrow otherwise, where it
+// settles or reverses like any other payment.
+func postLedgerBatchPayment(tx *gorm.DB, batch BatchPayout, payment NewPaymentHistory) error {
+	toKind, toOwner := LedgerHunter, payment.ReceiverPubKey
+	if payment.PaymentStatus == PaymentPending {
+		toKind, toOwner = LedgerEscrow, ""
+	}
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: payment.WorkspaceUuid,
+		Reference:     fmt.Sprintf("payment:%d", payment.ID),
+		AssetId:       payment.AssetId,
+		Kind:          string(Payment),
+		PaymentID:     payment.ID,
+		BountyID:      payment.BountyId,
+	}, ledgerMove(LedgerEscrow, batchEscrowOwner(batch), toKind, toOwner, payment.Amount)...)
+}
+
+// postLedgerBatchRelease returns what a batch payout left unspent to the
+// workspace budget.
+func postLedgerBatchRelease(tx *gorm.DB, batch BatchPayout, amount uint) error {
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: batch.WorkspaceUuid,
+		Reference:     fmt.Sprintf("batch-release:%s", batch.Uuid),
+		Kind:          "batch_release",
+	}, ledgerMove(LedgerEscrow, batchEscrowOwner(batch), LedgerWorkspace, "", amount)...)
+}
+
+// postLedgerBatchItemRelease returns the amount of one batch item whose
+// payment failed after its batch had finished.
+func postLedgerBatchItemRelease(tx *gorm.DB, batch BatchPayout, item BatchPayoutItem) error {
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: batch.WorkspaceUuid,
+		Reference:     fmt.Sprintf("batch-release:%s:%d", batch.Uuid, item.ID),
+		Kind:          "batch_release",
+		BountyID:      item.BountyID,
+	}, ledgerMove(LedgerEscrow, batchEscrowOwner(batch), LedgerWorkspace, "", item.Amount)...)
+}
+
+// milestoneEscrowOwner names the escrow account holding what the
+// milestones of a bounty have reserved.
+func milestoneEscrowOwner(bountyID uint) string {
+	return fmt.Sprintf("milestones:%d", bountyID)
+}
+
+// postLedgerMilestoneReserve moves the amount of a milestone out of the
+// workspace budget into its bounty's milestone escrow.
+func postLedgerMilestoneReserve(tx *gorm.DB, workspace_uuid string, milestone BountyMilestone) error {
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: workspace_uuid,
+		Reference:     fmt.Sprintf("milestone:%d", milestone.ID),
+		Kind:          "milestone_reserve",
+		BountyID:      milestone.BountyID,
+	}, ledgerMove(LedgerWorkspace, "", LedgerEscrow, milestoneEscrowOwner(milestone.BountyID), milestone.Amount)...)
+}
+
+// postLedgerMilestoneRelease returns the reservation of a milestone that
+// was removed unpaid to the workspace budget.
+func postLedgerMilestoneRelease(tx *gorm.DB, workspace_uuid string, milestone BountyMilestone) error {
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: workspace_uuid,
+		Reference:     fmt.Sprintf("milestone-release:%d", milestone.ID),
+		Kind:          "milestone_release",
+		BountyID:      milestone.BountyID,
+	}, ledgerMove(LedgerEscrow, milestoneEscrowOwner(milestone.BountyID), LedgerWorkspace, "", milestone.Amount)...)
+}
+
+// postLedgerMilestonePayment pays a milestone from its bounty's milestone
+// escrow, to the hunter when it settled and to the pending escrow
+// otherwise.
+func postLedgerMilestonePayment(tx *gorm.DB, payment NewPaymentHistory) error {
+	toKind, toOwner := LedgerHunter, payment.ReceiverPubKey
+	if payment.PaymentStatus == PaymentPending {
+		toKind, toOwner = LedgerEscrow, ""
+	}
+	return postLedgerTransaction(tx, LedgerTransaction{
+		WorkspaceUuid: payment.WorkspaceUuid,
+		Reference:     fmt.Sprintf("payment:%d", payment.ID),
+		Kind:          string(Payment),
+		PaymentID:     payment.ID,
+		BountyID:      payment.BountyId,
+	}, ledgerMove(LedgerEscrow, milestoneEscrowOwner(payment.BountyId), toKind, toOwner, payment.Amount)...)
+}
+
+// ledgerWorkspaceBalance returns the budget of a workspace in an asset,
+// sats for asset 0, from the balance of its workspace account.
+func ledgerWorkspaceBalance(tx *gorm.DB, workspace_uuid string, assetId uint) (int64, error) {
+	var balance int64
+	err := tx.Model(&LedgerAccount{}).Select("COALESCE(SUM(balance), 0)").
+		Where("workspace_uuid = ? AND kind = ? AND asset_id = ?", workspace_uuid, LedgerWorkspace, assetId).
+		Scan(&balance).Error
+	return balance, err
+}
+
+// ledgerBudget is the budget of a workspace in an asset as the ledger holds
+// it. The stored budget is returned when the ledger can't be read.
+func (db database) ledgerBudget(workspace_uuid string, assetId uint, stored uint) uint {
+	balance, err := ledgerWorkspaceBalance(db.db, workspace_uuid, assetId)
+	if err != nil {
+		db.log().Error("[ledger] could not read the budget of %s in asset %d: %v", workspace_uuid, assetId, err)
+		return stored
+	}
+	if balance < 0 {
+		return 0
+	}
+	return uint(balance)
+}
+
+// GetLedgerBalances derives the balance of every ledger account of a
+// workspace from its entries.
+func (db database) GetLedgerBalances(workspace_uuid string) ([]LedgerBalance, error) {
+	balances := []LedgerBalance{}
+	if workspace_uuid == "" {
+		return balances, errors.New("workspace uuid is required")
+	}
+
+	err := db.db.Raw(`SELECT account.id AS account_id, account.kind, account.owner, account.asset_id, COALESCE(SUM(entry.amount), 0) AS balance
+		FROM ledger_accounts AS account
+		LEFT OUTER JOIN ledger_entries AS entry ON entry.account_id = account.id
+		WHERE account.workspace_uuid = ?
+		GROUP BY account.id, account.kind, account.owner, account.asset_id
+		ORDER BY account.asset_id, account.kind, account.owner`, workspace_uuid).Scan(&balances).Error
+	if err != nil {
+		return []LedgerBalance{}, fmt.Errorf("failed to derive ledger balances: %w", err)
+	}
+	return balances, nil
+}
+
+// ReconcileLedger compares the workspace budgets summed from the ledger
+// entries, in sats and in every asset, with the stored budget columns and
+// lists transactions whose entries don't balance.
+func (db database) ReconcileLedger(workspace_uuid string) (LedgerReconciliation, error) {
+	balances, err := db.GetLedgerBalances(workspace_uuid)
+	if err != nil {
+		return LedgerReconciliation{}, err
+	}
+
+	stored := NewBountyBudget{}
+	db.db.Where("workspace_uuid = ?", workspace_uuid).Find(&stored)
+	storedAssets := []WorkspaceAssetBudget{}
+	db.db.Where("workspace_uuid = ?", workspace_uuid).Find(&storedAssets)
+
+	report := LedgerReconciliation{
+		WorkspaceUuid:          workspace_uuid,
+		StoredBudget:           stored.TotalBudget,
+		Balances:               balances,
+		Assets:                 []AssetLedgerReconciliation{},
+		UnbalancedTransactions: []string{},
+	}
+
+	assets := map[uint]*AssetLedgerReconciliation{}
+	for _, budget := range storedAssets {
+		assets[budget.AssetId] = &AssetLedgerReconciliation{AssetId: budget.AssetId, StoredBudget: budget.Balance}
+	}
+	for _, balance := range balances {
+		if balance.AssetId != 0 {
+			asset, ok := assets[balance.AssetId]
+			if !ok {
+				asset = &AssetLedgerReconciliation{AssetId: balance.AssetId}
+				assets[balance.AssetId] = asset
+			}
+			switch balance.Kind {
+			case LedgerWorkspace:
+				asset.LedgerBudget += balance.Balance
+			case LedgerEscrow:
+				asset.Escrow += balance.Balance
+			}
+			continue
+		}
+		switch balance.Kind {
+		case LedgerWorkspace:
+			report.LedgerBudget += balance.Balance
+		case LedgerEscrow:
+			report.Escrow += balance.Balance
+		}
+	}
+
+	err = db.db.Raw(`SELECT txn.reference FROM ledger_transactions AS txn
+		LEFT OUTER JOIN ledger_entries AS entry ON entry.transaction_id = txn.id
+		WHERE txn.workspace_uuid = ?
+		GROUP BY txn.id, txn.reference
+		HAVING COALESCE(SUM(entry.amount), 0) <> 0 OR COUNT(entry.id) < 2
+		ORDER BY txn.id`, workspace_uuid).Scan(&report.UnbalancedTransactions).Error
+	if err != nil {
+		return LedgerReconciliation{}, fmt.Errorf("failed to check ledger transactions: %w", err)
+	}
+
+	report.Drift = int64(report.StoredBudget) - report.LedgerBudget
+	report.InSync = report.Drift == 0 && len(report.UnbalancedTransactions) == 0
+
+	for _, asset := range assets {
+		asset.Drift = int64(asset.StoredBudget) - asset.LedgerBudget
+		report.InSync = report.InSync && asset.Drift == 0
+		report.Assets = append(report.Assets, *asset)
+	}
+	sort.Slice(report.Assets, func(i, j int) bool { return report.Assets[i].AssetId < report.Assets[j].AssetId })
+	return report, nil
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestLedger(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	workspaceUuid := uuid.New().String()
+	hunter := "ledger_test_hunter"
+
+	balanceOf := func(kind LedgerAccountKind, owner string) int64 {
+		balances, err := TestDB.GetLedgerBalances(workspaceUuid)
+		assert.NoError(t, err)
+		for _, balance := range balances {
+			if balance.Kind == kind && balance.Owner == owner {
+				return balance.Balance
+			}
+		}
+		return 0
+	}
+
+	t.Run("Deposit credits the workspace", func(t *testing.T) {
+		now := time.Now()
+		invoice := NewInvoiceList{
+			WorkspaceUuid:  workspaceUuid,
+			PaymentRequest: "ledger_test_" + uuid.New().String(),
+			Created:        &now,
+			Type:           "BUDGET",
+		}
+		TestDB.db.Create(&invoice)
+		TestDB.db.Create(&NewPaymentHistory{
+			WorkspaceUuid: workspaceUuid,
+			Amount:        5000,
+			PaymentType:   Deposit,
+			Created:       &now,
+		})
+
+		TestDB.AddAndUpdateBudget(invoice)
+
+		assert.Equal(t, int64(5000), balanceOf(LedgerWorkspace, ""))
+		assert.Equal(t, int64(-5000), balanceOf(LedgerExternal, ""))
+	})
+
+	t.Run("Pending payment is held in escrow until settled", func(t *testing.T) {
+		now := time.Now()
+		tag := uuid.New().String()
+		err := TestDB.ProcessBountyPayment(NewPaymentHistory{
+			WorkspaceUuid:  workspaceUuid,
+			Amount:         1500,
+			BountyId:       1,
+			PaymentType:    Payment,
+			PaymentStatus:  PaymentPending,
+			ReceiverPubKey: hunter,
+			Tag:            tag,
+			Status:         true,
+			Created:        &now,
+		}, NewBounty{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1500), balanceOf(LedgerEscrow, ""))
+		assert.Equal(t, int64(3500), balanceOf(LedgerWorkspace, ""))
+
+		assert.True(t, TestDB.SetPaymentAsComplete(tag))
+		assert.Equal(t, int64(0), balanceOf(LedgerEscrow, ""))
+		assert.Equal(t, int64(1500), balanceOf(LedgerHunter, hunter))
+
+		// completing again must not release escrow twice
+		assert.True(t, TestDB.SetPaymentAsComplete(tag))
+		assert.Equal(t, int64(1500), balanceOf(LedgerHunter, hunter))
+	})
+
+	t.Run("Withdrawal debits the workspace", func(t *testing.T) {
+		TestDB.WithdrawBudget("owner", workspaceUuid, 500)
+
+		assert.Equal(t, int64(3000), balanceOf(LedgerWorkspace, ""))
+	})
+
+	t.Run("Reconciliation is in sync", func(t *testing.T) {
+		report, err := TestDB.ReconcileLedger(workspaceUuid)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(3000), report.StoredBudget)
+		assert.Equal(t, int64(3000), report.LedgerBudget)
+		assert.True(t, report.InSync)
+
+		balance, err := ledgerWorkspaceBalance(TestDB.db, workspaceUuid, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, report.LedgerBudget, balance)
+		assert.Equal(t, uint(3000), TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget)
+	})
+
+	t.Run("Reconciliation flags drift", func(t *testing.T) {
+		TestDB.UpdateWorkspaceBudget(NewBountyBudget{WorkspaceUuid: workspaceUuid, TotalBudget: 3200})
+
+		report, err := TestDB.ReconcileLedger(workspaceUuid)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(200), report.Drift)
+		assert.False(t, report.InSync)
+
+		// reads keep following the ledger
+		assert.Equal(t, uint(3000), TestDB.GetWorkspaceBudget(workspaceUuid).TotalBudget)
+	})
+
+	t.Run("Rejects unbalanced and duplicate transactions", func(t *testing.T) {
+		txn := LedgerTransaction{WorkspaceUuid: workspaceUuid, Reference: "ledger_test:" + uuid.New().String(), Kind: "test"}
+
+		err := TestDB.db.Transaction(func(tx *gorm.DB) error {
+			return postLedgerTransaction(tx, txn,
+				ledgerLeg{Kind: LedgerWorkspace, Amount: -10},
+				ledgerLeg{Kind: LedgerExternal, Amount: 5})
+		})
+		assert.Error(t, err)
+
+		err = TestDB.db.Transaction(func(tx *gorm.DB) error {
+			return postLedgerTransaction(tx, txn, ledgerMove(LedgerWorkspace, "", LedgerExternal, "", 10)...)
+		})
+		assert.NoError(t, err)
+
+		err = TestDB.db.Transaction(func(tx *gorm.DB) error {
+			return postLedgerTransaction(tx, txn, ledgerMove(LedgerWorkspace, "", LedgerExternal, "", 10)...)
+		})
+		assert.ErrorIs(t, err, ErrLedgerDuplicate)
+	})
+
+	t.Run("Entries are append-only", func(t *testing.T) {
+		assert.Error(t, TestDB.db.Exec("UPDATE ledger_entries SET amount = 0").Error)
+		assert.Error(t, TestDB.db.Exec("DELETE FROM ledger_transactions").Error)
+	})
+}
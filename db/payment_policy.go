@@ -0,0 +1,116 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultPendingTimeoutHours keeps the week the payment cron used to wait
+// before reversing a pending payment.
+const DefaultPendingTimeoutHours = 7 * 24
+
+var ErrPaymentNotPending = errors.New("payment is not pending")
+
+// DefaultPaymentPolicy is the policy of a workspace that never set one:
+// reverse failed payments and payments pending for a week, silently.
+func DefaultPaymentPolicy(workspaceUuid string) WorkspacePaymentPolicy {
+	return WorkspacePaymentPolicy{
+		WorkspaceUuid:       workspaceUuid,
+		PendingTimeoutHours: DefaultPendingTimeoutHours,
+		Action:              PaymentPolicyReverse,
+	}
+}
+
+func (p WorkspacePaymentPolicy) PendingTimeout() time.Duration {
+	return time.Duration(p.PendingTimeoutHours) * time.Hour
+}
+
+// GetWorkspacePaymentPolicy returns the payment policy of the workspace, or
+// the default one when it has none.
+func (db database) GetWorkspacePaymentPolicy(workspace_uuid string) WorkspacePaymentPolicy {
+	policy := WorkspacePaymentPolicy{}
+	err := db.db.Where("workspace_uuid = ?", workspace_uuid).First(&policy).Error
+	if err != nil {
+		return DefaultPaymentPolicy(workspace_uuid)
+	}
+	return policy
+}
+
+func (db database) SaveWorkspacePaymentPolicy(policy WorkspacePaymentPolicy) (WorkspacePaymentPolicy, error) {
+	if policy.WorkspaceUuid == "" {
+		return WorkspacePaymentPolicy{}, errors.New("workspace uuid is required")
+	}
+
+	now := time.Now()
+	policy.ID = 0
+	policy.Created = now
+	policy.Updated = now
+
+	err := db.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "workspace_uuid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"pending_timeout_hours", "action", "max_retries", "notify_owner", "updated"}),
+	}).Create(&policy).Error
+	if err != nil {
+		return WorkspacePaymentPolicy{}, fmt.Errorf("failed to save payment policy: %w", err)
+	}
+	return db.GetWorkspacePaymentPolicy(policy.WorkspaceUuid), nil
+}
+
+func (db database) GetPaymentHistoryById(id uint) (NewPaymentHistory, error) {
+	payment := NewPaymentHistory{}
+	if err := db.db.Where("id = ?", id).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewPaymentHistory{}, fmt.Errorf("payment %d not found", id)
+		}
+		return NewPaymentHistory{}, err
+	}
+	return payment, nil
+}
+
+// RecordPaymentRetry stores the tag of the send retrying a pending
+// payment, counts the retry and clears a review flag. The replaced tag is
+// kept in PreviousTags. The pending timeout counts again from the retry on.
+func (db database) RecordPaymentRetry(paymentId uint, tag string) (NewPaymentHistory, error) {
+	now := time.Now()
+
+	result := db.db.Model(&NewPaymentHistory{}).
+		Where("id = ? AND payment_status = ?", paymentId, PaymentPending).
+		Updates(map[string]interface{}{
+			"tag":           tag,
+			"previous_tags": gorm.Expr("CASE WHEN COALESCE(tag, '') IN ('', ?) THEN previous_tags ELSE array_append(COALESCE(previous_tags, '{}'::text[]), tag) END", tag),
+			"retries":       gorm.Expr("retries + 1"),
+			"needs_review":  false,
+			"error":         "",
+			"updated":       now,
+		})
+	if result.Error != nil {
+		return NewPaymentHistory{}, fmt.Errorf("failed to record payment retry: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return NewPaymentHistory{}, ErrPaymentNotPending
+	}
+	return db.GetPaymentHistoryById(paymentId)
+}
+
+// FlagPaymentForReview keeps a pending payment out of the automatic checks
+// until the workspace owner retries it.
+func (db database) FlagPaymentForReview(paymentId uint, reason string) error {
+	result := db.db.Model(&NewPaymentHistory{}).
+		Where("id = ? AND payment_status = ?", paymentId, PaymentPending).
+		Updates(map[string]interface{}{
+			"needs_review": true,
+			"error":        reason,
+			"updated":      time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to flag payment for review: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPaymentNotPending
+	}
+	return nil
+}
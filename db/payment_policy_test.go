@@ -0,0 +1,66 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspacePaymentPolicy(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	workspaceUuid := uuid.New().String()
+
+	t.Run("Defaults to reversing after a week", func(t *testing.T) {
+		policy := TestDB.GetWorkspacePaymentPolicy(workspaceUuid)
+		assert.Equal(t, PaymentPolicyReverse, policy.Action)
+		assert.Equal(t, 7*24*time.Hour, policy.PendingTimeout())
+	})
+
+	t.Run("Saving twice updates the policy", func(t *testing.T) {
+		_, err := TestDB.SaveWorkspacePaymentPolicy(WorkspacePaymentPolicy{WorkspaceUuid: workspaceUuid, PendingTimeoutHours: 24, Action: PaymentPolicyReview})
+		assert.NoError(t, err)
+
+		saved, err := TestDB.SaveWorkspacePaymentPolicy(WorkspacePaymentPolicy{WorkspaceUuid: workspaceUuid, PendingTimeoutHours: 48, Action: PaymentPolicyRetry, MaxRetries: 2, NotifyOwner: true})
+		assert.NoError(t, err)
+		assert.Equal(t, 48, saved.PendingTimeoutHours)
+		assert.Equal(t, PaymentPolicyRetry, saved.Action)
+		assert.True(t, saved.NotifyOwner)
+
+		var count int64
+		TestDB.db.Model(&WorkspacePaymentPolicy{}).Where("workspace_uuid = ?", workspaceUuid).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Retries and reviews pending payments only", func(t *testing.T) {
+		created := time.Now().Add(-time.Hour)
+		payment := NewPaymentHistory{Amount: 100, BountyId: 1, WorkspaceUuid: workspaceUuid, PaymentType: Payment,
+			Tag: "first", PaymentStatus: PaymentPending, Status: true, Created: &created, Updated: &created}
+		TestDB.db.Create(&payment)
+
+		assert.NoError(t, TestDB.FlagPaymentForReview(payment.ID, "Payment failed"))
+		flagged, err := TestDB.GetPaymentHistoryById(payment.ID)
+		assert.NoError(t, err)
+		assert.True(t, flagged.NeedsReview)
+
+		for _, pending := range TestDB.GetPendingPaymentHistory() {
+			assert.NotEqual(t, payment.ID, pending.ID, "payments under review are not checked")
+		}
+
+		retried, err := TestDB.RecordPaymentRetry(payment.ID, "second")
+		assert.NoError(t, err)
+		assert.Equal(t, "second", retried.Tag)
+		assert.Equal(t, []string{"first"}, []string(retried.PreviousTags))
+		assert.Equal(t, 1, retried.Retries)
+		assert.False(t, retried.NeedsReview)
+		assert.True(t, retried.Updated.After(created))
+
+		TestDB.db.Model(&NewPaymentHistory{}).Where("id = ?", payment.ID).Update("payment_status", PaymentComplete)
+		_, err = TestDB.RecordPaymentRetry(payment.ID, "third")
+		assert.ErrorIs(t, err, ErrPaymentNotPending)
+		assert.ErrorIs(t, TestDB.FlagPaymentForReview(payment.ID, "late"), ErrPaymentNotPending)
+	})
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+const (
+	SearchEntityFeature  = "feature"
+	SearchEntityPhase    = "phase"
+	SearchEntityTicket   = "ticket"
+	SearchEntityActivity = "activity"
+	SearchEntitySnippet  = "snippet"
+)
+
+// SearchEntityTypes lists every entity type covered by workspace search.
+var SearchEntityTypes = []string{
+	SearchEntityFeature,
+	SearchEntityPhase,
+	SearchEntityTicket,
+	SearchEntityActivity,
+	SearchEntitySnippet,
+}
+
+// searchSource describes how one table takes part in workspace search.
+// vector is also the expression of the table's GIN index, so the two must
+// stay identical for the planner to use the index.
+type searchSource struct {
+	entity string
+	table  string
+	vector string
+	query  string
+}
+
+var searchSources = []searchSource{
+	{
+		entity: SearchEntityFeature,
+		table:  "workspace_features",
+		vector: `(setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(brief, '') || ' ' || coalesce(requirements, '') || ' ' || coalesce(architecture, '')), 'B'))`,
+		query: `SELECT 'feature' AS entity_type, uuid AS entity_id, name AS title,
+			uuid AS feature_uuid, '' AS phase_uuid,
+			coalesce(brief, '') || ' ' || coalesce(requirements, '') || ' ' || coalesce(architecture, '') AS body,
+			ts_rank(%[1]s, q.query) AS rank
+			FROM workspace_features, q
+			WHERE workspace_uuid = @workspace AND %[1]s @@ q.query`,
+	},
+	{
+		entity: SearchEntityPhase,
+		table:  "feature_phases",
+		vector: `(setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(phase_purpose, '') || ' ' || coalesce(phase_outcome, '') || ' ' || coalesce(phase_scope, '') || ' ' || coalesce(phase_design, '')), 'B'))`,
+		query: `SELECT 'phase' AS entity_type, p.uuid AS entity_id, p.name AS title,
+			p.feature_uuid AS feature_uuid, p.uuid AS phase_uuid,
+			coalesce(p.phase_purpose, '') || ' ' || coalesce(p.phase_outcome, '') || ' ' || coalesce(p.phase_scope, '') || ' ' || coalesce(p.phase_design, '') AS body,
+			ts_rank(%[1]s, q.query) AS rank
+			FROM feature_phases p
+			INNER JOIN workspace_features f ON f.uuid = p.feature_uuid, q
+			WHERE f.workspace_uuid = @workspace AND %[1]s @@ q.query`,
+	},
+	{
+		entity: SearchEntityTicket,
+		table:  "tickets",
+		vector: `(setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(description, '')), 'B'))`,
+		query: `SELECT 'ticket' AS entity_type, uuid::text AS entity_id, name AS title,
+			coalesce(feature_uuid, '') AS feature_uuid, coalesce(phase_uuid, '') AS phase_uuid,
+			coalesce(description, '') AS body,
+			ts_rank(%[1]s, q.query) AS rank
+			FROM tickets, q
+			WHERE (workspace_uuid = @workspace OR feature_uuid IN (SELECT uuid FROM workspace_features WHERE workspace_uuid = @workspace))
+			AND %[1]s @@ q.query`,
+	},
+	{
+		entity: SearchEntityActivity,
+		table:  "activities",
+		vector: `(setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(content, '')), 'B'))`,
+		query: `SELECT 'activity' AS entity_type, id::text AS entity_id, coalesce(title, '') AS title,
+			coalesce(feature_uuid, '') AS feature_uuid, coalesce(phase_uuid, '') AS phase_uuid,
+			coalesce(content, '') AS body,
+			ts_rank(%[1]s, q.query) AS rank
+			FROM activities, q
+			WHERE workspace = @workspace AND %[1]s @@ q.query`,
+	},
+	{
+		entity: SearchEntitySnippet,
+		table:  "text_snippets",
+		vector: `(setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(snippet, '')), 'B'))`,
+		query: `SELECT 'snippet' AS entity_type, id::text AS entity_id, title,
+			'' AS feature_uuid, '' AS phase_uuid,
+			coalesce(snippet, '') AS body,
+			ts_rank(%[1]s, q.query) AS rank
+			FROM text_snippets, q
+			WHERE workspace_uuid = @workspace AND %[1]s @@ q.query`,
+	},
+}
+
+// highlightOptions wraps matches in <mark> tags and keeps a couple of short
+// fragments around them.
+const highlightOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=\" … \""
+
+func ValidSearchEntityType(entity string) bool {
+	for _, e := range SearchEntityTypes {
+		if e == entity {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateSearchIndexes creates the GIN expression indexes backing
+// SearchWorkspace. Unlike tribes and bots, these tables keep no tsv column,
+// so every write path stays untouched.
+func (db database) migrateSearchIndexes() {
+	for _, source := range searchSources {
+		index := fmt.Sprintf("idx_%s_search", source.table)
+		if err := db.db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (%s)", index, source.table, source.vector)).Error; err != nil {
+			logger.Log.Error("[db] could not create search index %s: %v", index, err)
+		}
+	}
+}
+
+// SearchWorkspace runs a ranked full-text search across the planning data
+// of a workspace. An empty types list searches every entity type. The total
+// is the number of matches before limit and offset are applied.
+func (db database) SearchWorkspace(workspaceUuid string, query string, types []string, limit int, offset int) ([]WorkspaceSearchResult, int64, error) {
+	results := []WorkspaceSearchResult{}
+
+	if strings.TrimSpace(workspaceUuid) == "" {
+		return results, 0, errors.New("workspace uuid is required")
+	}
+	if strings.TrimSpace(query) == "" {
+		return results, 0, nil
+	}
+
+	var selects []string
+	for _, source := range searchSources {
+		if len(types) > 0 && !containsString(types, source.entity) {
+			continue
+		}
+		selects = append(selects, fmt.Sprintf(source.query, source.vector))
+	}
+	if len(selects) == 0 {
+		return results, 0, errors.New("no valid entity types to search")
+	}
+
+	// headlines are expensive, so they are only built for the page returned
+	sql := `WITH q AS (SELECT websearch_to_tsquery('english', @query) AS query),
+		hits AS (` + strings.Join(selects, "\nUNION ALL\n") + `),
+		page AS (
+			SELECT *, count(*) OVER () AS total FROM hits
+			ORDER BY rank DESC, entity_type, entity_id
+			LIMIT @limit OFFSET @offset
+		)
+		SELECT entity_type, entity_id, title, feature_uuid, phase_uuid, rank, total,
+			ts_headline('english', page.body, q.query, '` + highlightOptions + `') AS highlight
+		FROM page, q
+		ORDER BY rank DESC, entity_type, entity_id`
+
+	err := db.db.Raw(sql, map[string]interface{}{
+		"workspace": workspaceUuid,
+		"query":     query,
+		"limit":     limit,
+		"offset":    offset,
+	}).Scan(&results).Error
+	if err != nil {
+		return []WorkspaceSearchResult{}, 0, fmt.Errorf("failed to search workspace: %w", err)
+	}
+
+	var total int64
+	if len(results) > 0 {
+		total = results[0].Total
+	}
+	return results, total, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
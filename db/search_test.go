@@ -0,0 +1,109 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchWorkspace(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	workspaceUuid := uuid.New().String()
+	otherWorkspaceUuid := uuid.New().String()
+
+	feature, err := TestDB.CreateOrEditFeature(WorkspaceFeatures{
+		Uuid:          uuid.New().String(),
+		WorkspaceUuid: workspaceUuid,
+		Name:          "Lightning payouts",
+		Brief:         "Pay hunters with a lightning invoice once a bounty is accepted",
+		Requirements:  "Budget must cover the invoice amount",
+		Architecture:  "Payments go through the v2 bot",
+	})
+	assert.NoError(t, err)
+
+	phase, err := TestDB.CreateOrEditFeaturePhase(FeaturePhase{
+		Uuid:         uuid.New().String(),
+		FeatureUuid:  feature.Uuid,
+		Name:         "Invoice polling",
+		PhasePurpose: "Detect settled invoices",
+	})
+	assert.NoError(t, err)
+
+	ticket := Tickets{
+		UUID:          uuid.New(),
+		WorkspaceUuid: workspaceUuid,
+		FeatureUUID:   feature.Uuid,
+		PhaseUUID:     phase.Uuid,
+		Name:          "Retry failed payouts",
+		Description:   "Retry payouts whose invoice expired",
+	}
+	_, err = TestDB.CreateOrEditTicket(&ticket)
+	assert.NoError(t, err)
+
+	_, err = TestDB.CreateActivity(&Activity{
+		Title:       "Standup",
+		Content:     "Discussed the invoice retry schedule",
+		ContentType: GeneralUpdate,
+		Workspace:   workspaceUuid,
+		Author:      HumansAuthor,
+		AuthorRef:   "pm",
+	})
+	assert.NoError(t, err)
+
+	_, err = TestDB.CreateSnippet(&TextSnippet{
+		WorkspaceUUID: workspaceUuid,
+		Title:         "Invoice template",
+		Snippet:       "Thanks for your work, here is your invoice",
+	})
+	assert.NoError(t, err)
+
+	_, err = TestDB.CreateSnippet(&TextSnippet{
+		WorkspaceUUID: otherWorkspaceUuid,
+		Title:         "Invoice elsewhere",
+		Snippet:       "This invoice belongs to another workspace",
+	})
+	assert.NoError(t, err)
+
+	t.Run("Matches Every Entity Type In Workspace", func(t *testing.T) {
+		results, total, err := TestDB.SearchWorkspace(workspaceUuid, "invoice", nil, 20, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), total)
+
+		types := map[string]bool{}
+		for _, result := range results {
+			types[result.EntityType] = true
+			assert.Contains(t, result.Highlight, "<mark>")
+			assert.NotEqual(t, "Invoice elsewhere", result.Title)
+		}
+		assert.Len(t, types, len(SearchEntityTypes))
+	})
+
+	t.Run("Titles Rank Above Bodies", func(t *testing.T) {
+		results, _, err := TestDB.SearchWorkspace(workspaceUuid, "payouts", nil, 20, 0)
+		assert.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.GreaterOrEqual(t, results[0].Rank, results[1].Rank)
+	})
+
+	t.Run("Filters By Type And Paginates", func(t *testing.T) {
+		results, total, err := TestDB.SearchWorkspace(workspaceUuid, "invoice", []string{SearchEntityTicket, SearchEntityPhase}, 1, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Len(t, results, 1)
+	})
+
+	t.Run("Tolerates Operator Syntax", func(t *testing.T) {
+		_, _, err := TestDB.SearchWorkspace(workspaceUuid, `"lightning invoice" -template & |`, nil, 20, 0)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Empty Query", func(t *testing.T) {
+		results, total, err := TestDB.SearchWorkspace(workspaceUuid, "  ", nil, 20, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, results)
+		assert.Equal(t, int64(0), total)
+	})
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
-	"github.com/stakwork/sphinx-tribes/logger"
 	"gorm.io/gorm"
 )
 
@@ -31,7 +30,7 @@ func (db database) CreateSkill(skill *Skill) (*Skill, error) {
 	}
 
 	if err := db.db.Create(skill).Error; err != nil {
-		logger.Log.Error("failed to create skill", "error", err)
+		db.log().Error("failed to create skill", "error", err)
 		return nil, fmt.Errorf("failed to create skill: %w", err)
 	}
 
@@ -41,7 +40,7 @@ func (db database) CreateSkill(skill *Skill) (*Skill, error) {
 func (db database) GetAllSkills() ([]Skill, error) {
 	var skills []Skill
 	if err := db.db.Find(&skills).Error; err != nil {
-		logger.Log.Error("failed to get all skills", "error", err)
+		db.log().Error("failed to get all skills", "error", err)
 		return nil, fmt.Errorf("failed to get all skills: %w", err)
 	}
 	return skills, nil
@@ -53,7 +52,7 @@ func (db database) GetSkillByID(id uuid.UUID) (*Skill, error) {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("skill not found with ID: %s", id)
 		}
-		logger.Log.Error("failed to get skill by ID", "error", err, "id", id)
+		db.log().Error("failed to get skill by ID", "error", err, "id", id)
 		return nil, fmt.Errorf("failed to get skill: %w", err)
 	}
 	return &skill, nil
@@ -70,7 +69,7 @@ func (db database) UpdateSkillByID(skill *Skill) (*Skill, error) {
 	}
 
 	if err := db.db.Model(&existingSkill).Updates(skill).Error; err != nil {
-		logger.Log.Error("failed to update skill", "error", err, "id", skill.ID)
+		db.log().Error("failed to update skill", "error", err, "id", skill.ID)
 		return nil, fmt.Errorf("failed to update skill: %w", err)
 	}
 
@@ -93,7 +92,7 @@ func (db database) DeleteSkillByID(id uuid.UUID) error {
 	}
 
 	if err := db.db.Delete(&Skill{ID: id}).Error; err != nil {
-		logger.Log.Error("failed to delete skill", "error", err, "id", id)
+		db.log().Error("failed to delete skill", "error", err, "id", id)
 		return fmt.Errorf("failed to delete skill: %w", err)
 	}
 
@@ -119,7 +118,7 @@ func (db database) CreateSkillInstall(install *SkillInstall) (*SkillInstall, err
 	}
 
 	if err := db.db.Create(install).Error; err != nil {
-		logger.Log.Error("failed to create skill installation", "error", err)
+		db.log().Error("failed to create skill installation", "error", err)
 		return nil, fmt.Errorf("failed to create skill installation: %w", err)
 	}
 
@@ -129,7 +128,7 @@ func (db database) CreateSkillInstall(install *SkillInstall) (*SkillInstall, err
 func (db database) GetSkillInstallBySkillsID(skillID uuid.UUID) ([]SkillInstall, error) {
 	var installs []SkillInstall
 	if err := db.db.Where("skill_id = ?", skillID).Find(&installs).Error; err != nil {
-		logger.Log.Error("failed to get skill installations", "error", err, "skill_id", skillID)
+		db.log().Error("failed to get skill installations", "error", err, "skill_id", skillID)
 		return nil, fmt.Errorf("failed to get skill installations: %w", err)
 	}
 	return installs, nil
@@ -141,7 +140,7 @@ func (db database) GetSkillInstallByID(id uuid.UUID) (*SkillInstall, error) {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("skill installation not found with ID: %s", id)
 		}
-		logger.Log.Error("failed to get skill installation by ID", "error", err, "id", id)
+		db.log().Error("failed to get skill installation by ID", "error", err, "id", id)
 		return nil, fmt.Errorf("failed to get skill installation: %w", err)
 	}
 	return &install, nil
@@ -158,7 +157,7 @@ func (db database) UpdateSkillInstallByID(install *SkillInstall) (*SkillInstall,
 	}
 
 	if err := db.db.Model(&existingInstall).Updates(install).Error; err != nil {
-		logger.Log.Error("failed to update skill installation", "error", err, "id", install.ID)
+		db.log().Error("failed to update skill installation", "error", err, "id", install.ID)
 		return nil, fmt.Errorf("failed to update skill installation: %w", err)
 	}
 
@@ -181,7 +180,7 @@ func (db database) DeleteSkillInstallByID(id uuid.UUID) error {
 	}
 
 	if err := db.db.Delete(&SkillInstall{ID: id}).Error; err != nil {
-		logger.Log.Error("failed to delete skill installation", "error", err, "id", id)
+		db.log().Error("failed to delete skill installation", "error", err, "id", id)
 		return fmt.Errorf("failed to delete skill installation: %w", err)
 	}
 
@@ -0,0 +1,222 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BudgetLowEvent is the notification event of a low balance alert.
+const BudgetLowEvent = "budget_low"
+
+var ErrSpendLimitExceeded = errors.New("monthly spend limit exceeded")
+
+// spendLimitRoles maps the roles a spend limit can cap to the payments
+// spending through them.
+var spendLimitRoles = map[string]PaymentType{
+	PayBounty:      Payment,
+	WithdrawBudget: Withdraw,
+}
+
+// MonthStart is the start of the calendar month of t in UTC, spend limits
+// count from there.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ValidateSpendLimits checks that every limit caps something, and the
+// workspace or a role that spends the budget at most once.
+func ValidateSpendLimits(limits []WorkspaceSpendLimit) error {
+	seen := map[string]bool{}
+	for _, limit := range limits {
+		if _, ok := spendLimitRoles[limit.Role]; limit.Role != "" && !ok {
+			return fmt.Errorf("role %q does not spend the budget, limits apply to %q and %q", limit.Role, PayBounty, WithdrawBudget)
+		}
+		if seen[limit.Role] {
+			return fmt.Errorf("role %q has more than one limit", limit.Role)
+		}
+		seen[limit.Role] = true
+		if limit.MonthlyLimit == 0 {
+			return errors.New("monthly_limit must be above 0")
+		}
+	}
+	return nil
+}
+
+// monthlySpend sums the sats the workspace paid out with the given payment
+// types since the start of the month, by sender unless sender is empty.
+// Failed payments gave their sats back and don't count.
+func monthlySpend(tx *gorm.DB, workspace_uuid string, sender string, types []PaymentType, since time.Time) uint {
+	var spent uint
+	query := tx.Model(&NewPaymentHistory{}).
+		Where("workspace_uuid = ? AND status = ? AND asset_id = ?", workspace_uuid, true, 0).
+		Where("payment_type IN ? AND created >= ?", types, since).
+		Where("COALESCE(payment_status, '') <> ?", PaymentFailed)
+	if sender != "" {
+		query = query.Where("sender_pub_key = ?", sender)
+	}
+	query.Select("COALESCE(SUM(amount), 0)").Row().Scan(&spent)
+	return spent
+}
+
+// GetWorkspaceSpendRules returns the spend limits and low balance threshold
+// of a workspace, none when it never set them.
+func (db database) GetWorkspaceSpendRules(workspace_uuid string) WorkspaceSpendRules {
+	rules := WorkspaceSpendRules{Limits: []WorkspaceSpendLimit{}}
+	db.db.Where("workspace_uuid = ?", workspace_uuid).Order("role").Find(&rules.Limits)
+
+	alert := WorkspaceBudgetAlert{}
+	if err := db.db.Where("workspace_uuid = ?", workspace_uuid).First(&alert).Error; err == nil {
+		rules.LowBalanceThreshold = alert.Threshold
+	}
+	rules.MonthSpent = monthlySpend(db.db, workspace_uuid, "", []PaymentType{Payment, Withdraw}, MonthStart(time.Now()))
+	return rules
+}
+
+// SaveWorkspaceSpendRules replaces the spend limits of a workspace and sets
+// its low balance threshold, no threshold turns the alert off. Changing the
+// threshold rearms the alert.
+func (db database) SaveWorkspaceSpendRules(workspace_uuid string, rules WorkspaceSpendRules) (WorkspaceSpendRules, error) {
+	if workspace_uuid == "" {
+		return WorkspaceSpendRules{}, errors.New("workspace uuid is required")
+	}
+	if err := ValidateSpendLimits(rules.Limits); err != nil {
+		return WorkspaceSpendRules{}, err
+	}
+
+	now := time.Now()
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("workspace_uuid = ?", workspace_uuid).Delete(&WorkspaceSpendLimit{}).Error; err != nil {
+			return err
+		}
+		if len(rules.Limits) > 0 {
+			limits := make([]WorkspaceSpendLimit, len(rules.Limits))
+			for i, limit := range rules.Limits {
+				limits[i] = WorkspaceSpendLimit{
+					WorkspaceUuid: workspace_uuid,
+					Role:          limit.Role,
+					MonthlyLimit:  limit.MonthlyLimit,
+					Created:       now,
+					Updated:       now,
+				}
+			}
+			if err := tx.Create(&limits).Error; err != nil {
+				return err
+			}
+		}
+
+		if rules.LowBalanceThreshold == 0 {
+			return tx.Where("workspace_uuid = ?", workspace_uuid).Delete(&WorkspaceBudgetAlert{}).Error
+		}
+		alert := WorkspaceBudgetAlert{WorkspaceUuid: workspace_uuid, Threshold: rules.LowBalanceThreshold, Created: now, Updated: now}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "workspace_uuid"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"threshold": alert.Threshold, "notified_at": nil, "updated": now}),
+		}).Create(&alert).Error
+	})
+	if err != nil {
+		return WorkspaceSpendRules{}, fmt.Errorf("failed to save spend rules: %w", err)
+	}
+	return db.GetWorkspaceSpendRules(workspace_uuid), nil
+}
+
+// CheckSpendLimit fails with ErrSpendLimitExceeded when spending amount
+// sats through role would take the workspace, or pubkey, past a monthly
+// limit. The workspace owner is only held to the workspace limit.
+func (db database) CheckSpendLimit(workspace_uuid string, pubkey string, role string, amount uint) error {
+	limits := []WorkspaceSpendLimit{}
+	if err := db.db.Where("workspace_uuid = ?", workspace_uuid).Find(&limits).Error; err != nil {
+		return err
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+
+	since := MonthStart(time.Now())
+	for _, limit := range limits {
+		if limit.Role == "" {
+			spent := monthlySpend(db.db, workspace_uuid, "", []PaymentType{Payment, Withdraw}, since)
+			if spent+amount > limit.MonthlyLimit {
+				return fmt.Errorf("%w: the workspace spent %d of its %d sats this month", ErrSpendLimitExceeded, spent, limit.MonthlyLimit)
+			}
+			continue
+		}
+		if limit.Role != role || db.GetWorkspaceByUuid(workspace_uuid).OwnerPubKey == pubkey {
+			continue
+		}
+		spent := monthlySpend(db.db, workspace_uuid, pubkey, []PaymentType{spendLimitRoles[role]}, since)
+		if spent+amount > limit.MonthlyLimit {
+			return fmt.Errorf("%w: you spent %d of your %d sats this month", ErrSpendLimitExceeded, spent, limit.MonthlyLimit)
+		}
+	}
+	return nil
+}
+
+// TrackLowBalance alerts the workspace owner when the budget is below the
+// workspace's threshold. The alert is saved as a notification waiting to
+// be sent and returned, nil when no alert is due: the balance is above the
+// threshold, or the owner was alerted and nothing was deposited since.
+func (db database) TrackLowBalance(workspace_uuid string) (*Notification, error) {
+	var notification *Notification
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		alert := WorkspaceBudgetAlert{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("workspace_uuid = ?", workspace_uuid).First(&alert).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		balance, err := ledgerWorkspaceBalance(tx, workspace_uuid, 0)
+		if err != nil {
+			return err
+		}
+		if balance >= int64(alert.Threshold) {
+			return nil
+		}
+		if alert.NotifiedAt != nil {
+			var deposits int64
+			tx.Model(&NewPaymentHistory{}).
+				Where("workspace_uuid = ? AND payment_type = ? AND status = ?", workspace_uuid, Deposit, true).
+				Where("updated > ?", alert.NotifiedAt).
+				Count(&deposits)
+			if deposits == 0 {
+				return nil
+			}
+		}
+
+		workspace := Workspace{}
+		tx.Where("uuid = ?", workspace_uuid).Find(&workspace)
+		if workspace.OwnerPubKey == "" {
+			return nil
+		}
+
+		now := time.Now()
+		notification = &Notification{
+			UUID:      uuid.New().String(),
+			Event:     BudgetLowEvent,
+			PubKey:    workspace.OwnerPubKey,
+			Content:   fmt.Sprintf("The budget of workspace %s is down to %d sats, below your alert threshold of %d sats.", workspace.Name, balance, alert.Threshold),
+			Status:    "WAITING_KEY_EXCHANGE",
+			CreatedAt: &now,
+			UpdatedAt: &now,
+		}
+		if err := tx.Create(notification).Error; err != nil {
+			return err
+		}
+		return tx.Model(&WorkspaceBudgetAlert{}).Where("id = ?", alert.ID).Updates(map[string]interface{}{
+			"notified_at": now,
+			"updated":     now,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to track low balance: %w", err)
+	}
+	return notification, nil
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestValidateSpendLimits(t *testing.T) {
+	assert.NoError(t, ValidateSpendLimits(nil))
+	assert.NoError(t, ValidateSpendLimits([]WorkspaceSpendLimit{
+		{MonthlyLimit: 100000},
+		{Role: PayBounty, MonthlyLimit: 20000},
+		{Role: WithdrawBudget, MonthlyLimit: 5000},
+	}))
+
+	assert.Error(t, ValidateSpendLimits([]WorkspaceSpendLimit{{Role: AddBudget, MonthlyLimit: 1000}}))
+	assert.Error(t, ValidateSpendLimits([]WorkspaceSpendLimit{{Role: PayBounty}}))
+	assert.Error(t, ValidateSpendLimits([]WorkspaceSpendLimit{{MonthlyLimit: 1000}, {MonthlyLimit: 2000}}))
+}
+
+func TestMonthStart(t *testing.T) {
+	east := time.FixedZone("UTC+3", 3*60*60)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), MonthStart(time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), MonthStart(time.Date(2024, 3, 1, 1, 0, 0, 0, east)))
+}
+
+func TestSpendLimits(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	workspaceUuid := uuid.New().String()
+	now := time.Now()
+	TestDB.db.Create(&Workspace{Uuid: workspaceUuid, Name: "limits-" + workspaceUuid, OwnerPubKey: "owner"})
+	TestDB.CreateWorkspaceBudget(NewBountyBudget{WorkspaceUuid: workspaceUuid, TotalBudget: 10000, Created: &now, Updated: &now})
+
+	_, err := TestDB.SaveWorkspaceSpendRules(workspaceUuid, WorkspaceSpendRules{
+		Limits:              []WorkspaceSpendLimit{{MonthlyLimit: 5000}, {Role: PayBounty, MonthlyLimit: 2000}},
+		LowBalanceThreshold: 7000,
+	})
+	assert.NoError(t, err)
+
+	spend := func(sender string, amount uint, paymentType PaymentType, created time.Time) {
+		TestDB.db.Create(&NewPaymentHistory{WorkspaceUuid: workspaceUuid, SenderPubKey: sender, Amount: amount, PaymentType: paymentType,
+			Status: true, PaymentStatus: PaymentComplete, Created: &created, Updated: &created})
+	}
+
+	t.Run("Role limits cap each approver but the owner", func(t *testing.T) {
+		spend("manager", 1500, Payment, now)
+		spend("manager", 3000, Payment, MonthStart(now).Add(-time.Hour))
+
+		assert.NoError(t, TestDB.CheckSpendLimit(workspaceUuid, "manager", PayBounty, 500))
+		assert.ErrorIs(t, TestDB.CheckSpendLimit(workspaceUuid, "manager", PayBounty, 501), ErrSpendLimitExceeded)
+		assert.NoError(t, TestDB.CheckSpendLimit(workspaceUuid, "manager", WithdrawBudget, 1000))
+		assert.NoError(t, TestDB.CheckSpendLimit(workspaceUuid, "owner", PayBounty, 3000))
+	})
+
+	t.Run("The workspace limit caps everyone", func(t *testing.T) {
+		spend("owner", 3000, Withdraw, now)
+
+		assert.ErrorIs(t, TestDB.CheckSpendLimit(workspaceUuid, "owner", WithdrawBudget, 501), ErrSpendLimitExceeded)
+		assert.Equal(t, uint(4500), TestDB.GetWorkspaceSpendRules(workspaceUuid).MonthSpent)
+	})
+
+	t.Run("The owner is alerted once per dip below the threshold", func(t *testing.T) {
+		notification, err := TestDB.TrackLowBalance(workspaceUuid)
+		assert.NoError(t, err)
+		assert.Nil(t, notification, "the budget is above the threshold")
+
+		err = TestDB.db.Transaction(func(tx *gorm.DB) error {
+			return postLedgerTransaction(tx, LedgerTransaction{WorkspaceUuid: workspaceUuid, Reference: "spend_limits_test:" + workspaceUuid, Kind: "test"},
+				ledgerMove(LedgerWorkspace, "", LedgerExternal, "", 4000)...)
+		})
+		assert.NoError(t, err)
+		notification, err = TestDB.TrackLowBalance(workspaceUuid)
+		assert.NoError(t, err)
+		if assert.NotNil(t, notification) {
+			assert.Equal(t, "owner", notification.PubKey)
+			assert.Equal(t, BudgetLowEvent, notification.Event)
+		}
+
+		notification, err = TestDB.TrackLowBalance(workspaceUuid)
+		assert.NoError(t, err)
+		assert.Nil(t, notification, "no deposit since the last alert")
+
+		spend("owner", 500, Deposit, time.Now().Add(time.Second))
+		notification, err = TestDB.TrackLowBalance(workspaceUuid)
+		assert.NoError(t, err)
+		assert.NotNil(t, notification)
+	})
+}
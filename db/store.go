@@ -13,7 +13,6 @@ import (
 	"github.com/patrickmn/go-cache"
 	"github.com/rs/xid"
 	"github.com/stakwork/sphinx-tribes/auth"
-	"github.com/stakwork/sphinx-tribes/config"
 	"github.com/stakwork/sphinx-tribes/logger"
 )
 
@@ -72,36 +71,6 @@ func (s StoreData) GetLnCache(key string) (LnStore, error) {
 	return c, nil
 }
 
-func (s StoreData) SetInvoiceCache(value []InvoiceStoreData) error {
-	// The invoice should expire every 6 minutes
-	s.Cache.Set(config.InvoiceList, value, 6*time.Minute)
-	return nil
-}
-
-func (s StoreData) GetInvoiceCache() ([]InvoiceStoreData, error) {
-	value, found := s.Cache.Get(config.InvoiceList)
-	c, _ := value.([]InvoiceStoreData)
-	if !found {
-		return []InvoiceStoreData{}, errors.New("Invoice Cache not found")
-	}
-	return c, nil
-}
-
-func (s StoreData) SetBudgetInvoiceCache(value []BudgetStoreData) error {
-	// The invoice should expire every 6 minutes
-	s.Cache.Set(config.BudgetInvoiceList, value, 6*time.Minute)
-	return nil
-}
-
-func (s StoreData) GetBudgetInvoiceCache() ([]BudgetStoreData, error) {
-	value, found := s.Cache.Get(config.BudgetInvoiceList)
-	c, _ := value.([]BudgetStoreData)
-	if !found {
-		return []BudgetStoreData{}, errors.New("Budget Invoice Cache not found")
-	}
-	return c, nil
-}
-
 func (s StoreData) SetSocketConnections(value Client) error {
 	// The websocket in cache should not expire unless when deleted
 	s.Cache.Set(value.Host, value, cache.NoExpiration)
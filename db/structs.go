@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/lib/pq"
+	"github.com/stakwork/sphinx-tribes/vault"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 )
@@ -181,7 +182,7 @@ type AssetTx struct {
 	AssetId  uint   `json:"asset_id"`
 	Amount   uint   `json:"amount"`
 	Metadata string `json:"metadata"`
-	Txid     string `json:"metadata"`
+	Txid     string `json:"txid"`
 	Onchain  bool   `json:"onchain"`
 }
 
@@ -266,20 +267,6 @@ type InvoiceResponse struct {
 	Response Invoice `json:"response"`
 }
 
-type InvoiceStoreData struct {
-	Invoice        string `json:"invoice"`
-	Owner_pubkey   string `json:"owner_pubkey"`
-	User_pubkey    string `json:"user_pubkey"`
-	Amount         string `json:"amount"`
-	Created        string `json:"created"`
-	Host           string `json:"host,omitempty"`
-	Type           string `json:"type"`
-	Assigned_hours uint   `json:"assigned_hours,omitempty"`
-	Commitment_fee uint   `json:"commitment_fee,omitempty"`
-	Bounty_expires string `json:"bounty_expires,omitempty"`
-	Route_hint     string `json:"route_hint,omitempty"`
-}
-
 type InvoiceStatus struct {
 	Payment_request string `json:"payment_request"`
 	Status          bool   `json:"Status"`
@@ -390,6 +377,7 @@ type Bounty struct {
 	BountyExpires           string                 `json:"bounty_expires"`
 	CommitmentFee           uint64                 `json:"commitment_fee"`
 	Price                   uint                   `json:"price"`
+	AssetId                 uint                   `gorm:"not null;default:0" json:"asset_id"`
 	Title                   string                 `json:"title"`
 	Tribe                   string                 `json:"tribe"`
 	Assignee                string                 `json:"assignee"`
@@ -435,6 +423,7 @@ type NewBounty struct {
 	BountyExpires           string                 `json:"bounty_expires"`
 	CommitmentFee           uint64                 `json:"commitment_fee"`
 	Price                   uint                   `json:"price"`
+	AssetId                 uint                   `gorm:"not null;default:0" json:"asset_id"`
 	Title                   string                 `json:"title"`
 	Tribe                   string                 `json:"tribe"`
 	Assignee                string                 `json:"assignee"`
@@ -468,6 +457,66 @@ type NewBounty struct {
 	MaxStakers              int                    `gorm:"default:1" json:"max_stakers"`
 	CurrentStakers          int                    `gorm:"default:0" json:"current_stakers"`
 	Stakes                  []BountyStake          `gorm:"foreignKey:BountyID" json:"stakes,omitempty"`
+	Assignees               []BountyAssignee       `gorm:"foreignKey:BountyID;->" json:"assignees,omitempty"`
+	Milestones              []BountyMilestone      `gorm:"foreignKey:BountyID;->" json:"milestones,omitempty"`
+}
+
+// BountyMilestone is a part of a bounty paid on its own once a proof of
+// work submitted for it is accepted. The milestones of a bounty add up to
+// its price, which stays reserved from the workspace budget until each
+// milestone is paid. Reserved is cleared once the milestone's amount left
+// the reservation.
+type BountyMilestone struct {
+	ID                 uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	BountyID           uint      `gorm:"not null;index" json:"bounty_id"`
+	Position           int       `gorm:"not null;default:0" json:"position"`
+	Title              string    `gorm:"type:varchar(255);not null" json:"title"`
+	Amount             uint      `gorm:"not null" json:"amount"`
+	AcceptanceCriteria string    `gorm:"type:text" json:"acceptance_criteria"`
+	Reserved           bool      `gorm:"not null;default:false" json:"reserved"`
+	PaymentID          uint      `json:"payment_id,omitempty"`
+	PaymentStatus      string    `gorm:"type:varchar(20);not null;default:''" json:"payment_status,omitempty"`
+	Created            time.Time `json:"created"`
+	Updated            time.Time `json:"updated"`
+}
+
+type BountyMilestonesRequest struct {
+	Milestones []BountyMilestone `json:"milestones"`
+}
+
+// BountyAssignee is one hunter's share of a bounty paid to several
+// hunters. A share is either Percent of the price or a fixed Amount, the
+// shares of a bounty are all of one kind and add up to its price.
+// PaymentStatus follows the payment of the share, empty until it is paid.
+type BountyAssignee struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	BountyID      uint      `gorm:"not null;uniqueIndex:idx_bounty_assignee" json:"bounty_id"`
+	Pubkey        string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_bounty_assignee" json:"pubkey"`
+	Percent       uint      `gorm:"not null;default:0" json:"percent,omitempty"`
+	Amount        uint      `gorm:"not null;default:0" json:"amount,omitempty"`
+	PaymentID     uint      `json:"payment_id,omitempty"`
+	PaymentStatus string    `gorm:"type:varchar(20);not null;default:''" json:"payment_status,omitempty"`
+	Created       time.Time `json:"created"`
+}
+
+// BountySharePayment is the payment made for one share of a split bounty.
+type BountySharePayment struct {
+	ShareID uint
+	Payment NewPaymentHistory
+}
+
+type BountyAssigneesRequest struct {
+	Assignees []BountyAssignee `json:"assignees"`
+}
+
+// AssigneeShare shows a hunter of a split bounty with their share.
+type AssigneeShare struct {
+	Pubkey        string `json:"pubkey"`
+	Alias         string `json:"alias"`
+	Img           string `json:"img,omitempty"`
+	Percent       uint   `json:"percent,omitempty"`
+	Amount        uint   `json:"amount"`
+	PaymentStatus string `json:"payment_status,omitempty"`
 }
 
 type BountyOwners struct {
@@ -514,13 +563,14 @@ type BountyData struct {
 }
 
 type BountyResponse struct {
-	Bounty       NewBounty      `json:"bounty"`
-	Assignee     Person         `json:"assignee"`
-	Owner        Person         `json:"owner"`
-	Organization WorkspaceShort `json:"organization"`
-	Workspace    WorkspaceShort `json:"workspace"`
-	Proofs       []ProofOfWork  `json:"proofs,omitempty"`
-	Pow          int            `json:"pow"`
+	Bounty       NewBounty       `json:"bounty"`
+	Assignee     Person          `json:"assignee"`
+	Owner        Person          `json:"owner"`
+	Organization WorkspaceShort  `json:"organization"`
+	Workspace    WorkspaceShort  `json:"workspace"`
+	Proofs       []ProofOfWork   `json:"proofs,omitempty"`
+	Assignees    []AssigneeShare `json:"assignees,omitempty"`
+	Pow          int             `json:"pow"`
 }
 
 type BountyCountResponse struct {
@@ -700,6 +750,9 @@ type BountyBudget struct {
 }
 
 // Rename back to BountyBudget
+//
+// The stored TotalBudget follows the workspace's ledger account, which is
+// the source of truth: GetWorkspaceBudget reads the budget from the ledger.
 type NewBountyBudget struct {
 	ID            uint       `json:"id"`
 	OrgUuid       string     `gorm:"-" json:"org_uuid"`
@@ -709,6 +762,25 @@ type NewBountyBudget struct {
 	Updated       *time.Time `json:"updated"`
 }
 
+// WorkspaceAssetBudget is the budget a workspace holds in one Taproot
+// asset. The sats budget stays in NewBountyBudget. Like it, the stored
+// Balance follows the workspace's ledger account in the asset, which the
+// budget reads return.
+type WorkspaceAssetBudget struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkspaceUuid string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_workspace_asset_budget" json:"workspace_uuid"`
+	AssetId       uint      `gorm:"not null;uniqueIndex:idx_workspace_asset_budget" json:"asset_id"`
+	Balance       uint      `gorm:"not null;default:0" json:"balance"`
+	Created       time.Time `json:"created"`
+	Updated       time.Time `json:"updated"`
+}
+
+// AssetDepositRequest funds an asset budget with a transfer already sent
+// to the tribes node, identified by its transaction id.
+type AssetDepositRequest struct {
+	Txid string `json:"txid"`
+}
+
 type StatusBudget struct {
 	OrgUuid             string `json:"org_uuid"`
 	WorkspaceUuid       string `json:"workspace_uuid"`
@@ -743,15 +815,6 @@ type StakeInvoiceRequest struct {
 	StakeOperation bool        `json:"is_stake,omitempty"`
 }
 
-type BudgetStoreData struct {
-	Amount       uint       `json:"amount"`
-	SenderPubKey string     `json:"sender_pubkey"`
-	OrgUuid      string     `json:"org_uuid"`
-	Invoice      string     `json:"invoice"`
-	Host         string     `json:"host,omitempty"`
-	Created      *time.Time `json:"created"`
-}
-
 type PaymentType string
 
 const (
@@ -821,6 +884,12 @@ type NewPaymentHistory struct {
 	Created        *time.Time  `json:"created"`
 	Updated        *time.Time  `json:"updated"`
 	Status         bool        `json:"status"`
+	Retries        int         `gorm:"not null;default:0" json:"retries"`
+	NeedsReview    bool        `gorm:"not null;default:false" json:"needs_review"`
+	AssetId        uint        `gorm:"not null;default:0" json:"asset_id"`
+	// PreviousTags are the tags of the sends a retry replaced, still
+	// checked with the node alongside Tag.
+	PreviousTags pq.StringArray `gorm:"type:text[]" json:"previous_tags,omitempty"`
 }
 
 type PaymentHistoryData struct {
@@ -1009,6 +1078,7 @@ type BountyCard struct {
 	AssigneePic  string            `json:"assignee_img,omitempty"`
 	Assignee     string            `json:"assignee"`
 	AssigneeName string            `json:"assignee_name"`
+	Assignees    []AssigneeShare   `json:"assignees,omitempty"`
 	Features     WorkspaceFeatures `json:"features"`
 	Phase        FeaturePhase      `json:"phase"`
 	Workspace    Workspace         `json:"workspace"`
@@ -1194,6 +1264,16 @@ type Chat struct {
 	UpdatedAt   time.Time  `json:"updatedAt"`
 }
 
+// ChatSummary is the rolling summary of the turns of a chat that no longer
+// fit in the context sent with its messages, up to and including the
+// ThroughMessageID message.
+type ChatSummary struct {
+	ChatID           string    `json:"chat_id" gorm:"primaryKey"`
+	Summary          string    `json:"summary" gorm:"type:text"`
+	ThroughMessageID string    `json:"through_message_id"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
 type ChatWorkflowStatus struct {
 	UUID      uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"uuid"`
 	ChatID    string    `gorm:"index;not null" json:"chat_id"`
@@ -1215,6 +1295,7 @@ const (
 type ProofOfWork struct {
 	ID          uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey"`
 	BountyID    uint              `json:"bounty_id"`
+	MilestoneID *uint             `json:"milestone_id,omitempty" gorm:"index"`
 	Description string            `json:"description" gorm:"type:text;not null"`
 	Status      ProofOfWorkStatus `json:"status" gorm:"type:varchar(20);default:'New'"`
 	CreatedAt   time.Time         `json:"created_at" gorm:"type:timestamp;default:current_timestamp"`
@@ -1318,6 +1399,36 @@ type FileAsset struct {
 	CreatedAt      time.Time  `json:"createdAt"`
 	UpdatedAt      time.Time  `json:"updatedAt"`
 	DeletedAt      *time.Time `json:"deletedAt,omitempty" gorm:"index"`
+	ReferenceCount int64      `json:"referenceCount" gorm:"default:0"`
+	ArchivedAt     *time.Time `json:"archivedAt,omitempty"`
+}
+
+const (
+	DefaultFileArchiveAfterDays = 30
+	DefaultFilePurgeAfterDays   = 30
+	maxFileRetentionDays        = 3650
+)
+
+// FileRetention is how long a workspace keeps files no chat refers to:
+// they are archived ArchiveAfterDays after their last reference and
+// deleted from storage PurgeAfterDays after that.
+type FileRetention struct {
+	WorkspaceUuid    string    `gorm:"primaryKey;type:varchar(255)" json:"workspace_uuid"`
+	ArchiveAfterDays int       `gorm:"not null" json:"archive_after_days"`
+	PurgeAfterDays   int       `gorm:"not null" json:"purge_after_days"`
+	Updated          time.Time `json:"updated"`
+}
+
+// FileStorageUsage is what the files of a workspace take in storage. Files
+// deleted from storage are not counted.
+type FileStorageUsage struct {
+	WorkspaceUuid string `json:"workspace_uuid"`
+	Files         int64  `json:"files"`
+	Bytes         int64  `json:"bytes"`
+	ActiveFiles   int64  `json:"active_files"`
+	ActiveBytes   int64  `json:"active_bytes"`
+	ArchivedFiles int64  `json:"archived_files"`
+	ArchivedBytes int64  `json:"archived_bytes"`
 }
 
 type ListFileAssetsParams struct {
@@ -1601,10 +1712,9 @@ type CodeSpaceMap struct {
 	CodeSpaceURL string    `json:"codeSpaceURL"`
 	UserPubkey   string    `json:"userPubkey" gorm:"index"`
 	Username     string    `json:"username,omitempty"`
-	// TODO: Encrypt this field
-	GithubPat    string    `json:"githubPat,omitempty" gorm:"column:github_pat"`
-	BaseBranch string `json:"baseBranch"`
-	PoolAPIKey string `json:"poolAPIKey,omitempty" gorm:"column:pool_api_key"`
+	GithubPat    vault.Secret `json:"githubPat,omitempty" gorm:"column:github_pat"`
+	BaseBranch   string       `json:"baseBranch"`
+	PoolAPIKey   vault.Secret `json:"poolAPIKey,omitempty" gorm:"column:pool_api_key"`
 }
 
 type StakeStatus string
@@ -1659,6 +1769,401 @@ const (
 	StakeProcessStatusReturned StakeProcessStatus = "RETURNED"
 )
 
+// WebsocketOutboxStream allocates the sequences of a stream. A session
+// stream is owned by the pubkey that first connected the session, only it
+// may connect the session again or replay the stream.
+type WebsocketOutboxStream struct {
+	Stream       string    `gorm:"primaryKey;type:varchar(255)" json:"stream"`
+	Owner        string    `gorm:"type:varchar(255);not null;default:''" json:"owner"`
+	LastSequence uint64    `gorm:"not null;default:0" json:"last_sequence"`
+	UpdatedAt    time.Time `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
+}
+
+type WebsocketOutboxMessage struct {
+	ID        uuid.UUID   `gorm:"primaryKey;type:uuid" json:"id"`
+	Stream    string      `gorm:"type:varchar(255);not null;uniqueIndex:idx_websocket_outbox_stream_sequence" json:"stream"`
+	Sequence  uint64      `gorm:"not null;uniqueIndex:idx_websocket_outbox_stream_sequence" json:"sequence"`
+	Kind      string      `gorm:"type:varchar(50)" json:"kind"`
+	Payload   PropertyMap `gorm:"type:jsonb;not null;default:'{}'::jsonb" json:"payload"`
+	CreatedAt time.Time   `gorm:"type:timestamp;default:current_timestamp;index" json:"created_at"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+type WebhookSubscription struct {
+	ID            uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Uuid          string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"uuid"`
+	WorkspaceUuid string         `gorm:"type:varchar(255);index;not null" json:"workspace_uuid"`
+	Url           string         `gorm:"type:text;not null" json:"url"`
+	Secret        string         `gorm:"type:varchar(255);not null" json:"secret,omitempty"`
+	Events        pq.StringArray `gorm:"type:text[]" json:"events"`
+	Active        bool           `gorm:"default:true" json:"active"`
+	CreatedBy     string         `gorm:"type:varchar(255)" json:"created_by"`
+	Created       *time.Time     `json:"created"`
+	Updated       *time.Time     `json:"updated"`
+}
+
+type WebhookDelivery struct {
+	ID               uint                  `gorm:"primaryKey;autoIncrement" json:"id"`
+	Uuid             string                `gorm:"type:varchar(255);uniqueIndex;not null" json:"uuid"`
+	SubscriptionUuid string                `gorm:"type:varchar(255);index;not null" json:"subscription_uuid"`
+	WorkspaceUuid    string                `gorm:"type:varchar(255);index" json:"workspace_uuid"`
+	Event            string                `gorm:"type:varchar(100);not null" json:"event"`
+	Payload          string                `gorm:"type:text;not null" json:"payload"`
+	Status           WebhookDeliveryStatus `gorm:"type:varchar(20);index;not null" json:"status"`
+	Attempts         int                   `gorm:"default:0" json:"attempts"`
+	ResponseStatus   int                   `json:"response_status"`
+	Error            string                `gorm:"type:text" json:"error"`
+	NextAttemptAt    *time.Time            `gorm:"index" json:"next_attempt_at"`
+	DeliveredAt      *time.Time            `json:"delivered_at"`
+	Created          *time.Time            `json:"created"`
+	Updated          *time.Time            `json:"updated"`
+}
+
+// AuditLog is one entry of the append-only workspace audit trail. Changes
+// maps every changed field to its before and after values.
+type AuditLog struct {
+	ID            uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkspaceUuid string      `gorm:"type:varchar(255);index" json:"workspace_uuid"`
+	Actor         string      `gorm:"type:varchar(255);index" json:"actor"`
+	EntityType    string      `gorm:"type:varchar(50);index:idx_audit_entity" json:"entity_type"`
+	EntityID      string      `gorm:"type:varchar(255);index:idx_audit_entity" json:"entity_id"`
+	Action        string      `gorm:"type:varchar(50);not null" json:"action"`
+	Changes       PropertyMap `gorm:"type:jsonb;not null;default:'{}'::jsonb" json:"changes"`
+	Created       time.Time   `gorm:"index;not null" json:"created"`
+}
+
+type AuditLogFilter struct {
+	WorkspaceUuid string
+	EntityType    string
+	EntityID      string
+	Actor         string
+	Limit         int
+	Offset        int
+}
+
+type AuditLogResponse struct {
+	Entries []AuditLog `json:"entries"`
+	Total   int64      `json:"total"`
+}
+
+type LedgerAccountKind string
+
+const (
+	// LedgerWorkspace holds the spendable budget of a workspace.
+	LedgerWorkspace LedgerAccountKind = "workspace"
+	// LedgerEscrow holds bounty payments sent but not settled yet and the
+	// reservations of batch payouts, owned by "batch:<uuid>".
+	LedgerEscrow LedgerAccountKind = "escrow"
+	// LedgerHunter holds what a hunter was paid, one account per pubkey.
+	LedgerHunter LedgerAccountKind = "hunter"
+	// LedgerExternal is the outside world deposits come from and
+	// withdrawals go to.
+	LedgerExternal LedgerAccountKind = "external"
+)
+
+// LedgerAccount holds one asset, sats when AssetId is 0.
+type LedgerAccount struct {
+	ID            uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkspaceUuid string            `gorm:"type:varchar(255);not null;uniqueIndex:idx_ledger_asset_account" json:"workspace_uuid"`
+	Kind          LedgerAccountKind `gorm:"type:varchar(20);not null;uniqueIndex:idx_ledger_asset_account" json:"kind"`
+	Owner         string            `gorm:"type:varchar(255);not null;default:'';uniqueIndex:idx_ledger_asset_account" json:"owner"`
+	AssetId       uint              `gorm:"not null;default:0;uniqueIndex:idx_ledger_asset_account" json:"asset_id"`
+	// Balance is the sum of the account's entries, kept by a trigger on
+	// ledger_entries so budgets are read without summing them.
+	Balance       int64             `gorm:"not null;default:0" json:"balance"`
+	Created       time.Time         `gorm:"not null" json:"created"`
+}
+
+// LedgerTransaction groups the entries of one money movement. Reference
+// identifies the movement, e.g. "payment:42", so it is never posted twice.
+// All entries of a transaction move the same asset.
+type LedgerTransaction struct {
+	ID            uint          `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkspaceUuid string        `gorm:"type:varchar(255);not null;index" json:"workspace_uuid"`
+	Reference     string        `gorm:"type:varchar(255);not null;uniqueIndex" json:"reference"`
+	Kind          string        `gorm:"type:varchar(50);not null" json:"kind"`
+	PaymentID     uint          `gorm:"index" json:"payment_id,omitempty"`
+	BountyID      uint          `json:"bounty_id,omitempty"`
+	AssetId       uint          `gorm:"not null;default:0" json:"asset_id"`
+	Entries       []LedgerEntry `gorm:"foreignKey:TransactionID" json:"entries,omitempty"`
+	Created       time.Time     `gorm:"index;not null" json:"created"`
+}
+
+// LedgerEntry moves Amount units of the account's asset into it, or out of it when
+// negative. The entries of a transaction always sum to zero.
+type LedgerEntry struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TransactionID uint      `gorm:"not null;index" json:"transaction_id"`
+	AccountID     uint      `gorm:"not null;index" json:"account_id"`
+	Amount        int64     `gorm:"not null" json:"amount"`
+	Created       time.Time `gorm:"not null" json:"created"`
+}
+
+type LedgerBalance struct {
+	AccountID uint              `json:"account_id"`
+	Kind      LedgerAccountKind `json:"kind"`
+	Owner     string            `json:"owner,omitempty"`
+	AssetId   uint              `json:"asset_id"`
+	Balance   int64             `json:"balance"`
+}
+
+// LedgerReconciliation compares the budget derived from the ledger with
+// the stored BountyBudget.TotalBudget. Drift is stored minus derived. The
+// top level figures are in sats, Assets reconciles the asset budgets.
+type LedgerReconciliation struct {
+	WorkspaceUuid          string                      `json:"workspace_uuid"`
+	StoredBudget           uint                        `json:"stored_budget"`
+	LedgerBudget           int64                       `json:"ledger_budget"`
+	Escrow                 int64                       `json:"escrow"`
+	Drift                  int64                       `json:"drift"`
+	Assets                 []AssetLedgerReconciliation `json:"assets"`
+	UnbalancedTransactions []string                    `json:"unbalanced_transactions"`
+	InSync                 bool                        `json:"in_sync"`
+	Balances               []LedgerBalance             `json:"balances"`
+}
+
+// AssetLedgerReconciliation compares one asset budget of a workspace with
+// its ledger.
+type AssetLedgerReconciliation struct {
+	AssetId      uint  `json:"asset_id"`
+	StoredBudget uint  `json:"stored_budget"`
+	LedgerBudget int64 `json:"ledger_budget"`
+	Escrow       int64 `json:"escrow"`
+	Drift        int64 `json:"drift"`
+}
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header, so a retry of the same request is answered with
+// it instead of running again. Keys are scoped by endpoint and caller.
+type IdempotencyKey struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Key            string    `gorm:"column:idempotency_key;type:varchar(255);not null;uniqueIndex:idx_idempotency_key" json:"key"`
+	Scope          string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_idempotency_key" json:"scope"`
+	PubKey         string    `gorm:"type:varchar(255);not null;default:'';uniqueIndex:idx_idempotency_key" json:"pub_key"`
+	RequestHash    string    `gorm:"type:varchar(64);not null" json:"request_hash"`
+	Completed      bool      `gorm:"not null;default:false" json:"completed"`
+	ResponseStatus int       `json:"response_status"`
+	ResponseBody   string    `gorm:"type:text" json:"response_body"`
+	ContentType    string    `gorm:"type:varchar(255)" json:"content_type"`
+	Created        time.Time `gorm:"index;not null" json:"created"`
+	Updated        time.Time `gorm:"not null" json:"updated"`
+}
+
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	// JobDead marks a job that ran out of attempts or failed permanently.
+	JobDead JobStatus = "dead"
+)
+
+// Job is a unit of background work in the Postgres backed queue. A
+// running job is leased to one worker until LockedUntil; when the lease
+// runs out another worker picks it up again. DedupeKey, when set, keeps a
+// second job of the same type and key from being queued until the first
+// one is done.
+type Job struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Type        string     `gorm:"type:varchar(50);not null;index" json:"type"`
+	DedupeKey   string     `gorm:"type:varchar(255);not null;default:''" json:"dedupe_key,omitempty"`
+	Payload     string     `gorm:"type:jsonb;not null;default:'{}'" json:"payload"`
+	Status      JobStatus  `gorm:"type:varchar(20);not null;default:'pending';index:idx_jobs_due" json:"status"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"not null" json:"max_attempts"`
+	RunAt       time.Time  `gorm:"not null;index:idx_jobs_due" json:"run_at"`
+	LockedBy    string     `gorm:"type:varchar(255)" json:"locked_by,omitempty"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	Created     time.Time  `gorm:"not null" json:"created"`
+	Updated     time.Time  `gorm:"not null" json:"updated"`
+}
+
+type PaymentPolicyAction string
+
+const (
+	PaymentPolicyReverse PaymentPolicyAction = "reverse"
+	// PaymentPolicyRetry sends a failed payment again as a fresh keysend. A
+	// payment still pending past the timeout goes to review instead.
+	PaymentPolicyRetry PaymentPolicyAction = "retry"
+	// PaymentPolicyReview leaves the payment pending for the owner to act on.
+	PaymentPolicyReview PaymentPolicyAction = "review"
+)
+
+// WorkspacePaymentPolicy decides what happens to a bounty payment of the
+// workspace that failed or stayed pending for PendingTimeoutHours. Retried
+// payments fall back to review after MaxRetries retries.
+type WorkspacePaymentPolicy struct {
+	ID                  uint                `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkspaceUuid       string              `gorm:"type:varchar(255);not null;uniqueIndex" json:"workspace_uuid"`
+	PendingTimeoutHours int                 `gorm:"not null;default:168" json:"pending_timeout_hours"`
+	Action              PaymentPolicyAction `gorm:"type:varchar(20);not null;default:'reverse'" json:"action"`
+	MaxRetries          int                 `gorm:"not null;default:0" json:"max_retries"`
+	NotifyOwner         bool                `gorm:"not null;default:false" json:"notify_owner"`
+	Created             time.Time           `json:"created"`
+	Updated             time.Time           `json:"updated"`
+}
+
+// WorkspaceSpendLimit caps the sats the workspace budget pays out in a
+// calendar month. A limit without a Role caps the workspace as a whole,
+// one with a Role caps each member spending through that role: PayBounty
+// for bounty payments and WithdrawBudget for withdrawals.
+type WorkspaceSpendLimit struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkspaceUuid string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_workspace_spend_limit" json:"workspace_uuid"`
+	Role          string    `gorm:"type:varchar(50);not null;default:'';uniqueIndex:idx_workspace_spend_limit" json:"role"`
+	MonthlyLimit  uint      `gorm:"not null" json:"monthly_limit"`
+	Created       time.Time `json:"created"`
+	Updated       time.Time `json:"updated"`
+}
+
+// WorkspaceBudgetAlert notifies the workspace owner once its budget falls
+// below Threshold sats. NotifiedAt is when the last alert went out, the
+// next one waits for a deposit after it.
+type WorkspaceBudgetAlert struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkspaceUuid string     `gorm:"type:varchar(255);not null;uniqueIndex" json:"workspace_uuid"`
+	Threshold     uint       `gorm:"not null" json:"threshold"`
+	NotifiedAt    *time.Time `json:"notified_at"`
+	Created       time.Time  `json:"created"`
+	Updated       time.Time  `json:"updated"`
+}
+
+// WorkspaceWorkflow picks the provider and workflow a workspace runs one
+// kind of job with: chat, build, ticket review or plan review. Kinds
+// without one run the default Stakwork workflow.
+type WorkspaceWorkflow struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkspaceUuid string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_workspace_workflow" json:"workspace_uuid"`
+	Kind          string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_workspace_workflow" json:"kind"`
+	Provider      string    `gorm:"type:varchar(50);not null" json:"provider"`
+	WorkflowID    string    `gorm:"type:varchar(255)" json:"workflow_id"`
+	Created       time.Time `json:"created"`
+	Updated       time.Time `json:"updated"`
+}
+
+type WorkspaceWorkflowsRequest struct {
+	Workflows []WorkspaceWorkflow `json:"workflows"`
+}
+
+// WorkspaceSpendRules are the spend limits and the low balance threshold
+// of a workspace, MonthSpent is what the workspace paid out this month.
+type WorkspaceSpendRules struct {
+	Limits              []WorkspaceSpendLimit `json:"limits"`
+	LowBalanceThreshold uint                  `json:"low_balance_threshold"`
+	MonthSpent          uint                  `json:"month_spent"`
+}
+
+type BatchPayoutStatus string
+
+const (
+	BatchPayoutReserved BatchPayoutStatus = "reserved"
+	// BatchPayoutFinished marks a batch whose unspent reservation went back
+	// to the budget.
+	BatchPayoutFinished BatchPayoutStatus = "finished"
+)
+
+// BatchPayout reserves the budget for paying several bounties of a
+// workspace at once. Reserved is taken from the budget up front, payments
+// draw on it and what they leave unspent is released when the batch
+// finishes.
+type BatchPayout struct {
+	ID            uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	Uuid          string            `gorm:"type:varchar(255);not null;uniqueIndex" json:"uuid"`
+	WorkspaceUuid string            `gorm:"type:varchar(255);not null;index" json:"workspace_uuid"`
+	SenderPubKey  string            `gorm:"type:varchar(255);not null" json:"sender_pubkey"`
+	Reserved      uint              `gorm:"not null" json:"reserved"`
+	Spent         uint              `gorm:"not null;default:0" json:"spent"`
+	Released      uint              `gorm:"not null;default:0" json:"released"`
+	Status        BatchPayoutStatus `gorm:"type:varchar(20);not null;default:'reserved'" json:"status"`
+	Items         []BatchPayoutItem `gorm:"foreignKey:BatchID" json:"items,omitempty"`
+	Created       time.Time         `json:"created"`
+	Updated       time.Time         `json:"updated"`
+}
+
+const (
+	BatchItemReserved = "RESERVED"
+	// BatchItemSending marks an item whose keysend is going out. Finishing
+	// the batch holds it for review, since the payment may still land.
+	BatchItemSending = "SENDING"
+	// BatchItemNeedsReview marks an item whose keysend may have gone out
+	// but whose outcome could not be recorded. It stays held, with its
+	// bounty pending, until it is resolved with the node or released.
+	BatchItemNeedsReview = "NEEDS_REVIEW"
+	// BatchItemReleased marks a bounty the batch finished without paying.
+	BatchItemReleased = "RELEASED"
+	// BatchItemSkipped marks a requested bounty that could not be paid.
+	BatchItemSkipped = "SKIPPED"
+)
+
+// BatchPayoutItem is the payment of one bounty of a batch. Status is
+// BatchItemReserved until its keysend goes out, BatchItemSending while it
+// does, then the payment status.
+type BatchPayoutItem struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	BatchID   uint   `gorm:"not null;uniqueIndex:idx_batch_payout_item" json:"batch_id"`
+	BountyID  uint   `gorm:"not null;uniqueIndex:idx_batch_payout_item" json:"bounty_id"`
+	Amount    uint   `gorm:"not null" json:"amount"`
+	PaymentID uint   `json:"payment_id,omitempty"`
+	Tag       string `gorm:"type:varchar(255)" json:"tag,omitempty"`
+	Status    string `gorm:"type:varchar(20);not null" json:"status"`
+	Error     string `gorm:"type:text" json:"error,omitempty"`
+}
+
+// BatchPayoutResolveRequest resolves an item held for review. Items with
+// a keysend tag are resolved with the node; Release gives up on an item
+// without one, once the caller made sure its keysend never went out.
+type BatchPayoutResolveRequest struct {
+	Release bool `json:"release"`
+}
+
+type BatchPayoutRequest struct {
+	WorkspaceUuid   string `json:"workspace_uuid"`
+	BountyIDs       []uint `json:"bounty_ids"`
+	Websocket_token string `json:"websocket_token,omitempty"`
+}
+
+// BatchPayoutResult is the outcome of one requested bounty. Bounties that
+// could not be paid at all are reported as skipped with the reason.
+type BatchPayoutResult struct {
+	BountyID  uint   `json:"bounty_id"`
+	Amount    uint   `json:"amount"`
+	PaymentID uint   `json:"payment_id,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+type BatchPayoutResponse struct {
+	Batch   BatchPayout         `json:"batch"`
+	Results []BatchPayoutResult `json:"results"`
+}
+
+// WorkspaceSearchResult is one ranked hit of a workspace search. Highlight
+// is an excerpt of the matched text with matches wrapped in <mark> tags.
+type WorkspaceSearchResult struct {
+	EntityType  string  `json:"entity_type"`
+	EntityID    string  `json:"entity_id"`
+	Title       string  `json:"title"`
+	FeatureUuid string  `json:"feature_uuid,omitempty"`
+	PhaseUuid   string  `json:"phase_uuid,omitempty"`
+	Highlight   string  `json:"highlight"`
+	Rank        float64 `json:"rank"`
+	Total       int64   `json:"-"`
+}
+
+type WorkspaceSearchResponse struct {
+	Results []WorkspaceSearchResult `json:"results"`
+	Total   int64                   `json:"total"`
+}
+
 func (Person) TableName() string {
 	return "people"
 }
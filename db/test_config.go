@@ -94,6 +94,32 @@ func InitTestDB() {
 	db.AutoMigrate(&BountyStake{})
 	db.AutoMigrate(&ChatWorkflowStatus{})
 	db.AutoMigrate(&BountyStakeProcess{})
+	db.AutoMigrate(&WebsocketOutboxStream{})
+	db.AutoMigrate(&WebsocketOutboxMessage{})
+	db.AutoMigrate(&WebhookSubscription{})
+	db.AutoMigrate(&WebhookDelivery{})
+	db.AutoMigrate(&AuditLog{})
+	db.AutoMigrate(&LedgerAccount{})
+	db.AutoMigrate(&LedgerTransaction{})
+	db.AutoMigrate(&LedgerEntry{})
+	db.AutoMigrate(&IdempotencyKey{})
+	db.AutoMigrate(&Job{})
+	db.AutoMigrate(&WorkspacePaymentPolicy{})
+	db.AutoMigrate(&BatchPayout{})
+	db.AutoMigrate(&BatchPayoutItem{})
+	db.AutoMigrate(&WorkspaceAssetBudget{})
+	db.AutoMigrate(&BountyAssignee{})
+	db.AutoMigrate(&BountyMilestone{})
+	db.AutoMigrate(&WorkspaceSpendLimit{})
+	db.AutoMigrate(&WorkspaceBudgetAlert{})
+	db.AutoMigrate(&WorkspaceWorkflow{})
+	db.AutoMigrate(&ChatSummary{})
+	db.AutoMigrate(&FileRetention{})
+
+	TestDB.migrateSearchIndexes()
+	TestDB.migrateAuditLog()
+	TestDB.migrateLedger()
+	TestDB.migrateJobs()
 	
 	people := TestDB.GetAllPeople()
 	for _, p := range people {
@@ -7,7 +7,6 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
-	"github.com/stakwork/sphinx-tribes/logger"
 	"gorm.io/gorm"
 )
 
@@ -198,7 +197,7 @@ func (db database) UpdateTicketsWithoutGroup(ticket Tickets) error {
 		data["author"] = "HUMAN"
 	}
 
-	logger.Log.Info("data === %v", data)
+	db.log().Info("data === %v", data)
 
 	result := db.db.Model(&Tickets{}).Where("uuid = ?", ticket.UUID).Updates(data)
 
@@ -241,7 +240,7 @@ func (db database) CreateBountyFromTicket(ticket Tickets, pubkey string) (*NewBo
 	}
 
 	if err := db.db.Create(bounty).Error; err != nil {
-		logger.Log.Error("failed to create bounty", "error", err, "ticket_id", ticket.UUID)
+		db.log().Error("failed to create bounty", "error", err, "ticket_id", ticket.UUID)
 		return nil, fmt.Errorf("failed to create bounty: %w", err)
 	}
 
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (db database) CreateWebhookSubscription(subscription WebhookSubscription) (WebhookSubscription, error) {
+	if subscription.WorkspaceUuid == "" {
+		return WebhookSubscription{}, errors.New("workspace uuid is required")
+	}
+	if subscription.Url == "" {
+		return WebhookSubscription{}, errors.New("url is required")
+	}
+	if subscription.Secret == "" {
+		return WebhookSubscription{}, errors.New("secret is required")
+	}
+
+	now := time.Now()
+	if subscription.Uuid == "" {
+		subscription.Uuid = uuid.New().String()
+	}
+	subscription.Created = &now
+	subscription.Updated = &now
+
+	if err := db.db.Create(&subscription).Error; err != nil {
+		return WebhookSubscription{}, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (db database) UpdateWebhookSubscription(subscription WebhookSubscription) (WebhookSubscription, error) {
+	if subscription.Uuid == "" {
+		return WebhookSubscription{}, errors.New("webhook uuid is required")
+	}
+
+	now := time.Now()
+	subscription.Updated = &now
+
+	result := db.db.Model(&WebhookSubscription{}).
+		Where("uuid = ?", subscription.Uuid).
+		Select("url", "secret", "events", "active", "updated").
+		Updates(&subscription)
+	if result.Error != nil {
+		return WebhookSubscription{}, fmt.Errorf("failed to update webhook subscription: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return WebhookSubscription{}, errors.New("webhook subscription not found")
+	}
+
+	return db.GetWebhookSubscriptionByUuid(subscription.Uuid)
+}
+
+func (db database) GetWebhookSubscriptionByUuid(uuid string) (WebhookSubscription, error) {
+	subscription := WebhookSubscription{}
+
+	if err := db.db.Where("uuid = ?", uuid).First(&subscription).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return WebhookSubscription{}, errors.New("webhook subscription not found")
+		}
+		return WebhookSubscription{}, fmt.Errorf("failed to fetch webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (db database) GetWebhookSubscriptionsByWorkspace(workspaceUuid string) ([]WebhookSubscription, error) {
+	var subscriptions []WebhookSubscription
+
+	if err := db.db.Where("workspace_uuid = ?", workspaceUuid).Order("id ASC").Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// GetActiveWebhookSubscriptions returns the active subscriptions of a
+// workspace that want event. A subscription without an event filter
+// receives every event.
+func (db database) GetActiveWebhookSubscriptions(workspaceUuid string, event string) ([]WebhookSubscription, error) {
+	var subscriptions []WebhookSubscription
+
+	err := db.db.
+		Where("workspace_uuid = ? AND active = ?", workspaceUuid, true).
+		Where("events IS NULL OR cardinality(events) = 0 OR ? = ANY(events)", event).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (db database) DeleteWebhookSubscription(uuid string) error {
+	return db.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("uuid = ?", uuid).Delete(&WebhookSubscription{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete webhook subscription: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("webhook subscription not found")
+		}
+
+		if err := tx.Where("subscription_uuid = ?", uuid).Delete(&WebhookDelivery{}).Error; err != nil {
+			return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+		}
+		return nil
+	})
+}
+
+func (db database) CreateWebhookDelivery(delivery *WebhookDelivery) error {
+	if delivery.SubscriptionUuid == "" {
+		return errors.New("subscription uuid is required")
+	}
+
+	now := time.Now()
+	if delivery.Uuid == "" {
+		delivery.Uuid = uuid.New().String()
+	}
+	if delivery.Status == "" {
+		delivery.Status = WebhookDeliveryPending
+	}
+	delivery.Created = &now
+	delivery.Updated = &now
+
+	if err := db.db.Create(delivery).Error; err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (db database) UpdateWebhookDelivery(delivery *WebhookDelivery) error {
+	if delivery.Uuid == "" {
+		return errors.New("delivery uuid is required")
+	}
+
+	now := time.Now()
+	delivery.Updated = &now
+
+	err := db.db.Model(&WebhookDelivery{}).
+		Where("uuid = ?", delivery.Uuid).
+		Select("status", "attempts", "response_status", "error", "next_attempt_at", "delivered_at", "updated").
+		Updates(delivery).Error
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// GetDueWebhookDeliveries returns pending deliveries whose next attempt is
+// at or before now, oldest first.
+func (db database) GetDueWebhookDeliveries(now time.Time, limit int) ([]WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var deliveries []WebhookDelivery
+	err := db.db.
+		Where("status = ? AND next_attempt_at <= ?", WebhookDeliveryPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch due webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (db database) GetWebhookDeliveries(subscriptionUuid string, limit int) ([]WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var deliveries []WebhookDelivery
+	err := db.db.
+		Where("subscription_uuid = ?", subscriptionUuid).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSubscriptions(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	workspaceUuid := uuid.New().String()
+
+	all, err := TestDB.CreateWebhookSubscription(WebhookSubscription{
+		WorkspaceUuid: workspaceUuid,
+		Url:           "https://example.com/all",
+		Secret:        "secret",
+		Active:        true,
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, all.Uuid)
+
+	paid, err := TestDB.CreateWebhookSubscription(WebhookSubscription{
+		WorkspaceUuid: workspaceUuid,
+		Url:           "https://example.com/paid",
+		Secret:        "secret",
+		Events:        []string{"bounty.paid"},
+		Active:        true,
+	})
+	assert.NoError(t, err)
+
+	t.Run("Validation", func(t *testing.T) {
+		_, err := TestDB.CreateWebhookSubscription(WebhookSubscription{WorkspaceUuid: workspaceUuid, Secret: "secret"})
+		assert.Error(t, err)
+
+		_, err = TestDB.CreateWebhookSubscription(WebhookSubscription{WorkspaceUuid: workspaceUuid, Url: "https://example.com"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Active Subscriptions Match Event Filter", func(t *testing.T) {
+		subscriptions, err := TestDB.GetActiveWebhookSubscriptions(workspaceUuid, "bounty.paid")
+		assert.NoError(t, err)
+		assert.Len(t, subscriptions, 2)
+
+		subscriptions, err = TestDB.GetActiveWebhookSubscriptions(workspaceUuid, "bounty.created")
+		assert.NoError(t, err)
+		assert.Len(t, subscriptions, 1)
+		assert.Equal(t, all.Uuid, subscriptions[0].Uuid)
+	})
+
+	t.Run("Disabled Subscriptions Are Skipped", func(t *testing.T) {
+		paid.Active = false
+		updated, err := TestDB.UpdateWebhookSubscription(paid)
+		assert.NoError(t, err)
+		assert.False(t, updated.Active)
+
+		subscriptions, err := TestDB.GetActiveWebhookSubscriptions(workspaceUuid, "bounty.paid")
+		assert.NoError(t, err)
+		assert.Len(t, subscriptions, 1)
+	})
+
+	t.Run("List By Workspace", func(t *testing.T) {
+		subscriptions, err := TestDB.GetWebhookSubscriptionsByWorkspace(workspaceUuid)
+		assert.NoError(t, err)
+		assert.Len(t, subscriptions, 2)
+	})
+
+	t.Run("Delete Removes Deliveries", func(t *testing.T) {
+		delivery := &WebhookDelivery{SubscriptionUuid: paid.Uuid, Event: "bounty.paid", Payload: "{}"}
+		assert.NoError(t, TestDB.CreateWebhookDelivery(delivery))
+
+		assert.NoError(t, TestDB.DeleteWebhookSubscription(paid.Uuid))
+		assert.Error(t, TestDB.DeleteWebhookSubscription(paid.Uuid))
+
+		_, err := TestDB.GetWebhookSubscriptionByUuid(paid.Uuid)
+		assert.Error(t, err)
+
+		deliveries, err := TestDB.GetWebhookDeliveries(paid.Uuid, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, deliveries)
+	})
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	subscriptionUuid := uuid.New().String()
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	due := &WebhookDelivery{SubscriptionUuid: subscriptionUuid, Event: "bounty.created", Payload: "{}", NextAttemptAt: &now}
+	notDue := &WebhookDelivery{SubscriptionUuid: subscriptionUuid, Event: "bounty.created", Payload: "{}", NextAttemptAt: &later}
+	assert.NoError(t, TestDB.CreateWebhookDelivery(due))
+	assert.NoError(t, TestDB.CreateWebhookDelivery(notDue))
+	assert.Equal(t, WebhookDeliveryPending, due.Status)
+
+	t.Run("Due Deliveries", func(t *testing.T) {
+		deliveries, err := TestDB.GetDueWebhookDeliveries(now.Add(time.Second), 100)
+		assert.NoError(t, err)
+
+		var uuids []string
+		for _, d := range deliveries {
+			uuids = append(uuids, d.Uuid)
+		}
+		assert.Contains(t, uuids, due.Uuid)
+		assert.NotContains(t, uuids, notDue.Uuid)
+	})
+
+	t.Run("Delivered Deliveries Are No Longer Due", func(t *testing.T) {
+		due.Status = WebhookDeliveryDelivered
+		due.Attempts = 1
+		due.ResponseStatus = 200
+		due.NextAttemptAt = nil
+		assert.NoError(t, TestDB.UpdateWebhookDelivery(due))
+
+		deliveries, err := TestDB.GetDueWebhookDeliveries(now.Add(time.Second), 100)
+		assert.NoError(t, err)
+		for _, d := range deliveries {
+			assert.NotEqual(t, due.Uuid, d.Uuid)
+		}
+	})
+
+	t.Run("Delivery Log Is Newest First", func(t *testing.T) {
+		deliveries, err := TestDB.GetWebhookDeliveries(subscriptionUuid, 10)
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 2)
+		assert.Equal(t, notDue.Uuid, deliveries[0].Uuid)
+		assert.Equal(t, WebhookDeliveryDelivered, deliveries[1].Status)
+	})
+}
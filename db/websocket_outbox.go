@@ -0,0 +1,103 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AppendWebsocketOutboxMessage stores a websocket message for replay. The
+// sequence is allocated from a per-stream counter row so it stays
+// monotonic even when several replicas write to the same stream.
+func (db database) AppendWebsocketOutboxMessage(stream string, kind string, payload PropertyMap) (*WebsocketOutboxMessage, error) {
+	if stream == "" {
+		return nil, errors.New("stream is required")
+	}
+	if payload == nil {
+		payload = PropertyMap{}
+	}
+
+	now := time.Now()
+	message := &WebsocketOutboxMessage{
+		ID:        uuid.New(),
+		Stream:    stream,
+		Kind:      kind,
+		Payload:   payload,
+		CreatedAt: now,
+	}
+
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		var sequence uint64
+		if err := tx.Raw(`
+			INSERT INTO websocket_outbox_streams (stream, last_sequence, updated_at)
+			VALUES (?, 1, ?)
+			ON CONFLICT (stream) DO UPDATE
+				SET last_sequence = websocket_outbox_streams.last_sequence + 1,
+					updated_at = EXCLUDED.updated_at
+			RETURNING last_sequence`, stream, now).Scan(&sequence).Error; err != nil {
+			return err
+		}
+
+		message.Sequence = sequence
+		return tx.Create(message).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to append websocket outbox message: %w", err)
+	}
+
+	return message, nil
+}
+
+// ClaimWebsocketOutboxStream gives a stream nobody owns yet to owner and
+// returns the owner of the stream, which is not owner when another pubkey
+// claimed it first.
+func (db database) ClaimWebsocketOutboxStream(stream string, owner string) (string, error) {
+	if stream == "" || owner == "" {
+		return "", errors.New("stream and owner are required")
+	}
+
+	var claimed string
+	if err := db.db.Raw(`
+		INSERT INTO websocket_outbox_streams (stream, owner, last_sequence, updated_at)
+		VALUES (?, ?, 0, ?)
+		ON CONFLICT (stream) DO UPDATE
+			SET owner = CASE WHEN websocket_outbox_streams.owner = '' THEN EXCLUDED.owner
+				ELSE websocket_outbox_streams.owner END
+		RETURNING owner`, stream, owner, time.Now()).Scan(&claimed).Error; err != nil {
+		return "", fmt.Errorf("failed to claim websocket outbox stream %s: %w", stream, err)
+	}
+	return claimed, nil
+}
+
+func (db database) GetWebsocketOutboxMessagesAfter(stream string, sequence uint64, limit int) ([]WebsocketOutboxMessage, error) {
+	if stream == "" {
+		return nil, errors.New("stream is required")
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var messages []WebsocketOutboxMessage
+	if err := db.db.Where("stream = ? AND sequence > ?", stream, sequence).
+		Order("sequence ASC").
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve websocket outbox messages for %s: %w", stream, err)
+	}
+
+	return messages, nil
+}
+
+func (db database) DeleteOldWebsocketOutboxMessages(maxAge time.Duration) (int64, error) {
+	cutoffTime := time.Now().Add(-maxAge)
+
+	result := db.db.Where("created_at < ?", cutoffTime).Delete(&WebsocketOutboxMessage{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete old websocket outbox messages: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
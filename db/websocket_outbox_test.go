@@ -0,0 +1,110 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendWebsocketOutboxMessage(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	stream := "session:" + uuid.New().String()
+
+	t.Run("Sequences Start At One And Increase", func(t *testing.T) {
+		for i := 1; i <= 3; i++ {
+			message, err := TestDB.AppendWebsocketOutboxMessage(stream, "ticket", PropertyMap{"message": "hello"})
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(i), message.Sequence)
+			assert.Equal(t, stream, message.Stream)
+			assert.Equal(t, "ticket", message.Kind)
+		}
+	})
+
+	t.Run("Streams Have Independent Sequences", func(t *testing.T) {
+		other := "feature:" + uuid.New().String()
+		message, err := TestDB.AppendWebsocketOutboxMessage(other, "ticket", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), message.Sequence)
+		assert.NotNil(t, message.Payload)
+	})
+
+	t.Run("Empty Stream", func(t *testing.T) {
+		message, err := TestDB.AppendWebsocketOutboxMessage("", "ticket", PropertyMap{})
+		assert.Error(t, err)
+		assert.Nil(t, message)
+	})
+}
+
+func TestGetWebsocketOutboxMessagesAfter(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	stream := "session:" + uuid.New().String()
+	for i := 0; i < 5; i++ {
+		_, err := TestDB.AppendWebsocketOutboxMessage(stream, "ticket", PropertyMap{"index": i})
+		assert.NoError(t, err)
+	}
+
+	tests := []struct {
+		name          string
+		stream        string
+		after         uint64
+		limit         int
+		expectedFirst uint64
+		expectedCount int
+		expectError   bool
+	}{
+		{name: "From Beginning", stream: stream, after: 0, limit: 10, expectedFirst: 1, expectedCount: 5},
+		{name: "Only The Gap", stream: stream, after: 3, limit: 10, expectedFirst: 4, expectedCount: 2},
+		{name: "Limit Applied", stream: stream, after: 0, limit: 2, expectedFirst: 1, expectedCount: 2},
+		{name: "Nothing Missed", stream: stream, after: 5, limit: 10, expectedCount: 0},
+		{name: "Unknown Stream", stream: "session:unknown", after: 0, limit: 10, expectedCount: 0},
+		{name: "Empty Stream", stream: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := TestDB.GetWebsocketOutboxMessagesAfter(tt.stream, tt.after, tt.limit)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, messages, tt.expectedCount)
+			if tt.expectedCount > 0 {
+				assert.Equal(t, tt.expectedFirst, messages[0].Sequence)
+				for i := 1; i < len(messages); i++ {
+					assert.Greater(t, messages[i].Sequence, messages[i-1].Sequence)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteOldWebsocketOutboxMessages(t *testing.T) {
+	InitTestDB()
+	defer CloseTestDB()
+
+	stream := "session:" + uuid.New().String()
+	old, err := TestDB.AppendWebsocketOutboxMessage(stream, "ticket", PropertyMap{})
+	assert.NoError(t, err)
+	TestDB.db.Model(&WebsocketOutboxMessage{}).Where("id = ?", old.ID).
+		Update("created_at", time.Now().Add(-48*time.Hour))
+
+	recent, err := TestDB.AppendWebsocketOutboxMessage(stream, "ticket", PropertyMap{})
+	assert.NoError(t, err)
+
+	removed, err := TestDB.DeleteOldWebsocketOutboxMessages(24 * time.Hour)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, removed, int64(1))
+
+	messages, err := TestDB.GetWebsocketOutboxMessagesAfter(stream, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, recent.Sequence, messages[0].Sequence)
+}
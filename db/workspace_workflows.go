@@ -0,0 +1,78 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/stakwork/sphinx-tribes/workflows"
+	"gorm.io/gorm"
+)
+
+// ValidateWorkspaceWorkflows checks that every workflow is of a known kind
+// and provider, and that each kind is picked at most once.
+func ValidateWorkspaceWorkflows(picked []WorkspaceWorkflow) error {
+	seen := map[string]bool{}
+	for _, workflow := range picked {
+		if _, ok := workflows.Defaults[workflows.Kind(workflow.Kind)]; !ok {
+			return fmt.Errorf("unknown workflow kind %q, kinds are %v", workflow.Kind, workflows.Kinds)
+		}
+		if seen[workflow.Kind] {
+			return fmt.Errorf("kind %q has more than one workflow", workflow.Kind)
+		}
+		seen[workflow.Kind] = true
+
+		known := false
+		for _, provider := range workflows.Providers {
+			known = known || provider == workflow.Provider
+		}
+		if !known {
+			return fmt.Errorf("unknown workflow provider %q, providers are %v", workflow.Provider, workflows.Providers)
+		}
+	}
+	return nil
+}
+
+// GetWorkspaceWorkflows lists the workflows a workspace picked, by kind.
+func (db database) GetWorkspaceWorkflows(workspace_uuid string) []WorkspaceWorkflow {
+	workflows := []WorkspaceWorkflow{}
+	db.db.Where("workspace_uuid = ?", workspace_uuid).Order("kind").Find(&workflows)
+	return workflows
+}
+
+// GetWorkspaceWorkflow returns the workflow a workspace runs kind with, an
+// empty one when it runs the default.
+func (db database) GetWorkspaceWorkflow(workspace_uuid string, kind string) WorkspaceWorkflow {
+	workflow := WorkspaceWorkflow{}
+	db.db.Where("workspace_uuid = ? AND kind = ?", workspace_uuid, kind).Find(&workflow)
+	return workflow
+}
+
+// SaveWorkspaceWorkflows replaces the workflows a workspace picked, kinds
+// left out go back to the default.
+func (db database) SaveWorkspaceWorkflows(workspace_uuid string, workflows []WorkspaceWorkflow) ([]WorkspaceWorkflow, error) {
+	now := time.Now()
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("workspace_uuid = ?", workspace_uuid).Delete(&WorkspaceWorkflow{}).Error; err != nil {
+			return err
+		}
+		if len(workflows) == 0 {
+			return nil
+		}
+		saved := make([]WorkspaceWorkflow, len(workflows))
+		for i, workflow := range workflows {
+			saved[i] = WorkspaceWorkflow{
+				WorkspaceUuid: workspace_uuid,
+				Kind:          workflow.Kind,
+				Provider:      workflow.Provider,
+				WorkflowID:    workflow.WorkflowID,
+				Created:       now,
+				Updated:       now,
+			}
+		}
+		return tx.Create(&saved).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save workspace workflows: %w", err)
+	}
+	return db.GetWorkspaceWorkflows(workspace_uuid), nil
+}
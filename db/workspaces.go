@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/stakwork/sphinx-tribes/utils"
+	"gorm.io/gorm"
 )
 
 func (db database) GetWorkspaces(r *http.Request) []Workspace {
@@ -183,8 +184,22 @@ func (db database) AddBudgetHistory(budget BudgetHistory) BudgetHistory {
 	return budget
 }
 
+// CreateWorkspaceBudget stores a budget and opens the workspace's ledger
+// with its TotalBudget.
 func (db database) CreateWorkspaceBudget(budget NewBountyBudget) NewBountyBudget {
-	db.db.Create(&budget)
+	db.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&budget).Error; err != nil {
+			return err
+		}
+		if budget.TotalBudget == 0 || budget.WorkspaceUuid == "" {
+			return nil
+		}
+		return postLedgerTransaction(tx, LedgerTransaction{
+			WorkspaceUuid: budget.WorkspaceUuid,
+			Reference:     "opening:" + budget.WorkspaceUuid,
+			Kind:          "opening",
+		}, ledgerMove(LedgerExternal, "", LedgerWorkspace, "", budget.TotalBudget)...)
+	})
 	return budget
 }
 
@@ -201,10 +216,16 @@ func (db database) GetPaymentHistoryByCreated(created *time.Time, workspace_uuid
 	return ms
 }
 
+// GetWorkspaceBudget returns the sats budget of a workspace with
+// TotalBudget read from the balance of its ledger account. The stored
+// column is only compared with the ledger by ReconcileLedger.
 func (db database) GetWorkspaceBudget(workspace_uuid string) NewBountyBudget {
 	ms := NewBountyBudget{}
 	db.db.Model(&NewBountyBudget{}).Where("workspace_uuid = ?", workspace_uuid).Find(&ms)
 
+	if workspace_uuid != "" {
+		ms.TotalBudget = db.ledgerBudget(workspace_uuid, 0, ms.TotalBudget)
+	}
 	return ms
 }
 
@@ -333,7 +354,7 @@ func (db database) ProcessUpdateBudget(non_tx_invoice NewInvoiceList) error {
 
 			// get total deposits
 			var depositAmount uint
-			tx.Model(&NewPaymentHistory{}).Where("workspace_uuid = ?", workspace_uuid).Where("status = ?", true).Where("payment_type = ?", "deposit").Select("SUM(amount)").Row().Scan(&depositAmount)
+			tx.Model(&NewPaymentHistory{}).Where("workspace_uuid = ?", workspace_uuid).Where("status = ?", true).Where("payment_type = ?", "deposit").Where("asset_id = ?", 0).Select("SUM(amount)").Row().Scan(&depositAmount)
 
 			log.Println("Budget DepositAmount =====", depositAmount, workspace_uuid)
 
@@ -356,6 +377,11 @@ func (db database) ProcessUpdateBudget(non_tx_invoice NewInvoiceList) error {
 			}
 		}
 
+		if err = postLedgerDeposit(tx, paymentHistory); err != nil {
+			tx.Rollback()
+			return err
+		}
+
 		// update invoice
 		if err = tx.Model(&NewInvoiceList{}).Where("payment_request = ?", invoice.PaymentRequest).Update("status", true).Error; err != nil {
 			tx.Rollback()
@@ -411,7 +437,7 @@ func (db database) AddAndUpdateBudget(invoice NewInvoiceList) NewPaymentHistory
 
 			// get total deposits
 			var depositAmount uint
-			tx.Model(&NewPaymentHistory{}).Where("workspace_uuid = ?", workspace_uuid).Where("status = ?", true).Where("payment_type = ?", "deposit").Select("SUM(amount)").Row().Scan(&depositAmount)
+			tx.Model(&NewPaymentHistory{}).Where("workspace_uuid = ?", workspace_uuid).Where("status = ?", true).Where("payment_type = ?", "deposit").Where("asset_id = ?", 0).Select("SUM(amount)").Row().Scan(&depositAmount)
 
 			log.Println("Budget DepositAmount =====", depositAmount, workspace_uuid)
 
@@ -432,6 +458,10 @@ func (db database) AddAndUpdateBudget(invoice NewInvoiceList) NewPaymentHistory
 				tx.Rollback()
 			}
 		}
+
+		if err := postLedgerDeposit(tx, paymentHistory); err != nil {
+			tx.Rollback()
+		}
 	} else {
 		tx.Rollback()
 	}
@@ -483,6 +513,10 @@ func (db database) WithdrawBudget(sender_pubkey string, workspace_uuid string, a
 	if err = tx.Create(&budgetHistory).Error; err != nil {
 		tx.Rollback()
 	}
+
+	if err = postLedgerWithdrawal(tx, budgetHistory); err != nil {
+		tx.Rollback()
+	}
 	tx.Commit()
 }
 
@@ -513,16 +547,7 @@ func (db database) ProcessBountyPayment(payment NewPaymentHistory, bounty NewBou
 	}
 
 	if payment.PaymentStatus != PaymentFailed {
-		workspace_uuid := payment.WorkspaceUuid
-
-		// get Workspace budget and subtract payment from total budget
-		WorkspaceBudget := db.GetWorkspaceBudget(workspace_uuid)
-		totalBudget := WorkspaceBudget.TotalBudget
-		// update budget
-		WorkspaceBudget.TotalBudget = totalBudget - payment.Amount
-		if err = tx.Model(&NewBountyBudget{}).Where("workspace_uuid = ?", payment.WorkspaceUuid).Updates(map[string]interface{}{
-			"total_budget": WorkspaceBudget.TotalBudget,
-		}).Error; err != nil {
+		if err = spendBountyPayment(tx, payment); err != nil {
 			tx.Rollback()
 			return err
 		}
@@ -546,6 +571,24 @@ func (db database) ProcessBountyPayment(payment NewPaymentHistory, bounty NewBou
 	return tx.Commit().Error
 }
 
+// spendBountyPayment takes a bounty payment from the workspace budget in
+// its denomination and posts it to the ledger. The budget is updated in
+// place so a batch reservation made meanwhile is not overwritten.
+func spendBountyPayment(tx *gorm.DB, payment NewPaymentHistory) error {
+	var err error
+	if payment.AssetId != 0 {
+		err = updateAssetBudget(tx, payment.WorkspaceUuid, payment.AssetId, -int64(payment.Amount))
+	} else {
+		err = tx.Model(&NewBountyBudget{}).Where("workspace_uuid = ?", payment.WorkspaceUuid).Updates(map[string]interface{}{
+			"total_budget": gorm.Expr("total_budget - ?", payment.Amount),
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return postLedgerBountyPayment(tx, payment)
+}
+
 func (db database) GetPaymentHistory(workspace_uuid string, r *http.Request) []NewPaymentHistory {
 	payment := []NewPaymentHistory{}
 
@@ -563,7 +606,7 @@ func (db database) GetPaymentHistory(workspace_uuid string, r *http.Request) []N
 func (db database) GetPendingPaymentHistory() []NewPaymentHistory {
 	paymentHistories := []NewPaymentHistory{}
 
-	query := `SELECT * FROM payment_histories WHERE payment_status = '` + PaymentPending + `' AND status = true AND payment_type = 'payment' ORDER BY created DESC`
+	query := `SELECT * FROM payment_histories WHERE payment_status = '` + PaymentPending + `' AND status = true AND payment_type = 'payment' AND needs_review = false ORDER BY created DESC`
 
 	db.db.Raw(query).Find(&paymentHistories)
 	return paymentHistories
@@ -579,8 +622,37 @@ func (db database) GetPaymentByBountyId(bountyId uint) NewPaymentHistory {
 	return paymentHistories
 }
 
+// SetPaymentAsComplete marks the payments with tag as complete, releasing
+// the pending ones from escrow to their hunter.
 func (db database) SetPaymentAsComplete(tag string) bool {
-	db.db.Model(NewPaymentHistory{}).Where("tag = ?", tag).Update("payment_status", PaymentComplete)
+	err := db.db.Transaction(func(tx *gorm.DB) error {
+		pending := []NewPaymentHistory{}
+		tx.Model(&NewPaymentHistory{}).Where("tag = ?", tag).Where("payment_status = ?", PaymentPending).Find(&pending)
+
+		if err := tx.Model(NewPaymentHistory{}).Where("tag = ?", tag).Update("payment_status", PaymentComplete).Error; err != nil {
+			return err
+		}
+
+		for _, payment := range pending {
+			if payment.PaymentType != Payment || payment.WorkspaceUuid == "" {
+				continue
+			}
+			if err := postLedgerSettlement(tx, payment); err != nil {
+				return err
+			}
+			if err := setSharePaymentStatus(tx, payment, PaymentComplete); err != nil {
+				return err
+			}
+			if err := setMilestonePaymentStatus(tx, payment, PaymentComplete); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Could not complete payment %s: %s", tag, err)
+		return false
+	}
 	return true
 }
 
@@ -713,7 +785,7 @@ func (db database) GetWorkspacePendingPayments(workspace_uuid string) []NewPayme
 
 func (db database) GetSumOfDeposits(workspace_uuid string) uint {
 	var depositAmount uint
-	db.db.Model(&NewPaymentHistory{}).Where("workspace_uuid = ?", workspace_uuid).Where("status = ?", true).Where("payment_type = ?", "deposit").Select("SUM(amount)").Row().Scan(&depositAmount)
+	db.db.Model(&NewPaymentHistory{}).Where("workspace_uuid = ?", workspace_uuid).Where("status = ?", true).Where("payment_type = ?", "deposit").Where("asset_id = ?", 0).Select("SUM(amount)").Row().Scan(&depositAmount)
 
 	return depositAmount
 }
@@ -770,7 +842,13 @@ func (db database) ProcessReversePayments(paymentId uint) error {
 		return errors.New("not a valid bounty payment")
 	}
 
-	if paymentHistory.WorkspaceUuid != "" && paymentHistory.Amount != 0 {
+	if paymentHistory.AssetId != 0 {
+		if err = reverseAssetPayment(tx, paymentHistory); err != nil {
+			tx.Rollback()
+			return err
+		}
+	} else if paymentHistory.WorkspaceUuid != "" && paymentHistory.Amount != 0 {
+		wasPending := paymentHistory.PaymentStatus == PaymentPending
 		paymentHistory.PaymentStatus = PaymentFailed
 
 		workspace_uuid := paymentHistory.WorkspaceUuid
@@ -785,7 +863,7 @@ func (db database) ProcessReversePayments(paymentId uint) error {
 		// check that the sum of budget withdrawals and payments is not greater than deposits
 
 		var depositAmount uint
-		tx.Model(&NewPaymentHistory{}).Where("workspace_uuid = ?", workspace_uuid).Where("status = ?", true).Where("payment_type = ?", "deposit").Select("SUM(amount)").Row().Scan(&depositAmount)
+		tx.Model(&NewPaymentHistory{}).Where("workspace_uuid = ?", workspace_uuid).Where("status = ?", true).Where("payment_type = ?", "deposit").Where("asset_id = ?", 0).Select("SUM(amount)").Row().Scan(&depositAmount)
 
 		log.Println("DepositAmount =====", depositAmount)
 
@@ -840,26 +918,50 @@ func (db database) ProcessReversePayments(paymentId uint) error {
 			}).Error; err != nil {
 				tx.Rollback()
 			}
+
+			if err = postLedgerReversal(tx, paymentHistory, wasPending); err != nil {
+				tx.Rollback()
+				return err
+			}
 		}
 	}
 
-	var bounty NewBounty
-
-	// Get bounty
-	if err = tx.Model(&NewBounty{}).Where("id = ?", bounty_id).Find(&bounty).Error; err != nil {
+	// the payment of one share or milestone only fails that part, the
+	// flags of its bounty are worked out from all of its parts below
+	partPayment, err := isPartPayment(tx, paymentId)
+	if err != nil {
 		tx.Rollback()
+		return err
 	}
 
-	bounty.PaymentPending = false
-	bounty.Paid = false
-	bounty.PaymentFailed = true
+	if !partPayment {
+		var bounty NewBounty
 
-	if err = tx.Model(&NewBounty{}).Where("id = ?", bounty_id).Updates(map[string]interface{}{
-		"paid":            bounty.Paid,
-		"payment_pending": bounty.PaymentPending,
-		"payment_failed":  bounty.PaymentFailed,
-	}).Error; err != nil {
+		// Get bounty
+		if err = tx.Model(&NewBounty{}).Where("id = ?", bounty_id).Find(&bounty).Error; err != nil {
+			tx.Rollback()
+		}
+
+		bounty.PaymentPending = false
+		bounty.Paid = false
+		bounty.PaymentFailed = true
+
+		if err = tx.Model(&NewBounty{}).Where("id = ?", bounty_id).Updates(map[string]interface{}{
+			"paid":            bounty.Paid,
+			"payment_pending": bounty.PaymentPending,
+			"payment_failed":  bounty.PaymentFailed,
+		}).Error; err != nil {
+			tx.Rollback()
+		}
+	}
+
+	if err = setSharePaymentStatus(tx, paymentHistory, PaymentFailed); err != nil {
 		tx.Rollback()
+		return err
+	}
+	if err = setMilestonePaymentStatus(tx, paymentHistory, PaymentFailed); err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	log.Println("Reversed Payment Successfully =====", paymentId)
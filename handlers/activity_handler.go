@@ -158,6 +158,8 @@ func (ah *activityHandler) CreateActivity(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	publishActivityEvent("create", createdActivity)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(ActivityResponse{
 		Success: true,
@@ -214,6 +216,8 @@ func (ah *activityHandler) UpdateActivity(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	publishActivityEvent("update", updatedActivity)
+
 	json.NewEncoder(w).Encode(ActivityResponse{
 		Success: true,
 		Data:    updatedActivity,
@@ -270,6 +274,8 @@ func (ah *activityHandler) CreateActivityThread(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	publishActivityEvent("create", createdActivity)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(ActivityResponse{
 		Success: true,
@@ -800,6 +806,8 @@ func (ah *activityHandler) ReceiveActivity(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	publishActivityEvent("create", createdActivity)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(WebhookResponse{
 		Success:    true,
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/audit"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+// assetBackend returns the asset side of a Lightning backend, or
+// lightning.ErrAssetsUnsupported when it only moves sats.
+func assetBackend(backend LightningBackend) (AssetBackend, error) {
+	assets, ok := backend.(AssetBackend)
+	if !ok {
+		return nil, lightning.ErrAssetsUnsupported
+	}
+	return assets, nil
+}
+
+// sendBountyPayment pays a hunter in the bounty's denomination: a keysend
+// of sats when assetId is 0 and an asset transfer otherwise.
+func sendBountyPayment(backend LightningBackend, assetId uint, amount uint, pubkey string, routeHint string, memo string) (db.V2SendOnionRes, error) {
+	if assetId == 0 {
+		return backend.Keysend(amount, pubkey, routeHint, memo)
+	}
+	assets, err := assetBackend(backend)
+	if err != nil {
+		return db.V2SendOnionRes{}, err
+	}
+	return assets.SendAsset(assetId, amount, pubkey, routeHint, memo)
+}
+
+type assetBudgetHandler struct {
+	db            db.Database
+	lightning     LightningBackend
+	userHasAccess func(pubKeyFromAuth string, uuid string, role string) bool
+}
+
+func NewAssetBudgetHandler(database db.Database) *assetBudgetHandler {
+	configHandler := db.NewConfigHandler(database)
+	return &assetBudgetHandler{
+		db:            database,
+		lightning:     lightning.FromConfig(http.DefaultClient),
+		userHasAccess: configHandler.UserHasAccess,
+	}
+}
+
+// authorize writes the error response and returns an empty uuid when the
+// caller lacks role on the workspace in the request.
+func (ah *assetBudgetHandler) authorize(w http.ResponseWriter, r *http.Request, role string) (string, string) {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[asset budget] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return "", ""
+	}
+
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	workspace := ah.db.GetWorkspaceByUuid(workspaceUuid)
+	if workspace.Uuid == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Workspace not found"})
+		return "", ""
+	}
+
+	if !ah.userHasAccess(pubKeyFromAuth, workspaceUuid, role) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions"})
+		return "", ""
+	}
+	return pubKeyFromAuth, workspaceUuid
+}
+
+// GetAssetBudgets godoc
+//
+//	@Summary		Get workspace asset budgets
+//	@Description	The budgets a workspace holds in Taproot assets, the sats budget is served by /workspaces/budget/{uuid}
+//	@Tags			Workspace -  Payments
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path	string	true	"Workspace UUID"
+//	@Success		200				{array}	db.WorkspaceAssetBudget
+//	@Router			/workspaces/{workspace_uuid}/budget/assets [get]
+func (ah *assetBudgetHandler) GetAssetBudgets(w http.ResponseWriter, r *http.Request) {
+	_, workspaceUuid := ah.authorize(w, r, db.ViewReport)
+	if workspaceUuid == "" {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ah.db.GetWorkspaceAssetBudgets(workspaceUuid))
+}
+
+// DepositAssetBudget godoc
+//
+//	@Summary		Fund a workspace asset budget
+//	@Description	Credit an asset transfer the caller sent to the tribes node to the workspace's budget in that asset. Each transfer funds one budget once.
+//	@Tags			Workspace -  Payments
+//	@Accept			json
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string					true	"Workspace UUID"
+//	@Param			request			body		db.AssetDepositRequest	true	"Transfer transaction id"
+//	@Success		200				{object}	db.WorkspaceAssetBudget
+//	@Router			/workspaces/{workspace_uuid}/budget/assets/deposit [post]
+func (ah *assetBudgetHandler) DepositAssetBudget(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, workspaceUuid := ah.authorize(w, r, db.AddBudget)
+	if workspaceUuid == "" {
+		return
+	}
+
+	request := db.AssetDepositRequest{}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err == nil {
+		err = json.Unmarshal(body, &request)
+	}
+	request.Txid = strings.TrimSpace(request.Txid)
+	if err != nil || request.Txid == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "A transfer transaction id is required"})
+		return
+	}
+
+	assets, err := assetBackend(ah.lightning)
+	if err == nil {
+		var transfer db.AssetTx
+		transfer, err = assets.LookupAssetTransfer(request.Txid)
+		if err == nil {
+			ah.deposit(w, r, pubKeyFromAuth, workspaceUuid, transfer)
+			return
+		}
+	}
+
+	switch {
+	case errors.Is(err, lightning.ErrAssetsUnsupported):
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Asset payments are not supported by this node"})
+	case errors.Is(err, lightning.ErrTransferNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Transfer not found"})
+	default:
+		logger.FromContext(r.Context()).Error("[asset budget] could not look up transfer %s: %v", request.Txid, err)
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Could not look up the transfer"})
+	}
+}
+
+func (ah *assetBudgetHandler) deposit(w http.ResponseWriter, r *http.Request, pubKeyFromAuth string, workspaceUuid string, transfer db.AssetTx) {
+	// the transfer only proves who sent it, so only the sender may credit
+	// it to a workspace
+	if transfer.Sender != pubKeyFromAuth {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Transfer was not sent by you"})
+		return
+	}
+	if transfer.AssetId == 0 || transfer.Amount == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Transfer carries no asset"})
+		return
+	}
+
+	budget, err := ah.db.DepositAssetBudget(db.NewPaymentHistory{
+		Amount:         transfer.Amount,
+		AssetId:        transfer.AssetId,
+		WorkspaceUuid:  workspaceUuid,
+		SenderPubKey:   transfer.Sender,
+		ReceiverPubKey: transfer.Receiver,
+		Tag:            transfer.Txid,
+	})
+	if errors.Is(err, db.ErrAssetTransferUsed) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[asset budget] could not deposit transfer %s: %v", transfer.Txid, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to deposit the transfer"})
+		return
+	}
+
+	audit.Record(pubKeyFromAuth, workspaceUuid, audit.EntityBudget, workspaceUuid, audit.ActionDeposit, nil, transfer)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(budget)
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// satsOnlyNode hides the asset calls of the backend it wraps.
+type satsOnlyNode struct {
+	LightningBackend
+}
+
+func TestSendBountyPayment(t *testing.T) {
+	node := lightning.NewFakeNode()
+
+	_, err := sendBountyPayment(node, 0, 1000, "hunter", "", "memo")
+	assert.NoError(t, err)
+	_, err = sendBountyPayment(node, 7, 25, "hunter", "", "memo")
+	assert.NoError(t, err)
+
+	payments := node.Payments()
+	assert.Equal(t, uint(0), payments[0].AssetId)
+	assert.Equal(t, uint(1000), payments[0].Amount)
+	assert.Equal(t, uint(7), payments[1].AssetId)
+	assert.Equal(t, uint(25), payments[1].Amount)
+
+	_, err = sendBountyPayment(satsOnlyNode{node}, 7, 25, "hunter", "", "memo")
+	assert.ErrorIs(t, err, lightning.ErrAssetsUnsupported)
+}
+
+func TestAssetBountyPayment(t *testing.T) {
+	bounty := db.NewBounty{ID: 1, Price: 25, AssetId: 7, WorkspaceUuid: "workspace-1", Assignee: "hunter"}
+	hunter := db.Person{OwnerPubKey: "hunter"}
+	params := map[string]string{"id": "1"}
+
+	expectBounty := func(mockDb *dbMocks.Database, balance uint) {
+		mockDb.On("GetBounty", bounty.ID).Return(bounty).Once()
+		mockDb.On("GetWorkspaceAssetBudget", bounty.WorkspaceUuid, bounty.AssetId).
+			Return(db.WorkspaceAssetBudget{WorkspaceUuid: bounty.WorkspaceUuid, AssetId: bounty.AssetId, Balance: balance})
+		mockDb.On("GetPersonByPubkey", hunter.OwnerPubKey).Return(hunter).Maybe()
+	}
+
+	t.Run("Pays from the asset budget with an asset transfer", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		expectBounty(mockDb, 100)
+		mockDb.On("ProcessBountyPayment", mock.MatchedBy(func(payment db.NewPaymentHistory) bool {
+			return payment.AssetId == 7 && payment.Amount == 25 && payment.PaymentStatus == db.PaymentComplete
+		}), mock.MatchedBy(func(b db.NewBounty) bool { return b.Paid })).Return(nil)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBountyPayment(rr, webhookRequest(http.MethodPost, "/", db.BountyPayRequest{}, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		payments := node.Payments()
+		assert.Len(t, payments, 1)
+		assert.Equal(t, uint(7), payments[0].AssetId)
+		assert.Equal(t, uint(25), payments[0].Amount)
+		mockDb.AssertNotCalled(t, "GetWorkspaceBudget", mock.Anything)
+	})
+
+	t.Run("Forbidden when the asset budget is short", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		expectBounty(mockDb, 10)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBountyPayment(rr, webhookRequest(http.MethodPost, "/", db.BountyPayRequest{}, "owner", params))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Empty(t, node.Payments())
+	})
+
+	t.Run("Not implemented on a sats only node", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		handler.lightning = satsOnlyNode{node}
+		expectBounty(mockDb, 100)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBountyPayment(rr, webhookRequest(http.MethodPost, "/", db.BountyPayRequest{}, "owner", params))
+
+		assert.Equal(t, http.StatusNotImplemented, rr.Code)
+		assert.Empty(t, node.Payments())
+	})
+}
+
+func TestDepositAssetBudget(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	newHandler := func(t *testing.T) (*assetBudgetHandler, *dbMocks.Database, *lightning.FakeNode) {
+		mockDb := dbMocks.NewDatabase(t)
+		node := lightning.NewFakeNode()
+		handler := NewAssetBudgetHandler(mockDb)
+		handler.lightning = node
+		handler.userHasAccess = func(pubKeyFromAuth string, uuid string, role string) bool {
+			return role == db.AddBudget || role == db.ViewReport
+		}
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace).Maybe()
+		return handler, mockDb, node
+	}
+	deposit := func(handler *assetBudgetHandler, pubkey string, txid string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.DepositAssetBudget(rr, webhookRequest(http.MethodPost, "/", db.AssetDepositRequest{Txid: txid}, pubkey, params))
+		return rr
+	}
+
+	t.Run("Unauthorized without pubkey", func(t *testing.T) {
+		handler, _, _ := newHandler(t)
+		assert.Equal(t, http.StatusUnauthorized, deposit(handler, "", "tx").Code)
+	})
+
+	t.Run("Requires a transaction id", func(t *testing.T) {
+		handler, _, _ := newHandler(t)
+		assert.Equal(t, http.StatusBadRequest, deposit(handler, "owner", " ").Code)
+	})
+
+	t.Run("Unknown transfer", func(t *testing.T) {
+		handler, _, _ := newHandler(t)
+		assert.Equal(t, http.StatusNotFound, deposit(handler, "owner", "unknown").Code)
+	})
+
+	t.Run("Only the sender may deposit a transfer", func(t *testing.T) {
+		handler, _, node := newHandler(t)
+		txid := node.ReceiveAsset("someone-else", 7, 100)
+		assert.Equal(t, http.StatusForbidden, deposit(handler, "owner", txid).Code)
+	})
+
+	t.Run("Not implemented on a sats only node", func(t *testing.T) {
+		handler, _, node := newHandler(t)
+		handler.lightning = satsOnlyNode{node}
+		assert.Equal(t, http.StatusNotImplemented, deposit(handler, "owner", "tx").Code)
+	})
+
+	t.Run("Credits the transfer to the asset budget once", func(t *testing.T) {
+		handler, mockDb, node := newHandler(t)
+		txid := node.ReceiveAsset("owner", 7, 100)
+		mockDb.On("DepositAssetBudget", mock.MatchedBy(func(payment db.NewPaymentHistory) bool {
+			return payment.Tag == txid && payment.AssetId == 7 && payment.Amount == 100 && payment.WorkspaceUuid == workspace.Uuid
+		})).Return(db.WorkspaceAssetBudget{WorkspaceUuid: workspace.Uuid, AssetId: 7, Balance: 100}, nil).Once()
+
+		rr := deposit(handler, "owner", txid)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		budget := db.WorkspaceAssetBudget{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &budget))
+		assert.Equal(t, uint(100), budget.Balance)
+
+		mockDb.On("DepositAssetBudget", mock.Anything).Return(db.WorkspaceAssetBudget{}, db.ErrAssetTransferUsed).Once()
+		assert.Equal(t, http.StatusConflict, deposit(handler, "owner", txid).Code)
+	})
+
+	t.Run("Lists the asset budgets", func(t *testing.T) {
+		handler, mockDb, _ := newHandler(t)
+		mockDb.On("GetWorkspaceAssetBudgets", workspace.Uuid).Return([]db.WorkspaceAssetBudget{{AssetId: 7, Balance: 100}})
+		rr := httptest.NewRecorder()
+
+		handler.GetAssetBudgets(rr, webhookRequest(http.MethodGet, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		budgets := []db.WorkspaceAssetBudget{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &budgets))
+		assert.Len(t, budgets, 1)
+	})
+}
+
+func TestCreateAssetBounty(t *testing.T) {
+	owner := db.Person{OwnerPubKey: "owner"}
+	bounty := db.NewBounty{Type: "coding", Title: "asset bounty", Description: "paid in assets", WorkspaceUuid: "workspace-1", Price: 25, AssetId: 7}
+
+	t.Run("Refuses an asset bounty the configured node could never pay", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		handler.lightning = lightning.FromConfig(http.DefaultClient)
+		mockDb.On("GetPersonByPubkey", owner.OwnerPubKey).Return(owner)
+		rr := httptest.NewRecorder()
+
+		handler.CreateOrEditBounty(rr, webhookRequest(http.MethodPost, "/", bounty, "owner", nil))
+
+		assert.Equal(t, http.StatusNotImplemented, rr.Code)
+		mockDb.AssertNotCalled(t, "CreateOrEditBounty", mock.Anything)
+	})
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/stakwork/sphinx-tribes/audit"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+type auditHandler struct {
+	db            db.Database
+	userHasAccess func(pubKeyFromAuth string, uuid string, role string) bool
+}
+
+func NewAuditHandler(database db.Database) *auditHandler {
+	configHandler := db.NewConfigHandler(database)
+	return &auditHandler{
+		db:            database,
+		userHasAccess: configHandler.UserHasAccess,
+	}
+}
+
+// recordChange records a creation when the entity did not exist before
+// and an update otherwise.
+func recordChange(actor string, workspaceUuid string, entityType string, entityID string, existed bool, before interface{}, after interface{}) {
+	if !existed {
+		audit.Record(actor, workspaceUuid, entityType, entityID, audit.ActionCreate, nil, after)
+		return
+	}
+	audit.Record(actor, workspaceUuid, entityType, entityID, audit.ActionUpdate, before, after)
+}
+
+// ticketWorkspaceUuid returns the workspace of a ticket. Tickets don't
+// always carry their workspace, so it is looked up through the feature
+// when missing.
+func ticketWorkspaceUuid(database db.Database, ticket db.Tickets) string {
+	if ticket.WorkspaceUuid == "" && ticket.FeatureUUID != "" {
+		return database.GetFeatureByUuid(ticket.FeatureUUID).WorkspaceUuid
+	}
+	return ticket.WorkspaceUuid
+}
+
+// ticketAuditID keys ticket entries by ticket group, since every edit of a
+// ticket is stored as a new version with its own uuid.
+func ticketAuditID(ticket db.Tickets) string {
+	if ticket.TicketGroup != nil && *ticket.TicketGroup != uuid.Nil {
+		return ticket.TicketGroup.String()
+	}
+	return ticket.UUID.String()
+}
+
+// recordTicketChange records a ticket edit; a nil before is a creation and
+// a nil after a deletion.
+func recordTicketChange(database db.Database, actor string, before *db.Tickets, after *db.Tickets) {
+	switch {
+	case before == nil && after != nil:
+		audit.Record(actor, ticketWorkspaceUuid(database, *after), audit.EntityTicket, ticketAuditID(*after), audit.ActionCreate, nil, after)
+	case before != nil && after == nil:
+		audit.Record(actor, ticketWorkspaceUuid(database, *before), audit.EntityTicket, ticketAuditID(*before), audit.ActionDelete, before, nil)
+	case before != nil:
+		// every edit is stored as a new row, so identity fields always differ
+		previous := *before
+		previous.UUID = after.UUID
+		previous.Version = after.Version
+		previous.CreatedAt = after.CreatedAt
+		audit.Record(actor, ticketWorkspaceUuid(database, *after), audit.EntityTicket, ticketAuditID(*after), audit.ActionUpdate, previous, after)
+	}
+}
+
+// GetAuditLogs godoc
+//
+//	@Summary		Get workspace audit log
+//	@Description	List the audit entries of a workspace, newest first, optionally filtered by entity and actor
+//	@Tags			Workspaces
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string	true	"Workspace UUID"
+//	@Param			entity_type		query		string	false	"Entity type"
+//	@Param			entity_id		query		string	false	"Entity ID"
+//	@Param			actor			query		string	false	"Actor pubkey"
+//	@Param			limit			query		int		false	"Page size, at most 500"
+//	@Param			offset			query		int		false	"Offset"
+//	@Success		200				{object}	db.AuditLogResponse
+//	@Router			/workspaces/{workspace_uuid}/audit [get]
+func (ah *auditHandler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[audit] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	workspace := ah.db.GetWorkspaceByUuid(workspaceUuid)
+	if workspace.Uuid == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Workspace not found"})
+		return
+	}
+
+	if !ah.userHasAccess(pubKeyFromAuth, workspaceUuid, db.ViewReport) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions to view the audit log"})
+		return
+	}
+
+	query := r.URL.Query()
+	filter := db.AuditLogFilter{
+		WorkspaceUuid: workspaceUuid,
+		EntityType:    query.Get("entity_type"),
+		EntityID:      query.Get("entity_id"),
+		Actor:         query.Get("actor"),
+		Limit:         defaultAuditLimit,
+	}
+
+	if filter.EntityType != "" && !audit.ValidEntity(filter.EntityType) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unknown entity type: " + filter.EntityType})
+		return
+	}
+
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 {
+		filter.Limit = limit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	if offset, err := strconv.Atoi(query.Get("offset")); err == nil && offset > 0 {
+		filter.Offset = offset
+	}
+
+	entries, total, err := ah.db.GetAuditLogs(filter)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[audit] could not load audit log of %s: %v", workspaceUuid, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to load audit log"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(db.AuditLogResponse{
+		Entries: entries,
+		Total:   total,
+	})
+}
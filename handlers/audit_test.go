@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stakwork/sphinx-tribes/audit"
+	"github.com/stakwork/sphinx-tribes/db"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAuditHandler(t *testing.T, hasAccess bool) (*auditHandler, *dbMocks.Database) {
+	mockDb := dbMocks.NewDatabase(t)
+	handler := NewAuditHandler(mockDb)
+	handler.userHasAccess = func(pubKeyFromAuth string, uuid string, role string) bool {
+		assert.Equal(t, db.ViewReport, role)
+		return hasAccess
+	}
+	return handler, mockDb
+}
+
+func TestGetAuditLogs(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Unauthorized without pubkey", func(t *testing.T) {
+		handler, _ := newTestAuditHandler(t, true)
+		rr := httptest.NewRecorder()
+
+		handler.GetAuditLogs(rr, webhookRequest(http.MethodGet, "/", nil, "", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Forbidden without view role", func(t *testing.T) {
+		handler, mockDb := newTestAuditHandler(t, false)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		rr := httptest.NewRecorder()
+
+		handler.GetAuditLogs(rr, webhookRequest(http.MethodGet, "/", nil, "member", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Rejects unknown entity type", func(t *testing.T) {
+		handler, mockDb := newTestAuditHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		rr := httptest.NewRecorder()
+
+		handler.GetAuditLogs(rr, webhookRequest(http.MethodGet, "/?entity_type=chat", nil, "owner", params))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Filters and paginates", func(t *testing.T) {
+		handler, mockDb := newTestAuditHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetAuditLogs", db.AuditLogFilter{
+			WorkspaceUuid: workspace.Uuid,
+			EntityType:    audit.EntityFeature,
+			EntityID:      "feature-1",
+			Actor:         "alice",
+			Limit:         maxAuditLimit,
+			Offset:        10,
+		}).Return([]db.AuditLog{
+			{ID: 2, Actor: "alice", EntityType: audit.EntityFeature, EntityID: "feature-1", Action: audit.ActionUpdate,
+				Changes: db.PropertyMap{"brief": map[string]interface{}{"before": "old", "after": "new"}}},
+		}, int64(11), nil)
+		rr := httptest.NewRecorder()
+
+		handler.GetAuditLogs(rr, webhookRequest(http.MethodGet,
+			"/?entity_type=feature&entity_id=feature-1&actor=alice&limit=5000&offset=10", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		response := db.AuditLogResponse{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.Equal(t, int64(11), response.Total)
+		assert.Len(t, response.Entries, 1)
+		assert.Equal(t, "alice", response.Entries[0].Actor)
+		assert.Contains(t, response.Entries[0].Changes, "brief")
+	})
+}
+
+func TestRecordTicketChange(t *testing.T) {
+	store := &auditStore{}
+	original := audit.Default
+	audit.Init(store)
+	defer func() { audit.Default = original }()
+
+	mockDb := dbMocks.NewDatabase(t)
+	mockDb.On("GetFeatureByUuid", "feature-1").Return(db.WorkspaceFeatures{Uuid: "feature-1", WorkspaceUuid: "workspace-1"})
+
+	group := uuid.New()
+	previous := db.Tickets{UUID: uuid.New(), TicketGroup: &group, FeatureUUID: "feature-1", Name: "Ticket", Description: "old", Version: 1}
+	next := db.Tickets{UUID: uuid.New(), TicketGroup: &group, FeatureUUID: "feature-1", Name: "Ticket", Description: "new", Version: 2}
+
+	recordTicketChange(mockDb, "alice", &previous, &next)
+
+	assert.Len(t, store.entries, 1)
+	entry := store.entries[0]
+	assert.Equal(t, "workspace-1", entry.WorkspaceUuid)
+	assert.Equal(t, group.String(), entry.EntityID)
+	assert.Equal(t, audit.ActionUpdate, entry.Action)
+	assert.Equal(t, []string{"description"}, changedFields(entry.Changes))
+}
+
+type auditStore struct {
+	entries []db.AuditLog
+}
+
+func (s *auditStore) CreateAuditLog(entry *db.AuditLog) error {
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
+func changedFields(m db.PropertyMap) []string {
+	var result []string
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
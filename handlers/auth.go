@@ -118,7 +118,7 @@ func (ah *AuthHandler) CreateConnectionCode(w http.ResponseWriter, r *http.Reque
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		logger.Log.Error("ReadAll Error: %v", err)
+		logger.FromContext(r.Context()).Error("ReadAll Error: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -127,7 +127,7 @@ func (ah *AuthHandler) CreateConnectionCode(w http.ResponseWriter, r *http.Reque
 	err = json.Unmarshal(body, &codeBody)
 
 	if err != nil {
-		logger.Log.Error("Could not unmarshal connection code body")
+		logger.FromContext(r.Context()).Error("Could not unmarshal connection code body")
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -165,7 +165,7 @@ func (ah *AuthHandler) CreateConnectionCode(w http.ResponseWriter, r *http.Reque
 	_, err = ah.db.CreateConnectionCode(codeArr)
 
 	if err != nil {
-		logger.Log.Error("[auth] => ERR create connection code: %v", err)
+		logger.FromContext(r.Context()).Error("[auth] => ERR create connection code: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -293,7 +293,7 @@ func ReceiveLnAuthData(w http.ResponseWriter, r *http.Request) {
 
 	exVerify, err := auth.VerifyDerSig(sig, k1, userKey)
 	if err != nil || !exVerify {
-		logger.Log.Error("[auth] Error signing signature")
+		logger.FromContext(r.Context()).Error("[auth] Error signing signature")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(err.Error())
 		return
@@ -312,7 +312,7 @@ func ReceiveLnAuthData(w http.ResponseWriter, r *http.Request) {
 		tokenString, err := auth.EncodeJwt(userKey)
 
 		if err != nil {
-			logger.Log.Error("[auth] error creating LNAUTH JWT")
+			logger.FromContext(r.Context()).Error("[auth] error creating LNAUTH JWT")
 			w.WriteHeader(http.StatusNotAcceptable)
 			json.NewEncoder(w).Encode(err.Error())
 			return
@@ -336,7 +336,7 @@ func ReceiveLnAuthData(w http.ResponseWriter, r *http.Request) {
 			socket.Conn.WriteJSON(socketMsg)
 			db.Store.DeleteCache(k1[0:20])
 		} else {
-			logger.Log.Error("[auth] Socket Error: %v", err)
+			logger.FromContext(r.Context()).Error("[auth] Socket Error: %v", err)
 		}
 
 		responseMsg.Status = "OK"
@@ -365,7 +365,7 @@ func (ah *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	token := r.Header.Get("x-jwt")
 
 	if token == "" {
-		logger.Log.Error("[auth] Missing JWT token")
+		logger.FromContext(r.Context()).Error("[auth] Missing JWT token")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode("Missing JWT token")
 		return
@@ -375,7 +375,7 @@ func (ah *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	claims, err := ah.decodeJwt(token)
 
 	if err != nil {
-		logger.Log.Error("[auth] Failed to parse JWT", err)
+		logger.FromContext(r.Context()).Error("[auth] Failed to parse JWT", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(err.Error())
 		return
@@ -383,7 +383,7 @@ func (ah *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 
 	pubkey, ok := claims["pubkey"].(string)
 	if !ok || pubkey == "" {
-		logger.Log.Error("[auth] Missing pubkey claim in JWT")
+		logger.FromContext(r.Context()).Error("[auth] Missing pubkey claim in JWT")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode("Missing pubkey claim in JWT")
 		return
@@ -396,7 +396,7 @@ func (ah *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		tokenString, err := ah.encodeJwt(pubkey)
 
 		if err != nil {
-			logger.Log.Error("[auth] error creating refresh JWT")
+			logger.FromContext(r.Context()).Error("[auth] error creating refresh JWT")
 			w.WriteHeader(http.StatusNotAcceptable)
 			json.NewEncoder(w).Encode(err.Error())
 			return
@@ -0,0 +1,500 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/audit"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/jobs"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/utils"
+	"github.com/stakwork/sphinx-tribes/webhooks"
+)
+
+const (
+	maxBatchPayoutSize     = 100
+	batchPayoutConcurrency = 4
+	// batchPayoutTimeout is when the job queue finishes a batch its
+	// request left unfinished, releasing the reservation of a payout that
+	// crashed midway.
+	batchPayoutTimeout = time.Hour
+)
+
+type batchPayoutFinishPayload struct {
+	BatchID uint `json:"batch_id"`
+}
+
+// MakeBatchBountyPayment godoc
+//
+//	@Summary		Pay several bounties at once
+//	@Description	Reserve the total of the bounties from the workspace budget in one step, within the spend limits, pay them with bounded concurrency and release what failed back to the budget. Bounties that cannot be paid are skipped and reported with the reason. Payments that may have gone out but could not be confirmed are held for review, their bounty left pending until they are resolved.
+//	@Tags			Bounties - Payment
+//	@Accept			json
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			request	body		db.BatchPayoutRequest	true	"Workspace and bounty IDs"
+//	@Success		200		{object}	db.BatchPayoutResponse
+//	@Router			/gobounties/pay/batch [post]
+func (h *bountyHandler) MakeBatchBountyPayment(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Error("[bounty] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	request := db.BatchPayoutRequest{}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err == nil {
+		err = json.Unmarshal(body, &request)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	bountyIDs := uniqueBountyIDs(request.BountyIDs)
+	if len(bountyIDs) == 0 || len(bountyIDs) > maxBatchPayoutSize {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("A batch pays between 1 and %d bounties", maxBatchPayoutSize)})
+		return
+	}
+
+	workspace := h.db.GetWorkspaceByUuid(request.WorkspaceUuid)
+	if workspace.Uuid == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Workspace not found"})
+		return
+	}
+
+	if !h.userHasAccess(pubKeyFromAuth, workspace.Uuid, db.PayBounty) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions to pay bounties"})
+		return
+	}
+
+	// the budget is read and written under the same lock as single
+	// payments take, the batch only holds it while reserving and releasing
+	h.m.Lock()
+	batch, bounties, results := h.planBatchPayout(workspace.Uuid, pubKeyFromAuth, bountyIDs)
+	if len(batch.Items) == 0 {
+		h.m.Unlock()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(db.BatchPayoutResponse{Batch: batch, Results: results})
+		return
+	}
+	var total uint
+	for _, item := range batch.Items {
+		total += item.Amount
+	}
+	err = h.db.CheckSpendLimit(workspace.Uuid, pubKeyFromAuth, db.PayBounty, total)
+	if err == nil {
+		err = h.db.ReserveBatchPayout(&batch)
+	}
+	h.m.Unlock()
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, db.ErrInsufficientBudget), errors.Is(err, db.ErrSpendLimitExceeded):
+			status = http.StatusForbidden
+		case errors.Is(err, db.ErrBountyNotPayable):
+			status = http.StatusConflict
+		default:
+			logger.FromContext(r.Context()).Error("[bounty] could not reserve batch payout for %s: %v", workspace.Uuid, err)
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	jobs.EnqueueAt(jobs.TypeBatchPayoutFinish, batch.Uuid, batchPayoutFinishPayload{BatchID: batch.ID}, time.Now().Add(batchPayoutTimeout))
+
+	paid := h.payBatch(r.Context(), batch, bounties)
+
+	h.m.Lock()
+	finished, err := h.db.FinishBatchPayout(batch.ID)
+	h.m.Unlock()
+	if err != nil {
+		// the queued job retries the release
+		logger.FromContext(r.Context()).Error("[bounty] could not finish batch payout %s: %v", batch.Uuid, err)
+		finished = batch
+	}
+
+	for i := range results {
+		if result, ok := paid[results[i].BountyID]; ok {
+			results[i] = result
+		}
+	}
+
+	response := db.BatchPayoutResponse{Batch: finished, Results: results}
+	socket, err := h.getSocketConnections(request.Websocket_token)
+	if err == nil {
+		socket.Conn.WriteJSON(response)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// planBatchPayout adds the payable bounties to a batch and reports the
+// others as skipped.
+func (h *bountyHandler) planBatchPayout(workspaceUuid string, sender string, bountyIDs []uint) (db.BatchPayout, map[uint]db.NewBounty, []db.BatchPayoutResult) {
+	batch := db.BatchPayout{WorkspaceUuid: workspaceUuid, SenderPubKey: sender}
+	bounties := map[uint]db.NewBounty{}
+	results := make([]db.BatchPayoutResult, 0, len(bountyIDs))
+
+	for _, id := range bountyIDs {
+		bounty := h.db.GetBounty(id)
+		if bounty.WorkspaceUuid == "" && bounty.OrgUuid != "" {
+			bounty.WorkspaceUuid = bounty.OrgUuid
+		}
+
+		result := db.BatchPayoutResult{BountyID: id, Amount: bounty.Price, Status: db.BatchItemSkipped}
+		switch {
+		case bounty.ID != id:
+			result.Error = "Bounty not found"
+		case bounty.WorkspaceUuid != workspaceUuid:
+			result.Error = "Bounty belongs to another workspace"
+		case bounty.Paid:
+			result.Error = "Bounty has already been paid"
+		case bounty.PaymentPending:
+			result.Error = "Bounty payment is pending"
+		case bounty.Assignee == "":
+			result.Error = "Bounty has no assignee"
+		case bounty.Price == 0:
+			result.Error = "Bounty has no price"
+		case bounty.AssetId != 0:
+			// batches reserve from the sats budget only
+			result.Error = "Bounty is priced in an asset, pay it on its own"
+		case len(bounty.Assignees) > 0:
+			result.Error = "Bounty is split between hunters, pay it on its own"
+		case len(bounty.Milestones) > 0:
+			result.Error = "Bounty is paid by milestones"
+		default:
+			result.Status = db.BatchItemReserved
+			batch.Items = append(batch.Items, db.BatchPayoutItem{BountyID: id, Amount: bounty.Price})
+			bounties[id] = bounty
+		}
+		results = append(results, result)
+	}
+	return batch, bounties, results
+}
+
+// payBatch pays the items of a reserved batch, at most
+// batchPayoutConcurrency at a time, and returns the result per bounty.
+func (h *bountyHandler) payBatch(ctx context.Context, batch db.BatchPayout, bounties map[uint]db.NewBounty) map[uint]db.BatchPayoutResult {
+	results := make(map[uint]db.BatchPayoutResult, len(batch.Items))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, batchPayoutConcurrency)
+
+	for _, item := range batch.Items {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(item db.BatchPayoutItem) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			result := h.payBatchItem(ctx, batch, item, bounties[item.BountyID])
+			mu.Lock()
+			results[item.BountyID] = result
+			mu.Unlock()
+		}(item)
+	}
+	wg.Wait()
+	return results
+}
+
+func (h *bountyHandler) payBatchItem(ctx context.Context, batch db.BatchPayout, item db.BatchPayoutItem, bounty db.NewBounty) db.BatchPayoutResult {
+	result := db.BatchPayoutResult{BountyID: item.BountyID, Amount: item.Amount}
+
+	assignee := h.db.GetPersonByPubkey(bounty.Assignee)
+	memoText := url.QueryEscape(fmt.Sprintf("Payment For: %ss", bounty.Title))
+
+	// once marked, finishing the batch no longer releases the item
+	if err := h.db.StartBatchPayment(batch.ID, item.ID); err != nil {
+		logger.FromContext(ctx).Error("[bounty] could not start batch payment of bounty %d: %v", bounty.ID, err)
+		result.Status = db.BatchItemReleased
+		result.Error = "Batch payout finished before the bounty was paid"
+		return result
+	}
+
+	keysendRes, err := h.lightning.Keysend(item.Amount, assignee.OwnerPubKey, assignee.OwnerRouteHint, memoText)
+	if err != nil && !errors.Is(err, lightning.ErrRejected) {
+		// the keysend may have gone out before the node stopped answering
+		logger.FromContext(ctx).Error("[bounty] Keysend payment of bounty %d failed: %v", bounty.ID, err)
+		return h.holdBatchItem(ctx, batch, item, result, "", "Lightning node unavailable")
+	}
+
+	payment, err := h.recordBatchPayment(batch, item, bounty, assignee.OwnerPubKey, keysendRes, err != nil)
+	if err != nil {
+		log.Printf("[bounty] Could not record batch payment of bounty %d with tag %s: %v", bounty.ID, keysendRes.Tag, err)
+		return h.holdBatchItem(ctx, batch, item, result, keysendRes.Tag, "Payment sent but could not be recorded")
+	}
+
+	result.PaymentID = payment.ID
+	result.Status = payment.PaymentStatus
+	result.Error = payment.Error
+	return result
+}
+
+// recordBatchPayment records the outcome of an item's keysend as the node
+// reported it: a rejected or failed payment frees the bounty, any other is
+// drawn from the batch's reservation.
+func (h *bountyHandler) recordBatchPayment(batch db.BatchPayout, item db.BatchPayoutItem, bounty db.NewBounty, receiver string, keysendRes db.V2SendOnionRes, rejected bool) (db.NewPaymentHistory, error) {
+	now := time.Now()
+	payment := db.NewPaymentHistory{
+		Amount:         item.Amount,
+		SenderPubKey:   batch.SenderPubKey,
+		ReceiverPubKey: receiver,
+		WorkspaceUuid:  batch.WorkspaceUuid,
+		BountyId:       bounty.ID,
+		Created:        &now,
+		Updated:        &now,
+		PaymentType:    db.Payment,
+		Tag:            keysendRes.Tag,
+		PaymentStatus:  db.PaymentFailed,
+	}
+
+	switch {
+	case rejected:
+		payment.Error = "Payment Request Failed"
+	case keysendRes.Status == db.PaymentComplete:
+		payment.Status = true
+		payment.PaymentStatus = db.PaymentComplete
+		bounty.PaymentPending = false
+		bounty.Paid = true
+		bounty.PaidDate = &now
+		bounty.Completed = true
+		bounty.CompletionDate = &now
+	case keysendRes.Status == db.PaymentPending:
+		payment.Status = true
+		payment.PaymentStatus = db.PaymentPending
+		bounty.PaymentPending = true
+		bounty.PaidDate = &now
+		bounty.Completed = true
+		bounty.CompletionDate = &now
+	default:
+		payment.Error = keysendRes.Message
+	}
+	bounty.PaymentFailed = payment.PaymentStatus == db.PaymentFailed
+
+	payment, err := h.db.ProcessBatchPayment(batch.ID, item, payment, bounty)
+	if err != nil {
+		return payment, err
+	}
+
+	switch payment.PaymentStatus {
+	case db.PaymentComplete:
+		emitWebhook(bounty.WorkspaceUuid, webhooks.EventBountyPaid, bounty)
+		recordBountyPayment(batch.SenderPubKey, bounty)
+	case db.PaymentPending:
+		enqueuePaymentCheck(bounty.ID)
+	}
+	return payment, nil
+}
+
+// holdBatchItem leaves an item whose keysend may have gone out for review,
+// keeping its amount reserved and its bounty pending.
+func (h *bountyHandler) holdBatchItem(ctx context.Context, batch db.BatchPayout, item db.BatchPayoutItem, result db.BatchPayoutResult, tag string, reason string) db.BatchPayoutResult {
+	if err := h.db.HoldBatchPayment(batch.ID, item.ID, tag, reason); err != nil {
+		// still held: the item stays marked as being sent
+		logger.FromContext(ctx).Error("[bounty] could not hold batch payment of bounty %d: %v", item.BountyID, err)
+	}
+	result.Status = db.BatchItemNeedsReview
+	result.Error = reason
+	return result
+}
+
+// authorizeBatch loads the batch in the path and writes the error response,
+// returning false, when the caller can't pay bounties of its workspace.
+func (h *bountyHandler) authorizeBatch(w http.ResponseWriter, r *http.Request) (string, db.BatchPayout, bool) {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[bounty] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return "", db.BatchPayout{}, false
+	}
+
+	batch, err := h.db.GetBatchPayout(chi.URLParam(r, "uuid"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Batch payout not found"})
+		return "", db.BatchPayout{}, false
+	}
+
+	if !h.userHasAccess(pubKeyFromAuth, batch.WorkspaceUuid, db.PayBounty) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions to pay bounties"})
+		return "", db.BatchPayout{}, false
+	}
+	return pubKeyFromAuth, batch, true
+}
+
+// GetBatchPayout godoc
+//
+//	@Summary		Get a batch payout
+//	@Description	Get a batch payout with its items, including the items held for review.
+//	@Tags			Bounties - Payment
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			uuid	path		string	true	"Batch payout UUID"
+//	@Success		200		{object}	db.BatchPayout
+//	@Router			/gobounties/pay/batch/{uuid} [get]
+func (h *bountyHandler) GetBatchPayout(w http.ResponseWriter, r *http.Request) {
+	_, batch, ok := h.authorizeBatch(w, r)
+	if !ok {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(batch)
+}
+
+// ResolveBatchPayment godoc
+//
+//	@Summary		Resolve a batch payment held for review
+//	@Description	Resolve an item whose keysend may have gone out without being recorded. An item with a keysend tag is recorded as the node reports it, paid, pending or failed, a failed one returning its amount to the budget. An item without a tag can only be released, which returns its amount to the budget and frees its bounty; release it once you made sure its keysend never went out.
+//	@Tags			Bounties - Payment
+//	@Accept			json
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			uuid	path		string							true	"Batch payout UUID"
+//	@Param			item_id	path		int								true	"Batch payout item ID"
+//	@Param			request	body		db.BatchPayoutResolveRequest	false	"Release an item without a tag"
+//	@Success		200		{object}	db.BatchPayout
+//	@Router			/gobounties/pay/batch/{uuid}/items/{item_id}/resolve [post]
+func (h *bountyHandler) ResolveBatchPayment(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, batch, ok := h.authorizeBatch(w, r)
+	if !ok {
+		return
+	}
+
+	itemId, err := utils.ConvertStringToUint(chi.URLParam(r, "item_id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid item ID"})
+		return
+	}
+
+	request := db.BatchPayoutResolveRequest{}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err == nil && len(body) > 0 {
+		err = json.Unmarshal(body, &request)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	var item db.BatchPayoutItem
+	for _, batchItem := range batch.Items {
+		if batchItem.ID == itemId {
+			item = batchItem
+		}
+	}
+	if item.ID == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Batch payout item not found"})
+		return
+	}
+
+	// an item still being sent belongs to the running payout
+	held := item.Status == db.BatchItemNeedsReview || (item.Status == db.BatchItemSending && batch.Status == db.BatchPayoutFinished)
+	if !held {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Only payments held for review can be resolved"})
+		return
+	}
+
+	bounty := h.db.GetBounty(item.BountyID)
+
+	if item.Tag == "" {
+		if !request.Release {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "The payment has no tag to check with the node, release it once you made sure it was not sent"})
+			return
+		}
+
+		h.m.Lock()
+		err = h.db.ReleaseBatchPayment(batch.ID, item.ID, "Released by "+pubKeyFromAuth)
+		h.m.Unlock()
+		if errors.Is(err, db.ErrBatchItemDone) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Only payments held for review can be resolved"})
+			return
+		}
+		if err != nil {
+			logger.FromContext(r.Context()).Error("[bounty] could not release batch payment of bounty %d: %v", item.BountyID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to release payment"})
+			return
+		}
+		audit.Record(pubKeyFromAuth, batch.WorkspaceUuid, audit.EntityBounty, strconv.FormatUint(uint64(item.BountyID), 10), audit.ActionUpdate,
+			map[string]interface{}{"payment_pending": true, "batch_item_status": item.Status},
+			map[string]interface{}{"payment_pending": false, "batch_item_status": db.BatchItemReleased})
+	} else {
+		tagResult := h.lightning.PaymentStatusByTag(item.Tag)
+		switch tagResult.Status {
+		case db.PaymentComplete, db.PaymentPending, db.PaymentFailed:
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Could not check the payment with the node"})
+			return
+		}
+
+		assignee := h.db.GetPersonByPubkey(bounty.Assignee)
+		_, err = h.recordBatchPayment(batch, item, bounty, assignee.OwnerPubKey,
+			db.V2SendOnionRes{Tag: item.Tag, Status: tagResult.Status, Message: tagResult.Error}, false)
+		if errors.Is(err, db.ErrBatchItemDone) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Only payments held for review can be resolved"})
+			return
+		}
+		if err != nil {
+			logger.FromContext(r.Context()).Error("[bounty] could not resolve batch payment of bounty %d: %v", item.BountyID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to resolve payment"})
+			return
+		}
+	}
+
+	resolved, err := h.db.GetBatchPayout(batch.Uuid)
+	if err != nil {
+		resolved = batch
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resolved)
+}
+
+func uniqueBountyIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
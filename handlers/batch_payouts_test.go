@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// selectiveNode rejects keysends to one pubkey and tracks how many
+// keysends are in flight at once.
+type selectiveNode struct {
+	*lightning.FakeNode
+	rejectPubkey string
+	delay        time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (n *selectiveNode) Keysend(amount uint, pubkey string, routeHint string, memo string) (db.V2SendOnionRes, error) {
+	n.mu.Lock()
+	n.inFlight++
+	if n.inFlight > n.maxInFlight {
+		n.maxInFlight = n.inFlight
+	}
+	n.mu.Unlock()
+	defer func() {
+		n.mu.Lock()
+		n.inFlight--
+		n.mu.Unlock()
+	}()
+
+	time.Sleep(n.delay)
+	if pubkey == n.rejectPubkey {
+		return db.V2SendOnionRes{Status: db.PaymentFailed, Message: "no route"}, lightning.ErrRejected
+	}
+	return n.FakeNode.Keysend(amount, pubkey, routeHint, memo)
+}
+
+func expectBatchReservation(mockDb *dbMocks.Database) {
+	mockDb.On("CheckSpendLimit", "workspace-1", "owner", db.PayBounty, mock.Anything).Return(nil).Once()
+	mockDb.On("ReserveBatchPayout", mock.Anything).Run(func(args mock.Arguments) {
+		batch := args.Get(0).(*db.BatchPayout)
+		batch.ID = 9
+		batch.Uuid = "batch-1"
+		for i := range batch.Items {
+			batch.Items[i].ID = uint(i + 1)
+			batch.Reserved += batch.Items[i].Amount
+		}
+	}).Return(nil).Once()
+	mockDb.On("StartBatchPayment", uint(9), mock.Anything).Return(nil)
+	mockDb.On("ProcessBatchPayment", uint(9), mock.Anything, mock.Anything, mock.Anything).
+		Return(func(batchID uint, item db.BatchPayoutItem, payment db.NewPaymentHistory, bounty db.NewBounty) db.NewPaymentHistory {
+			payment.ID = 100 + item.ID
+			return payment
+		}, nil)
+}
+
+func TestMakeBatchBountyPayment(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	bounties := []db.NewBounty{
+		{ID: 1, Price: 1000, WorkspaceUuid: workspace.Uuid, Assignee: "alice", Title: "One"},
+		{ID: 2, Price: 2000, WorkspaceUuid: workspace.Uuid, Assignee: "bob", Title: "Two"},
+		{ID: 3, Price: 3000, WorkspaceUuid: workspace.Uuid, Assignee: "carol", Title: "Three", Paid: true},
+	}
+	expectBounties := func(mockDb *dbMocks.Database) {
+		for _, bounty := range bounties {
+			mockDb.On("GetBounty", bounty.ID).Return(bounty).Maybe()
+			mockDb.On("GetPersonByPubkey", bounty.Assignee).Return(db.Person{OwnerPubKey: bounty.Assignee}).Maybe()
+		}
+	}
+	request := func(ids ...uint) db.BatchPayoutRequest {
+		return db.BatchPayoutRequest{WorkspaceUuid: workspace.Uuid, BountyIDs: ids}
+	}
+	decode := func(t *testing.T, rr *httptest.ResponseRecorder) db.BatchPayoutResponse {
+		response := db.BatchPayoutResponse{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("Unauthorized without pubkey", func(t *testing.T) {
+		handler, _, _ := newFakeNodeBountyHandler(t)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(1), "", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Rejects empty and oversized batches", func(t *testing.T) {
+		handler, _, _ := newFakeNodeBountyHandler(t)
+		rr := httptest.NewRecorder()
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(), "owner", nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		ids := make([]uint, maxBatchPayoutSize+1)
+		for i := range ids {
+			ids[i] = uint(i + 1)
+		}
+		rr = httptest.NewRecorder()
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(ids...), "owner", nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Unauthorized without the pay bounty role", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		handler.userHasAccess = func(pubKeyFromAuth string, uuid string, role string) bool {
+			assert.Equal(t, db.PayBounty, role)
+			return false
+		}
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(1), "someone", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Nothing reserved when no bounty is payable", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		expectBounties(mockDb)
+		mockDb.On("GetBounty", uint(4)).Return(db.NewBounty{})
+		rr := httptest.NewRecorder()
+
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(3, 4), "owner", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		results := decode(t, rr).Results
+		assert.Len(t, results, 2)
+		assert.Equal(t, db.BatchItemSkipped, results[0].Status)
+		assert.Equal(t, "Bounty has already been paid", results[0].Error)
+		assert.Equal(t, "Bounty not found", results[1].Error)
+		assert.Empty(t, node.Payments())
+	})
+
+	t.Run("Forbidden when the budget does not cover the batch", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		expectBounties(mockDb)
+		mockDb.On("CheckSpendLimit", workspace.Uuid, "owner", db.PayBounty, uint(3000)).Return(nil)
+		mockDb.On("ReserveBatchPayout", mock.Anything).Return(db.ErrInsufficientBudget)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(1, 2), "owner", nil))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Empty(t, node.Payments())
+	})
+
+	t.Run("Forbidden when the batch goes past a spend limit", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		expectBounties(mockDb)
+		mockDb.On("CheckSpendLimit", workspace.Uuid, "owner", db.PayBounty, uint(3000)).Return(db.ErrSpendLimitExceeded)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(1, 2), "owner", nil))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		mockDb.AssertNotCalled(t, "ReserveBatchPayout", mock.Anything)
+		assert.Empty(t, node.Payments())
+	})
+
+	t.Run("Conflict when a bounty got paid meanwhile", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		expectBounties(mockDb)
+		mockDb.On("CheckSpendLimit", workspace.Uuid, "owner", db.PayBounty, uint(3000)).Return(nil)
+		mockDb.On("ReserveBatchPayout", mock.Anything).Return(db.ErrBountyNotPayable)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(1, 2), "owner", nil))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Pays payable bounties and releases failures", func(t *testing.T) {
+		handler, mockDb, fake := newFakeNodeBountyHandler(t)
+		node := &selectiveNode{FakeNode: fake, rejectPubkey: "bob"}
+		handler.lightning = node
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		expectBounties(mockDb)
+		expectBatchReservation(mockDb)
+		mockDb.On("FinishBatchPayout", uint(9)).
+			Return(db.BatchPayout{ID: 9, Uuid: "batch-1", Reserved: 3000, Spent: 1000, Released: 2000, Status: db.BatchPayoutFinished}, nil).Once()
+		rr := httptest.NewRecorder()
+
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(1, 2, 2, 3), "owner", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockDb.AssertCalled(t, "ReserveBatchPayout", mock.MatchedBy(func(batch *db.BatchPayout) bool {
+			return batch.WorkspaceUuid == workspace.Uuid && len(batch.Items) == 2 && batch.Reserved == 3000
+		}))
+
+		response := decode(t, rr)
+		assert.Equal(t, uint(2000), response.Batch.Released)
+		assert.Len(t, response.Results, 3, "duplicate ids are paid once")
+
+		byBounty := map[uint]db.BatchPayoutResult{}
+		for _, result := range response.Results {
+			byBounty[result.BountyID] = result
+		}
+		assert.Equal(t, db.PaymentComplete, byBounty[1].Status)
+		assert.Equal(t, uint(101), byBounty[1].PaymentID)
+		assert.Equal(t, db.PaymentFailed, byBounty[2].Status)
+		assert.Equal(t, "Payment Request Failed", byBounty[2].Error)
+		assert.Equal(t, db.BatchItemSkipped, byBounty[3].Status)
+
+		mockDb.AssertCalled(t, "ProcessBatchPayment", uint(9), mock.Anything,
+			mock.MatchedBy(func(p db.NewPaymentHistory) bool {
+				return p.BountyId == 1 && p.PaymentStatus == db.PaymentComplete && p.Status
+			}),
+			mock.MatchedBy(func(b db.NewBounty) bool { return b.ID == 1 && b.Paid && !b.PaymentPending }))
+		mockDb.AssertCalled(t, "ProcessBatchPayment", uint(9), mock.Anything,
+			mock.MatchedBy(func(p db.NewPaymentHistory) bool {
+				return p.BountyId == 2 && p.PaymentStatus == db.PaymentFailed && !p.Status
+			}),
+			mock.MatchedBy(func(b db.NewBounty) bool { return b.ID == 2 && b.PaymentFailed && !b.Paid }))
+
+		payments := fake.Payments()
+		assert.Len(t, payments, 1)
+		assert.Equal(t, "alice", payments[0].Pubkey)
+	})
+
+	t.Run("Pays with bounded concurrency", func(t *testing.T) {
+		handler, mockDb, fake := newFakeNodeBountyHandler(t)
+		node := &selectiveNode{FakeNode: fake, delay: 20 * time.Millisecond}
+		handler.lightning = node
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetPersonByPubkey", mock.Anything).Return(db.Person{OwnerPubKey: "hunter"})
+
+		ids := []uint{}
+		for id := uint(10); id < 22; id++ {
+			mockDb.On("GetBounty", id).Return(db.NewBounty{ID: id, Price: 100, WorkspaceUuid: workspace.Uuid, Assignee: "hunter"})
+			ids = append(ids, id)
+		}
+		expectBatchReservation(mockDb)
+		mockDb.On("FinishBatchPayout", uint(9)).Return(db.BatchPayout{ID: 9, Status: db.BatchPayoutFinished}, nil)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(ids...), "owner", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, fake.Payments(), len(ids))
+		assert.LessOrEqual(t, node.maxInFlight, batchPayoutConcurrency)
+		assert.Greater(t, node.maxInFlight, 1)
+	})
+
+	t.Run("Holds a payment that went out but could not be recorded", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		expectBounties(mockDb)
+		mockDb.On("CheckSpendLimit", workspace.Uuid, "owner", db.PayBounty, uint(1000)).Return(nil)
+		mockDb.On("ReserveBatchPayout", mock.Anything).Run(func(args mock.Arguments) {
+			batch := args.Get(0).(*db.BatchPayout)
+			batch.ID = 9
+			batch.Items[0].ID = 1
+		}).Return(nil).Once()
+		mockDb.On("StartBatchPayment", uint(9), uint(1)).Return(nil).Once()
+		mockDb.On("ProcessBatchPayment", uint(9), mock.Anything, mock.Anything, mock.Anything).
+			Return(db.NewPaymentHistory{}, errors.New("db down")).Once()
+		mockDb.On("HoldBatchPayment", uint(9), uint(1), mock.MatchedBy(func(tag string) bool { return tag != "" }), "Payment sent but could not be recorded").Return(nil).Once()
+		mockDb.On("FinishBatchPayout", uint(9)).Return(db.BatchPayout{ID: 9, Status: db.BatchPayoutFinished}, nil)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(1), "owner", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		results := decode(t, rr).Results
+		assert.Equal(t, db.BatchItemNeedsReview, results[0].Status)
+		assert.Len(t, node.Payments(), 1)
+	})
+
+	t.Run("Sends nothing for an item of a batch that already finished", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		expectBounties(mockDb)
+		mockDb.On("CheckSpendLimit", workspace.Uuid, "owner", db.PayBounty, uint(1000)).Return(nil)
+		mockDb.On("ReserveBatchPayout", mock.Anything).Run(func(args mock.Arguments) {
+			batch := args.Get(0).(*db.BatchPayout)
+			batch.ID = 9
+			batch.Items[0].ID = 1
+		}).Return(nil).Once()
+		mockDb.On("StartBatchPayment", uint(9), uint(1)).Return(db.ErrBatchItemDone).Once()
+		mockDb.On("FinishBatchPayout", uint(9)).Return(db.BatchPayout{ID: 9, Status: db.BatchPayoutFinished}, nil)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBatchBountyPayment(rr, webhookRequest(http.MethodPost, "/", request(1), "owner", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, db.BatchItemReleased, decode(t, rr).Results[0].Status)
+		assert.Empty(t, node.Payments())
+	})
+}
+
+func TestResolveBatchPayment(t *testing.T) {
+	bounty := db.NewBounty{ID: 1, Price: 1000, WorkspaceUuid: "workspace-1", Assignee: "alice", Title: "One", PaymentPending: true}
+	heldBatch := func(item db.BatchPayoutItem) db.BatchPayout {
+		item.ID, item.BatchID, item.BountyID, item.Amount = 1, 9, bounty.ID, bounty.Price
+		return db.BatchPayout{ID: 9, Uuid: "batch-1", WorkspaceUuid: "workspace-1", SenderPubKey: "owner", Reserved: 1000,
+			Status: db.BatchPayoutFinished, Items: []db.BatchPayoutItem{item}}
+	}
+	params := map[string]string{"uuid": "batch-1", "item_id": "1"}
+
+	t.Run("Unauthorized without the role", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		handler.userHasAccess = func(pubKeyFromAuth string, uuid string, role string) bool { return false }
+		mockDb.On("GetBatchPayout", "batch-1").Return(heldBatch(db.BatchPayoutItem{Status: db.BatchItemNeedsReview}), nil)
+		rr := httptest.NewRecorder()
+
+		handler.GetBatchPayout(rr, webhookRequest(http.MethodGet, "/", nil, "someone", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Returns the batch with its items", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		mockDb.On("GetBatchPayout", "batch-1").Return(heldBatch(db.BatchPayoutItem{Status: db.BatchItemNeedsReview, Tag: "tag-1"}), nil)
+		rr := httptest.NewRecorder()
+
+		handler.GetBatchPayout(rr, webhookRequest(http.MethodGet, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		batch := db.BatchPayout{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &batch))
+		assert.Equal(t, "tag-1", batch.Items[0].Tag)
+	})
+
+	t.Run("Conflict for an item that is not held", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		batch := heldBatch(db.BatchPayoutItem{Status: db.BatchItemSending})
+		batch.Status = db.BatchPayoutReserved
+		mockDb.On("GetBatchPayout", "batch-1").Return(batch, nil)
+		rr := httptest.NewRecorder()
+
+		handler.ResolveBatchPayment(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusConflict, rr.Code, "the running payout still records the item")
+	})
+
+	t.Run("Settles an item the node reports complete", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		res, _ := node.Keysend(1000, "alice", "", "batch")
+		batch := heldBatch(db.BatchPayoutItem{Status: db.BatchItemNeedsReview, Tag: res.Tag})
+		mockDb.On("GetBatchPayout", "batch-1").Return(batch, nil)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("GetPersonByPubkey", "alice").Return(db.Person{OwnerPubKey: "alice"})
+		mockDb.On("ProcessBatchPayment", uint(9), batch.Items[0], mock.MatchedBy(func(payment db.NewPaymentHistory) bool {
+			return payment.Tag == res.Tag && payment.PaymentStatus == db.PaymentComplete && payment.ReceiverPubKey == "alice"
+		}), mock.MatchedBy(func(b db.NewBounty) bool { return b.Paid && !b.PaymentPending })).Return(db.NewPaymentHistory{ID: 5, PaymentStatus: db.PaymentComplete}, nil).Once()
+		rr := httptest.NewRecorder()
+
+		handler.ResolveBatchPayment(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, node.Payments(), 1, "nothing is sent again")
+	})
+
+	t.Run("Records an item the node reports failed", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		node.SetPaymentOutcome(db.PaymentFailed, "no route")
+		res, _ := node.Keysend(1000, "alice", "", "batch")
+		batch := heldBatch(db.BatchPayoutItem{Status: db.BatchItemNeedsReview, Tag: res.Tag})
+		mockDb.On("GetBatchPayout", "batch-1").Return(batch, nil)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("GetPersonByPubkey", "alice").Return(db.Person{OwnerPubKey: "alice"})
+		mockDb.On("ProcessBatchPayment", uint(9), batch.Items[0], mock.MatchedBy(func(payment db.NewPaymentHistory) bool {
+			return payment.PaymentStatus == db.PaymentFailed
+		}), mock.MatchedBy(func(b db.NewBounty) bool { return b.PaymentFailed })).Return(db.NewPaymentHistory{ID: 5, PaymentStatus: db.PaymentFailed}, nil).Once()
+		rr := httptest.NewRecorder()
+
+		handler.ResolveBatchPayment(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Releases an item without a tag only when asked to", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		batch := heldBatch(db.BatchPayoutItem{Status: db.BatchItemNeedsReview})
+		mockDb.On("GetBatchPayout", "batch-1").Return(batch, nil)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		rr := httptest.NewRecorder()
+
+		handler.ResolveBatchPayment(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+
+		mockDb.On("ReleaseBatchPayment", uint(9), uint(1), "Released by owner").Return(nil).Once()
+		rr = httptest.NewRecorder()
+
+		handler.ResolveBatchPayment(rr, webhookRequest(http.MethodPost, "/", db.BatchPayoutResolveRequest{Release: true}, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, node.Payments())
+	})
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stakwork/sphinx-tribes/blobstore"
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+// fileURLExpiry is how long the links to chat files last.
+const fileURLExpiry = 15 * time.Minute
+
+// newBlobStores puts chat files in the FILE_STORAGE store, files put in the
+// other stores before it changed are still found.
+func newBlobStores(client *http.Client) *blobstore.Stores {
+	meme := blobstore.NewMeme(client, config.MemeUrl, memeToken)
+	local := blobstore.NewLocal(config.FileStorageDir, publicHost()+"/hivechat/file/download", []byte(config.FileSigningKey))
+	stores := &blobstore.Stores{Current: meme, Others: []blobstore.BlobStore{local}}
+
+	if config.S3Client != nil && config.S3BucketName != "" {
+		s3Client := s3.New(config.S3Client.Options(), func(o *s3.Options) {
+			// S3_URL points at an S3 compatible service
+			if config.S3Url != "" {
+				o.BaseEndpoint = &config.S3Url
+				o.UsePathStyle = true
+			}
+		})
+		store := blobstore.NewS3(s3Client, config.S3BucketName, config.S3FolderName)
+		stores.Others = append(stores.Others, store)
+		if config.FileStorage == blobstore.S3 {
+			stores.Current = store
+		}
+	} else if config.FileStorage == blobstore.S3 {
+		logger.Log.Error("[files] FILE_STORAGE is s3 but no bucket is set, files go to the meme server")
+	}
+
+	if config.FileStorage == blobstore.Local {
+		stores.Current = local
+	}
+	if stores.Current != meme {
+		stores.Others = append(stores.Others, meme)
+	}
+	return stores
+}
+
+// memeToken signs a meme server challenge for the token of an upload.
+func memeToken() (string, error) {
+	challenge := GetMemeChallenge()
+	signer := SignChallenge(challenge.Challenge)
+	mErr, mToken := GetMemeToken(challenge.Id, signer.Response.Sig)
+	if mErr != "" {
+		return "", errors.New(mErr)
+	}
+	if mToken.Token == "" {
+		return "", errors.New("no meme token")
+	}
+	return mToken.Token, nil
+}
+
+// publicHost is where the workflows and the files' links reach this server.
+func publicHost() string {
+	host := os.Getenv("HOST")
+	if host == "" {
+		host = "https://community.sphinx.chat"
+	}
+	return strings.TrimSuffix(host, "/")
+}
+
+// uploadFileType is the type a file is kept as: the one its content is
+// sniffed as, or JSON for text sent as JSON, since the sniffer takes JSON
+// for text. A file whose declared type is not its content's is refused.
+func uploadFileType(sniffed string, declared string) (string, bool) {
+	if i := strings.Index(declared, ";"); i >= 0 {
+		declared = declared[:i]
+	}
+	declared = strings.TrimSpace(strings.ToLower(declared))
+
+	fileType := sniffed
+	if sniffed == "text/plain" && declared == "application/json" {
+		fileType = declared
+	}
+	if declared != "" && declared != "application/octet-stream" && declared != fileType {
+		return "", false
+	}
+	return fileType, isAllowedFileType(fileType)
+}
@@ -65,17 +65,17 @@ func MigrateBounties(w http.ResponseWriter, r *http.Request) {
 	peeps := db.DB.GetAllPeople()
 
 	for indexPeep, peep := range peeps {
-		logger.Log.Info("peep: %d", indexPeep)
+		logger.FromContext(r.Context()).Info("peep: %d", indexPeep)
 		bounties, ok := peep.Extras["wanted"].([]interface{})
 
 		if !ok {
-			logger.Log.Info("Wanted not there")
+			logger.FromContext(r.Context()).Info("Wanted not there")
 			continue
 		}
 
 		for index, bounty := range bounties {
 
-			logger.Log.Info("looping bounties: %d", index)
+			logger.FromContext(r.Context()).Info("looping bounties: %d", index)
 			migrateBounty := bounty.(map[string]interface{})
 
 			migrateBountyFinal := db.Bounty{}
@@ -130,8 +130,8 @@ func MigrateBounties(w http.ResponseWriter, r *http.Request) {
 			if !ok7 {
 				migrateBountyFinal.Created = 0
 			} else {
-				logger.Log.Info("Type: %v", reflect.TypeOf(CreatedInt64))
-				logger.Log.Info("Timestamp: %d", CreatedInt64)
+				logger.FromContext(r.Context()).Info("Type: %v", reflect.TypeOf(CreatedInt64))
+				logger.FromContext(r.Context()).Info("Timestamp: %d", CreatedInt64)
 				migrateBountyFinal.Created = CreatedInt64
 			}
 
@@ -211,7 +211,7 @@ func MigrateBounties(w http.ResponseWriter, r *http.Request) {
 			} else {
 				migrateBountyFinal.EstimatedCompletionDate = EstimatedCompletionDate
 			}
-			logger.Log.Info("Bounty about to be added ")
+			logger.FromContext(r.Context()).Info("Bounty about to be added ")
 			db.DB.AddBounty(migrateBountyFinal)
 			//Migrate the bounties here
 		}
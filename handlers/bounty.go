@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -17,11 +18,14 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/audit"
 	"github.com/stakwork/sphinx-tribes/auth"
 	"github.com/stakwork/sphinx-tribes/config"
 	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/lightning"
 	"github.com/stakwork/sphinx-tribes/logger"
 	"github.com/stakwork/sphinx-tribes/utils"
+	"github.com/stakwork/sphinx-tribes/webhooks"
 	"gorm.io/gorm"
 )
 
@@ -40,6 +44,7 @@ type BountyTimingResponse struct {
 type bountyHandler struct {
 	httpClient               HttpClient
 	db                       db.Database
+	lightning                LightningBackend
 	getSocketConnections     func(host string) (db.Client, error)
 	generateBountyResponse   func(bounties []db.NewBounty) []db.BountyResponse
 	userHasAccess            func(pubKeyFromAuth string, uuid string, role string) bool
@@ -51,12 +56,14 @@ type bountyHandler struct {
 
 func NewBountyHandler(httpClient HttpClient, database db.Database) *bountyHandler {
 	dbConf := db.NewDatabaseConfig(&gorm.DB{})
+	backend := lightning.FromConfig(httpClient)
 	return &bountyHandler{
 		httpClient:               httpClient,
 		db:                       database,
+		lightning:                backend,
 		getSocketConnections:     db.Store.GetSocketConnections,
 		userHasAccess:            dbConf.UserHasAccess,
-		getInvoiceStatusByTag:    GetInvoiceStatusByTag,
+		getInvoiceStatusByTag:    backend.PaymentStatusByTag,
 		getHoursDifference:       utils.GetHoursDifference,
 		userHasManageBountyRoles: dbConf.UserHasManageBountyRoles,
 	}
@@ -67,8 +74,8 @@ type TimingError struct {
 	Error     string `json:"error"`
 }
 
-func handleTimingError(w http.ResponseWriter, operation string, err error) {
-	logger.Log.Error("[bounty_timing] %s failed: %v", operation, err)
+func handleTimingError(w http.ResponseWriter, r *http.Request, operation string, err error) {
+	logger.FromContext(r.Context()).Error("[bounty_timing] %s failed: %v", operation, err)
 	w.WriteHeader(http.StatusInternalServerError)
 	json.NewEncoder(w).Encode(TimingError{
 		Operation: operation,
@@ -107,7 +114,7 @@ func (h *bountyHandler) GetBountyById(w http.ResponseWriter, r *http.Request) {
 	bounties, err := h.db.GetBountyById(bountyId)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		logger.Log.Error("[bounty] Error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Error: %v", err)
 	} else {
 		var bountyResponse []db.BountyResponse = h.GenerateBountyResponse(bounties)
 		w.WriteHeader(http.StatusOK)
@@ -149,7 +156,7 @@ func (h *bountyHandler) GetNextBountyByCreated(w http.ResponseWriter, r *http.Re
 	bounties, err := h.db.GetNextBountyByCreated(r)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		logger.Log.Error("[bounty] Error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Error: %v", err)
 	} else {
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(bounties)
@@ -168,7 +175,7 @@ func (h *bountyHandler) GetPreviousBountyByCreated(w http.ResponseWriter, r *htt
 	bounties, err := h.db.GetPreviousBountyByCreated(r)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		logger.Log.Error("[bounty] Error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Error: %v", err)
 	} else {
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(bounties)
@@ -188,7 +195,7 @@ func (h *bountyHandler) GetWorkspaceNextBountyByCreated(w http.ResponseWriter, r
 	bounties, err := h.db.GetNextWorkspaceBountyByCreated(r)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		logger.Log.Error("[bounty] Error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Error: %v", err)
 	} else {
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(bounties)
@@ -208,7 +215,7 @@ func (h *bountyHandler) GetWorkspacePreviousBountyByCreated(w http.ResponseWrite
 	bounties, err := h.db.GetPreviousWorkspaceBountyByCreated(r)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		logger.Log.Error("[bounty] Error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Error: %v", err)
 	} else {
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(bounties)
@@ -250,7 +257,7 @@ func (h *bountyHandler) GetBountyByCreated(w http.ResponseWriter, r *http.Reques
 	bounties, err := h.db.GetBountyDataByCreated(created)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		logger.Log.Error("[bounty] Error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Error: %v", err)
 	} else {
 		var bountyResponse []db.BountyResponse = h.GenerateBountyResponse(bounties)
 
@@ -299,7 +306,7 @@ func (h *bountyHandler) GetPersonCreatedBounties(w http.ResponseWriter, r *http.
 
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		logger.Log.Error("[bounty] Error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Error: %v", err)
 	} else {
 		var bountyResponse []db.BountyResponse = h.GenerateBountyResponse(bounties)
 
@@ -312,7 +319,7 @@ func (h *bountyHandler) GetPersonAssignedBounties(w http.ResponseWriter, r *http
 	bounties, err := h.db.GetAssignedBounties(r)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		logger.Log.Error("[bounty] Error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Error: %v", err)
 	} else {
 		var bountyResponse []db.BountyResponse = h.GenerateBountyResponse(bounties)
 		w.WriteHeader(http.StatusOK)
@@ -345,28 +352,6 @@ func getContactKey(pubkey string) (*string, error) {
 	return contactResp.ContactKey, nil
 }
 
-func ProcessWaitingNotifications() {
-	notifications := db.DB.GetNotificationsByStatus("WAITING_KEY_EXCHANGE")
-
-	for _, n := range notifications {
-		contactKey, err := getContactKey(n.PubKey)
-		if err != nil {
-			logger.Log.Error("Error checking contact key for pubkey %s: %v", n.PubKey, err)
-			db.DB.IncrementNotificationRetry(n.UUID)
-			continue
-		}
-
-		if contactKey == nil {
-			db.DB.IncrementNotificationRetry(n.UUID)
-			continue
-		}
-
-		// Contact key is available, proceed with sending
-		sendRespStatus := sendNotification(n.PubKey, n.Content)
-		db.DB.UpdateNotificationStatus(n.UUID, sendRespStatus)
-	}
-}
-
 func sendNotification(pubkey, content string) string {
 	sendURL := fmt.Sprintf("%s/send", config.V2BotUrl)
 	msgBody, _ := json.Marshal(map[string]interface{}{
@@ -464,14 +449,14 @@ func (h *bountyHandler) CreateOrEditBounty(w http.ResponseWriter, r *http.Reques
 	r.Body.Close()
 
 	if err != nil {
-		logger.Log.Error("[bounty] Read error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Read error: %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
 
 	err = json.Unmarshal(body, &bounty)
 	if err != nil {
-		logger.Log.Error("[bounty] Unmarshal error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Unmarshal error: %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -515,13 +500,22 @@ func (h *bountyHandler) CreateOrEditBounty(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// an asset bounty could never be paid by a node that moves only sats
+	if bounty.AssetId != 0 {
+		if _, err := assetBackend(h.lightning); err != nil {
+			w.WriteHeader(http.StatusNotImplemented)
+			json.NewEncoder(w).Encode("Asset bounties are not supported by this node")
+			return
+		}
+	}
+
 	if bounty.Assignee != "" {
 		now := time.Now()
 		bounty.AssignedDate = &now
 
 		if bounty.ID != 0 {
 			if err := h.db.StartBountyTiming(bounty.ID); err != nil {
-				handleTimingError(w, "start_timing", err)
+				handleTimingError(w, r, "start_timing", err)
 			}
 		}
 
@@ -550,7 +544,7 @@ func (h *bountyHandler) CreateOrEditBounty(w http.ResponseWriter, r *http.Reques
 		// check if the bounty has a pending payment
 		if dbBounty.PaymentPending {
 			msg := "You cannot update a bounty with a pending payment"
-			logger.Log.Info("[bounty]: %v", msg)
+			logger.FromContext(r.Context()).Info("[bounty]: %v", msg)
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(msg)
 			return
@@ -563,14 +557,14 @@ func (h *bountyHandler) CreateOrEditBounty(w http.ResponseWriter, r *http.Reques
 				hasBountyRoles := h.userHasManageBountyRoles(pubKeyFromAuth, bounty.WorkspaceUuid)
 				if !hasBountyRoles {
 					msg := "You don't have the right permission ton update bounty"
-					logger.Log.Info("[bounty]: %v", msg)
+					logger.FromContext(r.Context()).Info("[bounty]: %v", msg)
 					w.WriteHeader(http.StatusBadRequest)
 					json.NewEncoder(w).Encode(msg)
 					return
 				}
 			} else {
 				msg := "Cannot edit another user's bounty"
-				logger.Log.Info("[bounty]: %v", msg)
+				logger.FromContext(r.Context()).Info("[bounty]: %v", msg)
 				w.WriteHeader(http.StatusBadRequest)
 				json.NewEncoder(w).Encode(msg)
 				return
@@ -593,15 +587,20 @@ func (h *bountyHandler) CreateOrEditBounty(w http.ResponseWriter, r *http.Reques
 	}
 	existingBounty := h.db.GetBounty(bounty.ID)
 	b, err := h.db.CreateOrEditBounty(bounty)
+	if errors.Is(err, db.ErrBountyTermsLocked) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		logger.Log.Error("[bounty] Error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Error: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
 	if bounty.ID == 0 && bounty.Assignee != "" {
 		if err := h.db.StartBountyTiming(b.ID); err != nil {
-			handleTimingError(w, "start_timing", err)
+			handleTimingError(w, r, "start_timing", err)
 		}
 	}
 
@@ -621,10 +620,31 @@ func (h *bountyHandler) CreateOrEditBounty(w http.ResponseWriter, r *http.Reques
 
 	}
 
+	if bounty.ID == 0 {
+		emitWebhook(b.WorkspaceUuid, webhooks.EventBountyCreated, b)
+	}
+	if bounty.Assignee != "" && bounty.Assignee != existingBounty.Assignee {
+		emitWebhook(b.WorkspaceUuid, webhooks.EventBountyAssigned, b)
+	}
+
+	bountyID := strconv.FormatUint(uint64(b.ID), 10)
+	if bounty.ID == 0 {
+		audit.Record(pubKeyFromAuth, b.WorkspaceUuid, audit.EntityBounty, bountyID, audit.ActionCreate, nil, b)
+	} else {
+		audit.Record(pubKeyFromAuth, b.WorkspaceUuid, audit.EntityBounty, bountyID, audit.ActionUpdate, existingBounty, b)
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(b)
 }
 
+// recordBountyPayment adds a settled bounty payment to the audit log.
+func recordBountyPayment(actor string, bounty db.NewBounty) {
+	audit.Record(actor, bounty.WorkspaceUuid, audit.EntityBounty, strconv.FormatUint(uint64(bounty.ID), 10), audit.ActionPay,
+		map[string]interface{}{"paid": false},
+		map[string]interface{}{"paid": true, "price": bounty.Price, "assignee": bounty.Assignee})
+}
+
 const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
 func generateUnlockCode() string {
@@ -653,7 +673,7 @@ func (h *bountyHandler) DeleteBounty(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -662,12 +682,12 @@ func (h *bountyHandler) DeleteBounty(w http.ResponseWriter, r *http.Request) {
 	pubkey := chi.URLParam(r, "pubkey")
 
 	if pubkey == "" {
-		logger.Log.Error("[bounty] no pubkey from route")
+		logger.FromContext(r.Context()).Error("[bounty] no pubkey from route")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 	if created == "" {
-		logger.Log.Error("[bounty] no created timestamp from route")
+		logger.FromContext(r.Context()).Error("[bounty] no created timestamp from route")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -676,14 +696,14 @@ func (h *bountyHandler) DeleteBounty(w http.ResponseWriter, r *http.Request) {
 	createdUint, _ := utils.ConvertStringToUint(created)
 	createdBounty, err := h.db.GetBountyByCreated(createdUint)
 	if err != nil {
-		logger.Log.Error("[bounty] failed to delete bounty: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] failed to delete bounty: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode("failed to delete bounty")
 		return
 	}
 
 	if createdBounty.ID == 0 {
-		logger.Log.Error("[bounty] failed to delete bounty")
+		logger.FromContext(r.Context()).Error("[bounty] failed to delete bounty")
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode("failed to delete bounty")
 		return
@@ -691,11 +711,13 @@ func (h *bountyHandler) DeleteBounty(w http.ResponseWriter, r *http.Request) {
 
 	b, err := h.db.DeleteBounty(pubkey, created)
 	if err != nil {
-		logger.Log.Error("[bounty] failed to delete bounty: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] failed to delete bounty: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode("failed to delete bounty")
 		return
 	}
+	audit.Record(pubKeyFromAuth, createdBounty.WorkspaceUuid, audit.EntityBounty, strconv.FormatUint(uint64(createdBounty.ID), 10), audit.ActionDelete, createdBounty, nil)
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(b)
 }
@@ -820,6 +842,7 @@ func (h *bountyHandler) GenerateBountyResponse(bounties []db.NewBounty) []db.Bou
 				BountyExpires:           bounty.BountyExpires,
 				CommitmentFee:           bounty.CommitmentFee,
 				Price:                   bounty.Price,
+				AssetId:                 bounty.AssetId,
 				Title:                   bounty.Title,
 				Tribe:                   bounty.Tribe,
 				Created:                 bounty.Created,
@@ -850,6 +873,8 @@ func (h *bountyHandler) GenerateBountyResponse(bounties []db.NewBounty) []db.Bou
 				MaxStakers:              bounty.MaxStakers,
 				CurrentStakers:          bounty.CurrentStakers,
 				Stakes:                  bounty.Stakes,
+				Assignees:               bounty.Assignees,
+				Milestones:              bounty.Milestones,
 			},
 			Assignee: db.Person{
 				ID:               assignee.ID,
@@ -895,7 +920,8 @@ func (h *bountyHandler) GenerateBountyResponse(bounties []db.NewBounty) []db.Bou
 				Uuid: workspace.Uuid,
 				Img:  workspace.Img,
 			},
-			Pow: bounty.ProofOfWorkCount,
+			Assignees: h.assigneeShares(bounty, bounty.Assignees),
+			Pow:       bounty.ProofOfWorkCount,
 		}
 
 		if len(proofs) > 0 {
@@ -928,14 +954,14 @@ func (h *bountyHandler) MakeBountyPayment(w http.ResponseWriter, r *http.Request
 
 	id, err := utils.ConvertStringToUint(idParam)
 	if err != nil {
-		logger.Log.Error("[bounty] could not parse id")
+		logger.FromContext(r.Context()).Error("[bounty] could not parse id")
 		w.WriteHeader(http.StatusForbidden)
 		h.m.Unlock()
 		return
 	}
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		h.m.Unlock()
 		return
@@ -969,6 +995,13 @@ func (h *bountyHandler) MakeBountyPayment(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if len(bounty.Milestones) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode("Bounty is paid by milestones as their proofs are accepted")
+		h.m.Unlock()
+		return
+	}
+
 	// check if user is the admin of the workspace
 	// or has a pay bounty role
 	hasRole := h.userHasAccess(pubKeyFromAuth, bounty.WorkspaceUuid, db.PayBounty)
@@ -979,21 +1012,46 @@ func (h *bountyHandler) MakeBountyPayment(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// a split bounty pays the shares that are not paid yet
+	if len(bounty.Assignees) > 0 {
+		amount = unpaidSharesTotal(bounty)
+	}
+
 	// check if the workspace bounty balance
-	// is greater than the amount
-	orgBudget := h.db.GetWorkspaceBudget(bounty.WorkspaceUuid)
-	if orgBudget.TotalBudget < amount {
+	// is greater than the amount, in the asset the bounty is priced in
+	var budget uint
+	if bounty.AssetId != 0 {
+		budget = h.db.GetWorkspaceAssetBudget(bounty.WorkspaceUuid, bounty.AssetId).Balance
+	} else {
+		budget = h.db.GetWorkspaceBudget(bounty.WorkspaceUuid).TotalBudget
+	}
+	if budget < amount {
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode("workspace budget is not enough to pay the amount")
 		h.m.Unlock()
 		return
 	}
 
+	// spend limits cap the sats budget
+	if bounty.AssetId == 0 {
+		if err := h.db.CheckSpendLimit(bounty.WorkspaceUuid, pubKeyFromAuth, db.PayBounty, amount); err != nil {
+			if errors.Is(err, db.ErrSpendLimitExceeded) {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(err.Error())
+			} else {
+				logger.FromContext(r.Context()).Error("[bounty] could not check the spend limits of %s: %v", bounty.WorkspaceUuid, err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			h.m.Unlock()
+			return
+		}
+	}
+
 	request := db.BountyPayRequest{}
 	body, err := io.ReadAll(r.Body)
 	r.Body.Close()
 	if err != nil {
-		logger.Log.Error("[bounty] Read body error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Read body error: %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		h.m.Unlock()
 		return
@@ -1001,12 +1059,18 @@ func (h *bountyHandler) MakeBountyPayment(w http.ResponseWriter, r *http.Request
 
 	err = json.Unmarshal(body, &request)
 	if err != nil {
-		logger.Log.Error("[bounty] Unmarshal error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Unmarshal error: %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		h.m.Unlock()
 		return
 	}
 
+	if len(bounty.Assignees) > 0 {
+		h.makeSplitBountyPayment(w, r, pubKeyFromAuth, bounty, request)
+		h.m.Unlock()
+		return
+	}
+
 	// Get Bounty Assignee
 	assignee := h.db.GetPersonByPubkey(bounty.Assignee)
 
@@ -1014,278 +1078,120 @@ func (h *bountyHandler) MakeBountyPayment(w http.ResponseWriter, r *http.Request
 	memoText := url.QueryEscape(memoData)
 	now := time.Now()
 
-	// If the v2contactkey is present
-	if config.IsV2Payment {
-		url := fmt.Sprintf("%s/pay", config.V2BotUrl)
-
-		logger.Log.Info("IS V2 PAYMENT ====")
+	log.Printf("[bounty] Making Bounty Payment: amount: %d, pubkey: %s, route_hint: %s", amount, assignee.OwnerPubKey, assignee.OwnerRouteHint)
 
-		// Build v2 keysend payment data
-		bodyData := utils.BuildV2KeysendBodyData(amount, assignee.OwnerPubKey, assignee.OwnerRouteHint, memoText)
-		jsonBody := []byte(bodyData)
-
-		log.Println("Payment Body Data", bodyData)
+	keysendRes, err := sendBountyPayment(h.lightning, bounty.AssetId, amount, assignee.OwnerPubKey, assignee.OwnerRouteHint, memoText)
+	if errors.Is(err, lightning.ErrAssetsUnsupported) {
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode("Asset payments are not supported by this node")
+		h.m.Unlock()
+		return
+	}
+	if err != nil && !errors.Is(err, lightning.ErrRejected) {
+		// the node didn't answer, so whether the payment went out is unknown
+		logger.FromContext(r.Context()).Error("[bounty] Keysend payment failed: %v", err)
+		w.WriteHeader(http.StatusNotAcceptable)
+		h.m.Unlock()
+		return
+	}
 
-		req, _ := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
-		req.Header.Set("x-admin-token", config.V2BotToken)
-		req.Header.Set("Content-Type", "application/json")
-		log.Printf("[bounty] Making Bounty V2 Payment: amount: %d, pubkey: %s, route_hint: %s", amount, assignee.OwnerPubKey, assignee.OwnerRouteHint)
+	log.Printf("[bounty] Status After Making Bounty Payment: amount: %d, pubkey: %s, route_hint: %s is : %s", amount, assignee.OwnerPubKey, assignee.OwnerRouteHint, keysendRes.Status)
 
-		res, err := h.httpClient.Do(req)
+	paymentHistory := db.NewPaymentHistory{
+		Amount:         amount,
+		AssetId:        bounty.AssetId,
+		SenderPubKey:   pubKeyFromAuth,
+		ReceiverPubKey: assignee.OwnerPubKey,
+		WorkspaceUuid:  bounty.WorkspaceUuid,
+		BountyId:       id,
+		Created:        &now,
+		Updated:        &now,
+		Status:         false,
+		PaymentType:    "payment",
+		Tag:            keysendRes.Tag,
+		PaymentStatus:  db.PaymentFailed,
+	}
 
-		if err != nil {
-			log.Printf("[bounty] Request Failed: %s", err)
-			h.m.Unlock()
-			return
-		}
+	msg := make(map[string]interface{})
+	msg["invoice"] = ""
+	status := http.StatusOK
 
-		log.Printf("[bounty] After Making Bounty V2 Payment: amount: %d, pubkey: %s, route_hint: %s", amount, assignee.OwnerPubKey, assignee.OwnerRouteHint)
+	if err != nil {
+		log.Println("Keysend payment error: Failed to send ===")
+		msg["msg"] = "keysend_error"
+		status = http.StatusBadRequest
 
-		defer res.Body.Close()
-		body, err = io.ReadAll(res.Body)
-		if err != nil {
-			log.Println("[read body failed]", err)
-			w.WriteHeader(http.StatusNotAcceptable)
-			h.m.Unlock()
-			return
-		}
+		bounty.Paid = false
+		bounty.PaymentPending = false
+		bounty.PaymentFailed = true
 
-		log.Println("[bounty] After Reading Keysend V2 Payment Body ===")
+		// set the error message
+		paymentHistory.Error = "Payment Request Failed"
 
-		msg := make(map[string]interface{})
+		h.db.AddPaymentHistory(paymentHistory)
+		h.db.UpdateBounty(bounty)
+	} else if keysendRes.Status == db.PaymentComplete {
 		// payment is successful add to payment history
 		// and reduce workspaces budget
-
-		paymentHistory := db.NewPaymentHistory{
-			Amount:         amount,
-			SenderPubKey:   pubKeyFromAuth,
-			ReceiverPubKey: assignee.OwnerPubKey,
-			WorkspaceUuid:  bounty.WorkspaceUuid,
-			BountyId:       id,
-			Created:        &now,
-			Updated:        &now,
-			Status:         false,
-			PaymentType:    "payment",
-			Tag:            "",
-			PaymentStatus:  "FAILED",
+		bounty.PaymentFailed = false
+		bounty.PaymentPending = false
+		bounty.Paid = true
+		bounty.PaidDate = &now
+		bounty.Completed = true
+		bounty.CompletionDate = &now
+
+		paymentHistory.Status = true
+		paymentHistory.PaymentStatus = db.PaymentComplete
+
+		h.db.ProcessBountyPayment(paymentHistory, bounty)
+		emitWebhook(bounty.WorkspaceUuid, webhooks.EventBountyPaid, bounty)
+		recordBountyPayment(pubKeyFromAuth, bounty)
+		enqueueBudgetAlert(bounty.WorkspaceUuid)
+
+		msg["msg"] = "keysend_success"
+	} else if keysendRes.Status == db.PaymentPending {
+		log.Printf("[bounty] Payment is pending:  %s", keysendRes.Tag)
+		bounty.Paid = false
+		bounty.PaymentFailed = false
+		bounty.PaymentPending = true
+		bounty.PaidDate = &now
+		bounty.Completed = true
+		bounty.CompletionDate = &now
+
+		paymentHistory.Status = true
+		paymentHistory.PaymentStatus = db.PaymentPending
+
+		if err := h.db.ProcessBountyPayment(paymentHistory, bounty); err == nil {
+			enqueuePaymentCheck(bounty.ID)
+			enqueueBudgetAlert(bounty.WorkspaceUuid)
 		}
 
-		if res.StatusCode == 200 {
-			// Unmarshal result
-			v2KeysendRes := db.V2SendOnionRes{}
-			err = json.Unmarshal(body, &v2KeysendRes)
-
-			if err != nil {
-				logger.Log.Error("[Unmarshal failed]: %v", err)
-				w.WriteHeader(http.StatusNotAcceptable)
-				h.m.Unlock()
-				return
-			}
-
-			log.Printf("[bounty] V2 Status After Making Bounty V2 Payment: amount: %d, pubkey: %s, route_hint: %s is : %s", amount, assignee.OwnerPubKey, assignee.OwnerRouteHint, v2KeysendRes.Status)
-
-			// if the payment has a completed status
-			if v2KeysendRes.Status == db.PaymentComplete {
-				bounty.PaymentFailed = false
-				bounty.PaymentPending = false
-				bounty.Paid = true
-				bounty.PaidDate = &now
-				bounty.Completed = true
-				bounty.CompletionDate = &now
-
-				paymentHistory.Status = true
-				paymentHistory.PaymentStatus = db.PaymentComplete
-				paymentHistory.Tag = v2KeysendRes.Tag
-
-				h.db.ProcessBountyPayment(paymentHistory, bounty)
-
-				msg["msg"] = "keysend_success"
-				msg["invoice"] = ""
-
-				socket, err := h.getSocketConnections(request.Websocket_token)
-				if err == nil {
-					socket.Conn.WriteJSON(msg)
-				}
-
-				h.m.Unlock()
-
-				w.WriteHeader(http.StatusOK)
-				json.NewEncoder(w).Encode(msg)
-				return
-			} else if v2KeysendRes.Status == db.PaymentPending {
-				// Send payment status
-				log.Printf("[bounty] V2 Status is pending:  %s", v2KeysendRes.Status)
-				bounty.Paid = false
-				bounty.PaymentFailed = false
-				bounty.PaymentPending = true
-				bounty.PaidDate = &now
-				bounty.Completed = true
-				bounty.CompletionDate = &now
-
-				paymentHistory.Status = true
-				paymentHistory.PaymentStatus = db.PaymentPending
-				paymentHistory.Tag = v2KeysendRes.Tag
-
-				h.db.ProcessBountyPayment(paymentHistory, bounty)
-
-				msg["msg"] = "keysend_pending"
-				msg["invoice"] = ""
-
-				socket, err := h.getSocketConnections(request.Websocket_token)
-				if err == nil {
-					socket.Conn.WriteJSON(msg)
-				}
-
-				h.m.Unlock()
-
-				w.WriteHeader(http.StatusOK)
-				json.NewEncoder(w).Encode(msg)
-				return
-			} else {
-				// Send payment status
-				log.Printf("[bounty] V2 Status Was not completed:  %s", v2KeysendRes.Status)
-
-				bounty.Paid = false
-				bounty.PaymentPending = false
-				bounty.PaymentFailed = true
-
-				// set the error message
-				paymentHistory.Error = v2KeysendRes.Message
-				paymentHistory.PaymentStatus = db.PaymentFailed
-				paymentHistory.Tag = v2KeysendRes.Tag
-
-				h.db.AddPaymentHistory(paymentHistory)
-				h.db.UpdateBounty(bounty)
-
-				log.Println("Keysend payment not completed ===")
-				msg["msg"] = "keysend_failed"
-				msg["invoice"] = ""
-
-				socket, err := h.getSocketConnections(request.Websocket_token)
-				if err == nil {
-					socket.Conn.WriteJSON(msg)
-				}
-
-				h.m.Unlock()
-
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(msg)
-				return
-			}
-		} else { // Send Payment error
-			log.Println("Keysend payment error: Failed to send ===")
-			msg["msg"] = "keysend_error"
-			msg["invoice"] = ""
-
-			bounty.Paid = false
-			bounty.PaymentPending = false
-			bounty.PaymentFailed = true
-
-			// set the error message
-			paymentHistory.Error = "Payment Request Failed"
-
-			h.db.AddPaymentHistory(paymentHistory)
-			h.db.UpdateBounty(bounty)
-
-			socket, err := h.getSocketConnections(request.Websocket_token)
-			if err == nil {
-				socket.Conn.WriteJSON(msg)
-			}
-
-			h.m.Unlock()
-
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(msg)
-			return
-		}
-	} else { // Process v1 payment
-		url := fmt.Sprintf("%s/payment", config.RelayUrl)
-
-		bodyData := utils.BuildKeysendBodyData(amount, assignee.OwnerPubKey, assignee.OwnerRouteHint, memoText)
-		jsonBody := []byte(bodyData)
-
-		log.Println("Payment Body Data", bodyData)
-
-		req, _ := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
-		req.Header.Set("x-user-token", config.RelayAuthKey)
-		req.Header.Set("Content-Type", "application/json")
-		log.Printf("[bounty] Making Bounty Payment: amount: %d, pubkey: %s, route_hint: %s", amount, assignee.OwnerPubKey, assignee.OwnerRouteHint)
-		res, err := h.httpClient.Do(req)
-
-		if err != nil {
-			log.Printf("[bounty] Request Failed: %s", err)
-			h.m.Unlock()
-			return
-		}
-
-		defer res.Body.Close()
-		body, err = io.ReadAll(res.Body)
-		if err != nil {
-			logger.Log.Error("[bounty] Read body error: %v", err)
-			w.WriteHeader(http.StatusNotAcceptable)
-			h.m.Unlock()
-			return
-		}
-
-		msg := make(map[string]interface{})
-
-		// payment is successful add to payment history
-		// and reduce workspaces budget
-		if res.StatusCode == 200 {
-			// Unmarshal result
-			keysendRes := db.KeysendSuccess{}
-			err = json.Unmarshal(body, &keysendRes)
-
-			if err != nil {
-				logger.Log.Error("[bounty] Unmarshal error: %v", err)
-				w.WriteHeader(http.StatusNotAcceptable)
-				h.m.Unlock()
-				return
-			}
-
-			now := time.Now()
-
-			paymentHistory := db.NewPaymentHistory{
-				Amount:         amount,
-				SenderPubKey:   pubKeyFromAuth,
-				ReceiverPubKey: assignee.OwnerPubKey,
-				WorkspaceUuid:  bounty.WorkspaceUuid,
-				BountyId:       id,
-				Created:        &now,
-				Updated:        &now,
-				Status:         true,
-				PaymentType:    "payment",
-			}
+		msg["msg"] = "keysend_pending"
+	} else {
+		log.Printf("[bounty] Payment was not completed:  %s", keysendRes.Status)
+		msg["msg"] = "keysend_failed"
+		status = http.StatusBadRequest
 
-			bounty.Paid = true
-			bounty.PaidDate = &now
-			bounty.Completed = true
-			bounty.CompletionDate = &now
+		bounty.Paid = false
+		bounty.PaymentPending = false
+		bounty.PaymentFailed = true
 
-			h.db.ProcessBountyPayment(paymentHistory, bounty)
+		// set the error message
+		paymentHistory.Error = keysendRes.Message
 
-			msg["msg"] = "keysend_success"
-			msg["invoice"] = ""
+		h.db.AddPaymentHistory(paymentHistory)
+		h.db.UpdateBounty(bounty)
+	}
 
-			socket, err := h.getSocketConnections(request.Websocket_token)
-			if err == nil {
-				socket.Conn.WriteJSON(msg)
-			}
-			h.m.Unlock()
-			return
-		} else {
-			msg["msg"] = "keysend_error"
-			msg["invoice"] = ""
+	socket, err := h.getSocketConnections(request.Websocket_token)
+	if err == nil {
+		socket.Conn.WriteJSON(msg)
+	}
 
-			socket, err := h.getSocketConnections(request.Websocket_token)
-			if err == nil {
-				socket.Conn.WriteJSON(msg)
-			}
+	h.m.Unlock()
 
-			h.m.Unlock()
-			return
-		}
-	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(msg)
 }
 
 // GetBountyPaymentStatus godoc
@@ -1305,13 +1211,13 @@ func (h *bountyHandler) GetBountyPaymentStatus(w http.ResponseWriter, r *http.Re
 
 	id, err := utils.ConvertStringToUint(idParam)
 	if err != nil {
-		logger.Log.Error("[bounty] could not parse id")
+		logger.FromContext(r.Context()).Error("[bounty] could not parse id")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1359,13 +1265,13 @@ func (h *bountyHandler) UpdateBountyPaymentStatus(w http.ResponseWriter, r *http
 
 	id, err := utils.ConvertStringToUint(idParam)
 	if err != nil {
-		logger.Log.Error("[bounty] could not parse id")
+		logger.FromContext(r.Context()).Error("[bounty] could not parse id")
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1400,6 +1306,9 @@ func (h *bountyHandler) UpdateBountyPaymentStatus(w http.ResponseWriter, r *http
 		tag := payment.Tag
 
 		tagResult := h.getInvoiceStatusByTag(tag)
+		if tagResult.Status != db.PaymentComplete && replacedSendCompleted(h.getInvoiceStatusByTag, payment) {
+			tagResult.Status = db.PaymentComplete
+		}
 
 		msg := map[string]string{
 			"payment_status": tagResult.Status,
@@ -1422,35 +1331,25 @@ func (h *bountyHandler) UpdateBountyPaymentStatus(w http.ResponseWriter, r *http
 			bounty.CompletionDate = &now
 
 			h.db.UpdateBountyPaymentStatuses(bounty)
+			emitWebhook(bounty.WorkspaceUuid, webhooks.EventBountyPaid, bounty)
+			recordBountyPayment(payment.SenderPubKey, bounty)
 
 			w.WriteHeader(http.StatusOK)
 			json.NewEncoder(w).Encode(msg)
 			return
-		} else if tagResult.Status == db.PaymentFailed {
-
-			err = h.db.ProcessReversePayments(payment.ID)
-
-			if err != nil {
-				log.Printf("Could not reverse bounty payment : Bounty ID - %d, Payment ID - %d, Error - %s", bounty.ID, payment.ID, err)
+		} else if tagResult.Status == db.PaymentFailed || tagResult.Status == db.PaymentPending {
+			// failed and timed out payments get the workspace's payment policy
+			if payment.PaymentStatus == db.PaymentPending && !payment.NeedsReview {
+				err = newPaymentResolver(h.db, h.lightning).Resolve(bounty, payment, tagResult.Status)
+				if err != nil && !errors.Is(err, errPaymentPending) {
+					log.Printf("Could not resolve bounty payment : Bounty ID - %d, Payment ID - %d, Error - %s", bounty.ID, payment.ID, err)
+				}
 			}
 
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(msg)
-			return
-		} else if tagResult.Status == db.PaymentPending {
-			if payment.PaymentStatus == db.PaymentPending {
-				created := utils.ConvertTimeToTimestamp(payment.Created.String())
-
-				now := time.Now()
-				daysDiff := utils.GetDateDaysDifference(int64(created), &now)
-
-				if daysDiff >= 7 {
-
-					err = h.db.ProcessReversePayments(payment.ID)
-					if err != nil {
-						log.Printf("Could not reverse bounty payment after 7 days : Bounty ID - %d, Payment ID - %d, Error - %s", bounty.ID, payment.ID, err)
-					}
-				}
+			if tagResult.Status == db.PaymentFailed {
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(msg)
+				return
 			}
 		}
 	}
@@ -1482,7 +1381,7 @@ func (h *bountyHandler) BountyBudgetWithdraw(w http.ResponseWriter, r *http.Requ
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty] no pubkey from auth")
 		h.m.Unlock()
 
 		w.WriteHeader(http.StatusUnauthorized)
@@ -1558,6 +1457,19 @@ func (h *bountyHandler) BountyBudgetWithdraw(w http.ResponseWriter, r *http.Requ
 			return
 		}
 
+		if err := h.db.CheckSpendLimit(request.WorkspaceUuid, pubKeyFromAuth, db.WithdrawBudget, amount); err != nil {
+			h.m.Unlock()
+
+			if !errors.Is(err, db.ErrSpendLimitExceeded) {
+				logger.FromContext(r.Context()).Error("[bounty] could not check the spend limits of %s: %v", request.WorkspaceUuid, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(formatPayError(err.Error()))
+			return
+		}
+
 		// Check that the deposit is more than the withdrawal plus amount to withdraw
 		sumOfWithdrawals := h.db.GetSumOfWithdrawal(request.WorkspaceUuid)
 		sumOfDeposits := h.db.GetSumOfDeposits(request.WorkspaceUuid)
@@ -1575,6 +1487,14 @@ func (h *bountyHandler) BountyBudgetWithdraw(w http.ResponseWriter, r *http.Requ
 		if paymentSuccess.Success {
 			// withdraw amount from workspace budget
 			h.db.WithdrawBudget(pubKeyFromAuth, request.WorkspaceUuid, amount)
+			enqueueBudgetAlert(request.WorkspaceUuid)
+			emitWebhook(request.WorkspaceUuid, webhooks.EventBudgetWithdraw, map[string]interface{}{
+				"amount":        amount,
+				"sender_pubkey": pubKeyFromAuth,
+			})
+			audit.Record(pubKeyFromAuth, request.WorkspaceUuid, audit.EntityBudget, request.WorkspaceUuid, audit.ActionWithdraw,
+				map[string]interface{}{"total_budget": orgBudget.TotalBudget},
+				map[string]interface{}{"total_budget": orgBudget.TotalBudget - amount, "amount": amount})
 
 			h.m.Unlock()
 
@@ -1603,255 +1523,11 @@ func formatPayError(errorMsg string) db.InvoicePayError {
 }
 
 func (h *bountyHandler) GetLightningInvoice(payment_request string) (db.InvoiceResult, db.InvoiceError) {
-	if config.IsV2Payment {
-		return h.GetV2LightningInvoice(payment_request)
-	} else {
-		return h.GetV1LightningInvoice(payment_request)
-	}
-}
-
-func (h *bountyHandler) GetV1LightningInvoice(payment_request string) (db.InvoiceResult, db.InvoiceError) {
-	url := fmt.Sprintf("%s/invoice?payment_request=%s", config.RelayUrl, payment_request)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-
-	req.Header.Set("x-user-token", config.RelayAuthKey)
-	req.Header.Set("Content-Type", "application/json")
-	res, _ := h.httpClient.Do(req)
-
-	if err != nil {
-		log.Printf("[bounty] Request Failed: %s", err)
-		return db.InvoiceResult{}, db.InvoiceError{}
-	}
-
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-
-	if err != nil {
-		log.Printf("Error reading: %s", err)
-		return db.InvoiceResult{}, db.InvoiceError{Success: false, Error: err.Error()}
-	}
-
-	if res.StatusCode != 200 {
-		// Unmarshal result
-		invoiceErr := db.InvoiceError{}
-		err = json.Unmarshal(body, &invoiceErr)
-
-		if err != nil {
-			log.Printf("[bounty] Reading Invoice body failed: %s", err)
-			return db.InvoiceResult{}, invoiceErr
-		}
-
-		return db.InvoiceResult{}, invoiceErr
-	} else {
-		// Unmarshal result
-		invoiceRes := db.InvoiceResult{}
-		err = json.Unmarshal(body, &invoiceRes)
-
-		if err != nil {
-			log.Printf("[bounty] Reading Invoice body failed: %s", err)
-			return invoiceRes, db.InvoiceError{}
-		}
-
-		return invoiceRes, db.InvoiceError{}
-	}
-}
-
-func (h *bountyHandler) GetV2LightningInvoice(payment_request string) (db.InvoiceResult, db.InvoiceError) {
-	url := fmt.Sprintf("%s/check_invoice", config.V2BotUrl)
-
-	invoiceBody := db.V2InvoiceBody{
-		Bolt11: payment_request,
-	}
-
-	jsonBody, _ := json.Marshal(invoiceBody)
-
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
-	req.Header.Set("x-admin-token", config.V2BotToken)
-	req.Header.Set("Content-Type", "application/json")
-	res, _ := h.httpClient.Do(req)
-
-	if err != nil {
-		log.Printf("[bounty] Request Failed: %s", err)
-		return db.InvoiceResult{}, db.InvoiceError{Success: false, Error: err.Error()}
-	}
-
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-
-	if err != nil {
-		log.Printf("[bounty] Reading Invoice body failed: %s", err)
-		return db.InvoiceResult{}, db.InvoiceError{Success: false, Error: err.Error()}
-	}
-
-	if res.StatusCode != 200 {
-		// Unmarshal result
-		invoiceErr := db.InvoiceError{}
-		err = json.Unmarshal(body, &invoiceErr)
-
-		if err != nil {
-			log.Printf("[bounty] Unmarshalling Invoice body failed: %s", err)
-			return db.InvoiceResult{}, invoiceErr
-		}
-
-		return db.InvoiceResult{}, invoiceErr
-	} else {
-		// Unmarshal result
-		invoiceRes := db.V2InvoiceResponse{}
-		err = json.Unmarshal(body, &invoiceRes)
-
-		if err != nil {
-			log.Printf("[bounty] Reading Invoice body failed: %s", err)
-			return db.InvoiceResult{}, db.InvoiceError{}
-		}
-
-		invoiceResult := db.InvoiceResult{
-			Success: false,
-			Response: db.InvoiceCheckResponse{
-				Settled:         false,
-				Payment_request: payment_request,
-				Payment_hash:    "",
-				Preimage:        "",
-			},
-		}
-
-		if invoiceRes.Status == db.InvoicePaid {
-			invoiceResult.Success = true
-			invoiceResult.Response.Settled = true
-			return invoiceResult, db.InvoiceError{}
-		}
-		return invoiceResult, db.InvoiceError{}
-	}
+	return h.lightning.LookupInvoice(payment_request)
 }
 
 func (h *bountyHandler) PayLightningInvoice(payment_request string) (db.InvoicePaySuccess, db.InvoicePayError) {
-	if config.IsV2Payment {
-		return h.PayV2LightningInvoice(payment_request)
-	} else {
-		return h.PayV1LightningInvoice(payment_request)
-	}
-}
-
-func (h *bountyHandler) PayV1LightningInvoice(payment_request string) (db.InvoicePaySuccess, db.InvoicePayError) {
-	url := fmt.Sprintf("%s/invoices", config.RelayUrl)
-	bodyData := fmt.Sprintf(`{"payment_request": "%s"}`, payment_request)
-	jsonBody := []byte(bodyData)
-
-	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(jsonBody))
-
-	if err != nil {
-		log.Printf("Error paying invoice: %s", err)
-	}
-
-	req.Header.Set("x-user-token", config.RelayAuthKey)
-	req.Header.Set("Content-Type", "application/json")
-	res, err := h.httpClient.Do(req)
-
-	if err != nil {
-		log.Printf("[bounty] Request Failed: %s", err)
-		return db.InvoicePaySuccess{}, db.InvoicePayError{}
-	}
-
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-
-	if err != nil {
-		log.Printf("Error could not read body: %s", err)
-	}
-
-	if res.StatusCode != 200 {
-		invoiceError := db.InvoicePayError{}
-		err = json.Unmarshal(body, &invoiceError)
-
-		if err != nil {
-			log.Printf("[bounty] Reading Invoice pay error body failed: %s", err)
-			return db.InvoicePaySuccess{}, db.InvoicePayError{}
-		}
-
-		return db.InvoicePaySuccess{}, invoiceError
-	} else {
-		invoiceSuccess := db.InvoicePaySuccess{}
-		err = json.Unmarshal(body, &invoiceSuccess)
-
-		if err != nil {
-			log.Printf("[bounty] Reading Invoice pay success body failed: %s", err)
-			return db.InvoicePaySuccess{}, db.InvoicePayError{}
-		}
-
-		return invoiceSuccess, db.InvoicePayError{}
-	}
-}
-
-func (h *bountyHandler) PayV2LightningInvoice(payment_request string) (db.InvoicePaySuccess, db.InvoicePayError) {
-	url := fmt.Sprintf("%s/pay_invoice", config.V2BotUrl)
-	bodyData := fmt.Sprintf(`{"bolt11": "%s", "wait": true}`, payment_request)
-	jsonBody := []byte(bodyData)
-
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
-
-	if err != nil {
-		log.Printf("Error paying invoice: %s", err)
-		return db.InvoicePaySuccess{}, db.InvoicePayError{}
-	}
-
-	req.Header.Set("x-admin-token", config.V2BotToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	res, err := h.httpClient.Do(req)
-
-	if err != nil {
-		log.Printf("[bounty] Request Failed: %s", err)
-		return db.InvoicePaySuccess{}, db.InvoicePayError{}
-	}
-
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-
-	if err != nil {
-		log.Printf("Error could not read body: %s", err)
-	}
-
-	if res.StatusCode != 200 {
-		invoiceError := db.InvoicePayError{}
-		err = json.Unmarshal(body, &invoiceError)
-
-		if err != nil {
-			log.Printf("[bounty] Reading Invoice pay error body failed: %s", err)
-			return db.InvoicePaySuccess{}, db.InvoicePayError{}
-		}
-
-		return db.InvoicePaySuccess{}, invoiceError
-	} else {
-		invoiceRes := db.V2InvoiceResponse{}
-		err = json.Unmarshal(body, &invoiceRes)
-
-		if err != nil {
-			log.Printf("[bounty] Reading Invoice pay success body failed: %s", err)
-			return db.InvoicePaySuccess{}, db.InvoicePayError{}
-		}
-
-		invoiceResult := db.InvoicePaySuccess{
-			Success: false,
-			Response: db.InvoiceCheckResponse{
-				Settled:         false,
-				Payment_request: payment_request,
-				Payment_hash:    "",
-				Preimage:        "",
-			},
-		}
-
-		if invoiceRes.Status == db.PaymentComplete {
-			invoiceResult.Success = true
-			invoiceResult.Response.Settled = true
-			return invoiceResult, db.InvoicePayError{}
-		}
-
-		return invoiceResult, db.InvoicePayError{}
-	}
+	return h.lightning.PayInvoice(payment_request)
 }
 
 // GetInvoiceData godoc
@@ -1893,7 +1569,7 @@ func (h *bountyHandler) PollInvoice(w http.ResponseWriter, r *http.Request) {
 	paymentRequest := chi.URLParam(r, "paymentRequest")
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1907,18 +1583,7 @@ func (h *bountyHandler) PollInvoice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if invoiceRes.Response.Settled {
-		// Todo if an invoice is settled
-		invoice := h.db.GetInvoice(paymentRequest)
-		dbInvoice := h.db.GetInvoice(paymentRequest)
-
-		// Make any change only if the invoice has not been settled
-		if !dbInvoice.Status {
-			if invoice.Type == "BUDGET" {
-				h.db.AddAndUpdateBudget(invoice)
-			}
-			// Update the invoice status
-			h.db.UpdateInvoice(paymentRequest)
-		}
+		settleInvoice(h.db, paymentRequest)
 	} else {
 		// Cheeck if time has expired
 		isInvoiceExpired := utils.GetInvoiceExpired(paymentRequest)
@@ -1932,6 +1597,24 @@ func (h *bountyHandler) PollInvoice(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(invoiceRes)
 }
 
+// settleInvoice books a paid invoice, crediting the workspace budget for
+// budget invoices. Invoices already marked as paid are left alone.
+func settleInvoice(database db.Database, paymentRequest string) {
+	invoice := database.GetInvoice(paymentRequest)
+	dbInvoice := database.GetInvoice(paymentRequest)
+
+	// Make any change only if the invoice has not been settled
+	if !dbInvoice.Status {
+		if invoice.Type == "BUDGET" {
+			database.AddAndUpdateBudget(invoice)
+			emitWebhook(invoice.WorkspaceUuid, webhooks.EventBudgetDeposit, invoice)
+			audit.Record(invoice.OwnerPubkey, invoice.WorkspaceUuid, audit.EntityBudget, invoice.WorkspaceUuid, audit.ActionDeposit, nil, invoice)
+		}
+		// Update the invoice status
+		database.UpdateInvoice(paymentRequest)
+	}
+}
+
 // GetFilterCount godoc
 //
 //	@Summary		Get filter count
@@ -1968,7 +1651,7 @@ func (h *bountyHandler) GetBountyCards(w http.ResponseWriter, r *http.Request) {
 
 	ticketCards, err := h.GenerateTicketCardResponse(workspaceUuid)
 	if err != nil {
-		logger.Log.Error("failed to generate ticket cards", "error", err)
+		logger.FromContext(r.Context()).Error("failed to generate ticket cards", "error", err)
 	} else {
 		bountyCardResponse = append(bountyCardResponse, ticketCards...)
 	}
@@ -2058,6 +1741,7 @@ func (h *bountyHandler) GenerateBountyCardResponse(bounties []db.NewBounty) []db
 			AssigneePic:  assigneePic,
 			Assignee:     assigneePubkey,
 			AssigneeName: assigneeName,
+			Assignees:    h.assigneeShares(bounty, h.db.GetBountyAssignees(bounty.ID)),
 			Features:     feature,
 			Phase:        phase,
 			Workspace:    workspace,
@@ -2121,6 +1805,11 @@ func (h *bountyHandler) AddProofOfWork(w http.ResponseWriter, r *http.Request) {
 
 	proof.ID = uuid.New()
 	proof.BountyID, _ = utils.ConvertStringToUint(bountyID)
+
+	if proof.MilestoneID != nil && !h.milestoneOpen(proof.BountyID, *proof.MilestoneID) {
+		http.Error(w, "Milestone not found or already paid", http.StatusBadRequest)
+		return
+	}
 	proof.CreatedAt = time.Now()
 	proof.SubmittedAt = time.Now()
 
@@ -2130,11 +1819,11 @@ func (h *bountyHandler) AddProofOfWork(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.db.PauseBountyTiming(proof.BountyID); err != nil {
-		handleTimingError(w, "pause_timing", err)
+		handleTimingError(w, r, "pause_timing", err)
 	}
 
 	if err := h.db.UpdateBountyTimingOnProof(proof.BountyID); err != nil {
-		handleTimingError(w, "update_timing_on_proof", err)
+		handleTimingError(w, r, "update_timing_on_proof", err)
 	}
 
 	if err := h.db.IncrementProofCount(proof.BountyID); err != nil {
@@ -2145,7 +1834,7 @@ func (h *bountyHandler) AddProofOfWork(w http.ResponseWriter, r *http.Request) {
 	bounties, err := h.db.GetBountyById(bountyID)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		logger.Log.Error("[bounty] Error: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Error: %v", err)
 	} else {
 		var bountyResponse []db.BountyResponse = h.GenerateBountyResponse(bounties)
 
@@ -2282,7 +1971,7 @@ func (h *bountyHandler) UpdateProofStatus(w http.ResponseWriter, r *http.Request
 		}
 
 		if err := h.db.ResumeBountyTiming(id); err != nil {
-			logger.Log.Error(fmt.Sprintf("Failed to resume timing for bounty ID %d: %v", id, err))
+			logger.FromContext(r.Context()).Error(fmt.Sprintf("Failed to resume timing for bounty ID %d: %v", id, err))
 		}
 
 	case db.AcceptedStatus:
@@ -2292,8 +1981,14 @@ func (h *bountyHandler) UpdateProofStatus(w http.ResponseWriter, r *http.Request
 			return
 		}
 
+		// accepting the proof of a milestone pays the milestone
+		if proof, err := h.db.GetProofByID(proofID); err == nil && proof.MilestoneID != nil {
+			h.acceptMilestoneProof(w, r, id, proof)
+			return
+		}
+
 		if err := h.db.CloseBountyTiming(id); err != nil {
-			logger.Log.Error(fmt.Sprintf("Failed to close timing for bounty ID %d: %v", id, err))
+			logger.FromContext(r.Context()).Error(fmt.Sprintf("Failed to close timing for bounty ID %d: %v", id, err))
 		}
 	}
 
@@ -2361,7 +2056,7 @@ func (h *bountyHandler) DeleteBountyAssignee(w http.ResponseWriter, r *http.Requ
 		h.db.UpdateBounty(b)
 
 		if err := h.db.CloseBountyTiming(b.ID); err != nil {
-			handleTimingError(w, "close_timing", err)
+			handleTimingError(w, r, "close_timing", err)
 		}
 
 		deletedAssignee = true
@@ -2686,7 +2381,7 @@ func (h *bountyHandler) DeleteBountyTiming(w http.ResponseWriter, r *http.Reques
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -2700,14 +2395,14 @@ func (h *bountyHandler) DeleteBountyTiming(w http.ResponseWriter, r *http.Reques
 
 	_, err = h.db.GetBountyTiming(id)
 	if err != nil {
-		logger.Log.Error(fmt.Sprintf("No bounty timing found for bounty ID %d: %v", id, err))
+		logger.FromContext(r.Context()).Error(fmt.Sprintf("No bounty timing found for bounty ID %d: %v", id, err))
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "No timing record found"})
 		return
 	}
 
 	if err := h.db.DeleteBountyTiming(id); err != nil {
-		logger.Log.Error(fmt.Sprintf("Failed to delete bounty timing for bounty ID %d: %v", id, err))
+		logger.FromContext(r.Context()).Error(fmt.Sprintf("Failed to delete bounty timing for bounty ID %d: %v", id, err))
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to delete bounty timing"})
 		return
@@ -2762,7 +2457,7 @@ func (h *bountyHandler) GetBountiesByWorkspaceTime(w http.ResponseWriter, r *htt
 
 	bounties, err := h.db.GetBountiesByWorkspaceAndTimeRange(workspaceId, startDate, endDate)
 	if err != nil {
-		logger.Log.Error("[bounty] Error retrieving bounties: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty] Error retrieving bounties: %v", err)
 		http.Error(w, "Error retrieving bounties", http.StatusInternalServerError)
 		return
 	}
@@ -2805,7 +2500,7 @@ func (h *bountyHandler) CreateBountyStake(w http.ResponseWriter, r *http.Request
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty_stake] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty_stake] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -2813,7 +2508,7 @@ func (h *bountyHandler) CreateBountyStake(w http.ResponseWriter, r *http.Request
 	
 	var stake db.BountyStake
 	if err := json.NewDecoder(r.Body).Decode(&stake); err != nil {
-		logger.Log.Error("[bounty_stake] invalid request body: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] invalid request body: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
 		return
@@ -2823,7 +2518,7 @@ func (h *bountyHandler) CreateBountyStake(w http.ResponseWriter, r *http.Request
 	
 	createdStake, err := h.db.CreateBountyStake(stake)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] failed to create stake: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] failed to create stake: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -2845,7 +2540,7 @@ func (h *bountyHandler) CreateBountyStake(w http.ResponseWriter, r *http.Request
 func (h *bountyHandler) GetAllBountyStakes(w http.ResponseWriter, r *http.Request) {
 	stakes, err := h.db.GetAllBountyStakes()
 	if err != nil {
-		logger.Log.Error("[bounty_stake] failed to get all stakes: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] failed to get all stakes: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to retrieve stakes"})
 		return
@@ -2870,7 +2565,7 @@ func (h *bountyHandler) GetBountyStakesByBountyID(w http.ResponseWriter, r *http
 	bountyIDStr := chi.URLParam(r, "bountyId")
 	bountyID, err := utils.ConvertStringToUint(bountyIDStr)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] invalid bounty ID: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] invalid bounty ID: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid bounty ID"})
 		return
@@ -2878,7 +2573,7 @@ func (h *bountyHandler) GetBountyStakesByBountyID(w http.ResponseWriter, r *http
 	
 	stakes, err := h.db.GetBountyStakesByBountyID(bountyID)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] failed to get stakes by bounty ID: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] failed to get stakes by bounty ID: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to retrieve stakes"})
 		return
@@ -2904,7 +2599,7 @@ func (h *bountyHandler) GetBountyStakeByID(w http.ResponseWriter, r *http.Reques
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] invalid stake ID: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] invalid stake ID: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid stake ID"})
 		return
@@ -2912,7 +2607,7 @@ func (h *bountyHandler) GetBountyStakeByID(w http.ResponseWriter, r *http.Reques
 	
 	stake, err := h.db.GetBountyStakeByID(id)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] failed to get stake by ID: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] failed to get stake by ID: %v", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Stake not found"})
 		return
@@ -2937,7 +2632,7 @@ func (h *bountyHandler) GetBountyStakesByHunterPubKey(w http.ResponseWriter, r *
 	
 	stakes, err := h.db.GetBountyStakesByHunterPubKey(hunterPubKey)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] failed to get stakes by hunter pubkey: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] failed to get stakes by hunter pubkey: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to retrieve stakes"})
 		return
@@ -2968,7 +2663,7 @@ func (h *bountyHandler) UpdateBountyStake(w http.ResponseWriter, r *http.Request
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty_stake] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty_stake] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -2977,7 +2672,7 @@ func (h *bountyHandler) UpdateBountyStake(w http.ResponseWriter, r *http.Request
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] invalid stake ID: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] invalid stake ID: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid stake ID"})
 		return
@@ -2985,7 +2680,7 @@ func (h *bountyHandler) UpdateBountyStake(w http.ResponseWriter, r *http.Request
 	
 	existingStake, err := h.db.GetBountyStakeByID(id)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] stake not found: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] stake not found: %v", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Stake not found"})
 		return
@@ -2993,7 +2688,7 @@ func (h *bountyHandler) UpdateBountyStake(w http.ResponseWriter, r *http.Request
 	
 	bounty := h.db.GetBounty(existingStake.BountyID)
 	if existingStake.HunterPubKey != pubKeyFromAuth && bounty.OwnerID != pubKeyFromAuth {
-		logger.Log.Error("[bounty_stake] unauthorized update attempt")
+		logger.FromContext(r.Context()).Error("[bounty_stake] unauthorized update attempt")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "You are not authorized to update this stake"})
 		return
@@ -3001,7 +2696,7 @@ func (h *bountyHandler) UpdateBountyStake(w http.ResponseWriter, r *http.Request
 	
 	var updates map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		logger.Log.Error("[bounty_stake] invalid request body: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] invalid request body: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
 		return
@@ -3009,7 +2704,7 @@ func (h *bountyHandler) UpdateBountyStake(w http.ResponseWriter, r *http.Request
 	
 	updatedStake, err := h.db.UpdateBountyStake(id, updates)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] failed to update stake: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] failed to update stake: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -3038,7 +2733,7 @@ func (h *bountyHandler) DeleteBountyStake(w http.ResponseWriter, r *http.Request
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty_stake] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty_stake] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -3047,7 +2742,7 @@ func (h *bountyHandler) DeleteBountyStake(w http.ResponseWriter, r *http.Request
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] invalid stake ID: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] invalid stake ID: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid stake ID"})
 		return
@@ -3055,7 +2750,7 @@ func (h *bountyHandler) DeleteBountyStake(w http.ResponseWriter, r *http.Request
 	
 	existingStake, err := h.db.GetBountyStakeByID(id)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] stake not found: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] stake not found: %v", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Stake not found"})
 		return
@@ -3063,7 +2758,7 @@ func (h *bountyHandler) DeleteBountyStake(w http.ResponseWriter, r *http.Request
 	
 	bounty := h.db.GetBounty(existingStake.BountyID)
 	if existingStake.HunterPubKey != pubKeyFromAuth && bounty.OwnerID != pubKeyFromAuth {
-		logger.Log.Error("[bounty_stake] unauthorized delete attempt")
+		logger.FromContext(r.Context()).Error("[bounty_stake] unauthorized delete attempt")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "You are not authorized to delete this stake"})
 		return
@@ -3071,7 +2766,7 @@ func (h *bountyHandler) DeleteBountyStake(w http.ResponseWriter, r *http.Request
 	
 	err = h.db.DeleteBountyStake(id)
 	if err != nil {
-		logger.Log.Error("[bounty_stake] failed to delete stake: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake] failed to delete stake: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -3100,7 +2795,7 @@ func (h *bountyHandler) CreateBountyStakeProcess(w http.ResponseWriter, r *http.
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty_stake_process] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -3108,7 +2803,7 @@ func (h *bountyHandler) CreateBountyStakeProcess(w http.ResponseWriter, r *http.
 	
 	var process db.BountyStakeProcess
 	if err := json.NewDecoder(r.Body).Decode(&process); err != nil {
-		logger.Log.Error("[bounty_stake_process] invalid request body: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] invalid request body: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
 		return
@@ -3120,7 +2815,7 @@ func (h *bountyHandler) CreateBountyStakeProcess(w http.ResponseWriter, r *http.
 	
 	createdProcess, err := h.db.CreateBountyStakeProcess(&process)
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] failed to create stake process: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] failed to create stake process: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -3146,7 +2841,7 @@ func (h *bountyHandler) GetAllBountyStakeProcesses(w http.ResponseWriter, r *htt
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty_stake_process] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -3154,7 +2849,7 @@ func (h *bountyHandler) GetAllBountyStakeProcesses(w http.ResponseWriter, r *htt
 	
 	processes, err := h.db.GetAllBountyStakeProcesses()
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] failed to get stake processes: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] failed to get stake processes: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to retrieve stake processes"})
 		return
@@ -3183,7 +2878,7 @@ func (h *bountyHandler) GetBountyStakeProcessByID(w http.ResponseWriter, r *http
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty_stake_process] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -3192,7 +2887,7 @@ func (h *bountyHandler) GetBountyStakeProcessByID(w http.ResponseWriter, r *http
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] invalid process ID: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] invalid process ID: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid process ID"})
 		return
@@ -3200,7 +2895,7 @@ func (h *bountyHandler) GetBountyStakeProcessByID(w http.ResponseWriter, r *http
 	
 	process, err := h.db.GetBountyStakeProcessByID(id)
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] failed to get process by ID: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] failed to get process by ID: %v", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Stake process not found"})
 		return
@@ -3231,7 +2926,7 @@ func (h *bountyHandler) UpdateBountyStakeProcess(w http.ResponseWriter, r *http.
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty_stake_process] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -3240,7 +2935,7 @@ func (h *bountyHandler) UpdateBountyStakeProcess(w http.ResponseWriter, r *http.
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] invalid process ID: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] invalid process ID: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid process ID"})
 		return
@@ -3248,7 +2943,7 @@ func (h *bountyHandler) UpdateBountyStakeProcess(w http.ResponseWriter, r *http.
 	
 	existingProcess, err := h.db.GetBountyStakeProcessByID(id)
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] process not found: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] process not found: %v", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Stake process not found"})
 		return
@@ -3256,7 +2951,7 @@ func (h *bountyHandler) UpdateBountyStakeProcess(w http.ResponseWriter, r *http.
 	
 	bounty := h.db.GetBounty(existingProcess.BountyID)
 	if existingProcess.HunterPubKey != pubKeyFromAuth && bounty.OwnerID != pubKeyFromAuth {
-		logger.Log.Error("[bounty_stake_process] unauthorized update attempt")
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] unauthorized update attempt")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "You are not authorized to update this stake process"})
 		return
@@ -3264,7 +2959,7 @@ func (h *bountyHandler) UpdateBountyStakeProcess(w http.ResponseWriter, r *http.
 	
 	var updates map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		logger.Log.Error("[bounty_stake_process] invalid request body: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] invalid request body: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
 		return
@@ -3272,7 +2967,7 @@ func (h *bountyHandler) UpdateBountyStakeProcess(w http.ResponseWriter, r *http.
 	
 	updatedProcess, err := h.db.UpdateBountyStakeProcess(id, updates)
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] failed to update process: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] failed to update process: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -3301,7 +2996,7 @@ func (h *bountyHandler) DeleteBountyStakeProcess(w http.ResponseWriter, r *http.
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("[bounty_stake_process] no pubkey from auth")
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -3310,7 +3005,7 @@ func (h *bountyHandler) DeleteBountyStakeProcess(w http.ResponseWriter, r *http.
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] invalid process ID: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] invalid process ID: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid process ID"})
 		return
@@ -3318,7 +3013,7 @@ func (h *bountyHandler) DeleteBountyStakeProcess(w http.ResponseWriter, r *http.
 	
 	existingProcess, err := h.db.GetBountyStakeProcessByID(id)
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] process not found: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] process not found: %v", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Stake process not found"})
 		return
@@ -3326,7 +3021,7 @@ func (h *bountyHandler) DeleteBountyStakeProcess(w http.ResponseWriter, r *http.
 	
 	bounty := h.db.GetBounty(existingProcess.BountyID)
 	if existingProcess.HunterPubKey != pubKeyFromAuth && bounty.OwnerID != pubKeyFromAuth {
-		logger.Log.Error("[bounty_stake_process] unauthorized delete attempt")
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] unauthorized delete attempt")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "You are not authorized to delete this stake process"})
 		return
@@ -3334,7 +3029,7 @@ func (h *bountyHandler) DeleteBountyStakeProcess(w http.ResponseWriter, r *http.
 	
 	err = h.db.DeleteBountyStakeProcess(id)
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] failed to delete process: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] failed to delete process: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -3348,7 +3043,7 @@ func (h *bountyHandler) GetBountyStakeProcessesByBountyID(w http.ResponseWriter,
 	bountyIDStr := chi.URLParam(r, "bountyId")
 	bountyID, err := strconv.ParseUint(bountyIDStr, 10, 32)
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] invalid bounty ID: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] invalid bounty ID: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid bounty ID format"})
 		return
@@ -3356,7 +3051,7 @@ func (h *bountyHandler) GetBountyStakeProcessesByBountyID(w http.ResponseWriter,
 
 	processes, err := h.db.GetBountyStakeProcessesByBountyID(uint(bountyID))
 	if err != nil {
-		logger.Log.Error("[bounty_stake_process] failed to get stake processes: %v", err)
+		logger.FromContext(r.Context()).Error("[bounty_stake_process] failed to get stake processes: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/audit"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/utils"
+	"github.com/stakwork/sphinx-tribes/webhooks"
+)
+
+// shareUnpaid reports whether a share still has to be paid, that is it was
+// never paid or its payment failed.
+func shareUnpaid(share db.BountyAssignee) bool {
+	return share.PaymentStatus == "" || share.PaymentStatus == db.PaymentFailed
+}
+
+// unpaidSharesTotal is what paying the unpaid shares of a split bounty
+// takes from the budget.
+func unpaidSharesTotal(bounty db.NewBounty) uint {
+	var total uint
+	for i, amount := range db.SplitBountyPrice(bounty.Price, bounty.Assignees) {
+		if shareUnpaid(bounty.Assignees[i]) {
+			total += amount
+		}
+	}
+	return total
+}
+
+// assigneeShares shows the shares of a split bounty with their hunters.
+func (h *bountyHandler) assigneeShares(bounty db.NewBounty, shares []db.BountyAssignee) []db.AssigneeShare {
+	if len(shares) == 0 {
+		return nil
+	}
+	amounts := db.SplitBountyPrice(bounty.Price, shares)
+	result := make([]db.AssigneeShare, 0, len(shares))
+	for i, share := range shares {
+		hunter := h.db.GetPersonByPubkey(share.Pubkey)
+		result = append(result, db.AssigneeShare{
+			Pubkey:        share.Pubkey,
+			Alias:         hunter.OwnerAlias,
+			Img:           hunter.Img,
+			Percent:       share.Percent,
+			Amount:        amounts[i],
+			PaymentStatus: share.PaymentStatus,
+		})
+	}
+	return result
+}
+
+// makeSplitBountyPayment pays every unpaid share of a split bounty to its
+// hunter and records all of the payments at once. Sending stops at the
+// first share the node does not answer for, since whether that one went
+// out is unknown; it stays unpaid and the shares sent before it are
+// recorded.
+func (h *bountyHandler) makeSplitBountyPayment(w http.ResponseWriter, r *http.Request, pubKeyFromAuth string, bounty db.NewBounty, request db.BountyPayRequest) {
+	if bounty.AssetId != 0 {
+		if _, err := assetBackend(h.lightning); err != nil {
+			w.WriteHeader(http.StatusNotImplemented)
+			json.NewEncoder(w).Encode("Asset payments are not supported by this node")
+			return
+		}
+	}
+
+	memoText := url.QueryEscape(fmt.Sprintf("Payment For: %ss", bounty.Title))
+	amounts := db.SplitBountyPrice(bounty.Price, bounty.Assignees)
+	now := time.Now()
+
+	payments := []db.BountySharePayment{}
+	unreachable := false
+	for i, share := range bounty.Assignees {
+		if !shareUnpaid(share) {
+			continue
+		}
+		hunter := h.db.GetPersonByPubkey(share.Pubkey)
+
+		keysendRes, err := sendBountyPayment(h.lightning, bounty.AssetId, amounts[i], share.Pubkey, hunter.OwnerRouteHint, memoText)
+		if err != nil && !errors.Is(err, lightning.ErrRejected) {
+			logger.FromContext(r.Context()).Error("[bounty] Keysend payment of share %d failed: %v", share.ID, err)
+			unreachable = true
+			break
+		}
+
+		payment := db.NewPaymentHistory{
+			Amount:         amounts[i],
+			AssetId:        bounty.AssetId,
+			SenderPubKey:   pubKeyFromAuth,
+			ReceiverPubKey: share.Pubkey,
+			WorkspaceUuid:  bounty.WorkspaceUuid,
+			BountyId:       bounty.ID,
+			Created:        &now,
+			Updated:        &now,
+			PaymentType:    db.Payment,
+			Tag:            keysendRes.Tag,
+			PaymentStatus:  db.PaymentFailed,
+		}
+		if err == nil && (keysendRes.Status == db.PaymentComplete || keysendRes.Status == db.PaymentPending) {
+			payment.Status = true
+			payment.PaymentStatus = keysendRes.Status
+		} else if err != nil {
+			payment.Error = "Payment Request Failed"
+		} else {
+			payment.Error = keysendRes.Message
+		}
+		payments = append(payments, db.BountySharePayment{ShareID: share.ID, Payment: payment})
+	}
+
+	bounty.Completed = true
+	bounty.CompletionDate = &now
+
+	bounty, recorded, err := h.db.ProcessBountySplitPayment(payments, bounty)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[bounty] could not record the split payment of bounty %d: %v", bounty.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode("Failed to record the bounty payments")
+		return
+	}
+
+	msg := map[string]interface{}{
+		"invoice":  "",
+		"payments": recorded,
+	}
+	status := http.StatusOK
+	switch {
+	case unreachable:
+		msg["msg"] = "keysend_error"
+		status = http.StatusBadRequest
+	case bounty.Paid:
+		msg["msg"] = "keysend_success"
+	case bounty.PaymentPending:
+		msg["msg"] = "keysend_pending"
+	default:
+		msg["msg"] = "keysend_failed"
+		status = http.StatusBadRequest
+	}
+
+	if bounty.Paid {
+		emitWebhook(bounty.WorkspaceUuid, webhooks.EventBountyPaid, bounty)
+		recordBountyPayment(pubKeyFromAuth, bounty)
+	}
+	for _, payment := range recorded {
+		if payment.PaymentStatus == db.PaymentPending {
+			enqueuePaymentCheck(bounty.ID)
+			break
+		}
+	}
+	if len(recorded) > 0 {
+		enqueueBudgetAlert(bounty.WorkspaceUuid)
+	}
+
+	socket, err := h.getSocketConnections(request.Websocket_token)
+	if err == nil {
+		socket.Conn.WriteJSON(msg)
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(msg)
+}
+
+// SetBountyAssignees godoc
+//
+//	@Summary		Split a bounty between hunters
+//	@Description	Replace the hunters of a bounty with shares of its price, all percents adding up to 100 or all fixed amounts adding up to the price. The first hunter becomes the bounty's assignee. Shares cannot change once one is paid.
+//	@Tags			Bounties
+//	@Accept			json
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			id		path	string						true	"Bounty ID"
+//	@Param			request	body	db.BountyAssigneesRequest	true	"Shares"
+//	@Success		200		{array}	db.AssigneeShare
+//	@Router			/gobounties/{id}/assignees [put]
+func (h *bountyHandler) SetBountyAssignees(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[bounty] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := utils.ConvertStringToUint(chi.URLParam(r, "id"))
+	bounty := db.NewBounty{}
+	if err == nil {
+		bounty = h.db.GetBounty(id)
+	}
+	if bounty.ID == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Bounty not found"})
+		return
+	}
+
+	if pubKeyFromAuth != bounty.OwnerID && (bounty.WorkspaceUuid == "" || !h.userHasManageBountyRoles(pubKeyFromAuth, bounty.WorkspaceUuid)) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions"})
+		return
+	}
+
+	request := db.BountyAssigneesRequest{}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err == nil {
+		err = json.Unmarshal(body, &request)
+	}
+	if err == nil {
+		err = db.ValidateBountyShares(bounty.Price, request.Assignees)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	shares, err := h.db.SetBountyAssignees(bounty.ID, request.Assignees)
+	switch {
+	case errors.Is(err, db.ErrBountySharesLocked):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, db.ErrInvalidBountyShares):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		logger.FromContext(r.Context()).Error("[bounty] could not set the assignees of bounty %d: %v", bounty.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to set the bounty assignees"})
+		return
+	}
+
+	audit.Record(pubKeyFromAuth, bounty.WorkspaceUuid, audit.EntityBounty, strconv.FormatUint(uint64(bounty.ID), 10), audit.ActionUpdate,
+		map[string]interface{}{"assignees": bounty.Assignees},
+		map[string]interface{}{"assignees": shares})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.assigneeShares(bounty, shares))
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetBountyAssignees(t *testing.T) {
+	bounty := db.NewBounty{ID: 1, Price: 1000, OwnerID: "owner", WorkspaceUuid: "workspace-1"}
+	params := map[string]string{"id": "1"}
+	shares := db.BountyAssigneesRequest{Assignees: []db.BountyAssignee{
+		{Pubkey: "alice", Percent: 60},
+		{Pubkey: "bob", Percent: 40},
+	}}
+	set := func(handler *bountyHandler, pubkey string, request db.BountyAssigneesRequest) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.SetBountyAssignees(rr, webhookRequest(http.MethodPut, "/", request, pubkey, params))
+		return rr
+	}
+
+	t.Run("Unauthorized without pubkey", func(t *testing.T) {
+		handler, _, _ := newFakeNodeBountyHandler(t)
+		assert.Equal(t, http.StatusUnauthorized, set(handler, "", shares).Code)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		mockDb.On("GetBounty", bounty.ID).Return(db.NewBounty{})
+		assert.Equal(t, http.StatusNotFound, set(handler, "owner", shares).Code)
+	})
+
+	t.Run("Only the owner or a bounty manager may split", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		handler.userHasManageBountyRoles = func(pubKeyFromAuth string, uuid string) bool { return false }
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		assert.Equal(t, http.StatusUnauthorized, set(handler, "someone", shares).Code)
+	})
+
+	t.Run("Rejects shares that do not add up", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+
+		invalid := []db.BountyAssigneesRequest{
+			{},
+			{Assignees: []db.BountyAssignee{{Pubkey: "alice", Percent: 60}, {Pubkey: "bob", Percent: 30}}},
+			{Assignees: []db.BountyAssignee{{Pubkey: "alice", Percent: 50}, {Pubkey: "bob", Amount: 500}}},
+			{Assignees: []db.BountyAssignee{{Pubkey: "alice", Amount: 500}, {Pubkey: "alice", Amount: 500}}},
+			{Assignees: []db.BountyAssignee{{Pubkey: "alice", Amount: 600}, {Pubkey: "bob", Amount: 600}}},
+		}
+		for _, request := range invalid {
+			assert.Equal(t, http.StatusBadRequest, set(handler, "owner", request).Code)
+		}
+		mockDb.AssertNotCalled(t, "SetBountyAssignees", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Conflict once a share is paid", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("SetBountyAssignees", bounty.ID, mock.Anything).Return(nil, db.ErrBountySharesLocked)
+		assert.Equal(t, http.StatusConflict, set(handler, "owner", shares).Code)
+	})
+
+	t.Run("Splits the bounty", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		handler.userHasManageBountyRoles = func(pubKeyFromAuth string, uuid string) bool { return pubKeyFromAuth == "manager" }
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("SetBountyAssignees", bounty.ID, shares.Assignees).Return(shares.Assignees, nil)
+		mockDb.On("GetPersonByPubkey", "alice").Return(db.Person{OwnerPubKey: "alice", OwnerAlias: "Alice"})
+		mockDb.On("GetPersonByPubkey", "bob").Return(db.Person{OwnerPubKey: "bob", OwnerAlias: "Bob"})
+
+		rr := set(handler, "manager", shares)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		result := []db.AssigneeShare{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+		assert.Equal(t, []db.AssigneeShare{
+			{Pubkey: "alice", Alias: "Alice", Percent: 60, Amount: 600},
+			{Pubkey: "bob", Alias: "Bob", Percent: 40, Amount: 400},
+		}, result)
+	})
+}
+
+func TestGenerateBountyResponseUsesLoadedShares(t *testing.T) {
+	handler, mockDb, _ := newFakeNodeBountyHandler(t)
+	bounty := db.NewBounty{ID: 1, Price: 1000, OwnerID: "owner", Assignee: "alice", WorkspaceUuid: "workspace-1",
+		Assignees:  []db.BountyAssignee{{BountyID: 1, Pubkey: "alice", Percent: 100}},
+		Milestones: []db.BountyMilestone{{BountyID: 1, Title: "Design", Amount: 1000}},
+	}
+	mockDb.On("GetPersonByPubkey", mock.Anything).Return(db.Person{})
+	mockDb.On("GetWorkspaceByUuid", "workspace-1").Return(db.Workspace{})
+	mockDb.On("GetProofsByBountyID", uint(1)).Return([]db.ProofOfWork{})
+
+	// the strict mock fails on a per-bounty share or milestone lookup
+	response := handler.GenerateBountyResponse([]db.NewBounty{bounty})
+
+	assert.Len(t, response, 1)
+	assert.Equal(t, bounty.Milestones, response[0].Bounty.Milestones)
+	assert.Len(t, response[0].Assignees, 1)
+	assert.Equal(t, uint(1000), response[0].Assignees[0].Amount)
+}
+
+func TestSplitBountyPayment(t *testing.T) {
+	bounty := db.NewBounty{ID: 1, Price: 1001, WorkspaceUuid: "workspace-1", Assignee: "alice", Title: "Pair",
+		Assignees: []db.BountyAssignee{
+			{ID: 11, BountyID: 1, Pubkey: "alice", Percent: 50},
+			{ID: 12, BountyID: 1, Pubkey: "bob", Percent: 50},
+		}}
+	params := map[string]string{"id": "1"}
+
+	expectBounty := func(mockDb *dbMocks.Database, bounty db.NewBounty, budget uint) {
+		mockDb.On("GetBounty", bounty.ID).Return(bounty).Once()
+		mockDb.On("GetWorkspaceBudget", bounty.WorkspaceUuid).Return(db.NewBountyBudget{TotalBudget: budget})
+		mockDb.On("CheckSpendLimit", bounty.WorkspaceUuid, "owner", db.PayBounty, mock.Anything).Return(nil).Maybe()
+		mockDb.On("GetPersonByPubkey", mock.Anything).Return(db.Person{}).Maybe()
+	}
+	pay := func(handler *bountyHandler) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.MakeBountyPayment(rr, webhookRequest(http.MethodPost, "/", db.BountyPayRequest{}, "owner", params))
+		return rr
+	}
+
+	t.Run("Pays every share and records them together", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		expectBounty(mockDb, bounty, 5000)
+		paid := bounty
+		paid.Paid = true
+		mockDb.On("ProcessBountySplitPayment", mock.MatchedBy(func(payments []db.BountySharePayment) bool {
+			return len(payments) == 2 &&
+				payments[0].ShareID == 11 && payments[0].Payment.Amount == 501 && payments[0].Payment.ReceiverPubKey == "alice" &&
+				payments[1].ShareID == 12 && payments[1].Payment.Amount == 500 && payments[1].Payment.ReceiverPubKey == "bob" &&
+				payments[0].Payment.PaymentStatus == db.PaymentComplete && payments[1].Payment.Status
+		}), mock.MatchedBy(func(b db.NewBounty) bool { return b.ID == 1 && b.Completed })).Return(paid, []db.NewPaymentHistory{{}, {}}, nil).Once()
+
+		rr := pay(handler)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		msg := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &msg))
+		assert.Equal(t, "keysend_success", msg["msg"])
+		assert.Len(t, node.Payments(), 2)
+		mockDb.AssertNotCalled(t, "ProcessBountyPayment", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Forbidden when the budget does not cover the unpaid shares", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		expectBounty(mockDb, bounty, 1000)
+
+		assert.Equal(t, http.StatusForbidden, pay(handler).Code)
+		assert.Empty(t, node.Payments())
+	})
+
+	t.Run("Retries only the shares that failed", func(t *testing.T) {
+		handler, mockDb, fake := newFakeNodeBountyHandler(t)
+		retried := bounty
+		retried.PaymentFailed = true
+		retried.Assignees = []db.BountyAssignee{
+			{ID: 11, BountyID: 1, Pubkey: "alice", Percent: 50, PaymentStatus: db.PaymentComplete},
+			{ID: 12, BountyID: 1, Pubkey: "bob", Percent: 50, PaymentStatus: db.PaymentFailed},
+		}
+		expectBounty(mockDb, retried, 500)
+		mockDb.On("ProcessBountySplitPayment", mock.MatchedBy(func(payments []db.BountySharePayment) bool {
+			return len(payments) == 1 && payments[0].ShareID == 12 && payments[0].Payment.Amount == 500
+		}), mock.Anything).Return(db.NewBounty{ID: 1, Paid: true}, []db.NewPaymentHistory{{}}, nil).Once()
+
+		assert.Equal(t, http.StatusOK, pay(handler).Code)
+		payments := fake.Payments()
+		assert.Len(t, payments, 1)
+		assert.Equal(t, "bob", payments[0].Pubkey)
+	})
+
+	t.Run("A rejected share fails the payment", func(t *testing.T) {
+		handler, mockDb, fake := newFakeNodeBountyHandler(t)
+		handler.lightning = &selectiveNode{FakeNode: fake, rejectPubkey: "bob"}
+		expectBounty(mockDb, bounty, 5000)
+		mockDb.On("ProcessBountySplitPayment", mock.MatchedBy(func(payments []db.BountySharePayment) bool {
+			return len(payments) == 2 &&
+				payments[0].Payment.PaymentStatus == db.PaymentComplete &&
+				payments[1].Payment.PaymentStatus == db.PaymentFailed && !payments[1].Payment.Status
+		}), mock.Anything).Return(db.NewBounty{ID: 1, PaymentFailed: true}, []db.NewPaymentHistory{{}, {}}, nil).Once()
+
+		rr := pay(handler)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		msg := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &msg))
+		assert.Equal(t, "keysend_failed", msg["msg"])
+		assert.Len(t, fake.Payments(), 1)
+	})
+}
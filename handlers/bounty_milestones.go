@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/audit"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/utils"
+	"github.com/stakwork/sphinx-tribes/webhooks"
+)
+
+// milestoneOpen reports whether a proof of work may still be submitted for
+// a milestone, that is the milestone belongs to the bounty and is unpaid.
+func (h *bountyHandler) milestoneOpen(bountyID uint, milestoneID uint) bool {
+	for _, milestone := range h.db.GetBountyMilestones(bountyID) {
+		if milestone.ID == milestoneID {
+			return milestone.PaymentStatus == "" || milestone.PaymentStatus == db.PaymentFailed
+		}
+	}
+	return false
+}
+
+// GetBountyMilestones godoc
+//
+//	@Summary		Get bounty milestones
+//	@Description	The milestones a bounty is paid by, in order, with what each has been paid
+//	@Tags			Bounties
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			id	path	string	true	"Bounty ID"
+//	@Success		200	{array}	db.BountyMilestone
+//	@Router			/gobounties/{id}/milestones [get]
+func (h *bountyHandler) GetBountyMilestones(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ConvertStringToUint(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid bounty ID"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.db.GetBountyMilestones(id))
+}
+
+// SetBountyMilestones godoc
+//
+//	@Summary		Pay a bounty by milestones
+//	@Description	Replace the milestones of a bounty. Their amounts add up to the price and are reserved from the workspace budget within its spend limits, each is paid to the assignee once a proof of work submitted for it is accepted. No milestones pays the bounty at once again. Milestones cannot change once one is paid.
+//	@Tags			Bounties
+//	@Accept			json
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			id		path	string						true	"Bounty ID"
+//	@Param			request	body	db.BountyMilestonesRequest	true	"Milestones"
+//	@Success		200		{array}	db.BountyMilestone
+//	@Router			/gobounties/{id}/milestones [put]
+func (h *bountyHandler) SetBountyMilestones(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[bounty] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := utils.ConvertStringToUint(chi.URLParam(r, "id"))
+	bounty := db.NewBounty{}
+	if err == nil {
+		bounty = h.db.GetBounty(id)
+	}
+	if bounty.ID == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Bounty not found"})
+		return
+	}
+
+	// milestones reserve the workspace budget, so setting them takes the
+	// role that spends it
+	if bounty.WorkspaceUuid == "" || !h.userHasAccess(pubKeyFromAuth, bounty.WorkspaceUuid, db.PayBounty) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions"})
+		return
+	}
+
+	request := db.BountyMilestonesRequest{}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err == nil {
+		err = json.Unmarshal(body, &request)
+	}
+	if err == nil {
+		err = db.ValidateBountyMilestones(bounty.Price, request.Milestones)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// the reservation is held to the spend limits like a payment of the
+	// price, which the milestones add up to
+	h.m.Lock()
+	if len(request.Milestones) > 0 {
+		err = h.db.CheckSpendLimit(bounty.WorkspaceUuid, pubKeyFromAuth, db.PayBounty, bounty.Price)
+	}
+	var milestones []db.BountyMilestone
+	if err == nil {
+		milestones, err = h.db.SetBountyMilestones(bounty.ID, request.Milestones)
+	}
+	h.m.Unlock()
+	switch {
+	case errors.Is(err, db.ErrInvalidBountyMilestones):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, db.ErrBountyMilestonesLocked):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, db.ErrInsufficientBudget), errors.Is(err, db.ErrSpendLimitExceeded):
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		logger.FromContext(r.Context()).Error("[bounty] could not set the milestones of bounty %d: %v", bounty.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to set the bounty milestones"})
+		return
+	}
+
+	audit.Record(pubKeyFromAuth, bounty.WorkspaceUuid, audit.EntityBounty, strconv.FormatUint(uint64(bounty.ID), 10), audit.ActionUpdate,
+		map[string]interface{}{"milestones": bounty.Milestones},
+		map[string]interface{}{"milestones": milestones})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(milestones)
+}
+
+// acceptMilestoneProof accepts a proof of work submitted for a milestone
+// and pays the milestone to the bounty's assignee from its reservation,
+// within the workspace spend limits.
+// Accepting the proof of a milestone whose payment failed pays it again.
+func (h *bountyHandler) acceptMilestoneProof(w http.ResponseWriter, r *http.Request, bountyID uint, proof db.ProofOfWork) {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[bounty] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	bounty := h.db.GetBounty(bountyID)
+	if bounty.ID == 0 || proof.BountyID != bounty.ID {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Bounty not found"})
+		return
+	}
+
+	var milestone db.BountyMilestone
+	for _, m := range bounty.Milestones {
+		if m.ID == *proof.MilestoneID {
+			milestone = m
+		}
+	}
+	if milestone.ID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Milestone not found"})
+		return
+	}
+
+	if !h.userHasAccess(pubKeyFromAuth, bounty.WorkspaceUuid, db.PayBounty) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions to pay bounties"})
+		return
+	}
+
+	// a milestone is paid once, further proofs for it are only accepted
+	if milestone.PaymentStatus == db.PaymentPending || milestone.PaymentStatus == db.PaymentComplete {
+		if err := h.db.UpdateProofStatus(proof.ID.String(), db.AcceptedStatus); err != nil {
+			http.Error(w, "Failed to update status", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"milestone": milestone})
+		return
+	}
+
+	if bounty.Assignee == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Bounty has no assignee"})
+		return
+	}
+
+	if err := h.db.CheckSpendLimit(bounty.WorkspaceUuid, pubKeyFromAuth, db.PayBounty, milestone.Amount); err != nil {
+		if !errors.Is(err, db.ErrSpendLimitExceeded) {
+			logger.FromContext(r.Context()).Error("[bounty] could not check the spend limits of %s: %v", bounty.WorkspaceUuid, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to check the spend limits"})
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	hunter := h.db.GetPersonByPubkey(bounty.Assignee)
+	memoText := url.QueryEscape(fmt.Sprintf("Payment For: %s, %s", bounty.Title, milestone.Title))
+
+	keysendRes, err := sendBountyPayment(h.lightning, 0, milestone.Amount, bounty.Assignee, hunter.OwnerRouteHint, memoText)
+	if err != nil && !errors.Is(err, lightning.ErrRejected) {
+		// the node didn't answer, so whether the payment went out is unknown
+		logger.FromContext(r.Context()).Error("[bounty] Keysend payment of milestone %d failed: %v", milestone.ID, err)
+		w.WriteHeader(http.StatusNotAcceptable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Could not reach the lightning node"})
+		return
+	}
+
+	now := time.Now()
+	payment := db.NewPaymentHistory{
+		Amount:         milestone.Amount,
+		SenderPubKey:   pubKeyFromAuth,
+		ReceiverPubKey: bounty.Assignee,
+		WorkspaceUuid:  bounty.WorkspaceUuid,
+		BountyId:       bounty.ID,
+		Created:        &now,
+		Updated:        &now,
+		PaymentType:    db.Payment,
+		Tag:            keysendRes.Tag,
+		PaymentStatus:  db.PaymentFailed,
+	}
+	if err == nil && (keysendRes.Status == db.PaymentComplete || keysendRes.Status == db.PaymentPending) {
+		payment.Status = true
+		payment.PaymentStatus = keysendRes.Status
+	} else if err != nil {
+		payment.Error = "Payment Request Failed"
+	} else {
+		payment.Error = keysendRes.Message
+	}
+
+	bounty, payment, err = h.db.ProcessMilestonePayment(milestone.ID, payment, bounty)
+	if errors.Is(err, db.ErrMilestonePaid) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[bounty] could not record the payment of milestone %d: %v", milestone.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to record the milestone payment"})
+		return
+	}
+
+	if err := h.db.UpdateProofStatus(proof.ID.String(), db.AcceptedStatus); err != nil {
+		http.Error(w, "Failed to update status", http.StatusInternalServerError)
+		return
+	}
+
+	// work goes on until the last milestone is paid out
+	if bounty.Completed {
+		if err := h.db.CloseBountyTiming(bounty.ID); err != nil {
+			logger.FromContext(r.Context()).Error(fmt.Sprintf("Failed to close timing for bounty ID %d: %v", bounty.ID, err))
+		}
+	} else if err := h.db.ResumeBountyTiming(bounty.ID); err != nil {
+		logger.FromContext(r.Context()).Error(fmt.Sprintf("Failed to resume timing for bounty ID %d: %v", bounty.ID, err))
+	}
+
+	if bounty.Paid {
+		emitWebhook(bounty.WorkspaceUuid, webhooks.EventBountyPaid, bounty)
+		recordBountyPayment(pubKeyFromAuth, bounty)
+	}
+	if payment.PaymentStatus == db.PaymentPending {
+		enqueuePaymentCheck(bounty.ID)
+	}
+
+	msg := map[string]interface{}{"payment": payment}
+	for _, m := range bounty.Milestones {
+		if m.ID == milestone.ID {
+			msg["milestone"] = m
+		}
+	}
+	status := http.StatusOK
+	switch payment.PaymentStatus {
+	case db.PaymentComplete:
+		msg["msg"] = "keysend_success"
+	case db.PaymentPending:
+		msg["msg"] = "keysend_pending"
+	default:
+		msg["msg"] = "keysend_failed"
+		status = http.StatusBadRequest
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(msg)
+}
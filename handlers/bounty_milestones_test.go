@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetBountyMilestones(t *testing.T) {
+	bounty := db.NewBounty{ID: 1, Price: 1000, OwnerID: "owner", WorkspaceUuid: "workspace-1"}
+	params := map[string]string{"id": "1"}
+	milestones := db.BountyMilestonesRequest{Milestones: []db.BountyMilestone{
+		{Title: "Design", Amount: 300, AcceptanceCriteria: "Approved mockups"},
+		{Title: "Build", Amount: 700},
+	}}
+	set := func(handler *bountyHandler, pubkey string, request db.BountyMilestonesRequest) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.SetBountyMilestones(rr, webhookRequest(http.MethodPut, "/", request, pubkey, params))
+		return rr
+	}
+
+	t.Run("Unauthorized without pubkey", func(t *testing.T) {
+		handler, _, _ := newFakeNodeBountyHandler(t)
+		assert.Equal(t, http.StatusUnauthorized, set(handler, "", milestones).Code)
+	})
+
+	t.Run("Unauthorized without the pay bounty role", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		handler.userHasAccess = func(pubKeyFromAuth string, uuid string, role string) bool {
+			assert.Equal(t, db.PayBounty, role)
+			return false
+		}
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		assert.Equal(t, http.StatusUnauthorized, set(handler, "owner", milestones).Code)
+	})
+
+	t.Run("Rejects milestones that do not add up to the price", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+
+		invalid := []db.BountyMilestonesRequest{
+			{Milestones: []db.BountyMilestone{{Title: "Design", Amount: 300}}},
+			{Milestones: []db.BountyMilestone{{Title: "Design", Amount: 1000}, {Title: "Build"}}},
+			{Milestones: []db.BountyMilestone{{Title: " ", Amount: 1000}}},
+		}
+		for _, request := range invalid {
+			assert.Equal(t, http.StatusBadRequest, set(handler, "owner", request).Code)
+		}
+		mockDb.AssertNotCalled(t, "SetBountyMilestones", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Forbidden when the budget cannot reserve them", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("CheckSpendLimit", bounty.WorkspaceUuid, "owner", db.PayBounty, uint(1000)).Return(nil)
+		mockDb.On("SetBountyMilestones", bounty.ID, mock.Anything).Return(nil, db.ErrInsufficientBudget)
+		assert.Equal(t, http.StatusForbidden, set(handler, "owner", milestones).Code)
+	})
+
+	t.Run("Forbidden when the reservation goes past a spend limit", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("CheckSpendLimit", bounty.WorkspaceUuid, "owner", db.PayBounty, uint(1000)).Return(db.ErrSpendLimitExceeded)
+		assert.Equal(t, http.StatusForbidden, set(handler, "owner", milestones).Code)
+		mockDb.AssertNotCalled(t, "SetBountyMilestones", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Conflict once a milestone is paid", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("CheckSpendLimit", bounty.WorkspaceUuid, "owner", db.PayBounty, uint(1000)).Return(nil)
+		mockDb.On("SetBountyMilestones", bounty.ID, mock.Anything).Return(nil, db.ErrBountyMilestonesLocked)
+		assert.Equal(t, http.StatusConflict, set(handler, "owner", milestones).Code)
+	})
+
+	t.Run("Sets the milestones", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("CheckSpendLimit", bounty.WorkspaceUuid, "owner", db.PayBounty, uint(1000)).Return(nil)
+		mockDb.On("SetBountyMilestones", bounty.ID, milestones.Milestones).Return(milestones.Milestones, nil)
+
+		rr := set(handler, "owner", milestones)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		result := []db.BountyMilestone{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+		assert.Len(t, result, 2)
+	})
+}
+
+func TestAcceptMilestoneProof(t *testing.T) {
+	milestoneID := uint(21)
+	proof := db.ProofOfWork{ID: uuid.New(), BountyID: 1, MilestoneID: &milestoneID, Description: "Mockups"}
+	bounty := db.NewBounty{ID: 1, Price: 1000, WorkspaceUuid: "workspace-1", Assignee: "hunter", Title: "Milestones",
+		Milestones: []db.BountyMilestone{
+			{ID: 21, BountyID: 1, Title: "Design", Amount: 300, Reserved: true},
+			{ID: 22, BountyID: 1, Title: "Build", Amount: 700, Reserved: true},
+		}}
+	params := map[string]string{"id": "1", "proofId": proof.ID.String()}
+
+	accept := func(handler *bountyHandler, pubkey string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.UpdateProofStatus(rr, webhookRequest(http.MethodPatch, "/", UpdateProofStatusResponse{Status: db.AcceptedStatus}, pubkey, params))
+		return rr
+	}
+
+	t.Run("Unauthorized without pubkey", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		mockDb.On("GetProofByID", proof.ID.String()).Return(proof, nil)
+
+		assert.Equal(t, http.StatusUnauthorized, accept(handler, "").Code)
+		assert.Empty(t, node.Payments())
+	})
+
+	t.Run("Pays the milestone from its reservation", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		mockDb.On("GetProofByID", proof.ID.String()).Return(proof, nil)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("CheckSpendLimit", bounty.WorkspaceUuid, "owner", db.PayBounty, uint(300)).Return(nil)
+		mockDb.On("GetPersonByPubkey", "hunter").Return(db.Person{OwnerPubKey: "hunter"})
+		mockDb.On("ProcessMilestonePayment", milestoneID, mock.MatchedBy(func(payment db.NewPaymentHistory) bool {
+			return payment.Amount == 300 && payment.ReceiverPubKey == "hunter" && payment.PaymentStatus == db.PaymentComplete && payment.Status
+		}), mock.Anything).Return(db.NewBounty{ID: 1, Milestones: []db.BountyMilestone{
+			{ID: 21, PaymentStatus: db.PaymentComplete}, {ID: 22},
+		}}, db.NewPaymentHistory{ID: 5, Amount: 300, PaymentStatus: db.PaymentComplete}, nil)
+		mockDb.On("UpdateProofStatus", proof.ID.String(), db.AcceptedStatus).Return(nil)
+		mockDb.On("ResumeBountyTiming", bounty.ID).Return(nil)
+
+		rr := accept(handler, "owner")
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		msg := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &msg))
+		assert.Equal(t, "keysend_success", msg["msg"])
+		payments := node.Payments()
+		assert.Len(t, payments, 1)
+		assert.Equal(t, uint(300), payments[0].Amount)
+		mockDb.AssertNotCalled(t, "CloseBountyTiming", mock.Anything)
+	})
+
+	t.Run("The last milestone completes the bounty", func(t *testing.T) {
+		handler, mockDb, _ := newFakeNodeBountyHandler(t)
+		mockDb.On("GetProofByID", proof.ID.String()).Return(proof, nil)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("CheckSpendLimit", bounty.WorkspaceUuid, "owner", db.PayBounty, uint(300)).Return(nil)
+		mockDb.On("GetPersonByPubkey", "hunter").Return(db.Person{OwnerPubKey: "hunter"})
+		mockDb.On("ProcessMilestonePayment", milestoneID, mock.Anything, mock.Anything).
+			Return(db.NewBounty{ID: 1, Paid: true, Completed: true}, db.NewPaymentHistory{ID: 6, PaymentStatus: db.PaymentComplete}, nil)
+		mockDb.On("UpdateProofStatus", proof.ID.String(), db.AcceptedStatus).Return(nil)
+		mockDb.On("CloseBountyTiming", bounty.ID).Return(nil)
+
+		assert.Equal(t, http.StatusOK, accept(handler, "owner").Code)
+		mockDb.AssertNotCalled(t, "ResumeBountyTiming", mock.Anything)
+	})
+
+	t.Run("Forbidden when the milestone goes past a spend limit", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		mockDb.On("GetProofByID", proof.ID.String()).Return(proof, nil)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("CheckSpendLimit", bounty.WorkspaceUuid, "owner", db.PayBounty, uint(300)).Return(db.ErrSpendLimitExceeded)
+
+		assert.Equal(t, http.StatusForbidden, accept(handler, "owner").Code)
+		assert.Empty(t, node.Payments())
+		mockDb.AssertNotCalled(t, "ProcessMilestonePayment", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("A paid milestone is not paid again", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		paid := bounty
+		paid.Milestones = []db.BountyMilestone{{ID: 21, BountyID: 1, Title: "Design", Amount: 300, PaymentStatus: db.PaymentComplete}}
+		mockDb.On("GetProofByID", proof.ID.String()).Return(proof, nil)
+		mockDb.On("GetBounty", bounty.ID).Return(paid)
+		mockDb.On("UpdateProofStatus", proof.ID.String(), db.AcceptedStatus).Return(nil)
+
+		assert.Equal(t, http.StatusOK, accept(handler, "owner").Code)
+		assert.Empty(t, node.Payments())
+		mockDb.AssertNotCalled(t, "ProcessMilestonePayment", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("A bounty paid by milestones is not paid at once", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBountyPayment(rr, webhookRequest(http.MethodPost, "/", db.BountyPayRequest{}, "owner", map[string]string{"id": "1"}))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Empty(t, node.Payments())
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"log"
 	"math"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -26,14 +27,18 @@ import (
 	"github.com/stakwork/sphinx-tribes/websocket"
 
 	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/blobstore"
 	"github.com/stakwork/sphinx-tribes/db"
 	"github.com/stakwork/sphinx-tribes/sse"
+	"github.com/stakwork/sphinx-tribes/workflows"
 )
 
 // ChatHandler handles chat-related requests
 type ChatHandler struct {
 	httpClient *http.Client
 	db         db.Database
+	workflow   workflowResolver
+	blobs      blobstore.BlobStore
 }
 
 // ChatResponse is the response format for chat requests
@@ -70,6 +75,7 @@ type ChatWebhookResponse struct {
 type FileResponse struct {
 	Success    bool         `json:"success"`
 	URL        string       `json:"url"`
+	URLExpires *time.Time   `json:"urlExpires,omitempty"`
 	IsExisting bool         `json:"isExisting"`
 	Asset      db.FileAsset `json:"asset"`
 	UploadTime time.Time    `json:"uploadTime"`
@@ -87,6 +93,9 @@ type SendMessageRequest struct {
 	SourceWebsocketID string `json:"sourceWebsocketId"`
 	WorkspaceUUID     string `json:"workspaceUUID"`
 	Mode              string `json:"mode,omitempty"`
+	// ContextTokens is the token budget of the history and context sent
+	// with the message, chatcontext.DefaultBudget when not set.
+	ContextTokens int `json:"contextTokens,omitempty"`
 }
 
 type BuildMessageRequest struct {
@@ -206,9 +215,21 @@ func NewChatHandler(httpClient *http.Client, database db.Database) *ChatHandler
 	return &ChatHandler{
 		httpClient: httpClient,
 		db:         database,
+		workflow:   newWorkflowResolver(database, httpClient),
+		blobs:      newBlobStores(httpClient),
 	}
 }
 
+// chatStatusURL is where the workflow provider posts the status of a chat
+// run, builds say so since they may run on another provider.
+func chatStatusURL(chatID string, kind workflows.Kind) string {
+	statusURL := fmt.Sprintf("%s/hivechat/%s/update", publicHost(), chatID)
+	if kind == workflows.KindBuild {
+		statusURL += "?kind=" + string(kind)
+	}
+	return statusURL
+}
+
 // CreateChat creates a new chat
 //
 //	@Summary		Create a new chat
@@ -383,6 +404,8 @@ func (ch *ChatHandler) ArchiveChat(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	// an archived chat no longer holds on to its files
+	enqueueFileRetention(updatedChat.WorkspaceID)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(ChatResponse{
@@ -450,12 +473,21 @@ func buildVarsPayload(request SendMessageRequest, createdMessage *db.ChatMessage
 		vars["username"] = codeSpace.Username
 	}
 
+	// the secrets are only decrypted here, as the workflow is dispatched
 	if codeSpace.GithubPat != "" {
-		vars["token"] = codeSpace.GithubPat
+		if token, err := codeSpace.GithubPat.Reveal(); err == nil {
+			vars["token"] = token
+		} else {
+			logger.Log.Error("[chat] could not decrypt the github token of codespace %s: %v", codeSpace.ID, err)
+		}
 	}
 
 	if codeSpace.PoolAPIKey != "" {
-		vars["pool_api_key"] = codeSpace.PoolAPIKey
+		if poolAPIKey, err := codeSpace.PoolAPIKey.Reveal(); err == nil {
+			vars["pool_api_key"] = poolAPIKey
+		} else {
+			logger.Log.Error("[chat] could not decrypt the pool api key of codespace %s: %v", codeSpace.ID, err)
+		}
 	}
 
 	vars["query"] = request.Message
@@ -481,7 +513,7 @@ func (ch *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -489,7 +521,7 @@ func (ch *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	user := ch.db.GetPersonByPubkey(pubKeyFromAuth)
 
 	if user.OwnerPubKey != pubKeyFromAuth {
-		logger.Log.Info("Person not exists")
+		logger.FromContext(r.Context()).Info("Person not exists")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -513,7 +545,7 @@ func (ch *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	context, err := ch.db.GetProductBrief(request.WorkspaceUUID)
+	productBrief, err := ch.db.GetProductBrief(request.WorkspaceUUID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatResponse{
@@ -523,7 +555,7 @@ func (ch *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	history, err := ch.db.GetChatMessagesForChatID(request.ChatID)
+	chatContext, err := ch.buildChatContext(request, productBrief)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatResponse{
@@ -532,30 +564,7 @@ func (ch *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-
-	start := 0
-	if len(history) > 20 {
-		start = len(history) - 20
-	}
-	recentHistory := history[start:]
-
-	messageHistory := make([]map[string]string, len(recentHistory))
-	for i, msg := range recentHistory {
-		artefacts, err := ch.db.GetArtifactsByMessageID(msg.ID)
-		if err != nil {
-			artefacts = []db.Artifact{}
-		}
-
-		artifactJSON, err := json.Marshal(map[string]interface{}{"artifacts": buildArtifactList(artefacts)})
-		if err != nil {
-			artifactJSON = []byte(`{"artifacts": []}`)
-		}
-
-		messageHistory[i] = map[string]string{
-			"role":    string(msg.Role),
-			"content": msg.Message + "\nArtifacts: " + string(artifactJSON),
-		}
-	}
+	messageHistory, contextTags := chatHistoryVars(chatContext)
 
 	message := &db.ChatMessage{
 		ID:        xid.New().String(),
@@ -599,34 +608,25 @@ func (ch *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	vars := buildVarsPayload(request, &createdMessage, messageHistory, context, &user, codeGraph, codeSpace, mode)
+	vars := buildVarsPayload(request, &createdMessage, messageHistory, contextTags, &user, codeGraph, codeSpace, mode)
 
-	stakworkPayload := StakworkChatPayload{
-		Name:       "Hive Chat Processor",
-		WorkflowID: 38842,
-		WorkflowParams: map[string]interface{}{
-			"set_var": map[string]interface{}{
-				"attributes": map[string]interface{}{
-					"vars": vars,
-				},
-			},
-		},
-		WebhookURL: fmt.Sprintf("https://community.sphinx.chat/hivechat/%s/update", request.ChatID),
+	job := workflows.Job{
+		Name:      "Hive Chat Processor",
+		Vars:      vars,
+		StatusURL: chatStatusURL(request.ChatID, workflows.KindChat),
 	}
-
+	kind := workflows.KindChat
 	if mode == "Build" {
-		stakworkPayload.Name = request.ChatID
-		stakworkPayload.WorkflowID = 43859
+		kind = workflows.KindBuild
+		job.Name = request.ChatID
+		job.StatusURL = chatStatusURL(request.ChatID, workflows.KindBuild)
 	}
 
-	apiKeyEnv := "SWWFKEY"
-	if mode == "Build" {
-		//apiKeyEnv = "SWWFSWKEY"
-		apiKeyEnv = "SWPR"
-	}
+	provider, workflowID := ch.workflow(request.WorkspaceUUID, kind)
+	job.WorkflowID = workflowID
 
-	apiKey := os.Getenv(apiKeyEnv)
-	if apiKey == "" {
+	run, err := provider.Submit(ctx, job)
+	if errors.Is(err, workflows.ErrNoCredentials) {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatResponse{
 			Success: false,
@@ -634,8 +634,6 @@ func (ch *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-
-	projectID, err := ch.sendToStakwork(stakworkPayload, apiKey)
 	if err != nil {
 		createdMessage.Status = "error"
 		ch.db.UpdateChatMessage(&createdMessage)
@@ -648,15 +646,17 @@ func (ch *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	projectMsg := websocket.TicketMessage{
-		BroadcastType:   "direct",
-		SourceSessionID: request.SourceWebsocketID,
-		Message:         fmt.Sprintf("https://jobs.stakwork.com/admin/projects/%d", projectID),
-		Action:          "swrun",
-	}
+	if run.URL != "" {
+		projectMsg := websocket.TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: request.SourceWebsocketID,
+			Message:         run.URL,
+			Action:          "swrun",
+		}
 
-	if err := websocket.WebsocketPool.SendTicketMessage(projectMsg); err != nil {
-		log.Printf("Failed to send Stakwork project WebSocket message: %v", err)
+		if err := websocket.WebsocketPool.SendTicketMessage(projectMsg); err != nil {
+			log.Printf("Failed to send Stakwork project WebSocket message: %v", err)
+		}
 	}
 
 	wsMessage := websocket.TicketMessage{
@@ -667,6 +667,8 @@ func (ch *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		ChatMessage:     createdMessage,
 	}
 
+	publishChatEvent(wsMessage)
+
 	if err := websocket.WebsocketPool.SendTicketMessage(wsMessage); err != nil {
 		log.Printf("Failed to send websocket message: %v", err)
 	}
@@ -679,44 +681,6 @@ func (ch *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (ch *ChatHandler) sendToStakwork(payload StakworkChatPayload, apiKey string) (int64, error) {
-
-	payloadJSON, err := json.Marshal(payload)
-	if err != nil {
-		return 0, fmt.Errorf("error marshaling payload: %v", err)
-	}
-
-	req, err := http.NewRequest(
-		http.MethodPost,
-		"https://api.stakwork.com/api/v1/projects",
-		bytes.NewBuffer(payloadJSON),
-	)
-	if err != nil {
-		return 0, fmt.Errorf("error creating request: %v", err)
-	}
-
-	req.Header.Set("Authorization", "Token token="+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := ch.httpClient.Do(req)
-	if err != nil {
-		return 0, fmt.Errorf("error sending request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("stakwork API error: %s", string(body))
-	}
-
-	var stakworkResp StakworkResponse
-	if err := json.NewDecoder(resp.Body).Decode(&stakworkResp); err != nil {
-		return 0, fmt.Errorf("error decoding response: %v", err)
-	}
-
-	return stakworkResp.Data.ProjectID, nil
-}
-
 // GetChat retrieves chats for a workspace
 //
 //	@Summary		Retrieve chats for a workspace
@@ -934,6 +898,8 @@ func (ch *ChatHandler) ProcessChatResponse(w http.ResponseWriter, r *http.Reques
 		Artifacts:       artifacts,
 	}
 
+	publishChatEvent(wsMessage)
+
 	if err := websocket.WebsocketPool.SendTicketMessage(wsMessage); err != nil {
 		log.Printf("Failed to send websocket message: %v", err)
 	}
@@ -961,7 +927,8 @@ func (ch *ChatHandler) ProcessChatResponse(w http.ResponseWriter, r *http.Reques
 //	@Failure		500		{object}	ChatResponse
 //	@Router			/hivechat/upload [post]
 func (ch *ChatHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
-	file, header, err := r.FormFile("file")
+	// the file is streamed to the store as it is read, never held whole
+	part, err := uploadedFilePart(r)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(ChatResponse{
@@ -970,10 +937,19 @@ func (ch *ChatHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	defer file.Close()
+	defer part.Close()
 
-	mimeType := header.Header.Get("Content-Type")
-	if !isAllowedFileType(mimeType) {
+	sniffed, body, err := blobstore.Sniff(part)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ChatResponse{
+			Success: false,
+			Message: "Failed to process file",
+		})
+		return
+	}
+	mimeType, ok := uploadFileType(sniffed, part.Header.Get("Content-Type"))
+	if !ok {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(ChatResponse{
 			Success: false,
@@ -983,17 +959,25 @@ func (ch *ChatHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h := sha256.New()
-	if _, err := io.Copy(h, file); err != nil {
+	size := &byteCounter{}
+	uploadFilename := uuid.New().String() + filepath.Ext(part.FileName())
+	location, err := ch.blobs.Put(r.Context(), uploadFilename, io.TeeReader(body, io.MultiWriter(h, size)), mimeType)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[chat] failed to store file %s: %v", part.FileName(), err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatResponse{
 			Success: false,
-			Message: "Failed to process file",
+			Message: "Failed to upload file",
 		})
 		return
 	}
 	fileHash := hex.EncodeToString(h.Sum(nil))
 
+	// a file is only known once it is read, the copy of a known one goes
 	if existing, err := ch.db.GetFileAssetByHash(fileHash); err == nil {
+		if err := ch.blobs.Delete(r.Context(), location); err != nil && !errors.Is(err, blobstore.ErrNotSupported) {
+			logger.FromContext(r.Context()).Error("[chat] failed to delete the copy of file %d: %v", existing.ID, err)
+		}
 		if err := ch.db.UpdateFileAssetReference(existing.ID); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(ChatResponse{
@@ -1003,74 +987,94 @@ func (ch *ChatHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(FileResponse{
-			Success:    true,
-			URL:        existing.StoragePath,
-			IsExisting: true,
-			Asset:      *existing,
-			UploadTime: existing.UploadTime,
-		})
+		ch.writeFileResponse(w, r, *existing, true)
 		return
 	}
 
-	file.Seek(0, 0)
-
-	uploadFilename := uuid.New().String() + filepath.Ext(header.Filename)
-
-	challenge := GetMemeChallenge()
-	signer := SignChallenge(challenge.Challenge)
-	mErr, mToken := GetMemeToken(challenge.Id, signer.Response.Sig)
-
-	if mErr != "" {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ChatResponse{
-			Success: false,
-			Message: "Failed to get meme token",
-		})
-		return
+	asset := &db.FileAsset{
+		OriginFilename: part.FileName(),
+		FileHash:       fileHash,
+		UploadFilename: uploadFilename,
+		FileSize:       size.n,
+		MimeType:       mimeType,
+		Status:         db.ActiveFileStatus,
+		UploadedBy:     r.Context().Value("pubkey").(string),
+		StoragePath:    location,
+		WorkspaceID:    r.URL.Query().Get("workspaceId"),
 	}
 
-	err, uploadURL := UploadMemeImage(file, mToken.Token, header.Filename)
+	asset, err = ch.db.CreateFileAsset(asset)
 	if err != nil {
+		// a file without a record is never found again, so it goes too
+		logger.FromContext(r.Context()).Error("[chat] failed to record file %s: %v", part.FileName(), err)
+		if err := ch.blobs.Delete(r.Context(), location); err != nil && !errors.Is(err, blobstore.ErrNotSupported) {
+			logger.FromContext(r.Context()).Error("[chat] failed to delete unrecorded file %s: %v", location, err)
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatResponse{
 			Success: false,
-			Message: "Failed to upload file",
+			Message: "Failed to create asset record",
 		})
 		return
 	}
 
-	asset := &db.FileAsset{
-		OriginFilename: header.Filename,
-		FileHash:       fileHash,
-		UploadFilename: uploadFilename,
-		FileSize:       header.Size,
-		MimeType:       mimeType,
-		Status:         db.ActiveFileStatus,
-		UploadedBy:     r.Context().Value("pubkey").(string),
-		StoragePath:    uploadURL,
-		WorkspaceID:    r.URL.Query().Get("workspaceId"),
+	ch.writeFileResponse(w, r, *asset, false)
+}
+
+// uploadedFilePart finds the file part of a multipart upload without
+// parsing the form into memory.
+func uploadedFilePart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
 	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" && part.FileName() != "" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
 
-	asset, err = ch.db.CreateFileAsset(asset)
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// writeFileResponse answers with asset and a link to it that expires.
+func (ch *ChatHandler) writeFileResponse(w http.ResponseWriter, r *http.Request, asset db.FileAsset, isExisting bool) {
+	url, err := ch.blobs.URL(r.Context(), asset.StoragePath, fileURLExpiry)
 	if err != nil {
+		logger.FromContext(r.Context()).Error("[chat] failed to link file %d: %v", asset.ID, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatResponse{
 			Success: false,
-			Message: "Failed to create asset record",
+			Message: "Failed to link file",
 		})
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(FileResponse{
+	response := FileResponse{
 		Success:    true,
-		URL:        uploadURL,
-		IsExisting: false,
-		Asset:      *asset,
+		URL:        url,
+		IsExisting: isExisting,
+		Asset:      asset,
 		UploadTime: asset.UploadTime,
-	})
+	}
+	if url != asset.StoragePath {
+		expires := time.Now().Add(fileURLExpiry)
+		response.URLExpires = &expires
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
 }
 
 // GetFile retrieves a file from a chat
@@ -1116,14 +1120,29 @@ func (ch *ChatHandler) GetFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(FileResponse{
-		Success:    true,
-		URL:        asset.StoragePath,
-		IsExisting: true,
-		Asset:      *asset,
-		UploadTime: asset.UploadTime,
-	})
+	ch.writeFileResponse(w, r, *asset, true)
+}
+
+// DownloadFile serves the files of the stores that serve their own links,
+// the link's signature is its authorization.
+//
+//	@Summary		Download a file from a chat
+//	@Description	Download a file from a chat with a signed link from GetFile
+//	@Tags			Hive Chat
+//	@Param			key		query	string	true	"File key"
+//	@Param			expires	query	int		true	"Link expiry, in unix seconds"
+//	@Param			sig		query	string	true	"Link signature"
+//	@Success		200
+//	@Failure		403
+//	@Failure		404
+//	@Router			/hivechat/file/download [get]
+func (ch *ChatHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	server, ok := ch.blobs.(http.Handler)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	server.ServeHTTP(w, r)
 }
 
 // ListFiles lists all files in a chat
@@ -1658,6 +1677,8 @@ func (ch *ChatHandler) SendActionMessage(w http.ResponseWriter, r *http.Request)
 		ChatMessage:     createdMessage,
 	}
 
+	publishChatEvent(wsMessage)
+
 	if err := websocket.WebsocketPool.SendTicketMessage(wsMessage); err != nil {
 		log.Printf("Failed to send websocket message: %v", err)
 	}
@@ -1673,7 +1694,7 @@ func (ch *ChatHandler) CreateOrEditChatWorkflow(w http.ResponseWriter, r *http.R
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -2129,7 +2150,7 @@ func (ch *ChatHandler) GetAllChatStatus(w http.ResponseWriter, r *http.Request)
 
 	statuses, err := ch.db.GetChatStatusByChatID(chatID)
 	if err != nil {
-		logger.Log.Error("Failed to get chat statuses: %v", err)
+		logger.FromContext(r.Context()).Error("Failed to get chat statuses: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatStatusResponse{
 			Success: false,
@@ -2180,7 +2201,7 @@ func (ch *ChatHandler) GetLatestChatStatus(w http.ResponseWriter, r *http.Reques
 			})
 			return
 		}
-		logger.Log.Error("Failed to get latest chat status: %v", err)
+		logger.FromContext(r.Context()).Error("Failed to get latest chat status: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatStatusResponse{
 			Success: false,
@@ -2259,7 +2280,7 @@ func (ch *ChatHandler) CreateChatStatus(w http.ResponseWriter, r *http.Request)
 
 	createdStatus, err := ch.db.AddChatStatus(chatStatus)
 	if err != nil {
-		logger.Log.Error("Failed to create chat status: %v", err)
+		logger.FromContext(r.Context()).Error("Failed to create chat status: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatStatusResponse{
 			Success: false,
@@ -2339,7 +2360,7 @@ func (ch *ChatHandler) UpdateChatStatus(w http.ResponseWriter, r *http.Request)
 			})
 			return
 		}
-		logger.Log.Error("Failed to update chat status: %v", err)
+		logger.FromContext(r.Context()).Error("Failed to update chat status: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatStatusResponse{
 			Success: false,
@@ -2400,7 +2421,7 @@ func (ch *ChatHandler) DeleteChatStatus(w http.ResponseWriter, r *http.Request)
 			})
 			return
 		}
-		logger.Log.Error("Failed to delete chat status: %v", err)
+		logger.FromContext(r.Context()).Error("Failed to delete chat status: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatStatusResponse{
 			Success: false,
@@ -2416,14 +2437,16 @@ func (ch *ChatHandler) DeleteChatStatus(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// HandleChatWebhook processes webhook requests from Stakwork
+// HandleChatWebhook processes the run status the workflow provider of the
+// chat's workspace posts
 //
 //	@Summary		Process chat webhook
-//	@Description	Receives status updates from Stakwork workflow system
+//	@Description	Receives status updates from the workflow provider of the chat's workspace, Stakwork's by default. Build runs post with kind=build.
 //	@Tags			Hive Chat
 //	@Accept			json
 //	@Produce		json
 //	@Param			chat_id	path		string	true	"Chat ID"
+//	@Param			kind	query		string	false	"Workflow kind, build for build runs"
 //	@Param			payload	body		WebhookPayload	true	"Webhook payload"
 //	@Success		200		{object}	ChatStatusWebhookResponse
 //	@Failure		400		{object}	ChatStatusWebhookResponse
@@ -2441,10 +2464,10 @@ func (ch *ChatHandler) HandleChatWebhook(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err := ch.db.GetChatByChatID(chatID)
+	chat, err := ch.db.GetChatByChatID(chatID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.Log.Error("Chat not found for webhook: %s", chatID)
+			logger.FromContext(r.Context()).Error("Chat not found for webhook: %s", chatID)
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(ChatStatusWebhookResponse{
 				Status:  "error",
@@ -2452,7 +2475,7 @@ func (ch *ChatHandler) HandleChatWebhook(w http.ResponseWriter, r *http.Request)
 			})
 			return
 		}
-		logger.Log.Error("Error fetching chat for webhook: %v", err)
+		logger.FromContext(r.Context()).Error("Error fetching chat for webhook: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatStatusWebhookResponse{
 			Status:  "error",
@@ -2461,9 +2484,27 @@ func (ch *ChatHandler) HandleChatWebhook(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var payload WebhookPayload
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		logger.Log.Error("Error parsing webhook payload: %v", err)
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ChatStatusWebhookResponse{
+			Status:  "error",
+			Message: "Invalid payload format",
+		})
+		return
+	}
+
+	// builds run on their own workflow, which may be on another provider
+	kind := workflows.KindChat
+	if r.URL.Query().Get("kind") == string(workflows.KindBuild) {
+		kind = workflows.KindBuild
+	}
+	provider, _ := ch.workflow(chat.WorkspaceID, kind)
+
+	payload, err := provider.ParseCallback(body)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error parsing webhook payload: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(ChatStatusWebhookResponse{
 			Status:  "error",
@@ -2472,23 +2513,22 @@ func (ch *ChatHandler) HandleChatWebhook(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	payloadBytes, _ := json.Marshal(payload)
-	logger.Log.Info("Received webhook for chat %s: %s", chatID, string(payloadBytes))
+	logger.FromContext(r.Context()).Info("Received webhook for chat %s: %s", chatID, string(body))
 
 	status := ""
 	message := ""
 
-	if payload.ProjectStatus == "completed" {
+	if payload.Status == workflows.StatusCompleted {
 		status = "success"
-	} else if payload.ProjectStatus == "error" {
+	} else if payload.Status == workflows.StatusFailed {
 		status = "error"
-		if payload.Error != nil {
-			message = payload.Error.Message
+		if payload.Message != "" {
+			message = payload.Message
 		} else {
 			message = "An error occurred during workflow execution"
 		}
 	} else {
-		status = payload.ProjectStatus
+		status = payload.Status
 	}
 
 	chatStatus := &db.ChatWorkflowStatus{
@@ -2499,7 +2539,7 @@ func (ch *ChatHandler) HandleChatWebhook(w http.ResponseWriter, r *http.Request)
 
 	createdStatus, err := ch.db.AddChatStatus(chatStatus)
 	if err != nil {
-		logger.Log.Error("Failed to create chat status: %v", err)
+		logger.FromContext(r.Context()).Error("Failed to create chat status: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(ChatStatusWebhookResponse{
 			Status:  "error",
@@ -2508,7 +2548,7 @@ func (ch *ChatHandler) HandleChatWebhook(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	logger.Log.Info("Created chat status for chat %s: %s - %s",
+	logger.FromContext(r.Context()).Info("Created chat status for chat %s: %s - %s",
 		chatID, createdStatus.Status, createdStatus.Message)
 
 	w.WriteHeader(http.StatusOK)
@@ -2547,7 +2587,7 @@ func (ch *ChatHandler) SSEMaintenance(w http.ResponseWriter, r *http.Request) {
 
 	if request.StopAllClients {
 		response.ClientsStopped = sse.ClientRegistry.StopAllClients()
-		logger.Log.Info("Stopped %d SSE clients during maintenance", response.ClientsStopped)
+		logger.FromContext(r.Context()).Info("Stopped %d SSE clients during maintenance", response.ClientsStopped)
 	}
 
 	if request.CleanupLogs {
@@ -2558,7 +2598,7 @@ func (ch *ChatHandler) SSEMaintenance(w http.ResponseWriter, r *http.Request) {
 		maxAge := time.Duration(request.LogMaxAgeHours) * time.Hour
 		logsRemoved, err := ch.db.DeleteOldSSEMessageLogs(maxAge)
 		if err != nil {
-			logger.Log.Error("Error cleaning up SSE logs: %v", err)
+			logger.FromContext(r.Context()).Error("Error cleaning up SSE logs: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(ChatResponse{
 				Success: false,
@@ -2568,7 +2608,7 @@ func (ch *ChatHandler) SSEMaintenance(w http.ResponseWriter, r *http.Request) {
 		}
 
 		response.LogsRemoved = logsRemoved
-		logger.Log.Info("Removed %d SSE logs older than %v during maintenance", logsRemoved, maxAge)
+		logger.FromContext(r.Context()).Info("Removed %d SSE logs older than %v during maintenance", logsRemoved, maxAge)
 	}
 
 	response.Message = fmt.Sprintf("Maintenance completed: stopped %d clients, removed %d logs",
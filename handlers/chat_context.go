@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/stakwork/sphinx-tribes/chatcontext"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+// buildChatContext fits the chat's history and the context the message is
+// tagged with into the budget of the request. The product brief goes with
+// every message, after the tags when none of them is the brief. Turns that
+// no longer fit are rolled into the chat's stored summary.
+func (ch *ChatHandler) buildChatContext(request SendMessageRequest, productBrief string) (chatcontext.Context, error) {
+	summary, err := ch.db.GetChatSummary(request.ChatID)
+	if err != nil {
+		logger.Log.Error("[chat context] could not load the summary of chat %s: %v", request.ChatID, err)
+		summary = db.ChatSummary{}
+	}
+
+	history, err := ch.db.GetChatMessagesForChatID(request.ChatID)
+	if err != nil {
+		return chatcontext.Context{}, err
+	}
+
+	// the summary covers the chat up to its last message
+	start := 0
+	for i, msg := range history {
+		if msg.ID == summary.ThroughMessageID {
+			start = i + 1
+		}
+	}
+
+	turns := make([]chatcontext.Turn, 0, len(history)-start)
+	for _, msg := range history[start:] {
+		artefacts, err := ch.db.GetArtifactsByMessageID(msg.ID)
+		if err != nil {
+			artefacts = []db.Artifact{}
+		}
+
+		artifactJSON, err := json.Marshal(map[string]interface{}{"artifacts": buildArtifactList(artefacts)})
+		if err != nil {
+			artifactJSON = []byte(`{"artifacts": []}`)
+		}
+
+		turns = append(turns, chatcontext.Turn{
+			ID:      msg.ID,
+			Role:    string(msg.Role),
+			Message: msg.Message,
+			Content: msg.Message + "\nArtifacts: " + string(artifactJSON),
+		})
+	}
+
+	built := chatcontext.Build(chatcontext.Input{
+		Message: request.Message,
+		Tags:    ch.contextTags(request, productBrief),
+		Summary: summary.Summary,
+		History: turns,
+	}, request.ContextTokens)
+
+	if len(built.Rolled) > 0 {
+		summary.ChatID = request.ChatID
+		summary.Summary = built.Summary
+		summary.ThroughMessageID = built.Rolled[len(built.Rolled)-1].ID
+		if err := ch.db.SaveChatSummary(&summary); err != nil {
+			logger.Log.Error("[chat context] could not save the summary of chat %s: %v", request.ChatID, err)
+		}
+	}
+	return built, nil
+}
+
+// contextTags resolves the tags of the message to the content they point
+// at, in the order they were given. Tags that point at nothing are left out.
+func (ch *ChatHandler) contextTags(request SendMessageRequest, productBrief string) []chatcontext.Tag {
+	tags := []chatcontext.Tag{}
+	seen := map[string]bool{}
+	hasBrief := false
+	for _, tag := range request.ContextTags {
+		key := tag.Type + ":" + tag.ID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		content := ""
+		switch db.ContextTagType(tag.Type) {
+		case db.ProductBriefContext:
+			content = productBrief
+			hasBrief = true
+		case db.FeatureBriefContext:
+			if brief, err := ch.db.GetFeatureBrief(tag.ID); err == nil {
+				content = brief
+			}
+		case db.SchematicContext:
+			if workspace := ch.db.GetWorkspaceByUuid(request.WorkspaceUUID); workspace.SchematicUrl != "" {
+				content = "Schematic: " + workspace.SchematicUrl
+			}
+		}
+		if content != "" {
+			tags = append(tags, chatcontext.Tag{Type: tag.Type, ID: tag.ID, Content: content})
+		}
+	}
+
+	if !hasBrief && productBrief != "" {
+		tags = append(tags, chatcontext.Tag{Type: string(db.ProductBriefContext), Content: productBrief})
+	}
+	return tags
+}
+
+// chatHistoryVars lays the built context out the way chat workflows read
+// it: the summary leads the history as a system turn, the tags go as one
+// text.
+func chatHistoryVars(built chatcontext.Context) ([]map[string]string, string) {
+	history := make([]map[string]string, 0, len(built.History)+1)
+	if built.Summary != "" {
+		history = append(history, map[string]string{
+			"role":    "system",
+			"content": "Summary of the earlier conversation:\n" + built.Summary,
+		})
+	}
+	for _, turn := range built.History {
+		history = append(history, map[string]string{
+			"role":    turn.Role,
+			"content": turn.Content,
+		})
+	}
+
+	contents := make([]string, len(built.Tags))
+	for i, tag := range built.Tags {
+		contents[i] = tag.Content
+	}
+	return history, strings.Join(contents, "\n\n")
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/chatcontext"
+	"github.com/stakwork/sphinx-tribes/db"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func chatMessages(count int, size int) []db.ChatMessage {
+	messages := make([]db.ChatMessage, count)
+	for i := range messages {
+		messages[i] = db.ChatMessage{
+			ID:      fmt.Sprintf("m%d", i+1),
+			ChatID:  "chat-1",
+			Role:    db.UserRole,
+			Message: fmt.Sprintf("message %d %s", i+1, strings.Repeat("x", size)),
+		}
+	}
+	return messages
+}
+
+func TestBuildChatContext(t *testing.T) {
+	t.Run("Rolls the turns past the budget into the stored summary", func(t *testing.T) {
+		mockDb := dbMocks.NewDatabase(t)
+		handler := &ChatHandler{db: mockDb}
+		mockDb.On("GetChatSummary", "chat-1").Return(db.ChatSummary{ChatID: "chat-1", Summary: "user: message 0", ThroughMessageID: "m0"}, nil)
+		mockDb.On("GetChatMessagesForChatID", "chat-1").Return(chatMessages(6, 2000), nil)
+		mockDb.On("GetArtifactsByMessageID", mock.Anything).Return([]db.Artifact{}, nil)
+		mockDb.On("SaveChatSummary", mock.MatchedBy(func(s *db.ChatSummary) bool {
+			return s.ChatID == "chat-1" && s.ThroughMessageID == "m3" &&
+				strings.HasPrefix(s.Summary, "user: message 0\nuser: message 1 x")
+		})).Return(nil).Once()
+
+		built, err := handler.buildChatContext(SendMessageRequest{ChatID: "chat-1", Message: "next", ContextTokens: 2000}, "brief")
+
+		assert.NoError(t, err)
+		assert.Len(t, built.Rolled, 3)
+		assert.Len(t, built.History, 3)
+		assert.LessOrEqual(t, built.Tokens, 2000)
+
+		history, tags := chatHistoryVars(built)
+		assert.Equal(t, "system", history[0]["role"])
+		assert.Contains(t, history[0]["content"], "user: message 0")
+		assert.True(t, strings.HasPrefix(history[1]["content"], "message 4 "))
+		assert.Contains(t, history[2]["content"], "\nArtifacts: ")
+		assert.Equal(t, "brief", tags)
+	})
+
+	t.Run("Sends only the turns the summary does not cover", func(t *testing.T) {
+		mockDb := dbMocks.NewDatabase(t)
+		handler := &ChatHandler{db: mockDb}
+		mockDb.On("GetChatSummary", "chat-1").Return(db.ChatSummary{ChatID: "chat-1", Summary: "user: earlier", ThroughMessageID: "m2"}, nil)
+		mockDb.On("GetChatMessagesForChatID", "chat-1").Return(chatMessages(3, 10), nil)
+		mockDb.On("GetArtifactsByMessageID", "m3").Return([]db.Artifact{}, nil).Once()
+
+		built, err := handler.buildChatContext(SendMessageRequest{ChatID: "chat-1", Message: "next"}, "brief")
+
+		assert.NoError(t, err)
+		assert.Empty(t, built.Rolled)
+		assert.Len(t, built.History, 1)
+		assert.Equal(t, "m3", built.History[0].ID)
+		assert.Equal(t, "user: earlier", built.Summary)
+	})
+
+	t.Run("Puts the tagged context first, the brief last unless tagged", func(t *testing.T) {
+		mockDb := dbMocks.NewDatabase(t)
+		handler := &ChatHandler{db: mockDb}
+		mockDb.On("GetFeatureBrief", "feature-1").Return("Feature: Leaderboard", nil)
+		mockDb.On("GetFeatureBrief", "feature-2").Return("", fmt.Errorf("feature not found"))
+		mockDb.On("GetWorkspaceByUuid", "workspace-1").Return(db.Workspace{Uuid: "workspace-1", SchematicUrl: "https://example.com/schematic.png"})
+
+		request := SendMessageRequest{WorkspaceUUID: "workspace-1"}
+		request.ContextTags = append(request.ContextTags,
+			struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+			}{Type: "schematic"},
+			struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+			}{Type: "featureBrief", ID: "feature-1"},
+			struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+			}{Type: "featureBrief", ID: "feature-2"},
+			struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+			}{Type: "featureBrief", ID: "feature-1"},
+		)
+
+		tags := handler.contextTags(request, "brief")
+
+		assert.Equal(t, []chatcontext.Tag{
+			{Type: "schematic", Content: "Schematic: https://example.com/schematic.png"},
+			{Type: "featureBrief", ID: "feature-1", Content: "Feature: Leaderboard"},
+			{Type: "productBrief", Content: "brief"},
+		}, tags)
+	})
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/blobstore"
+	"github.com/stakwork/sphinx-tribes/db"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func uploadRequest(t *testing.T, filename string, contentType string, content []byte) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, filename))
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	require.NoError(t, err)
+	part.Write(content)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/hivechat/upload?workspaceId=workspace-1", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req.WithContext(context.WithValue(req.Context(), "pubkey", "uploader"))
+}
+
+func TestChatFileStorage(t *testing.T) {
+	pdf := []byte("%PDF-1.7\n1 0 obj\n")
+	newHandler := func(t *testing.T) (*ChatHandler, *dbMocks.Database, string) {
+		dir := t.TempDir()
+		mockDb := dbMocks.NewDatabase(t)
+		store := blobstore.NewLocal(dir, "https://example.com/hivechat/file/download", []byte("signing key"))
+		return &ChatHandler{db: mockDb, blobs: &blobstore.Stores{Current: store}}, mockDb, dir
+	}
+
+	t.Run("Stores the upload as the type its content is", func(t *testing.T) {
+		handler, mockDb, dir := newHandler(t)
+		mockDb.On("GetFileAssetByHash", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+		mockDb.On("CreateFileAsset", mock.MatchedBy(func(asset *db.FileAsset) bool {
+			return asset.MimeType == "application/pdf" && asset.FileSize == int64(len(pdf)) &&
+				asset.StoragePath == "local://"+asset.UploadFilename && asset.WorkspaceID == "workspace-1"
+		})).Return(func(asset *db.FileAsset) *db.FileAsset { return asset }, nil).Once()
+
+		rr := httptest.NewRecorder()
+		handler.UploadFile(rr, uploadRequest(t, "brief.pdf", "application/pdf", pdf))
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var response FileResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.True(t, strings.HasPrefix(response.URL, "https://example.com/hivechat/file/download?"))
+		assert.NotNil(t, response.URLExpires)
+
+		stored, err := os.ReadFile(filepath.Join(dir, response.Asset.UploadFilename))
+		require.NoError(t, err)
+		assert.Equal(t, pdf, stored)
+	})
+
+	t.Run("Refuses a file that is not what it says", func(t *testing.T) {
+		handler, _, _ := newHandler(t)
+
+		rr := httptest.NewRecorder()
+		handler.UploadFile(rr, uploadRequest(t, "photo.png", "image/png", []byte("#!/bin/sh\nrm -rf /")))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "File type not allowed")
+	})
+
+	t.Run("Keeps JSON sent as JSON", func(t *testing.T) {
+		fileType, ok := uploadFileType("text/plain", "application/json; charset=utf-8")
+		assert.True(t, ok)
+		assert.Equal(t, "application/json", fileType)
+
+		_, ok = uploadFileType("application/pdf", "application/json")
+		assert.False(t, ok)
+	})
+
+	t.Run("Drops the copy of a known file", func(t *testing.T) {
+		handler, mockDb, dir := newHandler(t)
+		existing := &db.FileAsset{ID: 7, StoragePath: "local://known.pdf", UploadFilename: "known.pdf"}
+		mockDb.On("GetFileAssetByHash", mock.Anything).Return(existing, nil)
+		mockDb.On("UpdateFileAssetReference", uint(7)).Return(nil).Once()
+
+		rr := httptest.NewRecorder()
+		handler.UploadFile(rr, uploadRequest(t, "brief.pdf", "application/pdf", pdf))
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var response FileResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.True(t, response.IsExisting)
+		assert.Equal(t, uint(7), response.Asset.ID)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("Deletes the file when its record fails", func(t *testing.T) {
+		handler, mockDb, dir := newHandler(t)
+		mockDb.On("GetFileAssetByHash", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+		mockDb.On("CreateFileAsset", mock.Anything).Return(nil, errors.New("db down")).Once()
+
+		rr := httptest.NewRecorder()
+		handler.UploadFile(rr, uploadRequest(t, "brief.pdf", "application/pdf", pdf))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("Links and serves a stored file", func(t *testing.T) {
+		handler, mockDb, dir := newHandler(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "stored.pdf"), pdf, 0644))
+		mockDb.On("GetFileAssetByID", uint(3)).Return(&db.FileAsset{ID: 3, StoragePath: "local://stored.pdf"}, nil)
+
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "3")
+		req := httptest.NewRequest(http.MethodGet, "/hivechat/file/3", nil)
+		rr := httptest.NewRecorder()
+		handler.GetFile(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		var response FileResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		link, err := url.Parse(response.URL)
+		require.NoError(t, err)
+
+		rr = httptest.NewRecorder()
+		handler.DownloadFile(rr, httptest.NewRequest(http.MethodGet, "/hivechat/file/download?"+link.RawQuery, nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, pdf, rr.Body.Bytes())
+
+		rr = httptest.NewRecorder()
+		handler.DownloadFile(rr, httptest.NewRequest(http.MethodGet, "/hivechat/file/download?key=stored.pdf&expires=9999999999&sig=forged", nil))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Keeps the public links of files uploaded before", func(t *testing.T) {
+		handler, mockDb, _ := newHandler(t)
+		mockDb.On("GetFileAssetByID", uint(4)).Return(&db.FileAsset{ID: 4, StoragePath: "https://memes.sphinx.chat/public/abc"}, nil)
+
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "4")
+		req := httptest.NewRequest(http.MethodGet, "/hivechat/file/4", nil)
+		rr := httptest.NewRecorder()
+		handler.GetFile(rr, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+
+		var response FileResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, "https://memes.sphinx.chat/public/abc", response.URL)
+		assert.Nil(t, response.URLExpires)
+	})
+}
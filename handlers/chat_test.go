@@ -1951,9 +1951,9 @@ func TestUploadFile(t *testing.T) {
 			contentType string
 			content     []byte
 		}{
-			{"jpg", "image/jpeg", []byte("fake jpeg content")},
-			{"png", "image/png", []byte("fake png content")},
-			{"gif", "image/gif", []byte("fake gif content")},
+			{"jpg", "image/jpeg", []byte("\xff\xd8\xff\xe0fake jpeg content")},
+			{"png", "image/png", []byte("\x89PNG\r\n\x1a\nfake png content")},
+			{"gif", "image/gif", []byte("GIF89afake gif content")},
 		}
 
 		for _, img := range imageTypes {
@@ -38,7 +38,7 @@ func (ch *codeSpaceHandler) GetAllCodeSpaceMaps(w http.ResponseWriter, r *http.R
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[codespace] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[codespace] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -46,7 +46,7 @@ func (ch *codeSpaceHandler) GetAllCodeSpaceMaps(w http.ResponseWriter, r *http.R
 
 	codespaces, err := ch.db.GetCodeSpaceMaps()
 	if err != nil {
-		logger.Log.Error("[codespace] error getting codespace mappings: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error getting codespace mappings: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to retrieve codespace mappings"})
 		return
@@ -61,7 +61,7 @@ func (ch *codeSpaceHandler) GetCodeSpaceMapsByWorkspace(w http.ResponseWriter, r
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[codespace] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[codespace] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -76,7 +76,7 @@ func (ch *codeSpaceHandler) GetCodeSpaceMapsByWorkspace(w http.ResponseWriter, r
 
 	codespaces, err := ch.db.GetCodeSpaceMapByWorkspace(workspaceID)
 	if err != nil {
-		logger.Log.Error("[codespace] error getting codespace mappings: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error getting codespace mappings: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to retrieve codespace mappings"})
 		return
@@ -91,7 +91,7 @@ func (ch *codeSpaceHandler) GetCodeSpaceMapByWorkspaceAndUser(w http.ResponseWri
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[codespace] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[codespace] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -113,7 +113,7 @@ func (ch *codeSpaceHandler) GetCodeSpaceMapByWorkspaceAndUser(w http.ResponseWri
 			json.NewEncoder(w).Encode(map[string]string{"error": "Codespace mapping not found"})
 			return
 		}
-		logger.Log.Error("[codespace] error getting codespace mapping: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error getting codespace mapping: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to retrieve codespace mapping"})
 		return
@@ -128,7 +128,7 @@ func (ch *codeSpaceHandler) GetCodeSpaceMapsByUser(w http.ResponseWriter, r *htt
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[codespace] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[codespace] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -143,7 +143,7 @@ func (ch *codeSpaceHandler) GetCodeSpaceMapsByUser(w http.ResponseWriter, r *htt
 
 	codespaces, err := ch.db.GetCodeSpaceMapByUser(userPubkey)
 	if err != nil {
-		logger.Log.Error("[codespace] error getting codespace mappings: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error getting codespace mappings: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to retrieve codespace mappings"})
 		return
@@ -158,7 +158,7 @@ func (ch *codeSpaceHandler) GetCodeSpaceMapsByURL(w http.ResponseWriter, r *http
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[codespace] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[codespace] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -173,7 +173,7 @@ func (ch *codeSpaceHandler) GetCodeSpaceMapsByURL(w http.ResponseWriter, r *http
 
 	codespaces, err := ch.db.GetCodeSpaceMapByURL(codeSpaceURL)
 	if err != nil {
-		logger.Log.Error("[codespace] error getting codespace mappings: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error getting codespace mappings: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to retrieve codespace mappings"})
 		return
@@ -188,7 +188,7 @@ func (ch *codeSpaceHandler) QueryCodeSpaceMaps(w http.ResponseWriter, r *http.Re
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[codespace] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[codespace] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -199,19 +199,19 @@ func (ch *codeSpaceHandler) QueryCodeSpaceMaps(w http.ResponseWriter, r *http.Re
 	workspaceID := r.URL.Query().Get("workspaceID")
 	userPubkey := r.URL.Query().Get("userPubkey")
 
-	logger.Log.Info("[codespace] Query params - workspaceID: %s, userPubkey: %s", workspaceID, userPubkey)
+	logger.FromContext(r.Context()).Info("[codespace] Query params - workspaceID: %s, userPubkey: %s", workspaceID, userPubkey)
 
 	if workspaceID != "" && userPubkey != "" {
-		logger.Log.Info("[codespace] Querying by workspace and user")
+		logger.FromContext(r.Context()).Info("[codespace] Querying by workspace and user")
 		codeSpace, err := ch.db.GetCodeSpaceMapByWorkspaceAndUser(workspaceID, userPubkey)
 		if err != nil {
 			if err.Error() == "codespace mapping not found" {
-				logger.Log.Info("[codespace] No mapping found for workspace %s and user %s", workspaceID, userPubkey)
+				logger.FromContext(r.Context()).Info("[codespace] No mapping found for workspace %s and user %s", workspaceID, userPubkey)
 				w.WriteHeader(http.StatusOK)
 				json.NewEncoder(w).Encode([]db.CodeSpaceMap{})
 				return
 			}
-			logger.Log.Error("[codespace] error querying codespace mapping: %v", err)
+			logger.FromContext(r.Context()).Error("[codespace] error querying codespace mapping: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to query codespace mapping"})
 			return
@@ -222,44 +222,44 @@ func (ch *codeSpaceHandler) QueryCodeSpaceMaps(w http.ResponseWriter, r *http.Re
 	}
 
 	if workspaceID != "" {
-		logger.Log.Info("[codespace] Querying by workspace")
+		logger.FromContext(r.Context()).Info("[codespace] Querying by workspace")
 		codespaces, err := ch.db.GetCodeSpaceMapByWorkspace(workspaceID)
 		if err != nil {
-			logger.Log.Error("[codespace] error querying codespace mappings: %v", err)
+			logger.FromContext(r.Context()).Error("[codespace] error querying codespace mappings: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to query codespace mappings"})
 			return
 		}
-		logger.Log.Info("[codespace] Found %d mappings for workspace %s", len(codespaces), workspaceID)
+		logger.FromContext(r.Context()).Info("[codespace] Found %d mappings for workspace %s", len(codespaces), workspaceID)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(codespaces)
 		return
 	}
 
 	if userPubkey != "" {
-		logger.Log.Info("[codespace] Querying by user")
+		logger.FromContext(r.Context()).Info("[codespace] Querying by user")
 		codespaces, err := ch.db.GetCodeSpaceMapByUser(userPubkey)
 		if err != nil {
-			logger.Log.Error("[codespace] error querying codespace mappings: %v", err)
+			logger.FromContext(r.Context()).Error("[codespace] error querying codespace mappings: %v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to query codespace mappings"})
 			return
 		}
-		logger.Log.Info("[codespace] Found %d mappings for user %s", len(codespaces), userPubkey)
+		logger.FromContext(r.Context()).Info("[codespace] Found %d mappings for user %s", len(codespaces), userPubkey)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(codespaces)
 		return
 	}
 
-	logger.Log.Info("[codespace] Querying all mappings")
+	logger.FromContext(r.Context()).Info("[codespace] Querying all mappings")
 	codespaces, err := ch.db.GetCodeSpaceMaps()
 	if err != nil {
-		logger.Log.Error("[codespace] error querying all codespace mappings: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error querying all codespace mappings: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to query codespace mappings"})
 		return
 	}
-	logger.Log.Info("[codespace] Found %d total mappings", len(codespaces))
+	logger.FromContext(r.Context()).Info("[codespace] Found %d total mappings", len(codespaces))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(codespaces)
 }
@@ -269,7 +269,7 @@ func (ch *codeSpaceHandler) CreateCodeSpaceMap(w http.ResponseWriter, r *http.Re
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[codespace] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[codespace] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -279,7 +279,7 @@ func (ch *codeSpaceHandler) CreateCodeSpaceMap(w http.ResponseWriter, r *http.Re
 	body, err := io.ReadAll(r.Body)
 	r.Body.Close()
 	if err != nil {
-		logger.Log.Error("[codespace] error reading request body: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error reading request body: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to read request body"})
 		return
@@ -287,7 +287,7 @@ func (ch *codeSpaceHandler) CreateCodeSpaceMap(w http.ResponseWriter, r *http.Re
 
 	err = json.Unmarshal(body, &codeSpace)
 	if err != nil {
-		logger.Log.Error("[codespace] error unmarshaling request body: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error unmarshaling request body: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request format"})
 		return
@@ -301,7 +301,7 @@ func (ch *codeSpaceHandler) CreateCodeSpaceMap(w http.ResponseWriter, r *http.Re
 
 	createdCodeSpace, err := ch.db.CreateCodeSpaceMap(codeSpace)
 	if err != nil {
-		logger.Log.Error("[codespace] error creating codespace mapping: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error creating codespace mapping: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create codespace mapping"})
 		return
@@ -316,7 +316,7 @@ func (ch *codeSpaceHandler) UpdateCodeSpaceMap(w http.ResponseWriter, r *http.Re
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[codespace] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[codespace] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -334,7 +334,7 @@ func (ch *codeSpaceHandler) UpdateCodeSpaceMap(w http.ResponseWriter, r *http.Re
 	body, err := io.ReadAll(r.Body)
 	r.Body.Close()
 	if err != nil {
-		logger.Log.Error("[codespace] error reading request body: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error reading request body: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to read request body"})
 		return
@@ -342,7 +342,7 @@ func (ch *codeSpaceHandler) UpdateCodeSpaceMap(w http.ResponseWriter, r *http.Re
 
 	err = json.Unmarshal(body, &codeSpace)
 	if err != nil {
-		logger.Log.Error("[codespace] error unmarshaling request body: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error unmarshaling request body: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request format"})
 		return
@@ -358,11 +358,16 @@ func (ch *codeSpaceHandler) UpdateCodeSpaceMap(w http.ResponseWriter, r *http.Re
 	if codeSpace.UserPubkey != "" {
 		updates["user_pubkey"] = codeSpace.UserPubkey
 	}
-	// Also allow updating Username, GithubPat and BaseBranch, even if empty to clear them
+	// Also allow updating Username, GithubPat and BaseBranch, even if empty to clear them.
+	// Secrets sent back redacted keep their value.
 	updates["username"] = codeSpace.Username
-	updates["github_pat"] = codeSpace.GithubPat
+	if !codeSpace.GithubPat.IsRedacted() {
+		updates["github_pat"] = codeSpace.GithubPat
+	}
 	updates["base_branch"] = codeSpace.BaseBranch
-	updates["pool_api_key"] = codeSpace.PoolAPIKey
+	if !codeSpace.PoolAPIKey.IsRedacted() {
+		updates["pool_api_key"] = codeSpace.PoolAPIKey
+	}
 
 	updatedCodeSpace, err := ch.db.UpdateCodeSpaceMap(id, updates)
 	if err != nil {
@@ -371,7 +376,7 @@ func (ch *codeSpaceHandler) UpdateCodeSpaceMap(w http.ResponseWriter, r *http.Re
 			json.NewEncoder(w).Encode(map[string]string{"error": "CodeSpace mapping not found"})
 			return
 		}
-		logger.Log.Error("[codespace] error updating codespace mapping: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error updating codespace mapping: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to update codespace mapping"})
 		return
@@ -386,7 +391,7 @@ func (ch *codeSpaceHandler) DeleteCodeSpaceMap(w http.ResponseWriter, r *http.Re
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[codespace] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[codespace] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -407,7 +412,7 @@ func (ch *codeSpaceHandler) DeleteCodeSpaceMap(w http.ResponseWriter, r *http.Re
 			json.NewEncoder(w).Encode(map[string]string{"error": "CodeSpace mapping not found"})
 			return
 		}
-		logger.Log.Error("[codespace] error deleting codespace mapping: %v", err)
+		logger.FromContext(r.Context()).Error("[codespace] error deleting codespace mapping: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to delete codespace mapping"})
 		return
@@ -17,9 +17,11 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/rs/xid"
+	"github.com/stakwork/sphinx-tribes/audit"
 	"github.com/stakwork/sphinx-tribes/auth"
 	"github.com/stakwork/sphinx-tribes/db"
 	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/webhooks"
 )
 
 type PostData struct {
@@ -79,7 +81,7 @@ func (oh *featureHandler) CreateOrEditFeatures(w http.ResponseWriter, r *http.Re
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -90,7 +92,7 @@ func (oh *featureHandler) CreateOrEditFeatures(w http.ResponseWriter, r *http.Re
 	err := json.Unmarshal(body, &features)
 
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -121,11 +123,14 @@ func (oh *featureHandler) CreateOrEditFeatures(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	existing := oh.db.GetFeatureByUuid(features.Uuid)
 	p, err := oh.db.CreateOrEditFeature(features)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	emitWebhook(p.WorkspaceUuid, webhooks.EventFeatureUpdated, p)
+	recordChange(pubKeyFromAuth, p.WorkspaceUuid, audit.EntityFeature, p.Uuid, existing.Uuid != "", existing, p)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(p)
@@ -145,18 +150,20 @@ func (oh *featureHandler) DeleteFeature(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	uuid := chi.URLParam(r, "uuid")
+	existing := oh.db.GetFeatureByUuid(uuid)
 	err := oh.db.DeleteFeatureByUuid(uuid)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
+	audit.Record(pubKeyFromAuth, existing.WorkspaceUuid, audit.EntityFeature, uuid, audit.ActionDelete, existing, nil)
 
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprint(w, "Feature deleted successfully")
@@ -176,7 +183,7 @@ func (oh *featureHandler) GetFeaturesByWorkspaceUuid(w http.ResponseWriter, r *h
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -202,13 +209,13 @@ func (oh *featureHandler) GetWorkspaceFeaturesCount(w http.ResponseWriter, r *ht
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	if !utils.ValidateUUID(r) {
-		logger.Log.Info("invalid or missing uuid")
+		logger.FromContext(r.Context()).Info("invalid or missing uuid")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "invalid or missing uuid"})
 		return
@@ -216,7 +223,7 @@ func (oh *featureHandler) GetWorkspaceFeaturesCount(w http.ResponseWriter, r *ht
 
 	uuid := chi.URLParam(r, "uuid")
 	if uuid == "" {
-		logger.Log.Info("missing or empty uuid")
+		logger.FromContext(r.Context()).Info("missing or empty uuid")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "missing or empty uuid"})
 		return
@@ -243,7 +250,7 @@ func (oh *featureHandler) GetFeatureByUuid(w http.ResponseWriter, r *http.Reques
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -251,7 +258,7 @@ func (oh *featureHandler) GetFeatureByUuid(w http.ResponseWriter, r *http.Reques
 	uuid := chi.URLParam(r, "uuid")
 
 	if uuid == "" {
-		logger.Log.Info("missing uuid parameter")
+		logger.FromContext(r.Context()).Info("missing uuid parameter")
 		http.Error(w, "uuid parameter is required", http.StatusBadRequest)
 		return
 	}
@@ -277,7 +284,7 @@ func (oh *featureHandler) UpdateFeatureBrief(w http.ResponseWriter, r *http.Requ
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -333,6 +340,8 @@ func (oh *featureHandler) UpdateFeatureBrief(w http.ResponseWriter, r *http.Requ
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	emitWebhook(p.WorkspaceUuid, webhooks.EventFeatureUpdated, p)
+	audit.Record(pubKeyFromAuth, p.WorkspaceUuid, audit.EntityFeature, p.Uuid, audit.ActionUpdate, prevFeatureBrief, p)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(p)
@@ -352,7 +361,7 @@ func (oh *featureHandler) CreateOrEditFeaturePhase(w http.ResponseWriter, r *htt
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -392,6 +401,8 @@ func (oh *featureHandler) CreateOrEditFeaturePhase(w http.ResponseWriter, r *htt
 		fmt.Fprintf(w, "Error creating feature phase: %v", err)
 		return
 	}
+	emitWebhook(feature.WorkspaceUuid, webhooks.EventPhaseUpdated, phase)
+	recordChange(pubKeyFromAuth, feature.WorkspaceUuid, audit.EntityPhase, phase.Uuid, existingPhase.Uuid != "", existingPhase, phase)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(phase)
@@ -411,7 +422,7 @@ func (oh *featureHandler) GetFeaturePhases(w http.ResponseWriter, r *http.Reques
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -437,14 +448,14 @@ func (oh *featureHandler) GetFeaturePhaseByUUID(w http.ResponseWriter, r *http.R
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	person := oh.db.GetPersonByPubkey(pubKeyFromAuth)
 	if person.OwnerPubKey != pubKeyFromAuth {
-		logger.Log.Info("Invalid pubkey from auth")
+		logger.FromContext(r.Context()).Info("Invalid pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -476,7 +487,7 @@ func (oh *featureHandler) DeleteFeaturePhase(w http.ResponseWriter, r *http.Requ
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -485,18 +496,21 @@ func (oh *featureHandler) DeleteFeaturePhase(w http.ResponseWriter, r *http.Requ
 	phaseUuid := chi.URLParam(r, "phase_uuid")
 
 	if !isValidUUID(featureUuid) || !isValidUUID(phaseUuid) {
-		logger.Log.Info("Malformed UUIDs")
+		logger.FromContext(r.Context()).Info("Malformed UUIDs")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Malformed UUIDs"})
 		return
 	}
 
+	existingPhase, _ := oh.db.GetFeaturePhaseByUuid(featureUuid, phaseUuid)
 	err := oh.db.DeleteFeaturePhase(featureUuid, phaseUuid)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
+	feature := oh.db.GetFeatureByUuid(featureUuid)
+	audit.Record(pubKeyFromAuth, feature.WorkspaceUuid, audit.EntityPhase, phaseUuid, audit.ActionDelete, existingPhase, nil)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Phase deleted successfully"})
@@ -516,7 +530,7 @@ func (oh *featureHandler) CreateOrEditStory(w http.ResponseWriter, r *http.Reque
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -548,6 +562,8 @@ func (oh *featureHandler) CreateOrEditStory(w http.ResponseWriter, r *http.Reque
 		fmt.Fprintf(w, "Error creating feature story: %v", err)
 		return
 	}
+	feature := oh.db.GetFeatureByUuid(story.FeatureUuid)
+	recordChange(pubKeyFromAuth, feature.WorkspaceUuid, audit.EntityStory, story.Uuid, existingStory.Uuid != "", existingStory, story)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(story)
@@ -567,14 +583,14 @@ func (oh *featureHandler) GetStoriesByFeatureUuid(w http.ResponseWriter, r *http
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	featureUuid := chi.URLParam(r, "feature_uuid")
 	if featureUuid == "" {
-		logger.Log.Info("empty feature uuid")
+		logger.FromContext(r.Context()).Info("empty feature uuid")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -606,7 +622,7 @@ func (oh *featureHandler) GetStoryByUuid(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -635,7 +651,7 @@ func (oh *featureHandler) DeleteStory(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -643,12 +659,15 @@ func (oh *featureHandler) DeleteStory(w http.ResponseWriter, r *http.Request) {
 	featureUuid := chi.URLParam(r, "feature_uuid")
 	storyUuid := chi.URLParam(r, "story_uuid")
 
+	existingStory, _ := oh.db.GetFeatureStoryByUuid(featureUuid, storyUuid)
 	err := oh.db.DeleteFeatureStoryByUuid(featureUuid, storyUuid)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
+	feature := oh.db.GetFeatureByUuid(featureUuid)
+	audit.Record(pubKeyFromAuth, feature.WorkspaceUuid, audit.EntityStory, storyUuid, audit.ActionDelete, existingStory, nil)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Story deleted successfully"})
@@ -671,7 +690,7 @@ func (oh *featureHandler) GetBountiesByFeatureAndPhaseUuid(w http.ResponseWriter
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -705,7 +724,7 @@ func (oh *featureHandler) GetBountiesCountByFeatureAndPhaseUuid(w http.ResponseW
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -804,7 +823,7 @@ func (oh *featureHandler) StoriesSend(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -812,7 +831,7 @@ func (oh *featureHandler) StoriesSend(w http.ResponseWriter, r *http.Request) {
 	user := oh.db.GetPersonByPubkey(pubKeyFromAuth)
 
 	if user.OwnerPubKey != pubKeyFromAuth {
-		logger.Log.Info("Person not exists")
+		logger.FromContext(r.Context()).Info("Person not exists")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -827,7 +846,7 @@ func (oh *featureHandler) StoriesSend(w http.ResponseWriter, r *http.Request) {
 	var postData PostData
 	err = json.Unmarshal(body, &postData)
 	if err != nil {
-		logger.Log.Error("[StoriesSend] JSON Unmarshal error: %v", err)
+		logger.FromContext(r.Context()).Error("[StoriesSend] JSON Unmarshal error: %v", err)
 		http.Error(w, "Invalid JSON format", http.StatusNotAcceptable)
 		return
 	}
@@ -893,7 +912,7 @@ func (oh *featureHandler) BriefSend(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -901,7 +920,7 @@ func (oh *featureHandler) BriefSend(w http.ResponseWriter, r *http.Request) {
 	user := oh.db.GetPersonByPubkey(pubKeyFromAuth)
 
 	if user.OwnerPubKey != pubKeyFromAuth {
-		logger.Log.Info("Person not exists")
+		logger.FromContext(r.Context()).Info("Person not exists")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -916,14 +935,14 @@ func (oh *featureHandler) BriefSend(w http.ResponseWriter, r *http.Request) {
 	var postData AudioBriefPostData
 	err = json.Unmarshal(body, &postData)
 	if err != nil {
-		logger.Log.Error("[BriefSend] JSON Unmarshal error: %v", err)
+		logger.FromContext(r.Context()).Error("[BriefSend] JSON Unmarshal error: %v", err)
 		http.Error(w, "Invalid JSON format", http.StatusNotAcceptable)
 		return
 	}
 
 	host := os.Getenv("HOST")
 	if host == "" {
-		logger.Log.Error("[BriefSend] HOST environment variable not set")
+		logger.FromContext(r.Context()).Error("[BriefSend] HOST environment variable not set")
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -940,7 +959,7 @@ func (oh *featureHandler) BriefSend(w http.ResponseWriter, r *http.Request) {
 
 	apiKey := os.Getenv("SWWFKEY")
 	if apiKey == "" {
-		logger.Log.Error("[BriefSend] API key not set in environment")
+		logger.FromContext(r.Context()).Error("[BriefSend] API key not set in environment")
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -1003,14 +1022,14 @@ func (oh *featureHandler) UpdateFeatureStatus(w http.ResponseWriter, r *http.Req
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	person := oh.db.GetPersonByPubkey(pubKeyFromAuth)
 	if person.OwnerPubKey == "" {
-		logger.Log.Info("invalid pubkey")
+		logger.FromContext(r.Context()).Info("invalid pubkey")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{
 			"error": "Unauthorized: invalid pubkey",
@@ -1021,7 +1040,7 @@ func (oh *featureHandler) UpdateFeatureStatus(w http.ResponseWriter, r *http.Req
 	uuid := chi.URLParam(r, "uuid")
 
 	if uuid == "" {
-		logger.Log.Info("uuid parameter is missing")
+		logger.FromContext(r.Context()).Info("uuid parameter is missing")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
 			"error": "Missing uuid parameter",
@@ -1030,7 +1049,7 @@ func (oh *featureHandler) UpdateFeatureStatus(w http.ResponseWriter, r *http.Req
 	}
 
 	if r.Body == nil {
-		logger.Log.Info("request body is nil")
+		logger.FromContext(r.Context()).Info("request body is nil")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
 			"error": "Request body is required",
@@ -1043,7 +1062,7 @@ func (oh *featureHandler) UpdateFeatureStatus(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Log.Error("invalid request body", err)
+		logger.FromContext(r.Context()).Error("invalid request body", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -1054,7 +1073,7 @@ func (oh *featureHandler) UpdateFeatureStatus(w http.ResponseWriter, r *http.Req
 		db.CompletedFeature: true,
 		db.BacklogFeature:   true,
 	}[req.Status]; !valid {
-		logger.Log.Info("invalid feature status")
+		logger.FromContext(r.Context()).Info("invalid feature status")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{
 			"error": "Invalid feature status. Allowed values are: active, archived, completed, backlog",
@@ -1062,12 +1081,17 @@ func (oh *featureHandler) UpdateFeatureStatus(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	existing := oh.db.GetFeatureByUuid(uuid)
 	updatedFeature, err := oh.db.UpdateFeatureStatus(uuid, req.Status)
 	if err != nil {
-		logger.Log.Error("failed to update feature status", err)
+		logger.FromContext(r.Context()).Error("failed to update feature status", err)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	emitWebhook(updatedFeature.WorkspaceUuid, webhooks.EventFeatureUpdated, updatedFeature)
+	audit.Record(pubKeyFromAuth, updatedFeature.WorkspaceUuid, audit.EntityFeature, uuid, audit.ActionUpdate,
+		map[string]interface{}{"feat_status": existing.FeatStatus},
+		map[string]interface{}{"feat_status": updatedFeature.FeatStatus})
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updatedFeature)
@@ -1087,7 +1111,7 @@ func (oh *featureHandler) GetQuickBounties(w http.ResponseWriter, r *http.Reques
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1173,7 +1197,7 @@ func (oh *featureHandler) GetQuickTickets(w http.ResponseWriter, r *http.Request
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1243,7 +1267,7 @@ func (oh *featureHandler) CreateOrUpdateFeatureCall(w http.ResponseWriter, r *ht
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1251,7 +1275,7 @@ func (oh *featureHandler) CreateOrUpdateFeatureCall(w http.ResponseWriter, r *ht
 	var req FeatureCallRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Log.Error("invalid request body", err)
+		logger.FromContext(r.Context()).Error("invalid request body", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
 		return
@@ -1259,7 +1283,7 @@ func (oh *featureHandler) CreateOrUpdateFeatureCall(w http.ResponseWriter, r *ht
 
 	workspace := oh.db.GetWorkspaceByUuid(req.WorkspaceID)
 	if workspace.Uuid == "" {
-		logger.Log.Info("workspace not found")
+		logger.FromContext(r.Context()).Info("workspace not found")
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Workspace not found"})
 		return
@@ -1267,7 +1291,7 @@ func (oh *featureHandler) CreateOrUpdateFeatureCall(w http.ResponseWriter, r *ht
 
 	featureCall, err := oh.db.CreateOrUpdateFeatureCall(req.WorkspaceID, req.URL)
 	if err != nil {
-		logger.Log.Error("failed to create/update feature call", err)
+		logger.FromContext(r.Context()).Error("failed to create/update feature call", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -1281,14 +1305,14 @@ func (oh *featureHandler) GetFeatureCall(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	workspaceID := chi.URLParam(r, "workspace_uuid")
 	if workspaceID == "" {
-		logger.Log.Info("missing workspace_uuid parameter")
+		logger.FromContext(r.Context()).Info("missing workspace_uuid parameter")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "workspace_uuid parameter is required"})
 		return
@@ -1296,7 +1320,7 @@ func (oh *featureHandler) GetFeatureCall(w http.ResponseWriter, r *http.Request)
 
 	featureCall, err := oh.db.GetFeatureCallByWorkspaceID(workspaceID)
 	if err != nil {
-		logger.Log.Error("failed to get feature call", err)
+		logger.FromContext(r.Context()).Error("failed to get feature call", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -1310,14 +1334,14 @@ func (oh *featureHandler) DeleteFeatureCall(w http.ResponseWriter, r *http.Reque
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	workspaceID := chi.URLParam(r, "workspace_uuid")
 	if workspaceID == "" {
-		logger.Log.Info("missing workspace_uuid parameter")
+		logger.FromContext(r.Context()).Info("missing workspace_uuid parameter")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "workspace_uuid parameter is required"})
 		return
@@ -1325,7 +1349,7 @@ func (oh *featureHandler) DeleteFeatureCall(w http.ResponseWriter, r *http.Reque
 
 	err := oh.db.DeleteFeatureCall(workspaceID)
 	if err != nil {
-		logger.Log.Error("failed to delete feature call", err)
+		logger.FromContext(r.Context()).Error("failed to delete feature call", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -80,7 +80,7 @@ func DownloadYoutubeFeed(w http.ResponseWriter, r *http.Request) {
 	r.Body.Close()
 	err = json.Unmarshal(body, &youtube_download)
 	if err != nil {
-		logger.Log.Error("[feed] %v", err)
+		logger.FromContext(r.Context()).Error("[feed] %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -191,7 +191,7 @@ func GetPodcast(w http.ResponseWriter, r *http.Request) {
 	episodes, err := getEpisodes(url, feedid)
 
 	if err != nil {
-		logger.Log.Error("[feed] %v", err)
+		logger.FromContext(r.Context()).Error("[feed] %v", err)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
@@ -200,7 +200,7 @@ func GetPodcast(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(podcast)
 	if err != nil {
-		logger.Log.Error("[feed] %v", err)
+		logger.FromContext(r.Context()).Error("[feed] %v", err)
 	}
 }
 
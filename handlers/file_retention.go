@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/audit"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+type fileRetentionHandler struct {
+	db            db.Database
+	userHasAccess func(pubKeyFromAuth string, uuid string, role string) bool
+}
+
+func NewFileRetentionHandler(database db.Database) *fileRetentionHandler {
+	configHandler := db.NewConfigHandler(database)
+	return &fileRetentionHandler{
+		db:            database,
+		userHasAccess: configHandler.UserHasAccess,
+	}
+}
+
+// authorize writes the error response and returns an empty uuid when the
+// caller lacks role on the workspace in the request.
+func (fh *fileRetentionHandler) authorize(w http.ResponseWriter, r *http.Request, role string) (string, string) {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[files] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return "", ""
+	}
+
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	workspace := fh.db.GetWorkspaceByUuid(workspaceUuid)
+	if workspace.Uuid == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Workspace not found"})
+		return "", ""
+	}
+
+	if !fh.userHasAccess(pubKeyFromAuth, workspaceUuid, role) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions"})
+		return "", ""
+	}
+	return pubKeyFromAuth, workspaceUuid
+}
+
+// GetFileRetention godoc
+//
+//	@Summary		Get workspace file retention
+//	@Description	How many days the workspace keeps chat files no active chat refers to before archiving them, and how many days archived files are kept before they are deleted from storage
+//	@Tags			Workspaces
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string	true	"Workspace UUID"
+//	@Success		200				{object}	db.FileRetention
+//	@Router			/workspaces/{workspace_uuid}/files/retention [get]
+func (fh *fileRetentionHandler) GetFileRetention(w http.ResponseWriter, r *http.Request) {
+	_, workspaceUuid := fh.authorize(w, r, db.ViewReport)
+	if workspaceUuid == "" {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(fh.db.GetFileRetention(workspaceUuid))
+}
+
+// UpdateFileRetention godoc
+//
+//	@Summary		Update workspace file retention
+//	@Description	Set the days files stay unreferenced before they are archived, 1 to 3650, and the days archived files are kept before they are deleted from storage, 0 to 3650. A file referenced again before it is deleted is restored.
+//	@Tags			Workspaces
+//	@Accept			json
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string				true	"Workspace UUID"
+//	@Param			retention		body		db.FileRetention	true	"File retention"
+//	@Success		200				{object}	db.FileRetention
+//	@Router			/workspaces/{workspace_uuid}/files/retention [put]
+func (fh *fileRetentionHandler) UpdateFileRetention(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, workspaceUuid := fh.authorize(w, r, db.EditOrg)
+	if workspaceUuid == "" {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	retention := db.FileRetention{}
+	if err := json.Unmarshal(body, &retention); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+	retention.WorkspaceUuid = workspaceUuid
+
+	if err := db.ValidateFileRetention(retention); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	before := fh.db.GetFileRetention(workspaceUuid)
+	saved, err := fh.db.SaveFileRetention(retention)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[files] could not save file retention of %s: %v", workspaceUuid, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to save file retention"})
+		return
+	}
+	audit.Record(pubKeyFromAuth, workspaceUuid, audit.EntityFileRetention, workspaceUuid, audit.ActionUpdate,
+		map[string]interface{}{"archive_after_days": before.ArchiveAfterDays, "purge_after_days": before.PurgeAfterDays},
+		map[string]interface{}{"archive_after_days": saved.ArchiveAfterDays, "purge_after_days": saved.PurgeAfterDays})
+
+	// a shorter retention may already let files go
+	enqueueFileRetention(workspaceUuid)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// GetStorageUsage godoc
+//
+//	@Summary		Get workspace storage usage
+//	@Description	The count and size of the chat files the workspace keeps in storage, active and archived
+//	@Tags			Workspaces
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string	true	"Workspace UUID"
+//	@Success		200				{object}	db.FileStorageUsage
+//	@Router			/workspaces/{workspace_uuid}/files/usage [get]
+func (fh *fileRetentionHandler) GetStorageUsage(w http.ResponseWriter, r *http.Request) {
+	_, workspaceUuid := fh.authorize(w, r, db.ViewReport)
+	if workspaceUuid == "" {
+		return
+	}
+
+	usage, err := fh.db.GetWorkspaceStorageUsage(workspaceUuid)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[files] could not sum the storage usage of %s: %v", workspaceUuid, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to get storage usage"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(usage)
+}
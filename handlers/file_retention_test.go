@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestFileRetentionHandler(t *testing.T, hasAccess bool) (*fileRetentionHandler, *dbMocks.Database) {
+	mockDb := dbMocks.NewDatabase(t)
+	handler := NewFileRetentionHandler(mockDb)
+	handler.userHasAccess = func(pubKeyFromAuth string, uuid string, role string) bool {
+		return hasAccess
+	}
+	return handler, mockDb
+}
+
+func TestGetFileRetention(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Unauthorized without the role", func(t *testing.T) {
+		handler, mockDb := newTestFileRetentionHandler(t, false)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		rr := httptest.NewRecorder()
+
+		handler.GetFileRetention(rr, webhookRequest(http.MethodGet, "/", nil, "someone", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Returns the retention of the workspace", func(t *testing.T) {
+		handler, mockDb := newTestFileRetentionHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetFileRetention", workspace.Uuid).Return(db.FileRetention{
+			WorkspaceUuid:    workspace.Uuid,
+			ArchiveAfterDays: db.DefaultFileArchiveAfterDays,
+			PurgeAfterDays:   db.DefaultFilePurgeAfterDays,
+		})
+		rr := httptest.NewRecorder()
+
+		handler.GetFileRetention(rr, webhookRequest(http.MethodGet, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		retention := db.FileRetention{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &retention))
+		assert.Equal(t, db.DefaultFileArchiveAfterDays, retention.ArchiveAfterDays)
+	})
+}
+
+func TestUpdateFileRetention(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Unauthorized without the role", func(t *testing.T) {
+		handler, mockDb := newTestFileRetentionHandler(t, false)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		rr := httptest.NewRecorder()
+
+		handler.UpdateFileRetention(rr, webhookRequest(http.MethodPut, "/", db.FileRetention{ArchiveAfterDays: 7}, "someone", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	invalid := map[string]db.FileRetention{
+		"archiving at once":   {ArchiveAfterDays: 0, PurgeAfterDays: 30},
+		"a negative grace":    {ArchiveAfterDays: 30, PurgeAfterDays: -1},
+		"more than ten years": {ArchiveAfterDays: 4000, PurgeAfterDays: 30},
+	}
+	for name, retention := range invalid {
+		t.Run("Rejects "+name, func(t *testing.T) {
+			handler, mockDb := newTestFileRetentionHandler(t, true)
+			mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+			rr := httptest.NewRecorder()
+
+			handler.UpdateFileRetention(rr, webhookRequest(http.MethodPut, "/", retention, "owner", params))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+
+	t.Run("Saves the retention of the workspace in the path", func(t *testing.T) {
+		handler, mockDb := newTestFileRetentionHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetFileRetention", workspace.Uuid).Return(db.FileRetention{WorkspaceUuid: workspace.Uuid, ArchiveAfterDays: 30, PurgeAfterDays: 30})
+		mockDb.On("SaveFileRetention", mock.MatchedBy(func(r db.FileRetention) bool {
+			return r.WorkspaceUuid == workspace.Uuid && r.ArchiveAfterDays == 7 && r.PurgeAfterDays == 0
+		})).Return(func(r db.FileRetention) db.FileRetention { return r }, nil).Once()
+		rr := httptest.NewRecorder()
+
+		body := db.FileRetention{WorkspaceUuid: "another-workspace", ArchiveAfterDays: 7, PurgeAfterDays: 0}
+		handler.UpdateFileRetention(rr, webhookRequest(http.MethodPut, "/", body, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestGetStorageUsage(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Returns what the files of the workspace take", func(t *testing.T) {
+		handler, mockDb := newTestFileRetentionHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWorkspaceStorageUsage", workspace.Uuid).Return(db.FileStorageUsage{
+			WorkspaceUuid: workspace.Uuid,
+			Files:         3,
+			Bytes:         3072,
+			ActiveFiles:   2,
+			ActiveBytes:   2048,
+			ArchivedFiles: 1,
+			ArchivedBytes: 1024,
+		}, nil)
+		rr := httptest.NewRecorder()
+
+		handler.GetStorageUsage(rr, webhookRequest(http.MethodGet, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		usage := db.FileStorageUsage{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &usage))
+		assert.Equal(t, int64(3072), usage.Bytes)
+		assert.Equal(t, int64(1), usage.ArchivedFiles)
+	})
+}
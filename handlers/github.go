@@ -40,7 +40,7 @@ func GetGithubIssue(w http.ResponseWriter, r *http.Request) {
 	}
 	issue, err := GetIssue(owner, repo, issueNum)
 	if err != nil {
-		logger.Log.Error("Github error: %v", err)
+		logger.FromContext(r.Context()).Error("Github error: %v", err)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
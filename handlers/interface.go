@@ -1,7 +1,57 @@
 package handlers
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	"github.com/stakwork/sphinx-tribes/workflows"
+)
 
 type HttpClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
+
+// LightningBackend is the node invoices are created on and payments are
+// sent through. Keysend returns an error wrapping lightning.ErrRejected when
+// the node refused the payment, and any other error when the outcome is
+// unknown.
+type LightningBackend interface {
+	CreateInvoice(amount uint, memo string) (db.InvoiceResponse, db.InvoiceError)
+	LookupInvoice(paymentRequest string) (db.InvoiceResult, db.InvoiceError)
+	PayInvoice(paymentRequest string) (db.InvoicePaySuccess, db.InvoicePayError)
+	Keysend(amount uint, pubkey string, routeHint string, memo string) (db.V2SendOnionRes, error)
+	PaymentStatusByTag(tag string) db.V2TagRes
+}
+
+// AssetBackend is implemented by the Lightning backends that also move
+// Taproot assets. SendAsset follows the Keysend contract, its tag resolves
+// through PaymentStatusByTag. LookupAssetTransfer finds a transfer the node
+// received, lightning.ErrTransferNotFound when there is none.
+type AssetBackend interface {
+	SendAsset(assetId uint, amount uint, pubkey string, routeHint string, memo string) (db.V2SendOnionRes, error)
+	LookupAssetTransfer(txid string) (db.AssetTx, error)
+}
+
+// WorkflowProvider runs the AI workflows behind hive chat, builds and
+// ticket and plan reviews. Submit returns workflows.ErrNoCredentials when
+// the provider is not set up and a *workflows.APIError when it refused the
+// job; ParseCallback reads the run status the provider posted back.
+type WorkflowProvider interface {
+	Submit(ctx context.Context, job workflows.Job) (workflows.Run, error)
+	ParseCallback(body []byte) (workflows.Callback, error)
+	Cancel(ctx context.Context, runID string) error
+}
+
+var (
+	_ AssetBackend = (*lightning.FakeNode)(nil)
+
+	_ LightningBackend = (*lightning.Configured)(nil)
+	_ LightningBackend = (*lightning.Relay)(nil)
+	_ LightningBackend = (*lightning.V2Bot)(nil)
+	_ LightningBackend = (*lightning.FakeNode)(nil)
+
+	_ WorkflowProvider = (*workflows.Stakwork)(nil)
+	_ WorkflowProvider = (*workflows.Echo)(nil)
+)
@@ -0,0 +1,518 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/blobstore"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/jobs"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/utils"
+	"github.com/stakwork/sphinx-tribes/vault"
+)
+
+const (
+	// stuckJobOverdue is how far past its run time a pending job has to be
+	// to be listed as stuck.
+	stuckJobOverdue = 15 * time.Minute
+
+	// invoiceLookupGrace is how long after its expiry an invoice is still
+	// looked up when the node cannot be reached, in case it was paid just
+	// before it expired.
+	invoiceLookupGrace = 30 * time.Minute
+
+	defaultStuckJobLimit = 100
+	maxStuckJobLimit     = 500
+)
+
+var (
+	errInvoiceUnpaid    = fmt.Errorf("%w: invoice not paid yet", jobs.ErrWaiting)
+	errPaymentPending   = errors.New("payment still pending")
+	errContactKeyAbsent = errors.New("contact key not exchanged yet")
+)
+
+var (
+	invoiceWatchOptions     = jobs.Options{MaxAttempts: 10, BaseBackoff: 5 * time.Second, MaxBackoff: time.Minute}
+	paymentCheckOptions     = jobs.Options{MaxAttempts: 400, BaseBackoff: time.Minute, MaxBackoff: 30 * time.Minute}
+	notificationSendOptions = jobs.Options{MaxAttempts: 50, BaseBackoff: 30 * time.Second, MaxBackoff: time.Hour}
+	batchPayoutOptions      = jobs.Options{MaxAttempts: 20, BaseBackoff: time.Minute, MaxBackoff: 30 * time.Minute}
+	budgetAlertOptions      = jobs.Options{MaxAttempts: 10, BaseBackoff: 30 * time.Second, MaxBackoff: 10 * time.Minute}
+	secretsRewrapOptions    = jobs.Options{MaxAttempts: 10, BaseBackoff: time.Minute, MaxBackoff: 30 * time.Minute}
+	fileRetentionOptions    = jobs.Options{MaxAttempts: 10, BaseBackoff: time.Minute, MaxBackoff: time.Hour}
+)
+
+type invoiceWatchPayload struct {
+	PaymentRequest string `json:"payment_request"`
+}
+
+type paymentCheckPayload struct {
+	BountyID uint `json:"bounty_id"`
+}
+
+type notificationSendPayload struct {
+	UUID string `json:"uuid"`
+}
+
+type budgetAlertPayload struct {
+	WorkspaceUuid string `json:"workspace_uuid"`
+}
+
+type secretsRewrapPayload struct {
+	KeyID string `json:"key_id"`
+}
+
+type fileRetentionPayload struct {
+	WorkspaceUuid string `json:"workspace_uuid"`
+}
+
+// fileRetentionAction is what the retention job does with a file.
+type fileRetentionAction int
+
+const (
+	keepFile fileRetentionAction = iota
+	archiveFile
+	purgeFile
+)
+
+type jobHandler struct {
+	db               db.Database
+	lightning        LightningBackend
+	getContactKey    func(pubkey string) (*string, error)
+	sendNotification func(pubkey string, content string) string
+	notify           func(pubkey, event, content, alias, routeHint string) string
+	blobs            blobstore.BlobStore
+}
+
+func NewJobHandler(database db.Database) *jobHandler {
+	return &jobHandler{
+		db:               database,
+		lightning:        lightning.FromConfig(http.DefaultClient),
+		getContactKey:    getContactKey,
+		sendNotification: sendNotification,
+		notify:           processNotification,
+		blobs:            newBlobStores(http.DefaultClient),
+	}
+}
+
+// Register sets the handlers of the invoice, payment, notification, batch
+// payout, budget alert, secrets rewrap and file retention jobs on queue.
+func (jh *jobHandler) Register(queue *jobs.Queue) {
+	queue.Register(jobs.TypeInvoiceWatch, jh.WatchInvoice, invoiceWatchOptions)
+	queue.Register(jobs.TypePaymentCheck, jh.CheckPendingPayment, paymentCheckOptions)
+	queue.Register(jobs.TypeNotificationSend, jh.SendWaitingNotification, notificationSendOptions)
+	queue.Register(jobs.TypeBatchPayoutFinish, jh.FinishBatchPayout, batchPayoutOptions)
+	queue.Register(jobs.TypeBudgetAlert, jh.AlertLowBudget, budgetAlertOptions)
+	queue.Register(jobs.TypeSecretsRewrap, jh.RewrapSecrets, secretsRewrapOptions)
+	queue.Register(jobs.TypeFileRetention, jh.ApplyFileRetention, fileRetentionOptions)
+}
+
+// enqueueInvoiceWatch queues a job booking the invoice once it is paid.
+func enqueueInvoiceWatch(paymentRequest string) {
+	if paymentRequest == "" {
+		return
+	}
+	jobs.Enqueue(jobs.TypeInvoiceWatch, paymentRequest, invoiceWatchPayload{PaymentRequest: paymentRequest})
+}
+
+// enqueuePaymentCheck queues a job following the pending payment of a
+// bounty until the node settles or fails it.
+func enqueuePaymentCheck(bountyID uint) {
+	jobs.Enqueue(jobs.TypePaymentCheck, strconv.FormatUint(uint64(bountyID), 10), paymentCheckPayload{BountyID: bountyID})
+}
+
+// enqueueBudgetAlert queues a job alerting the owner of a workspace whose
+// budget fell below its low balance threshold.
+func enqueueBudgetAlert(workspaceUuid string) {
+	if workspaceUuid == "" {
+		return
+	}
+	jobs.Enqueue(jobs.TypeBudgetAlert, workspaceUuid, budgetAlertPayload{WorkspaceUuid: workspaceUuid})
+}
+
+// enqueueFileRetention queues a job counting the references to the files
+// of a workspace and archiving or deleting those it no longer keeps.
+func enqueueFileRetention(workspaceUuid string) {
+	if workspaceUuid == "" {
+		return
+	}
+	jobs.Enqueue(jobs.TypeFileRetention, workspaceUuid, fileRetentionPayload{WorkspaceUuid: workspaceUuid})
+}
+
+// EnqueueFileRetention queues the file retention of every workspace with
+// files in storage.
+func (jh *jobHandler) EnqueueFileRetention() {
+	workspaces, err := jh.db.GetFileAssetWorkspaces()
+	if err != nil {
+		logger.Log.Error("[jobs] could not list the workspaces with files: %v", err)
+		return
+	}
+	for _, workspaceUuid := range workspaces {
+		enqueueFileRetention(workspaceUuid)
+	}
+}
+
+// EnqueueSecretsRewrap queues a job wrapping every stored secret with the
+// active master key, which seals the secrets stored before the vault and
+// finishes a key rotation.
+func (jh *jobHandler) EnqueueSecretsRewrap() {
+	if vault.Default == nil {
+		return
+	}
+	keyID := vault.Default.ActiveKey()
+	jobs.Enqueue(jobs.TypeSecretsRewrap, keyID, secretsRewrapPayload{KeyID: keyID})
+}
+
+// EnqueuePendingPaymentChecks queues a check for every pending bounty
+// payment that has none queued yet, catching up on payments made before
+// the queue existed.
+func (jh *jobHandler) EnqueuePendingPaymentChecks() {
+	for _, payment := range jh.db.GetPendingPaymentHistory() {
+		if payment.BountyId > 0 {
+			enqueuePaymentCheck(payment.BountyId)
+		}
+	}
+}
+
+// EnqueueWaitingNotifications queues a send for every notification waiting
+// on a key exchange.
+func (jh *jobHandler) EnqueueWaitingNotifications() {
+	for _, notification := range jh.db.GetNotificationsByStatus("WAITING_KEY_EXCHANGE") {
+		jobs.Enqueue(jobs.TypeNotificationSend, notification.UUID, notificationSendPayload{UUID: notification.UUID})
+	}
+}
+
+// WatchInvoice books an invoice once it is paid and drops it once it
+// expired unpaid. The watch lasts as long as the invoice: until it expires
+// the job waits, whatever the node answers, so it is never dead-lettered
+// while the invoice can still be paid.
+func (jh *jobHandler) WatchInvoice(job db.Job) error {
+	payload := invoiceWatchPayload{}
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+
+	expiry, err := utils.GetInvoiceExpiry(payload.PaymentRequest)
+	if err != nil {
+		return fmt.Errorf("%w: invalid invoice: %v", jobs.ErrPermanent, err)
+	}
+
+	invoiceRes, invoiceErr := jh.lightning.LookupInvoice(payload.PaymentRequest)
+	if invoiceErr.Error != "" {
+		if time.Now().Before(expiry.Add(invoiceLookupGrace)) {
+			return fmt.Errorf("%w: invoice lookup failed: %s", jobs.ErrWaiting, invoiceErr.Error)
+		}
+		return fmt.Errorf("invoice lookup failed: %s", invoiceErr.Error)
+	}
+
+	if invoiceRes.Response.Settled {
+		settleInvoice(jh.db, payload.PaymentRequest)
+		return nil
+	}
+
+	if time.Now().After(expiry) {
+		jh.db.DeleteInvoice(payload.PaymentRequest)
+		return nil
+	}
+	return errInvoiceUnpaid
+}
+
+// CheckPendingPayment asks the node about the pending payment of a
+// bounty and resolves it as the payment policy of the workspace says.
+// Payments still in flight are checked again later.
+func (jh *jobHandler) CheckPendingPayment(job db.Job) error {
+	payload := paymentCheckPayload{}
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+
+	bounty := jh.db.GetBounty(payload.BountyID)
+	if bounty.ID == 0 {
+		return fmt.Errorf("%w: bounty %d not found", jobs.ErrPermanent, payload.BountyID)
+	}
+
+	if len(bounty.Assignees) > 0 || len(bounty.Milestones) > 0 {
+		return jh.checkPartPayments(bounty)
+	}
+
+	payment := jh.db.GetPaymentByBountyId(bounty.ID)
+	return jh.paymentResolver().Check(bounty, payment)
+}
+
+// checkPartPayments checks the pending payment of every share of a split
+// bounty and of every milestone of a bounty paid by milestones, the job
+// stays queued while any of them is pending.
+func (jh *jobHandler) checkPartPayments(bounty db.NewBounty) error {
+	paymentIDs := []uint{}
+	for _, share := range bounty.Assignees {
+		if share.PaymentStatus == db.PaymentPending {
+			paymentIDs = append(paymentIDs, share.PaymentID)
+		}
+	}
+	for _, milestone := range bounty.Milestones {
+		if milestone.PaymentStatus == db.PaymentPending {
+			paymentIDs = append(paymentIDs, milestone.PaymentID)
+		}
+	}
+
+	pending := false
+	for _, paymentID := range paymentIDs {
+		payment, err := jh.db.GetPaymentHistoryById(paymentID)
+		if err != nil {
+			return err
+		}
+		err = jh.paymentResolver().Check(bounty, payment)
+		if errors.Is(err, errPaymentPending) {
+			pending = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if pending {
+		return errPaymentPending
+	}
+	return nil
+}
+
+func (jh *jobHandler) paymentResolver() *paymentResolver {
+	return &paymentResolver{db: jh.db, lightning: jh.lightning, notify: jh.notify}
+}
+
+// SendWaitingNotification sends a notification once its recipient's
+// contact key is known; until then the job is retried.
+func (jh *jobHandler) SendWaitingNotification(job db.Job) error {
+	payload := notificationSendPayload{}
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+
+	notification, err := jh.db.GetNotification(payload.UUID)
+	if err != nil {
+		return err
+	}
+	if notification == nil || notification.Status != "WAITING_KEY_EXCHANGE" {
+		return nil
+	}
+
+	contactKey, err := jh.getContactKey(notification.PubKey)
+	if err != nil {
+		jh.db.IncrementNotificationRetry(notification.UUID)
+		return err
+	}
+	if contactKey == nil {
+		jh.db.IncrementNotificationRetry(notification.UUID)
+		return errContactKeyAbsent
+	}
+
+	status := jh.sendNotification(notification.PubKey, notification.Content)
+	jh.db.UpdateNotificationStatus(notification.UUID, status)
+	return nil
+}
+
+// AlertLowBudget saves a low balance alert for the owner of a workspace
+// whose budget fell below its threshold and queues the send of it.
+func (jh *jobHandler) AlertLowBudget(job db.Job) error {
+	payload := budgetAlertPayload{}
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+
+	notification, err := jh.db.TrackLowBalance(payload.WorkspaceUuid)
+	if err != nil {
+		return err
+	}
+	if notification != nil {
+		jobs.Enqueue(jobs.TypeNotificationSend, notification.UUID, notificationSendPayload{UUID: notification.UUID})
+	}
+	return nil
+}
+
+// RewrapSecrets wraps the stored secrets with the active master key. A
+// secret that cannot be opened fails the job, so it is retried and ends
+// up in the stuck jobs.
+func (jh *jobHandler) RewrapSecrets(job db.Job) error {
+	payload := secretsRewrapPayload{}
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+	if vault.Default == nil || vault.Default.ActiveKey() != payload.KeyID {
+		// the key was rotated again, the job for the new key does the work
+		return nil
+	}
+
+	changed, err := jh.db.RewrapCodeSpaceSecrets()
+	if err != nil {
+		return err
+	}
+	logger.Log.Info("[jobs] rewrapped the secrets of %d codespace mappings with master key %s", changed, payload.KeyID)
+	return nil
+}
+
+// ApplyFileRetention counts the references to the files of a workspace,
+// archives the files unreferenced for longer than its retention keeps them
+// and deletes the archived ones from storage once their grace period ends.
+// A file referenced again is restored.
+func (jh *jobHandler) ApplyFileRetention(job db.Job) error {
+	payload := fileRetentionPayload{}
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+
+	retention := jh.db.GetFileRetention(payload.WorkspaceUuid)
+	assets, err := jh.db.GetRetainedFileAssets(payload.WorkspaceUuid)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	archived, purged := 0, 0
+	for _, asset := range assets {
+		references, err := jh.db.CountFileAssetReferences(asset)
+		if err != nil {
+			return err
+		}
+		if err := jh.db.UpdateFileAssetReferences(asset.ID, references); err != nil {
+			return err
+		}
+
+		switch fileRetentionActionFor(asset, references, retention, now) {
+		case archiveFile:
+			if err := jh.db.ArchiveFileAsset(asset.ID); err != nil {
+				return err
+			}
+			archived++
+		case purgeFile:
+			err := jh.blobs.Delete(context.Background(), asset.StoragePath)
+			if errors.Is(err, blobstore.ErrNotSupported) {
+				// the meme server keeps its files, only the record goes
+				logger.Log.Warning("[jobs] file %d cannot be deleted from %s, dropping its record only", asset.ID, asset.StoragePath)
+			} else if err != nil && !errors.Is(err, blobstore.ErrNotFound) {
+				return err
+			}
+			if err := jh.db.DeleteFileAsset(asset.ID); err != nil {
+				return err
+			}
+			purged++
+		}
+	}
+
+	if archived > 0 || purged > 0 {
+		logger.Log.Info("[jobs] archived %d and deleted %d files of workspace %s", archived, purged, payload.WorkspaceUuid)
+	}
+	return nil
+}
+
+// fileRetentionActionFor decides what retention does with a file having
+// references references at now. Files referenced are kept, files last
+// referenced before the archive period are archived, and files archived
+// before the purge period are deleted.
+func fileRetentionActionFor(asset db.FileAsset, references int64, retention db.FileRetention, now time.Time) fileRetentionAction {
+	if references > 0 {
+		return keepFile
+	}
+
+	switch asset.Status {
+	case db.ActiveFileStatus:
+		lastReferenced := asset.LastReferenced
+		if lastReferenced.IsZero() {
+			lastReferenced = asset.UploadTime
+		}
+		if !now.Before(lastReferenced.AddDate(0, 0, retention.ArchiveAfterDays)) {
+			return archiveFile
+		}
+	case db.ArchivedFileStatus:
+		archivedAt := asset.UpdatedAt
+		if asset.ArchivedAt != nil {
+			archivedAt = *asset.ArchivedAt
+		}
+		if !now.Before(archivedAt.AddDate(0, 0, retention.PurgeAfterDays)) {
+			return purgeFile
+		}
+	}
+	return keepFile
+}
+
+// FinishBatchPayout releases the reservation of a batch payout whose
+// request never finished it. Finished batches are left as they are.
+func (jh *jobHandler) FinishBatchPayout(job db.Job) error {
+	payload := batchPayoutFinishPayload{}
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+
+	batch, err := jh.db.FinishBatchPayout(payload.BatchID)
+	if err != nil {
+		return err
+	}
+	if batch.Released > 0 {
+		logger.Log.Info("[jobs] released %d sats of batch payout %s", batch.Released, batch.Uuid)
+	}
+	return nil
+}
+
+// GetStuckJobs godoc
+//
+//	@Summary		List stuck jobs
+//	@Description	List dead jobs, running jobs whose lease expired and pending jobs long past their run time
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			limit	query	int	false	"Page size, at most 500"
+//	@Success		200		{array}	db.Job
+//	@Router			/admin/jobs/stuck [get]
+func (jh *jobHandler) GetStuckJobs(w http.ResponseWriter, r *http.Request) {
+	limit := defaultStuckJobLimit
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > maxStuckJobLimit {
+		limit = maxStuckJobLimit
+	}
+
+	stuck, err := jh.db.GetStuckJobs(time.Now(), stuckJobOverdue, limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[jobs] could not load stuck jobs: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to load stuck jobs"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stuck)
+}
+
+// RequeueJob godoc
+//
+//	@Summary		Requeue a dead job
+//	@Description	Give a dead-lettered job a fresh set of attempts
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			id	path		int	true	"Job ID"
+//	@Success		200	{object}	db.Job
+//	@Router			/admin/jobs/{id}/requeue [post]
+func (jh *jobHandler) RequeueJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := jh.db.RequeueJob(uint(id))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
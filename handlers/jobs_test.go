@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stakwork/sphinx-tribes/blobstore"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/jobs"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stakwork/sphinx-tribes/vault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestJobHandler(t *testing.T) (*jobHandler, *dbMocks.Database, *lightning.FakeNode) {
+	mockDb := dbMocks.NewDatabase(t)
+	node := lightning.NewFakeNode()
+	handler := NewJobHandler(mockDb)
+	handler.lightning = node
+	return handler, mockDb, node
+}
+
+func jobWithPayload(t *testing.T, payload interface{}) db.Job {
+	body, err := json.Marshal(payload)
+	assert.NoError(t, err)
+	return db.Job{ID: 1, Payload: string(body), Attempts: 1}
+}
+
+func TestWatchInvoice(t *testing.T) {
+	t.Run("Retries while the invoice is unpaid", func(t *testing.T) {
+		handler, _, node := newTestJobHandler(t)
+		invoice, _ := node.CreateInvoice(1000, "budget")
+
+		err := handler.WatchInvoice(jobWithPayload(t, invoiceWatchPayload{PaymentRequest: invoice.Response.Invoice}))
+
+		assert.ErrorIs(t, err, errInvoiceUnpaid)
+	})
+
+	t.Run("Books a settled budget invoice", func(t *testing.T) {
+		handler, mockDb, node := newTestJobHandler(t)
+		invoice, _ := node.CreateInvoice(1000, "budget")
+		paymentRequest := invoice.Response.Invoice
+		assert.NoError(t, node.Settle(paymentRequest))
+
+		stored := db.NewInvoiceList{PaymentRequest: paymentRequest, Type: "BUDGET", WorkspaceUuid: "workspace-1"}
+		mockDb.On("GetInvoice", paymentRequest).Return(stored)
+		mockDb.On("AddAndUpdateBudget", stored).Return(db.NewPaymentHistory{}).Once()
+		mockDb.On("UpdateInvoice", paymentRequest).Return(stored).Once()
+
+		assert.NoError(t, handler.WatchInvoice(jobWithPayload(t, invoiceWatchPayload{PaymentRequest: paymentRequest})))
+	})
+
+	t.Run("Leaves an invoice that was already booked alone", func(t *testing.T) {
+		handler, mockDb, node := newTestJobHandler(t)
+		invoice, _ := node.CreateInvoice(1000, "budget")
+		paymentRequest := invoice.Response.Invoice
+		assert.NoError(t, node.Settle(paymentRequest))
+
+		mockDb.On("GetInvoice", paymentRequest).Return(db.NewInvoiceList{PaymentRequest: paymentRequest, Type: "BUDGET", Status: true})
+
+		assert.NoError(t, handler.WatchInvoice(jobWithPayload(t, invoiceWatchPayload{PaymentRequest: paymentRequest})))
+	})
+
+	t.Run("Waits while the lookup of a payable invoice fails", func(t *testing.T) {
+		handler, _, _ := newTestJobHandler(t)
+		unknown, _ := lightning.NewFakeNode().CreateInvoice(1000, "budget")
+
+		err := handler.WatchInvoice(jobWithPayload(t, invoiceWatchPayload{PaymentRequest: unknown.Response.Invoice}))
+
+		assert.ErrorIs(t, err, jobs.ErrWaiting)
+		assert.False(t, errors.Is(err, jobs.ErrPermanent))
+	})
+
+	t.Run("Drops an invoice that expired unpaid", func(t *testing.T) {
+		handler, mockDb, node := newTestJobHandler(t)
+		invoice, _ := node.CreateInvoiceAt(1000, "budget", time.Now().Add(-2*time.Hour))
+		paymentRequest := invoice.Response.Invoice
+		mockDb.On("DeleteInvoice", paymentRequest).Return(db.NewInvoiceList{}).Once()
+
+		assert.NoError(t, handler.WatchInvoice(jobWithPayload(t, invoiceWatchPayload{PaymentRequest: paymentRequest})))
+	})
+
+	t.Run("Stops waiting on the node once the invoice is long expired", func(t *testing.T) {
+		handler, _, _ := newTestJobHandler(t)
+		unknown, _ := lightning.NewFakeNode().CreateInvoiceAt(1000, "budget", time.Now().Add(-3*time.Hour))
+		paymentRequest := unknown.Response.Invoice
+
+		err := handler.WatchInvoice(jobWithPayload(t, invoiceWatchPayload{PaymentRequest: paymentRequest}))
+
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, jobs.ErrWaiting))
+	})
+
+	t.Run("Dead-letters an invoice that does not decode", func(t *testing.T) {
+		handler, _, _ := newTestJobHandler(t)
+
+		err := handler.WatchInvoice(jobWithPayload(t, invoiceWatchPayload{PaymentRequest: "lnbcrt1unknown"}))
+
+		assert.ErrorIs(t, err, jobs.ErrPermanent)
+	})
+
+	t.Run("Dead-letters an invalid payload", func(t *testing.T) {
+		handler, _, _ := newTestJobHandler(t)
+
+		err := handler.WatchInvoice(db.Job{Payload: "not json"})
+
+		assert.ErrorIs(t, err, jobs.ErrPermanent)
+	})
+}
+
+func TestCheckPendingPayment(t *testing.T) {
+	bounty := db.NewBounty{ID: 7, WorkspaceUuid: "workspace-1", PaymentPending: true}
+	job := func(t *testing.T) db.Job {
+		return jobWithPayload(t, paymentCheckPayload{BountyID: bounty.ID})
+	}
+	pendingKeysend := func(node *lightning.FakeNode) string {
+		node.SetPaymentOutcome(db.PaymentPending, "")
+		res, _ := node.Keysend(1500, "hunter", "", "bounty")
+		return res.Tag
+	}
+
+	t.Run("Dead-letters a missing bounty", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("GetBounty", bounty.ID).Return(db.NewBounty{})
+
+		assert.ErrorIs(t, handler.CheckPendingPayment(job(t)), jobs.ErrPermanent)
+	})
+
+	t.Run("Stops once the payment is no longer pending", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("GetPaymentByBountyId", bounty.ID).Return(db.NewPaymentHistory{ID: 3, Tag: "tag", PaymentStatus: db.PaymentComplete})
+
+		assert.NoError(t, handler.CheckPendingPayment(job(t)))
+	})
+
+	t.Run("Retries a payment that is still pending", func(t *testing.T) {
+		handler, mockDb, node := newTestJobHandler(t)
+		tag := pendingKeysend(node)
+		created := time.Now().Add(-time.Hour)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("GetPaymentByBountyId", bounty.ID).Return(db.NewPaymentHistory{ID: 3, Tag: tag, PaymentStatus: db.PaymentPending, Created: &created})
+		mockDb.On("GetWorkspacePaymentPolicy", "").Return(db.DefaultPaymentPolicy(""))
+
+		assert.ErrorIs(t, handler.CheckPendingPayment(job(t)), errPaymentPending)
+	})
+
+	t.Run("Marks a completed payment paid", func(t *testing.T) {
+		handler, mockDb, node := newTestJobHandler(t)
+		tag := pendingKeysend(node)
+		assert.NoError(t, node.ResolvePayment(tag, db.PaymentComplete))
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("GetPaymentByBountyId", bounty.ID).Return(db.NewPaymentHistory{ID: 3, Tag: tag, PaymentStatus: db.PaymentPending})
+		mockDb.On("SetPaymentAsComplete", tag).Return(true).Once()
+		mockDb.On("UpdateBountyPaymentStatuses", mock.MatchedBy(func(b db.NewBounty) bool {
+			return b.ID == bounty.ID && b.Paid && !b.PaymentPending && b.PaidDate != nil
+		})).Return(bounty, nil).Once()
+
+		assert.NoError(t, handler.CheckPendingPayment(job(t)))
+	})
+
+	t.Run("Reverses a failed payment", func(t *testing.T) {
+		handler, mockDb, node := newTestJobHandler(t)
+		tag := pendingKeysend(node)
+		assert.NoError(t, node.ResolvePayment(tag, db.PaymentFailed))
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("GetPaymentByBountyId", bounty.ID).Return(db.NewPaymentHistory{ID: 3, Tag: tag, PaymentStatus: db.PaymentPending})
+		mockDb.On("GetWorkspacePaymentPolicy", "").Return(db.DefaultPaymentPolicy(""))
+		mockDb.On("ProcessReversePayments", uint(3)).Return(nil).Once()
+
+		assert.NoError(t, handler.CheckPendingPayment(job(t)))
+	})
+
+	t.Run("Reverses a payment pending for over a week", func(t *testing.T) {
+		handler, mockDb, node := newTestJobHandler(t)
+		tag := pendingKeysend(node)
+		created := time.Now().Add(-8 * 24 * time.Hour)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("GetPaymentByBountyId", bounty.ID).Return(db.NewPaymentHistory{ID: 3, Tag: tag, PaymentStatus: db.PaymentPending, Created: &created})
+		mockDb.On("GetWorkspacePaymentPolicy", "").Return(db.DefaultPaymentPolicy(""))
+		mockDb.On("ProcessReversePayments", uint(3)).Return(nil).Once()
+
+		assert.NoError(t, handler.CheckPendingPayment(job(t)))
+	})
+
+	t.Run("Retries a failed reversal", func(t *testing.T) {
+		handler, mockDb, node := newTestJobHandler(t)
+		tag := pendingKeysend(node)
+		assert.NoError(t, node.ResolvePayment(tag, db.PaymentFailed))
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("GetPaymentByBountyId", bounty.ID).Return(db.NewPaymentHistory{ID: 3, Tag: tag, PaymentStatus: db.PaymentPending})
+		mockDb.On("GetWorkspacePaymentPolicy", "").Return(db.DefaultPaymentPolicy(""))
+		mockDb.On("ProcessReversePayments", uint(3)).Return(errors.New("db down"))
+
+		err := handler.CheckPendingPayment(job(t))
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, jobs.ErrPermanent))
+	})
+}
+
+func TestSendWaitingNotification(t *testing.T) {
+	notification := &db.Notification{UUID: "notification-1", PubKey: "hunter", Content: "hello", Status: "WAITING_KEY_EXCHANGE"}
+	job := func(t *testing.T) db.Job {
+		return jobWithPayload(t, notificationSendPayload{UUID: notification.UUID})
+	}
+
+	t.Run("Retries until the contact key is known", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		handler.getContactKey = func(pubkey string) (*string, error) { return nil, nil }
+		mockDb.On("GetNotification", notification.UUID).Return(notification, nil)
+		mockDb.On("IncrementNotificationRetry", notification.UUID).Return().Once()
+
+		assert.ErrorIs(t, handler.SendWaitingNotification(job(t)), errContactKeyAbsent)
+	})
+
+	t.Run("Sends once the contact key is known", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		key := "contact-key"
+		handler.getContactKey = func(pubkey string) (*string, error) { return &key, nil }
+		handler.sendNotification = func(pubkey string, content string) string {
+			assert.Equal(t, notification.PubKey, pubkey)
+			assert.Equal(t, notification.Content, content)
+			return "COMPLETE"
+		}
+		mockDb.On("GetNotification", notification.UUID).Return(notification, nil)
+		mockDb.On("UpdateNotificationStatus", notification.UUID, "COMPLETE").Return().Once()
+
+		assert.NoError(t, handler.SendWaitingNotification(job(t)))
+	})
+
+	t.Run("Skips notifications no longer waiting", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		handler.getContactKey = func(pubkey string) (*string, error) {
+			t.Fatal("contact key looked up for a sent notification")
+			return nil, nil
+		}
+		mockDb.On("GetNotification", notification.UUID).Return(&db.Notification{UUID: notification.UUID, Status: "COMPLETE"}, nil)
+
+		assert.NoError(t, handler.SendWaitingNotification(job(t)))
+	})
+}
+
+func TestFinishBatchPayoutJob(t *testing.T) {
+	t.Run("Releases an unfinished batch", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("FinishBatchPayout", uint(9)).Return(db.BatchPayout{ID: 9, Released: 500, Status: db.BatchPayoutFinished}, nil).Once()
+
+		assert.NoError(t, handler.FinishBatchPayout(jobWithPayload(t, batchPayoutFinishPayload{BatchID: 9})))
+	})
+
+	t.Run("Retries when the release fails", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("FinishBatchPayout", uint(9)).Return(db.BatchPayout{}, errors.New("db down"))
+
+		assert.Error(t, handler.FinishBatchPayout(jobWithPayload(t, batchPayoutFinishPayload{BatchID: 9})))
+	})
+}
+
+func TestAlertLowBudget(t *testing.T) {
+	job := func(t *testing.T) db.Job {
+		return jobWithPayload(t, budgetAlertPayload{WorkspaceUuid: "workspace-1"})
+	}
+
+	t.Run("Saves the alert when the budget is low", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("TrackLowBalance", "workspace-1").Return(&db.Notification{UUID: "notification-1", Event: db.BudgetLowEvent}, nil).Once()
+
+		assert.NoError(t, handler.AlertLowBudget(job(t)))
+	})
+
+	t.Run("Does nothing when no alert is due", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("TrackLowBalance", "workspace-1").Return(nil, nil).Once()
+
+		assert.NoError(t, handler.AlertLowBudget(job(t)))
+	})
+
+	t.Run("Retries when the balance cannot be checked", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("TrackLowBalance", "workspace-1").Return(nil, errors.New("db down"))
+
+		assert.Error(t, handler.AlertLowBudget(job(t)))
+	})
+}
+
+func TestGetStuckJobs(t *testing.T) {
+	t.Run("Lists stuck jobs", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		stuck := []db.Job{{ID: 4, Type: jobs.TypePaymentCheck, Status: db.JobDead}}
+		mockDb.On("GetStuckJobs", mock.Anything, stuckJobOverdue, defaultStuckJobLimit).Return(stuck, nil)
+		rr := httptest.NewRecorder()
+
+		handler.GetStuckJobs(rr, webhookRequest(http.MethodGet, "/", nil, "admin", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		got := []db.Job{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+		assert.Equal(t, stuck[0].ID, got[0].ID)
+	})
+
+	t.Run("Caps the limit", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("GetStuckJobs", mock.Anything, stuckJobOverdue, maxStuckJobLimit).Return([]db.Job{}, nil)
+		rr := httptest.NewRecorder()
+
+		handler.GetStuckJobs(rr, webhookRequest(http.MethodGet, "/?limit=10000", nil, "admin", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Reports store failures", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("GetStuckJobs", mock.Anything, stuckJobOverdue, defaultStuckJobLimit).Return([]db.Job{}, errors.New("db down"))
+		rr := httptest.NewRecorder()
+
+		handler.GetStuckJobs(rr, webhookRequest(http.MethodGet, "/", nil, "admin", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestRequeueJob(t *testing.T) {
+	t.Run("Rejects an invalid id", func(t *testing.T) {
+		handler, _, _ := newTestJobHandler(t)
+		rr := httptest.NewRecorder()
+
+		handler.RequeueJob(rr, webhookRequest(http.MethodPost, "/", nil, "admin", map[string]string{"id": "abc"}))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Not found for jobs that are not dead", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("RequeueJob", uint(4)).Return(db.Job{}, errors.New("job not found or not dead"))
+		rr := httptest.NewRecorder()
+
+		handler.RequeueJob(rr, webhookRequest(http.MethodPost, "/", nil, "admin", map[string]string{"id": "4"}))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Requeues a dead job", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("RequeueJob", uint(4)).Return(db.Job{ID: 4, Status: db.JobPending}, nil)
+		rr := httptest.NewRecorder()
+
+		handler.RequeueJob(rr, webhookRequest(http.MethodPost, "/", nil, "admin", map[string]string{"id": "4"}))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		got := db.Job{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+		assert.Equal(t, db.JobPending, got.Status)
+	})
+}
+
+func TestRewrapSecrets(t *testing.T) {
+	original := vault.Default
+	defer func() { vault.Default = original }()
+	testVault, err := vault.New([]string{"k2"}, [][]byte{[]byte("0123456789abcdef0123456789abcdef")})
+	assert.NoError(t, err)
+	vault.Default = testVault
+
+	t.Run("Rewraps the codespace secrets", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("RewrapCodeSpaceSecrets").Return(2, nil).Once()
+
+		assert.NoError(t, handler.RewrapSecrets(jobWithPayload(t, secretsRewrapPayload{KeyID: "k2"})))
+	})
+
+	t.Run("Leaves a rotated away key to the job of the new one", func(t *testing.T) {
+		handler, _, _ := newTestJobHandler(t)
+
+		assert.NoError(t, handler.RewrapSecrets(jobWithPayload(t, secretsRewrapPayload{KeyID: "k1"})))
+	})
+
+	t.Run("Retries when a secret cannot be rewrapped", func(t *testing.T) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		mockDb.On("RewrapCodeSpaceSecrets").Return(0, vault.ErrUnknownKey).Once()
+
+		assert.Error(t, handler.RewrapSecrets(jobWithPayload(t, secretsRewrapPayload{KeyID: "k2"})))
+	})
+}
+
+func TestApplyFileRetention(t *testing.T) {
+	now := time.Now()
+	retention := db.FileRetention{WorkspaceUuid: "workspace-1", ArchiveAfterDays: 30, PurgeAfterDays: 7}
+	payload := fileRetentionPayload{WorkspaceUuid: "workspace-1"}
+
+	newHandler := func(t *testing.T) (*jobHandler, *dbMocks.Database, string) {
+		handler, mockDb, _ := newTestJobHandler(t)
+		dir := t.TempDir()
+		handler.blobs = &blobstore.Stores{Current: blobstore.NewLocal(dir, "https://example.com/hivechat/file/download", []byte("signing key"))}
+		mockDb.On("GetFileRetention", "workspace-1").Return(retention)
+		return handler, mockDb, dir
+	}
+
+	t.Run("Archives a file unreferenced past the archive period", func(t *testing.T) {
+		handler, mockDb, _ := newHandler(t)
+		asset := db.FileAsset{ID: 1, Status: db.ActiveFileStatus, LastReferenced: now.AddDate(0, 0, -31)}
+		mockDb.On("GetRetainedFileAssets", "workspace-1").Return([]db.FileAsset{asset}, nil)
+		mockDb.On("CountFileAssetReferences", asset).Return(int64(0), nil)
+		mockDb.On("UpdateFileAssetReferences", uint(1), int64(0)).Return(nil)
+		mockDb.On("ArchiveFileAsset", uint(1)).Return(nil).Once()
+
+		assert.NoError(t, handler.ApplyFileRetention(jobWithPayload(t, payload)))
+	})
+
+	t.Run("Keeps a file a chat refers to", func(t *testing.T) {
+		handler, mockDb, _ := newHandler(t)
+		asset := db.FileAsset{ID: 2, Status: db.ArchivedFileStatus, LastReferenced: now.AddDate(0, -6, 0), ArchivedAt: &now}
+		mockDb.On("GetRetainedFileAssets", "workspace-1").Return([]db.FileAsset{asset}, nil)
+		mockDb.On("CountFileAssetReferences", asset).Return(int64(2), nil)
+		mockDb.On("UpdateFileAssetReferences", uint(2), int64(2)).Return(nil).Once()
+
+		assert.NoError(t, handler.ApplyFileRetention(jobWithPayload(t, payload)))
+		mockDb.AssertNotCalled(t, "ArchiveFileAsset", mock.Anything)
+		mockDb.AssertNotCalled(t, "DeleteFileAsset", mock.Anything)
+	})
+
+	t.Run("Deletes an archived file from storage after the grace period", func(t *testing.T) {
+		handler, mockDb, dir := newHandler(t)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "old.pdf"), []byte("%PDF-1.7"), 0644))
+		archivedAt := now.AddDate(0, 0, -8)
+		asset := db.FileAsset{ID: 3, Status: db.ArchivedFileStatus, StoragePath: "local://old.pdf", ArchivedAt: &archivedAt}
+		mockDb.On("GetRetainedFileAssets", "workspace-1").Return([]db.FileAsset{asset}, nil)
+		mockDb.On("CountFileAssetReferences", asset).Return(int64(0), nil)
+		mockDb.On("UpdateFileAssetReferences", uint(3), int64(0)).Return(nil)
+		mockDb.On("DeleteFileAsset", uint(3)).Return(nil).Once()
+
+		assert.NoError(t, handler.ApplyFileRetention(jobWithPayload(t, payload)))
+		_, err := os.Stat(filepath.Join(dir, "old.pdf"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Drops the record of a file the store cannot delete", func(t *testing.T) {
+		handler, mockDb, _ := newHandler(t)
+		archivedAt := now.AddDate(0, 0, -8)
+		asset := db.FileAsset{ID: 4, Status: db.ArchivedFileStatus, StoragePath: "https://memes.sphinx.chat/public/abc", ArchivedAt: &archivedAt}
+		mockDb.On("GetRetainedFileAssets", "workspace-1").Return([]db.FileAsset{asset}, nil)
+		mockDb.On("CountFileAssetReferences", asset).Return(int64(0), nil)
+		mockDb.On("UpdateFileAssetReferences", uint(4), int64(0)).Return(nil)
+		mockDb.On("DeleteFileAsset", uint(4)).Return(nil).Once()
+
+		assert.NoError(t, handler.ApplyFileRetention(jobWithPayload(t, payload)))
+	})
+}
+
+func TestFileRetentionActionFor(t *testing.T) {
+	now := time.Now()
+	retention := db.FileRetention{ArchiveAfterDays: 30, PurgeAfterDays: 0}
+	recently := now.AddDate(0, 0, -1)
+
+	assert.Equal(t, keepFile, fileRetentionActionFor(db.FileAsset{Status: db.ActiveFileStatus, LastReferenced: now.AddDate(0, 0, -29)}, 0, retention, now))
+	assert.Equal(t, archiveFile, fileRetentionActionFor(db.FileAsset{Status: db.ActiveFileStatus, UploadTime: now.AddDate(0, 0, -30)}, 0, retention, now))
+	assert.Equal(t, purgeFile, fileRetentionActionFor(db.FileAsset{Status: db.ArchivedFileStatus, ArchivedAt: &recently}, 0, retention, now))
+	assert.Equal(t, keepFile, fileRetentionActionFor(db.FileAsset{Status: db.ArchivedFileStatus, ArchivedAt: &recently}, 1, retention, now))
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+type ledgerHandler struct {
+	db            db.Database
+	userHasAccess func(pubKeyFromAuth string, uuid string, role string) bool
+}
+
+func NewLedgerHandler(database db.Database) *ledgerHandler {
+	configHandler := db.NewConfigHandler(database)
+	return &ledgerHandler{
+		db:            database,
+		userHasAccess: configHandler.UserHasAccess,
+	}
+}
+
+// authorize writes the error response and returns an empty uuid when the
+// caller may not view the ledger of the workspace in the request.
+func (lh *ledgerHandler) authorize(w http.ResponseWriter, r *http.Request) string {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[ledger] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return ""
+	}
+
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	workspace := lh.db.GetWorkspaceByUuid(workspaceUuid)
+	if workspace.Uuid == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Workspace not found"})
+		return ""
+	}
+
+	if !lh.userHasAccess(pubKeyFromAuth, workspaceUuid, db.ViewReport) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions to view the ledger"})
+		return ""
+	}
+	return workspaceUuid
+}
+
+// GetLedgerBalances godoc
+//
+//	@Summary		Get workspace ledger balances
+//	@Description	Balances of the workspace, escrow, hunter and external ledger accounts, derived from the ledger entries
+//	@Tags			Workspaces
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path	string	true	"Workspace UUID"
+//	@Success		200				{array}	db.LedgerBalance
+//	@Router			/workspaces/{workspace_uuid}/ledger [get]
+func (lh *ledgerHandler) GetLedgerBalances(w http.ResponseWriter, r *http.Request) {
+	workspaceUuid := lh.authorize(w, r)
+	if workspaceUuid == "" {
+		return
+	}
+
+	balances, err := lh.db.GetLedgerBalances(workspaceUuid)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[ledger] could not derive balances of %s: %v", workspaceUuid, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to load ledger balances"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(balances)
+}
+
+// ReconcileLedger godoc
+//
+//	@Summary		Reconcile workspace ledger
+//	@Description	Compare the budget derived from the ledger with the stored workspace budget and flag any drift or unbalanced transactions
+//	@Tags			Workspaces
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string	true	"Workspace UUID"
+//	@Success		200				{object}	db.LedgerReconciliation
+//	@Router			/workspaces/{workspace_uuid}/ledger/reconcile [get]
+func (lh *ledgerHandler) ReconcileLedger(w http.ResponseWriter, r *http.Request) {
+	workspaceUuid := lh.authorize(w, r)
+	if workspaceUuid == "" {
+		return
+	}
+
+	report, err := lh.db.ReconcileLedger(workspaceUuid)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[ledger] could not reconcile %s: %v", workspaceUuid, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to reconcile ledger"})
+		return
+	}
+
+	if !report.InSync {
+		logger.FromContext(r.Context()).Warning("[ledger] workspace %s drifted by %d sats, %d unbalanced transactions",
+			workspaceUuid, report.Drift, len(report.UnbalancedTransactions))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
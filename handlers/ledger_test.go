@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLedgerHandler(t *testing.T, hasAccess bool) (*ledgerHandler, *dbMocks.Database) {
+	mockDb := dbMocks.NewDatabase(t)
+	handler := NewLedgerHandler(mockDb)
+	handler.userHasAccess = func(pubKeyFromAuth string, uuid string, role string) bool {
+		assert.Equal(t, db.ViewReport, role)
+		return hasAccess
+	}
+	return handler, mockDb
+}
+
+func TestGetLedgerBalances(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Unauthorized without pubkey", func(t *testing.T) {
+		handler, _ := newTestLedgerHandler(t, true)
+		rr := httptest.NewRecorder()
+
+		handler.GetLedgerBalances(rr, webhookRequest(http.MethodGet, "/", nil, "", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Not found for unknown workspace", func(t *testing.T) {
+		handler, mockDb := newTestLedgerHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(db.Workspace{})
+		rr := httptest.NewRecorder()
+
+		handler.GetLedgerBalances(rr, webhookRequest(http.MethodGet, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Returns derived balances", func(t *testing.T) {
+		handler, mockDb := newTestLedgerHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetLedgerBalances", workspace.Uuid).Return([]db.LedgerBalance{
+			{AccountID: 1, Kind: db.LedgerExternal, Balance: -5000},
+			{AccountID: 2, Kind: db.LedgerHunter, Owner: "hunter", Balance: 1500},
+			{AccountID: 3, Kind: db.LedgerWorkspace, Balance: 3500},
+		}, nil)
+		rr := httptest.NewRecorder()
+
+		handler.GetLedgerBalances(rr, webhookRequest(http.MethodGet, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		balances := []db.LedgerBalance{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &balances))
+		assert.Len(t, balances, 3)
+		assert.Equal(t, "hunter", balances[1].Owner)
+	})
+}
+
+func TestReconcileLedger(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Forbidden without view role", func(t *testing.T) {
+		handler, mockDb := newTestLedgerHandler(t, false)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		rr := httptest.NewRecorder()
+
+		handler.ReconcileLedger(rr, webhookRequest(http.MethodGet, "/", nil, "member", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Flags drift", func(t *testing.T) {
+		handler, mockDb := newTestLedgerHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("ReconcileLedger", workspace.Uuid).Return(db.LedgerReconciliation{
+			WorkspaceUuid:          workspace.Uuid,
+			StoredBudget:           4000,
+			LedgerBudget:           3500,
+			Drift:                  500,
+			UnbalancedTransactions: []string{},
+		}, nil)
+		rr := httptest.NewRecorder()
+
+		handler.ReconcileLedger(rr, webhookRequest(http.MethodGet, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		report := db.LedgerReconciliation{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+		assert.False(t, report.InSync)
+		assert.Equal(t, int64(500), report.Drift)
+	})
+
+	t.Run("Internal error when reconciliation fails", func(t *testing.T) {
+		handler, mockDb := newTestLedgerHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("ReconcileLedger", workspace.Uuid).Return(db.LedgerReconciliation{}, errors.New("db down"))
+		rr := httptest.NewRecorder()
+
+		handler.ReconcileLedger(rr, webhookRequest(http.MethodGet, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/handlers/mocks"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stakwork/sphinx-tribes/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newFakeNodeBountyHandler(t *testing.T) (*bountyHandler, *dbMocks.Database, *lightning.FakeNode) {
+	mockDb := dbMocks.NewDatabase(t)
+	node := lightning.NewFakeNode()
+
+	handler := NewBountyHandler(mocks.NewHttpClient(t), mockDb)
+	handler.lightning = node
+	handler.getInvoiceStatusByTag = node.PaymentStatusByTag
+	handler.userHasAccess = func(pubKeyFromAuth string, uuid string, role string) bool { return true }
+	handler.getSocketConnections = func(host string) (db.Client, error) { return db.Client{}, errors.New("no socket") }
+	return handler, mockDb, node
+}
+
+func TestFakeNodeBountyPayment(t *testing.T) {
+	bounty := db.NewBounty{ID: 1, Price: 1500, WorkspaceUuid: "workspace-1", Assignee: "hunter"}
+	hunter := db.Person{OwnerPubKey: "hunter", OwnerRouteHint: "hunter-route"}
+	params := map[string]string{"id": "1"}
+
+	expectPayment := func(mockDb *dbMocks.Database) {
+		mockDb.On("GetBounty", bounty.ID).Return(bounty).Once()
+		mockDb.On("GetWorkspaceBudget", bounty.WorkspaceUuid).Return(db.NewBountyBudget{TotalBudget: 5000})
+		mockDb.On("CheckSpendLimit", bounty.WorkspaceUuid, "owner", db.PayBounty, bounty.Price).Return(nil)
+		mockDb.On("GetPersonByPubkey", hunter.OwnerPubKey).Return(hunter)
+	}
+
+	t.Run("Completed keysend pays the bounty", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		expectPayment(mockDb)
+		mockDb.On("ProcessBountyPayment", mock.MatchedBy(func(payment db.NewPaymentHistory) bool {
+			return payment.PaymentStatus == db.PaymentComplete && payment.Amount == bounty.Price
+		}), mock.MatchedBy(func(b db.NewBounty) bool { return b.Paid })).Return(nil)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBountyPayment(rr, webhookRequest(http.MethodPost, "/", db.BountyPayRequest{}, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		payments := node.Payments()
+		assert.Len(t, payments, 1)
+		assert.Equal(t, hunter.OwnerPubKey, payments[0].Pubkey)
+		assert.Equal(t, bounty.Price, payments[0].Amount)
+	})
+
+	t.Run("Pending keysend settles through the payment status check", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		node.SetPaymentOutcome(db.PaymentPending, "")
+		expectPayment(mockDb)
+
+		var pending db.NewPaymentHistory
+		mockDb.On("ProcessBountyPayment", mock.MatchedBy(func(payment db.NewPaymentHistory) bool {
+			return payment.PaymentStatus == db.PaymentPending
+		}), mock.MatchedBy(func(b db.NewBounty) bool { return b.PaymentPending && !b.Paid })).
+			Run(func(args mock.Arguments) { pending = args.Get(0).(db.NewPaymentHistory) }).
+			Return(nil)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBountyPayment(rr, webhookRequest(http.MethodPost, "/", db.BountyPayRequest{}, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotEmpty(t, pending.Tag)
+
+		assert.NoError(t, node.ResolvePayment(pending.Tag, db.PaymentComplete))
+		pendingBounty := bounty
+		pendingBounty.PaymentPending = true
+		mockDb.On("GetBounty", bounty.ID).Return(pendingBounty).Once()
+		mockDb.On("GetPaymentByBountyId", bounty.ID).Return(pending)
+		mockDb.On("SetPaymentAsComplete", pending.Tag).Return(true)
+		mockDb.On("UpdateBountyPaymentStatuses", mock.MatchedBy(func(b db.NewBounty) bool {
+			return b.Paid && !b.PaymentPending
+		})).Return(pendingBounty, nil)
+		rr = httptest.NewRecorder()
+
+		handler.UpdateBountyPaymentStatus(rr, webhookRequest(http.MethodPut, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		response := map[string]string{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.Equal(t, db.PaymentComplete, response["payment_status"])
+	})
+
+	t.Run("Failed keysend marks the bounty payment as failed", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		node.SetPaymentOutcome(db.PaymentFailed, "no route")
+		expectPayment(mockDb)
+		mockDb.On("AddPaymentHistory", mock.MatchedBy(func(payment db.NewPaymentHistory) bool {
+			return payment.PaymentStatus == db.PaymentFailed && payment.Error == "no route"
+		})).Return(db.NewPaymentHistory{})
+		mockDb.On("UpdateBounty", mock.MatchedBy(func(b db.NewBounty) bool {
+			return b.PaymentFailed && !b.Paid
+		})).Return(bounty, nil)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBountyPayment(rr, webhookRequest(http.MethodPost, "/", db.BountyPayRequest{}, "owner", params))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestFakeNodeBudgetDeposit(t *testing.T) {
+	mockDb := dbMocks.NewDatabase(t)
+	node := lightning.NewFakeNode()
+
+	tHandler := NewTribeHandler(mockDb)
+	tHandler.lightning = node
+	bHandler := NewBountyHandler(mocks.NewHttpClient(t), mockDb)
+	bHandler.lightning = node
+
+	var created db.NewInvoiceList
+	mockDb.On("ProcessBudgetInvoice", mock.MatchedBy(func(payment db.NewPaymentHistory) bool {
+		return payment.Amount == 2000 && payment.WorkspaceUuid == "workspace-1"
+	}), mock.Anything).
+		Run(func(args mock.Arguments) { created = args.Get(1).(db.NewInvoiceList) }).
+		Return(nil)
+	rr := httptest.NewRecorder()
+
+	tHandler.GenerateBudgetInvoice(rr, webhookRequest(http.MethodPost, "/", db.BudgetInvoiceRequest{
+		Amount:        2000,
+		SenderPubKey:  "owner",
+		WorkspaceUuid: "workspace-1",
+		PaymentType:   db.Deposit,
+	}, "owner", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	response := db.InvoiceResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	paymentRequest := response.Response.Invoice
+	assert.Equal(t, paymentRequest, created.PaymentRequest)
+	assert.Equal(t, uint(2000), utils.GetInvoiceAmount(paymentRequest))
+
+	poll := func() db.InvoiceResult {
+		rr := httptest.NewRecorder()
+		bHandler.PollInvoice(rr, webhookRequest(http.MethodGet, "/", nil, "owner", map[string]string{"paymentRequest": paymentRequest}))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		result := db.InvoiceResult{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+		return result
+	}
+
+	assert.False(t, poll().Response.Settled)
+
+	assert.NoError(t, node.Settle(paymentRequest))
+	mockDb.On("GetInvoice", paymentRequest).Return(created)
+	mockDb.On("AddAndUpdateBudget", created).Return(db.NewPaymentHistory{})
+	mockDb.On("UpdateInvoice", paymentRequest).Return(created)
+
+	assert.True(t, poll().Response.Settled)
+}
+
+func TestFakeNodeBudgetWithdraw(t *testing.T) {
+	handler, mockDb, node := newFakeNodeBountyHandler(t)
+	recipient := lightning.NewFakeNode()
+	invoice, invoiceErr := recipient.CreateInvoice(800, "withdraw")
+	assert.Empty(t, invoiceErr.Error)
+	paymentRequest := invoice.Response.Invoice
+
+	mockDb.On("GetLastWithdrawal", "workspace-1").Return(db.NewPaymentHistory{})
+	mockDb.On("GetWorkspaceBudget", "workspace-1").Return(db.NewBountyBudget{TotalBudget: 5000})
+	mockDb.On("CheckSpendLimit", "workspace-1", "owner", db.WithdrawBudget, uint(800)).Return(nil)
+	mockDb.On("GetSumOfWithdrawal", "workspace-1").Return(uint(0))
+	mockDb.On("GetSumOfDeposits", "workspace-1").Return(uint(5000))
+	mockDb.On("WithdrawBudget", "owner", "workspace-1", uint(800)).Return()
+	rr := httptest.NewRecorder()
+
+	handler.BountyBudgetWithdraw(rr, webhookRequest(http.MethodPost, "/", db.NewWithdrawBudgetRequest{
+		PaymentRequest: paymentRequest,
+		WorkspaceUuid:  "workspace-1",
+	}, "owner", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	payments := node.Payments()
+	assert.Len(t, payments, 1)
+	assert.Equal(t, recipient.Pubkey(), payments[0].Pubkey)
+	assert.Equal(t, uint(800), payments[0].Amount)
+}
+
+func TestFakeNodeWorkspacePendingPayments(t *testing.T) {
+	mockDb := dbMocks.NewDatabase(t)
+	node := lightning.NewFakeNode()
+	node.SetPaymentOutcome(db.PaymentPending, "")
+	sent, err := node.Keysend(1500, "hunter", "", "bounty")
+	assert.NoError(t, err)
+	assert.NoError(t, node.ResolvePayment(sent.Tag, db.PaymentComplete))
+
+	handler := &workspaceHandler{db: mockDb, getInvoiceStatusByTag: node.PaymentStatusByTag}
+	bounty := db.NewBounty{ID: 1, Price: 1500, WorkspaceUuid: "workspace-1", PaymentPending: true}
+	mockDb.On("GetWorkspacePendingPayments", "workspace-1").Return([]db.NewPaymentHistory{{ID: 1, BountyId: 1, Tag: sent.Tag}})
+	mockDb.On("SetPaymentAsComplete", sent.Tag).Return(true)
+	mockDb.On("GetBounty", uint(1)).Return(bounty)
+	mockDb.On("UpdateBounty", mock.MatchedBy(func(b db.NewBounty) bool {
+		return b.Paid && !b.PaymentPending
+	})).Return(bounty, nil)
+	rr := httptest.NewRecorder()
+
+	handler.UpdateWorkspacePendingPayments(rr, webhookRequest(http.MethodPut, "/", nil, "owner", map[string]string{"workspace_uuid": "workspace-1"}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
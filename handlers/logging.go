@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+type LogSettings struct {
+	Level  string `json:"level"`
+	Format string `json:"format,omitempty"`
+}
+
+// GetLogLevel godoc
+//
+//	@Summary		Get log level
+//	@Description	Get the current log level and output format
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		SuperAdminAuth
+//	@Success		200	{object}	LogSettings
+//	@Router			/admin/log-level [get]
+func GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(LogSettings{
+		Level:  logger.Level(),
+		Format: config.LogFormat,
+	})
+}
+
+// SetLogLevel godoc
+//
+//	@Summary		Set log level
+//	@Description	Change the log level without restarting the server
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body	LogSettings	true	"New log level"
+//	@Security		SuperAdminAuth
+//	@Success		200	{object}	LogSettings
+//	@Failure		400	{string}	string	"Invalid log level"
+//	@Router			/admin/log-level [put]
+func SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode("Error reading request body")
+		return
+	}
+
+	settings := LogSettings{}
+	if err := json.Unmarshal(body, &settings); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode("Error parsing request body")
+		return
+	}
+
+	if err := logger.SetLevel(settings.Level); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err.Error())
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("Log level changed to %s", logger.Level())
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(LogSettings{
+		Level:  logger.Level(),
+		Format: config.LogFormat,
+	})
+}
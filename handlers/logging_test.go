@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLogLevel(t *testing.T) {
+	originalLogLevel := config.LogLevel
+	defer func() { config.LogLevel = originalLogLevel }()
+	config.LogLevel = "WARNING"
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin/log-level", nil)
+	http.HandlerFunc(GetLogLevel).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var settings LogSettings
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &settings))
+	assert.Equal(t, "WARNING", settings.Level)
+}
+
+func TestSetLogLevel(t *testing.T) {
+	originalLogLevel := config.LogLevel
+	defer func() { config.LogLevel = originalLogLevel }()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedLevel  string
+	}{
+		{
+			name:           "Valid Level",
+			body:           `{"level":"error"}`,
+			expectedStatus: http.StatusOK,
+			expectedLevel:  "ERROR",
+		},
+		{
+			name:           "Invalid Level",
+			body:           `{"level":"loud"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedLevel:  "INFO",
+		},
+		{
+			name:           "Invalid JSON",
+			body:           `{"level":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedLevel:  "INFO",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.LogLevel = "INFO"
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/admin/log-level", bytes.NewBufferString(tt.body))
+			http.HandlerFunc(SetLogLevel).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedLevel, config.LogLevel)
+		})
+	}
+}
@@ -35,7 +35,7 @@ func MemeImageUpload(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -73,7 +73,7 @@ func MemeImageUpload(w http.ResponseWriter, r *http.Request) {
 
 	if mErr != "" {
 		msg := "Could not get meme token"
-		logger.Log.Error("%s: %s", msg, mErr)
+		logger.FromContext(r.Context()).Error("%s: %s", msg, mErr)
 		w.WriteHeader(http.StatusNoContent)
 		json.NewEncoder(w).Encode(msg)
 	} else {
@@ -85,7 +85,7 @@ func MemeImageUpload(w http.ResponseWriter, r *http.Request) {
 		}
 
 		msg := "Could not get meme image"
-		logger.Log.Error("%s", msg)
+		logger.FromContext(r.Context()).Error("%s", msg)
 		w.WriteHeader(http.StatusNoContent)
 		json.NewEncoder(w).Encode(msg)
 	}
@@ -50,7 +50,7 @@ func PaymentMetrics(w http.ResponseWriter, r *http.Request) {
 	workspace := keys.Get("workspace")
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -88,7 +88,7 @@ func WorkspacetMetrics(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -126,7 +126,7 @@ func PeopleMetrics(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -174,7 +174,7 @@ func (mh *metricHandler) BountyMetrics(w http.ResponseWriter, r *http.Request) {
 	workspace := keys.Get("workspace")
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -210,7 +210,7 @@ func (mh *metricHandler) BountyMetrics(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else {
-		logger.Log.Info("Redis client is not initialized or there is an error with Redis")
+		logger.FromContext(r.Context()).Info("Redis client is not initialized or there is an error with Redis")
 	}
 
 	totalBountiesPosted := mh.db.TotalBountiesPosted(request, workspace)
@@ -247,7 +247,7 @@ func (mh *metricHandler) BountyMetrics(w http.ResponseWriter, r *http.Request) {
 		metricsMap := structs.Map(bountyMetrics)
 		db.SetMap(metricsKey, metricsMap)
 	} else {
-		logger.Log.Info("Redis client is not initialized or there is an error with Redis")
+		logger.FromContext(r.Context()).Info("Redis client is not initialized or there is an error with Redis")
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -270,7 +270,7 @@ func (mh *metricHandler) MetricsBounties(w http.ResponseWriter, r *http.Request)
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -309,7 +309,7 @@ func (mh *metricHandler) MetricsBountiesCount(w http.ResponseWriter, r *http.Req
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -346,7 +346,7 @@ func (mh *metricHandler) MetricsBountiesProviders(w http.ResponseWriter, r *http
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -383,7 +383,7 @@ func MetricsCsv(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -409,7 +409,7 @@ func MetricsCsv(w http.ResponseWriter, r *http.Request) {
 		err, url := UploadMetricsCsv(result, request)
 
 		if err != nil {
-			logger.Log.Error("Error uploading csv: %v", err)
+			logger.FromContext(r.Context()).Error("Error uploading csv: %v", err)
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -434,7 +434,7 @@ func GetAdminWorkspaces(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
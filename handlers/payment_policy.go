@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/audit"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/utils"
+	"github.com/stakwork/sphinx-tribes/webhooks"
+)
+
+const (
+	maxPendingTimeoutHours = 30 * 24
+	maxPaymentRetries      = 10
+
+	// paymentPolicyActor is the audit actor of the changes a payment
+	// policy makes on its own.
+	paymentPolicyActor = "payment_policy"
+)
+
+// paymentResolver settles, reverses, retries or flags pending bounty
+// payments as the payment policy of their workspace says.
+type paymentResolver struct {
+	db        db.Database
+	lightning LightningBackend
+	notify    func(pubkey, event, content, alias, routeHint string) string
+}
+
+func newPaymentResolver(database db.Database, backend LightningBackend) *paymentResolver {
+	return &paymentResolver{
+		db:        database,
+		lightning: backend,
+		notify:    processNotification,
+	}
+}
+
+// Check asks the node about a pending payment and resolves it. It returns
+// errPaymentPending while the payment is still in flight.
+func (pr *paymentResolver) Check(bounty db.NewBounty, payment db.NewPaymentHistory) error {
+	if payment.PaymentStatus != db.PaymentPending || payment.Tag == "" || payment.NeedsReview {
+		return nil
+	}
+	return pr.Resolve(bounty, payment, pr.Status(payment))
+}
+
+// Status asks the node about the payment's tag and the tags of the sends
+// it replaced: the payment is complete when any of them arrived, else it
+// has the status of its latest send.
+func (pr *paymentResolver) Status(payment db.NewPaymentHistory) string {
+	status := pr.lightning.PaymentStatusByTag(payment.Tag).Status
+	if status != db.PaymentComplete && replacedSendCompleted(pr.lightning.PaymentStatusByTag, payment) {
+		return db.PaymentComplete
+	}
+	return status
+}
+
+// replacedSendCompleted reports whether one of the sends a retry replaced
+// arrived after all.
+func replacedSendCompleted(statusByTag func(tag string) db.V2TagRes, payment db.NewPaymentHistory) bool {
+	for _, tag := range payment.PreviousTags {
+		if statusByTag(tag).Status == db.PaymentComplete {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve acts on the status the node reported for a pending payment: a
+// completed payment marks the bounty paid, a failed one and one pending
+// past the policy's timeout get the policy's action. A payment still
+// pending may yet arrive, so it is never sent again on its own; a retry
+// policy flags it for review instead.
+func (pr *paymentResolver) Resolve(bounty db.NewBounty, payment db.NewPaymentHistory, status string) error {
+	switch status {
+	case db.PaymentComplete:
+		completeBountyPayment(pr.db, bounty, payment, payment.Tag)
+		return nil
+	case db.PaymentFailed:
+		policy := pr.db.GetWorkspacePaymentPolicy(payment.WorkspaceUuid)
+		return pr.apply(policy, bounty, payment, status, "failed")
+	case db.PaymentPending:
+		policy := pr.db.GetWorkspacePaymentPolicy(payment.WorkspaceUuid)
+		if time.Since(lastPaymentAttempt(payment)) < policy.PendingTimeout() {
+			return errPaymentPending
+		}
+		return pr.apply(policy, bounty, payment, status, fmt.Sprintf("has been pending for over %d hours", policy.PendingTimeoutHours))
+	default:
+		return fmt.Errorf("unknown payment status %q", status)
+	}
+}
+
+func (pr *paymentResolver) apply(policy db.WorkspacePaymentPolicy, bounty db.NewBounty, payment db.NewPaymentHistory, status string, reason string) error {
+	action := policy.Action
+	if action == db.PaymentPolicyRetry && (status != db.PaymentFailed || payment.Retries >= policy.MaxRetries) {
+		action = db.PaymentPolicyReview
+	}
+	subject := fmt.Sprintf("The payment of %d sats for bounty %q %s", payment.Amount, bounty.Title, reason)
+
+	switch action {
+	case db.PaymentPolicyRetry:
+		status, err := pr.Retry(paymentPolicyActor, bounty, payment)
+		if err != nil {
+			return err
+		}
+		pr.notifyOwner(policy, fmt.Sprintf("%s and was sent again.", subject))
+		if status == db.PaymentComplete {
+			return nil
+		}
+		return errPaymentPending
+	case db.PaymentPolicyReview:
+		if err := pr.db.FlagPaymentForReview(payment.ID, fmt.Sprintf("Payment %s", reason)); err != nil {
+			return fmt.Errorf("could not flag payment %d for review: %w", payment.ID, err)
+		}
+		pr.notifyOwner(policy, fmt.Sprintf("%s and is waiting for your review.", subject))
+		return nil
+	default:
+		if err := pr.db.ProcessReversePayments(payment.ID); err != nil {
+			return fmt.Errorf("could not reverse payment %d of bounty %d: %w", payment.ID, bounty.ID, err)
+		}
+		audit.Record(paymentPolicyActor, payment.WorkspaceUuid, audit.EntityPayment, strconv.FormatUint(uint64(payment.ID), 10), audit.ActionReverse,
+			map[string]interface{}{"payment_status": db.PaymentPending},
+			map[string]interface{}{"payment_status": db.PaymentFailed, "reason": reason})
+		pr.notifyOwner(policy, fmt.Sprintf("%s and was reversed to the workspace budget.", subject))
+		return nil
+	}
+}
+
+// Retry sends a failed payment again as a new keysend, or a new asset
+// transfer for a payment in an asset. Callers check with Status that the
+// payment failed first. The payment keeps its budget and escrow, only its
+// tag changes; a send the node rejects still counts as a retry, so a
+// policy retrying failed payments runs out of retries instead of looping.
+// A send the node accepts without a tag can't be checked, so the payment
+// is flagged for review rather than sent again.
+func (pr *paymentResolver) Retry(actor string, bounty db.NewBounty, payment db.NewPaymentHistory) (string, error) {
+	assignee := pr.db.GetPersonByPubkey(payment.ReceiverPubKey)
+	memoText := url.QueryEscape(fmt.Sprintf("Payment For: %ss", bounty.Title))
+
+	keysendRes, err := sendBountyPayment(pr.lightning, payment.AssetId, payment.Amount, payment.ReceiverPubKey, assignee.OwnerRouteHint, memoText)
+	if err != nil && !errors.Is(err, lightning.ErrRejected) {
+		return "", fmt.Errorf("could not retry payment %d: %w", payment.ID, err)
+	}
+
+	tag := keysendRes.Tag
+	status := keysendRes.Status
+	untracked := false
+	switch {
+	case err != nil:
+		status = db.PaymentFailed
+		if tag == "" {
+			tag = payment.Tag
+		}
+	case tag == "" && status == db.PaymentComplete:
+		// settled by the payment's current tag
+		tag = payment.Tag
+	case tag == "":
+		untracked = true
+	}
+
+	retried, err := pr.db.RecordPaymentRetry(payment.ID, tag)
+	if err != nil {
+		return "", err
+	}
+	audit.Record(actor, payment.WorkspaceUuid, audit.EntityPayment, strconv.FormatUint(uint64(payment.ID), 10), audit.ActionRetry,
+		map[string]interface{}{"tag": payment.Tag, "retries": payment.Retries},
+		map[string]interface{}{"tag": retried.Tag, "retries": retried.Retries, "status": status})
+
+	if untracked {
+		if err := pr.db.FlagPaymentForReview(payment.ID, "Payment was sent again but the node returned no tag to check it"); err != nil {
+			return "", fmt.Errorf("could not flag payment %d for review: %w", payment.ID, err)
+		}
+		return status, nil
+	}
+	if status == db.PaymentComplete {
+		completeBountyPayment(pr.db, bounty, retried, tag)
+	}
+	return status, nil
+}
+
+func (pr *paymentResolver) notifyOwner(policy db.WorkspacePaymentPolicy, content string) {
+	if !policy.NotifyOwner {
+		return
+	}
+	workspace := pr.db.GetWorkspaceByUuid(policy.WorkspaceUuid)
+	if workspace.OwnerPubKey == "" {
+		return
+	}
+	owner := pr.db.GetPersonByPubkey(workspace.OwnerPubKey)
+	pr.notify(workspace.OwnerPubKey, "payment_policy", content, owner.OwnerAlias, owner.OwnerRouteHint)
+}
+
+// completeBountyPayment settles a payment the node reports complete and
+// marks its bounty paid, a bounty paid in parts once all of its shares or
+// milestones are.
+func completeBountyPayment(database db.Database, bounty db.NewBounty, payment db.NewPaymentHistory, tag string) {
+	database.SetPaymentAsComplete(tag)
+
+	now := time.Now()
+	bounty.PaymentPending = false
+	bounty.PaymentFailed = false
+	bounty.Paid = true
+	bounty.PaidDate = &now
+	bounty.Completed = true
+	bounty.CompletionDate = &now
+
+	updated, err := database.UpdateBountyPaymentStatuses(bounty)
+	if (len(bounty.Assignees) > 0 || len(bounty.Milestones) > 0) && (err != nil || !updated.Paid) {
+		return
+	}
+	emitWebhook(bounty.WorkspaceUuid, webhooks.EventBountyPaid, bounty)
+	recordBountyPayment(payment.SenderPubKey, bounty)
+}
+
+// lastPaymentAttempt is when the payment was last sent, the pending
+// timeout counts from there.
+func lastPaymentAttempt(payment db.NewPaymentHistory) time.Time {
+	if payment.Updated != nil {
+		return *payment.Updated
+	}
+	if payment.Created != nil {
+		return *payment.Created
+	}
+	return time.Now()
+}
+
+type paymentPolicyHandler struct {
+	db            db.Database
+	lightning     LightningBackend
+	userHasAccess func(pubKeyFromAuth string, uuid string, role string) bool
+	notify        func(pubkey, event, content, alias, routeHint string) string
+}
+
+func NewPaymentPolicyHandler(database db.Database) *paymentPolicyHandler {
+	configHandler := db.NewConfigHandler(database)
+	return &paymentPolicyHandler{
+		db:            database,
+		lightning:     lightning.FromConfig(http.DefaultClient),
+		userHasAccess: configHandler.UserHasAccess,
+		notify:        processNotification,
+	}
+}
+
+// authorize writes the error response and returns an empty uuid when the
+// caller lacks role on the workspace in the request.
+func (ph *paymentPolicyHandler) authorize(w http.ResponseWriter, r *http.Request, role string) (string, string) {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[payment policy] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return "", ""
+	}
+
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	workspace := ph.db.GetWorkspaceByUuid(workspaceUuid)
+	if workspace.Uuid == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Workspace not found"})
+		return "", ""
+	}
+
+	if !ph.userHasAccess(pubKeyFromAuth, workspaceUuid, role) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions"})
+		return "", ""
+	}
+	return pubKeyFromAuth, workspaceUuid
+}
+
+// GetPaymentPolicy godoc
+//
+//	@Summary		Get workspace payment policy
+//	@Description	What happens to bounty payments of the workspace that fail or stay pending
+//	@Tags			Workspaces
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string	true	"Workspace UUID"
+//	@Success		200				{object}	db.WorkspacePaymentPolicy
+//	@Router			/workspaces/{workspace_uuid}/payment-policy [get]
+func (ph *paymentPolicyHandler) GetPaymentPolicy(w http.ResponseWriter, r *http.Request) {
+	_, workspaceUuid := ph.authorize(w, r, db.ViewReport)
+	if workspaceUuid == "" {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ph.db.GetWorkspacePaymentPolicy(workspaceUuid))
+}
+
+// UpdatePaymentPolicy godoc
+//
+//	@Summary		Update workspace payment policy
+//	@Description	Set the pending timeout, the action taken on failed and timed out payments (reverse, retry or review; only failed payments are retried, timed out ones go to review), the retries before falling back to review and whether the owner is notified
+//	@Tags			Workspaces
+//	@Accept			json
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string						true	"Workspace UUID"
+//	@Param			policy			body		db.WorkspacePaymentPolicy	true	"Payment policy"
+//	@Success		200				{object}	db.WorkspacePaymentPolicy
+//	@Router			/workspaces/{workspace_uuid}/payment-policy [put]
+func (ph *paymentPolicyHandler) UpdatePaymentPolicy(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, workspaceUuid := ph.authorize(w, r, db.EditOrg)
+	if workspaceUuid == "" {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	policy := db.WorkspacePaymentPolicy{}
+	if err := json.Unmarshal(body, &policy); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+	policy.WorkspaceUuid = workspaceUuid
+
+	if err := validatePaymentPolicy(policy); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	before := ph.db.GetWorkspacePaymentPolicy(workspaceUuid)
+	saved, err := ph.db.SaveWorkspacePaymentPolicy(policy)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[payment policy] could not save policy of %s: %v", workspaceUuid, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to save payment policy"})
+		return
+	}
+	audit.Record(pubKeyFromAuth, workspaceUuid, audit.EntityPaymentPolicy, workspaceUuid, audit.ActionUpdate, before, saved)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(saved)
+}
+
+func validatePaymentPolicy(policy db.WorkspacePaymentPolicy) error {
+	if policy.PendingTimeoutHours < 1 || policy.PendingTimeoutHours > maxPendingTimeoutHours {
+		return fmt.Errorf("pending_timeout_hours must be between 1 and %d", maxPendingTimeoutHours)
+	}
+	switch policy.Action {
+	case db.PaymentPolicyReverse, db.PaymentPolicyReview:
+	case db.PaymentPolicyRetry:
+		if policy.MaxRetries < 1 {
+			return errors.New("max_retries must be at least 1 to retry payments")
+		}
+	default:
+		return errors.New("action must be one of reverse, retry or review")
+	}
+	if policy.MaxRetries < 0 || policy.MaxRetries > maxPaymentRetries {
+		return fmt.Errorf("max_retries must be between 0 and %d", maxPaymentRetries)
+	}
+	return nil
+}
+
+// RetryPayment godoc
+//
+//	@Summary		Retry a pending bounty payment
+//	@Description	Send a pending bounty payment the node reports failed again as a new keysend. Payments the node reports complete are settled instead, payments still in flight or without a tag to check are a conflict.
+//	@Tags			Workspaces
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string	true	"Workspace UUID"
+//	@Param			payment_id		path		int		true	"Payment history ID"
+//	@Success		200				{object}	db.NewPaymentHistory
+//	@Router			/workspaces/{workspace_uuid}/payments/{payment_id}/retry [post]
+func (ph *paymentPolicyHandler) RetryPayment(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, workspaceUuid := ph.authorize(w, r, db.PayBounty)
+	if workspaceUuid == "" {
+		return
+	}
+
+	paymentId, err := utils.ConvertStringToUint(chi.URLParam(r, "payment_id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid payment ID"})
+		return
+	}
+
+	payment, err := ph.db.GetPaymentHistoryById(paymentId)
+	if err != nil || payment.WorkspaceUuid != workspaceUuid {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Payment not found"})
+		return
+	}
+
+	if payment.PaymentType != db.Payment || payment.PaymentStatus != db.PaymentPending || payment.BountyId == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Only pending bounty payments can be retried"})
+		return
+	}
+
+	bounty := ph.db.GetBounty(payment.BountyId)
+	resolver := &paymentResolver{db: ph.db, lightning: ph.lightning, notify: ph.notify}
+
+	if payment.Tag == "" {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Payment has no tag to check with the node"})
+		return
+	}
+
+	// only a send the node reports failed is sent again, one that is
+	// still in flight may yet arrive
+	switch resolver.Status(payment) {
+	case db.PaymentFailed:
+	case db.PaymentComplete:
+		completeBountyPayment(ph.db, bounty, payment, payment.Tag)
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Payment already completed"})
+		return
+	case db.PaymentPending:
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Payment is still in flight"})
+		return
+	default:
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Could not check the payment with the node"})
+		return
+	}
+
+	status, err := resolver.Retry(pubKeyFromAuth, bounty, payment)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[payment policy] could not retry payment %d: %v", payment.ID, err)
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to retry payment"})
+		return
+	}
+	if status != db.PaymentComplete {
+		enqueuePaymentCheck(bounty.ID)
+	}
+
+	retried, err := ph.db.GetPaymentHistoryById(payment.ID)
+	if err != nil {
+		retried = payment
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(retried)
+}
@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type sentNotification struct {
+	pubkey  string
+	event   string
+	content string
+}
+
+func newTestPaymentResolver(t *testing.T) (*paymentResolver, *dbMocks.Database, *lightning.FakeNode, *[]sentNotification) {
+	mockDb := dbMocks.NewDatabase(t)
+	node := lightning.NewFakeNode()
+	sent := &[]sentNotification{}
+	resolver := newPaymentResolver(mockDb, node)
+	resolver.notify = func(pubkey, event, content, alias, routeHint string) string {
+		*sent = append(*sent, sentNotification{pubkey: pubkey, event: event, content: content})
+		return "COMPLETE"
+	}
+	return resolver, mockDb, node, sent
+}
+
+func TestPaymentResolver(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	bounty := db.NewBounty{ID: 7, Title: "Fix it", WorkspaceUuid: workspace.Uuid, PaymentPending: true}
+	failedPayment := func(node *lightning.FakeNode, retries int) db.NewPaymentHistory {
+		node.SetPaymentOutcome(db.PaymentFailed, "no route")
+		res, _ := node.Keysend(1500, "hunter", "", "bounty")
+		return db.NewPaymentHistory{ID: 3, Amount: 1500, BountyId: bounty.ID, WorkspaceUuid: workspace.Uuid, ReceiverPubKey: "hunter",
+			SenderPubKey: "owner", Tag: res.Tag, PaymentStatus: db.PaymentPending, PaymentType: db.Payment, Retries: retries}
+	}
+
+	t.Run("Waits for the policy's timeout", func(t *testing.T) {
+		resolver, mockDb, node, _ := newTestPaymentResolver(t)
+		node.SetPaymentOutcome(db.PaymentPending, "")
+		res, _ := node.Keysend(1500, "hunter", "", "bounty")
+		attempted := time.Now().Add(-2 * time.Hour)
+		payment := db.NewPaymentHistory{ID: 3, WorkspaceUuid: workspace.Uuid, Tag: res.Tag, PaymentStatus: db.PaymentPending, Updated: &attempted}
+
+		policy := db.DefaultPaymentPolicy(workspace.Uuid)
+		policy.PendingTimeoutHours = 3
+		mockDb.On("GetWorkspacePaymentPolicy", workspace.Uuid).Return(policy).Once()
+		assert.ErrorIs(t, resolver.Check(bounty, payment), errPaymentPending)
+
+		policy.PendingTimeoutHours = 1
+		mockDb.On("GetWorkspacePaymentPolicy", workspace.Uuid).Return(policy).Once()
+		mockDb.On("ProcessReversePayments", payment.ID).Return(nil).Once()
+		assert.NoError(t, resolver.Check(bounty, payment))
+	})
+
+	t.Run("Leaves payments waiting for review alone", func(t *testing.T) {
+		resolver, _, node, _ := newTestPaymentResolver(t)
+		payment := failedPayment(node, 0)
+		payment.NeedsReview = true
+
+		assert.NoError(t, resolver.Check(bounty, payment))
+	})
+
+	t.Run("Reverses and notifies the owner", func(t *testing.T) {
+		resolver, mockDb, node, sent := newTestPaymentResolver(t)
+		payment := failedPayment(node, 0)
+		policy := db.DefaultPaymentPolicy(workspace.Uuid)
+		policy.NotifyOwner = true
+		mockDb.On("GetWorkspacePaymentPolicy", workspace.Uuid).Return(policy)
+		mockDb.On("ProcessReversePayments", payment.ID).Return(nil).Once()
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetPersonByPubkey", "owner").Return(db.Person{OwnerPubKey: "owner"})
+
+		assert.NoError(t, resolver.Check(bounty, payment))
+		assert.Len(t, *sent, 1)
+		assert.Equal(t, "owner", (*sent)[0].pubkey)
+		assert.Contains(t, (*sent)[0].content, "was reversed")
+	})
+
+	t.Run("Retries with a fresh keysend", func(t *testing.T) {
+		resolver, mockDb, node, sent := newTestPaymentResolver(t)
+		payment := failedPayment(node, 0)
+		node.SetPaymentOutcome(db.PaymentComplete, "")
+		mockDb.On("GetWorkspacePaymentPolicy", workspace.Uuid).Return(db.WorkspacePaymentPolicy{
+			WorkspaceUuid: workspace.Uuid, PendingTimeoutHours: 24, Action: db.PaymentPolicyRetry, MaxRetries: 2,
+		})
+		mockDb.On("GetPersonByPubkey", "hunter").Return(db.Person{OwnerPubKey: "hunter", OwnerRouteHint: "hint"})
+
+		var newTag string
+		mockDb.On("RecordPaymentRetry", payment.ID, mock.MatchedBy(func(tag string) bool { return tag != payment.Tag })).
+			Run(func(args mock.Arguments) { newTag = args.String(1) }).
+			Return(func(id uint, tag string) db.NewPaymentHistory {
+				retried := payment
+				retried.Tag = tag
+				retried.Retries++
+				return retried
+			}, nil).Once()
+		mockDb.On("SetPaymentAsComplete", mock.Anything).Return(true).Once()
+		mockDb.On("UpdateBountyPaymentStatuses", mock.MatchedBy(func(b db.NewBounty) bool { return b.Paid })).Return(bounty, nil).Once()
+
+		assert.NoError(t, resolver.Check(bounty, payment))
+		assert.Empty(t, *sent, "owner is not notified unless the policy asks for it")
+
+		payments := node.Payments()
+		assert.Len(t, payments, 2)
+		assert.Equal(t, newTag, payments[1].Tag)
+		assert.Equal(t, db.PaymentComplete, payments[1].Status)
+		mockDb.AssertCalled(t, "SetPaymentAsComplete", newTag)
+	})
+
+	t.Run("Keeps checking a retry that did not complete", func(t *testing.T) {
+		resolver, mockDb, node, _ := newTestPaymentResolver(t)
+		payment := failedPayment(node, 0)
+		node.SetPaymentOutcome(db.PaymentPending, "")
+		mockDb.On("GetWorkspacePaymentPolicy", workspace.Uuid).Return(db.WorkspacePaymentPolicy{
+			WorkspaceUuid: workspace.Uuid, PendingTimeoutHours: 24, Action: db.PaymentPolicyRetry, MaxRetries: 2,
+		})
+		mockDb.On("GetPersonByPubkey", "hunter").Return(db.Person{OwnerPubKey: "hunter"})
+		mockDb.On("RecordPaymentRetry", payment.ID, mock.Anything).Return(payment, nil).Once()
+
+		assert.ErrorIs(t, resolver.Check(bounty, payment), errPaymentPending)
+	})
+
+	t.Run("Sends a payment still pending past the timeout to review instead of again", func(t *testing.T) {
+		resolver, mockDb, node, _ := newTestPaymentResolver(t)
+		node.SetPaymentOutcome(db.PaymentPending, "")
+		res, _ := node.Keysend(1500, "hunter", "", "bounty")
+		attempted := time.Now().Add(-48 * time.Hour)
+		payment := db.NewPaymentHistory{ID: 3, Amount: 1500, BountyId: bounty.ID, WorkspaceUuid: workspace.Uuid, ReceiverPubKey: "hunter",
+			Tag: res.Tag, PaymentStatus: db.PaymentPending, PaymentType: db.Payment, Updated: &attempted}
+		mockDb.On("GetWorkspacePaymentPolicy", workspace.Uuid).Return(db.WorkspacePaymentPolicy{
+			WorkspaceUuid: workspace.Uuid, PendingTimeoutHours: 24, Action: db.PaymentPolicyRetry, MaxRetries: 2,
+		})
+		mockDb.On("FlagPaymentForReview", payment.ID, "Payment has been pending for over 24 hours").Return(nil).Once()
+
+		assert.NoError(t, resolver.Check(bounty, payment))
+		assert.Len(t, node.Payments(), 1, "a payment that may still arrive is not sent twice")
+	})
+
+	t.Run("Settles through a replaced send that completed", func(t *testing.T) {
+		resolver, mockDb, node, _ := newTestPaymentResolver(t)
+		node.SetPaymentOutcome(db.PaymentComplete, "")
+		first, _ := node.Keysend(1500, "hunter", "", "bounty")
+		payment := failedPayment(node, 1)
+		payment.PreviousTags = []string{first.Tag}
+		mockDb.On("SetPaymentAsComplete", payment.Tag).Return(true).Once()
+		mockDb.On("UpdateBountyPaymentStatuses", mock.MatchedBy(func(b db.NewBounty) bool { return b.Paid })).Return(bounty, nil).Once()
+
+		assert.NoError(t, resolver.Check(bounty, payment))
+		assert.Len(t, node.Payments(), 2, "a payment that arrived is not sent again")
+	})
+
+	t.Run("Flags a retry the node accepted without a tag for review", func(t *testing.T) {
+		resolver, mockDb, node, _ := newTestPaymentResolver(t)
+		payment := failedPayment(node, 0)
+		node.SetPaymentOutcome(db.PaymentPending, "")
+		resolver.lightning = untaggedNode{node}
+		mockDb.On("GetWorkspacePaymentPolicy", workspace.Uuid).Return(db.WorkspacePaymentPolicy{
+			WorkspaceUuid: workspace.Uuid, PendingTimeoutHours: 24, Action: db.PaymentPolicyRetry, MaxRetries: 2,
+		})
+		mockDb.On("GetPersonByPubkey", "hunter").Return(db.Person{OwnerPubKey: "hunter"})
+		mockDb.On("RecordPaymentRetry", payment.ID, "").Return(payment, nil).Once()
+		mockDb.On("FlagPaymentForReview", payment.ID, mock.Anything).Return(nil).Once()
+
+		assert.ErrorIs(t, resolver.Check(bounty, payment), errPaymentPending)
+	})
+
+	t.Run("Falls back to review once retries run out", func(t *testing.T) {
+		resolver, mockDb, node, sent := newTestPaymentResolver(t)
+		payment := failedPayment(node, 2)
+		mockDb.On("GetWorkspacePaymentPolicy", workspace.Uuid).Return(db.WorkspacePaymentPolicy{
+			WorkspaceUuid: workspace.Uuid, PendingTimeoutHours: 24, Action: db.PaymentPolicyRetry, MaxRetries: 2, NotifyOwner: true,
+		})
+		mockDb.On("FlagPaymentForReview", payment.ID, "Payment failed").Return(nil).Once()
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetPersonByPubkey", "owner").Return(db.Person{OwnerPubKey: "owner"})
+
+		assert.NoError(t, resolver.Check(bounty, payment))
+		assert.Len(t, node.Payments(), 1, "no keysend once retries ran out")
+		assert.Contains(t, (*sent)[0].content, "waiting for your review")
+	})
+}
+
+// untaggedNode accepts keysends without returning their tag.
+type untaggedNode struct {
+	*lightning.FakeNode
+}
+
+func (n untaggedNode) Keysend(amount uint, pubkey string, routeHint string, memo string) (db.V2SendOnionRes, error) {
+	res, err := n.FakeNode.Keysend(amount, pubkey, routeHint, memo)
+	res.Tag = ""
+	return res, err
+}
+
+func newTestPaymentPolicyHandler(t *testing.T, hasAccess bool) (*paymentPolicyHandler, *dbMocks.Database, *lightning.FakeNode) {
+	mockDb := dbMocks.NewDatabase(t)
+	node := lightning.NewFakeNode()
+	handler := NewPaymentPolicyHandler(mockDb)
+	handler.lightning = node
+	handler.notify = func(pubkey, event, content, alias, routeHint string) string { return "COMPLETE" }
+	handler.userHasAccess = func(pubKeyFromAuth string, uuid string, role string) bool {
+		return hasAccess
+	}
+	return handler, mockDb, node
+}
+
+func TestGetPaymentPolicy(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Unauthorized without pubkey", func(t *testing.T) {
+		handler, _, _ := newTestPaymentPolicyHandler(t, true)
+		rr := httptest.NewRecorder()
+
+		handler.GetPaymentPolicy(rr, webhookRequest(http.MethodGet, "/", nil, "", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Returns the default policy", func(t *testing.T) {
+		handler, mockDb, _ := newTestPaymentPolicyHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWorkspacePaymentPolicy", workspace.Uuid).Return(db.DefaultPaymentPolicy(workspace.Uuid))
+		rr := httptest.NewRecorder()
+
+		handler.GetPaymentPolicy(rr, webhookRequest(http.MethodGet, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		policy := db.WorkspacePaymentPolicy{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &policy))
+		assert.Equal(t, db.PaymentPolicyReverse, policy.Action)
+		assert.Equal(t, db.DefaultPendingTimeoutHours, policy.PendingTimeoutHours)
+	})
+}
+
+func TestUpdatePaymentPolicy(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Unauthorized without the role", func(t *testing.T) {
+		handler, mockDb, _ := newTestPaymentPolicyHandler(t, false)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		rr := httptest.NewRecorder()
+
+		handler.UpdatePaymentPolicy(rr, webhookRequest(http.MethodPut, "/", db.DefaultPaymentPolicy(""), "someone", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	invalid := map[string]db.WorkspacePaymentPolicy{
+		"zero timeout":         {PendingTimeoutHours: 0, Action: db.PaymentPolicyReverse},
+		"timeout over 30 days": {PendingTimeoutHours: 721, Action: db.PaymentPolicyReverse},
+		"unknown action":       {PendingTimeoutHours: 24, Action: "ignore"},
+		"retry without tries":  {PendingTimeoutHours: 24, Action: db.PaymentPolicyRetry},
+		"too many retries":     {PendingTimeoutHours: 24, Action: db.PaymentPolicyRetry, MaxRetries: 11},
+	}
+	for name, policy := range invalid {
+		t.Run("Rejects "+name, func(t *testing.T) {
+			handler, mockDb, _ := newTestPaymentPolicyHandler(t, true)
+			mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+			rr := httptest.NewRecorder()
+
+			handler.UpdatePaymentPolicy(rr, webhookRequest(http.MethodPut, "/", policy, "owner", params))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+
+	t.Run("Saves the policy of the workspace in the path", func(t *testing.T) {
+		handler, mockDb, _ := newTestPaymentPolicyHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWorkspacePaymentPolicy", workspace.Uuid).Return(db.DefaultPaymentPolicy(workspace.Uuid))
+		mockDb.On("SaveWorkspacePaymentPolicy", mock.MatchedBy(func(p db.WorkspacePaymentPolicy) bool {
+			return p.WorkspaceUuid == workspace.Uuid && p.Action == db.PaymentPolicyRetry && p.MaxRetries == 3 && p.NotifyOwner
+		})).Return(func(p db.WorkspacePaymentPolicy) db.WorkspacePaymentPolicy { return p }, nil).Once()
+		rr := httptest.NewRecorder()
+
+		handler.UpdatePaymentPolicy(rr, webhookRequest(http.MethodPut, "/", db.WorkspacePaymentPolicy{
+			WorkspaceUuid: "other-workspace", PendingTimeoutHours: 48, Action: db.PaymentPolicyRetry, MaxRetries: 3, NotifyOwner: true,
+		}, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestRetryPayment(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	bounty := db.NewBounty{ID: 7, Title: "Fix it", WorkspaceUuid: workspace.Uuid, PaymentPending: true}
+	params := map[string]string{"workspace_uuid": workspace.Uuid, "payment_id": "3"}
+	pendingPayment := func(node *lightning.FakeNode, status string) db.NewPaymentHistory {
+		node.SetPaymentOutcome(status, "")
+		res, _ := node.Keysend(1500, "hunter", "", "bounty")
+		now := time.Now()
+		return db.NewPaymentHistory{ID: 3, Amount: 1500, BountyId: bounty.ID, WorkspaceUuid: workspace.Uuid, ReceiverPubKey: "hunter",
+			SenderPubKey: "owner", Tag: res.Tag, PaymentStatus: db.PaymentPending, PaymentType: db.Payment, Updated: &now}
+	}
+
+	t.Run("Not found for a payment of another workspace", func(t *testing.T) {
+		handler, mockDb, node := newTestPaymentPolicyHandler(t, true)
+		payment := pendingPayment(node, db.PaymentFailed)
+		payment.WorkspaceUuid = "other-workspace"
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetPaymentHistoryById", uint(3)).Return(payment, nil)
+		rr := httptest.NewRecorder()
+
+		handler.RetryPayment(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Rejects payments that are not pending", func(t *testing.T) {
+		handler, mockDb, node := newTestPaymentPolicyHandler(t, true)
+		payment := pendingPayment(node, db.PaymentComplete)
+		payment.PaymentStatus = db.PaymentComplete
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetPaymentHistoryById", uint(3)).Return(payment, nil)
+		rr := httptest.NewRecorder()
+
+		handler.RetryPayment(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Conflict while the payment is in flight", func(t *testing.T) {
+		handler, mockDb, node := newTestPaymentPolicyHandler(t, true)
+		payment := pendingPayment(node, db.PaymentPending)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetPaymentHistoryById", uint(3)).Return(payment, nil)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		rr := httptest.NewRecorder()
+
+		handler.RetryPayment(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Len(t, node.Payments(), 1)
+	})
+
+	t.Run("Conflict for a payment under review that is still in flight", func(t *testing.T) {
+		handler, mockDb, node := newTestPaymentPolicyHandler(t, true)
+		payment := pendingPayment(node, db.PaymentPending)
+		attempted := time.Now().Add(-72 * time.Hour)
+		payment.Updated = &attempted
+		payment.NeedsReview = true
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetPaymentHistoryById", uint(3)).Return(payment, nil)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		rr := httptest.NewRecorder()
+
+		handler.RetryPayment(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Len(t, node.Payments(), 1, "a payment that may still arrive is not sent twice")
+	})
+
+	t.Run("Conflict for a payment without a tag to check", func(t *testing.T) {
+		handler, mockDb, node := newTestPaymentPolicyHandler(t, true)
+		payment := pendingPayment(node, db.PaymentFailed)
+		payment.Tag = ""
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetPaymentHistoryById", uint(3)).Return(payment, nil)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		rr := httptest.NewRecorder()
+
+		handler.RetryPayment(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Len(t, node.Payments(), 1)
+	})
+
+	t.Run("Settles instead of retrying a completed payment", func(t *testing.T) {
+		handler, mockDb, node := newTestPaymentPolicyHandler(t, true)
+		payment := pendingPayment(node, db.PaymentComplete)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetPaymentHistoryById", uint(3)).Return(payment, nil)
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("SetPaymentAsComplete", payment.Tag).Return(true).Once()
+		mockDb.On("UpdateBountyPaymentStatuses", mock.Anything).Return(bounty, nil).Once()
+		rr := httptest.NewRecorder()
+
+		handler.RetryPayment(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Len(t, node.Payments(), 1)
+	})
+
+	t.Run("Retries a failed payment", func(t *testing.T) {
+		handler, mockDb, node := newTestPaymentPolicyHandler(t, true)
+		payment := pendingPayment(node, db.PaymentFailed)
+		node.SetPaymentOutcome(db.PaymentPending, "")
+		retried := payment
+		retried.Retries = 1
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetPaymentHistoryById", uint(3)).Return(payment, nil).Once()
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("GetPersonByPubkey", "hunter").Return(db.Person{OwnerPubKey: "hunter"})
+		mockDb.On("RecordPaymentRetry", payment.ID, mock.Anything).Return(retried, nil).Once()
+		mockDb.On("GetPaymentHistoryById", uint(3)).Return(retried, nil).Once()
+		rr := httptest.NewRecorder()
+
+		handler.RetryPayment(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		got := db.NewPaymentHistory{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+		assert.Equal(t, 1, got.Retries)
+		assert.Len(t, node.Payments(), 2)
+	})
+}
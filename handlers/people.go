@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -104,7 +105,7 @@ func (ph *peopleHandler) CreatePerson(w http.ResponseWriter, r *http.Request) {
 	person.OwnerPubKey = pubKeyFromAuth
 
 	if person.NewTicketTime != 0 {
-		go ph.db.ProcessAlerts(person)
+		go ph.db.WithContext(context.WithoutCancel(r.Context())).ProcessAlerts(person)
 	}
 
 	b := new(bytes.Buffer)
@@ -182,7 +183,7 @@ func (ph *peopleHandler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
 	} else {
 		if person.OwnerPubKey != existing.OwnerPubKey && person.OwnerAlias != existing.OwnerAlias {
 			// can't edit someone else's
-			logger.Log.Info("cant edit someone else")
+			logger.FromContext(r.Context()).Info("cant edit someone else")
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
@@ -191,7 +192,7 @@ func (ph *peopleHandler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
 	person.Updated = &now
 
 	if person.NewTicketTime != 0 {
-		go ph.db.ProcessAlerts(person)
+		go ph.db.WithContext(context.WithoutCancel(r.Context())).ProcessAlerts(person)
 	}
 
 	b := new(bytes.Buffer)
@@ -235,7 +236,7 @@ func (ph *peopleHandler) UpsertLogin(w http.ResponseWriter, r *http.Request) {
 	r.Body.Close()
 	err = json.Unmarshal(body, &person)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -248,7 +249,7 @@ func (ph *peopleHandler) UpsertLogin(w http.ResponseWriter, r *http.Request) {
 	if existing.ID == 0 {
 		if person.ID != 0 {
 			// cant try to "edit" if not exists already
-			logger.Log.Info("cant edit non existing")
+			logger.FromContext(r.Context()).Info("cant edit non existing")
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
@@ -258,7 +259,7 @@ func (ph *peopleHandler) UpsertLogin(w http.ResponseWriter, r *http.Request) {
 
 	} else { // editing! needs ID
 		if person.ID != 0 && person.ID != existing.ID { // can't edit someone else's
-			logger.Log.Info("cant edit someone else")
+			logger.FromContext(r.Context()).Info("cant edit someone else")
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
@@ -268,7 +269,7 @@ func (ph *peopleHandler) UpsertLogin(w http.ResponseWriter, r *http.Request) {
 	person.Updated = &now
 
 	if person.NewTicketTime != 0 {
-		go ph.db.ProcessAlerts(person)
+		go ph.db.WithContext(context.WithoutCancel(r.Context())).ProcessAlerts(person)
 	}
 
 	b := new(bytes.Buffer)
@@ -290,7 +291,7 @@ func (ph *peopleHandler) UpsertLogin(w http.ResponseWriter, r *http.Request) {
 	tokenString, err := auth.EncodeJwt(person.OwnerPubKey)
 
 	if err != nil {
-		logger.Log.Info("Cannot generate jwt token")
+		logger.FromContext(r.Context()).Info("Cannot generate jwt token")
 	}
 
 	responseData["jwt"] = tokenString
@@ -332,41 +333,41 @@ func DeleteTicketByAdmin(w http.ResponseWriter, r *http.Request) {
 	createdStr := chi.URLParam(r, "created")
 	created, err := strconv.ParseInt(createdStr, 10, 64)
 	if err != nil {
-		logger.Log.Info("Unable to convert created to int64")
+		logger.FromContext(r.Context()).Info("Unable to convert created to int64")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	if created == 0 || pubKey == "" {
-		logger.Log.Info("Insufficient details to delete ticket")
+		logger.FromContext(r.Context()).Info("Insufficient details to delete ticket")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 	existing := db.DB.GetPersonByPubkey(pubKeyFromAuth)
 	if existing.ID == 0 {
-		logger.Log.Info("Could not fetch admin details from db")
+		logger.FromContext(r.Context()).Info("Could not fetch admin details from db")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	} else if PersonIsAdmin(existing.OwnerPubKey) == false {
-		logger.Log.Info("Only admin is allowed to delete tickets")
+		logger.FromContext(r.Context()).Info("Only admin is allowed to delete tickets")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	person := db.DB.GetPersonByPubkey(pubKey)
 	if person.ID == 0 {
-		logger.Log.Info("Could not fetch person from db")
+		logger.FromContext(r.Context()).Info("Could not fetch person from db")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	wanteds, ok := person.Extras["wanted"].([]interface{})
 	if !ok {
-		logger.Log.Info("No tickets found for person")
+		logger.FromContext(r.Context()).Info("No tickets found for person")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -388,7 +389,7 @@ func DeleteTicketByAdmin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if index == -1 {
-		logger.Log.Info("Ticket to delete not found")
+		logger.FromContext(r.Context()).Info("Ticket to delete not found")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	} else {
@@ -602,7 +603,7 @@ func (ph *peopleHandler) GetPersonByUuid(w http.ResponseWriter, r *http.Request)
 	personResponse["twitter_confirmed"] = person.TwitterConfirmed
 	personResponse["github_issues"] = person.GithubIssues
 	if err != nil {
-		logger.Log.Error("==> error: %v", err)
+		logger.FromContext(r.Context()).Error("==> error: %v", err)
 	} else {
 		var badgeSlice []uint
 		for i := 0; i < len(assetBalanceData); i++ {
@@ -610,7 +611,7 @@ func (ph *peopleHandler) GetPersonByUuid(w http.ResponseWriter, r *http.Request)
 		}
 		personResponse["badges"] = badgeSlice
 	}
-	logger.Log.Info("")
+	logger.FromContext(r.Context()).Info("")
 	// FIXME use http to hit sphinx-element server for badges
 	// Todo: response should include no pubKey
 	// FIXME also filter by the tribe "profile_filters"
@@ -633,11 +634,11 @@ func GetPersonAssetsByUuid(w http.ResponseWriter, r *http.Request) {
 	person := db.DB.GetPersonByUuid(uuid)
 	assetList, err := GetAssetList(person.OwnerPubKey)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusServiceUnavailable)
 		return
 	}
-	logger.Log.Info("")
+	logger.FromContext(r.Context()).Info("")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(assetList)
 }
@@ -677,25 +678,25 @@ func (ph *peopleHandler) DeletePerson(w http.ResponseWriter, r *http.Request) {
 	idString := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idString)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	if id == 0 {
-		logger.Log.Info("id is 0")
+		logger.FromContext(r.Context()).Info("id is 0")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	existing := ph.db.GetPerson(uint(id))
 	if existing.ID == 0 {
-		logger.Log.Info("existing id is 0")
+		logger.FromContext(r.Context()).Info("existing id is 0")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 	if existing.OwnerPubKey != pubKeyFromAuth {
-		logger.Log.Info("keys dont match")
+		logger.FromContext(r.Context()).Info("keys dont match")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -797,44 +798,44 @@ func AddOrRemoveBadge(w http.ResponseWriter, r *http.Request) {
 	r.Body.Close()
 	err = json.Unmarshal(body, &badgeCreationData)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
 
 	if badgeCreationData.Badge == "" {
-		logger.Log.Info("Badge cannot be Empty")
+		logger.FromContext(r.Context()).Info("Badge cannot be Empty")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
 	if badgeCreationData.Action == "" {
-		logger.Log.Info("Action cannot be Empty")
+		logger.FromContext(r.Context()).Info("Action cannot be Empty")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
 	if !(badgeCreationData.Action == "add" || badgeCreationData.Action == "remove") {
-		logger.Log.Info("Invalid action in Request")
+		logger.FromContext(r.Context()).Info("Invalid action in Request")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
 	if badgeCreationData.TribeUUID == "" {
-		logger.Log.Info("tribeId cannot be Empty")
+		logger.FromContext(r.Context()).Info("tribeId cannot be Empty")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
 	extractedPubkey, err := auth.VerifyTribeUUID(badgeCreationData.TribeUUID, false)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -842,8 +843,8 @@ func AddOrRemoveBadge(w http.ResponseWriter, r *http.Request) {
 	tribe := db.DB.GetTribeByIdAndPubkey(badgeCreationData.TribeUUID, extractedPubkey)
 
 	if pubKeyFromAuth != tribe.OwnerPubKey {
-		logger.Log.Info("%s", pubKeyFromAuth)
-		logger.Log.Info("mismatched pubkey")
+		logger.FromContext(r.Context()).Info("%s", pubKeyFromAuth)
+		logger.FromContext(r.Context()).Info("mismatched pubkey")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
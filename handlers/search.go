@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchQuery     = 500
+)
+
+type searchHandler struct {
+	db db.Database
+}
+
+func NewSearchHandler(database db.Database) *searchHandler {
+	return &searchHandler{
+		db: database,
+	}
+}
+
+// SearchWorkspace godoc
+//
+//	@Summary		Search workspace
+//	@Description	Ranked full-text search over the features, phases, tickets, activities and snippets of a workspace. Matches in highlight are wrapped in <mark> tags.
+//	@Tags			Workspaces
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string	true	"Workspace UUID"
+//	@Param			q				query		string	true	"Search query, supports quoted phrases, OR and -exclusions"
+//	@Param			types			query		string	false	"Comma separated entity types: feature, phase, ticket, activity, snippet"
+//	@Param			limit			query		int		false	"Page size, at most 100"
+//	@Param			offset			query		int		false	"Offset"
+//	@Success		200				{object}	db.WorkspaceSearchResponse
+//	@Router			/workspaces/{workspace_uuid}/search [get]
+func (sh *searchHandler) SearchWorkspace(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[search] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	workspace := sh.db.GetWorkspaceByUuid(workspaceUuid)
+	if workspace.Uuid == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Workspace not found"})
+		return
+	}
+
+	if workspace.OwnerPubKey != pubKeyFromAuth && sh.db.GetWorkspaceUser(pubKeyFromAuth, workspaceUuid).OwnerPubKey == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You are not a member of this workspace"})
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" || len(query) > maxSearchQuery {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "A search query of at most 500 characters is required"})
+		return
+	}
+
+	var types []string
+	if param := r.URL.Query().Get("types"); param != "" {
+		for _, entity := range strings.Split(param, ",") {
+			entity = strings.TrimSpace(entity)
+			if !db.ValidSearchEntityType(entity) {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Unknown entity type: " + entity})
+				return
+			}
+			types = append(types, entity)
+		}
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	results, total, err := sh.db.SearchWorkspace(workspaceUuid, query, types, limit, offset)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[search] could not search workspace %s: %v", workspaceUuid, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to search workspace"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(db.WorkspaceSearchResponse{
+		Results: results,
+		Total:   total,
+	})
+}
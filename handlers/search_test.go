@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchWorkspace(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Unauthorized without pubkey", func(t *testing.T) {
+		handler := NewSearchHandler(dbMocks.NewDatabase(t))
+		rr := httptest.NewRecorder()
+
+		handler.SearchWorkspace(rr, webhookRequest(http.MethodGet, "/?q=invoice", nil, "", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Workspace not found", func(t *testing.T) {
+		mockDb := dbMocks.NewDatabase(t)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(db.Workspace{})
+		handler := NewSearchHandler(mockDb)
+		rr := httptest.NewRecorder()
+
+		handler.SearchWorkspace(rr, webhookRequest(http.MethodGet, "/?q=invoice", nil, "owner", params))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Rejects non members", func(t *testing.T) {
+		mockDb := dbMocks.NewDatabase(t)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWorkspaceUser", "stranger", workspace.Uuid).Return(db.WorkspaceUsers{})
+		handler := NewSearchHandler(mockDb)
+		rr := httptest.NewRecorder()
+
+		handler.SearchWorkspace(rr, webhookRequest(http.MethodGet, "/?q=invoice", nil, "stranger", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Rejects missing query and unknown types", func(t *testing.T) {
+		for _, target := range []string{"/", "/?q=%20", "/?q=invoice&types=feature,bounty"} {
+			mockDb := dbMocks.NewDatabase(t)
+			mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+			handler := NewSearchHandler(mockDb)
+			rr := httptest.NewRecorder()
+
+			handler.SearchWorkspace(rr, webhookRequest(http.MethodGet, target, nil, "owner", params))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+		}
+	})
+
+	t.Run("Members get ranked results", func(t *testing.T) {
+		mockDb := dbMocks.NewDatabase(t)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWorkspaceUser", "member", workspace.Uuid).Return(db.WorkspaceUsers{OwnerPubKey: "member", WorkspaceUuid: workspace.Uuid})
+		mockDb.On("SearchWorkspace", workspace.Uuid, "lightning invoice", []string{db.SearchEntityFeature, db.SearchEntityTicket}, 100, 20).Return([]db.WorkspaceSearchResult{
+			{EntityType: db.SearchEntityFeature, EntityID: "feature-1", Title: "Invoices", Highlight: "pay a <mark>lightning</mark> <mark>invoice</mark>", Rank: 0.9},
+			{EntityType: db.SearchEntityTicket, EntityID: "ticket-1", Title: "Poll invoices", Rank: 0.4},
+		}, int64(42), nil)
+		handler := NewSearchHandler(mockDb)
+		rr := httptest.NewRecorder()
+
+		handler.SearchWorkspace(rr, webhookRequest(http.MethodGet, "/?q=lightning+invoice&types=feature,%20ticket&limit=1000&offset=20", nil, "member", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		response := db.WorkspaceSearchResponse{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.Equal(t, int64(42), response.Total)
+		assert.Len(t, response.Results, 2)
+		assert.Equal(t, "feature-1", response.Results[0].EntityID)
+		assert.Contains(t, response.Results[0].Highlight, "<mark>lightning</mark>")
+	})
+
+	t.Run("Search failure", func(t *testing.T) {
+		mockDb := dbMocks.NewDatabase(t)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("SearchWorkspace", workspace.Uuid, "invoice", []string(nil), 20, 0).Return(nil, int64(0), errors.New("syntax error"))
+		handler := NewSearchHandler(mockDb)
+		rr := httptest.NewRecorder()
+
+		handler.SearchWorkspace(rr, webhookRequest(http.MethodGet, "/?q=invoice", nil, "owner", params))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stakwork/sphinx-tribes/vault"
+	"github.com/stretchr/testify/assert"
+)
+
+func useTestVault(t *testing.T) {
+	original := vault.Default
+	t.Cleanup(func() { vault.Default = original })
+
+	testVault, err := vault.New([]string{"k1"}, [][]byte{[]byte("0123456789abcdef0123456789abcdef")})
+	assert.NoError(t, err)
+	vault.Default = testVault
+}
+
+func sealedSecret(t *testing.T, plaintext string) vault.Secret {
+	sealed, err := vault.Default.Seal(plaintext)
+	assert.NoError(t, err)
+	return vault.Secret(sealed)
+}
+
+func TestCodeSpaceSecrets(t *testing.T) {
+	useTestVault(t)
+	codeSpace := db.CodeSpaceMap{
+		CodeSpaceURL: "pool-1",
+		GithubPat:    sealedSecret(t, "ghp_workflow_token"),
+		PoolAPIKey:   sealedSecret(t, "pool_workflow_key"),
+	}
+
+	t.Run("Are redacted in responses", func(t *testing.T) {
+		body, err := json.Marshal(codeSpace)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), `"githubPat":"[REDACTED]"`)
+		assert.Contains(t, string(body), `"poolAPIKey":"[REDACTED]"`)
+		assert.NotContains(t, string(body), "vault:v1:")
+	})
+
+	t.Run("Are decrypted when a workflow is dispatched", func(t *testing.T) {
+		vars := buildVarsPayload(SendMessageRequest{Message: "hi"}, &db.ChatMessage{}, nil, nil, &db.Person{}, nil, codeSpace, "Chat")
+
+		assert.Equal(t, "ghp_workflow_token", vars["token"])
+		assert.Equal(t, "pool_workflow_key", vars["pool_api_key"])
+	})
+
+	t.Run("Are left out when they cannot be decrypted", func(t *testing.T) {
+		testVault := vault.Default
+		vault.Default = nil
+		defer func() { vault.Default = testVault }()
+
+		vars := buildVarsPayload(SendMessageRequest{Message: "hi"}, &db.ChatMessage{}, nil, nil, &db.Person{}, nil, codeSpace, "Chat")
+
+		assert.NotContains(t, vars, "token")
+		assert.NotContains(t, vars, "pool_api_key")
+	})
+}
+
+func TestWorkspaceEnvVarSecrets(t *testing.T) {
+	useTestVault(t)
+
+	var received map[string][]map[string]string
+	pool := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer pool_workflow_key", r.Header.Get("Authorization"))
+		if r.Method == http.MethodPut {
+			json.NewDecoder(r.Body).Decode(&received)
+			json.NewEncoder(w).Encode(map[string]interface{}{"config": received})
+			return
+		}
+		w.Write([]byte(`{"config":{"env_vars":[{"name":"API_KEY","value":"sk_live_secret"},{"name":"EMPTY","value":""}]}}`))
+	}))
+	defer pool.Close()
+
+	originalURL := poolsURL
+	poolsURL = pool.URL + "/"
+	defer func() { poolsURL = originalURL }()
+
+	newHandler := func(t *testing.T) *workspaceHandler {
+		mockDb := dbMocks.NewDatabase(t)
+		mockDb.On("GetCodeSpaceMapByWorkspace", "workspace-1").Return([]db.CodeSpaceMap{{
+			CodeSpaceURL: "pool-1",
+			PoolAPIKey:   sealedSecret(t, "pool_workflow_key"),
+		}}, nil)
+		return &workspaceHandler{db: mockDb}
+	}
+	params := map[string]string{"workspace_uuid": "workspace-1"}
+
+	t.Run("Redacts the values it lists", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		newHandler(t).GetWorkspaceEnvVars(rr, webhookRequest(http.MethodGet, "/workspaces/workspace-1/env_vars", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `[{"name":"API_KEY","value":"[REDACTED]"},{"name":"EMPTY","value":""}]`, rr.Body.String())
+	})
+
+	t.Run("Keeps the values sent back redacted", func(t *testing.T) {
+		body := map[string]interface{}{"env_vars": []map[string]string{
+			{"name": "API_KEY", "value": vault.Redacted},
+			{"name": "NEW_KEY", "value": "sk_new_secret"},
+		}}
+
+		rr := httptest.NewRecorder()
+		newHandler(t).UpdateWorkspaceEnvVars(rr, webhookRequest(http.MethodPut, "/workspaces/workspace-1/env_vars", body, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []map[string]string{
+			{"name": "API_KEY", "value": "sk_live_secret"},
+			{"name": "NEW_KEY", "value": "sk_new_secret"},
+		}, received["env_vars"])
+		response, _ := io.ReadAll(rr.Body)
+		assert.False(t, strings.Contains(string(response), "sk_"))
+	})
+
+	t.Run("Refuses a redacted value it has nothing to keep for", func(t *testing.T) {
+		body := map[string]interface{}{"env_vars": []map[string]string{{"name": "UNKNOWN", "value": vault.Redacted}}}
+
+		rr := httptest.NewRecorder()
+		newHandler(t).UpdateWorkspaceEnvVars(rr, webhookRequest(http.MethodPut, "/workspaces/workspace-1/env_vars", body, "owner", params))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
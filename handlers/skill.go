@@ -28,7 +28,7 @@ func (sh *skillHandler) CreateSkill(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[skill] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[skill] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -36,7 +36,7 @@ func (sh *skillHandler) CreateSkill(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		logger.Log.Error("failed to read request body", "error", err)
+		logger.FromContext(r.Context()).Error("failed to read request body", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Error reading request body"})
 		return
@@ -45,7 +45,7 @@ func (sh *skillHandler) CreateSkill(w http.ResponseWriter, r *http.Request) {
 
 	var skill db.Skill
 	if err := json.Unmarshal(body, &skill); err != nil {
-		logger.Log.Error("failed to unmarshal skill data", "error", err)
+		logger.FromContext(r.Context()).Error("failed to unmarshal skill data", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid skill data format"})
 		return
@@ -53,9 +53,9 @@ func (sh *skillHandler) CreateSkill(w http.ResponseWriter, r *http.Request) {
 
 	skill.OwnerPubkey = pubKeyFromAuth
 
-	createdSkill, err := sh.db.CreateSkill(&skill)
+	createdSkill, err := sh.db.WithContext(r.Context()).CreateSkill(&skill)
 	if err != nil {
-		logger.Log.Error("failed to create skill", "error", err)
+		logger.FromContext(r.Context()).Error("failed to create skill", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -66,9 +66,9 @@ func (sh *skillHandler) CreateSkill(w http.ResponseWriter, r *http.Request) {
 }
 
 func (sh *skillHandler) GetAllSkills(w http.ResponseWriter, r *http.Request) {
-	skills, err := sh.db.GetAllSkills()
+	skills, err := sh.db.WithContext(r.Context()).GetAllSkills()
 	if err != nil {
-		logger.Log.Error("failed to get all skills", "error", err)
+		logger.FromContext(r.Context()).Error("failed to get all skills", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -93,9 +93,9 @@ func (sh *skillHandler) GetSkillByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	skill, err := sh.db.GetSkillByID(id)
+	skill, err := sh.db.WithContext(r.Context()).GetSkillByID(id)
 	if err != nil {
-		logger.Log.Error("failed to get skill by ID", "error", err, "id", id)
+		logger.FromContext(r.Context()).Error("failed to get skill by ID", "error", err, "id", id)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -110,7 +110,7 @@ func (sh *skillHandler) UpdateSkillByID(w http.ResponseWriter, r *http.Request)
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[skill] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[skill] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -130,16 +130,16 @@ func (sh *skillHandler) UpdateSkillByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	existingSkill, err := sh.db.GetSkillByID(id)
+	existingSkill, err := sh.db.WithContext(r.Context()).GetSkillByID(id)
 	if err != nil {
-		logger.Log.Error("failed to get skill by ID", "error", err, "id", id)
+		logger.FromContext(r.Context()).Error("failed to get skill by ID", "error", err, "id", id)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
 	if existingSkill.OwnerPubkey != pubKeyFromAuth {
-		logger.Log.Info("[skill] unauthorized update attempt", "pubkey", pubKeyFromAuth, "owner", existingSkill.OwnerPubkey)
+		logger.FromContext(r.Context()).Info("[skill] unauthorized update attempt", "pubkey", pubKeyFromAuth, "owner", existingSkill.OwnerPubkey)
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(map[string]string{"error": "You are not authorized to update this skill"})
 		return
@@ -147,7 +147,7 @@ func (sh *skillHandler) UpdateSkillByID(w http.ResponseWriter, r *http.Request)
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		logger.Log.Error("failed to read request body", "error", err)
+		logger.FromContext(r.Context()).Error("failed to read request body", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Error reading request body"})
 		return
@@ -156,7 +156,7 @@ func (sh *skillHandler) UpdateSkillByID(w http.ResponseWriter, r *http.Request)
 
 	var updatedSkill db.Skill
 	if err := json.Unmarshal(body, &updatedSkill); err != nil {
-		logger.Log.Error("failed to unmarshal skill data", "error", err)
+		logger.FromContext(r.Context()).Error("failed to unmarshal skill data", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid skill data format"})
 		return
@@ -165,9 +165,9 @@ func (sh *skillHandler) UpdateSkillByID(w http.ResponseWriter, r *http.Request)
 	updatedSkill.ID = id
 	updatedSkill.OwnerPubkey = pubKeyFromAuth
 
-	result, err := sh.db.UpdateSkillByID(&updatedSkill)
+	result, err := sh.db.WithContext(r.Context()).UpdateSkillByID(&updatedSkill)
 	if err != nil {
-		logger.Log.Error("failed to update skill", "error", err)
+		logger.FromContext(r.Context()).Error("failed to update skill", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -182,7 +182,7 @@ func (sh *skillHandler) DeleteSkillByID(w http.ResponseWriter, r *http.Request)
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[skill] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[skill] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -202,23 +202,23 @@ func (sh *skillHandler) DeleteSkillByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	existingSkill, err := sh.db.GetSkillByID(id)
+	existingSkill, err := sh.db.WithContext(r.Context()).GetSkillByID(id)
 	if err != nil {
-		logger.Log.Error("failed to get skill by ID", "error", err, "id", id)
+		logger.FromContext(r.Context()).Error("failed to get skill by ID", "error", err, "id", id)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
 	if existingSkill.OwnerPubkey != pubKeyFromAuth {
-		logger.Log.Info("[skill] unauthorized delete attempt", "pubkey", pubKeyFromAuth, "owner", existingSkill.OwnerPubkey)
+		logger.FromContext(r.Context()).Info("[skill] unauthorized delete attempt", "pubkey", pubKeyFromAuth, "owner", existingSkill.OwnerPubkey)
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(map[string]string{"error": "You are not authorized to delete this skill"})
 		return
 	}
 
-	if err := sh.db.DeleteSkillByID(id); err != nil {
-		logger.Log.Error("failed to delete skill", "error", err)
+	if err := sh.db.WithContext(r.Context()).DeleteSkillByID(id); err != nil {
+		logger.FromContext(r.Context()).Error("failed to delete skill", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -236,7 +236,7 @@ func (sh *skillHandler) CreateSkillInstall(w http.ResponseWriter, r *http.Reques
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[skill] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[skill] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -256,16 +256,16 @@ func (sh *skillHandler) CreateSkillInstall(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	existingSkill, err := sh.db.GetSkillByID(skillID)
+	existingSkill, err := sh.db.WithContext(r.Context()).GetSkillByID(skillID)
 	if err != nil {
-		logger.Log.Error("failed to get skill by ID", "error", err, "id", skillID)
+		logger.FromContext(r.Context()).Error("failed to get skill by ID", "error", err, "id", skillID)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
 	if existingSkill.OwnerPubkey != pubKeyFromAuth {
-		logger.Log.Info("[skill] unauthorized install creation attempt", "pubkey", pubKeyFromAuth, "owner", existingSkill.OwnerPubkey)
+		logger.FromContext(r.Context()).Info("[skill] unauthorized install creation attempt", "pubkey", pubKeyFromAuth, "owner", existingSkill.OwnerPubkey)
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(map[string]string{"error": "You are not authorized to create installations for this skill"})
 		return
@@ -273,7 +273,7 @@ func (sh *skillHandler) CreateSkillInstall(w http.ResponseWriter, r *http.Reques
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		logger.Log.Error("failed to read request body", "error", err)
+		logger.FromContext(r.Context()).Error("failed to read request body", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Error reading request body"})
 		return
@@ -282,7 +282,7 @@ func (sh *skillHandler) CreateSkillInstall(w http.ResponseWriter, r *http.Reques
 
 	var install db.SkillInstall
 	if err := json.Unmarshal(body, &install); err != nil {
-		logger.Log.Error("failed to unmarshal installation data", "error", err)
+		logger.FromContext(r.Context()).Error("failed to unmarshal installation data", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid installation data format"})
 		return
@@ -290,9 +290,9 @@ func (sh *skillHandler) CreateSkillInstall(w http.ResponseWriter, r *http.Reques
 
 	install.SkillID = skillID
 
-	createdInstall, err := sh.db.CreateSkillInstall(&install)
+	createdInstall, err := sh.db.WithContext(r.Context()).CreateSkillInstall(&install)
 	if err != nil {
-		logger.Log.Error("failed to create skill installation", "error", err)
+		logger.FromContext(r.Context()).Error("failed to create skill installation", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -317,17 +317,17 @@ func (sh *skillHandler) GetAllSkillInstallsBySkillID(w http.ResponseWriter, r *h
 		return
 	}
 
-	_, err = sh.db.GetSkillByID(skillID)
+	_, err = sh.db.WithContext(r.Context()).GetSkillByID(skillID)
 	if err != nil {
-		logger.Log.Error("failed to get skill by ID", "error", err, "id", skillID)
+		logger.FromContext(r.Context()).Error("failed to get skill by ID", "error", err, "id", skillID)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	installs, err := sh.db.GetSkillInstallBySkillsID(skillID)
+	installs, err := sh.db.WithContext(r.Context()).GetSkillInstallBySkillsID(skillID)
 	if err != nil {
-		logger.Log.Error("failed to get skill installations", "error", err, "skill_id", skillID)
+		logger.FromContext(r.Context()).Error("failed to get skill installations", "error", err, "skill_id", skillID)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -342,7 +342,7 @@ func (sh *skillHandler) DeleteSkillInstallByID(w http.ResponseWriter, r *http.Re
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[skill] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[skill] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -362,31 +362,31 @@ func (sh *skillHandler) DeleteSkillInstallByID(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	install, err := sh.db.GetSkillInstallByID(id)
+	install, err := sh.db.WithContext(r.Context()).GetSkillInstallByID(id)
 	if err != nil {
-		logger.Log.Error("failed to get skill installation by ID", "error", err, "id", id)
+		logger.FromContext(r.Context()).Error("failed to get skill installation by ID", "error", err, "id", id)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	skill, err := sh.db.GetSkillByID(install.SkillID)
+	skill, err := sh.db.WithContext(r.Context()).GetSkillByID(install.SkillID)
 	if err != nil {
-		logger.Log.Error("failed to get skill by ID", "error", err, "id", install.SkillID)
+		logger.FromContext(r.Context()).Error("failed to get skill by ID", "error", err, "id", install.SkillID)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
 	if skill.OwnerPubkey != pubKeyFromAuth {
-		logger.Log.Info("[skill] unauthorized delete attempt", "pubkey", pubKeyFromAuth, "owner", skill.OwnerPubkey)
+		logger.FromContext(r.Context()).Info("[skill] unauthorized delete attempt", "pubkey", pubKeyFromAuth, "owner", skill.OwnerPubkey)
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(map[string]string{"error": "You are not authorized to delete installations for this skill"})
 		return
 	}
 
-	if err := sh.db.DeleteSkillInstallByID(id); err != nil {
-		logger.Log.Error("failed to delete skill installation", "error", err)
+	if err := sh.db.WithContext(r.Context()).DeleteSkillInstallByID(id); err != nil {
+		logger.FromContext(r.Context()).Error("failed to delete skill installation", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -404,7 +404,7 @@ func (sh *skillHandler) UpdateSkillInstallByID(w http.ResponseWriter, r *http.Re
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[skill] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[skill] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -424,24 +424,24 @@ func (sh *skillHandler) UpdateSkillInstallByID(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	existingInstall, err := sh.db.GetSkillInstallByID(id)
+	existingInstall, err := sh.db.WithContext(r.Context()).GetSkillInstallByID(id)
 	if err != nil {
-		logger.Log.Error("failed to get skill installation by ID", "error", err, "id", id)
+		logger.FromContext(r.Context()).Error("failed to get skill installation by ID", "error", err, "id", id)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
-	skill, err := sh.db.GetSkillByID(existingInstall.SkillID)
+	skill, err := sh.db.WithContext(r.Context()).GetSkillByID(existingInstall.SkillID)
 	if err != nil {
-		logger.Log.Error("failed to get skill by ID", "error", err, "id", existingInstall.SkillID)
+		logger.FromContext(r.Context()).Error("failed to get skill by ID", "error", err, "id", existingInstall.SkillID)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
 
 	if skill.OwnerPubkey != pubKeyFromAuth {
-		logger.Log.Info("[skill] unauthorized update attempt", "pubkey", pubKeyFromAuth, "owner", skill.OwnerPubkey)
+		logger.FromContext(r.Context()).Info("[skill] unauthorized update attempt", "pubkey", pubKeyFromAuth, "owner", skill.OwnerPubkey)
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(map[string]string{"error": "You are not authorized to update installations for this skill"})
 		return
@@ -449,7 +449,7 @@ func (sh *skillHandler) UpdateSkillInstallByID(w http.ResponseWriter, r *http.Re
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		logger.Log.Error("failed to read request body", "error", err)
+		logger.FromContext(r.Context()).Error("failed to read request body", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Error reading request body"})
 		return
@@ -458,7 +458,7 @@ func (sh *skillHandler) UpdateSkillInstallByID(w http.ResponseWriter, r *http.Re
 
 	var updatedInstall db.SkillInstall
 	if err := json.Unmarshal(body, &updatedInstall); err != nil {
-		logger.Log.Error("failed to unmarshal installation data", "error", err)
+		logger.FromContext(r.Context()).Error("failed to unmarshal installation data", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid installation data format"})
 		return
@@ -467,9 +467,9 @@ func (sh *skillHandler) UpdateSkillInstallByID(w http.ResponseWriter, r *http.Re
 	updatedInstall.ID = id
 	updatedInstall.SkillID = existingInstall.SkillID
 
-	result, err := sh.db.UpdateSkillInstallByID(&updatedInstall)
+	result, err := sh.db.WithContext(r.Context()).UpdateSkillInstallByID(&updatedInstall)
 	if err != nil {
-		logger.Log.Error("failed to update skill installation", "error", err)
+		logger.FromContext(r.Context()).Error("failed to update skill installation", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -494,9 +494,9 @@ func (sh *skillHandler) GetSkillInstallByID(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	install, err := sh.db.GetSkillInstallByID(id)
+	install, err := sh.db.WithContext(r.Context()).GetSkillInstallByID(id)
 	if err != nil {
-		logger.Log.Error("failed to get skill installation by ID", "error", err, "id", id)
+		logger.FromContext(r.Context()).Error("failed to get skill installation by ID", "error", err, "id", id)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
@@ -2,11 +2,76 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
 	"github.com/stakwork/sphinx-tribes/websocket"
 )
 
+// websocketOutboxMaxAge is how long a disconnected client can still
+// replay the messages it missed.
+const websocketOutboxMaxAge = 24 * time.Hour
+
 func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	pool := websocket.WebsocketPool
 	websocket.ServeWs(pool, w, r)
 }
+
+func PruneWebsocketOutbox() {
+	removed, err := db.DB.DeleteOldWebsocketOutboxMessages(websocketOutboxMaxAge)
+	if err != nil {
+		logger.Log.Error("Error pruning websocket outbox: %v", err)
+		return
+	}
+	logger.Log.Info("Removed %d websocket outbox messages older than %v", removed, websocketOutboxMaxAge)
+}
+
+// publishToTopics sends an event to every websocket client subscribed to
+// the given topics. Topics built from an empty id are skipped.
+func publishToTopics(event string, data interface{}, topics ...string) {
+	for _, topic := range topics {
+		if websocket.ValidateTopic(topic) != nil {
+			continue
+		}
+		if err := websocket.WebsocketPool.PublishToTopic(topic, event, data); err != nil {
+			logger.Log.Error("Failed to publish websocket %s event to %s: %v", event, topic, err)
+		}
+	}
+}
+
+func publishTicketEvent(action string, ticket db.Tickets) {
+	publishToTopics(websocket.TopicEventTicket, websocket.TicketMessage{
+		Action: action,
+		TicketDetails: websocket.TicketData{
+			FeatureUUID:       ticket.FeatureUUID,
+			PhaseUUID:         ticket.PhaseUUID,
+			TicketUUID:        ticket.UUID.String(),
+			TicketDescription: ticket.Description,
+			TicketName:        ticket.Name,
+		},
+	}, websocket.FeatureTopic(ticket.FeatureUUID), websocket.WorkspaceTopic(ticket.WorkspaceUuid))
+}
+
+func publishTicketPlanEvent(workspaceUuid string, message websocket.TicketPlanMessage) {
+	message.BroadcastType = ""
+	message.SourceSessionID = ""
+	publishToTopics(websocket.TopicEventTicketPlan, message,
+		websocket.FeatureTopic(message.PlanDetails.FeatureUUID), websocket.WorkspaceTopic(workspaceUuid))
+}
+
+func publishChatEvent(message websocket.TicketMessage) {
+	message.BroadcastType = ""
+	message.SourceSessionID = ""
+	publishToTopics(websocket.TopicEventChatMessage, message, websocket.ChatTopic(message.ChatMessage.ChatID))
+}
+
+func publishActivityEvent(action string, activity *db.Activity) {
+	if activity == nil {
+		return
+	}
+	publishToTopics(websocket.TopicEventActivity, map[string]interface{}{
+		"action":   action,
+		"activity": activity,
+	}, websocket.WorkspaceTopic(activity.Workspace), websocket.FeatureTopic(activity.FeatureUUID))
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/stakwork/sphinx-tribes/audit"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+// GetSpendLimits godoc
+//
+//	@Summary		Get workspace spend limits
+//	@Description	The monthly spend limits of the workspace and of the roles spending its budget, the low balance alert threshold and what the workspace spent this month
+//	@Tags			Workspaces
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string	true	"Workspace UUID"
+//	@Success		200				{object}	db.WorkspaceSpendRules
+//	@Router			/workspaces/{workspace_uuid}/spend-limits [get]
+func (ph *paymentPolicyHandler) GetSpendLimits(w http.ResponseWriter, r *http.Request) {
+	_, workspaceUuid := ph.authorize(w, r, db.ViewReport)
+	if workspaceUuid == "" {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ph.db.GetWorkspaceSpendRules(workspaceUuid))
+}
+
+// UpdateSpendLimits godoc
+//
+//	@Summary		Update workspace spend limits
+//	@Description	Replace the monthly spend limits, in sats per calendar month (UTC). A limit without a role caps the workspace, one with the PAY BOUNTY or WITHDRAW BUDGET role caps each member spending through it; the owner is only held to the workspace limit. The owner is notified once the budget falls below a low balance threshold, 0 turns the alert off.
+//	@Tags			Workspaces
+//	@Accept			json
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string					true	"Workspace UUID"
+//	@Param			rules			body		db.WorkspaceSpendRules	true	"Spend limits"
+//	@Success		200				{object}	db.WorkspaceSpendRules
+//	@Router			/workspaces/{workspace_uuid}/spend-limits [put]
+func (ph *paymentPolicyHandler) UpdateSpendLimits(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, workspaceUuid := ph.authorize(w, r, db.EditOrg)
+	if workspaceUuid == "" {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	rules := db.WorkspaceSpendRules{}
+	if err := json.Unmarshal(body, &rules); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if err := db.ValidateSpendLimits(rules.Limits); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	before := ph.db.GetWorkspaceSpendRules(workspaceUuid)
+	saved, err := ph.db.SaveWorkspaceSpendRules(workspaceUuid, rules)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[payment policy] could not save spend limits of %s: %v", workspaceUuid, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to save spend limits"})
+		return
+	}
+	audit.Record(pubKeyFromAuth, workspaceUuid, audit.EntitySpendLimits, workspaceUuid, audit.ActionUpdate,
+		map[string]interface{}{"limits": before.Limits, "low_balance_threshold": before.LowBalanceThreshold},
+		map[string]interface{}{"limits": saved.Limits, "low_balance_threshold": saved.LowBalanceThreshold})
+
+	// a new threshold may already be crossed
+	enqueueBudgetAlert(workspaceUuid)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(saved)
+}
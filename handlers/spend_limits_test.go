@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/lightning"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetSpendLimits(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Unauthorized without the role", func(t *testing.T) {
+		handler, mockDb, _ := newTestPaymentPolicyHandler(t, false)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		rr := httptest.NewRecorder()
+
+		handler.GetSpendLimits(rr, webhookRequest(http.MethodGet, "/", nil, "someone", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Returns the limits and the month's spend", func(t *testing.T) {
+		handler, mockDb, _ := newTestPaymentPolicyHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWorkspaceSpendRules", workspace.Uuid).Return(db.WorkspaceSpendRules{
+			Limits:              []db.WorkspaceSpendLimit{{WorkspaceUuid: workspace.Uuid, MonthlyLimit: 100000}},
+			LowBalanceThreshold: 5000,
+			MonthSpent:          2500,
+		})
+		rr := httptest.NewRecorder()
+
+		handler.GetSpendLimits(rr, webhookRequest(http.MethodGet, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		rules := db.WorkspaceSpendRules{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rules))
+		assert.Len(t, rules.Limits, 1)
+		assert.Equal(t, uint(5000), rules.LowBalanceThreshold)
+		assert.Equal(t, uint(2500), rules.MonthSpent)
+	})
+}
+
+func TestUpdateSpendLimits(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Unauthorized without the role", func(t *testing.T) {
+		handler, mockDb, _ := newTestPaymentPolicyHandler(t, false)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		rr := httptest.NewRecorder()
+
+		handler.UpdateSpendLimits(rr, webhookRequest(http.MethodPut, "/", db.WorkspaceSpendRules{}, "someone", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	invalid := map[string][]db.WorkspaceSpendLimit{
+		"a role that spends nothing": {{Role: db.EditOrg, MonthlyLimit: 1000}},
+		"a zero limit":               {{MonthlyLimit: 0}},
+		"two limits of a role":       {{Role: db.PayBounty, MonthlyLimit: 1000}, {Role: db.PayBounty, MonthlyLimit: 2000}},
+	}
+	for name, limits := range invalid {
+		t.Run("Rejects "+name, func(t *testing.T) {
+			handler, mockDb, _ := newTestPaymentPolicyHandler(t, true)
+			mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+			rr := httptest.NewRecorder()
+
+			handler.UpdateSpendLimits(rr, webhookRequest(http.MethodPut, "/", db.WorkspaceSpendRules{Limits: limits}, "owner", params))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+
+	t.Run("Saves the rules of the workspace in the path", func(t *testing.T) {
+		handler, mockDb, _ := newTestPaymentPolicyHandler(t, true)
+		rules := db.WorkspaceSpendRules{
+			Limits: []db.WorkspaceSpendLimit{
+				{MonthlyLimit: 100000},
+				{Role: db.PayBounty, MonthlyLimit: 20000},
+				{Role: db.WithdrawBudget, MonthlyLimit: 5000},
+			},
+			LowBalanceThreshold: 10000,
+		}
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWorkspaceSpendRules", workspace.Uuid).Return(db.WorkspaceSpendRules{})
+		mockDb.On("SaveWorkspaceSpendRules", workspace.Uuid, mock.MatchedBy(func(r db.WorkspaceSpendRules) bool {
+			return len(r.Limits) == 3 && r.LowBalanceThreshold == 10000
+		})).Return(rules, nil).Once()
+		rr := httptest.NewRecorder()
+
+		handler.UpdateSpendLimits(rr, webhookRequest(http.MethodPut, "/", rules, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestSpendLimitEnforcement(t *testing.T) {
+	exceeded := fmt.Errorf("%w: you spent 19000 of your 20000 sats this month", db.ErrSpendLimitExceeded)
+
+	t.Run("A bounty payment past the limit is refused", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		bounty := db.NewBounty{ID: 1, Price: 1500, WorkspaceUuid: "workspace-1", Assignee: "hunter"}
+		mockDb.On("GetBounty", bounty.ID).Return(bounty)
+		mockDb.On("GetWorkspaceBudget", bounty.WorkspaceUuid).Return(db.NewBountyBudget{TotalBudget: 5000})
+		mockDb.On("CheckSpendLimit", bounty.WorkspaceUuid, "manager", db.PayBounty, bounty.Price).Return(exceeded)
+		rr := httptest.NewRecorder()
+
+		handler.MakeBountyPayment(rr, webhookRequest(http.MethodPost, "/", db.BountyPayRequest{}, "manager", map[string]string{"id": "1"}))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "monthly spend limit exceeded")
+		assert.Empty(t, node.Payments())
+	})
+
+	t.Run("A withdrawal past the limit is refused", func(t *testing.T) {
+		handler, mockDb, node := newFakeNodeBountyHandler(t)
+		recipient := lightning.NewFakeNode()
+		invoice, _ := recipient.CreateInvoice(800, "withdraw")
+		mockDb.On("GetLastWithdrawal", "workspace-1").Return(db.NewPaymentHistory{})
+		mockDb.On("GetWorkspaceBudget", "workspace-1").Return(db.NewBountyBudget{TotalBudget: 5000})
+		mockDb.On("CheckSpendLimit", "workspace-1", "manager", db.WithdrawBudget, uint(800)).Return(exceeded)
+		rr := httptest.NewRecorder()
+
+		handler.BountyBudgetWithdraw(rr, webhookRequest(http.MethodPost, "/", db.NewWithdrawBudgetRequest{
+			PaymentRequest: invoice.Response.Invoice,
+			WorkspaceUuid:  "workspace-1",
+		}, "manager", nil))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		payError := db.InvoicePayError{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &payError))
+		assert.Contains(t, payError.Error, "monthly spend limit exceeded")
+		assert.Empty(t, node.Payments())
+		mockDb.AssertNotCalled(t, "WithdrawBudget", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -1,8 +1,8 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,13 +15,16 @@ import (
 	"github.com/stakwork/sphinx-tribes/auth"
 	"github.com/stakwork/sphinx-tribes/db"
 	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/tracing"
 	"github.com/stakwork/sphinx-tribes/utils"
 	"github.com/stakwork/sphinx-tribes/websocket"
+	"github.com/stakwork/sphinx-tribes/workflows"
 )
 
 type ticketHandler struct {
 	httpClient HttpClient
 	db         db.Database
+	workflow   workflowResolver
 }
 
 type TicketResponse struct {
@@ -31,17 +34,11 @@ type TicketResponse struct {
 	Errors   []string `json:"errors,omitempty"`
 }
 
-type StakworkResponse struct {
-	Success bool `json:"success"`
-	Data    struct {
-		ProjectID int64 `json:"project_id"`
-	} `json:"data"`
-}
-
 func NewTicketHandler(httpClient HttpClient, database db.Database) *ticketHandler {
 	return &ticketHandler{
 		httpClient: httpClient,
 		db:         database,
+		workflow:   newWorkflowResolver(database, httpClient),
 	}
 }
 
@@ -140,7 +137,7 @@ func (th *ticketHandler) UpdateTicket(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -235,6 +232,14 @@ func (th *ticketHandler) UpdateTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	publishTicketEvent("update", createdTicket)
+	if existingTicket.UUID != uuid.Nil {
+		emitTicketStatusWebhook(th.db, createdTicket, existingTicket.Status)
+		recordTicketChange(th.db, pubKeyFromAuth, &existingTicket, &createdTicket)
+	} else {
+		recordTicketChange(th.db, pubKeyFromAuth, nil, &createdTicket)
+	}
+
 	if updateRequest.Metadata.Source == "websocket" && updateRequest.Metadata.ID != "" {
 		ticketMsg := websocket.TicketMessage{
 			BroadcastType:   "direct",
@@ -281,7 +286,7 @@ func (th *ticketHandler) UpdateTicketSequence(w http.ResponseWriter, r *http.Req
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -330,7 +335,7 @@ func (th *ticketHandler) UpdateTicketSequence(w http.ResponseWriter, r *http.Req
 
 		_, err := th.db.CreateOrEditTicket(&ticket)
 		if err != nil {
-			logger.Log.Error(fmt.Sprintf("Failed to update ticket UUID: %s, error: %v", ticket.UUID.String(), err))
+			logger.FromContext(r.Context()).Error(fmt.Sprintf("Failed to update ticket UUID: %s, error: %v", ticket.UUID.String(), err))
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to update ticket sequences: %v", err)})
 			return
@@ -357,7 +362,7 @@ func (th *ticketHandler) DeleteTicket(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -383,7 +388,7 @@ func (th *ticketHandler) DeleteTicket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := th.db.DeleteTicketGroup(*ticket.TicketGroup); err != nil {
-		logger.Log.Error("failed to delete ticket group",
+		logger.FromContext(r.Context()).Error("failed to delete ticket group",
 			"error", err,
 			"ticket_group", ticket.TicketGroup)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -391,9 +396,12 @@ func (th *ticketHandler) DeleteTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logger.Log.Info("ticket group deleted successfully",
+	logger.FromContext(r.Context()).Info("ticket group deleted successfully",
 		"ticket_group", ticket.TicketGroup)
 
+	publishTicketEvent("delete", ticket)
+	recordTicketChange(th.db, pubKeyFromAuth, &ticket, nil)
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Ticket group deleted successfully"})
 }
@@ -414,7 +422,7 @@ func (th *ticketHandler) PostTicketDataToStakwork(w http.ResponseWriter, r *http
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -423,7 +431,7 @@ func (th *ticketHandler) PostTicketDataToStakwork(w http.ResponseWriter, r *http
 	user := th.db.GetPersonByPubkey(pubKeyFromAuth)
 
 	if user.OwnerPubKey != pubKeyFromAuth {
-		logger.Log.Info("Person not exists")
+		logger.FromContext(r.Context()).Info("Person not exists")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -542,6 +550,7 @@ func (th *ticketHandler) PostTicketDataToStakwork(w http.ResponseWriter, r *http
 	}
 
 	webhookURL := fmt.Sprintf("%s/bounties/ticket/review", host)
+	webhookURL = tracing.InjectURL(ctx, webhookURL)
 
 	var schematicURL string
 	if feature.WorkspaceUuid != "" {
@@ -581,115 +590,57 @@ func (th *ticketHandler) PostTicketDataToStakwork(w http.ResponseWriter, r *http
       mode = ticketRequest.Ticket.Mode
    }
 
-	stakworkPayload := map[string]interface{}{
-		"name":        "Hive Ticket Builder",
-		"workflow_id": 37324,
-		"workflow_params": map[string]interface{}{
-			"set_var": map[string]interface{}{
-				"attributes": map[string]interface{}{
-					"vars": map[string]interface{}{
-						"featureUUID":         ticket.FeatureUUID,
-						"phaseUUID":           ticket.PhaseUUID,
-						"ticketUUID":          ticket.UUID.String(),
-						"phaseOutcome":        phase.PhaseOutcome,
-						"phasePurpose":        phase.PhasePurpose,
-						"phaseScope":          phase.PhaseScope,
-						"phaseDesign":         phase.PhaseDesign,
-						"ticketName":          ticket.Name,
-						"ticketDescription":   ticket.Description,
-						"productBrief":        productBrief,
-						"FeatureArchitecture": featureArchitecture,
-						"featureBrief":        featureBrief,
-						"examples":            "",
-						"sourceWebsocket":     ticketRequest.Metadata.ID,
-						"webhook_url":         webhookURL,
-						"phaseSchematic":      schematicURL,
-						"codeGraph":           codeGraphURL,
-						"codeGraphAlias":      codeGraphAlias,
-						"alias":               user.OwnerAlias,
-						"mode":                mode,
-					},
-				},
-			},
+	provider, workflowID := th.workflow(feature.WorkspaceUuid, workflows.KindTicketReview)
+	run, err := provider.Submit(ctx, workflows.Job{
+		Name:       "Hive Ticket Builder",
+		WorkflowID: workflowID,
+		Vars: map[string]interface{}{
+			"featureUUID":         ticket.FeatureUUID,
+			"phaseUUID":           ticket.PhaseUUID,
+			"ticketUUID":          ticket.UUID.String(),
+			"phaseOutcome":        phase.PhaseOutcome,
+			"phasePurpose":        phase.PhasePurpose,
+			"phaseScope":          phase.PhaseScope,
+			"phaseDesign":         phase.PhaseDesign,
+			"ticketName":          ticket.Name,
+			"ticketDescription":   ticket.Description,
+			"productBrief":        productBrief,
+			"FeatureArchitecture": featureArchitecture,
+			"featureBrief":        featureBrief,
+			"examples":            "",
+			"sourceWebsocket":     ticketRequest.Metadata.ID,
+			"webhook_url":         webhookURL,
+			"phaseSchematic":      schematicURL,
+			"codeGraph":           codeGraphURL,
+			"codeGraphAlias":      codeGraphAlias,
+			"alias":               user.OwnerAlias,
+			"mode":                mode,
 		},
-	}
-
-	stakworkPayloadJSON, err := json.Marshal(stakworkPayload)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketResponse{
-			Success: false,
-			Message: "Error encoding payload",
-			Errors:  []string{err.Error()},
-		})
-		return
-	}
-
-	apiKey := os.Getenv("SWWFKEY")
-	if apiKey == "" {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketResponse{
-			Success: false,
-			Message: "API key not set in environment",
-		})
-		return
-	}
-
-	req, err := http.NewRequest(http.MethodPost, "https://api.stakwork.com/api/v1/projects", bytes.NewBuffer(stakworkPayloadJSON))
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketResponse{
-			Success: false,
-			Message: "Error creating request",
-			Errors:  []string{err.Error()},
-		})
-		return
-	}
-
-	req.Header.Set("Authorization", "Token token="+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := th.httpClient.Do(req)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketResponse{
-			Success: false,
-			Message: "Error sending request to Stakwork",
-			Errors:  []string{err.Error()},
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketResponse{
-			Success: false,
-			Message: "Error reading response from Stakwork",
-			Errors:  []string{err.Error()},
-		})
-		return
-	}
-
-	var stakworkResp StakworkResponse
-	if err := json.Unmarshal(respBody, &stakworkResp); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketResponse{
-			Success: false,
-			Message: "Error parsing Stakwork response",
-			Errors:  []string{err.Error()},
-		})
-		return
-	}
-
-	if resp.StatusCode != http.StatusOK || !stakworkResp.Success {
-		w.WriteHeader(resp.StatusCode)
-		json.NewEncoder(w).Encode(TicketResponse{
-			Success: false,
-			Message: string(respBody),
-			Errors:  []string{fmt.Sprintf("Stakwork API returned status code: %d", resp.StatusCode)},
-		})
+		var apiErr *workflows.APIError
+		switch {
+		case errors.Is(err, workflows.ErrNoCredentials):
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(TicketResponse{
+				Success: false,
+				Message: "API key not set in environment",
+			})
+		case errors.As(err, &apiErr):
+			w.WriteHeader(apiErr.StatusCode)
+			json.NewEncoder(w).Encode(TicketResponse{
+				Success: false,
+				Message: apiErr.Body,
+				Errors:  []string{fmt.Sprintf("Stakwork API returned status code: %d", apiErr.StatusCode)},
+			})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(TicketResponse{
+				Success: false,
+				Message: "Error sending request to Stakwork",
+				Errors:  []string{err.Error()},
+			})
+		}
 		return
 	}
 
@@ -717,34 +668,36 @@ func (th *ticketHandler) PostTicketDataToStakwork(w http.ResponseWriter, r *http
 			return
 		}
 
-		projectMsg := websocket.TicketMessage{
-			BroadcastType:   "direct",
-			SourceSessionID: ticketRequest.Metadata.ID,
-			Message:         fmt.Sprintf("https://jobs.stakwork.com/admin/projects/%d", stakworkResp.Data.ProjectID),
-			Action:          "swrun",
-			TicketDetails: websocket.TicketData{
-				FeatureUUID:       ticketRequest.Ticket.FeatureUUID,
-				PhaseUUID:         ticketRequest.Ticket.PhaseUUID,
-				TicketUUID:        ticketRequest.Ticket.UUID.String(),
-				TicketDescription: ticketRequest.Ticket.Description,
-			},
-		}
+		if run.URL != "" {
+			projectMsg := websocket.TicketMessage{
+				BroadcastType:   "direct",
+				SourceSessionID: ticketRequest.Metadata.ID,
+				Message:         run.URL,
+				Action:          "swrun",
+				TicketDetails: websocket.TicketData{
+					FeatureUUID:       ticketRequest.Ticket.FeatureUUID,
+					PhaseUUID:         ticketRequest.Ticket.PhaseUUID,
+					TicketUUID:        ticketRequest.Ticket.UUID.String(),
+					TicketDescription: ticketRequest.Ticket.Description,
+				},
+			}
 
-		if err := websocket.WebsocketPool.SendTicketMessage(projectMsg); err != nil {
-			log.Printf("Failed to send project ID websocket message: %v", err)
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"ticket":          ticketRequest,
-				"websocket_error": err.Error(),
-			})
-			return
+			if err := websocket.WebsocketPool.SendTicketMessage(projectMsg); err != nil {
+				log.Printf("Failed to send project ID websocket message: %v", err)
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"ticket":          ticketRequest,
+					"websocket_error": err.Error(),
+				})
+				return
+			}
 		}
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(TicketResponse{
 		Success:  true,
-		Message:  string(respBody),
+		Message:  run.Response,
 		TicketID: ticket.UUID.String(),
 	})
 }
@@ -822,6 +775,8 @@ func (th *ticketHandler) ProcessTicketReview(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	publishTicketEvent("process", createdTicket)
+
 	ticketMsg := websocket.TicketMessage{
 		BroadcastType:   "direct",
 		SourceSessionID: reviewReq.SourceWebsocket,
@@ -867,7 +822,7 @@ func (th *ticketHandler) GetTicketsByPhaseUUID(w http.ResponseWriter, r *http.Re
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -927,7 +882,7 @@ func (th *ticketHandler) TicketToBounty(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("no pubkey from auth")
+		logger.FromContext(r.Context()).Error("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -945,18 +900,18 @@ func (th *ticketHandler) TicketToBounty(w http.ResponseWriter, r *http.Request)
 
 	ticket, err := th.db.GetTicket(ticketUUID)
 	if err != nil {
-		logger.Log.Error("failed to fetch ticket", "error", err, "ticket_uuid", ticketUUID)
+		logger.FromContext(r.Context()).Error("failed to fetch ticket", "error", err, "ticket_uuid", ticketUUID)
 		http.Error(w, "failed to fetch ticket", http.StatusNotFound)
 		return
 	}
 
-	logger.Log.Info("creating bounty from ticket",
+	logger.FromContext(r.Context()).Info("creating bounty from ticket",
 		"ticket_uuid", ticketUUID,
 		"pubkey", pubKeyFromAuth)
 
-	bounty, err := th.db.CreateBountyFromTicket(ticket, pubKeyFromAuth)
+	bounty, err := th.db.WithContext(r.Context()).CreateBountyFromTicket(ticket, pubKeyFromAuth)
 	if err != nil {
-		logger.Log.Error("failed to create bounty",
+		logger.FromContext(r.Context()).Error("failed to create bounty",
 			"error", err,
 			"ticket_uuid", ticketUUID,
 			"pubkey", pubKeyFromAuth)
@@ -964,13 +919,13 @@ func (th *ticketHandler) TicketToBounty(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	logger.Log.Info("bounty created successfully",
+	logger.FromContext(r.Context()).Info("bounty created successfully",
 		"bounty_id", bounty.ID,
 		"owner_id", bounty.OwnerID)
 
 	// Delete the ticket after successful bounty creation
 	if err := th.db.DeleteTicketGroup(*ticket.TicketGroup); err != nil {
-		logger.Log.Error("failed to delete ticket group after bounty creation",
+		logger.FromContext(r.Context()).Error("failed to delete ticket group after bounty creation",
 			"error", err,
 			"ticket_group", ticket.TicketGroup)
 
@@ -984,7 +939,7 @@ func (th *ticketHandler) TicketToBounty(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	logger.Log.Info("ticket deleted successfully after bounty creation",
+	logger.FromContext(r.Context()).Info("ticket deleted successfully after bounty creation",
 		"ticket_uuid", ticketUUID)
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1012,7 +967,7 @@ func (th *ticketHandler) GetTicketsByGroup(w http.ResponseWriter, r *http.Reques
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Error("no pubkey from auth")
+		logger.FromContext(r.Context()).Error("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1031,7 +986,7 @@ func (th *ticketHandler) GetTicketsByGroup(w http.ResponseWriter, r *http.Reques
 
 	tickets, err := th.db.GetTicketsByGroup(parsedUUID.String())
 	if err != nil {
-		logger.Log.Error("failed to fetch tickets by group", "error", err, "group_uuid", groupUUID)
+		logger.FromContext(r.Context()).Error("failed to fetch tickets by group", "error", err, "group_uuid", groupUUID)
 		http.Error(w, "failed to fetch tickets", http.StatusInternalServerError)
 		return
 	}
@@ -1059,7 +1014,7 @@ func (th *ticketHandler) CreateWorkspaceDraftTicket(w http.ResponseWriter, r *ht
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -1107,6 +1062,9 @@ func (th *ticketHandler) CreateWorkspaceDraftTicket(w http.ResponseWriter, r *ht
 		return
 	}
 
+	publishTicketEvent("create", createdTicket)
+	recordTicketChange(th.db, pubKeyFromAuth, nil, &createdTicket)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(createdTicket)
 }
@@ -1130,7 +1088,7 @@ func (th *ticketHandler) GetWorkspaceDraftTicket(w http.ResponseWriter, r *http.
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -1178,7 +1136,7 @@ func (th *ticketHandler) UpdateWorkspaceDraftTicket(w http.ResponseWriter, r *ht
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -1218,6 +1176,8 @@ func (th *ticketHandler) UpdateWorkspaceDraftTicket(w http.ResponseWriter, r *ht
 	if ticketRequest.Description != "" {
 		existingTicket.Description = ticketRequest.Description
 	}
+	previousTicket := existingTicket
+	previousStatus := existingTicket.Status
 	if ticketRequest.Status != "" {
 		if !db.IsValidTicketStatus(ticketRequest.Status) {
 			w.WriteHeader(http.StatusBadRequest)
@@ -1234,6 +1194,10 @@ func (th *ticketHandler) UpdateWorkspaceDraftTicket(w http.ResponseWriter, r *ht
 		return
 	}
 
+	publishTicketEvent("update", updatedTicket)
+	emitTicketStatusWebhook(th.db, updatedTicket, previousStatus)
+	recordTicketChange(th.db, pubKeyFromAuth, &previousTicket, &updatedTicket)
+
 	ticketMsg := websocket.TicketMessage{
 		BroadcastType: "direct",
 		Action:        "update",
@@ -1276,7 +1240,7 @@ func (th *ticketHandler) DeleteWorkspaceDraftTicket(w http.ResponseWriter, r *ht
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -1295,7 +1259,7 @@ func (th *ticketHandler) DeleteWorkspaceDraftTicket(w http.ResponseWriter, r *ht
 		return
 	}
 
-	_, err := th.db.GetWorkspaceDraftTicket(workspaceUuid, ticketUuid)
+	draftTicket, err := th.db.GetWorkspaceDraftTicket(workspaceUuid, ticketUuid)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "ticket not found"})
@@ -1308,6 +1272,9 @@ func (th *ticketHandler) DeleteWorkspaceDraftTicket(w http.ResponseWriter, r *ht
 		return
 	}
 
+	publishTicketEvent("delete", draftTicket)
+	recordTicketChange(th.db, pubKeyFromAuth, &draftTicket, nil)
+
 	ticketMsg := websocket.TicketMessage{
 		BroadcastType: "direct",
 		Action:        "delete",
@@ -1317,7 +1284,7 @@ func (th *ticketHandler) DeleteWorkspaceDraftTicket(w http.ResponseWriter, r *ht
 	}
 
 	if err := websocket.WebsocketPool.SendTicketMessage(ticketMsg); err != nil {
-		logger.Log.Error("Failed to send websocket message", "error", err)
+		logger.FromContext(r.Context()).Error("Failed to send websocket message", "error", err)
 	}
 
 	w.WriteHeader(http.StatusNoContent)
@@ -1377,7 +1344,7 @@ func (th *ticketHandler) TicketsToBounties(w http.ResponseWriter, r *http.Reques
 			continue
 		}
 
-		bounty, err := th.db.CreateBountyFromTicket(ticket, pubKeyFromAuth)
+		bounty, err := th.db.WithContext(r.Context()).CreateBountyFromTicket(ticket, pubKeyFromAuth)
 		if err != nil {
 			result.Message = fmt.Sprintf("Failed to create bounty: %v", err)
 			results = append(results, result)
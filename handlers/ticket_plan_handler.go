@@ -1,8 +1,8 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,10 +12,13 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
+	"github.com/stakwork/sphinx-tribes/audit"
 	"github.com/stakwork/sphinx-tribes/auth"
 	"github.com/stakwork/sphinx-tribes/db"
 	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/tracing"
 	"github.com/stakwork/sphinx-tribes/websocket"
+	"github.com/stakwork/sphinx-tribes/workflows"
 )
 
 type CreateTicketPlanRequest struct {
@@ -70,7 +73,7 @@ func (th *ticketHandler) CreateTicketPlan(w http.ResponseWriter, r *http.Request
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket plan] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket plan] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -142,6 +145,17 @@ func (th *ticketHandler) CreateTicketPlan(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	audit.Record(pubKeyFromAuth, createdPlan.WorkspaceUuid, audit.EntityTicketPlan, createdPlan.UUID.String(), audit.ActionCreate, nil, createdPlan)
+
+	publishTicketPlanEvent(createdPlan.WorkspaceUuid, websocket.TicketPlanMessage{
+		Message: fmt.Sprintf("Created ticket plan %s", createdPlan.UUID.String()),
+		Action:  "TICKET_PLAN_CREATED",
+		PlanDetails: websocket.TicketPlanDetails{
+			FeatureUUID: createdPlan.FeatureUUID,
+			PhaseUUID:   createdPlan.PhaseUUID,
+		},
+	})
+
 	if planRequest.SourceWebsocket != "" {
 		websocketErr := websocket.WebsocketPool.SendTicketMessage(websocket.TicketMessage{
 			BroadcastType:   "direct",
@@ -151,7 +165,7 @@ func (th *ticketHandler) CreateTicketPlan(w http.ResponseWriter, r *http.Request
 		})
 
 		if websocketErr != nil {
-			logger.Log.Error("Failed to send websocket message", "error", websocketErr)
+			logger.FromContext(r.Context()).Error("Failed to send websocket message", "error", websocketErr)
 		}
 	}
 
@@ -179,7 +193,7 @@ func (th *ticketHandler) GetTicketPlan(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket plan] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket plan] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -223,7 +237,7 @@ func (th *ticketHandler) DeleteTicketPlan(w http.ResponseWriter, r *http.Request
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket plan] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket plan] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -236,6 +250,7 @@ func (th *ticketHandler) DeleteTicketPlan(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	plan, _ := th.db.GetTicketPlan(uuid)
 	err := th.db.DeleteTicketPlan(uuid)
 	if err != nil {
 		status := http.StatusInternalServerError
@@ -246,6 +261,9 @@ func (th *ticketHandler) DeleteTicketPlan(w http.ResponseWriter, r *http.Request
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
+	if plan != nil {
+		audit.Record(pubKeyFromAuth, plan.WorkspaceUuid, audit.EntityTicketPlan, uuid, audit.ActionDelete, plan, nil)
+	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Ticket plan deleted successfully"})
@@ -267,7 +285,7 @@ func (th *ticketHandler) GetTicketPlansByFeature(w http.ResponseWriter, r *http.
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket plan] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket plan] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -307,7 +325,7 @@ func (th *ticketHandler) GetTicketPlansByPhase(w http.ResponseWriter, r *http.Re
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket plan] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket plan] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -347,7 +365,7 @@ func (th *ticketHandler) GetTicketPlansByWorkspace(w http.ResponseWriter, r *htt
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket plan] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket plan] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -387,7 +405,7 @@ func (th *ticketHandler) SendTicketPlanToStakwork(w http.ResponseWriter, r *http
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[ticket plan] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[ticket plan] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
 		return
@@ -395,7 +413,7 @@ func (th *ticketHandler) SendTicketPlanToStakwork(w http.ResponseWriter, r *http
 
 	user := th.db.GetPersonByPubkey(pubKeyFromAuth)
 	if user.OwnerPubKey != pubKeyFromAuth {
-		logger.Log.Info("Person not exists")
+		logger.FromContext(r.Context()).Info("Person not exists")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -493,6 +511,7 @@ func (th *ticketHandler) SendTicketPlanToStakwork(w http.ResponseWriter, r *http
 	}
 
 	webhookURL := fmt.Sprintf("%s/bounties/ticket/plan/review", host)
+	webhookURL = tracing.InjectURL(ctx, webhookURL)
 
 	var schematicURL string
 	if feature.WorkspaceUuid != "" {
@@ -526,114 +545,66 @@ func (th *ticketHandler) SendTicketPlanToStakwork(w http.ResponseWriter, r *http
 
 	ticketArray := th.db.BuildTicketArray(planRequest.TicketGroupIDs)
 
-	stakworkPayload := map[string]interface{}{
-		"name":        "Ticket Plan Builder",
-		"workflow_id": 42472,
-		"workflow_params": map[string]interface{}{
-			"set_var": map[string]interface{}{
-				"attributes": map[string]interface{}{
-					"vars": map[string]interface{}{
-						"featureUUID":     planRequest.FeatureID,
-						"phaseUUID":       planRequest.PhaseID,
-						"ticketPlanUUID":  uuid.New().String(),
-						"phaseOutcome":    phase.PhaseOutcome,
-						"phasePurpose":    phase.PhasePurpose,
-						"phaseScope":      phase.PhaseScope,
-						"phaseDesign":     phaseDesign,
-						"ticketArray":     ticketArray,
-						"productBrief":    productBrief,
-						"featureBrief":    featureBrief,
-						"sourceWebsocket": planRequest.SourceWebsocket,
-						"webhook_url":     webhookURL,
-						"phaseSchematic":  schematicURL,
-						"codeGraph":       codeGraphURL,
-						"alias":           user.OwnerAlias,
-						"requestUUID":     planRequest.RequestUUID,
-						"codeGraphAlias":  codeGraphAlias,
-					},
-				},
-			},
+	provider, workflowID := th.workflow(feature.WorkspaceUuid, workflows.KindPlanReview)
+	run, err := provider.Submit(ctx, workflows.Job{
+		Name:       "Ticket Plan Builder",
+		WorkflowID: workflowID,
+		Vars: map[string]interface{}{
+			"featureUUID":     planRequest.FeatureID,
+			"phaseUUID":       planRequest.PhaseID,
+			"ticketPlanUUID":  uuid.New().String(),
+			"phaseOutcome":    phase.PhaseOutcome,
+			"phasePurpose":    phase.PhasePurpose,
+			"phaseScope":      phase.PhaseScope,
+			"phaseDesign":     phaseDesign,
+			"ticketArray":     ticketArray,
+			"productBrief":    productBrief,
+			"featureBrief":    featureBrief,
+			"sourceWebsocket": planRequest.SourceWebsocket,
+			"webhook_url":     webhookURL,
+			"phaseSchematic":  schematicURL,
+			"codeGraph":       codeGraphURL,
+			"alias":           user.OwnerAlias,
+			"requestUUID":     planRequest.RequestUUID,
+			"codeGraphAlias":  codeGraphAlias,
 		},
-	}
-
-	stakworkPayloadJSON, err := json.Marshal(stakworkPayload)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketPlanResponse{
-			Success: false,
-			Message: "Error encoding payload",
-			Errors:  []string{err.Error()},
-		})
-		return
-	}
-
-	apiKey := os.Getenv("SWWFKEY")
-	if apiKey == "" {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketPlanResponse{
-			Success: false,
-			Message: "API key not set in environment",
-		})
-		return
-	}
-
-	req, err := http.NewRequest(http.MethodPost, "https://api.stakwork.com/api/v1/projects", bytes.NewBuffer(stakworkPayloadJSON))
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketPlanResponse{
-			Success: false,
-			Message: "Error creating request",
-			Errors:  []string{err.Error()},
-		})
-		return
-	}
-
-	req.Header.Set("Authorization", "Token token="+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := th.httpClient.Do(req)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketPlanResponse{
-			Success: false,
-			Message: "Error sending request to Stakwork",
-			Errors:  []string{err.Error()},
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketPlanResponse{
-			Success: false,
-			Message: "Error reading response from Stakwork",
-			Errors:  []string{err.Error()},
-		})
-		return
-	}
-
-	var stakworkResp StakworkResponse
-	if err := json.Unmarshal(respBody, &stakworkResp); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(TicketPlanResponse{
-			Success: false,
-			Message: "Error parsing Stakwork response",
-			Errors:  []string{err.Error()},
-		})
+		var apiErr *workflows.APIError
+		switch {
+		case errors.Is(err, workflows.ErrNoCredentials):
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(TicketPlanResponse{
+				Success: false,
+				Message: "API key not set in environment",
+			})
+		case errors.As(err, &apiErr):
+			w.WriteHeader(apiErr.StatusCode)
+			json.NewEncoder(w).Encode(TicketPlanResponse{
+				Success: false,
+				Message: apiErr.Body,
+				Errors:  []string{fmt.Sprintf("Stakwork API returned status code: %d", apiErr.StatusCode)},
+			})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(TicketPlanResponse{
+				Success: false,
+				Message: "Error sending request to Stakwork",
+				Errors:  []string{err.Error()},
+			})
+		}
 		return
 	}
 
-	if resp.StatusCode != http.StatusOK || !stakworkResp.Success {
-		w.WriteHeader(resp.StatusCode)
-		json.NewEncoder(w).Encode(TicketPlanResponse{
-			Success: false,
-			Message: string(respBody),
-			Errors:  []string{fmt.Sprintf("Stakwork API returned status code: %d", resp.StatusCode)},
-		})
-		return
-	}
+	publishTicketPlanEvent(feature.WorkspaceUuid, websocket.TicketPlanMessage{
+		Message: "Processing ticket plan generation",
+		Action:  "TICKET_PLAN_PROCESSING",
+		PlanDetails: websocket.TicketPlanDetails{
+			RequestUUID: planRequest.RequestUUID,
+			FeatureUUID: planRequest.FeatureID,
+			PhaseUUID:   planRequest.PhaseID,
+		},
+	})
 
 	if planRequest.SourceWebsocket != "" {
 		ticketMsg := websocket.TicketPlanMessage{
@@ -658,33 +629,35 @@ func (th *ticketHandler) SendTicketPlanToStakwork(w http.ResponseWriter, r *http
 			return
 		}
 
-		projectMsg := websocket.TicketPlanMessage{
-			BroadcastType:   "direct",
-			SourceSessionID: planRequest.SourceWebsocket,
-			Message:         fmt.Sprintf("https://jobs.stakwork.com/admin/projects/%d", stakworkResp.Data.ProjectID),
-			Action:          "swrun",
-			PlanDetails: websocket.TicketPlanDetails{
-				RequestUUID: planRequest.RequestUUID,
-				FeatureUUID: planRequest.FeatureID,
-				PhaseUUID:   planRequest.PhaseID,
-			},
-		}
+		if run.URL != "" {
+			projectMsg := websocket.TicketPlanMessage{
+				BroadcastType:   "direct",
+				SourceSessionID: planRequest.SourceWebsocket,
+				Message:         run.URL,
+				Action:          "swrun",
+				PlanDetails: websocket.TicketPlanDetails{
+					RequestUUID: planRequest.RequestUUID,
+					FeatureUUID: planRequest.FeatureID,
+					PhaseUUID:   planRequest.PhaseID,
+				},
+			}
 
-		if err := websocket.WebsocketPool.SendTicketPlanMessage(projectMsg); err != nil {
-			log.Printf("Failed to send project ID websocket message: %v", err)
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"plan":            planRequest,
-				"websocket_error": err.Error(),
-			})
-			return
+			if err := websocket.WebsocketPool.SendTicketPlanMessage(projectMsg); err != nil {
+				log.Printf("Failed to send project ID websocket message: %v", err)
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"plan":            planRequest,
+					"websocket_error": err.Error(),
+				})
+				return
+			}
 		}
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(SendTicketPlanResponse{
 		Success:     true,
-		Message:     string(respBody),
+		Message:     run.Response,
 		RequestUUID: planRequest.RequestUUID,
 	})
 }
@@ -788,6 +761,7 @@ func (th *ticketHandler) ProcessTicketPlanReview(w http.ResponseWriter, r *http.
 			return
 		}
 		createdTickets = append(createdTickets, createdTicket)
+		publishTicketEvent("process", createdTicket)
 
 		if planReview.SourceWebsocket != "" {
 			ticketMsg := websocket.TicketMessage{
@@ -810,19 +784,21 @@ func (th *ticketHandler) ProcessTicketPlanReview(w http.ResponseWriter, r *http.
 		}
 	}
 
-	if planReview.SourceWebsocket != "" {
-		completionMsg := websocket.TicketPlanMessage{
-			BroadcastType:   "direct",
-			SourceSessionID: planReview.SourceWebsocket,
-			Message:         fmt.Sprintf("Successfully created %d tickets for phase %s", len(createdTickets), phase.Name),
-			Action:          "TICKET_PLAN_COMPLETED",
-			PlanDetails: websocket.TicketPlanDetails{
-				RequestUUID: planReview.RequestUUID,
-				FeatureUUID: planReview.Value.FeatureUUID,
-				PhaseUUID:   planReview.Value.PhaseUUID,
-			},
-		}
+	completionMsg := websocket.TicketPlanMessage{
+		BroadcastType:   "direct",
+		SourceSessionID: planReview.SourceWebsocket,
+		Message:         fmt.Sprintf("Successfully created %d tickets for phase %s", len(createdTickets), phase.Name),
+		Action:          "TICKET_PLAN_COMPLETED",
+		PlanDetails: websocket.TicketPlanDetails{
+			RequestUUID: planReview.RequestUUID,
+			FeatureUUID: planReview.Value.FeatureUUID,
+			PhaseUUID:   planReview.Value.PhaseUUID,
+		},
+	}
+
+	publishTicketPlanEvent(feature.WorkspaceUuid, completionMsg)
 
+	if planReview.SourceWebsocket != "" {
 		if err := websocket.WebsocketPool.SendTicketPlanMessage(completionMsg); err != nil {
 			log.Printf("Failed to send completion websocket message: %v", err)
 		}
@@ -3,9 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -13,14 +11,15 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/stakwork/sphinx-tribes/auth"
-	"github.com/stakwork/sphinx-tribes/config"
 	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/lightning"
 	"github.com/stakwork/sphinx-tribes/logger"
 	"github.com/stakwork/sphinx-tribes/utils"
 )
 
 type tribeHandler struct {
 	db                      db.Database
+	lightning               LightningBackend
 	verifyTribeUUID         func(uuid string, checkTimestamp bool) (string, error)
 	tribeUniqueNameFromName func(name string) (string, error)
 }
@@ -28,6 +27,7 @@ type tribeHandler struct {
 func NewTribeHandler(db db.Database) *tribeHandler {
 	return &tribeHandler{
 		db:                      db,
+		lightning:               lightning.FromConfig(http.DefaultClient),
 		verifyTribeUUID:         auth.VerifyTribeUUID,
 		tribeUniqueNameFromName: TribeUniqueNameFromName,
 	}
@@ -150,7 +150,7 @@ func PutTribeStats(w http.ResponseWriter, r *http.Request) {
 	r.Body.Close()
 	err = json.Unmarshal(body, &tribe)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -162,7 +162,7 @@ func PutTribeStats(w http.ResponseWriter, r *http.Request) {
 
 	extractedPubkey, err := auth.VerifyTribeUUID(tribe.UUID, false)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -209,7 +209,7 @@ func (th *tribeHandler) DeleteTribe(w http.ResponseWriter, r *http.Request) {
 
 	extractedPubkey, err := th.verifyTribeUUID(uuid, false)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -319,13 +319,13 @@ func (th *tribeHandler) CreateOrEditTribe(w http.ResponseWriter, r *http.Request
 	r.Body.Close()
 	err = json.Unmarshal(body, &tribe)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
 
 	if tribe.UUID == "" {
-		logger.Log.Info("createOrEditTribe no uuid")
+		logger.FromContext(r.Context()).Info("createOrEditTribe no uuid")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -334,7 +334,7 @@ func (th *tribeHandler) CreateOrEditTribe(w http.ResponseWriter, r *http.Request
 
 	extractedPubkey, err := th.verifyTribeUUID(tribe.UUID, false)
 	if err != nil {
-		logger.Log.Error("extract UUID error: %v", err)
+		logger.FromContext(r.Context()).Error("extract UUID error: %v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -343,7 +343,7 @@ func (th *tribeHandler) CreateOrEditTribe(w http.ResponseWriter, r *http.Request
 		tribe.Created = &now
 	} else { // IF PUBKEY IN CONTEXT, MUST AUTH!
 		if pubKeyFromAuth != extractedPubkey {
-			logger.Log.Info("createOrEditTribe pubkeys dont match")
+			logger.FromContext(r.Context()).Info("createOrEditTribe pubkeys dont match")
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
@@ -354,9 +354,9 @@ func (th *tribeHandler) CreateOrEditTribe(w http.ResponseWriter, r *http.Request
 		tribe.UniqueName, _ = th.tribeUniqueNameFromName(tribe.Name)
 	} else { // already exists! make sure it's owned
 		if existing.OwnerPubKey != extractedPubkey {
-			logger.Log.Info("createOrEditTribe tribe.ownerPubKey not match")
-			logger.Log.Info("existing owner: %s", existing.OwnerPubKey)
-			logger.Log.Info("extracted pubkey: %s", extractedPubkey)
+			logger.FromContext(r.Context()).Info("createOrEditTribe tribe.ownerPubKey not match")
+			logger.FromContext(r.Context()).Info("existing owner: %s", existing.OwnerPubKey)
+			logger.FromContext(r.Context()).Info("extracted pubkey: %s", extractedPubkey)
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
@@ -368,7 +368,7 @@ func (th *tribeHandler) CreateOrEditTribe(w http.ResponseWriter, r *http.Request
 
 	_, err = th.db.CreateOrEditTribe(tribe)
 	if err != nil {
-		logger.Log.Error("=> ERR createOrEditTribe: %v", err)
+		logger.FromContext(r.Context()).Error("=> ERR createOrEditTribe: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -400,7 +400,7 @@ func PutTribeActivity(w http.ResponseWriter, r *http.Request) {
 
 	extractedPubkey, err := auth.VerifyTribeUUID(uuid, false)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -441,7 +441,7 @@ func (th *tribeHandler) SetTribePreview(w http.ResponseWriter, r *http.Request)
 
 	extractedPubkey, err := th.verifyTribeUUID(uuid, false)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -487,7 +487,7 @@ func CreateLeaderBoard(w http.ResponseWriter, r *http.Request) {
 
 	extractedPubkey, err := auth.VerifyTribeUUID(uuid, false)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -502,7 +502,7 @@ func CreateLeaderBoard(w http.ResponseWriter, r *http.Request) {
 	r.Body.Close()
 	err = json.Unmarshal(body, &leaderBoard)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -510,7 +510,7 @@ func CreateLeaderBoard(w http.ResponseWriter, r *http.Request) {
 	_, err = db.DB.CreateLeaderBoard(uuid, leaderBoard)
 
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -579,7 +579,7 @@ func UpdateLeaderBoard(w http.ResponseWriter, r *http.Request) {
 
 	extractedPubkey, err := auth.VerifyTribeUUID(uuid, false)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -596,7 +596,7 @@ func UpdateLeaderBoard(w http.ResponseWriter, r *http.Request) {
 	r.Body.Close()
 	err = json.Unmarshal(body, &leaderBoard)
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -628,14 +628,10 @@ func UpdateLeaderBoard(w http.ResponseWriter, r *http.Request) {
 //	@Param			invoice	body		db.InvoiceRequest	true	"Invoice request"
 //	@Success		200		{object}	db.InvoiceResponse
 //	@Router			/invoice [post]
-func GenerateInvoice(w http.ResponseWriter, r *http.Request) {
+func (th *tribeHandler) GenerateInvoice(w http.ResponseWriter, r *http.Request) {
 	invoiceRes, invoiceErr := db.InvoiceResponse{}, db.InvoiceError{}
 
-	if config.IsV2Payment {
-		invoiceRes, invoiceErr = GenerateV2Invoice(w, r)
-	} else {
-		invoiceRes, invoiceErr = GenerateV1Invoice(w, r)
-	}
+	invoiceRes, invoiceErr = th.createInvoice(r)
 
 	if invoiceErr.Error != "" {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -648,7 +644,7 @@ func GenerateInvoice(w http.ResponseWriter, r *http.Request) {
 	r.Body.Close()
 
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -656,7 +652,7 @@ func GenerateInvoice(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(body, &invoice)
 
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -688,145 +684,35 @@ func GenerateInvoice(w http.ResponseWriter, r *http.Request) {
 		RouteHint:      routeHint,
 	}
 
-	db.DB.ProcessAddInvoice(newInvoice, newInvoiceData)
+	if err := db.DB.ProcessAddInvoice(newInvoice, newInvoiceData); err == nil {
+		enqueueInvoiceWatch(newInvoice.PaymentRequest)
+	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(invoiceRes)
 }
 
-func GenerateV1Invoice(w http.ResponseWriter, r *http.Request) (db.InvoiceResponse, db.InvoiceError) {
-	invoice := db.InvoiceRequest{}
-	body, err := io.ReadAll(r.Body)
-
-	r.Body.Close()
-
-	if err != nil {
-		logger.Log.Error("%v", err)
-		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
-	}
-
-	err = json.Unmarshal(body, &invoice)
-
-	if err != nil {
-		logger.Log.Error("%v", err)
-		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
-	}
-
-	memo := invoice.Memo
-	amount, _ := utils.ConvertStringToUint(invoice.Amount)
-
-	url := fmt.Sprintf("%s/invoices", config.RelayUrl)
-
-	bodyData := fmt.Sprintf(`{"amount": %d, "memo": "%s"}`, amount, memo)
-
-	jsonBody := []byte(bodyData)
-
-	client := &http.Client{}
-	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
-
-	req.Header.Set("x-user-token", config.RelayAuthKey)
-	req.Header.Set("Content-Type", "application/json")
-	res, _ := client.Do(req)
-
-	if err != nil {
-		log.Printf("Request Failed: %s", err)
-		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
-	}
-
-	defer res.Body.Close()
-
-	body, err = io.ReadAll(res.Body)
-
-	if err != nil {
-		log.Printf("Reading body failed: %s", err)
-		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
-	}
-
-	// Unmarshal result
-	invoiceRes := db.InvoiceResponse{}
-
-	err = json.Unmarshal(body, &invoiceRes)
-
-	if err != nil {
-		log.Printf("Unmarshal body failed: %s", err)
-		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
-	}
-
-	return invoiceRes, db.InvoiceError{Success: true}
-}
-
-// GenerateInvoice godoc
-//
-//	@Summary		Generate an invoice
-//	@Description	Generate an invoice for a tribe
-//	@Tags			Tribes
-//	@Param			invoice	body		db.InvoiceRequest	true	"Invoice request"
-//	@Success		200		{object}	db.InvoiceResponse
-//	@Router			/tribes/invoice [post]
-func GenerateV2Invoice(w http.ResponseWriter, r *http.Request) (db.InvoiceResponse, db.InvoiceError) {
+func (th *tribeHandler) createInvoice(r *http.Request) (db.InvoiceResponse, db.InvoiceError) {
 	invoice := db.InvoiceRequest{}
-
-	var err error
 	body, err := io.ReadAll(r.Body)
 
 	r.Body.Close()
 
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
 	}
 
 	err = json.Unmarshal(body, &invoice)
 
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
 	}
 
-	url := fmt.Sprintf("%s/invoice", config.V2BotUrl)
-
 	amount, _ := utils.ConvertStringToUint(invoice.Amount)
 
-	amountMsat := amount * 1000
-
-	bodyData := fmt.Sprintf(`{"amt_msat": %d}`, amountMsat)
-
-	jsonBody := []byte(bodyData)
-
-	client := &http.Client{}
-	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
-
-	req.Header.Set("x-admin-token", config.V2BotToken)
-	req.Header.Set("Content-Type", "application/json")
-	res, err := client.Do(req)
-
-	if err != nil {
-		log.Printf("Client Request Failed: %s", err)
-		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
-	}
-
-	defer res.Body.Close()
-
-	body, err = io.ReadAll(res.Body)
-
-	if err != nil {
-		log.Printf("Reading body failed: %s", err)
-		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
-	}
-
-	// Unmarshal result
-	v2InvoiceRes := db.V2CreateInvoiceResponse{}
-	err = json.Unmarshal(body, &v2InvoiceRes)
-
-	if err != nil {
-		log.Printf("Json Unmarshal failed: %s", err)
-		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
-	}
-	return db.InvoiceResponse{
-		Response: db.Invoice{
-			Invoice: v2InvoiceRes.Bolt11,
-		},
-	}, db.InvoiceError{Success: true}
+	return th.lightning.CreateInvoice(amount, invoice.Memo)
 }
 
 // GenerateBudgetInvoice godoc
@@ -838,67 +724,6 @@ func GenerateV2Invoice(w http.ResponseWriter, r *http.Request) (db.InvoiceRespon
 //	@Success		200		{object}	db.InvoiceResponse
 //	@Router			/tribes/budget_invoice [post]
 func (th *tribeHandler) GenerateBudgetInvoice(w http.ResponseWriter, r *http.Request) {
-	if config.IsV2Payment {
-		th.GenerateV2BudgetInvoice(w, r)
-	} else {
-		th.GenerateV1BudgetInvoice(w, r)
-	}
-}
-
-func (th *tribeHandler) ProcessStake(w http.ResponseWriter, r *http.Request) {
-	var stakeReq db.StakeInvoiceRequest
-
-	body, err := io.ReadAll(r.Body)
-	defer r.Body.Close()
-	if err != nil {
-		logger.Log.Error("Failed reading request body: %v", err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	err = json.Unmarshal(body, &stakeReq)
-	if err != nil {
-		logger.Log.Error("Failed unmarshaling request: %v", err)
-		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
-		return
-	}
-
-	bountyIDStr := chi.URLParam(r, "bountyId")
-	bountyIDUint, err := strconv.ParseUint(bountyIDStr, 10, 64)
-	if err != nil {
-		logger.Log.Error("Invalid bountyID: %v", err)
-		http.Error(w, "Invalid bounty ID", http.StatusBadRequest)
-		return
-	}
-	stakeReq.BountyID = uint(bountyIDUint)
-
-	if !stakeReq.StakeOperation {
-		http.Error(w, "Stake operation flag not set", http.StatusBadRequest)
-		return
-	}
-
-	invoiceReq := db.BudgetInvoiceRequest{
-		Amount:        stakeReq.Amount,
-		SenderPubKey:  stakeReq.SenderPubKey,
-		WorkspaceUuid: stakeReq.WorkspaceUuid,
-		PaymentType:   stakeReq.PaymentType,
-		BountyID:      stakeReq.BountyID,
-	}
-
-	modifiedBody, err := json.Marshal(invoiceReq)
-	if err != nil {
-		logger.Log.Error("Failed to marshal invoice request: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	r.Body = io.NopCloser(bytes.NewBuffer(modifiedBody))
-
-	th.GenerateBudgetInvoice(w, r)
-
-}
-
-func (th *tribeHandler) GenerateV1BudgetInvoice(w http.ResponseWriter, r *http.Request) {
 	invoice := db.BudgetInvoiceRequest{}
 
 	var err error
@@ -907,7 +732,7 @@ func (th *tribeHandler) GenerateV1BudgetInvoice(w http.ResponseWriter, r *http.R
 	r.Body.Close()
 
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -915,7 +740,7 @@ func (th *tribeHandler) GenerateV1BudgetInvoice(w http.ResponseWriter, r *http.R
 	err = json.Unmarshal(body, &invoice)
 
 	if err != nil {
-		logger.Log.Error("%v", err)
+		logger.FromContext(r.Context()).Error("%v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -924,40 +749,11 @@ func (th *tribeHandler) GenerateV1BudgetInvoice(w http.ResponseWriter, r *http.R
 		invoice.WorkspaceUuid = invoice.OrgUuid
 	}
 
-	url := fmt.Sprintf("%s/invoices", config.RelayUrl)
-
-	bodyData := fmt.Sprintf(`{"amount": %d, "memo": "%s"}`, invoice.Amount, "Budget Invoice")
-
-	jsonBody := []byte(bodyData)
-
-	client := &http.Client{}
-	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
-
-	req.Header.Set("x-user-token", config.RelayAuthKey)
-	req.Header.Set("Content-Type", "application/json")
-	res, _ := client.Do(req)
-
-	if err != nil {
-		log.Printf("Request Failed: %s", err)
-		return
-	}
-
-	defer res.Body.Close()
-
-	body, err = io.ReadAll(res.Body)
-
-	if err != nil {
-		log.Printf("Reading body failed: %s", err)
-		return
-	}
-
-	// Unmarshal result
-	invoiceRes := db.InvoiceResponse{}
-
-	err = json.Unmarshal(body, &invoiceRes)
+	invoiceRes, invoiceErr := th.lightning.CreateInvoice(invoice.Amount, "Budget Invoice")
 
-	if err != nil {
-		log.Printf("Json Unmarshal failed: %s", err)
+	if invoiceErr.Error != "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(invoiceErr)
 		return
 	}
 
@@ -984,108 +780,63 @@ func (th *tribeHandler) GenerateV1BudgetInvoice(w http.ResponseWriter, r *http.R
 		Status:         false,
 	}
 
-	th.db.ProcessBudgetInvoice(paymentHistory, newInvoice)
+	if err := th.db.ProcessBudgetInvoice(paymentHistory, newInvoice); err == nil {
+		enqueueInvoiceWatch(newInvoice.PaymentRequest)
+	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(invoiceRes)
 }
 
-func (th *tribeHandler) GenerateV2BudgetInvoice(w http.ResponseWriter, r *http.Request) {
-	invoice := db.BudgetInvoiceRequest{}
+func (th *tribeHandler) ProcessStake(w http.ResponseWriter, r *http.Request) {
+	var stakeReq db.StakeInvoiceRequest
 
-	var err error
 	body, err := io.ReadAll(r.Body)
-
-	r.Body.Close()
-
+	defer r.Body.Close()
 	if err != nil {
-		logger.Log.Error("%v", err)
-		w.WriteHeader(http.StatusNotAcceptable)
+		logger.FromContext(r.Context()).Error("Failed reading request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	err = json.Unmarshal(body, &invoice)
-
+	err = json.Unmarshal(body, &stakeReq)
 	if err != nil {
-		logger.Log.Error("%v", err)
-		w.WriteHeader(http.StatusNotAcceptable)
+		logger.FromContext(r.Context()).Error("Failed unmarshaling request: %v", err)
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
 		return
 	}
 
-	if invoice.WorkspaceUuid == "" && invoice.OrgUuid != "" {
-		invoice.WorkspaceUuid = invoice.OrgUuid
-	}
-
-	url := fmt.Sprintf("%s/invoice", config.V2BotUrl)
-
-	amountMsat := invoice.Amount * 1000
-
-	bodyData := fmt.Sprintf(`{"amt_msat": %d}`, amountMsat)
-
-	jsonBody := []byte(bodyData)
-
-	client := &http.Client{}
-	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonBody))
-
-	req.Header.Set("x-admin-token", config.V2BotToken)
-	req.Header.Set("Content-Type", "application/json")
-	res, err := client.Do(req)
-
+	bountyIDStr := chi.URLParam(r, "bountyId")
+	bountyIDUint, err := strconv.ParseUint(bountyIDStr, 10, 64)
 	if err != nil {
-		log.Printf("Client Request Failed: %s", err)
+		logger.FromContext(r.Context()).Error("Invalid bountyID: %v", err)
+		http.Error(w, "Invalid bounty ID", http.StatusBadRequest)
 		return
 	}
+	stakeReq.BountyID = uint(bountyIDUint)
 
-	defer res.Body.Close()
-
-	body, err = io.ReadAll(res.Body)
-
-	if err != nil {
-		log.Printf("Reading body failed: %s", err)
+	if !stakeReq.StakeOperation {
+		http.Error(w, "Stake operation flag not set", http.StatusBadRequest)
 		return
 	}
 
-	// Unmarshal result
-	v2InvoiceRes := db.V2CreateInvoiceResponse{}
-	err = json.Unmarshal(body, &v2InvoiceRes)
+	invoiceReq := db.BudgetInvoiceRequest{
+		Amount:        stakeReq.Amount,
+		SenderPubKey:  stakeReq.SenderPubKey,
+		WorkspaceUuid: stakeReq.WorkspaceUuid,
+		PaymentType:   stakeReq.PaymentType,
+		BountyID:      stakeReq.BountyID,
+	}
 
+	modifiedBody, err := json.Marshal(invoiceReq)
 	if err != nil {
-		log.Printf("Json Unmarshal failed: %s", err)
+		logger.FromContext(r.Context()).Error("Failed to marshal invoice request: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	now := time.Now()
-	var paymentHistory = db.NewPaymentHistory{
-		Amount:         invoice.Amount,
-		WorkspaceUuid:  invoice.WorkspaceUuid,
-		PaymentType:    invoice.PaymentType,
-		SenderPubKey:   invoice.SenderPubKey,
-		ReceiverPubKey: "",
-		Created:        &now,
-		Updated:        &now,
-		Status:         false,
-		BountyId:       0,
-	}
-
-	newInvoice := db.NewInvoiceList{
-		PaymentRequest: v2InvoiceRes.Bolt11,
-		Type:           db.InvoiceType("BUDGET"),
-		OwnerPubkey:    invoice.SenderPubKey,
-		WorkspaceUuid:  invoice.WorkspaceUuid,
-		Created:        &now,
-		Updated:        &now,
-		Status:         false,
-	}
-
-	th.db.ProcessBudgetInvoice(paymentHistory, newInvoice)
+	r.Body = io.NopCloser(bytes.NewBuffer(modifiedBody))
 
-	invoiceRes := db.InvoiceResponse{
-		Succcess: true,
-		Response: db.Invoice{
-			Invoice: v2InvoiceRes.Bolt11,
-		},
-	}
+	th.GenerateBudgetInvoice(w, r)
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(invoiceRes)
 }
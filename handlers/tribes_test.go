@@ -1058,6 +1058,7 @@ func TestGenerateInvoice(t *testing.T) {
 	config.IsV2Payment = true
 	config.V2BotUrl = "http://v2-bot-url.com"
 	config.V2BotToken = "v2-bot-token"
+	tHandler := NewTribeHandler(db.TestDB)
 
 	t.Run("Create Invoice - Happy Path", func(t *testing.T) {
 
@@ -1101,7 +1102,7 @@ func TestGenerateInvoice(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		rr := httptest.NewRecorder()
 
-		GenerateInvoice(rr, req)
+		tHandler.GenerateInvoice(rr, req)
 
 		assert.Equal(t, http.StatusNotAcceptable, rr.Code)
 	})
@@ -1111,7 +1112,7 @@ func TestGenerateInvoice(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		rr := httptest.NewRecorder()
 
-		GenerateInvoice(rr, req)
+		tHandler.GenerateInvoice(rr, req)
 
 		assert.Equal(t, http.StatusInternalServerError, rr.Code)
 	})
@@ -1123,7 +1124,7 @@ func TestGenerateInvoice(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		rr := httptest.NewRecorder()
 
-		GenerateInvoice(rr, req)
+		tHandler.GenerateInvoice(rr, req)
 
 		assert.Equal(t, http.StatusInternalServerError, rr.Code)
 	})
@@ -1140,7 +1141,7 @@ func TestGenerateInvoice(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		rr := httptest.NewRecorder()
 
-		GenerateInvoice(rr, req)
+		tHandler.GenerateInvoice(rr, req)
 
 		assert.Equal(t, http.StatusInternalServerError, rr.Code)
 	})
@@ -1172,7 +1173,7 @@ func TestGenerateInvoice(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		rr := httptest.NewRecorder()
 
-		GenerateInvoice(rr, req)
+		tHandler.GenerateInvoice(rr, req)
 
 		assert.Equal(t, http.StatusNotAcceptable, rr.Code)
 	})
@@ -1212,7 +1213,7 @@ func TestGenerateInvoice(t *testing.T) {
 				req.Header.Set("Content-Type", "application/json")
 				rr := httptest.NewRecorder()
 
-				GenerateInvoice(rr, req)
+				tHandler.GenerateInvoice(rr, req)
 				responses[index] = rr
 			}(i)
 		}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/utils"
+	"github.com/stakwork/sphinx-tribes/webhooks"
+)
+
+type webhookHandler struct {
+	db            db.Database
+	userHasAccess func(pubKeyFromAuth string, uuid string, role string) bool
+	resolver      webhooks.Resolver
+}
+
+func NewWebhookHandler(database db.Database) *webhookHandler {
+	configHandler := db.NewConfigHandler(database)
+	return &webhookHandler{
+		db:            database,
+		userHasAccess: configHandler.UserHasAccess,
+		resolver:      net.DefaultResolver,
+	}
+}
+
+type WebhookRequest struct {
+	Url    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// emitWebhook notifies the workspace's webhook subscribers of event.
+func emitWebhook(workspaceUuid string, event string, data interface{}) {
+	webhooks.Emit(workspaceUuid, event, data)
+}
+
+// emitTicketStatusWebhook reports a ticket whose status differs from
+// previousStatus.
+func emitTicketStatusWebhook(database db.Database, ticket db.Tickets, previousStatus db.TicketStatus) {
+	if ticket.Status == previousStatus {
+		return
+	}
+
+	emitWebhook(ticketWorkspaceUuid(database, ticket), webhooks.EventTicketStatusChanged, map[string]interface{}{
+		"ticket":          ticket,
+		"previous_status": previousStatus,
+	})
+}
+
+func (wh *webhookHandler) validateWebhookRequest(ctx context.Context, request WebhookRequest) string {
+	parsed, err := url.Parse(request.Url)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "A valid http or https url is required"
+	}
+	if err := webhooks.CheckTarget(ctx, wh.resolver, request.Url); err != nil {
+		if errors.Is(err, webhooks.ErrForbiddenTarget) {
+			return "The url must reach a public address"
+		}
+		return "The url host could not be resolved"
+	}
+	for _, event := range request.Events {
+		if !webhooks.ValidEvent(event) {
+			return "Unknown event: " + event
+		}
+	}
+	return ""
+}
+
+// authorize writes the error response and returns false when the caller
+// may not manage the workspace's webhooks.
+func (wh *webhookHandler) authorize(w http.ResponseWriter, r *http.Request, workspaceUuid string) bool {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[webhooks] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return false
+	}
+
+	workspace := wh.db.GetWorkspaceByUuid(workspaceUuid)
+	if workspace.Uuid == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Workspace not found"})
+		return false
+	}
+
+	if !wh.userHasAccess(pubKeyFromAuth, workspaceUuid, db.EditOrg) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions to manage webhooks"})
+		return false
+	}
+
+	return true
+}
+
+// getSubscription loads a subscription of the workspace in the url, writing
+// a 404 when it belongs elsewhere.
+func (wh *webhookHandler) getSubscription(w http.ResponseWriter, r *http.Request, workspaceUuid string) (db.WebhookSubscription, bool) {
+	subscription, err := wh.db.GetWebhookSubscriptionByUuid(chi.URLParam(r, "uuid"))
+	if err != nil || subscription.WorkspaceUuid != workspaceUuid {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Webhook not found"})
+		return db.WebhookSubscription{}, false
+	}
+	return subscription, true
+}
+
+// CreateWebhook godoc
+//
+//	@Summary		Create webhook subscription
+//	@Description	Subscribe a url to workspace events. The secret is only returned on creation.
+//	@Tags			Workspaces - Webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string			true	"Workspace UUID"
+//	@Param			webhook			body		WebhookRequest	true	"Webhook"
+//	@Success		201				{object}	db.WebhookSubscription
+//	@Router			/workspaces/{workspace_uuid}/webhooks [post]
+func (wh *webhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	if !wh.authorize(w, r, workspaceUuid) {
+		return
+	}
+
+	request := WebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if msg := wh.validateWebhookRequest(r.Context(), request); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+		return
+	}
+
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	subscription := db.WebhookSubscription{
+		WorkspaceUuid: workspaceUuid,
+		Url:           request.Url,
+		Secret:        request.Secret,
+		Events:        request.Events,
+		Active:        request.Active == nil || *request.Active,
+		CreatedBy:     pubKeyFromAuth,
+	}
+	if subscription.Secret == "" {
+		subscription.Secret = utils.GetRandomToken(40)
+	}
+
+	created, err := wh.db.CreateWebhookSubscription(subscription)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[webhooks] could not create subscription: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create webhook"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetWebhooks godoc
+//
+//	@Summary		List webhook subscriptions
+//	@Description	List the webhook subscriptions of a workspace
+//	@Tags			Workspaces - Webhooks
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path	string	true	"Workspace UUID"
+//	@Success		200				{array}	db.WebhookSubscription
+//	@Router			/workspaces/{workspace_uuid}/webhooks [get]
+func (wh *webhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	if !wh.authorize(w, r, workspaceUuid) {
+		return
+	}
+
+	subscriptions, err := wh.db.GetWebhookSubscriptionsByWorkspace(workspaceUuid)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[webhooks] could not list subscriptions: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to fetch webhooks"})
+		return
+	}
+
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+// UpdateWebhook godoc
+//
+//	@Summary		Update webhook subscription
+//	@Description	Change the url, secret, event filter or active flag of a webhook
+//	@Tags			Workspaces - Webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string			true	"Workspace UUID"
+//	@Param			uuid			path		string			true	"Webhook UUID"
+//	@Param			webhook			body		WebhookRequest	true	"Webhook"
+//	@Success		200				{object}	db.WebhookSubscription
+//	@Router			/workspaces/{workspace_uuid}/webhooks/{uuid} [put]
+func (wh *webhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	if !wh.authorize(w, r, workspaceUuid) {
+		return
+	}
+
+	subscription, ok := wh.getSubscription(w, r, workspaceUuid)
+	if !ok {
+		return
+	}
+
+	request := WebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if request.Url == "" {
+		request.Url = subscription.Url
+	}
+	if msg := wh.validateWebhookRequest(r.Context(), request); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+		return
+	}
+
+	subscription.Url = request.Url
+	if request.Secret != "" {
+		subscription.Secret = request.Secret
+	}
+	if request.Events != nil {
+		subscription.Events = request.Events
+	}
+	if request.Active != nil {
+		subscription.Active = *request.Active
+	}
+
+	updated, err := wh.db.UpdateWebhookSubscription(subscription)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[webhooks] could not update subscription: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to update webhook"})
+		return
+	}
+	updated.Secret = ""
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteWebhook godoc
+//
+//	@Summary		Delete webhook subscription
+//	@Description	Remove a webhook and its delivery log
+//	@Tags			Workspaces - Webhooks
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string	true	"Workspace UUID"
+//	@Param			uuid			path		string	true	"Webhook UUID"
+//	@Success		200				{string}	string	"Webhook deleted"
+//	@Router			/workspaces/{workspace_uuid}/webhooks/{uuid} [delete]
+func (wh *webhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	if !wh.authorize(w, r, workspaceUuid) {
+		return
+	}
+
+	subscription, ok := wh.getSubscription(w, r, workspaceUuid)
+	if !ok {
+		return
+	}
+
+	if err := wh.db.DeleteWebhookSubscription(subscription.Uuid); err != nil {
+		logger.FromContext(r.Context()).Error("[webhooks] could not delete subscription: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to delete webhook"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode("Webhook deleted")
+}
+
+// GetWebhookDeliveries godoc
+//
+//	@Summary		Get webhook deliveries
+//	@Description	Get the most recent deliveries of a webhook, newest first
+//	@Tags			Workspaces - Webhooks
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path	string	true	"Workspace UUID"
+//	@Param			uuid			path	string	true	"Webhook UUID"
+//	@Param			limit			query	int		false	"Maximum number of deliveries"
+//	@Success		200				{array}	db.WebhookDelivery
+//	@Router			/workspaces/{workspace_uuid}/webhooks/{uuid}/deliveries [get]
+func (wh *webhookHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	if !wh.authorize(w, r, workspaceUuid) {
+		return
+	}
+
+	subscription, ok := wh.getSubscription(w, r, workspaceUuid)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit > 500 {
+		limit = 500
+	}
+
+	deliveries, err := wh.db.GetWebhookDeliveries(subscription.Uuid, limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[webhooks] could not list deliveries: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to fetch deliveries"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stakwork/sphinx-tribes/webhooks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func webhookRequest(method string, target string, body interface{}, pubkey string, params map[string]string) *http.Request {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+
+	ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+	if pubkey != "" {
+		ctx = context.WithValue(ctx, auth.ContextKey, pubkey)
+	}
+	return httptest.NewRequest(method, target, &buf).WithContext(ctx)
+}
+
+// publicResolver resolves every host to a public address, and internal.test
+// to a private one.
+type publicResolver struct{}
+
+func (publicResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if host == "internal.test" {
+		return []net.IPAddr{{IP: net.ParseIP("10.0.0.5")}}, nil
+	}
+	return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}}, nil
+}
+
+func newTestWebhookHandler(t *testing.T, hasAccess bool) (*webhookHandler, *dbMocks.Database) {
+	mockDb := dbMocks.NewDatabase(t)
+	handler := NewWebhookHandler(mockDb)
+	handler.resolver = publicResolver{}
+	handler.userHasAccess = func(pubKeyFromAuth string, uuid string, role string) bool {
+		assert.Equal(t, db.EditOrg, role)
+		return hasAccess
+	}
+	return handler, mockDb
+}
+
+func TestCreateWebhook(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Unauthorized without pubkey", func(t *testing.T) {
+		handler, _ := newTestWebhookHandler(t, true)
+		rr := httptest.NewRecorder()
+
+		handler.CreateWebhook(rr, webhookRequest(http.MethodPost, "/", WebhookRequest{Url: "https://example.com"}, "", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Forbidden without edit role", func(t *testing.T) {
+		handler, mockDb := newTestWebhookHandler(t, false)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		rr := httptest.NewRecorder()
+
+		handler.CreateWebhook(rr, webhookRequest(http.MethodPost, "/", WebhookRequest{Url: "https://example.com"}, "member", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Rejects invalid url and unknown events", func(t *testing.T) {
+		for _, request := range []WebhookRequest{
+			{Url: "ftp://example.com"},
+			{Url: "not a url"},
+			{Url: "https://example.com", Events: []string{"bounty.exploded"}},
+		} {
+			handler, mockDb := newTestWebhookHandler(t, true)
+			mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+			rr := httptest.NewRecorder()
+
+			handler.CreateWebhook(rr, webhookRequest(http.MethodPost, "/", request, "owner", params))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("Rejects urls reaching internal addresses", func(t *testing.T) {
+		for _, target := range []string{
+			"http://127.0.0.1:5002/internal/metrics",
+			"http://169.254.169.254/latest/meta-data",
+			"http://[::1]/hook",
+			"https://internal.test/hook",
+		} {
+			handler, mockDb := newTestWebhookHandler(t, true)
+			mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+			rr := httptest.NewRecorder()
+
+			handler.CreateWebhook(rr, webhookRequest(http.MethodPost, "/", WebhookRequest{Url: target}, "owner", params))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+		}
+	})
+
+	t.Run("Creates subscription with generated secret", func(t *testing.T) {
+		handler, mockDb := newTestWebhookHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("CreateWebhookSubscription", mock.MatchedBy(func(s db.WebhookSubscription) bool {
+			return s.WorkspaceUuid == workspace.Uuid &&
+				s.Url == "https://example.com/hook" &&
+				len(s.Secret) == 40 &&
+				s.Active &&
+				s.CreatedBy == "owner" &&
+				len(s.Events) == 1 && s.Events[0] == webhooks.EventBountyPaid
+		})).Return(func(s db.WebhookSubscription) db.WebhookSubscription {
+			s.Uuid = "hook-1"
+			return s
+		}, nil)
+		rr := httptest.NewRecorder()
+
+		handler.CreateWebhook(rr, webhookRequest(http.MethodPost, "/", WebhookRequest{
+			Url:    "https://example.com/hook",
+			Events: []string{webhooks.EventBountyPaid},
+		}, "owner", params))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		created := db.WebhookSubscription{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+		assert.Equal(t, "hook-1", created.Uuid)
+		assert.Len(t, created.Secret, 40)
+	})
+}
+
+func TestGetWebhooks(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	handler, mockDb := newTestWebhookHandler(t, true)
+	mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+	mockDb.On("GetWebhookSubscriptionsByWorkspace", workspace.Uuid).Return([]db.WebhookSubscription{
+		{Uuid: "hook-1", WorkspaceUuid: workspace.Uuid, Url: "https://example.com", Secret: "secret"},
+	}, nil)
+	rr := httptest.NewRecorder()
+
+	handler.GetWebhooks(rr, webhookRequest(http.MethodGet, "/", nil, "owner", map[string]string{"workspace_uuid": workspace.Uuid}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "secret")
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	existing := db.WebhookSubscription{Uuid: "hook-1", WorkspaceUuid: workspace.Uuid, Url: "https://example.com", Secret: "secret", Active: true}
+
+	t.Run("Not found in another workspace", func(t *testing.T) {
+		handler, mockDb := newTestWebhookHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", "workspace-2").Return(db.Workspace{Uuid: "workspace-2"})
+		mockDb.On("GetWebhookSubscriptionByUuid", "hook-1").Return(existing, nil)
+		rr := httptest.NewRecorder()
+
+		handler.UpdateWebhook(rr, webhookRequest(http.MethodPut, "/", WebhookRequest{}, "owner",
+			map[string]string{"workspace_uuid": "workspace-2", "uuid": "hook-1"}))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Disables subscription", func(t *testing.T) {
+		handler, mockDb := newTestWebhookHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWebhookSubscriptionByUuid", "hook-1").Return(existing, nil)
+		mockDb.On("UpdateWebhookSubscription", mock.MatchedBy(func(s db.WebhookSubscription) bool {
+			return s.Uuid == "hook-1" && !s.Active && s.Url == existing.Url && s.Secret == "secret"
+		})).Return(func(s db.WebhookSubscription) db.WebhookSubscription { return s }, nil)
+		rr := httptest.NewRecorder()
+
+		active := false
+		handler.UpdateWebhook(rr, webhookRequest(http.MethodPut, "/", WebhookRequest{Active: &active}, "owner",
+			map[string]string{"workspace_uuid": workspace.Uuid, "uuid": "hook-1"}))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "secret")
+	})
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid, "uuid": "hook-1"}
+
+	t.Run("Deletes subscription", func(t *testing.T) {
+		handler, mockDb := newTestWebhookHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWebhookSubscriptionByUuid", "hook-1").Return(db.WebhookSubscription{Uuid: "hook-1", WorkspaceUuid: workspace.Uuid}, nil)
+		mockDb.On("DeleteWebhookSubscription", "hook-1").Return(nil)
+		rr := httptest.NewRecorder()
+
+		handler.DeleteWebhook(rr, webhookRequest(http.MethodDelete, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Missing subscription", func(t *testing.T) {
+		handler, mockDb := newTestWebhookHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWebhookSubscriptionByUuid", "hook-1").Return(db.WebhookSubscription{}, errors.New("webhook subscription not found"))
+		rr := httptest.NewRecorder()
+
+		handler.DeleteWebhook(rr, webhookRequest(http.MethodDelete, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestGetWebhookDeliveries(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	handler, mockDb := newTestWebhookHandler(t, true)
+	mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+	mockDb.On("GetWebhookSubscriptionByUuid", "hook-1").Return(db.WebhookSubscription{Uuid: "hook-1", WorkspaceUuid: workspace.Uuid}, nil)
+	mockDb.On("GetWebhookDeliveries", "hook-1", 500).Return([]db.WebhookDelivery{
+		{Uuid: "delivery-1", SubscriptionUuid: "hook-1", Event: webhooks.EventBountyCreated, Status: db.WebhookDeliveryDelivered, Attempts: 1},
+	}, nil)
+	rr := httptest.NewRecorder()
+
+	handler.GetWebhookDeliveries(rr, webhookRequest(http.MethodGet, "/?limit=1000", nil, "owner",
+		map[string]string{"workspace_uuid": workspace.Uuid, "uuid": "hook-1"}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var deliveries []db.WebhookDelivery
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deliveries))
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, "delivery-1", deliveries[0].Uuid)
+}
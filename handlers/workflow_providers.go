@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/audit"
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/workflows"
+)
+
+// echoWorkflows is shared by every workspace running on the echo provider,
+// so a run can be cancelled from another request than the one starting it.
+var echoWorkflows = workflows.NewEcho(http.DefaultClient, true)
+
+// workflowResolver returns the provider and workflow id a workspace runs a
+// kind of workflow with.
+type workflowResolver func(workspaceUuid string, kind workflows.Kind) (WorkflowProvider, string)
+
+// newWorkflowResolver resolves workflows from the workspaces' settings. A
+// kind a workspace did not pick runs its default Stakwork workflow, on the
+// WORKFLOW_PROVIDER provider when that is set.
+func newWorkflowResolver(database db.Database, client HttpClient) workflowResolver {
+	return func(workspaceUuid string, kind workflows.Kind) (WorkflowProvider, string) {
+		defaults := workflows.Defaults[kind]
+		picked := database.GetWorkspaceWorkflow(workspaceUuid, string(kind))
+
+		provider := picked.Provider
+		if provider == "" {
+			provider = config.WorkflowProvider
+		}
+		workflowID := picked.WorkflowID
+		if workflowID == "" {
+			workflowID = defaults.WorkflowID
+		}
+
+		if provider == workflows.ProviderEcho {
+			return echoWorkflows, workflowID
+		}
+		return workflows.NewStakwork(client, os.Getenv(defaults.APIKeyEnv)), workflowID
+	}
+}
+
+type workspaceWorkflowHandler struct {
+	db            db.Database
+	userHasAccess func(pubKeyFromAuth string, uuid string, role string) bool
+	resolve       workflowResolver
+}
+
+func NewWorkspaceWorkflowHandler(httpClient HttpClient, database db.Database) *workspaceWorkflowHandler {
+	configHandler := db.NewConfigHandler(database)
+	return &workspaceWorkflowHandler{
+		db:            database,
+		userHasAccess: configHandler.UserHasAccess,
+		resolve:       newWorkflowResolver(database, httpClient),
+	}
+}
+
+// authorize writes the error response and returns an empty uuid when the
+// caller lacks role on the workspace in the request.
+func (wh *workspaceWorkflowHandler) authorize(w http.ResponseWriter, r *http.Request, role string) (string, string) {
+	pubKeyFromAuth, _ := r.Context().Value(auth.ContextKey).(string)
+	if pubKeyFromAuth == "" {
+		logger.FromContext(r.Context()).Info("[workflows] no pubkey from auth")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		return "", ""
+	}
+
+	workspaceUuid := chi.URLParam(r, "workspace_uuid")
+	workspace := wh.db.GetWorkspaceByUuid(workspaceUuid)
+	if workspace.Uuid == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Workspace not found"})
+		return "", ""
+	}
+
+	if !wh.userHasAccess(pubKeyFromAuth, workspaceUuid, role) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "You don't have appropriate permissions"})
+		return "", ""
+	}
+	return pubKeyFromAuth, workspaceUuid
+}
+
+// workspaceWorkflows lists the workflow of every kind the workspace runs,
+// the ones it did not pick with an id of 0.
+func (wh *workspaceWorkflowHandler) workspaceWorkflows(workspaceUuid string) []db.WorkspaceWorkflow {
+	picked := map[string]db.WorkspaceWorkflow{}
+	for _, workflow := range wh.db.GetWorkspaceWorkflows(workspaceUuid) {
+		picked[workflow.Kind] = workflow
+	}
+
+	list := make([]db.WorkspaceWorkflow, 0, len(workflows.Kinds))
+	for _, kind := range workflows.Kinds {
+		workflow, ok := picked[string(kind)]
+		if !ok {
+			workflow = db.WorkspaceWorkflow{
+				WorkspaceUuid: workspaceUuid,
+				Kind:          string(kind),
+				Provider:      workflows.ProviderStakwork,
+				WorkflowID:    workflows.Defaults[kind].WorkflowID,
+			}
+			if config.WorkflowProvider == workflows.ProviderEcho {
+				workflow.Provider = workflows.ProviderEcho
+			}
+		}
+		if workflow.WorkflowID == "" {
+			workflow.WorkflowID = workflows.Defaults[kind].WorkflowID
+		}
+		list = append(list, workflow)
+	}
+	return list
+}
+
+// GetWorkspaceWorkflows godoc
+//
+//	@Summary		Get workspace workflows
+//	@Description	The provider and workflow each kind of AI workflow (chat, build, ticket_review, plan_review) runs with in the workspace. Kinds the workspace did not pick have an id of 0 and run the default Stakwork workflow.
+//	@Tags			Workspaces
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string	true	"Workspace UUID"
+//	@Success		200				{array}		db.WorkspaceWorkflow
+//	@Router			/workspaces/{workspace_uuid}/workflows [get]
+func (wh *workspaceWorkflowHandler) GetWorkspaceWorkflows(w http.ResponseWriter, r *http.Request) {
+	_, workspaceUuid := wh.authorize(w, r, db.ViewReport)
+	if workspaceUuid == "" {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(wh.workspaceWorkflows(workspaceUuid))
+}
+
+// UpdateWorkspaceWorkflows godoc
+//
+//	@Summary		Update workspace workflows
+//	@Description	Replace the workflows the workspace picked. Each kind takes a provider (stakwork or echo) and optionally a workflow id, kinds left out go back to the default Stakwork workflow.
+//	@Tags			Workspaces
+//	@Accept			json
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path		string							true	"Workspace UUID"
+//	@Param			workflows		body		db.WorkspaceWorkflowsRequest	true	"Workflows"
+//	@Success		200				{array}		db.WorkspaceWorkflow
+//	@Router			/workspaces/{workspace_uuid}/workflows [put]
+func (wh *workspaceWorkflowHandler) UpdateWorkspaceWorkflows(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, workspaceUuid := wh.authorize(w, r, db.EditOrg)
+	if workspaceUuid == "" {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	request := db.WorkspaceWorkflowsRequest{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	if err := db.ValidateWorkspaceWorkflows(request.Workflows); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	before := wh.db.GetWorkspaceWorkflows(workspaceUuid)
+	saved, err := wh.db.SaveWorkspaceWorkflows(workspaceUuid, request.Workflows)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[workflows] could not save workflows of %s: %v", workspaceUuid, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to save workflows"})
+		return
+	}
+	audit.Record(pubKeyFromAuth, workspaceUuid, audit.EntityWorkflows, workspaceUuid, audit.ActionUpdate,
+		map[string]interface{}{"workflows": before},
+		map[string]interface{}{"workflows": saved})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(wh.workspaceWorkflows(workspaceUuid))
+}
+
+// CancelWorkflowRun godoc
+//
+//	@Summary		Cancel a workflow run
+//	@Description	Stop a run the workspace started of the given kind of workflow, on the provider the workspace runs that kind with
+//	@Tags			Workspaces
+//	@Produce		json
+//	@Security		PubKeyContextAuth
+//	@Param			workspace_uuid	path	string	true	"Workspace UUID"
+//	@Param			kind			path	string	true	"Workflow kind"
+//	@Param			run_id			path	string	true	"Run ID"
+//	@Success		200
+//	@Router			/workspaces/{workspace_uuid}/workflows/{kind}/runs/{run_id}/cancel [post]
+func (wh *workspaceWorkflowHandler) CancelWorkflowRun(w http.ResponseWriter, r *http.Request) {
+	pubKeyFromAuth, workspaceUuid := wh.authorize(w, r, db.EditOrg)
+	if workspaceUuid == "" {
+		return
+	}
+
+	kind := workflows.Kind(chi.URLParam(r, "kind"))
+	if _, ok := workflows.Defaults[kind]; !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Unknown workflow kind"})
+		return
+	}
+	runID := chi.URLParam(r, "run_id")
+
+	provider, _ := wh.resolve(workspaceUuid, kind)
+	err := provider.Cancel(r.Context(), runID)
+	var apiErr *workflows.APIError
+	switch {
+	case err == nil:
+	case errors.Is(err, workflows.ErrRunNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, workflows.ErrRunFinished):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	case errors.As(err, &apiErr):
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": apiErr.Body})
+		return
+	default:
+		logger.FromContext(r.Context()).Error("[workflows] could not cancel %s run %s of %s: %v", kind, runID, workspaceUuid, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to cancel workflow run"})
+		return
+	}
+	logger.FromContext(r.Context()).Info("[workflows] %s cancelled %s run %s of %s", pubKeyFromAuth, kind, runID, workspaceUuid)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"run_id": runID, "status": workflows.StatusCancelled})
+}
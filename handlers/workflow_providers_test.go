@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stakwork/sphinx-tribes/db"
+	dbMocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stakwork/sphinx-tribes/workflows"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestWorkspaceWorkflowHandler(t *testing.T, hasAccess bool) (*workspaceWorkflowHandler, *dbMocks.Database, *workflows.Echo) {
+	mockDb := dbMocks.NewDatabase(t)
+	echo := workflows.NewEcho(nil, false)
+	handler := NewWorkspaceWorkflowHandler(http.DefaultClient, mockDb)
+	handler.resolve = func(workspaceUuid string, kind workflows.Kind) (WorkflowProvider, string) {
+		return echo, workflows.Defaults[kind].WorkflowID
+	}
+	handler.userHasAccess = func(pubKeyFromAuth string, uuid string, role string) bool {
+		return hasAccess
+	}
+	return handler, mockDb, echo
+}
+
+func TestWorkflowResolver(t *testing.T) {
+	mockDb := dbMocks.NewDatabase(t)
+	resolve := newWorkflowResolver(mockDb, http.DefaultClient)
+
+	t.Run("Runs the default Stakwork workflow when the workspace picked none", func(t *testing.T) {
+		mockDb.On("GetWorkspaceWorkflow", "workspace-1", "build").Return(db.WorkspaceWorkflow{}).Once()
+
+		provider, workflowID := resolve("workspace-1", workflows.KindBuild)
+
+		assert.IsType(t, &workflows.Stakwork{}, provider)
+		assert.Equal(t, "43859", workflowID)
+	})
+
+	t.Run("Runs the workflow the workspace picked", func(t *testing.T) {
+		mockDb.On("GetWorkspaceWorkflow", "workspace-1", "chat").
+			Return(db.WorkspaceWorkflow{Kind: "chat", Provider: workflows.ProviderEcho, WorkflowID: "local-chat"}).Once()
+
+		provider, workflowID := resolve("workspace-1", workflows.KindChat)
+
+		assert.Same(t, echoWorkflows, provider)
+		assert.Equal(t, "local-chat", workflowID)
+	})
+}
+
+func TestGetWorkspaceWorkflows(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	t.Run("Unauthorized without the role", func(t *testing.T) {
+		handler, mockDb, _ := newTestWorkspaceWorkflowHandler(t, false)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		rr := httptest.NewRecorder()
+
+		handler.GetWorkspaceWorkflows(rr, webhookRequest(http.MethodGet, "/", nil, "someone", params))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Lists every kind, the defaults for the ones not picked", func(t *testing.T) {
+		handler, mockDb, _ := newTestWorkspaceWorkflowHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWorkspaceWorkflows", workspace.Uuid).Return([]db.WorkspaceWorkflow{
+			{ID: 1, WorkspaceUuid: workspace.Uuid, Kind: "chat", Provider: workflows.ProviderEcho},
+		})
+		rr := httptest.NewRecorder()
+
+		handler.GetWorkspaceWorkflows(rr, webhookRequest(http.MethodGet, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		list := []db.WorkspaceWorkflow{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+		assert.Len(t, list, len(workflows.Kinds))
+		assert.Equal(t, workflows.ProviderEcho, list[0].Provider)
+		assert.Equal(t, "38842", list[0].WorkflowID)
+		assert.Equal(t, "ticket_review", list[2].Kind)
+		assert.Equal(t, uint(0), list[2].ID)
+		assert.Equal(t, "37324", list[2].WorkflowID)
+	})
+}
+
+func TestUpdateWorkspaceWorkflows(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+	params := map[string]string{"workspace_uuid": workspace.Uuid}
+
+	invalid := map[string][]db.WorkspaceWorkflow{
+		"an unknown kind":       {{Kind: "deploy", Provider: workflows.ProviderEcho}},
+		"an unknown provider":   {{Kind: "chat", Provider: "elsewhere"}},
+		"two workflows of kind": {{Kind: "chat", Provider: workflows.ProviderEcho}, {Kind: "chat", Provider: workflows.ProviderStakwork}},
+	}
+	for name, picked := range invalid {
+		t.Run("Rejects "+name, func(t *testing.T) {
+			handler, mockDb, _ := newTestWorkspaceWorkflowHandler(t, true)
+			mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+			rr := httptest.NewRecorder()
+
+			handler.UpdateWorkspaceWorkflows(rr, webhookRequest(http.MethodPut, "/", db.WorkspaceWorkflowsRequest{Workflows: picked}, "owner", params))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+
+	t.Run("Saves the workflows of the workspace in the path", func(t *testing.T) {
+		handler, mockDb, _ := newTestWorkspaceWorkflowHandler(t, true)
+		picked := []db.WorkspaceWorkflow{{Kind: "plan_review", Provider: workflows.ProviderStakwork, WorkflowID: "50000"}}
+		saved := []db.WorkspaceWorkflow{{ID: 3, WorkspaceUuid: workspace.Uuid, Kind: "plan_review", Provider: workflows.ProviderStakwork, WorkflowID: "50000"}}
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		mockDb.On("GetWorkspaceWorkflows", workspace.Uuid).Return([]db.WorkspaceWorkflow{}).Once()
+		mockDb.On("SaveWorkspaceWorkflows", workspace.Uuid, mock.MatchedBy(func(w []db.WorkspaceWorkflow) bool {
+			return len(w) == 1 && w[0].WorkflowID == "50000"
+		})).Return(saved, nil).Once()
+		mockDb.On("GetWorkspaceWorkflows", workspace.Uuid).Return(saved).Once()
+		rr := httptest.NewRecorder()
+
+		handler.UpdateWorkspaceWorkflows(rr, webhookRequest(http.MethodPut, "/", db.WorkspaceWorkflowsRequest{Workflows: picked}, "owner", params))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		list := []db.WorkspaceWorkflow{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+		assert.Equal(t, "50000", list[3].WorkflowID)
+	})
+}
+
+func TestCancelWorkflowRun(t *testing.T) {
+	workspace := db.Workspace{Uuid: "workspace-1", OwnerPubKey: "owner"}
+
+	t.Run("Cancels a running run once", func(t *testing.T) {
+		handler, mockDb, echo := newTestWorkspaceWorkflowHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		run, _ := echo.Submit(context.Background(), workflows.Job{Name: "chat"})
+		params := map[string]string{"workspace_uuid": workspace.Uuid, "kind": "chat", "run_id": run.ID}
+
+		rr := httptest.NewRecorder()
+		handler.CancelWorkflowRun(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, workflows.StatusCancelled, echo.Runs()[0].Status)
+
+		rr = httptest.NewRecorder()
+		handler.CancelWorkflowRun(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Not found for an unknown run", func(t *testing.T) {
+		handler, mockDb, _ := newTestWorkspaceWorkflowHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		params := map[string]string{"workspace_uuid": workspace.Uuid, "kind": "build", "run_id": "echo-9"}
+		rr := httptest.NewRecorder()
+
+		handler.CancelWorkflowRun(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Rejects an unknown kind", func(t *testing.T) {
+		handler, mockDb, _ := newTestWorkspaceWorkflowHandler(t, true)
+		mockDb.On("GetWorkspaceByUuid", workspace.Uuid).Return(workspace)
+		params := map[string]string{"workspace_uuid": workspace.Uuid, "kind": "deploy", "run_id": "echo-1"}
+		rr := httptest.NewRecorder()
+
+		handler.CancelWorkflowRun(rr, webhookRequest(http.MethodPost, "/", nil, "owner", params))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestHandleChatWebhookWithEcho(t *testing.T) {
+	mockDb := dbMocks.NewDatabase(t)
+	echo := workflows.NewEcho(nil, false)
+	handler := &ChatHandler{db: mockDb}
+	handler.workflow = func(workspaceUuid string, kind workflows.Kind) (WorkflowProvider, string) {
+		assert.Equal(t, "workspace-1", workspaceUuid)
+		assert.Equal(t, workflows.KindBuild, kind)
+		return echo, ""
+	}
+
+	mockDb.On("GetChatByChatID", "chat-1").Return(db.Chat{ID: "chat-1", WorkspaceID: "workspace-1"}, nil)
+	mockDb.On("AddChatStatus", mock.MatchedBy(func(s *db.ChatWorkflowStatus) bool {
+		return s.ChatID == "chat-1" && s.Status == "error" && s.Message == "out of tokens"
+	})).Return(db.ChatWorkflowStatus{ChatID: "chat-1", Status: "error"}, nil).Once()
+
+	body, _ := json.Marshal(map[string]string{"run_id": "echo-1", "status": workflows.StatusFailed, "message": "out of tokens"})
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("chat_id", "chat-1")
+	req := httptest.NewRequest(http.MethodPost, "/hivechat/chat-1/update?kind=build", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+
+	handler.HandleChatWebhook(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
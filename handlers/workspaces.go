@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,10 +15,13 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/rs/xid"
+	"github.com/stakwork/sphinx-tribes/audit"
 	"github.com/stakwork/sphinx-tribes/auth"
 	"github.com/stakwork/sphinx-tribes/db"
 	"github.com/stakwork/sphinx-tribes/logger"
 	"github.com/stakwork/sphinx-tribes/utils"
+	"github.com/stakwork/sphinx-tribes/vault"
+	"github.com/stakwork/sphinx-tribes/webhooks"
 	"gorm.io/gorm"
 )
 
@@ -24,6 +29,7 @@ type workspaceHandler struct {
 	db                             db.Database
 	generateBountyHandler          func(bounties []db.NewBounty) []db.BountyResponse
 	getLightningInvoice            func(payment_request string) (db.InvoiceResult, db.InvoiceError)
+	getInvoiceStatusByTag          func(tag string) db.V2TagRes
 	userHasAccess                  func(pubKeyFromAuth string, uuid string, role string) bool
 	configUserHasAccess            func(pubKeyFromAuth string, uuid string, role string) bool
 	configUserHasManageBountyRoles func(pubKeyFromAuth string, uuid string) bool
@@ -39,6 +45,7 @@ func NewWorkspaceHandler(database db.Database) *workspaceHandler {
 		db:                             database,
 		generateBountyHandler:          bHandler.GenerateBountyResponse,
 		getLightningInvoice:            bHandler.GetLightningInvoice,
+		getInvoiceStatusByTag:          bHandler.getInvoiceStatusByTag,
 		userHasAccess:                  dbConf.UserHasAccess,
 		configUserHasAccess:            configHandler.UserHasAccess,
 		configUserHasManageBountyRoles: configHandler.UserHasManageBountyRoles,
@@ -62,7 +69,7 @@ func (oh *workspaceHandler) CreateOrEditWorkspace(w http.ResponseWriter, r *http
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -74,7 +81,7 @@ func (oh *workspaceHandler) CreateOrEditWorkspace(w http.ResponseWriter, r *http
 	err := json.Unmarshal(body, &workspace)
 
 	if err != nil {
-		logger.Log.Error("[workspaces] %v", err)
+		logger.FromContext(r.Context()).Error("[workspaces] %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -82,14 +89,14 @@ func (oh *workspaceHandler) CreateOrEditWorkspace(w http.ResponseWriter, r *http
 	workspace.Name = strings.TrimSpace(workspace.Name)
 
 	if len(workspace.Name) == 0 || len(workspace.Name) > 20 {
-		logger.Log.Info("[workspaces] invalid workspace name %s", workspace.Name)
+		logger.FromContext(r.Context()).Info("[workspaces] invalid workspace name %s", workspace.Name)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode("Error: workspace name must be present and should not exceed 20 character")
 		return
 	}
 
 	if len(workspace.Description) > 120 {
-		logger.Log.Info("[workspaces] invalid workspace name %s", workspace.Description)
+		logger.FromContext(r.Context()).Info("[workspaces] invalid workspace name %s", workspace.Description)
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode("Error: workspace description should not exceed 120 character")
 		return
@@ -98,9 +105,9 @@ func (oh *workspaceHandler) CreateOrEditWorkspace(w http.ResponseWriter, r *http
 	if pubKeyFromAuth != workspace.OwnerPubKey {
 		hasRole := db.UserHasAccess(pubKeyFromAuth, workspace.Uuid, db.EditOrg)
 		if !hasRole {
-			logger.Log.Info("[workspaces] mismatched pubkey")
-			logger.Log.Info("[workspaces] Auth pubkey: %s", pubKeyFromAuth)
-			logger.Log.Info("[workspaces] OwnerPubKey: %s", workspace.OwnerPubKey)
+			logger.FromContext(r.Context()).Info("[workspaces] mismatched pubkey")
+			logger.FromContext(r.Context()).Info("[workspaces] Auth pubkey: %s", pubKeyFromAuth)
+			logger.FromContext(r.Context()).Info("[workspaces] OwnerPubKey: %s", workspace.OwnerPubKey)
 			w.WriteHeader(http.StatusUnauthorized)
 			json.NewEncoder(w).Encode("Don't have access to Edit workspace")
 			return
@@ -126,7 +133,7 @@ func (oh *workspaceHandler) CreateOrEditWorkspace(w http.ResponseWriter, r *http
 	existing := oh.db.GetWorkspaceByUuid(workspace.Uuid)
 	if existing.ID == 0 { // new!
 		if workspace.ID != 0 { // can't try to "edit" if it does not exist already
-			logger.Log.Info("[workspaces] cant edit non existing")
+			logger.FromContext(r.Context()).Info("[workspaces] cant edit non existing")
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
@@ -157,6 +164,12 @@ func (oh *workspaceHandler) CreateOrEditWorkspace(w http.ResponseWriter, r *http
 		return
 	}
 
+	if existing.ID == 0 {
+		audit.Record(pubKeyFromAuth, p.Uuid, audit.EntityWorkspace, p.Uuid, audit.ActionCreate, nil, p)
+	} else {
+		audit.Record(pubKeyFromAuth, p.Uuid, audit.EntityWorkspace, p.Uuid, audit.ActionUpdate, existing, p)
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(p)
 }
@@ -232,7 +245,7 @@ func (oh *workspaceHandler) CreateWorkspaceUser(w http.ResponseWriter, r *http.R
 	r.Body.Close()
 
 	if err != nil {
-		logger.Log.Error("[body] %v", err)
+		logger.FromContext(r.Context()).Error("[body] %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -247,13 +260,13 @@ func (oh *workspaceHandler) CreateWorkspaceUser(w http.ResponseWriter, r *http.R
 	workspace := oh.db.GetWorkspaceByUuid(workspaceUser.WorkspaceUuid)
 
 	if err != nil {
-		logger.Log.Error("[workspaces] %v", err)
+		logger.FromContext(r.Context()).Error("[workspaces] %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -302,6 +315,8 @@ func (oh *workspaceHandler) CreateWorkspaceUser(w http.ResponseWriter, r *http.R
 
 	// create user
 	user := oh.db.CreateWorkspaceUser(workspaceUser)
+	audit.Record(pubKeyFromAuth, user.WorkspaceUuid, audit.EntityUserRole, user.OwnerPubKey, audit.ActionCreate, nil, user)
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(user)
 }
@@ -340,7 +355,7 @@ func GetWorkspaceUser(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -391,7 +406,7 @@ func DeleteWorkspaceUser(w http.ResponseWriter, r *http.Request) {
 	r.Body.Close()
 
 	if err != nil {
-		logger.Log.Error("[body] %v", err)
+		logger.FromContext(r.Context()).Error("[body] %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -403,13 +418,13 @@ func DeleteWorkspaceUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		logger.Log.Error("[workspaces] %v", err)
+		logger.FromContext(r.Context()).Error("[workspaces] %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -430,6 +445,7 @@ func DeleteWorkspaceUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	db.DB.DeleteWorkspaceUser(workspaceUser, workspaceUser.WorkspaceUuid)
+	audit.Record(pubKeyFromAuth, workspaceUser.WorkspaceUuid, audit.EntityUserRole, workspaceUser.OwnerPubKey, audit.ActionDelete, workspaceUser, nil)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(workspaceUser)
@@ -483,7 +499,7 @@ func (oh *workspaceHandler) AddUserRoles(w http.ResponseWriter, r *http.Request)
 	r.Body.Close()
 
 	if err != nil {
-		logger.Log.Error("[body] %v", err)
+		logger.FromContext(r.Context()).Error("[body] %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -491,7 +507,7 @@ func (oh *workspaceHandler) AddUserRoles(w http.ResponseWriter, r *http.Request)
 	err = json.Unmarshal(body, &roles)
 
 	if err != nil {
-		logger.Log.Error("[workspaces]: %v", err)
+		logger.FromContext(r.Context()).Error("[workspaces]: %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -565,12 +581,23 @@ func (oh *workspaceHandler) AddUserRoles(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	previousRoles := oh.db.GetUserRoles(uuid, user)
 	oh.db.CreateUserRoles(insertRoles, uuid, user)
+	audit.Record(pubKeyFromAuth, uuid, audit.EntityUserRole, user, audit.ActionUpdate, roleNames(previousRoles), roleNames(insertRoles))
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(insertRoles)
 }
 
+// roleNames keeps the audit diff of a role change to the role names.
+func roleNames(roles []db.WorkspaceUserRoles) map[string]interface{} {
+	names := []string{}
+	for _, role := range roles {
+		names = append(names, role.Role)
+	}
+	return map[string]interface{}{"roles": names}
+}
+
 // GetUserRoles godoc
 //
 //	@Summary		Get User Roles
@@ -608,7 +635,7 @@ func GetUserWorkspaces(w http.ResponseWriter, r *http.Request) {
 	userId, _ := utils.ConvertStringToUint(userIdParam)
 
 	if userId == 0 {
-		logger.Log.Info("[workspaces] provide user id")
+		logger.FromContext(r.Context()).Info("[workspaces] provide user id")
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -636,7 +663,7 @@ func (oh *workspaceHandler) GetUserDropdownWorkspaces(w http.ResponseWriter, r *
 	userId, _ := utils.ConvertStringToUint(userIdParam)
 
 	if userId == 0 {
-		logger.Log.Info("[workspaces] provide user id")
+		logger.FromContext(r.Context()).Info("[workspaces] provide user id")
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -784,7 +811,7 @@ func (oh *workspaceHandler) GetWorkspaceBudget(w http.ResponseWriter, r *http.Re
 	uuid := chi.URLParam(r, "uuid")
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -852,7 +879,7 @@ func GetPaymentHistory(w http.ResponseWriter, r *http.Request) {
 	uuid := chi.URLParam(r, "uuid")
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -897,27 +924,30 @@ func GetPaymentHistory(w http.ResponseWriter, r *http.Request) {
 //	@Param			workspace_uuid	path		string	true	"Workspace UUID"
 //	@Success		200				{string}	string	"Updated Payments Successfully"
 //	@Router			/workspaces/{workspace_uuid}/payments [put]
-func UpdateWorkspacePendingPayments(w http.ResponseWriter, r *http.Request) {
+func (oh *workspaceHandler) UpdateWorkspacePendingPayments(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	workspace_uuid := chi.URLParam(r, "workspace_uuid")
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("no pubkey from auth")
+		logger.FromContext(r.Context()).Info("no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	paymentsHistory := db.DB.GetWorkspacePendingPayments(workspace_uuid)
+	paymentsHistory := oh.db.GetWorkspacePendingPayments(workspace_uuid)
 
 	for _, payment := range paymentsHistory {
 		tag := payment.Tag
-		tagResult := GetInvoiceStatusByTag(tag)
+		tagResult := oh.getInvoiceStatusByTag(tag)
+		if tagResult.Status != db.PaymentComplete && replacedSendCompleted(oh.getInvoiceStatusByTag, payment) {
+			tagResult.Status = db.PaymentComplete
+		}
 
 		if tagResult.Status == db.PaymentComplete {
-			db.DB.SetPaymentAsComplete(tag)
+			oh.db.SetPaymentAsComplete(tag)
 
-			bounty := db.DB.GetBounty(payment.ID)
+			bounty := oh.db.GetBounty(payment.ID)
 
 			if bounty.ID > 0 {
 				now := time.Now()
@@ -929,20 +959,20 @@ func UpdateWorkspacePendingPayments(w http.ResponseWriter, r *http.Request) {
 				bounty.Completed = true
 				bounty.CompletionDate = &now
 
-				db.DB.UpdateBounty(bounty)
+				oh.db.UpdateBounty(bounty)
 			}
 		} else if tagResult.Status == db.PaymentFailed {
 			// Handle failed payments
-			bounty := db.DB.GetBounty(payment.ID)
+			bounty := oh.db.GetBounty(payment.ID)
 
 			if bounty.ID > 0 {
-				db.DB.SetPaymentStatusByBountyId(bounty.ID, tagResult)
+				oh.db.SetPaymentStatusByBountyId(bounty.ID, tagResult)
 
 				bounty.Paid = false
 				bounty.PaymentPending = false
 				bounty.PaymentFailed = true
 
-				db.DB.UpdateBounty(bounty)
+				oh.db.UpdateBounty(bounty)
 			}
 		}
 	}
@@ -968,7 +998,7 @@ func (oh *workspaceHandler) PollBudgetInvoices(w http.ResponseWriter, r *http.Re
 	uuid := chi.URLParam(r, "uuid")
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -986,7 +1016,10 @@ func (oh *workspaceHandler) PollBudgetInvoices(w http.ResponseWriter, r *http.Re
 
 		if invoiceRes.Response.Settled {
 			if !inv.Status && inv.Type == "BUDGET" {
-				oh.db.ProcessUpdateBudget(inv)
+				if err := oh.db.ProcessUpdateBudget(inv); err == nil {
+					emitWebhook(inv.WorkspaceUuid, webhooks.EventBudgetDeposit, inv)
+					audit.Record(inv.OwnerPubkey, inv.WorkspaceUuid, audit.EntityBudget, inv.WorkspaceUuid, audit.ActionDeposit, nil, inv)
+				}
 			}
 		} else {
 			// Cheeck if time has expired
@@ -1017,7 +1050,7 @@ func (oh *workspaceHandler) PollUserWorkspacesBudget(w http.ResponseWriter, r *h
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1041,7 +1074,10 @@ func (oh *workspaceHandler) PollUserWorkspacesBudget(w http.ResponseWriter, r *h
 
 			if invoiceRes.Response.Settled {
 				if !inv.Status && inv.Type == "BUDGET" {
-					oh.db.ProcessUpdateBudget(inv)
+					if err := oh.db.ProcessUpdateBudget(inv); err == nil {
+						emitWebhook(inv.WorkspaceUuid, webhooks.EventBudgetDeposit, inv)
+						audit.Record(inv.OwnerPubkey, inv.WorkspaceUuid, audit.EntityBudget, inv.WorkspaceUuid, audit.ActionDeposit, nil, inv)
+					}
 				}
 			} else {
 				// Cheeck if time has expired
@@ -1075,7 +1111,7 @@ func GetInvoicesCount(w http.ResponseWriter, r *http.Request) {
 	uuid := chi.URLParam(r, "uuid")
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1100,7 +1136,7 @@ func GetAllUserInvoicesCount(w http.ResponseWriter, r *http.Request) {
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1132,7 +1168,7 @@ func (oh *workspaceHandler) DeleteWorkspace(w http.ResponseWriter, r *http.Reque
 	uuid := chi.URLParam(r, "uuid")
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1140,7 +1176,7 @@ func (oh *workspaceHandler) DeleteWorkspace(w http.ResponseWriter, r *http.Reque
 	workspace := oh.db.GetWorkspaceByUuid(uuid)
 	if pubKeyFromAuth != workspace.OwnerPubKey {
 		msg := "only workspace admin can delete an workspace"
-		logger.Log.Info("[workspaces] %s", msg)
+		logger.FromContext(r.Context()).Info("[workspaces] %s", msg)
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(msg)
 		return
@@ -1149,11 +1185,12 @@ func (oh *workspaceHandler) DeleteWorkspace(w http.ResponseWriter, r *http.Reque
 	// Soft delete Workspace and delete user data
 	if err := oh.db.ProcessDeleteWorkspace(uuid); err != nil {
 		msg := "Error removing users from workspace"
-		logger.Log.Error("%s: %v", msg, err)
+		logger.FromContext(r.Context()).Error("%s: %v", msg, err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(msg)
 		return
 	}
+	audit.Record(pubKeyFromAuth, uuid, audit.EntityWorkspace, uuid, audit.ActionDelete, workspace, nil)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(workspace)
@@ -1176,7 +1213,7 @@ func (oh *workspaceHandler) UpdateWorkspace(w http.ResponseWriter, r *http.Reque
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1187,7 +1224,7 @@ func (oh *workspaceHandler) UpdateWorkspace(w http.ResponseWriter, r *http.Reque
 	err := json.Unmarshal(body, &workspace)
 
 	if err != nil {
-		logger.Log.Error("[workspaces] %v", err)
+		logger.FromContext(r.Context()).Error("[workspaces] %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -1195,9 +1232,9 @@ func (oh *workspaceHandler) UpdateWorkspace(w http.ResponseWriter, r *http.Reque
 	if pubKeyFromAuth != workspace.OwnerPubKey {
 		hasRole := db.UserHasAccess(pubKeyFromAuth, workspace.Uuid, db.EditOrg)
 		if !hasRole {
-			logger.Log.Info("[workspaces] mismatched pubkey")
-			logger.Log.Info("Auth Pubkey: %s", pubKeyFromAuth)
-			logger.Log.Info("OwnerPubKey: %s", workspace.OwnerPubKey)
+			logger.FromContext(r.Context()).Info("[workspaces] mismatched pubkey")
+			logger.FromContext(r.Context()).Info("Auth Pubkey: %s", pubKeyFromAuth)
+			logger.FromContext(r.Context()).Info("OwnerPubKey: %s", workspace.OwnerPubKey)
 			w.WriteHeader(http.StatusUnauthorized)
 			json.NewEncoder(w).Encode("Don't have access to Edit workspace")
 			return
@@ -1213,11 +1250,13 @@ func (oh *workspaceHandler) UpdateWorkspace(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	existing := oh.db.GetWorkspaceByUuid(workspace.Uuid)
 	p, err := oh.db.CreateOrEditWorkspace(workspace)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	audit.Record(pubKeyFromAuth, p.Uuid, audit.EntityWorkspace, p.Uuid, audit.ActionUpdate, existing, p)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(p)
@@ -1238,7 +1277,7 @@ func (oh *workspaceHandler) CreateOrEditWorkspaceRepository(w http.ResponseWrite
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1249,7 +1288,7 @@ func (oh *workspaceHandler) CreateOrEditWorkspaceRepository(w http.ResponseWrite
 	err := json.Unmarshal(body, &workspaceRepo)
 
 	if err != nil {
-		logger.Log.Error("[workspaces] %v", err)
+		logger.FromContext(r.Context()).Error("[workspaces] %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -1284,12 +1323,19 @@ func (oh *workspaceHandler) CreateOrEditWorkspaceRepository(w http.ResponseWrite
 		return
 	}
 
+	existing, existsErr := oh.db.GetWorkspaceRepoByWorkspaceUuidAndRepoUuid(workspaceRepo.WorkspaceUuid, workspaceRepo.Uuid)
 	p, err := oh.db.CreateOrEditWorkspaceRepository(workspaceRepo)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
+	if existsErr != nil {
+		audit.Record(pubKeyFromAuth, p.WorkspaceUuid, audit.EntityRepository, p.Uuid, audit.ActionCreate, nil, p)
+	} else {
+		audit.Record(pubKeyFromAuth, p.WorkspaceUuid, audit.EntityRepository, p.Uuid, audit.ActionUpdate, existing, p)
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(p)
 }
@@ -1308,7 +1354,7 @@ func (oh *workspaceHandler) GetWorkspaceRepositorByWorkspaceUuid(w http.Response
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1336,7 +1382,7 @@ func (oh *workspaceHandler) GetWorkspaceRepoByWorkspaceUuidAndRepoUuid(w http.Re
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1345,7 +1391,7 @@ func (oh *workspaceHandler) GetWorkspaceRepoByWorkspaceUuidAndRepoUuid(w http.Re
 	uuid := chi.URLParam(r, "uuid")
 	WorkspaceRepository, err := oh.db.GetWorkspaceRepoByWorkspaceUuidAndRepoUuid(workspace_uuid, uuid)
 	if err != nil {
-		logger.Log.Error("[workspaces] workspace repository not found: %v", err)
+		logger.FromContext(r.Context()).Error("[workspaces] workspace repository not found: %v", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Repository not found"})
 		return
@@ -1373,7 +1419,7 @@ func (oh *workspaceHandler) DeleteWorkspaceRepository(w http.ResponseWriter, r *
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1381,7 +1427,11 @@ func (oh *workspaceHandler) DeleteWorkspaceRepository(w http.ResponseWriter, r *
 	workspace_uuid := chi.URLParam(r, "workspace_uuid")
 	uuid := chi.URLParam(r, "uuid")
 
+	existing, existsErr := oh.db.GetWorkspaceRepoByWorkspaceUuidAndRepoUuid(workspace_uuid, uuid)
 	oh.db.DeleteWorkspaceRepository(workspace_uuid, uuid)
+	if existsErr == nil {
+		audit.Record(pubKeyFromAuth, workspace_uuid, audit.EntityRepository, uuid, audit.ActionDelete, existing, nil)
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -1408,7 +1458,7 @@ func (oh *workspaceHandler) GetFeaturesByWorkspaceUuid(w http.ResponseWriter, r
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1416,13 +1466,13 @@ func (oh *workspaceHandler) GetFeaturesByWorkspaceUuid(w http.ResponseWriter, r
 	uuid := chi.URLParam(r, "workspace_uuid")
 
 	if uuid == "" {
-		logger.Log.Info("workspace_uuid parameter is missing")
+		logger.FromContext(r.Context()).Info("workspace_uuid parameter is missing")
 		http.Error(w, "Missing workspace_uuid parameter", http.StatusBadRequest)
 		return
 	}
 
 	if !isValidUUID(uuid) {
-		logger.Log.Info("invalid UUID format or contains special characters")
+		logger.FromContext(r.Context()).Info("invalid UUID format or contains special characters")
 		http.Error(w, "Invalid UUID format or contains special characters", http.StatusBadRequest)
 		return
 	}
@@ -1467,7 +1517,7 @@ func (oh *workspaceHandler) GetLastWithdrawal(w http.ResponseWriter, r *http.Req
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1550,7 +1600,7 @@ func (oh *workspaceHandler) CreateOrEditWorkspaceCodeGraph(w http.ResponseWriter
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1561,7 +1611,7 @@ func (oh *workspaceHandler) CreateOrEditWorkspaceCodeGraph(w http.ResponseWriter
 	err := json.Unmarshal(body, &codeGraph)
 
 	if err != nil {
-		logger.Log.Error("[workspaces] %v", err)
+		logger.FromContext(r.Context()).Error("[workspaces] %v", err)
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
@@ -1612,7 +1662,7 @@ func (oh *workspaceHandler) GetWorkspaceCodeGraphByUUID(w http.ResponseWriter, r
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1620,7 +1670,7 @@ func (oh *workspaceHandler) GetWorkspaceCodeGraphByUUID(w http.ResponseWriter, r
 	uuid := chi.URLParam(r, "uuid")
 	codeGraph, err := oh.db.GetCodeGraphByUUID(uuid)
 	if err != nil {
-		logger.Log.Error("[workspaces] code graph not found: %v", err)
+		logger.FromContext(r.Context()).Error("[workspaces] code graph not found: %v", err)
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Code graph not found"})
 		return
@@ -1646,7 +1696,7 @@ func (oh *workspaceHandler) GetCodeGraphByWorkspaceUuid(w http.ResponseWriter, r
 	ctx := r.Context()
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1680,7 +1730,7 @@ func (oh *workspaceHandler) DeleteWorkspaceCodeGraph(w http.ResponseWriter, r *h
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1709,7 +1759,7 @@ func (oh *workspaceHandler) RefreshCodeGraph(w http.ResponseWriter, r *http.Requ
 	pubKeyFromAuth, _ := ctx.Value(auth.ContextKey).(string)
 
 	if pubKeyFromAuth == "" {
-		logger.Log.Info("[workspaces] no pubkey from auth")
+		logger.FromContext(r.Context()).Info("[workspaces] no pubkey from auth")
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -1752,7 +1802,41 @@ func (oh *workspaceHandler) RefreshCodeGraph(w http.ResponseWriter, r *http.Requ
 	w.Write(body)
 }
 
-// GetWorkspaceEnvVars proxies env var fetch to 3rd party
+// poolsURL is where the pools of codespaces keep their env vars.
+var poolsURL = "https://workspaces.sphinx.chat/api/pools/"
+
+var errPoolUnreachable = errors.New("failed to contact 3rd party service")
+
+// fetchPoolEnvVars gets the env vars of a pool. A response other than 200
+// is returned for the caller to pass on, and to close.
+func fetchPoolEnvVars(url string, poolAPIKey string) ([]map[string]interface{}, *http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, nil, errors.New("failed to create request")
+	}
+	req.Header.Set("Authorization", "Bearer "+poolAPIKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, errPoolUnreachable
+	}
+	if resp.StatusCode != 200 {
+		return nil, resp, nil
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Config struct {
+			EnvVars []map[string]interface{} `json:"env_vars"`
+		} `json:"config"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, nil, errors.New("failed to decode response")
+	}
+	return result.Config.EnvVars, nil, nil
+}
+
+// GetWorkspaceEnvVars proxies env var fetch to 3rd party. The values are
+// secrets, they come back redacted.
 func (oh *workspaceHandler) GetWorkspaceEnvVars(w http.ResponseWriter, r *http.Request) {
 	workspaceUUID := chi.URLParam(r, "workspace_uuid")
 	codespaces, err := oh.db.GetCodeSpaceMapByWorkspace(workspaceUUID)
@@ -1761,43 +1845,44 @@ func (oh *workspaceHandler) GetWorkspaceEnvVars(w http.ResponseWriter, r *http.R
 		json.NewEncoder(w).Encode(map[string]string{"error": "codespaceURL not found for workspace"})
 		return
 	}
-	codespaceURL := codespaces[0].CodeSpaceURL
-	url := "https://workspaces.sphinx.chat/api/pools/" + codespaceURL
-
-	req, err := http.NewRequest("GET", url, nil)
+	poolAPIKey, err := codespaces[0].PoolAPIKey.Reveal()
 	if err != nil {
+		logger.FromContext(r.Context()).Error("[workspaces] could not decrypt the pool api key of workspace %s: %v", workspaceUUID, err)
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "failed to create request"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to decrypt pool api key"})
 		return
 	}
-	req.Header.Set("Authorization", "Bearer "+codespaces[0].PoolAPIKey)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
+
+	envVars, resp, err := fetchPoolEnvVars(poolsURL+codespaces[0].CodeSpaceURL, poolAPIKey)
+	if errors.Is(err, errPoolUnreachable) {
 		w.WriteHeader(http.StatusBadGateway)
-		json.NewEncoder(w).Encode(map[string]string{"error": "failed to contact 3rd party service"})
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if resp != nil {
+		defer resp.Body.Close()
 		w.WriteHeader(resp.StatusCode)
 		io.Copy(w, resp.Body)
 		return
 	}
-	var result struct {
-		Config struct {
-			EnvVars []map[string]interface{} `json:"env_vars"`
-		} `json:"config"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "failed to decode response"})
-		return
+
+	for _, envVar := range envVars {
+		if value, ok := envVar["value"].(string); ok && value != "" {
+			logger.Redact(value)
+			envVar["value"] = vault.Redacted
+		}
 	}
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(result.Config.EnvVars)
+	json.NewEncoder(w).Encode(envVars)
 }
 
-// UpdateWorkspaceEnvVars proxies env var update to 3rd party
+// UpdateWorkspaceEnvVars proxies env var update to 3rd party. An env var
+// sent back with its redacted value keeps the value it has.
 func (oh *workspaceHandler) UpdateWorkspaceEnvVars(w http.ResponseWriter, r *http.Request) {
 	workspaceUUID := chi.URLParam(r, "workspace_uuid")
 	codespaces, err := oh.db.GetCodeSpaceMapByWorkspace(workspaceUUID)
@@ -1806,8 +1891,7 @@ func (oh *workspaceHandler) UpdateWorkspaceEnvVars(w http.ResponseWriter, r *htt
 		json.NewEncoder(w).Encode(map[string]string{"error": "codespaceURL not found for workspace"})
 		return
 	}
-	codespaceURL := codespaces[0].CodeSpaceURL
-	url := "https://workspaces.sphinx.chat/api/pools/" + codespaceURL
+	url := poolsURL + codespaces[0].CodeSpaceURL
 
 	var body struct {
 		EnvVars []map[string]string `json:"env_vars"`
@@ -1817,6 +1901,55 @@ func (oh *workspaceHandler) UpdateWorkspaceEnvVars(w http.ResponseWriter, r *htt
 		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
 		return
 	}
+	poolAPIKey, err := codespaces[0].PoolAPIKey.Reveal()
+	if err != nil {
+		logger.FromContext(r.Context()).Error("[workspaces] could not decrypt the pool api key of workspace %s: %v", workspaceUUID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to decrypt pool api key"})
+		return
+	}
+
+	redacted := false
+	for _, envVar := range body.EnvVars {
+		if envVar["value"] == vault.Redacted {
+			redacted = true
+		}
+	}
+	if redacted {
+		current, resp, err := fetchPoolEnvVars(url, poolAPIKey)
+		if err == nil && resp != nil {
+			resp.Body.Close()
+			err = fmt.Errorf("fetching the current env vars returned %d", resp.StatusCode)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		values := map[string]string{}
+		for _, envVar := range current {
+			name, _ := envVar["name"].(string)
+			value, _ := envVar["value"].(string)
+			values[name] = value
+		}
+		for _, envVar := range body.EnvVars {
+			if envVar["value"] != vault.Redacted {
+				continue
+			}
+			value, ok := values[envVar["name"]]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "no value to keep for env var " + envVar["name"]})
+				return
+			}
+			envVar["value"] = value
+		}
+	}
+	for _, envVar := range body.EnvVars {
+		logger.Redact(envVar["value"])
+	}
+
 	b, _ := json.Marshal(map[string]interface{}{"env_vars": body.EnvVars})
 	req, err := http.NewRequest("PUT", url, strings.NewReader(string(b)))
 	if err != nil {
@@ -1824,7 +1957,7 @@ func (oh *workspaceHandler) UpdateWorkspaceEnvVars(w http.ResponseWriter, r *htt
 		json.NewEncoder(w).Encode(map[string]string{"error": "failed to create request"})
 		return
 	}
-	req.Header.Set("Authorization", "Bearer "+codespaces[0].PoolAPIKey)
+	req.Header.Set("Authorization", "Bearer "+poolAPIKey)
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -1833,6 +1966,22 @@ func (oh *workspaceHandler) UpdateWorkspaceEnvVars(w http.ResponseWriter, r *htt
 		return
 	}
 	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]string{"error": "failed to read response"})
+		return
+	}
+
+	// the pool may echo the env vars back
+	redactedValue, _ := json.Marshal(vault.Redacted)
+	for _, envVar := range body.EnvVars {
+		if envVar["value"] == "" {
+			continue
+		}
+		value, _ := json.Marshal(envVar["value"])
+		respBody = bytes.ReplaceAll(respBody, value, redactedValue)
+	}
 	w.WriteHeader(resp.StatusCode)
-	io.Copy(w, resp.Body)
+	w.Write(respBody)
 }
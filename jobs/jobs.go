@@ -0,0 +1,241 @@
+// Package jobs runs background work from a Postgres backed queue. Jobs
+// are leased with SELECT ... FOR UPDATE SKIP LOCKED, so any number of
+// replicas can work the same queue without running a job twice; failed
+// jobs are retried with exponential backoff and dead-lettered once they
+// run out of attempts.
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/metrics"
+)
+
+const (
+	TypeInvoiceWatch      = "invoice.watch"
+	TypePaymentCheck      = "payment.check"
+	TypeNotificationSend  = "notification.send"
+	TypeBatchPayoutFinish = "batch_payout.finish"
+	TypeBudgetAlert       = "budget.alert"
+	TypeSecretsRewrap     = "secrets.rewrap"
+	TypeFileRetention     = "files.retention"
+)
+
+const (
+	// LeaseDuration is how long a worker owns a job before another one may
+	// take it over.
+	LeaseDuration = 5 * time.Minute
+
+	leaseBatchSize = 50
+)
+
+// Store persists the queue.
+type Store interface {
+	EnqueueJob(job *db.Job) (bool, error)
+	LeaseJobs(workerID string, now time.Time, lease time.Duration, limit int) ([]db.Job, error)
+	UpdateJob(job *db.Job) error
+}
+
+// Handler runs one job. Returning nil completes it, any other error
+// retries it unless it wraps ErrPermanent.
+type Handler func(job db.Job) error
+
+// ErrPermanent marks a failure that retrying cannot fix, the job is
+// dead-lettered right away.
+var ErrPermanent = errors.New("permanent job failure")
+
+// ErrWaiting marks a job waiting on something outside of it, like an
+// invoice being paid. It is retried with backoff but never dead-lettered
+// for it; the handler decides when to stop waiting.
+var ErrWaiting = errors.New("job waiting")
+
+// Options tune the retries of a job type.
+type Options struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+type registration struct {
+	handler Handler
+	options Options
+}
+
+type Queue struct {
+	store    Store
+	workerID string
+	handlers map[string]registration
+	running  sync.Mutex
+	now      func() time.Time
+}
+
+func NewQueue(store Store) *Queue {
+	host, _ := os.Hostname()
+	return &Queue{
+		store:    store,
+		workerID: fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8]),
+		handlers: map[string]registration{},
+		now:      time.Now,
+	}
+}
+
+// Default is the queue used by Enqueue and RunDue. Jobs are dropped until
+// Init is called.
+var Default *Queue
+
+func Init(store Store) {
+	Default = NewQueue(store)
+}
+
+// Register sets the handler of a job type. Jobs can only be queued for
+// registered types.
+func (q *Queue) Register(jobType string, handler Handler, options Options) {
+	if options.MaxAttempts < 1 {
+		options.MaxAttempts = 1
+	}
+	q.handlers[jobType] = registration{handler: handler, options: options}
+}
+
+// Enqueue queues a job on the default queue.
+func Enqueue(jobType string, dedupeKey string, payload interface{}) {
+	EnqueueAt(jobType, dedupeKey, payload, time.Time{})
+}
+
+// EnqueueAt queues a job on the default queue to run at runAt.
+func EnqueueAt(jobType string, dedupeKey string, payload interface{}, runAt time.Time) {
+	queue := Default
+	if queue == nil {
+		return
+	}
+	if _, err := queue.Enqueue(jobType, dedupeKey, payload, runAt); err != nil {
+		logger.Log.Error("[jobs] could not enqueue %s job %s: %v", jobType, dedupeKey, err)
+	}
+}
+
+// RunDue works the due jobs of the default queue.
+func RunDue() {
+	if Default == nil {
+		return
+	}
+	Default.RunDue()
+}
+
+// Enqueue queues a job to run at runAt, or right away when runAt is zero.
+// It reports false when an unfinished job with the same type and dedupe
+// key is already queued.
+func (q *Queue) Enqueue(jobType string, dedupeKey string, payload interface{}, runAt time.Time) (bool, error) {
+	registered, ok := q.handlers[jobType]
+	if !ok {
+		return false, fmt.Errorf("no handler registered for %s jobs", jobType)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+
+	return q.store.EnqueueJob(&db.Job{
+		Type:        jobType,
+		DedupeKey:   dedupeKey,
+		Payload:     string(body),
+		MaxAttempts: registered.options.MaxAttempts,
+		RunAt:       runAt,
+	})
+}
+
+// RunDue leases the due jobs and runs them, returning how many ran. A run
+// still in progress on this replica makes it return right away.
+func (q *Queue) RunDue() int {
+	if !q.running.TryLock() {
+		return 0
+	}
+	defer q.running.Unlock()
+
+	failed := false
+	defer func() { metrics.CronRun("jobs", failed) }()
+
+	leased, err := q.store.LeaseJobs(q.workerID, q.now(), LeaseDuration, leaseBatchSize)
+	if err != nil {
+		failed = true
+		logger.Log.Error("[jobs] could not lease jobs: %v", err)
+		return 0
+	}
+
+	for i := range leased {
+		if !q.run(&leased[i]) {
+			failed = true
+		}
+	}
+	return len(leased)
+}
+
+// Backoff is the wait before the next attempt once attempts have failed,
+// doubling from base up to max.
+func Backoff(options Options, attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := options.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if options.MaxBackoff > 0 && delay >= options.MaxBackoff {
+			return options.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// Decode unmarshals the payload of a job.
+func Decode(job db.Job, payload interface{}) error {
+	if err := json.Unmarshal([]byte(job.Payload), payload); err != nil {
+		return fmt.Errorf("%w: invalid payload: %v", ErrPermanent, err)
+	}
+	return nil
+}
+
+func (q *Queue) run(job *db.Job) bool {
+	registered, ok := q.handlers[job.Type]
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("%w: no handler registered for %s jobs", ErrPermanent, job.Type)
+	} else {
+		err = call(registered.handler, *job)
+	}
+
+	switch {
+	case err == nil:
+		job.Status = db.JobDone
+		job.LastError = ""
+	case errors.Is(err, ErrPermanent) || (job.Attempts >= job.MaxAttempts && !errors.Is(err, ErrWaiting)):
+		job.Status = db.JobDead
+		job.LastError = err.Error()
+		logger.Log.Error("[jobs] %s job %d dead after %d attempts: %v", job.Type, job.ID, job.Attempts, err)
+	default:
+		job.Status = db.JobPending
+		job.LastError = err.Error()
+		job.RunAt = q.now().Add(Backoff(registered.options, job.Attempts))
+	}
+
+	if updateErr := q.store.UpdateJob(job); updateErr != nil {
+		logger.Log.Error("[jobs] could not update %s job %d: %v", job.Type, job.ID, updateErr)
+	}
+	return job.Status != db.JobDead
+}
+
+func call(handler Handler, job db.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(job)
+}
@@ -0,0 +1,249 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryStore struct {
+	mu   sync.Mutex
+	jobs []db.Job
+}
+
+func (s *memoryStore) EnqueueJob(job *db.Job) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.jobs {
+		if job.DedupeKey != "" && existing.Type == job.Type && existing.DedupeKey == job.DedupeKey && existing.Status != db.JobDone {
+			return false, nil
+		}
+	}
+	job.ID = uint(len(s.jobs) + 1)
+	job.Status = db.JobPending
+	s.jobs = append(s.jobs, *job)
+	return true, nil
+}
+
+func (s *memoryStore) LeaseJobs(workerID string, now time.Time, lease time.Duration, limit int) ([]db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	leased := []db.Job{}
+	for i := range s.jobs {
+		job := &s.jobs[i]
+		due := job.Status == db.JobPending && !job.RunAt.After(now)
+		expired := job.Status == db.JobRunning && job.LockedUntil.Before(now)
+		if (due || expired) && len(leased) < limit {
+			until := now.Add(lease)
+			job.Status = db.JobRunning
+			job.LockedBy = workerID
+			job.LockedUntil = &until
+			job.Attempts++
+			leased = append(leased, *job)
+		}
+	}
+	return leased, nil
+}
+
+func (s *memoryStore) UpdateJob(job *db.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := &s.jobs[job.ID-1]
+	if stored.LockedBy != job.LockedBy {
+		return db.ErrJobLeaseLost
+	}
+	stored.Status = job.Status
+	stored.RunAt = job.RunAt
+	stored.LastError = job.LastError
+	stored.LockedBy = ""
+	stored.LockedUntil = nil
+	return nil
+}
+
+func (s *memoryStore) job(id uint) db.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[id-1]
+}
+
+func newTestQueue(now *time.Time) (*Queue, *memoryStore) {
+	store := &memoryStore{}
+	queue := NewQueue(store)
+	queue.now = func() time.Time { return *now }
+	return queue, store
+}
+
+func TestQueue(t *testing.T) {
+	options := Options{MaxAttempts: 3, BaseBackoff: time.Second, MaxBackoff: time.Minute}
+
+	t.Run("Rejects unregistered job types", func(t *testing.T) {
+		now := time.Now()
+		queue, _ := newTestQueue(&now)
+
+		_, err := queue.Enqueue("unknown", "", nil, time.Time{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Completes a successful job", func(t *testing.T) {
+		now := time.Now()
+		queue, store := newTestQueue(&now)
+		var got string
+		queue.Register("test", func(job db.Job) error {
+			payload := map[string]string{}
+			if err := Decode(job, &payload); err != nil {
+				return err
+			}
+			got = payload["name"]
+			return nil
+		}, options)
+
+		queued, err := queue.Enqueue("test", "", map[string]string{"name": "alice"}, now)
+		assert.NoError(t, err)
+		assert.True(t, queued)
+
+		assert.Equal(t, 1, queue.RunDue())
+		assert.Equal(t, "alice", got)
+		assert.Equal(t, db.JobDone, store.job(1).Status)
+		assert.Equal(t, 0, queue.RunDue())
+	})
+
+	t.Run("Deduplicates unfinished jobs", func(t *testing.T) {
+		now := time.Now()
+		queue, _ := newTestQueue(&now)
+		queue.Register("test", func(job db.Job) error { return nil }, options)
+
+		queued, _ := queue.Enqueue("test", "key", nil, now)
+		assert.True(t, queued)
+		queued, _ = queue.Enqueue("test", "key", nil, now)
+		assert.False(t, queued)
+
+		queue.RunDue()
+		queued, _ = queue.Enqueue("test", "key", nil, now)
+		assert.True(t, queued)
+	})
+
+	t.Run("Retries with backoff and dead-letters after max attempts", func(t *testing.T) {
+		now := time.Now()
+		queue, store := newTestQueue(&now)
+		calls := 0
+		queue.Register("test", func(job db.Job) error {
+			calls++
+			return fmt.Errorf("attempt %d failed", calls)
+		}, options)
+		queue.Enqueue("test", "", nil, now)
+
+		queue.RunDue()
+		job := store.job(1)
+		assert.Equal(t, db.JobPending, job.Status)
+		assert.Equal(t, now.Add(time.Second), job.RunAt)
+		assert.Equal(t, "attempt 1 failed", job.LastError)
+
+		assert.Equal(t, 0, queue.RunDue(), "not due before the backoff elapsed")
+
+		now = now.Add(time.Second)
+		queue.RunDue()
+		assert.Equal(t, now.Add(2*time.Second), store.job(1).RunAt)
+
+		now = now.Add(2 * time.Second)
+		queue.RunDue()
+		job = store.job(1)
+		assert.Equal(t, db.JobDead, job.Status)
+		assert.Equal(t, 3, job.Attempts)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Keeps waiting jobs past max attempts", func(t *testing.T) {
+		now := time.Now()
+		queue, store := newTestQueue(&now)
+		queue.Register("test", func(job db.Job) error {
+			return fmt.Errorf("%w: not paid yet", ErrWaiting)
+		}, options)
+		queue.Enqueue("test", "", nil, now)
+
+		for i := 0; i < 5; i++ {
+			queue.RunDue()
+			now = now.Add(time.Minute)
+		}
+		job := store.job(1)
+		assert.Equal(t, db.JobPending, job.Status)
+		assert.Equal(t, 5, job.Attempts)
+		assert.Equal(t, "job waiting: not paid yet", job.LastError)
+	})
+
+	t.Run("Dead-letters permanent failures right away", func(t *testing.T) {
+		now := time.Now()
+		queue, store := newTestQueue(&now)
+		queue.Register("test", func(job db.Job) error {
+			return fmt.Errorf("%w: bad input", ErrPermanent)
+		}, options)
+		queue.Enqueue("test", "", nil, now)
+
+		queue.RunDue()
+		assert.Equal(t, db.JobDead, store.job(1).Status)
+		assert.Equal(t, 1, store.job(1).Attempts)
+	})
+
+	t.Run("Recovers from panicking handlers", func(t *testing.T) {
+		now := time.Now()
+		queue, store := newTestQueue(&now)
+		queue.Register("test", func(job db.Job) error { panic("boom") }, options)
+		queue.Enqueue("test", "", nil, now)
+
+		assert.NotPanics(t, func() { queue.RunDue() })
+		assert.Equal(t, db.JobPending, store.job(1).Status)
+		assert.Contains(t, store.job(1).LastError, "boom")
+	})
+
+	t.Run("Takes over jobs whose lease expired", func(t *testing.T) {
+		now := time.Now()
+		queue, store := newTestQueue(&now)
+		queue.Register("test", func(job db.Job) error { return nil }, options)
+		queue.Enqueue("test", "", nil, now)
+
+		crashed, _ := store.LeaseJobs("crashed-worker", now, LeaseDuration, 10)
+		assert.Len(t, crashed, 1)
+		assert.Equal(t, 0, queue.RunDue())
+
+		now = now.Add(LeaseDuration + time.Second)
+		assert.Equal(t, 1, queue.RunDue())
+		assert.Equal(t, db.JobDone, store.job(1).Status)
+		assert.ErrorIs(t, store.UpdateJob(&crashed[0]), db.ErrJobLeaseLost)
+	})
+
+	t.Run("Default queue is a no-op until initialised", func(t *testing.T) {
+		Default = nil
+		assert.NotPanics(t, func() {
+			Enqueue("test", "", nil)
+			RunDue()
+		})
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	options := Options{BaseBackoff: 5 * time.Second, MaxBackoff: time.Minute}
+
+	assert.Equal(t, 5*time.Second, Backoff(options, 0))
+	assert.Equal(t, 5*time.Second, Backoff(options, 1))
+	assert.Equal(t, 10*time.Second, Backoff(options, 2))
+	assert.Equal(t, 40*time.Second, Backoff(options, 4))
+	assert.Equal(t, time.Minute, Backoff(options, 5))
+	assert.Equal(t, time.Minute, Backoff(options, 50))
+}
+
+func TestDecode(t *testing.T) {
+	payload := struct {
+		ID uint `json:"id"`
+	}{}
+
+	assert.NoError(t, Decode(db.Job{Payload: `{"id": 4}`}, &payload))
+	assert.Equal(t, uint(4), payload.ID)
+	assert.True(t, errors.Is(Decode(db.Job{Payload: `not json`}, &payload), ErrPermanent))
+}
@@ -0,0 +1,298 @@
+package lightning
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+	decodepay "github.com/nbd-wtf/ln-decodepay"
+	"github.com/stakwork/sphinx-tribes/db"
+)
+
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+// FakeNode is an in-process Lightning node for tests. The invoices it
+// creates are real signed regtest bolt11 strings, so they decode like any
+// other, but nothing leaves the process: tests settle incoming invoices
+// with Settle, receive asset transfers with ReceiveAsset and decide how
+// outgoing payments end with SetPaymentOutcome and ResolvePayment.
+type FakeNode struct {
+	mu        sync.Mutex
+	key       *btcec.PrivateKey
+	invoices  map[string]*fakeInvoice
+	transfers map[string]db.AssetTx
+	payments  []FakePayment
+	status    string
+	message   string
+}
+
+type fakeInvoice struct {
+	amount   uint
+	hash     string
+	preimage string
+	settled  bool
+}
+
+// FakePayment is an outgoing payment made through a FakeNode, either a
+// keysend, an invoice payment or an asset transfer when AssetId is set.
+type FakePayment struct {
+	Tag            string
+	PaymentRequest string
+	Pubkey         string
+	AssetId        uint
+	Amount         uint
+	Memo           string
+	Status         string
+	Message        string
+}
+
+func NewFakeNode() *FakeNode {
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		panic(err)
+	}
+	return &FakeNode{
+		key:       key,
+		invoices:  map[string]*fakeInvoice{},
+		transfers: map[string]db.AssetTx{},
+		status:    db.PaymentComplete,
+	}
+}
+
+// Pubkey is the node's identity, the payee of every invoice it creates.
+func (n *FakeNode) Pubkey() string {
+	return hex.EncodeToString(n.key.PubKey().SerializeCompressed())
+}
+
+// SetPaymentOutcome decides how the following outgoing payments end:
+// db.PaymentComplete (the default), db.PaymentPending or db.PaymentFailed
+// with message as the reason.
+func (n *FakeNode) SetPaymentOutcome(status string, message string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.status = status
+	n.message = message
+}
+
+// Settle marks one of the node's invoices as paid.
+func (n *FakeNode) Settle(paymentRequest string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	invoice, ok := n.invoices[paymentRequest]
+	if !ok {
+		return ErrInvoiceNotFound
+	}
+	invoice.settled = true
+	return nil
+}
+
+// ReceiveAsset records an incoming asset transfer from sender and returns
+// its transaction id.
+func (n *FakeNode) ReceiveAsset(sender string, assetId uint, amount uint) string {
+	txid := make([]byte, 32)
+	rand.Read(txid)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	transfer := db.AssetTx{
+		Sender:   sender,
+		Receiver: hex.EncodeToString(n.key.PubKey().SerializeCompressed()),
+		AssetId:  assetId,
+		Amount:   amount,
+		Txid:     hex.EncodeToString(txid),
+	}
+	n.transfers[transfer.Txid] = transfer
+	return transfer.Txid
+}
+
+// ResolvePayment moves an outgoing payment, usually a pending one, to a
+// new status.
+func (n *FakeNode) ResolvePayment(tag string, status string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for i := range n.payments {
+		if n.payments[i].Tag == tag {
+			n.payments[i].Status = status
+			return nil
+		}
+	}
+	return errors.New("payment not found")
+}
+
+// Payments lists the outgoing payments in the order they were made.
+func (n *FakeNode) Payments() []FakePayment {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]FakePayment(nil), n.payments...)
+}
+
+func (n *FakeNode) CreateInvoice(amount uint, memo string) (db.InvoiceResponse, db.InvoiceError) {
+	return n.CreateInvoiceAt(amount, memo, time.Now())
+}
+
+// CreateInvoiceAt creates an invoice as if it had been created at created,
+// letting tests hold invoices that already expired.
+func (n *FakeNode) CreateInvoiceAt(amount uint, memo string, created time.Time) (db.InvoiceResponse, db.InvoiceError) {
+	preimage := make([]byte, 32)
+	if _, err := rand.Read(preimage); err != nil {
+		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+	hash := sha256.Sum256(preimage)
+
+	invoice, err := zpay32.NewInvoice(&chaincfg.RegressionNetParams, hash, created,
+		zpay32.Amount(lnwire.MilliSatoshi(amount*1000)),
+		zpay32.Description(memo))
+	if err != nil {
+		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	paymentRequest, err := invoice.Encode(zpay32.MessageSigner{
+		SignCompact: func(msg []byte) ([]byte, error) {
+			digest := sha256.Sum256(msg)
+			return ecdsa.SignCompact(n.key, digest[:], true)
+		},
+	})
+	if err != nil {
+		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	n.mu.Lock()
+	n.invoices[paymentRequest] = &fakeInvoice{
+		amount:   amount,
+		hash:     hex.EncodeToString(hash[:]),
+		preimage: hex.EncodeToString(preimage),
+	}
+	n.mu.Unlock()
+
+	return db.InvoiceResponse{
+		Succcess: true,
+		Response: db.Invoice{Invoice: paymentRequest},
+	}, db.InvoiceError{}
+}
+
+func (n *FakeNode) LookupInvoice(paymentRequest string) (db.InvoiceResult, db.InvoiceError) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	invoice, ok := n.invoices[paymentRequest]
+	if !ok {
+		return db.InvoiceResult{}, db.InvoiceError{Success: false, Error: ErrInvoiceNotFound.Error()}
+	}
+
+	result := db.InvoiceResult{
+		Success: invoice.settled,
+		Response: db.InvoiceCheckResponse{
+			Settled:         invoice.settled,
+			Payment_request: paymentRequest,
+			Payment_hash:    invoice.hash,
+			Amount:          strconv.FormatUint(uint64(invoice.amount), 10),
+		},
+	}
+	if invoice.settled {
+		result.Response.Preimage = invoice.preimage
+	}
+	return result, db.InvoiceError{}
+}
+
+// PayInvoice pays any decodable invoice. Paying one of the node's own
+// invoices also settles it.
+func (n *FakeNode) PayInvoice(paymentRequest string) (db.InvoicePaySuccess, db.InvoicePayError) {
+	decoded, err := decodepay.Decodepay(paymentRequest)
+	if err != nil {
+		return db.InvoicePaySuccess{}, db.InvoicePayError{Success: false, Error: err.Error()}
+	}
+
+	amount := uint(decoded.MSatoshi / 1000)
+	payment := n.pay(FakePayment{PaymentRequest: paymentRequest, Pubkey: decoded.Payee, Amount: amount, Memo: decoded.Description})
+	if payment.Status == db.PaymentFailed {
+		return db.InvoicePaySuccess{}, db.InvoicePayError{Success: false, Error: payment.Message}
+	}
+
+	settled := payment.Status == db.PaymentComplete
+	if settled {
+		n.Settle(paymentRequest)
+	}
+
+	return db.InvoicePaySuccess{
+		Success: settled,
+		Response: db.InvoiceCheckResponse{
+			Settled:         settled,
+			Payment_request: paymentRequest,
+			Payment_hash:    decoded.PaymentHash,
+			Amount:          strconv.FormatUint(uint64(amount), 10),
+		},
+	}, db.InvoicePayError{}
+}
+
+func (n *FakeNode) Keysend(amount uint, pubkey string, routeHint string, memo string) (db.V2SendOnionRes, error) {
+	payment := n.pay(FakePayment{Pubkey: pubkey, Amount: amount, Memo: memo})
+	return db.V2SendOnionRes{
+		Status:  payment.Status,
+		Tag:     payment.Tag,
+		Message: payment.Message,
+	}, nil
+}
+
+func (n *FakeNode) SendAsset(assetId uint, amount uint, pubkey string, routeHint string, memo string) (db.V2SendOnionRes, error) {
+	payment := n.pay(FakePayment{Pubkey: pubkey, AssetId: assetId, Amount: amount, Memo: memo})
+	return db.V2SendOnionRes{
+		Status:  payment.Status,
+		Tag:     payment.Tag,
+		Message: payment.Message,
+	}, nil
+}
+
+func (n *FakeNode) LookupAssetTransfer(txid string) (db.AssetTx, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	transfer, ok := n.transfers[txid]
+	if !ok {
+		return db.AssetTx{}, ErrTransferNotFound
+	}
+	return transfer, nil
+}
+
+func (n *FakeNode) PaymentStatusByTag(tag string) db.V2TagRes {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, payment := range n.payments {
+		if payment.Tag == tag {
+			return db.V2TagRes{
+				Tag:    payment.Tag,
+				Status: payment.Status,
+				Error:  payment.Message,
+			}
+		}
+	}
+	return db.V2TagRes{}
+}
+
+func (n *FakeNode) pay(payment FakePayment) FakePayment {
+	tag := make([]byte, 16)
+	rand.Read(tag)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	payment.Tag = hex.EncodeToString(tag)
+	payment.Status = n.status
+	if n.status == db.PaymentFailed {
+		payment.Message = n.message
+	}
+	n.payments = append(n.payments, payment)
+	return payment
+}
@@ -0,0 +1,90 @@
+package lightning
+
+import (
+	"testing"
+
+	decodepay "github.com/nbd-wtf/ln-decodepay"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeNodeInvoices(t *testing.T) {
+	node := NewFakeNode()
+
+	invoice, invoiceErr := node.CreateInvoice(1500, "Budget Invoice")
+	assert.Empty(t, invoiceErr.Error)
+	paymentRequest := invoice.Response.Invoice
+
+	decoded, err := decodepay.Decodepay(paymentRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500000), decoded.MSatoshi)
+	assert.Equal(t, node.Pubkey(), decoded.Payee)
+	assert.Equal(t, "Budget Invoice", decoded.Description)
+
+	result, _ := node.LookupInvoice(paymentRequest)
+	assert.False(t, result.Response.Settled)
+
+	assert.NoError(t, node.Settle(paymentRequest))
+	result, _ = node.LookupInvoice(paymentRequest)
+	assert.True(t, result.Response.Settled)
+	assert.NotEmpty(t, result.Response.Preimage)
+
+	_, invoiceErr = node.LookupInvoice("lnbcrt1unknown")
+	assert.NotEmpty(t, invoiceErr.Error)
+	assert.ErrorIs(t, node.Settle("lnbcrt1unknown"), ErrInvoiceNotFound)
+}
+
+func TestFakeNodePayments(t *testing.T) {
+	t.Run("Paying an invoice settles it on the payee", func(t *testing.T) {
+		payer, payee := NewFakeNode(), NewFakeNode()
+		invoice, _ := payee.CreateInvoice(800, "withdraw")
+
+		paid, payErr := payer.PayInvoice(invoice.Response.Invoice)
+
+		assert.Empty(t, payErr.Error)
+		assert.True(t, paid.Success)
+		assert.Equal(t, payee.Pubkey(), payer.Payments()[0].Pubkey)
+		assert.Equal(t, uint(800), payer.Payments()[0].Amount)
+	})
+
+	t.Run("Failed invoice payment", func(t *testing.T) {
+		payer, payee := NewFakeNode(), NewFakeNode()
+		payer.SetPaymentOutcome(db.PaymentFailed, "insufficient balance")
+		invoice, _ := payee.CreateInvoice(800, "withdraw")
+
+		paid, payErr := payer.PayInvoice(invoice.Response.Invoice)
+
+		assert.False(t, paid.Success)
+		assert.Equal(t, "insufficient balance", payErr.Error)
+	})
+
+	t.Run("Pending keysend resolves by tag", func(t *testing.T) {
+		node := NewFakeNode()
+		node.SetPaymentOutcome(db.PaymentPending, "")
+
+		res, err := node.Keysend(500, "hunter", "", "memo")
+		assert.NoError(t, err)
+		assert.Equal(t, db.PaymentPending, res.Status)
+		assert.Equal(t, db.PaymentPending, node.PaymentStatusByTag(res.Tag).Status)
+
+		assert.NoError(t, node.ResolvePayment(res.Tag, db.PaymentComplete))
+		assert.Equal(t, db.PaymentComplete, node.PaymentStatusByTag(res.Tag).Status)
+		assert.Empty(t, node.PaymentStatusByTag("unknown").Status)
+	})
+	t.Run("Asset transfers", func(t *testing.T) {
+		node := NewFakeNode()
+
+		txid := node.ReceiveAsset("owner", 7, 100)
+		transfer, err := node.LookupAssetTransfer(txid)
+		assert.NoError(t, err)
+		assert.Equal(t, node.Pubkey(), transfer.Receiver)
+		assert.Equal(t, uint(7), transfer.AssetId)
+		_, err = node.LookupAssetTransfer("unknown")
+		assert.ErrorIs(t, err, ErrTransferNotFound)
+
+		res, err := node.SendAsset(7, 25, "hunter", "", "memo")
+		assert.NoError(t, err)
+		assert.Equal(t, db.PaymentComplete, res.Status)
+		assert.Equal(t, uint(7), node.Payments()[0].AssetId)
+	})
+}
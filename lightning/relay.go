@@ -0,0 +1,191 @@
+package lightning
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/utils"
+)
+
+// Relay talks to a v1 sphinx relay at config.RelayUrl.
+type Relay struct {
+	client HttpClient
+}
+
+func NewRelay(client HttpClient) *Relay {
+	return &Relay{client: client}
+}
+
+func (r *Relay) newRequest(method string, path string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewBuffer(body)
+	}
+
+	req, err := http.NewRequest(method, config.RelayUrl+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-user-token", config.RelayAuthKey)
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func (r *Relay) CreateInvoice(amount uint, memo string) (db.InvoiceResponse, db.InvoiceError) {
+	bodyData := fmt.Sprintf(`{"amount": %d, "memo": "%s"}`, amount, memo)
+
+	req, err := r.newRequest(http.MethodPost, "/invoices", []byte(bodyData))
+	if err != nil {
+		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		log.Printf("[lightning] Relay request failed: %s", err)
+		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Printf("[lightning] Reading relay invoice body failed: %s", err)
+		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	invoiceRes := db.InvoiceResponse{}
+	err = json.Unmarshal(body, &invoiceRes)
+	if err != nil {
+		log.Printf("[lightning] Unmarshal relay invoice body failed: %s", err)
+		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	return invoiceRes, db.InvoiceError{}
+}
+
+func (r *Relay) LookupInvoice(paymentRequest string) (db.InvoiceResult, db.InvoiceError) {
+	req, err := r.newRequest(http.MethodGet, "/invoice?payment_request="+paymentRequest, nil)
+	if err != nil {
+		return db.InvoiceResult{}, db.InvoiceError{}
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		log.Printf("[lightning] Relay request failed: %s", err)
+		return db.InvoiceResult{}, db.InvoiceError{}
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Printf("Error reading: %s", err)
+		return db.InvoiceResult{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	if res.StatusCode != 200 {
+		invoiceErr := db.InvoiceError{}
+		err = json.Unmarshal(body, &invoiceErr)
+		if err != nil {
+			log.Printf("[lightning] Reading relay invoice body failed: %s", err)
+		}
+		return db.InvoiceResult{}, invoiceErr
+	}
+
+	invoiceRes := db.InvoiceResult{}
+	err = json.Unmarshal(body, &invoiceRes)
+	if err != nil {
+		log.Printf("[lightning] Reading relay invoice body failed: %s", err)
+	}
+	return invoiceRes, db.InvoiceError{}
+}
+
+func (r *Relay) PayInvoice(paymentRequest string) (db.InvoicePaySuccess, db.InvoicePayError) {
+	bodyData := fmt.Sprintf(`{"payment_request": "%s"}`, paymentRequest)
+
+	req, err := r.newRequest(http.MethodPut, "/invoices", []byte(bodyData))
+	if err != nil {
+		log.Printf("Error paying invoice: %s", err)
+		return db.InvoicePaySuccess{}, db.InvoicePayError{}
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		log.Printf("[lightning] Relay request failed: %s", err)
+		return db.InvoicePaySuccess{}, db.InvoicePayError{}
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Printf("Error could not read body: %s", err)
+	}
+
+	if res.StatusCode != 200 {
+		invoiceError := db.InvoicePayError{}
+		err = json.Unmarshal(body, &invoiceError)
+		if err != nil {
+			log.Printf("[lightning] Reading invoice pay error body failed: %s", err)
+			return db.InvoicePaySuccess{}, db.InvoicePayError{}
+		}
+		return db.InvoicePaySuccess{}, invoiceError
+	}
+
+	invoiceSuccess := db.InvoicePaySuccess{}
+	err = json.Unmarshal(body, &invoiceSuccess)
+	if err != nil {
+		log.Printf("[lightning] Reading invoice pay success body failed: %s", err)
+		return db.InvoicePaySuccess{}, db.InvoicePayError{}
+	}
+	return invoiceSuccess, db.InvoicePayError{}
+}
+
+// Keysend pays the relay's /payment endpoint. The relay answers once the
+// payment settled, so a successful call is always COMPLETE.
+func (r *Relay) Keysend(amount uint, pubkey string, routeHint string, memo string) (db.V2SendOnionRes, error) {
+	bodyData := utils.BuildKeysendBodyData(amount, pubkey, routeHint, memo)
+
+	req, err := r.newRequest(http.MethodPost, "/payment", []byte(bodyData))
+	if err != nil {
+		return db.V2SendOnionRes{}, err
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return db.V2SendOnionRes{}, err
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return db.V2SendOnionRes{}, err
+	}
+
+	if res.StatusCode != 200 {
+		keysendError := db.KeysendError{}
+		json.Unmarshal(body, &keysendError)
+		log.Printf("[lightning] Keysend payment to %s failed, with error: %s", pubkey, keysendError.Error)
+		return db.V2SendOnionRes{Status: db.PaymentFailed, Message: keysendError.Error}, fmt.Errorf("%w: %s", ErrRejected, keysendError.Error)
+	}
+
+	keysendRes := db.KeysendSuccess{}
+	if err := json.Unmarshal(body, &keysendRes); err != nil {
+		return db.V2SendOnionRes{}, err
+	}
+
+	return db.V2SendOnionRes{Status: db.PaymentComplete}, nil
+}
+
+// PaymentStatusByTag always returns an empty result, relay payments are
+// settled synchronously and never tagged.
+func (r *Relay) PaymentStatusByTag(tag string) db.V2TagRes {
+	return db.V2TagRes{}
+}
@@ -0,0 +1,96 @@
+package lightning
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func withRelay(t *testing.T, handler http.HandlerFunc) *Relay {
+	server := httptest.NewServer(handler)
+	originalUrl, originalKey := config.RelayUrl, config.RelayAuthKey
+	config.RelayUrl, config.RelayAuthKey = server.URL, "relay-key"
+	t.Cleanup(func() {
+		server.Close()
+		config.RelayUrl, config.RelayAuthKey = originalUrl, originalKey
+	})
+	return NewRelay(http.DefaultClient)
+}
+
+func TestRelayCreateInvoice(t *testing.T) {
+	relay := withRelay(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/invoices", r.URL.Path)
+		assert.Equal(t, "relay-key", r.Header.Get("x-user-token"))
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"amount": 1000, "memo": "Budget Invoice"}`, string(body))
+
+		json.NewEncoder(w).Encode(db.InvoiceResponse{Succcess: true, Response: db.Invoice{Invoice: "lnbc1"}})
+	})
+
+	invoice, invoiceErr := relay.CreateInvoice(1000, "Budget Invoice")
+
+	assert.Empty(t, invoiceErr.Error)
+	assert.Equal(t, "lnbc1", invoice.Response.Invoice)
+}
+
+func TestRelayLookupInvoice(t *testing.T) {
+	relay := withRelay(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "lnbc1", r.URL.Query().Get("payment_request"))
+		json.NewEncoder(w).Encode(db.InvoiceResult{Success: true, Response: db.InvoiceCheckResponse{Settled: true, Payment_request: "lnbc1"}})
+	})
+
+	result, invoiceErr := relay.LookupInvoice("lnbc1")
+
+	assert.Empty(t, invoiceErr.Error)
+	assert.True(t, result.Response.Settled)
+}
+
+func TestRelayKeysend(t *testing.T) {
+	t.Run("Success is complete", func(t *testing.T) {
+		relay := withRelay(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/payment", r.URL.Path)
+			body := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, float64(500), body["amount"])
+			assert.Equal(t, "hunter", body["destination_key"])
+
+			w.Write([]byte(`{"success": true, "response": {"sumAmount": "500"}}`))
+		})
+
+		res, err := relay.Keysend(500, "hunter", "", "memo")
+
+		assert.NoError(t, err)
+		assert.Equal(t, db.PaymentComplete, res.Status)
+	})
+
+	t.Run("Error status is a rejection", func(t *testing.T) {
+		relay := withRelay(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"success": false, "error": "no route"}`))
+		})
+
+		res, err := relay.Keysend(500, "hunter", "", "memo")
+
+		assert.True(t, errors.Is(err, ErrRejected))
+		assert.Equal(t, "no route", res.Message)
+	})
+
+	t.Run("Unreadable answer is not a rejection", func(t *testing.T) {
+		relay := withRelay(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`not json`))
+		})
+
+		_, err := relay.Keysend(500, "hunter", "", "memo")
+
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, ErrRejected))
+	})
+}
@@ -0,0 +1,250 @@
+package lightning
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/utils"
+)
+
+// V2Bot talks to a sphinx v2 bot at config.V2BotUrl.
+type V2Bot struct {
+	client HttpClient
+}
+
+func NewV2Bot(client HttpClient) *V2Bot {
+	return &V2Bot{client: client}
+}
+
+func (b *V2Bot) newRequest(method string, path string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewBuffer(body)
+	}
+
+	req, err := http.NewRequest(method, config.V2BotUrl+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-admin-token", config.V2BotToken)
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func (b *V2Bot) CreateInvoice(amount uint, memo string) (db.InvoiceResponse, db.InvoiceError) {
+	bodyData := fmt.Sprintf(`{"amt_msat": %d}`, amount*1000)
+
+	req, err := b.newRequest(http.MethodPost, "/invoice", []byte(bodyData))
+	if err != nil {
+		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		log.Printf("[lightning] V2 bot request failed: %s", err)
+		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Printf("[lightning] Reading v2 invoice body failed: %s", err)
+		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	v2InvoiceRes := db.V2CreateInvoiceResponse{}
+	err = json.Unmarshal(body, &v2InvoiceRes)
+	if err != nil {
+		log.Printf("[lightning] Unmarshal v2 invoice body failed: %s", err)
+		return db.InvoiceResponse{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	return db.InvoiceResponse{
+		Succcess: true,
+		Response: db.Invoice{
+			Invoice: v2InvoiceRes.Bolt11,
+		},
+	}, db.InvoiceError{}
+}
+
+func (b *V2Bot) LookupInvoice(paymentRequest string) (db.InvoiceResult, db.InvoiceError) {
+	jsonBody, _ := json.Marshal(db.V2InvoiceBody{Bolt11: paymentRequest})
+
+	req, err := b.newRequest(http.MethodPost, "/check_invoice", jsonBody)
+	if err != nil {
+		return db.InvoiceResult{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		log.Printf("[lightning] V2 bot request failed: %s", err)
+		return db.InvoiceResult{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Printf("[lightning] Reading v2 invoice body failed: %s", err)
+		return db.InvoiceResult{}, db.InvoiceError{Success: false, Error: err.Error()}
+	}
+
+	if res.StatusCode != 200 {
+		invoiceErr := db.InvoiceError{}
+		err = json.Unmarshal(body, &invoiceErr)
+		if err != nil {
+			log.Printf("[lightning] Unmarshalling v2 invoice body failed: %s", err)
+		}
+		return db.InvoiceResult{}, invoiceErr
+	}
+
+	invoiceRes := db.V2InvoiceResponse{}
+	err = json.Unmarshal(body, &invoiceRes)
+	if err != nil {
+		log.Printf("[lightning] Reading v2 invoice body failed: %s", err)
+		return db.InvoiceResult{}, db.InvoiceError{}
+	}
+
+	invoiceResult := db.InvoiceResult{
+		Success: false,
+		Response: db.InvoiceCheckResponse{
+			Settled:         false,
+			Payment_request: paymentRequest,
+		},
+	}
+
+	if invoiceRes.Status == db.InvoicePaid {
+		invoiceResult.Success = true
+		invoiceResult.Response.Settled = true
+	}
+	return invoiceResult, db.InvoiceError{}
+}
+
+func (b *V2Bot) PayInvoice(paymentRequest string) (db.InvoicePaySuccess, db.InvoicePayError) {
+	bodyData := fmt.Sprintf(`{"bolt11": "%s", "wait": true}`, paymentRequest)
+
+	req, err := b.newRequest(http.MethodPost, "/pay_invoice", []byte(bodyData))
+	if err != nil {
+		log.Printf("Error paying invoice: %s", err)
+		return db.InvoicePaySuccess{}, db.InvoicePayError{}
+	}
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		log.Printf("[lightning] V2 bot request failed: %s", err)
+		return db.InvoicePaySuccess{}, db.InvoicePayError{}
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Printf("Error could not read body: %s", err)
+	}
+
+	if res.StatusCode != 200 {
+		invoiceError := db.InvoicePayError{}
+		err = json.Unmarshal(body, &invoiceError)
+		if err != nil {
+			log.Printf("[lightning] Reading invoice pay error body failed: %s", err)
+			return db.InvoicePaySuccess{}, db.InvoicePayError{}
+		}
+		return db.InvoicePaySuccess{}, invoiceError
+	}
+
+	invoiceRes := db.V2InvoiceResponse{}
+	err = json.Unmarshal(body, &invoiceRes)
+	if err != nil {
+		log.Printf("[lightning] Reading invoice pay success body failed: %s", err)
+		return db.InvoicePaySuccess{}, db.InvoicePayError{}
+	}
+
+	invoiceResult := db.InvoicePaySuccess{
+		Success: false,
+		Response: db.InvoiceCheckResponse{
+			Settled:         false,
+			Payment_request: paymentRequest,
+		},
+	}
+
+	if invoiceRes.Status == db.PaymentComplete {
+		invoiceResult.Success = true
+		invoiceResult.Response.Settled = true
+	}
+	return invoiceResult, db.InvoicePayError{}
+}
+
+// Keysend sends an onion payment through the bot's /pay endpoint. The bot
+// may answer before the payment settles, in which case the result is
+// PENDING and PaymentStatusByTag resolves it later.
+func (b *V2Bot) Keysend(amount uint, pubkey string, routeHint string, memo string) (db.V2SendOnionRes, error) {
+	bodyData := utils.BuildV2KeysendBodyData(amount, pubkey, routeHint, memo)
+
+	req, err := b.newRequest(http.MethodPost, "/pay", []byte(bodyData))
+	if err != nil {
+		return db.V2SendOnionRes{}, err
+	}
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return db.V2SendOnionRes{}, err
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return db.V2SendOnionRes{}, err
+	}
+
+	if res.StatusCode != 200 {
+		log.Printf("[lightning] V2 keysend to %s failed with status %d", pubkey, res.StatusCode)
+		return db.V2SendOnionRes{Status: db.PaymentFailed}, fmt.Errorf("%w: status %d", ErrRejected, res.StatusCode)
+	}
+
+	keysendRes := db.V2SendOnionRes{}
+	if err := json.Unmarshal(body, &keysendRes); err != nil {
+		return db.V2SendOnionRes{}, err
+	}
+
+	return keysendRes, nil
+}
+
+func (b *V2Bot) PaymentStatusByTag(tag string) db.V2TagRes {
+	req, err := b.newRequest(http.MethodGet, "/sends/"+tag, nil)
+	if err != nil {
+		log.Printf("[lightning] Could not build tag request: %s", err)
+		return db.V2TagRes{}
+	}
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		log.Printf("[Get Tag] Request Failed: %s", err)
+		return db.V2TagRes{}
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Printf("Could not read body: %s", err)
+	}
+
+	tagRes := []db.V2TagRes{}
+	err = json.Unmarshal(body, &tagRes)
+	if err != nil {
+		log.Printf("Could not unmarshal get tag result: %s", err)
+	}
+
+	if len(tagRes) > 0 {
+		return tagRes[0]
+	}
+	return db.V2TagRes{}
+}
@@ -0,0 +1,115 @@
+package lightning
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func withV2Bot(t *testing.T, handler http.HandlerFunc) *V2Bot {
+	server := httptest.NewServer(handler)
+	originalUrl, originalToken := config.V2BotUrl, config.V2BotToken
+	config.V2BotUrl, config.V2BotToken = server.URL, "bot-token"
+	t.Cleanup(func() {
+		server.Close()
+		config.V2BotUrl, config.V2BotToken = originalUrl, originalToken
+	})
+	return NewV2Bot(http.DefaultClient)
+}
+
+func TestV2BotCreateInvoice(t *testing.T) {
+	bot := withV2Bot(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/invoice", r.URL.Path)
+		assert.Equal(t, "bot-token", r.Header.Get("x-admin-token"))
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"amt_msat": 1000000}`, string(body))
+
+		json.NewEncoder(w).Encode(db.V2CreateInvoiceResponse{Bolt11: "lnbc1"})
+	})
+
+	invoice, invoiceErr := bot.CreateInvoice(1000, "Budget Invoice")
+
+	assert.Empty(t, invoiceErr.Error)
+	assert.True(t, invoice.Succcess)
+	assert.Equal(t, "lnbc1", invoice.Response.Invoice)
+}
+
+func TestV2BotLookupInvoice(t *testing.T) {
+	bot := withV2Bot(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/check_invoice", r.URL.Path)
+		json.NewEncoder(w).Encode(db.V2InvoiceResponse{Status: db.InvoicePaid})
+	})
+
+	result, invoiceErr := bot.LookupInvoice("lnbc1")
+
+	assert.Empty(t, invoiceErr.Error)
+	assert.True(t, result.Response.Settled)
+	assert.Equal(t, "lnbc1", result.Response.Payment_request)
+}
+
+func TestV2BotKeysend(t *testing.T) {
+	t.Run("Returns the bot status and tag", func(t *testing.T) {
+		bot := withV2Bot(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/pay", r.URL.Path)
+			body := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, float64(500000), body["amt_msat"])
+			assert.Equal(t, "hunter", body["dest"])
+
+			json.NewEncoder(w).Encode(db.V2SendOnionRes{Status: db.PaymentPending, Tag: "tag-1"})
+		})
+
+		res, err := bot.Keysend(500, "hunter", "route", "memo")
+
+		assert.NoError(t, err)
+		assert.Equal(t, db.PaymentPending, res.Status)
+		assert.Equal(t, "tag-1", res.Tag)
+	})
+
+	t.Run("Error status is a rejection", func(t *testing.T) {
+		bot := withV2Bot(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotAcceptable)
+		})
+
+		_, err := bot.Keysend(500, "hunter", "route", "memo")
+
+		assert.True(t, errors.Is(err, ErrRejected))
+	})
+}
+
+func TestV2BotPaymentStatusByTag(t *testing.T) {
+	bot := withV2Bot(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/sends/tag-1", r.URL.Path)
+		json.NewEncoder(w).Encode([]db.V2TagRes{{Tag: "tag-1", Status: db.PaymentComplete}})
+	})
+
+	assert.Equal(t, db.PaymentComplete, bot.PaymentStatusByTag("tag-1").Status)
+}
+
+func TestConfiguredFollowsPaymentVersion(t *testing.T) {
+	withV2Bot(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(db.V2CreateInvoiceResponse{Bolt11: "from-bot"})
+	})
+	withRelay(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(db.InvoiceResponse{Response: db.Invoice{Invoice: "from-relay"}})
+	})
+	original := config.IsV2Payment
+	t.Cleanup(func() { config.IsV2Payment = original })
+
+	backend := FromConfig(http.DefaultClient)
+
+	config.IsV2Payment = true
+	invoice, _ := backend.CreateInvoice(10, "")
+	assert.Equal(t, "from-bot", invoice.Response.Invoice)
+
+	config.IsV2Payment = false
+	invoice, _ = backend.CreateInvoice(10, "")
+	assert.Equal(t, "from-relay", invoice.Response.Invoice)
+}
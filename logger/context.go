@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/go-chi/chi"
+)
+
+type fieldsKey struct{}
+
+// Fields identify the request a log line belongs to.
+type Fields struct {
+	RequestID string `json:"request_id,omitempty"`
+	PubKey    string `json:"pubkey,omitempty"`
+	Workspace string `json:"workspace,omitempty"`
+	Route     string `json:"route,omitempty"`
+}
+
+// workspaceParams are the route parameters that always hold a workspace uuid.
+var workspaceParams = []string{"workspace_uuid", "workspaceId", "workspaceID", "workspace"}
+
+// WithFields returns a copy of ctx carrying fields. Empty values keep the
+// ones already present, so middlewares can each add what they know.
+func WithFields(ctx context.Context, fields Fields) context.Context {
+	current, _ := ctx.Value(fieldsKey{}).(Fields)
+
+	if fields.RequestID != "" {
+		current.RequestID = fields.RequestID
+	}
+	if fields.PubKey != "" {
+		current.PubKey = fields.PubKey
+	}
+	if fields.Workspace != "" {
+		current.Workspace = fields.Workspace
+	}
+	if fields.Route != "" {
+		current.Route = fields.Route
+	}
+
+	return context.WithValue(ctx, fieldsKey{}, current)
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return WithFields(ctx, Fields{RequestID: requestID})
+}
+
+func WithPubKey(ctx context.Context, pubkey string) context.Context {
+	return WithFields(ctx, Fields{PubKey: pubkey})
+}
+
+func WithWorkspace(ctx context.Context, workspaceUuid string) context.Context {
+	return WithFields(ctx, Fields{Workspace: workspaceUuid})
+}
+
+// FieldsFromContext returns the fields stored in ctx. The chi route
+// pattern and workspace parameter are resolved lazily because routing
+// completes after the outer middlewares have run.
+func FieldsFromContext(ctx context.Context) Fields {
+	if ctx == nil {
+		return Fields{}
+	}
+
+	fields, _ := ctx.Value(fieldsKey{}).(Fields)
+
+	rctx := chi.RouteContext(ctx)
+	if rctx == nil {
+		return fields
+	}
+
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		method := rctx.RouteMethod
+		if method == "" {
+			method = requestMethod(fields.Route)
+		}
+		fields.Route = method + " " + pattern
+	}
+
+	if fields.Workspace == "" {
+		for _, param := range workspaceParams {
+			if value := rctx.URLParam(param); value != "" {
+				fields.Workspace = value
+				break
+			}
+		}
+	}
+
+	return fields
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	return FieldsFromContext(ctx).RequestID
+}
+
+func requestMethod(route string) string {
+	for i, c := range route {
+		if c == ' ' {
+			return route[:i]
+		}
+	}
+	return ""
+}
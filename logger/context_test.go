@@ -0,0 +1,235 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func() context.Context
+		expected Fields
+	}{
+		{
+			name:     "Empty Context",
+			build:    context.Background,
+			expected: Fields{},
+		},
+		{
+			name: "Single Field",
+			build: func() context.Context {
+				return WithRequestID(context.Background(), "req-1")
+			},
+			expected: Fields{RequestID: "req-1"},
+		},
+		{
+			name: "Fields Accumulate",
+			build: func() context.Context {
+				ctx := WithRequestID(context.Background(), "req-1")
+				ctx = WithPubKey(ctx, "pubkey-1")
+				return WithWorkspace(ctx, "workspace-1")
+			},
+			expected: Fields{RequestID: "req-1", PubKey: "pubkey-1", Workspace: "workspace-1"},
+		},
+		{
+			name: "Empty Values Keep Existing",
+			build: func() context.Context {
+				ctx := WithFields(context.Background(), Fields{RequestID: "req-1", Route: "GET /a"})
+				return WithFields(ctx, Fields{PubKey: "pubkey-1"})
+			},
+			expected: Fields{RequestID: "req-1", PubKey: "pubkey-1", Route: "GET /a"},
+		},
+		{
+			name: "Later Values Override",
+			build: func() context.Context {
+				ctx := WithRequestID(context.Background(), "req-1")
+				return WithRequestID(ctx, "req-2")
+			},
+			expected: Fields{RequestID: "req-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FieldsFromContext(tt.build()))
+		})
+	}
+
+	t.Run("Parent Context Is Unchanged", func(t *testing.T) {
+		parent := WithRequestID(context.Background(), "parent")
+		_ = WithPubKey(parent, "child-pubkey")
+		assert.Empty(t, FieldsFromContext(parent).PubKey)
+	})
+}
+
+func TestFieldsFromChiRoute(t *testing.T) {
+	var captured Fields
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(RouteBasedUUIDMiddleware)
+	r.Route("/workspaces", func(r chi.Router) {
+		r.Get("/{workspace_uuid}/features", func(w http.ResponseWriter, r *http.Request) {
+			captured = FieldsFromContext(r.Context())
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/workspaces/ws-123/features", nil)
+	req.Header.Set(RequestIDHeader, "incoming-id")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, "incoming-id", captured.RequestID)
+	assert.Equal(t, "GET /workspaces/{workspace_uuid}/features", captured.Route)
+	assert.Equal(t, "ws-123", captured.Workspace)
+	assert.Equal(t, "incoming-id", rec.Header().Get(RequestIDHeader))
+}
+
+func TestRequestIDsAreIsolated(t *testing.T) {
+	handler := RouteBasedUUIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(RequestIDFromContext(r.Context())))
+	}))
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			assert.Equal(t, rec.Header().Get(RequestIDHeader), rec.Body.String())
+			mu.Lock()
+			seen[rec.Body.String()] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, 50)
+}
+
+func TestContextLoggerOutput(t *testing.T) {
+	originalLogLevel := config.LogLevel
+	originalLogFormat := config.LogFormat
+	defer func() {
+		config.LogLevel = originalLogLevel
+		config.LogFormat = originalLogFormat
+	}()
+	config.LogLevel = LevelInfo
+
+	ctx := WithFields(context.Background(), Fields{
+		RequestID: "req-1",
+		PubKey:    "pubkey-1",
+		Workspace: "workspace-1",
+		Route:     "GET /test",
+	})
+
+	t.Run("Text Format", func(t *testing.T) {
+		config.LogFormat = FormatText
+		var buf bytes.Buffer
+		l := (&Logger{infoLogger: log.New(&buf, "INFO: ", 0)}).WithContext(ctx)
+
+		l.Info("hello %s", "world")
+
+		output := buf.String()
+		assert.Contains(t, output, "[context_test.go:")
+		assert.Contains(t, output, "[req-1]")
+		assert.Contains(t, output, "[route=GET /test]")
+		assert.Contains(t, output, "[pubkey=pubkey-1]")
+		assert.Contains(t, output, "[workspace=workspace-1]")
+		assert.Contains(t, output, "hello world")
+	})
+
+	t.Run("JSON Format", func(t *testing.T) {
+		config.LogFormat = FormatJSON
+		var buf bytes.Buffer
+		l := (&Logger{errorLogger: log.New(&buf, "ERROR: ", log.Ldate|log.Ltime)}).WithContext(ctx)
+
+		l.Error("failed %d times", 3)
+
+		var line map[string]string
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		assert.Equal(t, "ERROR", line["level"])
+		assert.Equal(t, "failed 3 times", line["msg"])
+		assert.Equal(t, "req-1", line["request_id"])
+		assert.Equal(t, "pubkey-1", line["pubkey"])
+		assert.Equal(t, "workspace-1", line["workspace"])
+		assert.Equal(t, "GET /test", line["route"])
+		assert.True(t, strings.HasPrefix(line["caller"], "context_test.go:"))
+		assert.NotEmpty(t, line["time"])
+	})
+
+	t.Run("JSON Format Without Context", func(t *testing.T) {
+		config.LogFormat = FormatJSON
+		var buf bytes.Buffer
+		l := &Logger{infoLogger: log.New(&buf, "INFO: ", 0)}
+
+		l.Info("plain")
+
+		var line map[string]string
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		assert.Equal(t, "plain", line["msg"])
+		assert.NotContains(t, line, "request_id")
+	})
+}
+
+func TestSetLevel(t *testing.T) {
+	originalLogLevel := config.LogLevel
+	defer func() { config.LogLevel = originalLogLevel }()
+
+	tests := []struct {
+		name     string
+		level    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Upper Case", level: "WARNING", expected: LevelWarning},
+		{name: "Lower Case With Spaces", level: " error ", expected: LevelError},
+		{name: "Machine", level: "machine", expected: LevelMachine},
+		{name: "Invalid Level", level: "VERBOSE", wantErr: true},
+		{name: "Empty Level", level: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.LogLevel = LevelInfo
+			err := SetLevel(tt.level)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, LevelInfo, Level())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, Level())
+		})
+	}
+
+	t.Run("Level Applies Immediately", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := &Logger{infoLogger: log.New(&buf, "INFO: ", 0)}
+
+		assert.NoError(t, SetLevel(LevelError))
+		l.Info("hidden")
+		assert.Empty(t, buf.String())
+
+		assert.NoError(t, SetLevel(LevelInfo))
+		l.Info("visible")
+		assert.Contains(t, buf.String(), "visible")
+	})
+}
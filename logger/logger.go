@@ -1,15 +1,49 @@
 package logger
 
 import (
-	"github.com/google/uuid"
-	"github.com/stakwork/sphinx-tribes/config"
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/google/uuid"
+	"github.com/stakwork/sphinx-tribes/config"
+)
+
+const (
+	LevelMachine = "MACHINE"
+	LevelDebug   = "DEBUG"
+	LevelInfo    = "INFO"
+	LevelWarning = "WARNING"
+	LevelError   = "ERROR"
+
+	FormatText = "text"
+	FormatJSON = "json"
+
+	RequestIDHeader = "X-Request-Id"
+)
+
+// levelRank orders the levels from most to least verbose.
+var levelRank = map[string]int{
+	LevelMachine: 0,
+	LevelDebug:   1,
+	LevelInfo:    2,
+	LevelWarning: 3,
+	LevelError:   4,
+}
+
+var (
+	levelMu  sync.RWMutex
+	outputMu sync.Mutex
 )
 
 type Logger struct {
@@ -18,8 +52,7 @@ type Logger struct {
 	errorLogger   *log.Logger
 	debugLogger   *log.Logger
 	machineLogger *log.Logger
-	mu            sync.Mutex
-	requestUUID   string
+	ctx           context.Context
 }
 
 var Log = Logger{
@@ -30,34 +63,84 @@ var Log = Logger{
 	machineLogger: log.New(os.Stdout, "MACHINE: ", log.Ldate|log.Ltime),
 }
 
-func (l *Logger) SetRequestUUID(uuidString string) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.requestUUID = uuidString
+// WithContext returns a logger that tags every line with the request
+// fields carried by ctx.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	return &Logger{
+		infoLogger:    l.infoLogger,
+		warningLogger: l.warningLogger,
+		errorLogger:   l.errorLogger,
+		debugLogger:   l.debugLogger,
+		machineLogger: l.machineLogger,
+		ctx:           ctx,
+	}
 }
 
-func (l *Logger) ClearRequestUUID() {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.requestUUID = ""
+// FromContext is shorthand for Log.WithContext(ctx).
+func FromContext(ctx context.Context) *Logger {
+	return Log.WithContext(ctx)
 }
 
-func RouteBasedUUIDMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		uuid := uuid.NewString()
-		Log.SetRequestUUID(uuid)
+// Level returns the current log level.
+func Level() string {
+	levelMu.RLock()
+	defer levelMu.RUnlock()
+	return config.LogLevel
+}
+
+// SetLevel changes the log level while the server is running.
+func SetLevel(level string) error {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	if _, ok := levelRank[level]; !ok {
+		return fmt.Errorf("invalid log level: %s", level)
+	}
 
-		defer Log.ClearRequestUUID()
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	config.LogLevel = level
+	return nil
+}
+
+func enabled(level string) bool {
+	current, ok := levelRank[Level()]
+	if !ok {
+		return false
+	}
+	return current <= levelRank[level]
+}
 
-		next.ServeHTTP(w, r)
+// RouteBasedUUIDMiddleware stores a request ID and the route in the request
+// context, reusing the ID set by chi's RequestID middleware when present.
+func RouteBasedUUIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := middleware.GetReqID(r.Context())
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := WithFields(r.Context(), Fields{
+			RequestID: requestID,
+			Route:     r.Method + " " + r.URL.Path,
+		})
+
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func (l *Logger) logWithPrefix(logger *log.Logger, format string, v ...interface{}) {
-	l.mu.Lock()
+type jsonLine struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Caller  string `json:"caller"`
+	Message string `json:"msg"`
+	Fields
+}
 
-	requestUUID := l.requestUUID
-	l.mu.Unlock()
+func (l *Logger) logWithPrefix(logger *log.Logger, format string, v ...interface{}) {
+	var fields Fields
+	if l.ctx != nil {
+		fields = FieldsFromContext(l.ctx)
+	}
 
 	var file string
 	var line int
@@ -73,39 +156,72 @@ func (l *Logger) logWithPrefix(logger *log.Logger, format string, v ...interface
 	shortFile := filepath.Base(file)
 	line_str := strconv.Itoa(line)
 
-	if requestUUID == "" {
-		logger.Printf("["+shortFile+":"+line_str+"] "+format, v...)
-	} else {
-		logger.Printf("["+shortFile+":"+line_str+"] ["+requestUUID+"] "+format, v...)
+	if config.LogFormat == FormatJSON {
+		data, err := json.Marshal(jsonLine{
+			Time:    time.Now().UTC().Format(time.RFC3339Nano),
+			Level:   strings.TrimSuffix(logger.Prefix(), ": "),
+			Caller:  shortFile + ":" + line_str,
+			Message: scrub(formatMessage(format, v)),
+			Fields:  fields,
+		})
+		if err == nil {
+			outputMu.Lock()
+			logger.Writer().Write(append(data, '\n'))
+			outputMu.Unlock()
+			return
+		}
+	}
+
+	prefix := "[" + shortFile + ":" + line_str + "] "
+	if fields.RequestID != "" {
+		prefix += "[" + fields.RequestID + "] "
+	}
+	if fields.Route != "" {
+		prefix += "[route=" + fields.Route + "] "
+	}
+	if fields.PubKey != "" {
+		prefix += "[pubkey=" + fields.PubKey + "] "
 	}
+	if fields.Workspace != "" {
+		prefix += "[workspace=" + fields.Workspace + "] "
+	}
+
+	logger.Print(prefix + scrub(formatMessage(format, v)))
+}
+
+// formatMessage takes the arguments as a slice so vet keeps treating the
+// logging methods the way it did before JSON output, many existing call
+// sites pass arguments without directives.
+func formatMessage(format string, v []interface{}) string {
+	return fmt.Sprintf(format, v...)
 }
 
 func (l *Logger) Machine(format string, v ...interface{}) {
-	if config.LogLevel == "MACHINE" {
+	if enabled(LevelMachine) {
 		l.logWithPrefix(l.machineLogger, format, v...)
 	}
 }
 
 func (l *Logger) Debug(format string, v ...interface{}) {
-	if config.LogLevel == "MACHINE" || config.LogLevel == "DEBUG" {
+	if enabled(LevelDebug) {
 		l.logWithPrefix(l.debugLogger, format, v...)
 	}
 }
 
 func (l *Logger) Info(format string, v ...interface{}) {
-	if config.LogLevel == "MACHINE" || config.LogLevel == "DEBUG" || config.LogLevel == "INFO" {
+	if enabled(LevelInfo) {
 		l.logWithPrefix(l.infoLogger, format, v...)
 	}
 }
 
 func (l *Logger) Warning(format string, v ...interface{}) {
-	if config.LogLevel == "MACHINE" || config.LogLevel == "DEBUG" || config.LogLevel == "INFO" || config.LogLevel == "WARNING" {
+	if enabled(LevelWarning) {
 		l.logWithPrefix(l.warningLogger, format, v...)
 	}
 }
 
 func (l *Logger) Error(format string, v ...interface{}) {
-	if config.LogLevel == "MACHINE" || config.LogLevel == "DEBUG" || config.LogLevel == "INFO" || config.LogLevel == "WARNING" || config.LogLevel == "ERROR" {
+	if enabled(LevelError) {
 		l.logWithPrefix(l.errorLogger, format, v...)
 	}
 }
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+)
+
+// redactedText replaces a secret in a log line.
+const redactedText = "[REDACTED]"
+
+// minSecretLength keeps short values, which would match all over a log
+// line, from being redacted.
+const minSecretLength = 8
+
+var (
+	secretsMu sync.RWMutex
+	secrets   = map[string]struct{}{}
+)
+
+// Redact keeps values out of every log line from now on. Secrets are
+// registered as they are decrypted, so a secret that reaches a log line by
+// way of an error or a payload is still masked.
+func Redact(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, value := range values {
+		if len(value) >= minSecretLength {
+			secrets[value] = struct{}{}
+		}
+	}
+}
+
+func scrub(message string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for secret := range secrets {
+		if strings.Contains(message, secret) {
+			message = strings.ReplaceAll(message, secret, redactedText)
+		}
+	}
+	return message
+}
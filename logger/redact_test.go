@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	originalFormat := config.LogFormat
+	originalLevel := config.LogLevel
+	defer func() {
+		config.LogFormat = originalFormat
+		config.LogLevel = originalLevel
+	}()
+	config.LogLevel = LevelInfo
+
+	Redact("ghp_redact_test_token", "short")
+
+	t.Run("Text Format", func(t *testing.T) {
+		config.LogFormat = FormatText
+		var buf bytes.Buffer
+		l := &Logger{infoLogger: log.New(&buf, "INFO: ", 0)}
+
+		l.Info("calling with %s and %s", "ghp_redact_test_token", "short")
+
+		assert.NotContains(t, buf.String(), "ghp_redact_test_token")
+		assert.Contains(t, buf.String(), "calling with [REDACTED] and short")
+	})
+
+	t.Run("JSON Format", func(t *testing.T) {
+		config.LogFormat = FormatJSON
+		var buf bytes.Buffer
+		l := &Logger{errorLogger: log.New(&buf, "ERROR: ", 0)}
+
+		l.Error("request failed: token=%s", "ghp_redact_test_token")
+
+		var line map[string]string
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		assert.Equal(t, "request failed: token=[REDACTED]", line["msg"])
+	})
+}
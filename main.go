@@ -10,12 +10,20 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/robfig/cron"
+	"github.com/stakwork/sphinx-tribes/audit"
 	"github.com/stakwork/sphinx-tribes/auth"
 	"github.com/stakwork/sphinx-tribes/config"
 	"github.com/stakwork/sphinx-tribes/db"
 	_ "github.com/stakwork/sphinx-tribes/docs"
 	"github.com/stakwork/sphinx-tribes/handlers"
+	"github.com/stakwork/sphinx-tribes/jobs"
+	"github.com/stakwork/sphinx-tribes/metrics"
+	customMiddleware "github.com/stakwork/sphinx-tribes/middlewares"
 	"github.com/stakwork/sphinx-tribes/routes"
+	"github.com/stakwork/sphinx-tribes/sse"
+	"github.com/stakwork/sphinx-tribes/tracing"
+	"github.com/stakwork/sphinx-tribes/vault"
+	"github.com/stakwork/sphinx-tribes/webhooks"
 	"github.com/stakwork/sphinx-tribes/websocket"
 	"gopkg.in/go-playground/validator.v9"
 )
@@ -55,10 +63,28 @@ func main() {
 	config.InitConfig()
 	auth.InitJwt()
 
+	if err := vault.Init(config.SecretsMasterKeys); err != nil {
+		fmt.Printf("error loading the secrets master keys: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if err := tracing.Init(context.Background()); err != nil {
+		fmt.Printf("error starting tracing: %s", err.Error())
+	}
+
 	// validate
 	db.Validate = validator.New()
 	// Start websocket pool
+	websocket.InitBroadcaster()
+	websocket.WebsocketPool.UseOutbox(db.DB)
+	websocket.WebsocketPool.UseAccess(db.DB)
 	go websocket.WebsocketPool.Start()
+	webhooks.Init(db.DB)
+	audit.Init(db.DB)
+	jobs.Init(db.DB)
+	jobHandler := handlers.NewJobHandler(db.DB)
+	jobHandler.Register(jobs.Default)
+	jobHandler.EnqueueSecretsRewrap()
 
 	skipLoops := os.Getenv("SKIP_LOOPS")
 	if skipLoops != "true" {
@@ -66,14 +92,37 @@ func main() {
 		go handlers.ProcessGithubIssuesLoop()
 	}
 
+	initMetrics()
 	runCron()
 	run()
 }
 
+func initMetrics() {
+	metrics.RegisterGauge("websocket_clients", "Websocket sessions connected to this node.", func() float64 {
+		return float64(websocket.WebsocketPool.Size())
+	})
+	metrics.RegisterGauge("sse_clients", "Active SSE clients relaying chat events.", func() float64 {
+		return float64(sse.ClientRegistry.Count())
+	})
+	metrics.RegisterGauge("notification_queue_depth", "Notifications waiting to be sent.", func() float64 {
+		notifications, err := db.DB.GetPendingNotifications()
+		if err != nil {
+			return 0
+		}
+		return float64(len(notifications))
+	})
+}
+
 func runCron() {
 	c := cron.New()
-	c.AddFunc("@every 0h30m0s", handlers.InitV2PaymentsCron)
-	c.AddFunc("@every 0h0m30s", handlers.ProcessWaitingNotifications)
+	jobHandler := handlers.NewJobHandler(db.DB)
+	c.AddFunc("@every 0h0m5s", jobs.RunDue)
+	c.AddFunc("@every 0h30m0s", jobHandler.EnqueuePendingPaymentChecks)
+	c.AddFunc("@every 0h0m30s", jobHandler.EnqueueWaitingNotifications)
+	c.AddFunc("@every 24h0m0s", jobHandler.EnqueueFileRetention)
+	c.AddFunc("@every 1h0m0s", handlers.PruneWebsocketOutbox)
+	c.AddFunc("@every 0h0m30s", webhooks.RetryDue)
+	c.AddFunc("@every 1h0m0s", func() { customMiddleware.PruneIdempotencyKeys(db.DB) })
 	c.Start()
 }
 
@@ -91,4 +140,7 @@ func run() {
 	if err := router.Shutdown(ctx); err != nil {
 		fmt.Printf("error shutting down server: %s", err.Error())
 	}
+	if err := tracing.Shutdown(ctx); err != nil {
+		fmt.Printf("error flushing traces: %s", err.Error())
+	}
 }
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/stakwork/sphinx-tribes/config"
+)
+
+const namespace = "tribes"
+
+// Registry holds the operational metrics. It is kept apart from the
+// default registry so only what is registered here is exposed.
+var Registry = prometheus.NewRegistry()
+
+var (
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by chi route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_request_errors_total",
+		Help:      "HTTP requests answered with a 5xx status by chi route.",
+	}, []string{"method", "route"})
+
+	cronIterations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cron_iterations_total",
+		Help:      "Completed runs of a cron job.",
+	}, []string{"job"})
+
+	cronFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cron_failures_total",
+		Help:      "Runs of a cron job that hit at least one error.",
+	}, []string{"job"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestDuration,
+		requestErrors,
+		cronIterations,
+		cronFailures,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format. When
+// METRICS_TOKEN is set scrapers have to send it as a bearer token.
+func Handler() http.Handler {
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if config.MetricsToken != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(config.MetricsToken)) != 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Middleware records latency and errors for every request, labelled with
+// the chi route pattern rather than the raw path to keep cardinality low.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		requestDuration.WithLabelValues(r.Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+		if status >= http.StatusInternalServerError {
+			requestErrors.WithLabelValues(r.Method, route).Inc()
+		}
+	})
+}
+
+// CronRun counts one run of job, and a failure when failed is true.
+func CronRun(job string, failed bool) {
+	cronIterations.WithLabelValues(job).Inc()
+	if failed {
+		cronFailures.WithLabelValues(job).Inc()
+	}
+}
+
+// RegisterDBStats exposes the connection pool statistics of sqlDB.
+func RegisterDBStats(sqlDB *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(sqlDB, "tribes"))
+}
+
+// RegisterGauge exposes a gauge whose value is read from fn on every
+// scrape.
+func RegisterGauge(name string, help string, fn func() float64) error {
+	return Registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/tickets/{uuid}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Post("/tickets", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tickets/abc", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tickets/def", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/tickets", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(requestErrors.WithLabelValues(http.MethodPost, "/tickets")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(requestErrors.WithLabelValues(http.MethodGet, "/tickets/{uuid}")))
+
+	expected := `
+		# HELP tribes_http_request_errors_total HTTP requests answered with a 5xx status by chi route.
+		# TYPE tribes_http_request_errors_total counter
+		tribes_http_request_errors_total{method="GET",route="/tickets/{uuid}"} 0
+		tribes_http_request_errors_total{method="POST",route="/tickets"} 1
+	`
+	assert.NoError(t, testutil.CollectAndCompare(requestErrors, strings.NewReader(expected)))
+
+	count, err := testutil.GatherAndCount(Registry, "tribes_http_request_duration_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestCronRun(t *testing.T) {
+	CronRun("test_job", false)
+	CronRun("test_job", true)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(cronIterations.WithLabelValues("test_job")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(cronFailures.WithLabelValues("test_job")))
+}
+
+func TestRegisterGauge(t *testing.T) {
+	depth := 3
+	assert.NoError(t, RegisterGauge("test_queue_depth", "Test queue depth.", func() float64 {
+		return float64(depth)
+	}))
+	assert.Error(t, RegisterGauge("test_queue_depth", "Test queue depth.", func() float64 { return 0 }))
+
+	expected := `
+		# HELP tribes_test_queue_depth Test queue depth.
+		# TYPE tribes_test_queue_depth gauge
+		tribes_test_queue_depth 3
+	`
+	assert.NoError(t, testutil.GatherAndCompare(Registry, strings.NewReader(expected), "tribes_test_queue_depth"))
+
+	depth = 5
+	assert.NoError(t, testutil.GatherAndCompare(Registry, strings.NewReader(strings.Replace(expected, " 3\n", " 5\n", 1)), "tribes_test_queue_depth"))
+}
+
+func TestHandler(t *testing.T) {
+	originalToken := config.MetricsToken
+	defer func() { config.MetricsToken = originalToken }()
+
+	tests := []struct {
+		name           string
+		token          string
+		header         string
+		expectedStatus int
+	}{
+		{
+			name:           "No Token Configured",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Valid Token",
+			token:          "secret",
+			header:         "Bearer secret",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Missing Token",
+			token:          "secret",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Wrong Token",
+			token:          "secret",
+			header:         "Bearer nope",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.MetricsToken = tt.token
+
+			req := httptest.NewRequest(http.MethodGet, "/internal/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			Handler().ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, rr.Body.String(), "go_goroutines")
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotencyReplayedHeader is set on responses replayed from an
+	// earlier request with the same key.
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+	// IdempotencyKeyMaxAge is how long a key is remembered.
+	IdempotencyKeyMaxAge = 24 * time.Hour
+
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency makes the wrapped endpoint safe to retry. A request carrying
+// an Idempotency-Key header runs once per key, caller and scope; repeating
+// it replays the stored response, while reusing the key for a different
+// request or while the first one is still running is a conflict. Requests
+// without the header, or without an authenticated caller, are passed
+// through.
+func Idempotency(database db.Database, scope string) func(http.Handler) http.Handler {
+	return idempotency(database, scope, func(r *http.Request, body []byte) string {
+		pubKey, _ := r.Context().Value(auth.ContextKey).(string)
+		return pubKey
+	})
+}
+
+// WorkspaceIdempotency is Idempotency for unauthenticated endpoints whose
+// JSON body names a workspace. Keys are scoped to that workspace and the
+// body's sender pubkey instead of an authenticated caller, so anonymous
+// callers of other workspaces never share them.
+func WorkspaceIdempotency(database db.Database, scope string) func(http.Handler) http.Handler {
+	return idempotency(database, scope, func(r *http.Request, body []byte) string {
+		caller := struct {
+			OrgUuid       string `json:"org_uuid"`
+			WorkspaceUuid string `json:"workspace_uuid"`
+			SenderPubKey  string `json:"sender_pubkey"`
+		}{}
+		if err := json.Unmarshal(body, &caller); err != nil {
+			return ""
+		}
+		if caller.WorkspaceUuid == "" {
+			caller.WorkspaceUuid = caller.OrgUuid
+		}
+		if caller.WorkspaceUuid == "" {
+			return ""
+		}
+		return "workspace:" + caller.WorkspaceUuid + ":" + caller.SenderPubKey
+	})
+}
+
+// idempotency runs requests once per key, scope and the caller identified
+// by callerOf; requests it can't identify are passed through.
+func idempotency(database db.Database, scope string, callerOf func(r *http.Request, body []byte) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+			if idempotencyKey == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(idempotencyKey) > maxIdempotencyKeyLength {
+				writeIdempotencyError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			var body []byte
+			if r.Body != nil {
+				var err error
+				body, err = io.ReadAll(r.Body)
+				r.Body.Close()
+				if err != nil {
+					writeIdempotencyError(w, http.StatusBadRequest, "Could not read request body")
+					return
+				}
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			caller := callerOf(r, body)
+			if caller == "" || len(caller) > maxIdempotencyKeyLength {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			hash := requestHash(r, body)
+			key, claimed, err := database.ClaimIdempotencyKey(db.IdempotencyKey{
+				Key:         idempotencyKey,
+				Scope:       scope,
+				PubKey:      caller,
+				RequestHash: hash,
+			})
+			if err != nil {
+				logger.Log.Error("[idempotency] could not claim key for %s: %v", scope, err)
+				writeIdempotencyError(w, http.StatusInternalServerError, "Could not process Idempotency-Key")
+				return
+			}
+
+			if !claimed {
+				switch {
+				case key.RequestHash != hash:
+					writeIdempotencyError(w, http.StatusConflict, "Idempotency-Key was already used for a different request")
+				case !key.Completed:
+					writeIdempotencyError(w, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+				default:
+					if key.ContentType != "" {
+						w.Header().Set("Content-Type", key.ContentType)
+					}
+					w.Header().Set(IdempotencyReplayedHeader, "true")
+					w.WriteHeader(key.ResponseStatus)
+					w.Write([]byte(key.ResponseBody))
+				}
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				if p := recover(); p != nil {
+					if err := database.ReleaseIdempotencyKey(key.ID); err != nil {
+						logger.Log.Error("[idempotency] could not release key for %s: %v", scope, err)
+					}
+					panic(p)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+
+			key.ResponseStatus = recorder.statusCode()
+			key.ResponseBody = recorder.body.String()
+			key.ContentType = w.Header().Get("Content-Type")
+			if err := database.CompleteIdempotencyKey(key); err != nil {
+				logger.Log.Error("[idempotency] could not store response for %s: %v", scope, err)
+			}
+		})
+	}
+}
+
+// PruneIdempotencyKeys forgets keys older than IdempotencyKeyMaxAge.
+func PruneIdempotencyKeys(database db.Database) {
+	removed, err := database.DeleteOldIdempotencyKeys(IdempotencyKeyMaxAge)
+	if err != nil {
+		logger.Log.Error("Error pruning idempotency keys: %v", err)
+		return
+	}
+	logger.Log.Info("Removed %d idempotency keys older than %v", removed, IdempotencyKeyMaxAge)
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func writeIdempotencyError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
+
+func (rr *responseRecorder) statusCode() int {
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+	return rr.status
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stakwork/sphinx-tribes/auth"
+	"github.com/stakwork/sphinx-tribes/db"
+	dbmocks "github.com/stakwork/sphinx-tribes/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIdempotency(t *testing.T) {
+	newRequest := func(key string, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/gobounties/pay/1", bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		return req.WithContext(context.WithValue(req.Context(), auth.ContextKey, "owner"))
+	}
+
+	newHandler := func(calls *int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls++
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{"body": string(body)})
+		})
+	}
+
+	t.Run("Passes requests without a key through", func(t *testing.T) {
+		mockDb := dbmocks.NewDatabase(t)
+		calls := 0
+		rr := httptest.NewRecorder()
+
+		Idempotency(mockDb, "bounty_payment")(newHandler(&calls)).ServeHTTP(rr, newRequest("", `{}`))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Runs and stores the first request", func(t *testing.T) {
+		mockDb := dbmocks.NewDatabase(t)
+		var claimed db.IdempotencyKey
+		mockDb.On("ClaimIdempotencyKey", mock.MatchedBy(func(key db.IdempotencyKey) bool {
+			return key.Key == "key-1" && key.Scope == "bounty_payment" && key.PubKey == "owner" && key.RequestHash != ""
+		})).Run(func(args mock.Arguments) {
+			claimed = args.Get(0).(db.IdempotencyKey)
+			claimed.ID = 7
+		}).Return(func(key db.IdempotencyKey) db.IdempotencyKey { return claimed }, true, nil)
+		mockDb.On("CompleteIdempotencyKey", mock.MatchedBy(func(key db.IdempotencyKey) bool {
+			return key.ID == 7 && key.ResponseStatus == http.StatusCreated &&
+				key.ContentType == "application/json" && key.ResponseBody == "{\"body\":\"{\\\"amount\\\":10}\"}\n"
+		})).Return(nil)
+		calls := 0
+		rr := httptest.NewRecorder()
+
+		Idempotency(mockDb, "bounty_payment")(newHandler(&calls)).ServeHTTP(rr, newRequest("key-1", `{"amount":10}`))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 1, calls)
+		assert.Contains(t, rr.Body.String(), "amount")
+	})
+
+	t.Run("Replays the stored response", func(t *testing.T) {
+		mockDb := dbmocks.NewDatabase(t)
+		mockDb.On("ClaimIdempotencyKey", mock.Anything).Return(func(key db.IdempotencyKey) db.IdempotencyKey {
+			key.Completed = true
+			key.ResponseStatus = http.StatusOK
+			key.ResponseBody = `{"status":"paid"}`
+			key.ContentType = "application/json"
+			return key
+		}, false, nil)
+		calls := 0
+		rr := httptest.NewRecorder()
+
+		Idempotency(mockDb, "bounty_payment")(newHandler(&calls)).ServeHTTP(rr, newRequest("key-1", `{"amount":10}`))
+
+		assert.Equal(t, 0, calls)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `{"status":"paid"}`, rr.Body.String())
+		assert.Equal(t, "true", rr.Header().Get(IdempotencyReplayedHeader))
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("Conflicts when the payload differs", func(t *testing.T) {
+		mockDb := dbmocks.NewDatabase(t)
+		mockDb.On("ClaimIdempotencyKey", mock.Anything).Return(db.IdempotencyKey{
+			ID: 7, Completed: true, RequestHash: "other", ResponseStatus: http.StatusOK,
+		}, false, nil)
+		calls := 0
+		rr := httptest.NewRecorder()
+
+		Idempotency(mockDb, "bounty_payment")(newHandler(&calls)).ServeHTTP(rr, newRequest("key-1", `{"amount":20}`))
+
+		assert.Equal(t, 0, calls)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Conflicts while the first request is running", func(t *testing.T) {
+		mockDb := dbmocks.NewDatabase(t)
+		mockDb.On("ClaimIdempotencyKey", mock.Anything).Return(func(key db.IdempotencyKey) db.IdempotencyKey {
+			return key
+		}, false, nil)
+		calls := 0
+		rr := httptest.NewRecorder()
+
+		Idempotency(mockDb, "bounty_payment")(newHandler(&calls)).ServeHTTP(rr, newRequest("key-1", `{"amount":10}`))
+
+		assert.Equal(t, 0, calls)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("Releases the key when the handler panics", func(t *testing.T) {
+		mockDb := dbmocks.NewDatabase(t)
+		mockDb.On("ClaimIdempotencyKey", mock.Anything).Return(db.IdempotencyKey{ID: 9}, true, nil)
+		mockDb.On("ReleaseIdempotencyKey", uint(9)).Return(nil)
+		panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+		assert.Panics(t, func() {
+			Idempotency(mockDb, "bounty_payment")(panicking).ServeHTTP(httptest.NewRecorder(), newRequest("key-1", `{}`))
+		})
+	})
+
+	t.Run("Fails when the key cannot be claimed", func(t *testing.T) {
+		mockDb := dbmocks.NewDatabase(t)
+		mockDb.On("ClaimIdempotencyKey", mock.Anything).Return(db.IdempotencyKey{}, false, errors.New("db down"))
+		calls := 0
+		rr := httptest.NewRecorder()
+
+		Idempotency(mockDb, "bounty_payment")(newHandler(&calls)).ServeHTTP(rr, newRequest("key-1", `{}`))
+
+		assert.Equal(t, 0, calls)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("Passes anonymous requests through", func(t *testing.T) {
+		mockDb := dbmocks.NewDatabase(t)
+		calls := 0
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/gobounties/pay/1", bytes.NewBufferString(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+
+		Idempotency(mockDb, "bounty_payment")(newHandler(&calls)).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Scopes anonymous keys to the workspace in the body", func(t *testing.T) {
+		mockDb := dbmocks.NewDatabase(t)
+		mockDb.On("ClaimIdempotencyKey", mock.MatchedBy(func(key db.IdempotencyKey) bool {
+			return key.Scope == "budget_invoice" && key.PubKey == "workspace:ws-1:sender"
+		})).Return(db.IdempotencyKey{ID: 3}, true, nil)
+		mockDb.On("CompleteIdempotencyKey", mock.Anything).Return(nil)
+		calls := 0
+
+		for _, body := range []string{`{"workspace_uuid":"ws-1","sender_pubkey":"sender"}`, `{"amount":10}`} {
+			req := httptest.NewRequest(http.MethodPost, "/budgetinvoices", bytes.NewBufferString(body))
+			req.Header.Set(IdempotencyKeyHeader, "key-1")
+			WorkspaceIdempotency(mockDb, "budget_invoice")(newHandler(&calls)).ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		assert.Equal(t, 2, calls, "a body without a workspace is passed through")
+		mockDb.AssertNumberOfCalls(t, "ClaimIdempotencyKey", 1)
+	})
+}
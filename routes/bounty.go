@@ -8,6 +8,7 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/stakwork/sphinx-tribes/db"
 	"github.com/stakwork/sphinx-tribes/handlers"
+	customMiddleware "github.com/stakwork/sphinx-tribes/middlewares"
 )
 
 func BountyRoutes() chi.Router {
@@ -39,7 +40,7 @@ func BountyRoutes() chi.Router {
 		r.Get("/stake/bounty/{bountyId}", bountyHandler.GetBountyStakesByBountyID)
 		r.Get("/stake/{id}", bountyHandler.GetBountyStakeByID)
 		r.Get("/stake/hunter/{hunterPubKey}", bountyHandler.GetBountyStakesByHunterPubKey)
-		r.Post("/process_stake/{bountyId}", tribeHandlers.ProcessStake)
+		r.With(customMiddleware.Idempotency(db.DB, "stake")).Post("/process_stake/{bountyId}", tribeHandlers.ProcessStake)
 	})
 	r.Group(func(r chi.Router) {
 		r.Use(auth.CombinedAuthContext)
@@ -48,8 +49,11 @@ func BountyRoutes() chi.Router {
 		r.Delete("/featured/delete/{bountyId}", bountyHandler.DeleteFeaturedBounty)
 
 		r.Get("/bounty-cards", bountyHandler.GetBountyCards)
-		r.Post("/budget/withdraw", bountyHandler.BountyBudgetWithdraw)
-		r.Post("/pay/{id}", bountyHandler.MakeBountyPayment)
+		r.With(customMiddleware.Idempotency(db.DB, "budget_withdraw")).Post("/budget/withdraw", bountyHandler.BountyBudgetWithdraw)
+		r.With(customMiddleware.Idempotency(db.DB, "bounty_batch_payment")).Post("/pay/batch", bountyHandler.MakeBatchBountyPayment)
+		r.Get("/pay/batch/{uuid}", bountyHandler.GetBatchPayout)
+		r.Post("/pay/batch/{uuid}/items/{item_id}/resolve", bountyHandler.ResolveBatchPayment)
+		r.With(customMiddleware.Idempotency(db.DB, "bounty_payment")).Post("/pay/{id}", bountyHandler.MakeBountyPayment)
 		r.Get("/payment/status/{id}", bountyHandler.GetBountyPaymentStatus)
 		r.Get("/payment/{bountyId}", handlers.GetPaymentByBountyId)
 		r.Put("/payment/status/{id}", bountyHandler.UpdateBountyPaymentStatus)
@@ -60,6 +64,9 @@ func BountyRoutes() chi.Router {
 		r.Patch("/{id}/proofs/{proofId}/status", bountyHandler.UpdateProofStatus)
 
 		r.Post("/", bountyHandler.CreateOrEditBounty)
+		r.Put("/{id}/assignees", bountyHandler.SetBountyAssignees)
+		r.Get("/{id}/milestones", bountyHandler.GetBountyMilestones)
+		r.Put("/{id}/milestones", bountyHandler.SetBountyMilestones)
 		r.Delete("/assignee", bountyHandler.DeleteBountyAssignee)
 		r.Delete("/{pubkey}/{created}", bountyHandler.DeleteBounty)
 		r.Post("/paymentstatus/{created}", handlers.UpdatePaymentStatus)
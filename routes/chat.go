@@ -16,6 +16,7 @@ func ChatRoutes() chi.Router {
 
 	r.Post("/response", chatHandler.ProcessChatResponse)
 	r.Post("/{chat_id}/update", chatHandler.HandleChatWebhook)
+	r.Get("/file/download", chatHandler.DownloadFile)
 
 	r.Group(func(r chi.Router) {
 		r.Use(auth.CombinedAuthContext)
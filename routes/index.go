@@ -28,7 +28,9 @@ import (
 	_ "github.com/stakwork/sphinx-tribes/docs"
 	"github.com/stakwork/sphinx-tribes/handlers"
 	"github.com/stakwork/sphinx-tribes/logger"
+	"github.com/stakwork/sphinx-tribes/metrics"
 	customMiddleware "github.com/stakwork/sphinx-tribes/middlewares"
+	"github.com/stakwork/sphinx-tribes/tracing"
 	"github.com/stakwork/sphinx-tribes/utils"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
@@ -41,6 +43,7 @@ func NewRouter() *http.Server {
 	channelHandler := handlers.NewChannelHandler(db.DB)
 	botHandler := handlers.NewBotHandler(db.DB)
 	bHandler := handlers.NewBountyHandler(http.DefaultClient, db.DB)
+	jobHandler := handlers.NewJobHandler(db.DB)
 
 	r.Mount("/tribes", TribeRoutes())
 	r.Mount("/bots", BotsRoutes())
@@ -52,6 +55,7 @@ func NewRouter() *http.Server {
 	r.Mount("/gobounties", BountyRoutes())
 	r.Mount("/workspaces", WorkspaceRoutes())
 	r.Mount("/metrics", MetricsRoutes())
+	r.Handle("/internal/metrics", metrics.Handler())
 	r.Mount("/features", FeatureRoutes())
 	r.Mount("/workflows", WorkflowRoutes())
 	r.Mount("/bounties/ticket", TicketRoutes())
@@ -108,12 +112,20 @@ func NewRouter() *http.Server {
 		r.Get("/admin/auth", authHandler.GetIsAdmin)
 	})
 
+	r.Group(func(r chi.Router) {
+		r.Use(auth.PubKeyContextSuperAdmin)
+		r.Get("/admin/log-level", handlers.GetLogLevel)
+		r.Put("/admin/log-level", handlers.SetLogLevel)
+		r.Get("/admin/jobs/stuck", jobHandler.GetStuckJobs)
+		r.Post("/admin/jobs/{id}/requeue", jobHandler.RequeueJob)
+	})
+
 	r.Group(func(r chi.Router) {
 		r.Get("/lnauth_login", handlers.ReceiveLnAuthData)
 		r.Get("/lnauth", handlers.GetLnurlAuth)
 		r.Get("/refresh_jwt", authHandler.RefreshToken)
-		r.Post("/invoices", handlers.GenerateInvoice)
-		r.Post("/budgetinvoices", tribeHandlers.GenerateBudgetInvoice)
+		r.Post("/invoices", tribeHandlers.GenerateInvoice)
+		r.With(customMiddleware.WorkspaceIdempotency(db.DB, "budget_invoice")).Post("/budgetinvoices", tribeHandlers.GenerateBudgetInvoice)
 	})
 
 	PORT := os.Getenv("PORT")
@@ -332,6 +344,8 @@ func internalServerErrorHandler(next http.Handler) http.Handler {
 func initChi() *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
+	r.Use(tracing.Middleware)
+	r.Use(metrics.Middleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(logger.RouteBasedUUIDMiddleware)
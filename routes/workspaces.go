@@ -1,15 +1,26 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi"
 	"github.com/stakwork/sphinx-tribes/auth"
 	"github.com/stakwork/sphinx-tribes/db"
 	"github.com/stakwork/sphinx-tribes/handlers"
+	customMiddleware "github.com/stakwork/sphinx-tribes/middlewares"
 )
 
 func WorkspaceRoutes() chi.Router {
 	r := chi.NewRouter()
 	workspaceHandlers := handlers.NewWorkspaceHandler(db.DB)
+	webhookHandler := handlers.NewWebhookHandler(db.DB)
+	searchHandler := handlers.NewSearchHandler(db.DB)
+	auditHandler := handlers.NewAuditHandler(db.DB)
+	ledgerHandler := handlers.NewLedgerHandler(db.DB)
+	paymentPolicyHandler := handlers.NewPaymentPolicyHandler(db.DB)
+	assetBudgetHandler := handlers.NewAssetBudgetHandler(db.DB)
+	workflowHandler := handlers.NewWorkspaceWorkflowHandler(http.DefaultClient, db.DB)
+	fileRetentionHandler := handlers.NewFileRetentionHandler(db.DB)
 	r.Group(func(r chi.Router) {
 		r.Get("/", handlers.GetWorkspaces)
 		r.Get("/count", handlers.GetWorkspacesCount)
@@ -44,7 +55,7 @@ func WorkspaceRoutes() chi.Router {
 		r.Post("/mission", workspaceHandlers.UpdateWorkspace)
 		r.Post("/tactics", workspaceHandlers.UpdateWorkspace)
 		r.Post("/schematicurl", workspaceHandlers.UpdateWorkspace)
-		r.Put("/{workspace_uuid}/payments", handlers.UpdateWorkspacePendingPayments)
+		r.Put("/{workspace_uuid}/payments", workspaceHandlers.UpdateWorkspacePendingPayments)
 
 		r.Post("/repositories", workspaceHandlers.CreateOrEditWorkspaceRepository)
 		r.Get("/repositories/{uuid}", workspaceHandlers.GetWorkspaceRepositorByWorkspaceUuid)
@@ -63,6 +74,30 @@ func WorkspaceRoutes() chi.Router {
 		r.Get("/codegraph/{uuid}", workspaceHandlers.GetWorkspaceCodeGraphByUUID)
 		r.Get("/{workspace_uuid}/codegraph", workspaceHandlers.GetCodeGraphByWorkspaceUuid)
 		r.Delete("/{workspace_uuid}/codegraph/{uuid}", workspaceHandlers.DeleteWorkspaceCodeGraph)
+
+		r.Post("/{workspace_uuid}/webhooks", webhookHandler.CreateWebhook)
+		r.Get("/{workspace_uuid}/webhooks", webhookHandler.GetWebhooks)
+		r.Put("/{workspace_uuid}/webhooks/{uuid}", webhookHandler.UpdateWebhook)
+		r.Delete("/{workspace_uuid}/webhooks/{uuid}", webhookHandler.DeleteWebhook)
+		r.Get("/{workspace_uuid}/webhooks/{uuid}/deliveries", webhookHandler.GetWebhookDeliveries)
+
+		r.Get("/{workspace_uuid}/search", searchHandler.SearchWorkspace)
+		r.Get("/{workspace_uuid}/audit", auditHandler.GetAuditLogs)
+		r.Get("/{workspace_uuid}/ledger", ledgerHandler.GetLedgerBalances)
+		r.Get("/{workspace_uuid}/ledger/reconcile", ledgerHandler.ReconcileLedger)
+		r.Get("/{workspace_uuid}/payment-policy", paymentPolicyHandler.GetPaymentPolicy)
+		r.Put("/{workspace_uuid}/payment-policy", paymentPolicyHandler.UpdatePaymentPolicy)
+		r.Get("/{workspace_uuid}/spend-limits", paymentPolicyHandler.GetSpendLimits)
+		r.Put("/{workspace_uuid}/spend-limits", paymentPolicyHandler.UpdateSpendLimits)
+		r.With(customMiddleware.Idempotency(db.DB, "payment_retry")).Post("/{workspace_uuid}/payments/{payment_id}/retry", paymentPolicyHandler.RetryPayment)
+		r.Get("/{workspace_uuid}/budget/assets", assetBudgetHandler.GetAssetBudgets)
+		r.With(customMiddleware.Idempotency(db.DB, "asset_budget_deposit")).Post("/{workspace_uuid}/budget/assets/deposit", assetBudgetHandler.DepositAssetBudget)
+		r.Get("/{workspace_uuid}/workflows", workflowHandler.GetWorkspaceWorkflows)
+		r.Put("/{workspace_uuid}/workflows", workflowHandler.UpdateWorkspaceWorkflows)
+		r.Post("/{workspace_uuid}/workflows/{kind}/runs/{run_id}/cancel", workflowHandler.CancelWorkflowRun)
+		r.Get("/{workspace_uuid}/files/retention", fileRetentionHandler.GetFileRetention)
+		r.Put("/{workspace_uuid}/files/retention", fileRetentionHandler.UpdateFileRetention)
+		r.Get("/{workspace_uuid}/files/usage", fileRetentionHandler.GetStorageUsage)
 	})
 	return r
 }
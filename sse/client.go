@@ -265,6 +265,13 @@ func (r *Registry) HasClient(sseURL, chatID string) bool {
 	return exists
 }
 
+func (r *Registry) Count() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return len(r.clients)
+}
+
 func (r *Registry) StopAllClients() int {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
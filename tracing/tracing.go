@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+const (
+	TracerName = "github.com/stakwork/sphinx-tribes"
+
+	// TraceparentParam carries the trace context on webhook URLs handed to
+	// external services, which call back without our headers.
+	TraceparentParam = "traceparent"
+	tracestateParam  = "tracestate"
+)
+
+var (
+	mu            sync.Mutex
+	provider      *sdktrace.TracerProvider
+	baseTransport = http.DefaultTransport
+)
+
+// Init sets up the global tracer provider. Spans are exported over OTLP
+// when OTEL_EXPORTER_OTLP_ENDPOINT is set, otherwise tracing stays a no-op.
+func Init(ctx context.Context) error {
+	if config.OtelExporterEndpoint == "" {
+		logger.Log.Info("Tracing disabled, no OTLP endpoint configured")
+		return nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(config.OtelExporterEndpoint))
+	if err != nil {
+		return err
+	}
+
+	use(sdktrace.WithBatcher(exporter))
+	logger.Log.Info("Tracing exporting to %s", config.OtelExporterEndpoint)
+	return nil
+}
+
+// InitTest records spans in memory so tests can assert on them.
+func InitTest() *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	use(sdktrace.WithSyncer(exporter))
+	return exporter
+}
+
+func use(exporter sdktrace.TracerProviderOption) {
+	serviceName := config.OtelServiceName
+	if serviceName == "" {
+		serviceName = "sphinx-tribes"
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		exporter,
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+
+	mu.Lock()
+	previous := provider
+	provider = tp
+	mu.Unlock()
+
+	if previous != nil {
+		previous.Shutdown(context.Background())
+	}
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	// every client without its own transport, including http.DefaultClient
+	// passed to the handlers, now creates client spans and propagates them
+	http.DefaultTransport = Transport(baseTransport)
+}
+
+// Shutdown flushes any spans still queued for export.
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	tp := provider
+	mu.Unlock()
+
+	if tp == nil {
+		return nil
+	}
+	return tp.Shutdown(ctx)
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// Transport wraps base so outbound requests are traced and carry the
+// caller's trace context.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
+
+// InjectURL adds the trace context of ctx to rawURL so a webhook callback
+// continues the same trace. The URL is returned unchanged when ctx holds
+// no span.
+func InjectURL(ctx context.Context, rawURL string) string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	query := parsed.Query()
+	for _, key := range []string{TraceparentParam, tracestateParam} {
+		if value := carrier.Get(key); value != "" {
+			query.Set(key, value)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// extract returns the trace context sent with r, read from the headers or,
+// for webhook callbacks, from the query string.
+func extract(r *http.Request) context.Context {
+	propagator := otel.GetTextMapPropagator()
+	ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+
+	query := r.URL.Query()
+	if query.Get(TraceparentParam) == "" {
+		return ctx
+	}
+
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{
+		TraceparentParam: query.Get(TraceparentParam),
+		tracestateParam:  query.Get(tracestateParam),
+	})
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	exporter := InitTest()
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/tickets/{uuid}", func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, trace.SpanContextFromContext(r.Context()).IsValid())
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Post("/tickets", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	t.Run("Names span after route pattern", func(t *testing.T) {
+		exporter.Reset()
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/tickets/abc", nil))
+
+		spans := exporter.GetSpans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "GET /tickets/{uuid}", spans[0].Name)
+		assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind)
+		assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	})
+
+	t.Run("Marks server errors", func(t *testing.T) {
+		exporter.Reset()
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/tickets", nil))
+
+		spans := exporter.GetSpans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+	})
+
+	t.Run("Continues trace from callback URL", func(t *testing.T) {
+		exporter.Reset()
+
+		ctx, parent := Tracer().Start(context.Background(), "send to stakwork")
+		callback := InjectURL(ctx, "http://localhost/tickets/abc?source=stakwork")
+		parent.End()
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, callback, nil))
+
+		spans := exporter.GetSpans()
+		assert.Len(t, spans, 2)
+		assert.Equal(t, parent.SpanContext().TraceID(), spans[1].SpanContext.TraceID())
+		assert.Equal(t, parent.SpanContext().SpanID(), spans[1].Parent.SpanID())
+	})
+}
+
+func TestInjectURL(t *testing.T) {
+	InitTest()
+
+	t.Run("Without span", func(t *testing.T) {
+		assert.Equal(t, "http://localhost/review", InjectURL(context.Background(), "http://localhost/review"))
+	})
+
+	t.Run("With span", func(t *testing.T) {
+		ctx, span := Tracer().Start(context.Background(), "test")
+		defer span.End()
+
+		injected, err := url.Parse(InjectURL(ctx, "http://localhost/review?id=1"))
+		assert.NoError(t, err)
+		assert.Equal(t, "1", injected.Query().Get("id"))
+		assert.Contains(t, injected.Query().Get(TraceparentParam), span.SpanContext().TraceID().String())
+	})
+}
+
+func TestTransport(t *testing.T) {
+	exporter := InitTest()
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(TraceparentParam)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx, span := Tracer().Start(context.Background(), "outbound")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	span.End()
+
+	assert.Contains(t, received, span.SpanContext().TraceID().String())
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind)
+}
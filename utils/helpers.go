@@ -58,20 +58,24 @@ func GetInvoiceAmount(paymentRequest string) uint {
 }
 
 func GetInvoiceExpired(paymentRequest string) bool {
-	decodedInvoice, err := decodepay.Decodepay(paymentRequest)
+	expiry, err := GetInvoiceExpiry(paymentRequest)
 	if err != nil {
 		logger.Log.Error("Could not Decode Invoice: %v", err)
 		return false
 	}
 
-	timeInUnix := time.Now().Unix()
-	expiryDate := decodedInvoice.CreatedAt + decodedInvoice.Expiry
+	return time.Now().After(expiry)
+}
 
-	if timeInUnix > int64(expiryDate) {
-		return true
-	} else {
-		return false
+// GetInvoiceExpiry returns the time after which the invoice can no longer
+// be paid.
+func GetInvoiceExpiry(paymentRequest string) (time.Time, error) {
+	decodedInvoice, err := decodepay.Decodepay(paymentRequest)
+	if err != nil {
+		return time.Time{}, err
 	}
+
+	return time.Unix(int64(decodedInvoice.CreatedAt+decodedInvoice.Expiry), 0), nil
 }
 
 func ConvertTimeToTimestamp(date string) int {
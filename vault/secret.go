@@ -0,0 +1,80 @@
+package vault
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+// Secret is a column holding a secret. It is sealed with the default vault
+// as it is written, it never shows in JSON, in logs or when printed, and it
+// only gives up its plaintext through Reveal.
+//
+// A Secret holds plaintext between the request that sets it and its save,
+// and for rows saved before the vault until they are rewrapped.
+type Secret string
+
+// Reveal decrypts the secret for a single use, the plaintext is kept out of
+// the logs from then on.
+func (s Secret) Reveal() (string, error) {
+	value := string(s)
+	if !IsSealed(value) {
+		logger.Redact(value)
+		return value, nil
+	}
+	if Default == nil {
+		return "", ErrNoMasterKey
+	}
+	plaintext, err := Default.Open(value)
+	if err != nil {
+		return "", err
+	}
+	logger.Redact(plaintext)
+	return plaintext, nil
+}
+
+// IsRedacted reports whether s is the placeholder a client got back for a
+// secret, which means keep the secret as it is.
+func (s Secret) IsRedacted() bool {
+	return s == Redacted
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("vault.Secret(%q)", s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Value seals the secret unless it is sealed already.
+func (s Secret) Value() (driver.Value, error) {
+	value := string(s)
+	if value == "" || IsSealed(value) || Default == nil {
+		return value, nil
+	}
+	return Default.Seal(value)
+}
+
+func (s *Secret) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = ""
+	case string:
+		*s = Secret(v)
+	case []byte:
+		*s = Secret(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a secret", src)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+// Package vault keeps secrets encrypted at rest with envelope encryption:
+// every secret is sealed with its own random data key, and the data key is
+// wrapped with a master key. Rotating the master key only rewraps data
+// keys, the secrets themselves are not encrypted again.
+//
+// Master keys come from SECRETS_MASTER_KEYS as comma separated id:key
+// pairs, each key 32 bytes in base64. The first key seals, the others are
+// only kept to open what they sealed until it is rewrapped. To rotate, put
+// a new key first and restart: the old key can go once every secret was
+// rewrapped.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Redacted stands in for a secret wherever it would be shown.
+	Redacted = "[REDACTED]"
+
+	sealedPrefix = "vault:v1:"
+	keySize      = 32
+)
+
+var (
+	ErrNoMasterKey  = errors.New("no secrets master key configured")
+	ErrUnknownKey   = errors.New("secret sealed with an unknown master key")
+	ErrInvalidValue = errors.New("invalid sealed secret")
+)
+
+// Default is the vault secrets are sealed with. Without one secrets are
+// stored as they are given, the way they were before the vault.
+var Default *Vault
+
+type Vault struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+// New makes a vault sealing with the first of keys, by id. Every key must
+// be 32 bytes.
+func New(ids []string, keys [][]byte) (*Vault, error) {
+	if len(ids) == 0 || len(ids) != len(keys) {
+		return nil, ErrNoMasterKey
+	}
+	v := &Vault{active: ids[0], keys: map[string]cipher.AEAD{}}
+	for i, id := range ids {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid master key id %q", id)
+		}
+		if _, ok := v.keys[id]; ok {
+			return nil, fmt.Errorf("master key %q is listed twice", id)
+		}
+		aead, err := newAEAD(keys[i])
+		if err != nil {
+			return nil, fmt.Errorf("master key %q: %w", id, err)
+		}
+		v.keys[id] = aead
+	}
+	return v, nil
+}
+
+// Parse makes a vault from SECRETS_MASTER_KEYS, nil when it is empty.
+func Parse(spec string) (*Vault, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	ids := []string{}
+	keys := [][]byte{}
+	for _, pair := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("master key %q is not id:key", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q is not base64: %w", id, err)
+		}
+		ids = append(ids, id)
+		keys = append(keys, key)
+	}
+	return New(ids, keys)
+}
+
+// Init sets the default vault from SECRETS_MASTER_KEYS.
+func Init(spec string) error {
+	v, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	Default = v
+	return nil
+}
+
+// ActiveKey is the id of the master key new secrets are sealed with.
+func (v *Vault) ActiveKey() string {
+	return v.active
+}
+
+// Seal encrypts plaintext with a new data key wrapped by the active master
+// key.
+func (v *Vault) Seal(plaintext string) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	wrapped, err := seal(v.keys[v.active], dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(data, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + v.active + ":" + wrapped + ":" + sealed, nil
+}
+
+// Open decrypts a sealed secret.
+func (v *Vault) Open(value string) (string, error) {
+	keyID, wrapped, sealed, err := split(value)
+	if err != nil {
+		return "", err
+	}
+	master, ok := v.keys[keyID]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+
+	dataKey, err := open(master, wrapped)
+	if err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", ErrInvalidValue
+	}
+	plaintext, err := open(data, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap wraps the data key of a secret with the active master key, and
+// seals a secret stored before the vault. It reports whether value changed.
+func (v *Vault) Rewrap(value string) (string, bool, error) {
+	if value == "" {
+		return value, false, nil
+	}
+	if !IsSealed(value) {
+		sealed, err := v.Seal(value)
+		return sealed, err == nil, err
+	}
+
+	keyID, wrapped, sealed, err := split(value)
+	if err != nil {
+		return value, false, err
+	}
+	if keyID == v.active {
+		return value, false, nil
+	}
+	master, ok := v.keys[keyID]
+	if !ok {
+		return value, false, ErrUnknownKey
+	}
+	dataKey, err := open(master, wrapped)
+	if err != nil {
+		return value, false, err
+	}
+	rewrapped, err := seal(v.keys[v.active], dataKey)
+	if err != nil {
+		return value, false, err
+	}
+	return sealedPrefix + v.active + ":" + rewrapped + ":" + sealed, true, nil
+}
+
+// IsSealed reports whether value is a sealed secret rather than one stored
+// before the vault.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+func split(value string) (string, string, string, error) {
+	if !IsSealed(value) {
+		return "", "", "", ErrInvalidValue
+	}
+	parts := strings.Split(strings.TrimPrefix(value, sealedPrefix), ":")
+	if len(parts) != 3 {
+		return "", "", "", ErrInvalidValue
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes", keySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts with a random nonce, prepended to the ciphertext.
+func seal(aead cipher.AEAD, plaintext []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+func open(aead cipher.AEAD, encoded string) ([]byte, error) {
+	ciphertext, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(ciphertext) < aead.NonceSize() {
+		return nil, ErrInvalidValue
+	}
+	nonce := ciphertext[:aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, ciphertext[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrInvalidValue
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,161 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keySize)
+}
+
+func testVault(t *testing.T, ids ...string) *Vault {
+	keys := make([][]byte, len(ids))
+	for i := range ids {
+		keys[i] = testKey(byte(i + 1))
+	}
+	v, err := New(ids, keys)
+	assert.NoError(t, err)
+	return v
+}
+
+func TestParse(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+
+	v, err := Parse("2026:" + k1 + ", 2025:" + k2)
+	assert.NoError(t, err)
+	assert.Equal(t, "2026", v.ActiveKey())
+
+	v, err = Parse(" ")
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	_, err = Parse("2026")
+	assert.Error(t, err)
+	_, err = Parse("2026:not base64")
+	assert.Error(t, err)
+	_, err = Parse("2026:" + base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+	_, err = Parse("2026:" + k1 + ",2026:" + k2)
+	assert.Error(t, err)
+}
+
+func TestSealOpen(t *testing.T) {
+	v := testVault(t, "k1")
+
+	sealed, err := v.Seal("ghp_secret")
+	assert.NoError(t, err)
+	assert.True(t, IsSealed(sealed))
+	assert.True(t, strings.HasPrefix(sealed, "vault:v1:k1:"))
+	assert.NotContains(t, sealed, "ghp_secret")
+
+	again, err := v.Seal("ghp_secret")
+	assert.NoError(t, err)
+	assert.NotEqual(t, sealed, again)
+
+	plaintext, err := v.Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "ghp_secret", plaintext)
+
+	_, err = v.Open("ghp_secret")
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	_, err = v.Open(sealed[:len(sealed)-2] + "AA")
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	_, err = testVault(t, "k2").Open(sealed)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestRewrap(t *testing.T) {
+	old := testVault(t, "k1")
+	sealed, err := old.Seal("ghp_secret")
+	assert.NoError(t, err)
+
+	rotated, err := New([]string{"k2", "k1"}, [][]byte{testKey(2), testKey(1)})
+	assert.NoError(t, err)
+
+	rewrapped, changed, err := rotated.Rewrap(sealed)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, strings.HasPrefix(rewrapped, "vault:v1:k2:"))
+	// only the data key is wrapped again
+	assert.Equal(t, sealed[strings.LastIndex(sealed, ":"):], rewrapped[strings.LastIndex(rewrapped, ":"):])
+
+	_, changed, err = rotated.Rewrap(rewrapped)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	onlyNew, err := New([]string{"k2"}, [][]byte{testKey(2)})
+	assert.NoError(t, err)
+	plaintext, err := onlyNew.Open(rewrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, "ghp_secret", plaintext)
+
+	legacy, changed, err := rotated.Rewrap("plaintext pat")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	plaintext, err = rotated.Open(legacy)
+	assert.NoError(t, err)
+	assert.Equal(t, "plaintext pat", plaintext)
+
+	_, changed, err = rotated.Rewrap("")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestSecret(t *testing.T) {
+	original := Default
+	defer func() { Default = original }()
+
+	t.Run("Seals when written and reveals on use", func(t *testing.T) {
+		Default = testVault(t, "k1")
+		secret := Secret("ghp_secret")
+
+		stored, err := secret.Value()
+		assert.NoError(t, err)
+		assert.True(t, IsSealed(stored.(string)))
+
+		var scanned Secret
+		assert.NoError(t, scanned.Scan(stored))
+		restored, err := scanned.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, stored, restored)
+
+		plaintext, err := scanned.Reveal()
+		assert.NoError(t, err)
+		assert.Equal(t, "ghp_secret", plaintext)
+	})
+
+	t.Run("Never shows", func(t *testing.T) {
+		Default = testVault(t, "k1")
+		data, err := json.Marshal(struct {
+			Pat   Secret `json:"pat"`
+			Empty Secret `json:"empty"`
+		}{Pat: "ghp_secret"})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"pat":"[REDACTED]","empty":""}`, string(data))
+
+		secret := Secret("ghp_secret")
+		assert.Equal(t, "[REDACTED] [REDACTED]", fmt.Sprintf("%s %v", secret, secret))
+		assert.NotContains(t, fmt.Sprintf("%#v %+v", secret, struct{ Pat Secret }{secret}), "ghp_secret")
+		assert.True(t, Secret(Redacted).IsRedacted())
+	})
+
+	t.Run("Stays plaintext without a master key", func(t *testing.T) {
+		Default = nil
+		stored, err := Secret("ghp_secret").Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "ghp_secret", stored)
+
+		sealed, err := testVault(t, "k1").Seal("ghp_secret")
+		assert.NoError(t, err)
+		_, err = Secret(sealed).Reveal()
+		assert.ErrorIs(t, err, ErrNoMasterKey)
+	})
+}
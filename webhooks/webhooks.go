@@ -0,0 +1,277 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+const (
+	EventBountyCreated       = "bounty.created"
+	EventBountyAssigned      = "bounty.assigned"
+	EventBountyPaid          = "bounty.paid"
+	EventTicketStatusChanged = "ticket.status_changed"
+	EventFeatureUpdated      = "feature.updated"
+	EventPhaseUpdated        = "phase.updated"
+	EventBudgetDeposit       = "budget.deposit"
+	EventBudgetWithdraw      = "budget.withdraw"
+)
+
+// Events lists every event a subscription can filter on.
+var Events = []string{
+	EventBountyCreated,
+	EventBountyAssigned,
+	EventBountyPaid,
+	EventTicketStatusChanged,
+	EventFeatureUpdated,
+	EventPhaseUpdated,
+	EventBudgetDeposit,
+	EventBudgetWithdraw,
+}
+
+const (
+	SignatureHeader = "x-hub-signature-256"
+	EventHeader     = "x-tribes-event"
+	DeliveryHeader  = "x-tribes-delivery"
+
+	// MaxAttempts is how often a delivery is tried before it is marked
+	// failed.
+	MaxAttempts = 8
+
+	retryBatchSize = 100
+	baseBackoff    = 30 * time.Second
+	maxBackoff     = 6 * time.Hour
+)
+
+// Store persists subscriptions and the delivery log.
+type Store interface {
+	GetActiveWebhookSubscriptions(workspaceUuid string, event string) ([]db.WebhookSubscription, error)
+	GetWebhookSubscriptionByUuid(uuid string) (db.WebhookSubscription, error)
+	CreateWebhookDelivery(delivery *db.WebhookDelivery) error
+	UpdateWebhookDelivery(delivery *db.WebhookDelivery) error
+	GetDueWebhookDeliveries(now time.Time, limit int) ([]db.WebhookDelivery, error)
+}
+
+type HttpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Envelope is the body posted to subscribers.
+type Envelope struct {
+	ID            string      `json:"id"`
+	Event         string      `json:"event"`
+	WorkspaceUuid string      `json:"workspace_uuid"`
+	Created       int64       `json:"created"`
+	Data          interface{} `json:"data"`
+}
+
+type Dispatcher struct {
+	store  Store
+	client HttpClient
+	now    func() time.Time
+}
+
+func NewDispatcher(store Store, client HttpClient) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Default is the dispatcher used by Emit. Events are dropped until Init is
+// called.
+var Default *Dispatcher
+
+func Init(store Store) {
+	Default = NewDispatcher(store, NewClient(10*time.Second))
+}
+
+// Emit delivers event to the subscribers of a workspace in the background
+// so handlers never wait on external endpoints.
+func Emit(workspaceUuid string, event string, data interface{}) {
+	dispatcher := Default
+	if dispatcher == nil || workspaceUuid == "" {
+		return
+	}
+	go dispatcher.Emit(workspaceUuid, event, data)
+}
+
+// RetryDue retries pending deliveries of the default dispatcher.
+func RetryDue() {
+	if Default == nil {
+		return
+	}
+	Default.RetryDue()
+}
+
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the signature header value for body, in the same format as
+// the ticket alerts sent to relay.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is the wait before the next attempt once attempts have failed.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+// Emit records a delivery for every matching subscription and makes the
+// first attempt right away.
+func (d *Dispatcher) Emit(workspaceUuid string, event string, data interface{}) []db.WebhookDelivery {
+	subscriptions, err := d.store.GetActiveWebhookSubscriptions(workspaceUuid, event)
+	if err != nil {
+		logger.Log.Error("[webhooks] could not load subscriptions for %s: %v", workspaceUuid, err)
+		return nil
+	}
+
+	var deliveries []db.WebhookDelivery
+	for _, subscription := range subscriptions {
+		now := d.now()
+		envelope := Envelope{
+			ID:            uuid.New().String(),
+			Event:         event,
+			WorkspaceUuid: workspaceUuid,
+			Created:       now.Unix(),
+			Data:          data,
+		}
+
+		payload, err := json.Marshal(envelope)
+		if err != nil {
+			logger.Log.Error("[webhooks] could not encode %s event: %v", event, err)
+			return deliveries
+		}
+
+		delivery := db.WebhookDelivery{
+			Uuid:             envelope.ID,
+			SubscriptionUuid: subscription.Uuid,
+			WorkspaceUuid:    workspaceUuid,
+			Event:            event,
+			Payload:          string(payload),
+			Status:           db.WebhookDeliveryPending,
+			NextAttemptAt:    &now,
+		}
+		if err := d.store.CreateWebhookDelivery(&delivery); err != nil {
+			logger.Log.Error("[webhooks] could not record delivery to %s: %v", subscription.Uuid, err)
+			continue
+		}
+
+		d.attempt(subscription, &delivery)
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries
+}
+
+// RetryDue makes another attempt for every pending delivery whose backoff
+// has elapsed and returns how many were attempted.
+func (d *Dispatcher) RetryDue() int {
+	deliveries, err := d.store.GetDueWebhookDeliveries(d.now(), retryBatchSize)
+	if err != nil {
+		logger.Log.Error("[webhooks] could not load due deliveries: %v", err)
+		return 0
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		subscription, err := d.store.GetWebhookSubscriptionByUuid(delivery.SubscriptionUuid)
+		if err != nil || !subscription.Active {
+			delivery.Status = db.WebhookDeliveryFailed
+			delivery.Error = "subscription removed or disabled"
+			delivery.NextAttemptAt = nil
+			if err := d.store.UpdateWebhookDelivery(delivery); err != nil {
+				logger.Log.Error("[webhooks] could not update delivery %s: %v", delivery.Uuid, err)
+			}
+			continue
+		}
+
+		d.attempt(subscription, delivery)
+	}
+
+	return len(deliveries)
+}
+
+func (d *Dispatcher) attempt(subscription db.WebhookSubscription, delivery *db.WebhookDelivery) {
+	delivery.Attempts++
+	status, err := d.post(subscription, delivery)
+	delivery.ResponseStatus = status
+
+	if err == nil {
+		now := d.now()
+		delivery.Status = db.WebhookDeliveryDelivered
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	} else {
+		delivery.Error = err.Error()
+		if delivery.Attempts >= MaxAttempts {
+			delivery.Status = db.WebhookDeliveryFailed
+			delivery.NextAttemptAt = nil
+		} else {
+			next := d.now().Add(Backoff(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+		}
+		logger.Log.Info("[webhooks] delivery %s to %s failed (attempt %d): %v", delivery.Uuid, subscription.Url, delivery.Attempts, err)
+	}
+
+	if err := d.store.UpdateWebhookDelivery(delivery); err != nil {
+		logger.Log.Error("[webhooks] could not update delivery %s: %v", delivery.Uuid, err)
+	}
+}
+
+func (d *Dispatcher) post(subscription db.WebhookSubscription, delivery *db.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, body))
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.Uuid)
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
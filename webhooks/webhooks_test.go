@@ -0,0 +1,288 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryStore struct {
+	mu            sync.Mutex
+	subscriptions []db.WebhookSubscription
+	deliveries    map[string]db.WebhookDelivery
+}
+
+func newMemoryStore(subscriptions ...db.WebhookSubscription) *memoryStore {
+	return &memoryStore{
+		subscriptions: subscriptions,
+		deliveries:    map[string]db.WebhookDelivery{},
+	}
+}
+
+func (s *memoryStore) GetActiveWebhookSubscriptions(workspaceUuid string, event string) ([]db.WebhookSubscription, error) {
+	var matched []db.WebhookSubscription
+	for _, sub := range s.subscriptions {
+		if sub.WorkspaceUuid != workspaceUuid || !sub.Active {
+			continue
+		}
+		if len(sub.Events) == 0 {
+			matched = append(matched, sub)
+			continue
+		}
+		for _, e := range sub.Events {
+			if e == event {
+				matched = append(matched, sub)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+func (s *memoryStore) GetWebhookSubscriptionByUuid(uuid string) (db.WebhookSubscription, error) {
+	for _, sub := range s.subscriptions {
+		if sub.Uuid == uuid {
+			return sub, nil
+		}
+	}
+	return db.WebhookSubscription{}, errors.New("webhook subscription not found")
+}
+
+func (s *memoryStore) CreateWebhookDelivery(delivery *db.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[delivery.Uuid] = *delivery
+	return nil
+}
+
+func (s *memoryStore) UpdateWebhookDelivery(delivery *db.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[delivery.Uuid] = *delivery
+	return nil
+}
+
+func (s *memoryStore) GetDueWebhookDeliveries(now time.Time, limit int) ([]db.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []db.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.Status == db.WebhookDeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) get(uuid string) db.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deliveries[uuid]
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t,
+		"sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		Sign("key", []byte("The quick brown fox jumps over the lazy dog")))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(0))
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 4*time.Minute, Backoff(4))
+	assert.Equal(t, 6*time.Hour, Backoff(20))
+}
+
+func TestValidEvent(t *testing.T) {
+	assert.True(t, ValidEvent(EventBountyPaid))
+	assert.False(t, ValidEvent("bounty.exploded"))
+}
+
+func TestDispatcherEmit(t *testing.T) {
+	type received struct {
+		signature string
+		event     string
+		delivery  string
+		body      []byte
+	}
+	var requests []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, received{
+			signature: r.Header.Get(SignatureHeader),
+			event:     r.Header.Get(EventHeader),
+			delivery:  r.Header.Get(DeliveryHeader),
+			body:      body,
+		})
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := newMemoryStore(
+		db.WebhookSubscription{Uuid: "all", WorkspaceUuid: "ws-1", Url: server.URL, Secret: "s1", Active: true},
+		db.WebhookSubscription{Uuid: "paid", WorkspaceUuid: "ws-1", Url: server.URL, Secret: "s2", Active: true, Events: []string{EventBountyPaid}},
+		db.WebhookSubscription{Uuid: "disabled", WorkspaceUuid: "ws-1", Url: server.URL, Secret: "s3", Active: false},
+		db.WebhookSubscription{Uuid: "other", WorkspaceUuid: "ws-2", Url: server.URL, Secret: "s4", Active: true},
+	)
+	dispatcher := NewDispatcher(store, server.Client())
+
+	t.Run("Filters subscriptions by event", func(t *testing.T) {
+		requests = nil
+
+		deliveries := dispatcher.Emit("ws-1", EventBountyCreated, map[string]interface{}{"id": 7})
+
+		assert.Len(t, deliveries, 1)
+		assert.Len(t, requests, 1)
+		assert.Equal(t, "all", deliveries[0].SubscriptionUuid)
+	})
+
+	t.Run("Signs and records deliveries", func(t *testing.T) {
+		requests = nil
+
+		deliveries := dispatcher.Emit("ws-1", EventBountyPaid, map[string]interface{}{"id": 7})
+
+		assert.Len(t, deliveries, 2)
+		assert.Len(t, requests, 2)
+		for i, req := range requests {
+			secret := map[string]string{"all": "s1", "paid": "s2"}[deliveries[i].SubscriptionUuid]
+			assert.Equal(t, Sign(secret, req.body), req.signature)
+			assert.Equal(t, EventBountyPaid, req.event)
+			assert.Equal(t, deliveries[i].Uuid, req.delivery)
+
+			envelope := Envelope{}
+			assert.NoError(t, json.Unmarshal(req.body, &envelope))
+			assert.Equal(t, "ws-1", envelope.WorkspaceUuid)
+			assert.Equal(t, EventBountyPaid, envelope.Event)
+
+			stored := store.get(deliveries[i].Uuid)
+			assert.Equal(t, db.WebhookDeliveryDelivered, stored.Status)
+			assert.Equal(t, 1, stored.Attempts)
+			assert.Equal(t, http.StatusOK, stored.ResponseStatus)
+			assert.Nil(t, stored.NextAttemptAt)
+		}
+	})
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	failing := true
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if failing {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := newMemoryStore(
+		db.WebhookSubscription{Uuid: "sub", WorkspaceUuid: "ws-1", Url: server.URL, Secret: "secret", Active: true},
+	)
+	dispatcher := NewDispatcher(store, server.Client())
+
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+
+	deliveries := dispatcher.Emit("ws-1", EventBudgetDeposit, map[string]interface{}{"amount": 1000})
+	assert.Len(t, deliveries, 1)
+	id := deliveries[0].Uuid
+
+	stored := store.get(id)
+	assert.Equal(t, db.WebhookDeliveryPending, stored.Status)
+	assert.Equal(t, http.StatusBadGateway, stored.ResponseStatus)
+	assert.Equal(t, now.Add(Backoff(1)), *stored.NextAttemptAt)
+
+	// nothing is due until the backoff has elapsed
+	assert.Equal(t, 0, dispatcher.RetryDue())
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(Backoff(1))
+	failing = false
+	assert.Equal(t, 1, dispatcher.RetryDue())
+
+	stored = store.get(id)
+	assert.Equal(t, db.WebhookDeliveryDelivered, stored.Status)
+	assert.Equal(t, 2, stored.Attempts)
+	assert.Equal(t, "", stored.Error)
+
+	t.Run("Gives up after max attempts", func(t *testing.T) {
+		failing = true
+		deliveries := dispatcher.Emit("ws-1", EventBudgetWithdraw, nil)
+		id := deliveries[0].Uuid
+
+		for i := 1; i < MaxAttempts; i++ {
+			now = now.Add(maxBackoff)
+			assert.Equal(t, 1, dispatcher.RetryDue())
+		}
+
+		stored := store.get(id)
+		assert.Equal(t, db.WebhookDeliveryFailed, stored.Status)
+		assert.Equal(t, MaxAttempts, stored.Attempts)
+		assert.Nil(t, stored.NextAttemptAt)
+	})
+}
+
+func TestEmitWithoutInit(t *testing.T) {
+	original := Default
+	defer func() { Default = original }()
+	Default = nil
+
+	assert.NotPanics(t, func() {
+		Emit("ws-1", EventBountyCreated, nil)
+		RetryDue()
+	})
+}
+
+type staticResolver map[string]string
+
+func (r staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ip, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+}
+
+func TestCheckTarget(t *testing.T) {
+	resolver := staticResolver{"hooks.example.com": "93.184.215.14", "rebound.example.com": "192.168.1.10"}
+
+	assert.NoError(t, CheckTarget(context.Background(), resolver, "https://hooks.example.com/tribes"))
+	assert.NoError(t, CheckTarget(context.Background(), resolver, "http://93.184.215.14:8080"))
+
+	for _, target := range []string{
+		"http://127.0.0.1/internal/metrics",
+		"http://[::1]:5002",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.1.2.3",
+		"http://100.100.100.200",
+		"http://0.0.0.0",
+		"https://rebound.example.com",
+	} {
+		assert.ErrorIs(t, CheckTarget(context.Background(), resolver, target), ErrForbiddenTarget, target)
+	}
+	assert.Error(t, CheckTarget(context.Background(), resolver, "https://unknown.example.com"))
+	assert.Error(t, CheckTarget(context.Background(), resolver, "file:///etc/passwd"))
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Post(server.URL, "application/json", nil)
+	assert.ErrorIs(t, err, ErrForbiddenTarget)
+}
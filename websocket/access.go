@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/stakwork/sphinx-tribes/db"
+)
+
+// accessRole is the workspace role a client needs to receive its updates,
+// the same role used to decide which workspaces a user belongs to.
+const accessRole = db.ViewReport
+
+// AccessStore resolves the workspace behind a websocket topic or message
+// and whether a pubkey may see it. db.Database satisfies it.
+type AccessStore interface {
+	UserHasAccess(pubKeyFromAuth string, uuid string, role string) bool
+	GetFeatureByUuid(uuid string) db.WorkspaceFeatures
+	GetChatByChatID(chatID string) (db.Chat, error)
+	GetBounty(id uint) db.NewBounty
+}
+
+func (pool *Pool) UseAccess(access AccessStore) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.Access = access
+}
+
+func (pool *Pool) access() AccessStore {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.Access
+}
+
+// topicWorkspace returns the workspace owning a topic. Bounties created
+// outside a workspace are public and resolve to an empty uuid.
+func topicWorkspace(access AccessStore, topic string) (string, error) {
+	kind, id, _ := strings.Cut(topic, ":")
+
+	switch kind {
+	case TopicWorkspace:
+		return id, nil
+	case TopicFeature:
+		feature := access.GetFeatureByUuid(id)
+		if feature.Uuid == "" {
+			return "", fmt.Errorf("feature not found: %s", id)
+		}
+		return feature.WorkspaceUuid, nil
+	case TopicChat:
+		chat, err := access.GetChatByChatID(id)
+		if err != nil || chat.ID == "" {
+			return "", fmt.Errorf("chat not found: %s", id)
+		}
+		return chat.WorkspaceID, nil
+	case TopicBounty:
+		bountyID, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid bounty id: %s", id)
+		}
+		bounty := access.GetBounty(uint(bountyID))
+		if bounty.ID == 0 {
+			return "", fmt.Errorf("bounty not found: %s", id)
+		}
+		return bounty.WorkspaceUuid, nil
+	}
+	return "", fmt.Errorf("unknown topic type: %s", kind)
+}
+
+// messageTopic returns the topic whose access rules apply to a direct
+// message, or an empty string when the message is not tied to an entity.
+func messageTopic(payload []byte) string {
+	var message struct {
+		TicketDetails struct {
+			FeatureUUID string `json:"featureUUID"`
+		} `json:"ticketDetails"`
+		ChatMessage struct {
+			ChatID string `json:"chatId"`
+		} `json:"chatMessage"`
+		PlanDetails struct {
+			FeatureUUID string `json:"feature_uuid"`
+		} `json:"plan_details"`
+	}
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return ""
+	}
+
+	switch {
+	case message.TicketDetails.FeatureUUID != "":
+		return FeatureTopic(message.TicketDetails.FeatureUUID)
+	case message.PlanDetails.FeatureUUID != "":
+		return FeatureTopic(message.PlanDetails.FeatureUUID)
+	case message.ChatMessage.ChatID != "":
+		return ChatTopic(message.ChatMessage.ChatID)
+	}
+	return ""
+}
+
+// CanAccessTopic reports whether the client's owner may receive messages
+// published to topic. Pools without an AccessStore do not restrict topics.
+func (pool *Pool) CanAccessTopic(client *Client, topic string) error {
+	access := pool.access()
+	if access == nil {
+		return nil
+	}
+
+	workspaceUuid, err := topicWorkspace(access, topic)
+	if err != nil {
+		return err
+	}
+	if workspaceUuid == "" {
+		return nil
+	}
+
+	if client.OwnerPubKey == "" || !access.UserHasAccess(client.OwnerPubKey, workspaceUuid, accessRole) {
+		return fmt.Errorf("access denied: %s", topic)
+	}
+	return nil
+}
+
+// canReceive reports whether an encoded direct message may be written to
+// the client. A message tied to no entity is only for the session's owner,
+// which an anonymous client cannot be.
+func (pool *Pool) canReceive(client *Client, payload []byte) bool {
+	if pool.access() == nil {
+		return true
+	}
+
+	topic := messageTopic(payload)
+	if topic == "" {
+		return client.OwnerPubKey != ""
+	}
+	return pool.CanAccessTopic(client, topic) == nil
+}
+
+// writeDirect writes an encoded direct message to the client once its
+// owner has been checked against the message's workspace.
+func (pool *Pool) writeDirect(client *Client, payload []byte) error {
+	if !pool.canReceive(client, payload) {
+		return fmt.Errorf("access denied for client: %s", client.Host)
+	}
+	return client.WriteRaw(payload)
+}
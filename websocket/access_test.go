@@ -0,0 +1,317 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryAccess struct {
+	members  map[string]map[string]bool
+	features map[string]string
+	chats    map[string]string
+	bounties map[uint]string
+}
+
+func newMemoryAccess() *memoryAccess {
+	return &memoryAccess{
+		members: map[string]map[string]bool{
+			"workspace-1": {"member": true},
+		},
+		features: map[string]string{"feature-1": "workspace-1"},
+		chats:    map[string]string{"chat-1": "workspace-1"},
+		bounties: map[uint]string{1: "workspace-1", 2: ""},
+	}
+}
+
+func (a *memoryAccess) UserHasAccess(pubKeyFromAuth string, uuid string, role string) bool {
+	return role == db.ViewReport && a.members[uuid][pubKeyFromAuth]
+}
+
+func (a *memoryAccess) GetFeatureByUuid(uuid string) db.WorkspaceFeatures {
+	workspaceUuid, ok := a.features[uuid]
+	if !ok {
+		return db.WorkspaceFeatures{}
+	}
+	return db.WorkspaceFeatures{Uuid: uuid, WorkspaceUuid: workspaceUuid}
+}
+
+func (a *memoryAccess) GetChatByChatID(chatID string) (db.Chat, error) {
+	workspaceUuid, ok := a.chats[chatID]
+	if !ok {
+		return db.Chat{}, errors.New("chat not found")
+	}
+	return db.Chat{ID: chatID, WorkspaceID: workspaceUuid}, nil
+}
+
+func (a *memoryAccess) GetBounty(id uint) db.NewBounty {
+	workspaceUuid, ok := a.bounties[id]
+	if !ok {
+		return db.NewBounty{}
+	}
+	return db.NewBounty{ID: id, WorkspaceUuid: workspaceUuid}
+}
+
+func TestCanAccessTopic(t *testing.T) {
+	pool := NewPool()
+	pool.UseAccess(newMemoryAccess())
+
+	member := &Client{Host: "member-session", OwnerPubKey: "member"}
+	outsider := &Client{Host: "outsider-session", OwnerPubKey: "outsider"}
+	anonymous := &Client{Host: "anonymous-session"}
+
+	tests := []struct {
+		name    string
+		client  *Client
+		topic   string
+		wantErr bool
+	}{
+		{name: "Member Workspace Topic", client: member, topic: WorkspaceTopic("workspace-1")},
+		{name: "Member Feature Topic", client: member, topic: FeatureTopic("feature-1")},
+		{name: "Member Chat Topic", client: member, topic: ChatTopic("chat-1")},
+		{name: "Member Bounty Topic", client: member, topic: BountyTopic("1")},
+		{name: "Outsider Workspace Topic", client: outsider, topic: WorkspaceTopic("workspace-1"), wantErr: true},
+		{name: "Outsider Feature Topic", client: outsider, topic: FeatureTopic("feature-1"), wantErr: true},
+		{name: "Outsider Chat Topic", client: outsider, topic: ChatTopic("chat-1"), wantErr: true},
+		{name: "Public Bounty Topic", client: outsider, topic: BountyTopic("2")},
+		{name: "Unknown Feature", client: member, topic: FeatureTopic("missing"), wantErr: true},
+		{name: "Unknown Chat", client: member, topic: ChatTopic("missing"), wantErr: true},
+		{name: "Invalid Bounty ID", client: member, topic: BountyTopic("abc"), wantErr: true},
+		{name: "Client Without Pubkey", client: anonymous, topic: WorkspaceTopic("workspace-1"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pool.CanAccessTopic(tt.client, tt.topic)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("Pool Without Access Store", func(t *testing.T) {
+		assert.NoError(t, NewPool().CanAccessTopic(anonymous, WorkspaceTopic("workspace-1")))
+	})
+
+	t.Run("Subscribe Is Denied Without Access", func(t *testing.T) {
+		assert.Error(t, pool.Subscribe(outsider, FeatureTopic("feature-1")))
+		assert.Len(t, pool.TopicSubscribers(FeatureTopic("feature-1")), 0)
+	})
+}
+
+func TestDirectMessageAccess(t *testing.T) {
+	t.Run("Only Clients With Workspace Access Receive Direct Messages", func(t *testing.T) {
+		pool := NewPool()
+		pool.UseAccess(newMemoryAccess())
+
+		memberWs, memberReceived, memberServer := setupRecordingWebsocket(t)
+		defer memberServer.Close()
+		defer memberWs.Close()
+		outsiderWs, outsiderReceived, outsiderServer := setupRecordingWebsocket(t)
+		defer outsiderServer.Close()
+		defer outsiderWs.Close()
+
+		addTestClient(pool, "member-session", memberWs)
+		pool.Clients["member-session"].Client.OwnerPubKey = "member"
+		addTestClient(pool, "outsider-session", outsiderWs)
+		pool.Clients["outsider-session"].Client.OwnerPubKey = "outsider"
+
+		draft := TicketData{FeatureUUID: "feature-1", TicketUUID: "ticket-1"}
+
+		assert.NoError(t, pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "member-session",
+			TicketDetails:   draft,
+		}))
+		assert.Contains(t, string(waitForMessage(t, memberReceived)), "ticket-1")
+
+		err := pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "outsider-session",
+			TicketDetails:   draft,
+		})
+		assert.Error(t, err)
+
+		err = pool.SendTicketPlanMessage(TicketPlanMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "outsider-session",
+			PlanDetails:     TicketPlanDetails{FeatureUUID: "feature-1"},
+		})
+		assert.Error(t, err)
+
+		select {
+		case msg := <-outsiderReceived:
+			t.Fatalf("client without access received %s", string(msg))
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("Messages Without An Entity Go To The Session Owner Only", func(t *testing.T) {
+		pool := NewPool()
+		pool.UseAccess(newMemoryAccess())
+
+		ws, received, server := setupRecordingWebsocket(t)
+		defer server.Close()
+		defer ws.Close()
+		addTestClient(pool, "session", ws)
+
+		err := pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "session",
+			Message:         "hello",
+		})
+		assert.Error(t, err)
+
+		pool.Clients["session"].Client.OwnerPubKey = "member"
+		assert.NoError(t, pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "session",
+			Message:         "hello",
+		}))
+		assert.Contains(t, string(waitForMessage(t, received)), "hello")
+	})
+
+	t.Run("Replay Of Another User's Session Is Denied", func(t *testing.T) {
+		pool := NewPool()
+		pool.UseOutbox(newMemoryOutbox())
+		pool.UseAccess(newMemoryAccess())
+		assert.NoError(t, pool.claimSession("owned", "member"))
+
+		_, err := pool.Replay(&Client{Host: "owned", OwnerPubKey: "outsider", Pool: pool}, SessionStream("owned"), 0)
+		assert.ErrorIs(t, err, errSessionOwned)
+	})
+
+	t.Run("Replay Skips Messages Without Access", func(t *testing.T) {
+		pool := NewPool()
+		pool.UseOutbox(newMemoryOutbox())
+		pool.UseAccess(newMemoryAccess())
+
+		assert.NoError(t, pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "shared",
+			TicketDetails:   TicketData{FeatureUUID: "feature-1"},
+		}))
+		assert.NoError(t, pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "shared",
+			Message:         "public",
+		}))
+
+		ws, received, server := setupRecordingWebsocket(t)
+		defer server.Close()
+		defer ws.Close()
+
+		replayed, err := pool.Replay(&Client{Host: "shared", OwnerPubKey: "outsider", Conn: ws, Pool: pool}, SessionStream("shared"), 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, replayed)
+		assert.Contains(t, string(waitForMessage(t, received)), `"sequence":2`)
+	})
+}
+
+func TestAuthenticatedHandshake(t *testing.T) {
+	db.InitCache()
+	pool := NewPool()
+	pool.UseAccess(newMemoryAccess())
+	go pool.Start()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(pool, w, r)
+	}))
+	defer server.Close()
+
+	baseURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	t.Run("Missing Token Is Rejected", func(t *testing.T) {
+		ws, resp, err := websocket.DefaultDialer.Dial(baseURL+"?uniqueId=anonymous", nil)
+		assert.Error(t, err)
+		assert.Nil(t, ws)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Invalid Token Is Rejected", func(t *testing.T) {
+		ws, resp, err := websocket.DefaultDialer.Dial(baseURL+"?uniqueId=forged&token=not.a.jwt", nil)
+		assert.Error(t, err)
+		assert.Nil(t, ws)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	ws, _, err := websocket.DefaultDialer.Dial(baseURL+"?uniqueId=owned", testAuthHeader(t, "member"))
+	assert.NoError(t, err)
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var connected Message
+	assert.NoError(t, ws.ReadJSON(&connected))
+
+	t.Run("Client Carries Owner Pubkey", func(t *testing.T) {
+		client, ok := pool.getClient("owned")
+		assert.True(t, ok)
+		assert.Equal(t, "member", client.OwnerPubKey)
+	})
+
+	t.Run("Token Accepted As Query Parameter", func(t *testing.T) {
+		token := testAuthHeader(t, "member").Get("x-jwt")
+		other, resp, err := websocket.DefaultDialer.Dial(baseURL+"?uniqueId=query-token&token="+token, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+		other.Close()
+	})
+
+	t.Run("Offline Session Cannot Be Taken Over By Another Pubkey", func(t *testing.T) {
+		pool := NewPool()
+		pool.UseOutbox(newMemoryOutbox())
+		go pool.Start()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ServeWs(pool, w, r)
+		}))
+		defer server.Close()
+		url := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=away"
+
+		owner, _, err := websocket.DefaultDialer.Dial(url, testAuthHeader(t, "member"))
+		assert.NoError(t, err)
+		owner.Close()
+		// the owner's session may be on another replica, the outbox still knows it
+		pool.Unregister <- &Client{Host: "away"}
+
+		hijack, resp, err := websocket.DefaultDialer.Dial(url, testAuthHeader(t, "outsider"))
+		assert.Error(t, err)
+		assert.Nil(t, hijack)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		again, _, err := websocket.DefaultDialer.Dial(url, testAuthHeader(t, "member"))
+		assert.NoError(t, err)
+		again.Close()
+	})
+
+	t.Run("Session Cannot Be Taken Over By Another Pubkey", func(t *testing.T) {
+		hijack, resp, err := websocket.DefaultDialer.Dial(baseURL+"?uniqueId=owned", testAuthHeader(t, "outsider"))
+		assert.Error(t, err)
+		assert.Nil(t, hijack)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Subscribe To Workspace Without Access", func(t *testing.T) {
+		assert.NoError(t, ws.WriteJSON(SubscriptionRequest{Action: SubscribeAction, Topic: WorkspaceTopic("workspace-2")}))
+
+		var reply Message
+		assert.NoError(t, ws.ReadJSON(&reply))
+		assert.Equal(t, "subscription_error", reply.Msg)
+	})
+
+	t.Run("Subscribe To Workspace With Access", func(t *testing.T) {
+		assert.NoError(t, ws.WriteJSON(SubscriptionRequest{Action: SubscribeAction, Topic: WorkspaceTopic("workspace-1")}))
+
+		var reply Message
+		assert.NoError(t, ws.ReadJSON(&reply))
+		assert.Equal(t, "subscribed", reply.Msg)
+	})
+}
@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+const (
+	BroadcastKindTicket     = "ticket"
+	BroadcastKindTicketPlan = "ticket_plan"
+
+	DefaultBroadcastChannel = "tribes:websocket:broadcast"
+)
+
+// BroadcastMessage is the envelope that travels between replicas so a
+// message sent on one node can be written to a session held by another.
+type BroadcastMessage struct {
+	Origin    string          `json:"origin"`
+	Kind      string          `json:"kind"`
+	SessionID string          `json:"session_id,omitempty"`
+	Topic     string          `json:"topic,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// DeliverFunc writes a broadcast message to a locally connected session and
+// returns an error when the session is not held by this node.
+type DeliverFunc func(message BroadcastMessage) error
+
+// Broadcaster fans websocket messages out to every pool subscribed to it.
+type Broadcaster interface {
+	Publish(message BroadcastMessage) error
+	Subscribe(nodeID string, deliver DeliverFunc) error
+	Close() error
+}
+
+type memoryBroadcaster struct {
+	mu          sync.RWMutex
+	subscribers map[string]DeliverFunc
+}
+
+// NewMemoryBroadcaster returns a Broadcaster that only reaches pools living
+// in the same process, which is the default for single node deployments.
+func NewMemoryBroadcaster() Broadcaster {
+	return &memoryBroadcaster{
+		subscribers: make(map[string]DeliverFunc),
+	}
+}
+
+func (b *memoryBroadcaster) Publish(message BroadcastMessage) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	// topic messages fan out to every pool, direct messages stop at the
+	// pool holding the session
+	if message.Topic != "" {
+		for nodeID, deliver := range b.subscribers {
+			if nodeID != message.Origin {
+				deliver(message)
+			}
+		}
+		return nil
+	}
+
+	for nodeID, deliver := range b.subscribers {
+		if nodeID == message.Origin {
+			continue
+		}
+		if err := deliver(message); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("client not found: %s", message.SessionID)
+}
+
+func (b *memoryBroadcaster) Subscribe(nodeID string, deliver DeliverFunc) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers[nodeID] = deliver
+	return nil
+}
+
+func (b *memoryBroadcaster) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = make(map[string]DeliverFunc)
+	return nil
+}
+
+type redisBroadcaster struct {
+	client  *redis.Client
+	channel string
+
+	mu     sync.Mutex
+	pubsub []*redis.PubSub
+}
+
+// NewRedisBroadcaster returns a Broadcaster backed by Redis pub/sub so every
+// replica sharing the Redis instance receives each published message.
+func NewRedisBroadcaster(client *redis.Client, channel string) Broadcaster {
+	if channel == "" {
+		channel = DefaultBroadcastChannel
+	}
+	return &redisBroadcaster{
+		client:  client,
+		channel: channel,
+	}
+}
+
+func (b *redisBroadcaster) Publish(message BroadcastMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode broadcast message: %w", err)
+	}
+
+	return b.client.Publish(context.Background(), b.channel, data).Err()
+}
+
+func (b *redisBroadcaster) Subscribe(nodeID string, deliver DeliverFunc) error {
+	ctx := context.Background()
+	pubsub := b.client.Subscribe(ctx, b.channel)
+
+	// wait for the subscription to be confirmed so no message published
+	// right after Subscribe returns is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe to %s: %w", b.channel, err)
+	}
+
+	b.mu.Lock()
+	b.pubsub = append(b.pubsub, pubsub)
+	b.mu.Unlock()
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			var message BroadcastMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+				logger.Log.Error("Websocket broadcast decode error: %v", err)
+				continue
+			}
+			if message.Origin == nodeID {
+				continue
+			}
+			// sessions held by other replicas are expected to miss here
+			_ = deliver(message)
+		}
+	}()
+
+	return nil
+}
+
+func (b *redisBroadcaster) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var firstErr error
+	for _, pubsub := range b.pubsub {
+		if err := pubsub.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	b.pubsub = nil
+	return firstErr
+}
@@ -0,0 +1,239 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBroadcaster(t *testing.T) {
+	t.Run("Delivers To Session On Another Pool", func(t *testing.T) {
+		broadcaster := NewMemoryBroadcaster()
+		sender := NewPool()
+		receiver := NewPool()
+		assert.NoError(t, sender.UseBroadcaster(broadcaster))
+		assert.NoError(t, receiver.UseBroadcaster(broadcaster))
+
+		ws, received, server := setupRecordingWebsocket(t)
+		defer server.Close()
+		defer ws.Close()
+		addTestClient(receiver, "remote-session", ws)
+
+		err := sender.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "remote-session",
+			Message:         "hello from another pool",
+		})
+		assert.NoError(t, err)
+
+		var msg TicketMessage
+		assert.NoError(t, json.Unmarshal(waitForMessage(t, received), &msg))
+		assert.Equal(t, "hello from another pool", msg.Message)
+	})
+
+	t.Run("Session Not Connected Anywhere", func(t *testing.T) {
+		broadcaster := NewMemoryBroadcaster()
+		sender := NewPool()
+		receiver := NewPool()
+		assert.NoError(t, sender.UseBroadcaster(broadcaster))
+		assert.NoError(t, receiver.UseBroadcaster(broadcaster))
+
+		err := sender.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "missing-session",
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "client not found")
+	})
+
+	t.Run("Default Pool Does Not Publish To Itself", func(t *testing.T) {
+		pool := NewPool()
+
+		err := pool.SendTicketPlanMessage(TicketPlanMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "missing-session",
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "client not found")
+	})
+
+	t.Run("Pool Without Broadcaster", func(t *testing.T) {
+		pool := &Pool{Clients: make(map[string]*ClientData)}
+
+		err := pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "missing-session",
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "client not found")
+	})
+}
+
+func TestRedisBroadcaster(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	newReplica := func(t *testing.T) *Pool {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		pool := NewPool()
+		broadcaster := NewRedisBroadcaster(client, "test:websocket")
+		assert.NoError(t, pool.UseBroadcaster(broadcaster))
+		t.Cleanup(func() { broadcaster.Close() })
+		return pool
+	}
+
+	t.Run("Ticket Message Reaches Session On Another Replica", func(t *testing.T) {
+		sender := newReplica(t)
+		receiver := newReplica(t)
+
+		ws, received, server := setupRecordingWebsocket(t)
+		defer server.Close()
+		defer ws.Close()
+		addTestClient(receiver, "session-on-b", ws)
+
+		err := sender.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "session-on-b",
+			Message:         "ticket updated",
+			Action:          "message",
+			TicketDetails: TicketData{
+				TicketUUID: "ticket-uuid",
+			},
+		})
+		assert.NoError(t, err)
+
+		var msg TicketMessage
+		assert.NoError(t, json.Unmarshal(waitForMessage(t, received), &msg))
+		assert.Equal(t, "ticket updated", msg.Message)
+		assert.Equal(t, "ticket-uuid", msg.TicketDetails.TicketUUID)
+	})
+
+	t.Run("Ticket Plan Message Reaches Session On Another Replica", func(t *testing.T) {
+		sender := newReplica(t)
+		receiver := newReplica(t)
+
+		ws, received, server := setupRecordingWebsocket(t)
+		defer server.Close()
+		defer ws.Close()
+		addTestClient(receiver, "plan-session", ws)
+
+		err := sender.SendTicketPlanMessage(TicketPlanMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "plan-session",
+			Message:         "plan ready",
+			PlanDetails: TicketPlanDetails{
+				RequestUUID: "request-uuid",
+			},
+		})
+		assert.NoError(t, err)
+
+		var msg TicketPlanMessage
+		assert.NoError(t, json.Unmarshal(waitForMessage(t, received), &msg))
+		assert.Equal(t, "plan ready", msg.Message)
+		assert.Equal(t, "request-uuid", msg.PlanDetails.RequestUUID)
+	})
+
+	t.Run("Local Session Is Written Once Without Publishing", func(t *testing.T) {
+		pool := newReplica(t)
+		other := newReplica(t)
+
+		ws, received, server := setupRecordingWebsocket(t)
+		defer server.Close()
+		defer ws.Close()
+		addTestClient(pool, "local-session", ws)
+		addTestClient(other, "local-session", ws)
+
+		err := pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "local-session",
+			Message:         "local",
+		})
+		assert.NoError(t, err)
+
+		waitForMessage(t, received)
+		select {
+		case extra := <-received:
+			t.Fatalf("unexpected duplicate delivery: %s", string(extra))
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("Unknown Session Does Not Error", func(t *testing.T) {
+		sender := newReplica(t)
+
+		err := sender.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "not-connected",
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Publish Fails When Redis Is Down", func(t *testing.T) {
+		down := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: down.Addr(), MaxRetries: -1})
+		defer client.Close()
+
+		broadcaster := NewRedisBroadcaster(client, "")
+		down.Close()
+
+		err := broadcaster.Publish(BroadcastMessage{SessionID: "any"})
+		assert.Error(t, err)
+	})
+}
+
+func addTestClient(pool *Pool, host string, conn *websocket.Conn) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.Clients[host] = &ClientData{
+		Client: &Client{Host: host, Conn: conn, Pool: pool},
+		Status: true,
+	}
+}
+
+func waitForMessage(t *testing.T, received <-chan []byte) []byte {
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for websocket message")
+	}
+	return nil
+}
+
+func setupRecordingWebsocket(t *testing.T) (*websocket.Conn, <-chan []byte, *httptest.Server) {
+	received := make(chan []byte, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			_, p, err := conn.ReadMessage()
+			if err != nil {
+				break
+			}
+			received <- p
+		}
+	}))
+
+	wsURL := "ws" + server.URL[4:]
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ws, received, server
+}
@@ -4,15 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 
 	"github.com/gorilla/websocket"
 	"github.com/stakwork/sphinx-tribes/db"
 )
 
 type Client struct {
-	Host string
-	Conn *websocket.Conn
-	Pool *Pool
+	Host        string
+	OwnerPubKey string
+	Conn        *websocket.Conn
+	Pool        *Pool
+	writeMu     sync.Mutex
 }
 
 type ClientData struct {
@@ -35,6 +38,7 @@ type TicketMessage struct {
 	TicketDetails   TicketData     `json:"ticketDetails"`
 	ChatMessage     db.ChatMessage `json:"chatMessage"`
 	Artifacts       []db.Artifact  `json:"artifacts"`
+	Sequence        uint64         `json:"sequence,omitempty"`
 }
 
 type TicketData struct {
@@ -51,6 +55,7 @@ type TicketPlanMessage struct {
     Message         string             `json:"message"`
     Action          string             `json:"action"`
     PlanDetails     TicketPlanDetails  `json:"plan_details"`
+    Sequence        uint64             `json:"sequence,omitempty"`
 }
 
 type TicketPlanDetails struct {
@@ -59,6 +64,29 @@ type TicketPlanDetails struct {
     PhaseUUID    string `json:"phase_uuid"`
 }
 
+// WriteJSON serializes writes to the connection, which gorilla does not
+// allow to happen concurrently.
+func (c *Client) WriteJSON(v interface{}) error {
+	if c.Conn == nil {
+		return fmt.Errorf("client connection is nil: %s", c.Host)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.WriteJSON(v)
+}
+
+// WriteRaw writes an already encoded JSON payload to the connection.
+func (c *Client) WriteRaw(payload []byte) error {
+	if c.Conn == nil {
+		return fmt.Errorf("client connection is nil: %s", c.Host)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.Conn.WriteMessage(websocket.TextMessage, payload)
+}
+
 func (c *Client) Read() {
 	defer func() {
 		// ceck to acoid nil pointer
@@ -77,6 +105,11 @@ func (c *Client) Read() {
 			return
 		}
 
+		if request, ok := ParseSubscriptionRequest(p); ok {
+			c.handleSubscription(request)
+			continue
+		}
+
 		err = json.Unmarshal(p, &socketMsg)
 		if err != nil {
 			fmt.Println("Message Decode Error", err, string(p))
@@ -0,0 +1,183 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
+)
+
+const (
+	ReplayAction = "replay"
+
+	outboxReplayBatch = 200
+)
+
+// OutboxStore persists websocket messages so they can be replayed to a
+// client that reconnects after missing them. db.Database satisfies it.
+type OutboxStore interface {
+	AppendWebsocketOutboxMessage(stream string, kind string, payload db.PropertyMap) (*db.WebsocketOutboxMessage, error)
+	GetWebsocketOutboxMessagesAfter(stream string, sequence uint64, limit int) ([]db.WebsocketOutboxMessage, error)
+	ClaimWebsocketOutboxStream(stream string, owner string) (string, error)
+}
+
+var errSessionOwned = errors.New("session belongs to another user")
+
+type sequenced interface {
+	setSequence(sequence uint64)
+}
+
+func (m *TicketMessage) setSequence(sequence uint64)     { m.Sequence = sequence }
+func (m *TicketPlanMessage) setSequence(sequence uint64) { m.Sequence = sequence }
+func (m *TopicMessage) setSequence(sequence uint64)      { m.Sequence = sequence }
+
+// SessionStream is the outbox stream holding direct messages for a session.
+func SessionStream(sessionID string) string {
+	return "session:" + sessionID
+}
+
+func (pool *Pool) UseOutbox(outbox OutboxStore) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.Outbox = outbox
+}
+
+func (pool *Pool) outbox() OutboxStore {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.Outbox
+}
+
+// claimSession binds a session to the pubkey connecting it. The binding is
+// kept with the session's outbox stream, so it holds while the session is
+// offline or connected to another replica; without an outbox only the
+// sessions connected here are checked.
+func (pool *Pool) claimSession(sessionID string, pubkey string) error {
+	if existing, ok := pool.getClient(sessionID); ok && existing.OwnerPubKey != pubkey {
+		return errSessionOwned
+	}
+
+	outbox := pool.outbox()
+	if outbox == nil {
+		return nil
+	}
+	owner, err := outbox.ClaimWebsocketOutboxStream(SessionStream(sessionID), pubkey)
+	if err != nil {
+		return err
+	}
+	if owner != pubkey {
+		return errSessionOwned
+	}
+	return nil
+}
+
+// record appends message to the outbox stream and stamps it with the
+// allocated sequence. It reports whether the message was persisted.
+func (pool *Pool) record(stream string, kind string, message sequenced) bool {
+	outbox := pool.outbox()
+	if outbox == nil || stream == "" {
+		return false
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		logger.Log.Error("Websocket outbox encode error: %v", err)
+		return false
+	}
+
+	var payload db.PropertyMap
+	if err := json.Unmarshal(data, &payload); err != nil {
+		logger.Log.Error("Websocket outbox encode error: %v", err)
+		return false
+	}
+
+	entry, err := outbox.AppendWebsocketOutboxMessage(stream, kind, payload)
+	if err != nil {
+		logger.Log.Error("Websocket outbox append error for %s: %v", stream, err)
+		return false
+	}
+
+	message.setSequence(entry.Sequence)
+	return true
+}
+
+// Replay writes every message stored for stream after the given sequence
+// to the client, oldest first.
+func (pool *Pool) Replay(client *Client, stream string, after uint64) (int, error) {
+	outbox := pool.outbox()
+	if outbox == nil {
+		return 0, fmt.Errorf("websocket outbox is not configured")
+	}
+	if stream == SessionStream(client.Host) {
+		if err := pool.claimSession(client.Host, client.OwnerPubKey); err != nil {
+			return 0, err
+		}
+	}
+
+	replayed := 0
+	for {
+		entries, err := outbox.GetWebsocketOutboxMessagesAfter(stream, after, outboxReplayBatch)
+		if err != nil {
+			return replayed, err
+		}
+
+		for _, entry := range entries {
+			after = entry.Sequence
+
+			payload := entry.Payload
+			if payload == nil {
+				payload = db.PropertyMap{}
+			}
+			payload["sequence"] = entry.Sequence
+
+			data, err := json.Marshal(payload)
+			if err != nil {
+				return replayed, err
+			}
+			if !pool.canReceive(client, data) {
+				continue
+			}
+
+			if err := client.WriteRaw(data); err != nil {
+				return replayed, err
+			}
+			replayed++
+		}
+
+		if len(entries) < outboxReplayBatch {
+			return replayed, nil
+		}
+	}
+}
+
+func (c *Client) handleReplay(request SubscriptionRequest) {
+	stream := SessionStream(c.Host)
+	if request.Topic != "" {
+		if err := ValidateTopic(request.Topic); err != nil {
+			c.WriteJSON(Message{Type: 1, Msg: "replay_error", Body: err.Error()})
+			return
+		}
+		if err := c.Pool.CanAccessTopic(c, request.Topic); err != nil {
+			c.WriteJSON(Message{Type: 1, Msg: "replay_error", Body: err.Error()})
+			return
+		}
+		stream = request.Topic
+	}
+
+	var after uint64
+	if request.LastSequence != nil {
+		after = *request.LastSequence
+	}
+
+	replayed, err := c.Pool.Replay(c, stream, after)
+	if err != nil {
+		c.WriteJSON(Message{Type: 1, Msg: "replay_error", Body: err.Error()})
+		return
+	}
+	logger.Log.Info("Replayed %d websocket messages from %s", replayed, stream)
+	c.WriteJSON(Message{Type: 1, Msg: "replayed", Body: stream})
+}
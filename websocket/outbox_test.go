@@ -0,0 +1,248 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryOutbox struct {
+	mu        sync.Mutex
+	sequences map[string]uint64
+	messages  map[string][]db.WebsocketOutboxMessage
+	owners    map[string]string
+	appendErr error
+}
+
+func newMemoryOutbox() *memoryOutbox {
+	return &memoryOutbox{
+		sequences: make(map[string]uint64),
+		messages:  make(map[string][]db.WebsocketOutboxMessage),
+		owners:    make(map[string]string),
+	}
+}
+
+func (o *memoryOutbox) ClaimWebsocketOutboxStream(stream string, owner string) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if owner == "" {
+		return "", errors.New("stream and owner are required")
+	}
+	if o.owners[stream] == "" {
+		o.owners[stream] = owner
+	}
+	return o.owners[stream], nil
+}
+
+func (o *memoryOutbox) AppendWebsocketOutboxMessage(stream string, kind string, payload db.PropertyMap) (*db.WebsocketOutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.appendErr != nil {
+		return nil, o.appendErr
+	}
+
+	o.sequences[stream]++
+	message := db.WebsocketOutboxMessage{
+		Stream:   stream,
+		Sequence: o.sequences[stream],
+		Kind:     kind,
+		Payload:  payload,
+	}
+	o.messages[stream] = append(o.messages[stream], message)
+	return &message, nil
+}
+
+func (o *memoryOutbox) GetWebsocketOutboxMessagesAfter(stream string, sequence uint64, limit int) ([]db.WebsocketOutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var messages []db.WebsocketOutboxMessage
+	for _, message := range o.messages[stream] {
+		if message.Sequence > sequence && len(messages) < limit {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func TestPoolOutbox(t *testing.T) {
+	t.Run("Offline Session Message Is Queued", func(t *testing.T) {
+		pool := NewPool()
+		outbox := newMemoryOutbox()
+		pool.UseOutbox(outbox)
+
+		err := pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "offline-session",
+			Message:         "queued while away",
+		})
+		assert.NoError(t, err)
+		assert.Len(t, outbox.messages[SessionStream("offline-session")], 1)
+	})
+
+	t.Run("Sequences Increase Per Stream", func(t *testing.T) {
+		pool := NewPool()
+		outbox := newMemoryOutbox()
+		pool.UseOutbox(outbox)
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, pool.SendTicketPlanMessage(TicketPlanMessage{
+				BroadcastType:   "direct",
+				SourceSessionID: "session-a",
+			}))
+		}
+		assert.NoError(t, pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "session-b",
+		}))
+
+		streamA := outbox.messages[SessionStream("session-a")]
+		assert.Len(t, streamA, 3)
+		for i, message := range streamA {
+			assert.Equal(t, uint64(i+1), message.Sequence)
+			assert.Equal(t, BroadcastKindTicketPlan, message.Kind)
+		}
+		assert.Equal(t, uint64(1), outbox.messages[SessionStream("session-b")][0].Sequence)
+	})
+
+	t.Run("Live Message Carries Sequence", func(t *testing.T) {
+		pool := NewPool()
+		pool.UseOutbox(newMemoryOutbox())
+
+		ws, received, server := setupRecordingWebsocket(t)
+		defer server.Close()
+		defer ws.Close()
+		addTestClient(pool, "online", ws)
+
+		assert.NoError(t, pool.SendTicketMessage(TicketMessage{BroadcastType: "direct", SourceSessionID: "online"}))
+		assert.NoError(t, pool.SendTicketMessage(TicketMessage{BroadcastType: "direct", SourceSessionID: "online"}))
+
+		assert.Contains(t, string(waitForMessage(t, received)), `"sequence":1`)
+		assert.Contains(t, string(waitForMessage(t, received)), `"sequence":2`)
+	})
+
+	t.Run("Append Failure Falls Back To Client Not Found", func(t *testing.T) {
+		pool := NewPool()
+		outbox := newMemoryOutbox()
+		outbox.appendErr = errors.New("database down")
+		pool.UseOutbox(outbox)
+
+		err := pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "offline-session",
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "client not found")
+	})
+
+	t.Run("Topic Messages Are Queued Under The Topic", func(t *testing.T) {
+		pool := NewPool()
+		outbox := newMemoryOutbox()
+		pool.UseOutbox(outbox)
+
+		assert.NoError(t, pool.PublishToTopic(FeatureTopic("f1"), TopicEventTicket, TicketMessage{Action: "update"}))
+
+		messages := outbox.messages[FeatureTopic("f1")]
+		assert.Len(t, messages, 1)
+		assert.Equal(t, FeatureTopic("f1"), messages[0].Payload["topic"])
+	})
+
+	t.Run("Replay Without Outbox", func(t *testing.T) {
+		pool := &Pool{Clients: make(map[string]*ClientData)}
+		_, err := pool.Replay(&Client{Host: "h"}, SessionStream("h"), 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("Replay Sends Only The Gap", func(t *testing.T) {
+		pool := NewPool()
+		pool.UseOutbox(newMemoryOutbox())
+
+		for i := 0; i < outboxReplayBatch+5; i++ {
+			assert.NoError(t, pool.SendTicketMessage(TicketMessage{BroadcastType: "direct", SourceSessionID: "gap"}))
+		}
+
+		ws, received, server := setupRecordingWebsocket(t)
+		defer server.Close()
+		defer ws.Close()
+
+		replayed, err := pool.Replay(&Client{Host: "gap", OwnerPubKey: "owner", Conn: ws, Pool: pool}, SessionStream("gap"), 3)
+		assert.NoError(t, err)
+		assert.Equal(t, outboxReplayBatch+2, replayed)
+		assert.Contains(t, string(waitForMessage(t, received)), `"sequence":4`)
+	})
+}
+
+func TestClientReplayProtocol(t *testing.T) {
+	db.InitCache()
+	pool := NewPool()
+	pool.UseOutbox(newMemoryOutbox())
+	go pool.Start()
+
+	for _, text := range []string{"first", "second", "third"} {
+		assert.NoError(t, pool.SendTicketMessage(TicketMessage{
+			BroadcastType:   "direct",
+			SourceSessionID: "returning",
+			Message:         text,
+		}))
+	}
+	assert.NoError(t, pool.PublishToTopic(ChatTopic("c1"), TopicEventChatMessage, "missed chat"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(pool, w, r)
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=returning"
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
+	assert.NoError(t, err)
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var connected Message
+	assert.NoError(t, ws.ReadJSON(&connected))
+
+	t.Run("Session Replay From Last Seen Sequence", func(t *testing.T) {
+		lastSeen := uint64(1)
+		assert.NoError(t, ws.WriteJSON(SubscriptionRequest{Action: ReplayAction, LastSequence: &lastSeen}))
+
+		for _, expected := range []struct {
+			sequence uint64
+			message  string
+		}{{2, "second"}, {3, "third"}} {
+			var msg TicketMessage
+			assert.NoError(t, ws.ReadJSON(&msg))
+			assert.Equal(t, expected.sequence, msg.Sequence)
+			assert.Equal(t, expected.message, msg.Message)
+		}
+
+		var done Message
+		assert.NoError(t, ws.ReadJSON(&done))
+		assert.Equal(t, "replayed", done.Msg)
+		assert.Equal(t, SessionStream("returning"), done.Body)
+	})
+
+	t.Run("Subscribe With Last Sequence Replays Topic", func(t *testing.T) {
+		lastSeen := uint64(0)
+		assert.NoError(t, ws.WriteJSON(SubscriptionRequest{Action: SubscribeAction, Topic: ChatTopic("c1"), LastSequence: &lastSeen}))
+
+		var reply Message
+		assert.NoError(t, ws.ReadJSON(&reply))
+		assert.Equal(t, "subscribed", reply.Msg)
+
+		var msg TopicMessage
+		assert.NoError(t, ws.ReadJSON(&msg))
+		assert.Equal(t, ChatTopic("c1"), msg.Topic)
+		assert.Equal(t, uint64(1), msg.Sequence)
+		assert.Equal(t, "missed chat", msg.Data)
+	})
+}
@@ -1,31 +1,63 @@
 package websocket
 
 import (
+	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/utils"
 )
 
 type Pool struct {
-	Register   chan *Client
-	Unregister chan *Client
-	Clients    map[string]*ClientData
-	Broadcast  chan Message
+	Register    chan *Client
+	Unregister  chan *Client
+	Clients     map[string]*ClientData
+	Topics      map[string]map[string]*Client
+	Broadcast   chan Message
+	NodeID      string
+	Broadcaster Broadcaster
+	Outbox      OutboxStore
+	Access      AccessStore
+	mu          sync.RWMutex
 }
 
 func NewPool() *Pool {
-	return &Pool{
+	pool := &Pool{
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 		Clients:    make(map[string]*ClientData),
+		Topics:     make(map[string]map[string]*Client),
 		Broadcast:  make(chan Message),
+		NodeID:     utils.GetRandomToken(20),
 	}
+	pool.UseBroadcaster(NewMemoryBroadcaster())
+	return pool
+}
+
+// UseBroadcaster swaps the backend used to reach sessions connected to
+// other nodes and subscribes this pool to it.
+func (pool *Pool) UseBroadcaster(broadcaster Broadcaster) error {
+	if err := broadcaster.Subscribe(pool.NodeID, pool.deliver); err != nil {
+		return err
+	}
+
+	pool.mu.Lock()
+	previous := pool.Broadcaster
+	pool.Broadcaster = broadcaster
+	pool.mu.Unlock()
+
+	if previous != nil && previous != broadcaster {
+		previous.Close()
+	}
+	return nil
 }
 
 func (pool *Pool) Start() {
 	for {
 		select {
 		case client := <-pool.Register:
+			pool.mu.Lock()
 			// ceck to acoid nil pointer
 			if pool.Clients == nil {
 				pool.Clients = make(map[string]*ClientData)
@@ -36,39 +68,118 @@ func (pool *Pool) Start() {
 				Status: true,
 			}
 			fmt.Println("Size of Websocket Connection Pool: ", len(pool.Clients))
+			pool.mu.Unlock()
+
 			err := db.Store.SetSocketConnections(db.Client{
 				Host: client.Host,
 				Conn: client.Conn,
 			})
 			if err == nil {
-				if pool.Clients[client.Host].Client.Conn != nil {
-					pool.Clients[client.Host].Client.Conn.WriteJSON(Message{Type: 1, Msg: "user_connect", Body: client.Host})
+				if client.Conn != nil {
+					client.WriteJSON(Message{Type: 1, Msg: "user_connect", Body: client.Host})
 					go client.Read()
 				}
 			} else {
 				fmt.Println("Websocket pool client save error")
 			}
 		case client := <-pool.Unregister:
+			pool.mu.Lock()
 			// ceck to acoid nil pointer
-			if pool.Clients[client.Host] != nil {
-				pool.Clients[client.Host].Client.Conn.WriteJSON(Message{Type: 1, Body: "User Disconnected..."})
+			if existing := pool.Clients[client.Host]; existing != nil {
+				existing.Client.WriteJSON(Message{Type: 1, Body: "User Disconnected..."})
 				delete(pool.Clients, client.Host)
+				pool.removeClientTopics(client.Host)
 				fmt.Println("Size of Connection Pool: ", len(pool.Clients))
 			}
+			pool.mu.Unlock()
 
 		case message := <-pool.Broadcast:
 			fmt.Println("Sending message to all clients in Pool")
+			pool.mu.RLock()
 			// ceck to acoid nil pointer
-			if pool.Clients != nil {
-				for client, _ := range pool.Clients {
-					if err := pool.Clients[client].Client.Conn.WriteJSON(message); err != nil {
-						fmt.Println(err)
-						return
-					}
+			for _, clientData := range pool.Clients {
+				if err := clientData.Client.WriteJSON(message); err != nil {
+					fmt.Println(err)
 				}
 			}
+			pool.mu.RUnlock()
+		}
+	}
+}
+
+// Size is the number of sessions connected to this node.
+func (pool *Pool) Size() int {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return len(pool.Clients)
+}
+
+func (pool *Pool) getClient(sessionID string) (*Client, bool) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	clientData, ok := pool.Clients[sessionID]
+	if !ok || clientData == nil || clientData.Client == nil {
+		return nil, false
+	}
+	return clientData.Client, true
+}
+
+// deliver is invoked by the broadcaster for messages published by other
+// nodes. Direct messages only succeed when the target session is connected
+// here, topic messages go to whichever local clients are subscribed.
+func (pool *Pool) deliver(message BroadcastMessage) error {
+	if message.Topic != "" {
+		pool.writeToTopic(message.Topic, message.Payload)
+		return nil
+	}
+
+	client, ok := pool.getClient(message.SessionID)
+	if !ok {
+		return fmt.Errorf("client not found: %s", message.SessionID)
+	}
+	return pool.writeDirect(client, message.Payload)
+}
+
+// sendDirect writes message to the session wherever it is connected. A
+// message already queued in the outbox is not an error when the session
+// is offline, since it will be replayed once the client reconnects.
+func (pool *Pool) sendDirect(kind string, sessionID string, message sequenced, queued bool) error {
+	if sessionID == "" {
+		return fmt.Errorf("client not found")
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %w", kind, err)
+	}
+
+	if client, ok := pool.getClient(sessionID); ok {
+		return pool.writeDirect(client, payload)
+	}
+
+	pool.mu.RLock()
+	broadcaster := pool.Broadcaster
+	pool.mu.RUnlock()
+
+	if broadcaster == nil {
+		if queued {
+			return nil
 		}
+		return fmt.Errorf("client not found: %s", sessionID)
 	}
+
+	err = broadcaster.Publish(BroadcastMessage{
+		Origin:    pool.NodeID,
+		Kind:      kind,
+		SessionID: sessionID,
+		Payload:   payload,
+	})
+	if err != nil && queued {
+		return nil
+	}
+	return err
 }
 
 func (pool *Pool) SendTicketMessage(message TicketMessage) error {
@@ -78,14 +189,9 @@ func (pool *Pool) SendTicketMessage(message TicketMessage) error {
 	}
 
 	if message.BroadcastType == "direct" {
-		if message.SourceSessionID == "" {
-			return fmt.Errorf("client not found")
-		}
-		// check if client
-		if client, ok := pool.Clients[message.SourceSessionID]; ok {
-			return client.Client.Conn.WriteJSON(message)
-		}
-		return fmt.Errorf("client not found: %s", message.SourceSessionID)
+		queued := message.SourceSessionID != "" &&
+			pool.record(SessionStream(message.SourceSessionID), BroadcastKindTicket, &message)
+		return pool.sendDirect(BroadcastKindTicket, message.SourceSessionID, &message, queued)
 	}
 
 	return nil
@@ -97,14 +203,9 @@ func (pool *Pool) SendTicketPlanMessage(message TicketPlanMessage) error {
 	}
 
 	if message.BroadcastType == "direct" {
-		if message.SourceSessionID == "" {
-			return fmt.Errorf("client not found")
-		}
-
-		if client, ok := pool.Clients[message.SourceSessionID]; ok {
-			return client.Client.Conn.WriteJSON(message)
-		}
-		return fmt.Errorf("client not found: %s", message.SourceSessionID)
+		queued := message.SourceSessionID != "" &&
+			pool.record(SessionStream(message.SourceSessionID), BroadcastKindTicketPlan, &message)
+		return pool.sendDirect(BroadcastKindTicketPlan, message.SourceSessionID, &message, queued)
 	}
 
 	return nil
@@ -72,6 +72,15 @@ func TestNewPool(t *testing.T) {
 	}
 }
 
+func TestPoolSize(t *testing.T) {
+	pool := NewPool()
+	assert.Equal(t, 0, pool.Size())
+
+	pool.Clients["session-1"] = &ClientData{Client: &Client{Host: "session-1"}, Status: true}
+	pool.Clients["session-2"] = &ClientData{Client: &Client{Host: "session-2"}, Status: true}
+	assert.Equal(t, 2, pool.Size())
+}
+
 func TestSendTicketMessage(t *testing.T) {
 	t.Run("Direct Broadcast with Valid Client", func(t *testing.T) {
 		pool := NewPool()
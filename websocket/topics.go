@@ -0,0 +1,231 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	TopicWorkspace = "workspace"
+	TopicFeature   = "feature"
+	TopicChat      = "chat"
+	TopicBounty    = "bounty"
+
+	SubscribeAction   = "subscribe"
+	UnsubscribeAction = "unsubscribe"
+
+	TopicEventTicket      = "ticket"
+	TopicEventTicketPlan  = "ticket_plan"
+	TopicEventChatMessage = "chat_message"
+	TopicEventActivity    = "activity"
+)
+
+var topicKinds = map[string]bool{
+	TopicWorkspace: true,
+	TopicFeature:   true,
+	TopicChat:      true,
+	TopicBounty:    true,
+}
+
+// SubscriptionRequest is sent by websocket clients to start or stop
+// receiving every update published for an entity. LastSequence lets a
+// reconnecting client ask for the messages it missed.
+type SubscriptionRequest struct {
+	Action       string  `json:"action"`
+	Topic        string  `json:"topic"`
+	LastSequence *uint64 `json:"last_sequence,omitempty"`
+}
+
+// TopicMessage is written to every client subscribed to Topic.
+type TopicMessage struct {
+	Topic    string      `json:"topic"`
+	Event    string      `json:"event"`
+	Data     interface{} `json:"data"`
+	Sequence uint64      `json:"sequence,omitempty"`
+}
+
+func Topic(kind string, id string) string {
+	return kind + ":" + id
+}
+
+func WorkspaceTopic(uuid string) string {
+	return Topic(TopicWorkspace, uuid)
+}
+
+func FeatureTopic(uuid string) string {
+	return Topic(TopicFeature, uuid)
+}
+
+func ChatTopic(chatID string) string {
+	return Topic(TopicChat, chatID)
+}
+
+func BountyTopic(id string) string {
+	return Topic(TopicBounty, id)
+}
+
+// ValidateTopic checks a topic has the "<kind>:<id>" form with a known kind.
+func ValidateTopic(topic string) error {
+	kind, id, found := strings.Cut(topic, ":")
+	if !found || id == "" {
+		return fmt.Errorf("invalid topic: %s", topic)
+	}
+	if !topicKinds[kind] {
+		return fmt.Errorf("unknown topic type: %s", kind)
+	}
+	return nil
+}
+
+// ParseSubscriptionRequest returns the request contained in a raw client
+// message, or false if the message is not a subscribe, unsubscribe or
+// replay request.
+func ParseSubscriptionRequest(p []byte) (SubscriptionRequest, bool) {
+	var request SubscriptionRequest
+	if err := json.Unmarshal(p, &request); err != nil {
+		return request, false
+	}
+
+	switch request.Action {
+	case SubscribeAction, UnsubscribeAction:
+		return request, request.Topic != ""
+	case ReplayAction:
+		return request, true
+	}
+	return request, false
+}
+
+func (pool *Pool) Subscribe(client *Client, topic string) error {
+	if err := ValidateTopic(topic); err != nil {
+		return err
+	}
+	if err := pool.CanAccessTopic(client, topic); err != nil {
+		return err
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.Topics == nil {
+		pool.Topics = make(map[string]map[string]*Client)
+	}
+	if pool.Topics[topic] == nil {
+		pool.Topics[topic] = make(map[string]*Client)
+	}
+	pool.Topics[topic][client.Host] = client
+	return nil
+}
+
+func (pool *Pool) Unsubscribe(client *Client, topic string) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.removeSubscription(client.Host, topic)
+}
+
+// removeSubscription must be called with pool.mu held.
+func (pool *Pool) removeSubscription(host string, topic string) {
+	subscribers, ok := pool.Topics[topic]
+	if !ok {
+		return
+	}
+	delete(subscribers, host)
+	if len(subscribers) == 0 {
+		delete(pool.Topics, topic)
+	}
+}
+
+// removeClientTopics must be called with pool.mu held.
+func (pool *Pool) removeClientTopics(host string) {
+	for topic := range pool.Topics {
+		pool.removeSubscription(host, topic)
+	}
+}
+
+func (pool *Pool) TopicSubscribers(topic string) []*Client {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(pool.Topics[topic]))
+	for _, client := range pool.Topics[topic] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+func (pool *Pool) writeToTopic(topic string, payload []byte) {
+	for _, client := range pool.TopicSubscribers(topic) {
+		if err := client.WriteRaw(payload); err != nil {
+			fmt.Println("Websocket topic write error", topic, err)
+		}
+	}
+}
+
+// PublishToTopic writes an event to every client subscribed to topic on
+// this node and forwards it to the other nodes through the broadcaster.
+func (pool *Pool) PublishToTopic(topic string, event string, data interface{}) error {
+	if pool == nil {
+		return fmt.Errorf("pool is nil")
+	}
+	if err := ValidateTopic(topic); err != nil {
+		return err
+	}
+
+	message := TopicMessage{
+		Topic: topic,
+		Event: event,
+		Data:  data,
+	}
+	pool.record(topic, event, &message)
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode topic message: %w", err)
+	}
+
+	pool.writeToTopic(topic, payload)
+
+	pool.mu.RLock()
+	broadcaster := pool.Broadcaster
+	pool.mu.RUnlock()
+
+	if broadcaster == nil {
+		return nil
+	}
+
+	return broadcaster.Publish(BroadcastMessage{
+		Origin:  pool.NodeID,
+		Kind:    event,
+		Topic:   topic,
+		Payload: payload,
+	})
+}
+
+func (c *Client) handleSubscription(request SubscriptionRequest) {
+	if request.Action == ReplayAction {
+		c.handleReplay(request)
+		return
+	}
+
+	var err error
+	var reply string
+
+	switch request.Action {
+	case SubscribeAction:
+		err = c.Pool.Subscribe(c, request.Topic)
+		reply = "subscribed"
+	case UnsubscribeAction:
+		c.Pool.Unsubscribe(c, request.Topic)
+		reply = "unsubscribed"
+	}
+
+	if err != nil {
+		c.WriteJSON(Message{Type: 1, Msg: "subscription_error", Body: err.Error()})
+		return
+	}
+	c.WriteJSON(Message{Type: 1, Msg: reply, Body: request.Topic})
+
+	if request.Action == SubscribeAction && request.LastSequence != nil {
+		c.handleReplay(request)
+	}
+}
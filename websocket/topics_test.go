@@ -0,0 +1,249 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTopic(t *testing.T) {
+	tests := []struct {
+		name    string
+		topic   string
+		wantErr bool
+	}{
+		{name: "Workspace Topic", topic: WorkspaceTopic("ws-uuid")},
+		{name: "Feature Topic", topic: FeatureTopic("feature-uuid")},
+		{name: "Chat Topic", topic: ChatTopic("chat-id")},
+		{name: "Bounty Topic", topic: BountyTopic("42")},
+		{name: "Empty Topic", topic: "", wantErr: true},
+		{name: "Missing ID", topic: "workspace:", wantErr: true},
+		{name: "Missing Separator", topic: "workspace", wantErr: true},
+		{name: "Unknown Kind", topic: "person:123", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTopic(tt.topic)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseSubscriptionRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected SubscriptionRequest
+		ok       bool
+	}{
+		{
+			name:     "Subscribe Request",
+			input:    `{"action":"subscribe","topic":"feature:abc"}`,
+			expected: SubscriptionRequest{Action: SubscribeAction, Topic: "feature:abc"},
+			ok:       true,
+		},
+		{
+			name:     "Unsubscribe Request",
+			input:    `{"action":"unsubscribe","topic":"chat:xyz"}`,
+			expected: SubscriptionRequest{Action: UnsubscribeAction, Topic: "chat:xyz"},
+			ok:       true,
+		},
+		{
+			name:  "Missing Topic",
+			input: `{"action":"subscribe"}`,
+		},
+		{
+			name:  "Other Message",
+			input: `{"host":"abc","ln":"lnurl"}`,
+		},
+		{
+			name:  "Invalid JSON",
+			input: `not json`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, ok := ParseSubscriptionRequest([]byte(tt.input))
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.expected, request)
+			}
+		})
+	}
+}
+
+func TestPoolTopicSubscriptions(t *testing.T) {
+	t.Run("Subscribe And Unsubscribe", func(t *testing.T) {
+		pool := NewPool()
+		client := &Client{Host: "client-1", Pool: pool}
+
+		assert.NoError(t, pool.Subscribe(client, FeatureTopic("f1")))
+		assert.Len(t, pool.TopicSubscribers(FeatureTopic("f1")), 1)
+
+		pool.Unsubscribe(client, FeatureTopic("f1"))
+		assert.Len(t, pool.TopicSubscribers(FeatureTopic("f1")), 0)
+		assert.NotContains(t, pool.Topics, FeatureTopic("f1"))
+	})
+
+	t.Run("Invalid Topic Is Rejected", func(t *testing.T) {
+		pool := NewPool()
+		client := &Client{Host: "client-1", Pool: pool}
+
+		assert.Error(t, pool.Subscribe(client, "invalid"))
+		assert.Len(t, pool.Topics, 0)
+	})
+
+	t.Run("Pool Without Topics Map", func(t *testing.T) {
+		pool := &Pool{Clients: make(map[string]*ClientData)}
+		client := &Client{Host: "client-1", Pool: pool}
+
+		assert.NoError(t, pool.Subscribe(client, ChatTopic("c1")))
+		assert.Len(t, pool.TopicSubscribers(ChatTopic("c1")), 1)
+		assert.NoError(t, pool.PublishToTopic(ChatTopic("c2"), TopicEventChatMessage, "nobody listening"))
+	})
+
+	t.Run("Publish Reaches Every Subscriber", func(t *testing.T) {
+		pool := NewPool()
+
+		ws1, received1, server1 := setupRecordingWebsocket(t)
+		defer server1.Close()
+		defer ws1.Close()
+		ws2, received2, server2 := setupRecordingWebsocket(t)
+		defer server2.Close()
+		defer ws2.Close()
+		ws3, received3, server3 := setupRecordingWebsocket(t)
+		defer server3.Close()
+		defer ws3.Close()
+
+		assert.NoError(t, pool.Subscribe(&Client{Host: "a", Conn: ws1, Pool: pool}, WorkspaceTopic("w1")))
+		assert.NoError(t, pool.Subscribe(&Client{Host: "b", Conn: ws2, Pool: pool}, WorkspaceTopic("w1")))
+		assert.NoError(t, pool.Subscribe(&Client{Host: "c", Conn: ws3, Pool: pool}, WorkspaceTopic("w2")))
+
+		err := pool.PublishToTopic(WorkspaceTopic("w1"), TopicEventTicket, TicketMessage{Action: "update"})
+		assert.NoError(t, err)
+
+		for _, received := range []<-chan []byte{received1, received2} {
+			var msg struct {
+				Topic string        `json:"topic"`
+				Event string        `json:"event"`
+				Data  TicketMessage `json:"data"`
+			}
+			assert.NoError(t, json.Unmarshal(waitForMessage(t, received), &msg))
+			assert.Equal(t, WorkspaceTopic("w1"), msg.Topic)
+			assert.Equal(t, TopicEventTicket, msg.Event)
+			assert.Equal(t, "update", msg.Data.Action)
+		}
+
+		select {
+		case msg := <-received3:
+			t.Fatalf("unsubscribed client received %s", string(msg))
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("Publish Reaches Subscribers On Other Pools", func(t *testing.T) {
+		broadcaster := NewMemoryBroadcaster()
+		sender := NewPool()
+		receiver := NewPool()
+		assert.NoError(t, sender.UseBroadcaster(broadcaster))
+		assert.NoError(t, receiver.UseBroadcaster(broadcaster))
+
+		ws, received, server := setupRecordingWebsocket(t)
+		defer server.Close()
+		defer ws.Close()
+		assert.NoError(t, receiver.Subscribe(&Client{Host: "remote", Conn: ws, Pool: receiver}, BountyTopic("7")))
+
+		assert.NoError(t, sender.PublishToTopic(BountyTopic("7"), "bounty", map[string]string{"status": "paid"}))
+
+		var msg TopicMessage
+		assert.NoError(t, json.Unmarshal(waitForMessage(t, received), &msg))
+		assert.Equal(t, BountyTopic("7"), msg.Topic)
+	})
+
+	t.Run("Unregister Removes Topic Membership", func(t *testing.T) {
+		db.InitCache()
+		pool := NewPool()
+		go pool.Start()
+
+		ws, _, server := setupRecordingWebsocket(t)
+		defer server.Close()
+		defer ws.Close()
+
+		client := &Client{Host: "leaving", Conn: ws, Pool: pool}
+		addTestClient(pool, client.Host, ws)
+		assert.NoError(t, pool.Subscribe(client, FeatureTopic("f1")))
+
+		pool.Unregister <- client
+
+		assert.Eventually(t, func() bool {
+			return len(pool.TopicSubscribers(FeatureTopic("f1"))) == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestClientSubscriptionProtocol(t *testing.T) {
+	db.InitCache()
+	pool := NewPool()
+	go pool.Start()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(pool, w, r)
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=subscriber"
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
+	assert.NoError(t, err)
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var connected Message
+	assert.NoError(t, ws.ReadJSON(&connected))
+	assert.Equal(t, "user_connect", connected.Msg)
+
+	t.Run("Subscribe Then Receive Topic Messages", func(t *testing.T) {
+		assert.NoError(t, ws.WriteJSON(SubscriptionRequest{Action: SubscribeAction, Topic: FeatureTopic("f1")}))
+
+		var reply Message
+		assert.NoError(t, ws.ReadJSON(&reply))
+		assert.Equal(t, "subscribed", reply.Msg)
+		assert.Equal(t, FeatureTopic("f1"), reply.Body)
+
+		assert.NoError(t, pool.PublishToTopic(FeatureTopic("f1"), TopicEventTicketPlan, TicketPlanMessage{Action: "TICKET_PLAN_CREATED"}))
+
+		var msg TopicMessage
+		assert.NoError(t, ws.ReadJSON(&msg))
+		assert.Equal(t, FeatureTopic("f1"), msg.Topic)
+		assert.Equal(t, TopicEventTicketPlan, msg.Event)
+	})
+
+	t.Run("Invalid Topic Returns Error", func(t *testing.T) {
+		assert.NoError(t, ws.WriteJSON(SubscriptionRequest{Action: SubscribeAction, Topic: "nope"}))
+
+		var reply Message
+		assert.NoError(t, ws.ReadJSON(&reply))
+		assert.Equal(t, "subscription_error", reply.Msg)
+	})
+
+	t.Run("Unsubscribe Stops Delivery", func(t *testing.T) {
+		assert.NoError(t, ws.WriteJSON(SubscriptionRequest{Action: UnsubscribeAction, Topic: FeatureTopic("f1")}))
+
+		var reply Message
+		assert.NoError(t, ws.ReadJSON(&reply))
+		assert.Equal(t, "unsubscribed", reply.Msg)
+		assert.Len(t, pool.TopicSubscribers(FeatureTopic("f1")), 0)
+	})
+}
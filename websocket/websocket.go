@@ -1,17 +1,43 @@
 package websocket
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/gorilla/websocket"
+	"github.com/stakwork/sphinx-tribes/auth"
 	"github.com/stakwork/sphinx-tribes/config"
+	"github.com/stakwork/sphinx-tribes/db"
+	"github.com/stakwork/sphinx-tribes/logger"
 	"github.com/stakwork/sphinx-tribes/utils"
 )
 
 var WebsocketPool = NewPool()
 
+// InitBroadcaster attaches the configured broadcast backend to the shared
+// pool. Redis is used when WEBSOCKET_BROADCASTER=redis so messages reach
+// sessions connected to any replica; otherwise delivery stays in-process.
+func InitBroadcaster() {
+	if config.WebsocketBroadcaster != "redis" {
+		return
+	}
+
+	if db.RedisClient == nil || db.RedisError != nil {
+		logger.Log.Error("Websocket Redis broadcaster unavailable, falling back to memory: %v", db.RedisError)
+		return
+	}
+
+	broadcaster := NewRedisBroadcaster(db.RedisClient, config.WebsocketBroadcastChannel)
+	if err := WebsocketPool.UseBroadcaster(broadcaster); err != nil {
+		logger.Log.Error("Websocket Redis broadcaster subscribe error: %v", err)
+		return
+	}
+
+	logger.Log.Info("Websocket pool %s using Redis broadcaster", WebsocketPool.NodeID)
+}
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		if config.Host == "https://people.sphinx.chat" {
@@ -34,8 +60,25 @@ func Upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
 	return conn, nil
 }
 
+// tokenFromRequest reads the credential from the token query parameter,
+// which browsers can set on a websocket URL, or the x-jwt header.
+func tokenFromRequest(r *http.Request) string {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = r.Header.Get("x-jwt")
+	}
+	return token
+}
+
 func ServeWs(pool *Pool, w http.ResponseWriter, r *http.Request) { // get url query params
 
+	pubkey, err := auth.PubKeyFromToken(tokenFromRequest(r))
+	if err != nil {
+		logger.Log.Info("[websocket] unauthorized connection: %v", err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
 	// get url query params
 	queryParams := r.URL.Query()
 	uniqueId := queryParams.Get("uniqueId")
@@ -44,20 +87,29 @@ func ServeWs(pool *Pool, w http.ResponseWriter, r *http.Request) { // get url qu
 		uniqueId = utils.GetRandomToken(40)
 	}
 
+	// a session stays bound to the pubkey that opened it
+	if err := pool.claimSession(uniqueId, pubkey); err != nil {
+		logger.Log.Info("[websocket] session %s not claimed: %v", uniqueId, err)
+		status := http.StatusForbidden
+		if !errors.Is(err, errSessionOwned) {
+			status = http.StatusInternalServerError
+		}
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
 	conn, err := Upgrade(w, r)
 	if err != nil {
 		fmt.Println("Error in ServeWs", err)
 		fmt.Fprintf(w, "%+v\n", err)
-	}
-
-	if uniqueId == "" {
 		return
 	}
 
 	client := &Client{
-		Host: uniqueId,
-		Conn: conn,
-		Pool: pool,
+		Host:        uniqueId,
+		OwnerPubKey: pubkey,
+		Conn:        conn,
+		Pool:        pool,
 	}
 	pool.Register <- client
 }
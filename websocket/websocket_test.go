@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/gorilla/websocket"
+	"github.com/stakwork/sphinx-tribes/auth"
 	"github.com/stakwork/sphinx-tribes/config"
 	"github.com/stretchr/testify/assert"
 )
@@ -24,7 +25,7 @@ func TestServeWs(t *testing.T) {
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=test123"
 
-		ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		defer ws.Close()
 
@@ -39,7 +40,7 @@ func TestServeWs(t *testing.T) {
 		defer server.Close()
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
-		ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		defer ws.Close()
 
@@ -54,7 +55,7 @@ func TestServeWs(t *testing.T) {
 		defer server.Close()
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=null"
-		ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		defer ws.Close()
 
@@ -69,7 +70,7 @@ func TestServeWs(t *testing.T) {
 		defer server.Close()
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=undefined"
-		ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		defer ws.Close()
 
@@ -85,11 +86,11 @@ func TestServeWs(t *testing.T) {
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=same123"
 
-		ws1, _, err1 := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws1, _, err1 := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err1)
 		defer ws1.Close()
 
-		ws2, _, err2 := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws2, _, err2 := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err2)
 		defer ws2.Close()
 
@@ -107,7 +108,7 @@ func TestServeWs(t *testing.T) {
 		uniqueID := url.QueryEscape("test@123!#$%")
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=" + uniqueID
 
-		ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		defer ws.Close()
 
@@ -138,7 +139,7 @@ func TestServeWs(t *testing.T) {
 				uniqueID := url.QueryEscape(testID)
 				wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=" + uniqueID
 
-				ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+				ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 				assert.NoError(t, err)
 				if ws != nil {
 					defer ws.Close()
@@ -170,7 +171,7 @@ func TestServeWs(t *testing.T) {
 				uniqueID := url.QueryEscape(testID)
 				wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=" + uniqueID
 
-				ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+				ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 				assert.NoError(t, err)
 				if ws != nil {
 					defer ws.Close()
@@ -191,7 +192,7 @@ func TestServeWs(t *testing.T) {
 		uniqueID := url.QueryEscape(longID)
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=" + uniqueID
 
-		ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		defer ws.Close()
 
@@ -207,7 +208,7 @@ func TestServeWs(t *testing.T) {
 
 		longID := strings.Repeat("a", 1000)
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=" + longID
-		ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		defer ws.Close()
 
@@ -227,7 +228,7 @@ func TestServeWs(t *testing.T) {
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=test789"
 
-		header := http.Header{}
+		header := testAuthHeader(t, "test-pubkey")
 		header.Add("Host", "people.sphinx.chat")
 
 		ws, _, err := websocket.DefaultDialer.Dial(wsURL, header)
@@ -245,7 +246,7 @@ func TestServeWs(t *testing.T) {
 		defer server.Close()
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=valid-test-id"
-		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		assert.NotNil(t, ws)
 		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
@@ -260,7 +261,7 @@ func TestServeWs(t *testing.T) {
 		defer server.Close()
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
-		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		assert.NotNil(t, ws)
 		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
@@ -275,7 +276,7 @@ func TestServeWs(t *testing.T) {
 		defer server.Close()
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=null"
-		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		assert.NotNil(t, ws)
 		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
@@ -290,7 +291,7 @@ func TestServeWs(t *testing.T) {
 		defer server.Close()
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=undefined"
-		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		assert.NotNil(t, ws)
 		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
@@ -305,7 +306,7 @@ func TestServeWs(t *testing.T) {
 		defer server.Close()
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId="
-		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		assert.NotNil(t, ws)
 		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
@@ -323,7 +324,7 @@ func TestServeWs(t *testing.T) {
 		defer server.Close()
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=test-id"
-		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, resp, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.Error(t, err)
 		assert.Nil(t, ws)
 		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
@@ -396,7 +397,7 @@ func TestServeWs(t *testing.T) {
 		defer server.Close()
 
 		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?uniqueId=test-id"
-		ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 		assert.NoError(t, err)
 		if ws != nil {
 			defer ws.Close()
@@ -463,7 +464,7 @@ func TestServeWs(t *testing.T) {
 		for i := 0; i < 5; i++ {
 			wsURL := fmt.Sprintf("ws%s?uniqueId=test-id-%d",
 				strings.TrimPrefix(server.URL, "http"), i)
-			ws, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			ws, resp, err := websocket.DefaultDialer.Dial(wsURL, testAuthHeader(t, "test-pubkey"))
 			assert.NoError(t, err)
 			assert.NotNil(t, ws)
 			assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
@@ -472,3 +473,19 @@ func TestServeWs(t *testing.T) {
 	})
 
 }
+
+// testAuthHeader returns the x-jwt header a client owning pubkey would
+// send on the websocket handshake.
+func testAuthHeader(t *testing.T, pubkey string) http.Header {
+	if config.JwtKey == "" {
+		config.JwtKey = "websocket-test-key"
+	}
+	auth.InitJwt()
+
+	token, err := auth.EncodeJwt(pubkey)
+	assert.NoError(t, err)
+
+	header := http.Header{}
+	header.Set("x-jwt", token)
+	return header
+}
@@ -0,0 +1,128 @@
+package workflows
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Echo is an in-process provider for tests and offline development. It
+// runs nothing: every job is kept, and Complete, Fail and Cancel post the
+// run's status with the job's vars echoed back to its StatusURL. An Echo
+// that completes on its own does so right after Submit.
+type Echo struct {
+	mu           sync.Mutex
+	client       HttpClient
+	autoComplete bool
+	runs         []*EchoRun
+}
+
+// EchoRun is a job submitted to an Echo and how it ended, Status is empty
+// while it runs.
+type EchoRun struct {
+	ID      string
+	Job     Job
+	Status  string
+	Message string
+}
+
+type echoStatus struct {
+	RunID   string      `json:"run_id"`
+	Status  string      `json:"status"`
+	Message string      `json:"message,omitempty"`
+	Vars    interface{} `json:"vars,omitempty"`
+}
+
+func NewEcho(client HttpClient, autoComplete bool) *Echo {
+	return &Echo{client: client, autoComplete: autoComplete}
+}
+
+func (e *Echo) Submit(ctx context.Context, job Job) (Run, error) {
+	e.mu.Lock()
+	run := &EchoRun{ID: fmt.Sprintf("echo-%d", len(e.runs)+1), Job: job}
+	e.runs = append(e.runs, run)
+	e.mu.Unlock()
+
+	response, _ := json.Marshal(map[string]interface{}{"success": true, "data": map[string]string{"run_id": run.ID}})
+	if e.autoComplete {
+		go e.Complete(run.ID)
+	}
+	return Run{ID: run.ID, Response: string(response)}, nil
+}
+
+func (e *Echo) Cancel(ctx context.Context, runID string) error {
+	return e.finish(runID, StatusCancelled, "")
+}
+
+// Complete ends a run successfully.
+func (e *Echo) Complete(runID string) error {
+	return e.finish(runID, StatusCompleted, "")
+}
+
+// Fail ends a run with an error.
+func (e *Echo) Fail(runID string, message string) error {
+	return e.finish(runID, StatusFailed, message)
+}
+
+func (e *Echo) ParseCallback(body []byte) (Callback, error) {
+	status := echoStatus{}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return Callback{}, fmt.Errorf("invalid echo status: %w", err)
+	}
+	return Callback{RunID: status.RunID, Status: status.Status, Message: status.Message}, nil
+}
+
+// Runs lists the runs submitted so far, in order.
+func (e *Echo) Runs() []EchoRun {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	runs := make([]EchoRun, len(e.runs))
+	for i, run := range e.runs {
+		runs[i] = *run
+	}
+	return runs
+}
+
+func (e *Echo) finish(runID string, status string, message string) error {
+	e.mu.Lock()
+	var run *EchoRun
+	for _, r := range e.runs {
+		if r.ID == runID {
+			run = r
+		}
+	}
+	if run == nil {
+		e.mu.Unlock()
+		return ErrRunNotFound
+	}
+	if run.Status != "" {
+		e.mu.Unlock()
+		return ErrRunFinished
+	}
+	run.Status = status
+	run.Message = message
+	job := run.Job
+	e.mu.Unlock()
+
+	if job.StatusURL == "" || e.client == nil {
+		return nil
+	}
+	body, err := json.Marshal(echoStatus{RunID: runID, Status: status, Message: message, Vars: job.Vars})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, job.StatusURL, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
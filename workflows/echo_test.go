@@ -0,0 +1,61 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEcho(t *testing.T) {
+	t.Run("Posts the run's status with its vars to the status URL", func(t *testing.T) {
+		posted := make(chan []byte, 1)
+		client := clientFunc(func(r *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, "https://example.com/status", r.URL.String())
+			posted <- body
+			return respond(http.StatusOK, `{}`), nil
+		})
+		echo := NewEcho(client, false)
+
+		run, err := echo.Submit(context.Background(), Job{Vars: map[string]string{"a": "b"}, StatusURL: "https://example.com/status"})
+		assert.NoError(t, err)
+		assert.Equal(t, "echo-1", run.ID)
+		assert.Empty(t, run.URL)
+
+		assert.NoError(t, echo.Fail(run.ID, "boom"))
+		callback, err := echo.ParseCallback(<-posted)
+		assert.NoError(t, err)
+		assert.Equal(t, Callback{RunID: "echo-1", Status: StatusFailed, Message: "boom"}, callback)
+
+		status := map[string]interface{}{}
+		echo.Submit(context.Background(), Job{Vars: map[string]string{"a": "b"}, StatusURL: "https://example.com/status"})
+		echo.Complete("echo-2")
+		json.Unmarshal(<-posted, &status)
+		assert.Equal(t, map[string]interface{}{"a": "b"}, status["vars"])
+	})
+
+	t.Run("Completes on its own", func(t *testing.T) {
+		echo := NewEcho(nil, true)
+
+		run, _ := echo.Submit(context.Background(), Job{})
+
+		assert.Eventually(t, func() bool { return echo.Runs()[0].Status == StatusCompleted }, time.Second, 10*time.Millisecond)
+		assert.ErrorIs(t, echo.Cancel(context.Background(), run.ID), ErrRunFinished)
+	})
+
+	t.Run("Cancels a run once", func(t *testing.T) {
+		echo := NewEcho(nil, false)
+
+		run, _ := echo.Submit(context.Background(), Job{})
+
+		assert.NoError(t, echo.Cancel(context.Background(), run.ID))
+		assert.Equal(t, StatusCancelled, echo.Runs()[0].Status)
+		assert.ErrorIs(t, echo.Cancel(context.Background(), run.ID), ErrRunFinished)
+		assert.ErrorIs(t, echo.Cancel(context.Background(), "echo-7"), ErrRunNotFound)
+	})
+}
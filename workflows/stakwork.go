@@ -0,0 +1,163 @@
+package workflows
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	stakworkProjectsURL = "https://api.stakwork.com/api/v1/projects"
+	stakworkProjectURL  = "https://jobs.stakwork.com/admin/projects/%s"
+)
+
+// Stakwork runs workflows as Stakwork projects.
+type Stakwork struct {
+	client      HttpClient
+	apiKey      string
+	projectsURL string
+}
+
+func NewStakwork(client HttpClient, apiKey string) *Stakwork {
+	return &Stakwork{
+		client:      client,
+		apiKey:      apiKey,
+		projectsURL: stakworkProjectsURL,
+	}
+}
+
+type stakworkProject struct {
+	Name           string      `json:"name"`
+	WorkflowID     interface{} `json:"workflow_id"`
+	WorkflowParams interface{} `json:"workflow_params"`
+	WebhookURL     string      `json:"webhook_url,omitempty"`
+}
+
+type stakworkProjectResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		ProjectID int64 `json:"project_id"`
+	} `json:"data"`
+}
+
+type stakworkStatus struct {
+	ProjectID     int64  `json:"project_id"`
+	ProjectStatus string `json:"project_status"`
+	Error         *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Submit creates a project running the job's workflow with its vars.
+// Stakwork posts the project status to the job's StatusURL.
+func (s *Stakwork) Submit(ctx context.Context, job Job) (Run, error) {
+	if s.apiKey == "" {
+		return Run{}, ErrNoCredentials
+	}
+
+	// Stakwork workflow ids are numbers
+	var workflowID interface{} = job.WorkflowID
+	if id, err := strconv.Atoi(job.WorkflowID); err == nil {
+		workflowID = id
+	}
+	payload, err := json.Marshal(stakworkProject{
+		Name:       job.Name,
+		WorkflowID: workflowID,
+		WorkflowParams: map[string]interface{}{
+			"set_var": map[string]interface{}{
+				"attributes": map[string]interface{}{
+					"vars": job.Vars,
+				},
+			},
+		},
+		WebhookURL: job.StatusURL,
+	})
+	if err != nil {
+		return Run{}, fmt.Errorf("error encoding payload: %w", err)
+	}
+
+	body, status, err := s.post(ctx, s.projectsURL, payload)
+	if err != nil {
+		return Run{}, err
+	}
+
+	project := stakworkProjectResponse{}
+	if err := json.Unmarshal(body, &project); err != nil && status == http.StatusOK {
+		return Run{}, fmt.Errorf("error decoding response: %w", err)
+	}
+	if status != http.StatusOK || !project.Success {
+		return Run{}, &APIError{StatusCode: status, Body: string(body)}
+	}
+
+	id := strconv.FormatInt(project.Data.ProjectID, 10)
+	return Run{
+		ID:       id,
+		URL:      fmt.Sprintf(stakworkProjectURL, id),
+		Response: string(body),
+	}, nil
+}
+
+// Cancel stops a running project.
+func (s *Stakwork) Cancel(ctx context.Context, runID string) error {
+	if s.apiKey == "" {
+		return ErrNoCredentials
+	}
+	if _, err := strconv.ParseInt(runID, 10, 64); err != nil {
+		return ErrRunNotFound
+	}
+
+	body, status, err := s.post(ctx, fmt.Sprintf("%s/%s/stop", s.projectsURL, runID), []byte("{}"))
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrRunNotFound
+	default:
+		return &APIError{StatusCode: status, Body: string(body)}
+	}
+}
+
+// ParseCallback reads the project status Stakwork posted.
+func (s *Stakwork) ParseCallback(body []byte) (Callback, error) {
+	status := stakworkStatus{}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return Callback{}, fmt.Errorf("invalid stakwork status: %w", err)
+	}
+
+	callback := Callback{Status: status.ProjectStatus}
+	if status.ProjectID != 0 {
+		callback.RunID = strconv.FormatInt(status.ProjectID, 10)
+	}
+	if status.Error != nil {
+		callback.Message = status.Error.Message
+	}
+	return callback, nil
+}
+
+func (s *Stakwork) post(ctx context.Context, url string, payload []byte) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Token token="+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading response: %w", err)
+	}
+	return body, resp.StatusCode, nil
+}
@@ -0,0 +1,99 @@
+package workflows
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type clientFunc func(req *http.Request) (*http.Response, error)
+
+func (f clientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func respond(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewBufferString(body)), Header: make(http.Header)}
+}
+
+func TestStakworkSubmit(t *testing.T) {
+	t.Run("Creates a project running the workflow with the job's vars", func(t *testing.T) {
+		var sent map[string]interface{}
+		var req *http.Request
+		client := clientFunc(func(r *http.Request) (*http.Response, error) {
+			req = r
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &sent)
+			return respond(http.StatusOK, `{"success":true,"data":{"project_id":12345}}`), nil
+		})
+
+		run, err := NewStakwork(client, "key").Submit(context.Background(), Job{
+			Name:       "Hive Chat Processor",
+			WorkflowID: "38842",
+			Vars:       map[string]string{"chatId": "chat-1"},
+			StatusURL:  "https://example.com/hivechat/chat-1/update",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "12345", run.ID)
+		assert.Equal(t, "https://jobs.stakwork.com/admin/projects/12345", run.URL)
+		assert.Equal(t, "Token token=key", req.Header.Get("Authorization"))
+		assert.Equal(t, stakworkProjectsURL, req.URL.String())
+		assert.Equal(t, float64(38842), sent["workflow_id"])
+		assert.Equal(t, "https://example.com/hivechat/chat-1/update", sent["webhook_url"])
+		vars := sent["workflow_params"].(map[string]interface{})["set_var"].(map[string]interface{})["attributes"].(map[string]interface{})["vars"]
+		assert.Equal(t, map[string]interface{}{"chatId": "chat-1"}, vars)
+	})
+
+	t.Run("Needs an API key", func(t *testing.T) {
+		_, err := NewStakwork(nil, "").Submit(context.Background(), Job{WorkflowID: "38842"})
+
+		assert.ErrorIs(t, err, ErrNoCredentials)
+	})
+
+	t.Run("Returns the refusal as an APIError", func(t *testing.T) {
+		client := clientFunc(func(r *http.Request) (*http.Response, error) {
+			return respond(http.StatusUnprocessableEntity, `{"success":false,"error":"bad workflow"}`), nil
+		})
+
+		_, err := NewStakwork(client, "key").Submit(context.Background(), Job{WorkflowID: "1"})
+
+		var apiErr *APIError
+		assert.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+		assert.Contains(t, apiErr.Body, "bad workflow")
+	})
+}
+
+func TestStakworkCancel(t *testing.T) {
+	var url string
+	client := clientFunc(func(r *http.Request) (*http.Response, error) {
+		url = r.URL.String()
+		if r.URL.Path == "/api/v1/projects/404/stop" {
+			return respond(http.StatusNotFound, `{}`), nil
+		}
+		return respond(http.StatusOK, `{"success":true}`), nil
+	})
+	stakwork := NewStakwork(client, "key")
+
+	assert.NoError(t, stakwork.Cancel(context.Background(), "12345"))
+	assert.Equal(t, stakworkProjectsURL+"/12345/stop", url)
+	assert.ErrorIs(t, stakwork.Cancel(context.Background(), "404"), ErrRunNotFound)
+	assert.ErrorIs(t, stakwork.Cancel(context.Background(), "echo-1"), ErrRunNotFound)
+}
+
+func TestStakworkParseCallback(t *testing.T) {
+	callback, err := NewStakwork(nil, "").ParseCallback([]byte(`{"project_id":12345,"project_status":"error","error":{"message":"timed out"}}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Callback{RunID: "12345", Status: StatusFailed, Message: "timed out"}, callback)
+
+	_, err = NewStakwork(nil, "").ParseCallback([]byte(`not json`))
+	assert.Error(t, err)
+}
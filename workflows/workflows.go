@@ -0,0 +1,110 @@
+// Package workflows implements the providers running the AI workflows
+// behind hive chat, builds and ticket and plan reviews: Stakwork, and an
+// in-process echo provider for tests and offline development.
+//
+// A provider starts a run of a job's workflow, reports the run's status to
+// the job's StatusURL and can cancel a run that has not finished. What a
+// workflow produces is its own business, it posts that wherever its vars
+// tell it to.
+package workflows
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kind is what a workflow is run for, a workspace picks the provider and
+// workflow of each kind.
+type Kind string
+
+const (
+	KindChat         Kind = "chat"
+	KindBuild        Kind = "build"
+	KindTicketReview Kind = "ticket_review"
+	KindPlanReview   Kind = "plan_review"
+)
+
+// Kinds lists every kind a workspace can configure.
+var Kinds = []Kind{KindChat, KindBuild, KindTicketReview, KindPlanReview}
+
+const (
+	ProviderStakwork = "stakwork"
+	ProviderEcho     = "echo"
+)
+
+// Providers lists the providers a workspace can pick.
+var Providers = []string{ProviderStakwork, ProviderEcho}
+
+// Run statuses reported to a job's StatusURL.
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "error"
+	StatusCancelled = "cancelled"
+)
+
+var (
+	// ErrNoCredentials is returned by Submit when the provider has no
+	// credentials to run the workflow with.
+	ErrNoCredentials = errors.New("workflow provider credentials not set")
+	// ErrRunNotFound is returned by Cancel for a run the provider does not
+	// know.
+	ErrRunNotFound = errors.New("workflow run not found")
+	// ErrRunFinished is returned by Cancel for a run that already ended.
+	ErrRunFinished = errors.New("workflow run already finished")
+)
+
+type HttpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Workflow is the workflow a kind of job runs by default: Stakwork's, with
+// the API key read from the APIKeyEnv environment variable.
+type Workflow struct {
+	WorkflowID string
+	APIKeyEnv  string
+}
+
+// Defaults are the Stakwork workflows each kind ran before workspaces could
+// pick their own.
+var Defaults = map[Kind]Workflow{
+	KindChat:         {WorkflowID: "38842", APIKeyEnv: "SWWFKEY"},
+	KindBuild:        {WorkflowID: "43859", APIKeyEnv: "SWPR"},
+	KindTicketReview: {WorkflowID: "37324", APIKeyEnv: "SWWFKEY"},
+	KindPlanReview:   {WorkflowID: "42472", APIKeyEnv: "SWWFKEY"},
+}
+
+// Job is a run of a workflow to start. Vars are handed to the workflow as
+// they are; the provider posts the status of the run to StatusURL when it
+// is set.
+type Job struct {
+	Name       string
+	WorkflowID string
+	Vars       interface{}
+	StatusURL  string
+}
+
+// Run is a started job. URL is where a person can follow the run, empty
+// when the provider has no such page; Response is the provider's answer to
+// the submission, passed on to clients as it is.
+type Run struct {
+	ID       string
+	URL      string
+	Response string
+}
+
+// Callback is the status of a run a provider posted to a job's StatusURL.
+type Callback struct {
+	RunID   string
+	Status  string
+	Message string
+}
+
+// APIError is a submission the provider answered with an error.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return "workflow provider error: " + e.Body
+}